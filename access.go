@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// mutatingTools change files in the workspace, directly or through the language
+// server, or run code that may. They are disabled in read-only mode.
+var mutatingTools = map[string]bool{
+	"apply_text_edit":  true,
+	"rename_symbol":    true,
+	"execute_codelens": true,
+	"fix_all":          true,
+	"extract_function": true,
+	"extract_variable": true,
+	"move_symbol":      true,
+	"run_runnable":     true,
+}
+
+// mutatingToolNames returns the names of mutatingTools in sorted order
+func mutatingToolNames() []string {
+	names := make([]string, 0, len(mutatingTools))
+	for name := range mutatingTools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// registerTool registers a tool with the MCP server and remembers its name. Path
+// arguments are confined to the workspace sandbox, large responses get a footer,
+// language server errors are explained and calls wait while the workspace is
+// switched. The tool is registered under its name with --tool-prefix and
+// --tool-suffix, everywhere else it goes by its own name.
+func (s *server) registerTool(name string, description string, handler any) error {
+	s.toolNames = append(s.toolNames, name)
+	if name != setDefaultsTool {
+		s.defaults.addTool(s.toolName(name), handler, toolFormats[name])
+	}
+	middleware := []toolMiddleware{s.withErrorGuidance, s.sandboxed, s.withVerbosity, withFooter, s.withTokenBudget}
+	if name != setWorkspaceTool {
+		middleware = append([]toolMiddleware{s.workspaceLocked}, middleware...)
+	}
+	handler = wrapHandler(handler, middleware...)
+	if s.config.toolLanguage != "" {
+		description = fmt.Sprintf("[%s] %s", s.config.toolLanguage, description)
+	}
+	return s.mcpServer.RegisterTool(s.toolName(name), description, handler)
+}
+
+// toolName is the name a tool is registered under
+func (s *server) toolName(name string) string {
+	return s.config.toolPrefix + name + s.config.toolSuffix
+}
+
+// validToolAffix reports whether a tool name prefix or suffix keeps names within
+// the characters MCP clients accept
+func validToolAffix(affix string) bool {
+	for _, r := range affix {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// applyToolAccess deregisters the tools disabled by --read-only, --tools and
+// --disable-tools. It runs after registerTools so that unknown names are caught.
+// Tools may be named with or without --tool-prefix and --tool-suffix.
+func (s *server) applyToolAccess() error {
+	for _, name := range append(append([]string{}, s.config.enabledTools...), s.config.disabledTools...) {
+		if !s.mcpServer.CheckToolRegistered(s.toolName(s.baseToolName(name))) {
+			return fmt.Errorf("unknown tool %q", name)
+		}
+	}
+
+	enabled := make(map[string]bool)
+	for _, name := range s.config.enabledTools {
+		enabled[s.baseToolName(name)] = true
+	}
+	disabled := make(map[string]bool)
+	for _, name := range s.config.disabledTools {
+		disabled[s.baseToolName(name)] = true
+	}
+	if s.config.readOnly {
+		for name := range mutatingTools {
+			if enabled[name] {
+				log.Printf("Warning: %s is disabled in read-only mode", name)
+			}
+			disabled[name] = true
+		}
+	}
+
+	var removed []string
+	for _, name := range s.toolNames {
+		if disabled[name] || (len(enabled) > 0 && !enabled[name]) {
+			if err := s.mcpServer.DeregisterTool(s.toolName(name)); err != nil {
+				return fmt.Errorf("failed to disable tool %s: %v", name, err)
+			}
+			removed = append(removed, name)
+		}
+	}
+	if len(removed) > 0 {
+		sort.Strings(removed)
+		log.Printf("Disabled tools: %s", strings.Join(removed, ", "))
+	}
+	return nil
+}
+
+// baseToolName strips --tool-prefix and --tool-suffix from a tool name that has them
+func (s *server) baseToolName(name string) string {
+	if s.config.toolPrefix == "" && s.config.toolSuffix == "" {
+		return name
+	}
+	base, hasPrefix := strings.CutPrefix(name, s.config.toolPrefix)
+	base, hasSuffix := strings.CutSuffix(base, s.config.toolSuffix)
+	if hasPrefix && hasSuffix && base != "" {
+		return base
+	}
+	return name
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -91,7 +91,7 @@ func main() {
 
 	///////////////////////////////////////////////////////////////////////////
 	// Test Tools
-	response, err := tools.ReadDefinition(ctx, client, cfg.keyword, true)
+	response, err := tools.ReadDefinition(ctx, client, cfg.keyword, true, false, tools.DefinitionOptions{})
 	if err != nil {
 		log.Fatalf("ReadDefinition failed: %v", err)
 	}
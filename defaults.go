@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	mcp_golang "github.com/metoro-io/mcp-golang"
+)
+
+// setDefaultsTool sets the defaults. Its own arguments are never filled in, they
+// would overwrite the defaults with themselves.
+const setDefaultsTool = "set_defaults"
+
+// toolDefaults are the arguments a session set with set_defaults. They are filled
+// into the tool calls that leave them out, except maxTokens, which no tool takes
+// and which limits the size of every response instead.
+type toolDefaults struct {
+	ShowLineNumbers *bool  `json:"showLineNumbers,omitempty"`
+	Verbosity       string `json:"verbosity,omitempty"`
+	ScopePath       string `json:"scopePath,omitempty"`
+	Format          string `json:"format,omitempty"`
+	MaxTokens       int    `json:"maxTokens,omitempty"`
+}
+
+// toolFormats are the formats of the tools that take a format. Each accepts its
+// own, so a format default only goes to the tools that accept it.
+var toolFormats = map[string][]string{
+	"call_graph":       tools.CallGraphFormats,
+	"dependency_graph": tools.DependencyGraphFormats,
+}
+
+// sessionDefaults keeps the defaults of each session and the arguments each tool
+// takes, so defaults only go to the tools that have them
+type sessionDefaults struct {
+	mu        sync.Mutex
+	sessions  map[string]toolDefaults
+	arguments map[string]map[string]bool
+	formats   map[string][]string
+}
+
+func newSessionDefaults() *sessionDefaults {
+	return &sessionDefaults{
+		sessions:  make(map[string]toolDefaults),
+		arguments: make(map[string]map[string]bool),
+		formats:   make(map[string][]string),
+	}
+}
+
+// addTool records the argument names of a tool's handler and the formats it accepts
+func (d *sessionDefaults) addTool(name string, handler any, formats []string) {
+	fnType := reflect.TypeOf(handler)
+	if fnType.Kind() != reflect.Func || fnType.NumIn() != 2 || fnType.In(1).Kind() != reflect.Struct {
+		return
+	}
+	argsType := fnType.In(1)
+	arguments := make(map[string]bool)
+	for i := 0; i < argsType.NumField(); i++ {
+		if argument, _, _ := strings.Cut(argsType.Field(i).Tag.Get("json"), ","); argument != "" && argument != "-" {
+			arguments[argument] = true
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.arguments[name] = arguments
+	d.formats[name] = formats
+}
+
+// validate checks defaults before they are stored, a bad one would make every
+// call that gets it fail
+func (defaults toolDefaults) validate() error {
+	if defaults.MaxTokens < 0 {
+		return fmt.Errorf("maxTokens must not be negative")
+	}
+	if err := tools.ValidateVerbosity(defaults.Verbosity); err != nil {
+		return err
+	}
+	if defaults.Format == "" {
+		return nil
+	}
+	var known []string
+	for _, formats := range toolFormats {
+		known = append(known, formats...)
+	}
+	slices.Sort(known)
+	known = slices.Compact(known)
+	if !slices.Contains(known, defaults.Format) {
+		return fmt.Errorf("invalid format %q, no tool takes it, expected one of %s", defaults.Format, strings.Join(known, ", "))
+	}
+	return nil
+}
+
+func (d *sessionDefaults) get(sessionID string) toolDefaults {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.sessions[sessionID]
+}
+
+func (d *sessionDefaults) set(sessionID string, defaults toolDefaults) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if defaults == (toolDefaults{}) {
+		delete(d.sessions, sessionID)
+		return
+	}
+	d.sessions[sessionID] = defaults
+}
+
+// forget drops the defaults of a disconnected session
+func (d *sessionDefaults) forget(sessionID string) {
+	d.set(sessionID, toolDefaults{})
+}
+
+// apply fills a session's defaults into the arguments of a tools/call request that
+// leaves them out. Arguments given in the call are kept, even when empty.
+// showLineNumbers is true unless the session set it.
+func (d *sessionDefaults) apply(sessionID string, params json.RawMessage) json.RawMessage {
+	var call map[string]json.RawMessage
+	if err := json.Unmarshal(params, &call); err != nil {
+		return params
+	}
+	var name string
+	if err := json.Unmarshal(call["name"], &name); err != nil {
+		return params
+	}
+
+	d.mu.Lock()
+	defaults := d.sessions[sessionID]
+	accepted := d.arguments[name]
+	formats := d.formats[name]
+	d.mu.Unlock()
+	if len(accepted) == 0 {
+		return params
+	}
+	if !slices.Contains(formats, defaults.Format) {
+		defaults.Format = ""
+	}
+
+	// Without a session default, line numbers are on as the tools document
+	if defaults.ShowLineNumbers == nil {
+		on := true
+		defaults.ShowLineNumbers = &on
+	}
+	defaults.MaxTokens = 0
+	encoded, err := json.Marshal(defaults)
+	if err != nil {
+		return params
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &values); err != nil {
+		return params
+	}
+	arguments := make(map[string]json.RawMessage)
+	if raw := call["arguments"]; len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &arguments); err != nil {
+			return params
+		}
+	}
+	changed := false
+	for argument, value := range values {
+		if _, given := arguments[argument]; accepted[argument] && !given {
+			arguments[argument] = value
+			changed = true
+		}
+	}
+	if !changed {
+		return params
+	}
+
+	if call["arguments"], err = json.Marshal(arguments); err != nil {
+		return params
+	}
+	merged, err := json.Marshal(call)
+	if err != nil {
+		return params
+	}
+	return merged
+}
+
+// describe lists a session's defaults for the set_defaults response
+func (defaults toolDefaults) describe() string {
+	var lines []string
+	if defaults.ShowLineNumbers != nil {
+		lines = append(lines, fmt.Sprintf("showLineNumbers: %t", *defaults.ShowLineNumbers))
+	}
+	if defaults.Verbosity != "" {
+		lines = append(lines, "verbosity: "+defaults.Verbosity)
+	}
+	if defaults.ScopePath != "" {
+		lines = append(lines, "scopePath: "+defaults.ScopePath)
+	}
+	if defaults.Format != "" {
+		lines = append(lines, "format: "+defaults.Format)
+	}
+	if defaults.MaxTokens > 0 {
+		lines = append(lines, fmt.Sprintf("maxTokens: %d", defaults.MaxTokens))
+	}
+	if len(lines) == 0 {
+		return "No defaults are set for this session."
+	}
+	return "Defaults for this session, used by the tools that take them when a call leaves them out:\n  " + strings.Join(lines, "\n  ")
+}
+
+// withTokenBudget cuts a tool call's response to about the maxTokens its session
+// set with set_defaults
+func (s *server) withTokenBudget(next toolHandler) toolHandler {
+	return func(ctx context.Context, args any) (*mcp_golang.ToolResponse, error) {
+		response, err := next(ctx, args)
+		if maxTokens := s.defaults.get(lsp.SessionFromContext(ctx)).MaxTokens; maxTokens > 0 && response != nil {
+			limitResponse(response, maxTokens)
+		}
+		return response, err
+	}
+}
+
+// limitResponse cuts the text parts of a response to about maxTokens together and
+// ends it with a footer counting the lines left out
+func limitResponse(response *mcp_golang.ToolResponse, maxTokens int) {
+	budget, total, omitted := maxTokens, 0, 0
+	var kept []*mcp_golang.Content
+	for _, content := range response.Content {
+		if content == nil || content.TextContent == nil {
+			kept = append(kept, content)
+			continue
+		}
+		text := content.TextContent.Text
+		total += tools.EstimateTokens(text)
+		if budget <= 0 {
+			omitted += len(strings.Split(strings.TrimSuffix(text, "\n"), "\n"))
+			continue
+		}
+		truncated, lines := tools.TruncateToTokens(text, budget)
+		budget -= tools.EstimateTokens(truncated)
+		omitted += lines
+		content.TextContent.Text = truncated
+		kept = append(kept, content)
+	}
+	if omitted == 0 {
+		return
+	}
+
+	footer := tools.Footer(total, tools.ResponseHints{Omitted: omitted, OmittedKind: "lines over the session's maxTokens"})
+	response.Content = kept
+	if len(kept) > 0 && kept[len(kept)-1] != nil && kept[len(kept)-1].TextContent != nil {
+		last := kept[len(kept)-1].TextContent
+		last.Text = strings.TrimRight(last.Text, "\n") + footer
+		return
+	}
+	response.Content = append(response.Content, mcp_golang.NewTextContent(strings.TrimPrefix(footer, "\n")))
+}
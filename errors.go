@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	mcp_golang "github.com/metoro-io/mcp-golang"
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// toolCallKey is the context key of a tool call's request ID
+type toolCallKey struct{}
+
+// withToolCall returns ctx with the ID of a tools/call request
+func withToolCall(ctx context.Context, request *transport.BaseJSONRPCRequest) context.Context {
+	return context.WithValue(ctx, toolCallKey{}, request.Id)
+}
+
+// withErrorGuidance makes errors caused by a language server error response say
+// what the code means and whether retrying may help. The code is also added to the
+// result's _meta, so clients can retry without parsing the text.
+func (s *server) withErrorGuidance(next toolHandler) toolHandler {
+	return func(ctx context.Context, args any) (*mcp_golang.ToolResponse, error) {
+		response, err := next(ctx, args)
+		responseErr, ok := lsp.ResponseErrorFrom(err)
+		if !ok {
+			return response, err
+		}
+
+		if id, ok := ctx.Value(toolCallKey{}).(transport.RequestId); ok && s.mcpTransport != nil {
+			s.mcpTransport.setToolError(id, responseErr)
+		}
+		return response, fmt.Errorf("%v\n\n%s", err, errorGuidance(responseErr))
+	}
+}
+
+// errorGuidance describes a language server error to the agent that got it
+func errorGuidance(responseErr *lsp.ResponseError) string {
+	name := responseErr.Name()
+	if name == "" {
+		name = "unknown code"
+	}
+	retry := "no"
+	if responseErr.Retryable() {
+		retry = "yes"
+	}
+	return fmt.Sprintf("Language server error: %s (%d)\nRetryable: %s\n%s",
+		name, responseErr.Code, retry, responseErr.Explanation())
+}
+
+// toolErrorMeta is added to the _meta of a tool result that failed because of a
+// language server error response
+type toolErrorMeta struct {
+	Code        int    `json:"code"`
+	Name        string `json:"name,omitempty"`
+	Retryable   bool   `json:"retryable"`
+	Explanation string `json:"explanation"`
+}
+
+// setToolError remembers the language server error a tool call failed with, so
+// that its result can carry it, see withToolErrorMeta
+func (t *subscriptionTransport) setToolError(id transport.RequestId, responseErr *lsp.ResponseError) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if call, ok := t.toolCalls[id]; ok {
+		call.lspError = responseErr
+		t.toolCalls[id] = call
+	}
+}
+
+// withToolErrorMeta adds the language server error to a tool result's _meta under
+// "languageServerError"
+func withToolErrorMeta(result json.RawMessage, responseErr *lsp.ResponseError) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(result, &fields); err != nil {
+		return nil, err
+	}
+	meta := make(map[string]json.RawMessage)
+	if raw, ok := fields["_meta"]; ok {
+		if err := json.Unmarshal(raw, &meta); err != nil {
+			return nil, err
+		}
+	}
+
+	info, err := json.Marshal(toolErrorMeta{
+		Code:        responseErr.Code,
+		Name:        responseErr.Name(),
+		Retryable:   responseErr.Retryable(),
+		Explanation: responseErr.Explanation(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	meta["languageServerError"] = info
+	if fields["_meta"], err = json.Marshal(meta); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	mcp_golang "github.com/metoro-io/mcp-golang"
+)
+
+// withFooter ends large responses with a footer giving their estimated token count.
+// Tools that know what they left out add their own footer with suggestions, which
+// is kept as is.
+func withFooter(next toolHandler) toolHandler {
+	return func(ctx context.Context, args any) (*mcp_golang.ToolResponse, error) {
+		response, err := next(ctx, args)
+		if response != nil {
+			addFooter(response)
+		}
+		return response, err
+	}
+}
+
+// addFooter appends a footer part to a response whose text parts add up to a large
+// response. Parts are counted together, find_references returns one per file.
+func addFooter(response *mcp_golang.ToolResponse) {
+	tokens := 0
+	for _, content := range response.Content {
+		if content == nil || content.TextContent == nil {
+			continue
+		}
+		if tools.HasFooter(content.TextContent.Text) {
+			return
+		}
+		tokens += tools.EstimateTokens(content.TextContent.Text)
+	}
+	if tokens < tools.LargeResponseTokens {
+		return
+	}
+
+	footer := tools.Footer(tokens, tools.ResponseHints{})
+	if len(response.Content) == 1 && response.Content[0].TextContent != nil {
+		response.Content[0].TextContent.Text += footer
+		return
+	}
+	response.Content = append(response.Content, mcp_golang.NewTextContent(strings.TrimPrefix(footer, "\n")))
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"log"
+
+	"github.com/isaacphi/mcp-language-server/internal/index"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+)
+
+// maxIndexedFiles caps the files indexed by one reconcile, the rest are indexed on
+// later runs
+const maxIndexedFiles = 5000
+
+// loadSymbolIndex loads the persisted symbol index for the workspace, so tools can
+// fall back to it before the language server has indexed the workspace
+func (s *server) loadSymbolIndex() {
+	path, err := index.DefaultPath(s.config.workspaceDir, s.config.lspCommand)
+	if err != nil {
+		log.Printf("Symbol index disabled: %v", err)
+		return
+	}
+	idx, err := index.Load(path)
+	if err != nil {
+		log.Printf("Symbol index disabled: %v", err)
+		return
+	}
+	s.symbolIndex = idx
+	tools.SetSymbolIndex(idx)
+
+	stats := idx.Stats()
+	log.Printf("Loaded symbol index with %d symbols in %d files from %s", stats.Symbols, stats.Files, path)
+}
+
+// reconcileSymbolIndex updates the symbol index from the language server in the
+// background
+func (s *server) reconcileSymbolIndex() {
+	if s.symbolIndex == nil {
+		return
+	}
+	ctx, client, w, idx := s.workspaceCtx, s.lspClient, s.workspaceWatcher, s.symbolIndex
+	go func() {
+		if err := index.Reconcile(ctx, client, w, idx, maxIndexedFiles); err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Failed to update symbol index: %v", err)
+			}
+			return
+		}
+		stats := idx.Stats()
+		log.Printf("Symbol index up to date: %d symbols in %d files", stats.Symbols, stats.Files)
+	}()
+}
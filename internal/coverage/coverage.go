@@ -0,0 +1,232 @@
+// Package coverage reads test coverage reports, Go coverprofiles and lcov
+// tracefiles, into per-file statement blocks that tools can match with symbols.
+package coverage
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Block is a run of statements that execute together, with lines 1-based and inclusive
+type Block struct {
+	StartLine  int
+	EndLine    int
+	Statements int
+	Count      int // Times the block ran
+}
+
+// Profile is the coverage of each file, by absolute path
+type Profile struct {
+	Files map[string][]Block
+}
+
+// Load reads a Go coverprofile or an lcov tracefile. File names in the report are
+// resolved against workspaceDir: Go import paths through the module path in go.mod,
+// relative paths against the workspace, and anything else by the longest suffix that
+// exists in the workspace. Files that cannot be found are left out.
+func Load(path string, workspaceDir string) (*Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read coverage report: %w", err)
+	}
+
+	resolver := newPathResolver(workspaceDir)
+	var blocks map[string][]Block
+	if bytes.HasPrefix(data, []byte("mode:")) {
+		blocks, err = parseGoProfile(data)
+	} else {
+		blocks, err = parseLcov(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &Profile{Files: make(map[string][]Block)}
+	for name, fileBlocks := range blocks {
+		if resolved := resolver.resolve(name); resolved != "" {
+			profile.Files[resolved] = append(profile.Files[resolved], fileBlocks...)
+		}
+	}
+	if len(profile.Files) == 0 && len(blocks) > 0 {
+		return nil, fmt.Errorf("none of the %d files in the coverage report were found in %s", len(blocks), workspaceDir)
+	}
+	return profile, nil
+}
+
+// Summary is the coverage of a set of blocks
+type Summary struct {
+	Covered int // Statements that ran
+	Total   int
+}
+
+// Percent returns the share of statements covered, or 0 without statements
+func (s Summary) Percent() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.Covered) * 100 / float64(s.Total)
+}
+
+// Summarize counts the statements of blocks starting within lines start to end
+func Summarize(blocks []Block, start, end int) Summary {
+	var summary Summary
+	for _, block := range blocks {
+		if block.StartLine < start || block.StartLine > end {
+			continue
+		}
+		summary.Total += block.Statements
+		if block.Count > 0 {
+			summary.Covered += block.Statements
+		}
+	}
+	return summary
+}
+
+// LineState is whether a line ran in the tests
+type LineState int
+
+const (
+	NotExecutable LineState = iota // No statements on the line
+	Covered
+	Uncovered
+)
+
+// Lines returns the state of each line with statements. A line is covered if any
+// block on it ran.
+func Lines(blocks []Block) map[int]LineState {
+	lines := make(map[int]LineState)
+	for _, block := range blocks {
+		for line := block.StartLine; line <= block.EndLine; line++ {
+			if block.Count > 0 {
+				lines[line] = Covered
+			} else if lines[line] != Covered {
+				lines[line] = Uncovered
+			}
+		}
+	}
+	return lines
+}
+
+// parseGoProfile parses the output of go test -coverprofile. Lines after the mode
+// line look like "example.com/pkg/file.go:10.13,12.3 2 1": the block's start and end
+// as line.column, its number of statements and how often it ran.
+func parseGoProfile(data []byte) (map[string][]Block, error) {
+	blocks := make(map[string][]Block)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		colon := strings.LastIndex(line, ":")
+		fields := strings.Fields(line[colon+1:])
+		if colon < 0 || len(fields) != 3 {
+			return nil, fmt.Errorf("invalid coverprofile line %d: %q", lineNum, line)
+		}
+		var block Block
+		var startCol, endCol int
+		if _, err := fmt.Sscanf(fields[0], "%d.%d,%d.%d", &block.StartLine, &startCol, &block.EndLine, &endCol); err != nil {
+			return nil, fmt.Errorf("invalid coverprofile line %d: %q", lineNum, line)
+		}
+		var err error
+		if block.Statements, err = strconv.Atoi(fields[1]); err != nil {
+			return nil, fmt.Errorf("invalid coverprofile line %d: %q", lineNum, line)
+		}
+		if block.Count, err = strconv.Atoi(fields[2]); err != nil {
+			return nil, fmt.Errorf("invalid coverprofile line %d: %q", lineNum, line)
+		}
+		name := line[:colon]
+		blocks[name] = append(blocks[name], block)
+	}
+	return blocks, scanner.Err()
+}
+
+// parseLcov parses an lcov tracefile. Each "DA:<line>,<count>" record becomes a
+// block of one statement in the file named by the preceding "SF:" record.
+func parseLcov(data []byte) (map[string][]Block, error) {
+	blocks := make(map[string][]Block)
+	var current string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "SF:"):
+			current = strings.TrimPrefix(line, "SF:")
+		case line == "end_of_record":
+			current = ""
+		case strings.HasPrefix(line, "DA:") && current != "":
+			fields := strings.Split(strings.TrimPrefix(line, "DA:"), ",")
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("invalid lcov line %d: %q", lineNum, line)
+			}
+			lineNo, err := strconv.Atoi(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid lcov line %d: %q", lineNum, line)
+			}
+			// Some tools write counts as floats
+			count, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid lcov line %d: %q", lineNum, line)
+			}
+			blocks[current] = append(blocks[current], Block{StartLine: lineNo, EndLine: lineNo, Statements: 1, Count: int(count)})
+		}
+	}
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("not a Go coverprofile or lcov tracefile")
+	}
+	return blocks, scanner.Err()
+}
+
+// pathResolver maps file names in coverage reports to files in the workspace
+type pathResolver struct {
+	workspaceDir string
+	modulePath   string
+}
+
+func newPathResolver(workspaceDir string) *pathResolver {
+	r := &pathResolver{workspaceDir: workspaceDir}
+	if data, err := os.ReadFile(filepath.Join(workspaceDir, "go.mod")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "module" {
+				r.modulePath = strings.Trim(fields[1], `"`)
+				break
+			}
+		}
+	}
+	return r
+}
+
+func (r *pathResolver) resolve(name string) string {
+	exists := func(path string) bool {
+		info, err := os.Stat(path)
+		return err == nil && !info.IsDir()
+	}
+
+	if filepath.IsAbs(name) {
+		if exists(name) {
+			return name
+		}
+	} else if exists(filepath.Join(r.workspaceDir, name)) {
+		return filepath.Join(r.workspaceDir, name)
+	}
+	if r.modulePath != "" && strings.HasPrefix(name, r.modulePath+"/") {
+		if path := filepath.Join(r.workspaceDir, strings.TrimPrefix(name, r.modulePath+"/")); exists(path) {
+			return path
+		}
+	}
+
+	// Reports from other machines or nested modules, drop leading directories but
+	// keep one, a bare file name is too likely to match the wrong file
+	parts := strings.Split(filepath.ToSlash(name), "/")
+	for i := 1; i < len(parts)-1; i++ {
+		if path := filepath.Join(r.workspaceDir, filepath.Join(parts[i:]...)); exists(path) {
+			return path
+		}
+	}
+	return ""
+}
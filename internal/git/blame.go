@@ -0,0 +1,89 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BlameLine is the commit that last changed a line
+type BlameLine struct {
+	Hash    string
+	Author  string
+	Time    time.Time
+	Summary string
+}
+
+// Uncommitted reports whether the line has changes that are not committed yet
+func (b BlameLine) Uncommitted() bool {
+	return strings.Trim(b.Hash, "0") == ""
+}
+
+// Blame returns the commits that last changed lines start to end (1-based,
+// inclusive) of path, keyed by line number. With content set, the lines are those
+// of content instead of the file on disk, so unsaved text can be annotated too.
+func Blame(ctx context.Context, path string, content []byte, start, end int) (map[int]BlameLine, error) {
+	args := []string{"blame", "--porcelain", "-L", fmt.Sprintf("%d,%d", start, end)}
+	if content != nil {
+		args = append(args, "--contents", "-")
+	}
+	args = append(args, "--", filepath.Base(path))
+
+	out, err := runWithInput(ctx, filepath.Dir(path), content, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseBlame(out)
+}
+
+// parseBlame parses git blame --porcelain output. Commit details are only given
+// the first time a commit appears, later lines refer back to them by hash.
+func parseBlame(out []byte) (map[int]BlameLine, error) {
+	lines := make(map[int]BlameLine)
+	commits := make(map[string]*BlameLine)
+
+	var current *BlameLine
+	var finalLine int
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			// The line's content ends its entry
+			if current != nil {
+				lines[finalLine] = *current
+			}
+		case current != nil && strings.HasPrefix(line, "author "):
+			current.Author = strings.TrimPrefix(line, "author ")
+		case current != nil && strings.HasPrefix(line, "author-time "):
+			if seconds, err := strconv.ParseInt(strings.TrimPrefix(line, "author-time "), 10, 64); err == nil {
+				current.Time = time.Unix(seconds, 0)
+			}
+		case current != nil && strings.HasPrefix(line, "summary "):
+			current.Summary = strings.TrimPrefix(line, "summary ")
+		default:
+			// Entry header: <hash> <original line> <final line> [<lines in group>]
+			fields := strings.Fields(line)
+			if len(fields) < 3 || len(fields[0]) != 40 {
+				continue
+			}
+			n, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("invalid blame header %q", line)
+			}
+			finalLine = n
+			current = commits[fields[0]]
+			if current == nil {
+				current = &BlameLine{Hash: fields[0]}
+				commits[fields[0]] = current
+			}
+		}
+	}
+	return lines, scanner.Err()
+}
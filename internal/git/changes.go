@@ -0,0 +1,117 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LineRange is a range of lines, 1-based and inclusive
+type LineRange struct {
+	Start, End int
+}
+
+// Overlaps reports whether the range overlaps lines start to end
+func (r LineRange) Overlaps(start, end int) bool {
+	return start <= r.End && end >= r.Start
+}
+
+// FileChanges are the lines of a file that differ from a base revision, numbered
+// as in the working tree
+type FileChanges struct {
+	Path      string // Absolute
+	Ranges    []LineRange
+	Untracked bool // The whole file is new and not known to git
+}
+
+// hunkHeader matches the new file side of a unified diff hunk header
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// ChangedLines returns the files under dir whose working tree content differs from
+// revision base, ordered by path, with the lines that changed. Untracked files are
+// included as changed throughout. Files deleted since base have nothing to report
+// and are left out.
+func ChangedLines(ctx context.Context, dir string, base string) ([]FileChanges, error) {
+	if err := checkRevision(base); err != nil {
+		return nil, err
+	}
+	root, err := run(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	top := strings.TrimSpace(string(root))
+
+	out, err := run(ctx, dir, "diff", "--no-color", "--no-ext-diff", "--diff-filter=d", "-U0", base, "--", ".")
+	if err != nil {
+		return nil, err
+	}
+	files, err := parseDiffHunks(out, top)
+	if err != nil {
+		return nil, err
+	}
+
+	untracked, err := run(ctx, dir, "ls-files", "--others", "--exclude-standard", "--full-name", "--", ".")
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range strings.Split(strings.TrimSpace(string(untracked)), "\n") {
+		if path != "" {
+			files = append(files, FileChanges{Path: filepath.Join(top, path), Untracked: true})
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
+
+// parseDiffHunks reads the changed line ranges from git diff -U0 output. A hunk
+// that only deletes lines is recorded as touching the lines either side of it.
+func parseDiffHunks(out []byte, top string) ([]FileChanges, error) {
+	var files []FileChanges
+	var current *FileChanges
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			current = nil
+		case strings.HasPrefix(line, "+++ "):
+			name := strings.TrimPrefix(line, "+++ ")
+			if unquoted, err := strconv.Unquote(name); err == nil {
+				name = unquoted
+			}
+			if !strings.HasPrefix(name, "b/") {
+				continue
+			}
+			files = append(files, FileChanges{Path: filepath.Join(top, strings.TrimPrefix(name, "b/"))})
+			current = &files[len(files)-1]
+		case current != nil && strings.HasPrefix(line, "@@ "):
+			match := hunkHeader.FindStringSubmatch(line)
+			if match == nil {
+				return nil, fmt.Errorf("invalid hunk header %q", line)
+			}
+			start, _ := strconv.Atoi(match[1])
+			count := 1
+			if match[2] != "" {
+				count, _ = strconv.Atoi(match[2])
+			}
+			if count == 0 {
+				// Lines were removed after line start
+				current.Ranges = append(current.Ranges, LineRange{Start: max(start, 1), End: start + 1})
+			} else {
+				current.Ranges = append(current.Ranges, LineRange{Start: start, End: start + count - 1})
+			}
+		}
+	}
+	return files, scanner.Err()
+}
@@ -0,0 +1,61 @@
+// Package git runs git commands for tools that look at the history of workspace
+// files. Commands run in the directory of the file they concern, so files in nested
+// repositories and submodules work.
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotRepository is returned for files outside any git repository
+var ErrNotRepository = errors.New("not in a git repository")
+
+// run runs git in dir and returns its standard output. Failures include git's
+// error message.
+func run(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	return runWithInput(ctx, dir, nil, args...)
+}
+
+// runWithInput is run with input as git's standard input
+func runWithInput(ctx context.Context, dir string, input []byte, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	if input != nil {
+		cmd.Stdin = bytes.NewReader(input)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if strings.Contains(message, "not a git repository") {
+			return nil, ErrNotRepository
+		}
+		if message == "" {
+			message = err.Error()
+		}
+		return nil, fmt.Errorf("git %s: %s", args[0], message)
+	}
+	return out, nil
+}
+
+// checkRevision rejects revisions git would parse as options
+func checkRevision(rev string) error {
+	if rev == "" || strings.HasPrefix(rev, "-") {
+		return fmt.Errorf("invalid revision %q", rev)
+	}
+	return nil
+}
+
+// Show returns the content of path at revision rev
+func Show(ctx context.Context, path string, rev string) ([]byte, error) {
+	if err := checkRevision(rev); err != nil {
+		return nil, err
+	}
+	return run(ctx, filepath.Dir(path), "show", rev+":./"+filepath.Base(path))
+}
@@ -0,0 +1,196 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Commit is a commit that changed a range of lines, with how many of the range's
+// lines it added and removed
+type Commit struct {
+	Hash    string
+	Author  string
+	Email   string
+	Time    time.Time
+	Subject string
+	Added   int
+	Removed int
+}
+
+// commitMarker starts the header line of each commit in LineHistory's log output,
+// written with git's escapes since arguments cannot hold a NUL
+const (
+	commitMarker       = "\x00commit\x1f"
+	commitMarkerFormat = "%x00commit%x1f"
+)
+
+// LineHistory returns the commits that changed lines start to end (1-based,
+// inclusive) of path, newest first, following the range back as the file changed
+// with git log -L. The lines are numbered as in the working tree and mapped to HEAD
+// first; uncommitted reports whether any of them differ from HEAD. Lines that were
+// all added since HEAD have no history.
+func LineHistory(ctx context.Context, path string, start, end int) (commits []Commit, uncommitted bool, err error) {
+	dir, base := filepath.Dir(path), filepath.Base(path)
+	if _, err := run(ctx, dir, "cat-file", "-e", "HEAD:./"+base); err != nil {
+		if errors.Is(err, ErrNotRepository) {
+			return nil, false, err
+		}
+		return nil, true, nil
+	}
+
+	out, err := run(ctx, dir, "diff", "--no-color", "--no-ext-diff", "-U0", "HEAD", "--", base)
+	if err != nil {
+		return nil, false, err
+	}
+	hunks, err := parseHunks(out)
+	if err != nil {
+		return nil, false, err
+	}
+	headStart, headEnd, uncommitted, committed := mapToHead(hunks, start, end)
+	if !committed {
+		return nil, true, nil
+	}
+
+	out, err = run(ctx, dir, "log", "--no-color", "--no-ext-diff",
+		"--format="+commitMarkerFormat+"%H%x1f%an%x1f%ae%x1f%at%x1f%s",
+		"-L", fmt.Sprintf("%d,%d:./%s", headStart, headEnd, base))
+	if err != nil {
+		return nil, uncommitted, err
+	}
+	commits, err = parseLineLog(out)
+	return commits, uncommitted, err
+}
+
+// hunk is the old and new line ranges of a diff hunk. A count of 0 means lines
+// were only added or removed after line start.
+type hunk struct {
+	oldStart, oldCount int
+	newStart, newCount int
+}
+
+// parseHunks reads the hunks of a git diff -U0 of a single file
+func parseHunks(out []byte) ([]hunk, error) {
+	var hunks []hunk
+	for _, line := range strings.Split(string(out), "\n") {
+		if !strings.HasPrefix(line, "@@ ") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("invalid hunk header %q", line)
+		}
+		oldStart, oldCount, err := parseHunkRange(fields[1], "-")
+		if err != nil {
+			return nil, fmt.Errorf("invalid hunk header %q", line)
+		}
+		newStart, newCount, err := parseHunkRange(fields[2], "+")
+		if err != nil {
+			return nil, fmt.Errorf("invalid hunk header %q", line)
+		}
+		hunks = append(hunks, hunk{oldStart, oldCount, newStart, newCount})
+	}
+	return hunks, nil
+}
+
+// parseHunkRange parses "-start,count" or "+start", where the count defaults to 1
+func parseHunkRange(field, sign string) (int, int, error) {
+	field, ok := strings.CutPrefix(field, sign)
+	if !ok {
+		return 0, 0, fmt.Errorf("missing %s", sign)
+	}
+	startText, countText, hasCount := strings.Cut(field, ",")
+	start, err := strconv.Atoi(startText)
+	if err != nil {
+		return 0, 0, err
+	}
+	count := 1
+	if hasCount {
+		if count, err = strconv.Atoi(countText); err != nil {
+			return 0, 0, err
+		}
+	}
+	return start, count, nil
+}
+
+// mapToHead maps working tree lines start to end to the lines of HEAD. Changed
+// lines map to the lines they replaced. committed is false if every line in the
+// range was added since HEAD.
+func mapToHead(hunks []hunk, start, end int) (headStart, headEnd int, uncommitted, committed bool) {
+	changed := 0
+	mapLine := func(n int, last bool) int {
+		mapped := n
+		for _, h := range hunks {
+			// The first lines after the hunk on each side
+			newNext, oldNext := h.newStart+h.newCount, h.oldStart+h.oldCount
+			if h.newCount == 0 {
+				newNext = h.newStart + 1
+			}
+			if h.oldCount == 0 {
+				oldNext = h.oldStart + 1
+			}
+			switch {
+			case n >= newNext:
+				mapped = n - newNext + oldNext
+			case n >= h.newStart && h.newCount > 0:
+				// Inside the changed lines
+				if last {
+					return max(h.oldStart+h.oldCount-1, h.oldStart)
+				}
+				return max(h.oldStart, 1)
+			default:
+				return mapped
+			}
+		}
+		return mapped
+	}
+	for _, h := range hunks {
+		if h.newCount > 0 && h.newStart <= end && h.newStart+h.newCount-1 >= start {
+			uncommitted = true
+			changed += min(end, h.newStart+h.newCount-1) - max(start, h.newStart) + 1
+		} else if h.newCount == 0 && h.newStart >= start && h.newStart < end {
+			// Lines were removed inside the range
+			uncommitted = true
+		}
+	}
+	headStart, headEnd = mapLine(start, false), mapLine(end, true)
+	return headStart, max(headEnd, headStart), uncommitted, changed < end-start+1
+}
+
+// parseLineLog parses git log -L output, counting the lines each commit's patch
+// added to and removed from the range
+func parseLineLog(out []byte) ([]Commit, error) {
+	var commits []Commit
+	var current *Commit
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, commitMarker):
+			fields := strings.SplitN(strings.TrimPrefix(line, commitMarker), "\x1f", 5)
+			if len(fields) != 5 {
+				return nil, fmt.Errorf("invalid log line %q", line)
+			}
+			commit := Commit{Hash: fields[0], Author: fields[1], Email: fields[2], Subject: fields[4]}
+			if seconds, err := strconv.ParseInt(fields[3], 10, 64); err == nil {
+				commit.Time = time.Unix(seconds, 0)
+			}
+			commits = append(commits, commit)
+			current = &commits[len(commits)-1]
+		case current == nil:
+		case strings.HasPrefix(line, "+++ ") || strings.HasPrefix(line, "--- "):
+		case strings.HasPrefix(line, "+"):
+			current.Added++
+		case strings.HasPrefix(line, "-"):
+			current.Removed++
+		}
+	}
+	return commits, scanner.Err()
+}
@@ -0,0 +1,262 @@
+// Package index keeps a lightweight index of workspace symbols on disk, so symbol
+// lookups can be answered right after a restart while the language server is still
+// building its own index. The index is reconciled with the server once it is ready.
+package index
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// formatVersion is bumped when the file format changes, older files are discarded
+const formatVersion = 1
+
+// Symbol is a symbol declared in a file
+type Symbol struct {
+	Name      string              `json:"name"`
+	Kind      protocol.SymbolKind `json:"kind"`
+	Container string              `json:"container,omitempty"`
+	Range     protocol.Range      `json:"range"`
+}
+
+// File is the indexed state of a file. Entries whose file has a different size or
+// modification time on disk are stale and not used.
+type File struct {
+	ModTime time.Time `json:"modTime"`
+	Size    int64     `json:"size"`
+	Symbols []Symbol  `json:"symbols"`
+}
+
+// Index maps files to the symbols they declare, and symbols to their number of
+// references when last counted
+type Index struct {
+	mu         sync.RWMutex
+	path       string
+	files      map[string]*File
+	references map[string]int
+	built      time.Time
+	reconciled bool // Checked against the language server since it was loaded
+	dirty      bool
+}
+
+// persisted is the on-disk format of an index
+type persisted struct {
+	Version    int              `json:"version"`
+	Built      time.Time        `json:"built"`
+	Files      map[string]*File `json:"files"`
+	References map[string]int   `json:"references"`
+}
+
+// DefaultPath returns where the index for a workspace and language server is kept
+// in the user's cache directory
+func DefaultPath(workspaceDir string, lspCommand string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(workspaceDir + "\x00" + lspCommand))
+	name := fmt.Sprintf("%s-%s.json", filepath.Base(workspaceDir), hex.EncodeToString(sum[:8]))
+	return filepath.Join(cacheDir, "mcp-language-server", "index", name), nil
+}
+
+// Load reads the index at path. A missing or outdated file gives an empty index
+// that is saved to path.
+func Load(path string) (*Index, error) {
+	idx := &Index{
+		path:       path,
+		files:      make(map[string]*File),
+		references: make(map[string]int),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read symbol index: %w", err)
+	}
+
+	var p persisted
+	if err := json.Unmarshal(data, &p); err != nil || p.Version != formatVersion {
+		// Rebuilt by the next reconcile
+		return idx, nil
+	}
+	if p.Files != nil {
+		idx.files = p.Files
+	}
+	if p.References != nil {
+		idx.references = p.References
+	}
+	idx.built = p.Built
+	return idx, nil
+}
+
+// Save writes the index if it changed since it was loaded or last saved
+func (idx *Index) Save() error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if !idx.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(persisted{
+		Version:    formatVersion,
+		Built:      idx.built,
+		Files:      idx.files,
+		References: idx.references,
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+	// Write to a temporary file first so a crash never leaves a partial index
+	tmp := idx.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write symbol index: %w", err)
+	}
+	if err := os.Rename(tmp, idx.path); err != nil {
+		return fmt.Errorf("failed to write symbol index: %w", err)
+	}
+	idx.dirty = false
+	return nil
+}
+
+// Lookup returns the indexed symbols named name, or member symbols named by a
+// qualified name like "Foo.Name", as workspace symbol results. Symbols of files that
+// changed since they were indexed are left out.
+func (idx *Index) Lookup(name string) []protocol.WorkspaceSymbolResult {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	paths := make([]string, 0, len(idx.files))
+	for path := range idx.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var results []protocol.WorkspaceSymbolResult
+	for _, path := range paths {
+		file := idx.files[path]
+		var matches []Symbol
+		for _, sym := range file.Symbols {
+			if sym.Name == name || (sym.Container != "" && sym.Container+"."+sym.Name == name) {
+				matches = append(matches, sym)
+			}
+		}
+		if len(matches) == 0 || !file.Current(path) {
+			continue
+		}
+		for _, sym := range matches {
+			info := &protocol.SymbolInformation{
+				Name:     sym.Name,
+				Kind:     sym.Kind,
+				Location: protocol.Location{URI: protocol.DocumentUri("file://" + path), Range: sym.Range},
+			}
+			info.ContainerName = sym.Container
+			results = append(results, info)
+		}
+	}
+	return results
+}
+
+// Current reports whether the file at path is unchanged since it was indexed
+func (f *File) Current(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Size() == f.Size && info.ModTime().Equal(f.ModTime)
+}
+
+// Entry returns the indexed state of a file
+func (idx *Index) Entry(path string) (*File, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	file, ok := idx.files[path]
+	return file, ok
+}
+
+// Paths returns the indexed files
+func (idx *Index) Paths() []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	paths := make([]string, 0, len(idx.files))
+	for path := range idx.files {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// SetFile records the symbols of a file as of its state described by info
+func (idx *Index) SetFile(path string, info os.FileInfo, symbols []Symbol) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.files[path] = &File{ModTime: info.ModTime(), Size: info.Size(), Symbols: symbols}
+	idx.dirty = true
+}
+
+// RemoveFile drops a file from the index
+func (idx *Index) RemoveFile(path string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if _, ok := idx.files[path]; ok {
+		delete(idx.files, path)
+		idx.dirty = true
+	}
+}
+
+// RecordReferences stores the number of references found for a symbol
+func (idx *Index) RecordReferences(name string, count int) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if current, ok := idx.references[name]; !ok || current != count {
+		idx.references[name] = count
+		idx.dirty = true
+	}
+}
+
+// References returns the number of references last found for a symbol
+func (idx *Index) References(name string) (int, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	count, ok := idx.references[name]
+	return count, ok
+}
+
+// MarkReconciled records that the index was checked against the language server
+func (idx *Index) MarkReconciled() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.reconciled = true
+	idx.built = time.Now()
+	idx.dirty = true
+}
+
+// Stats describes an index for health reports
+type Stats struct {
+	Path       string
+	Files      int
+	Symbols    int
+	Built      time.Time // Zero if never reconciled
+	Reconciled bool
+}
+
+// Stats returns the size and state of the index
+func (idx *Index) Stats() Stats {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	stats := Stats{Path: idx.path, Files: len(idx.files), Built: idx.built, Reconciled: idx.reconciled}
+	for _, file := range idx.files {
+		stats.Symbols += len(file.Symbols)
+	}
+	return stats
+}
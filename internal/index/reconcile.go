@@ -0,0 +1,158 @@
+package index
+
+import (
+	"context"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+)
+
+const (
+	// reconcileSession is the session files are opened under while indexing, so
+	// they are closed again unless a client opened them too
+	reconcileSession = "symbol-index"
+
+	// releaseEvery is how many files are indexed between closing the files opened
+	// for indexing
+	releaseEvery = 50
+
+	// unsupportedAfter is how many files of an extension may fail to give symbols,
+	// with none succeeding, before the rest are skipped as not handled by the server
+	unsupportedAfter = 3
+)
+
+// Reconcile brings the index up to date with the workspace: files that changed
+// since they were indexed are indexed again with the language server's document
+// symbols, and deleted files are dropped. At most maxFiles files are indexed, 0 for
+// no limit. The index is saved afterwards.
+func Reconcile(ctx context.Context, client *lsp.Client, w *watcher.WorkspaceWatcher, idx *Index, maxFiles int) error {
+	ctx = lsp.WithSession(ctx, reconcileSession)
+	defer client.ReleaseSession(context.WithoutCancel(ctx), reconcileSession)
+
+	var stale []string
+	present := make(map[string]bool)
+	err := w.WalkWorkspace(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			if w.IsExcluded(path, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if lsp.DetectLanguageID(path) == "" || w.IsExcluded(path, false) {
+			return nil
+		}
+		present[path] = true
+		if file, ok := idx.Entry(path); !ok || !file.Current(path) {
+			stale = append(stale, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, path := range idx.Paths() {
+		if !present[path] {
+			idx.RemoveFile(path)
+		}
+	}
+
+	failures := make(map[string]int)
+	supported := make(map[string]bool)
+	indexed := 0
+	for _, path := range stale {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if maxFiles > 0 && indexed >= maxFiles {
+			log.Printf("Symbol index: stopped after %d files, %d remain for the next run", indexed, len(stale)-indexed)
+			break
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if !supported[ext] && failures[ext] >= unsupportedAfter {
+			continue
+		}
+
+		// Stat first, a file changing while it is indexed then shows as stale
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		symbols, err := fileSymbols(ctx, client, path)
+		if err != nil || len(symbols) == 0 {
+			failures[ext]++
+			continue
+		}
+		supported[ext] = true
+		idx.SetFile(path, info, symbols)
+
+		indexed++
+		if indexed%releaseEvery == 0 {
+			client.ReleaseSession(ctx, reconcileSession)
+		}
+	}
+
+	idx.MarkReconciled()
+	return idx.Save()
+}
+
+// fileSymbols returns the declarations of a file from the language server, leaving
+// out the locals of functions
+func fileSymbols(ctx context.Context, client *lsp.Client, path string) ([]Symbol, error) {
+	if err := client.OpenFile(ctx, path); err != nil {
+		return nil, err
+	}
+	result, err := client.DocumentSymbol(ctx, protocol.DocumentSymbolParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentUri("file://" + path)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	results, err := result.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	var symbols []Symbol
+	var collect func(results []protocol.DocumentSymbolResult, container string)
+	collect = func(results []protocol.DocumentSymbolResult, container string) {
+		for _, result := range results {
+			switch sym := result.(type) {
+			case *protocol.DocumentSymbol:
+				symbols = append(symbols, Symbol{Name: sym.Name, Kind: sym.Kind, Container: container, Range: sym.SelectionRange})
+				if !hasLocals(sym.Kind) {
+					children := make([]protocol.DocumentSymbolResult, len(sym.Children))
+					for i := range sym.Children {
+						children[i] = &sym.Children[i]
+					}
+					collect(children, sym.Name)
+				}
+			case *protocol.SymbolInformation:
+				symbols = append(symbols, Symbol{Name: sym.Name, Kind: sym.Kind, Container: sym.ContainerName, Range: sym.Location.Range})
+			}
+		}
+	}
+	collect(results, "")
+	return symbols, nil
+}
+
+// hasLocals reports whether the children of symbols of a kind are local to them
+func hasLocals(kind protocol.SymbolKind) bool {
+	switch kind {
+	case protocol.Function, protocol.Method, protocol.Constructor:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,344 @@
+// Package install installs pinned versions of language servers into the user's
+// cache directory, for when the configured server is not on the PATH.
+//
+// What is checked before a server is used:
+//   - Go servers are built from modules whose go.sum hash is pinned in Servers.
+//     The module is downloaded and its hash compared before go install runs, and
+//     the module's own go.sum pins its dependencies.
+//   - Downloaded servers must match the SHA-256 given with --install-sha256.
+//
+// After installing, the checksum of the command is recorded in a manifest next to
+// it. Later starts compare against it to catch a modified or incomplete install;
+// the manifest was written from the install itself, so it is not a check against
+// tampering on its own.
+package install
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// manifestName is the file recording what was installed in a server's directory
+const manifestName = "manifest.json"
+
+// Installer is how a server is installed
+type Installer string
+
+const (
+	// Go installs Packages with go install after checking their Sums
+	Go Installer = "go"
+	// Download fetches URL, gunzipping it if it ends in .gz, and checks its SHA256
+	Download Installer = "download"
+)
+
+// Server is a language server that can be installed
+type Server struct {
+	Command   string
+	Version   string
+	Installer Installer
+	// Go modules with their versions, each a module root with a main package
+	Packages []string
+	// Sums are the go.sum hashes of Packages, as in "h1:..."
+	Sums   map[string]string
+	URL    string
+	SHA256 string
+}
+
+// Servers are the language servers installed by command name, at pinned versions.
+// Every package needs its hash from the checksum database in Sums.
+var Servers = map[string]Server{
+	"gopls": {
+		Command:   "gopls",
+		Version:   "v0.16.2",
+		Installer: Go,
+		Packages:  []string{"golang.org/x/tools/gopls@v0.16.2"},
+		Sums: map[string]string{
+			"golang.org/x/tools/gopls@v0.16.2": "h1:K1z03MlikHfaMTtG01cUeL5FAOTJnITuNe0TWOcg8tM=",
+		},
+	},
+}
+
+// manifest is what was installed, and the checksum of the command
+type manifest struct {
+	Command   string    `json:"command"`
+	Version   string    `json:"version"`
+	Path      string    `json:"path"`
+	SHA256    string    `json:"sha256"`
+	Installed time.Time `json:"installed"`
+}
+
+// Dir returns the directory servers are installed in
+func Dir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "mcp-language-server", "servers"), nil
+}
+
+// DownloadServer describes a server downloaded from url, which must have the given
+// SHA-256
+func DownloadServer(command, url, sum string) (Server, error) {
+	sum = strings.ToLower(strings.TrimPrefix(sum, "sha256:"))
+	if len(sum) != sha256.Size*2 {
+		return Server{}, fmt.Errorf("--install-sha256 must be the 64 hex digit SHA-256 of %s", url)
+	}
+	return Server{
+		Command:   filepath.Base(command),
+		Version:   sum[:12],
+		Installer: Download,
+		URL:       url,
+		SHA256:    sum,
+	}, nil
+}
+
+// Ensure returns the path of the installed server's command, installing it first
+// if it is missing or its checksum does not match the recorded one
+func Ensure(ctx context.Context, server Server) (string, error) {
+	root, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(root, server.Command+"-"+server.Version)
+	if path, err := verify(dir); err == nil {
+		return path, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Reinstalling %s: %v", server.Command, err)
+	}
+
+	log.Printf("Installing %s %s into %s", server.Command, server.Version, dir)
+	start := time.Now()
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return "", err
+	}
+	// Install next to the final directory and rename, so a failed or concurrent
+	// install never leaves a partial one behind
+	tmp, err := os.MkdirTemp(root, server.Command+"-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	rel, err := install(ctx, server, tmp)
+	if err != nil {
+		return "", fmt.Errorf("failed to install %s: %w", server.Command, err)
+	}
+	sum, err := fileSHA256(filepath.Join(tmp, rel))
+	if err != nil {
+		return "", fmt.Errorf("failed to install %s: %w", server.Command, err)
+	}
+	m := manifest{Command: server.Command, Version: server.Version, Path: rel, SHA256: sum, Installed: time.Now()}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(tmp, manifestName), data, 0o644); err != nil {
+		return "", err
+	}
+
+	// A broken install is moved aside rather than deleted, another process may
+	// still be running its command
+	if _, err := os.Stat(dir); err == nil {
+		aside := fmt.Sprintf("%s.replaced-%d", dir, time.Now().UnixNano())
+		if err := os.Rename(dir, aside); err != nil {
+			return "", fmt.Errorf("failed to replace %s: %w", dir, err)
+		}
+		log.Printf("Moved the previous install of %s to %s, remove it once nothing runs it", server.Command, aside)
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		// Another process may have installed it first
+		if path, verifyErr := verify(dir); verifyErr == nil {
+			return path, nil
+		}
+		return "", fmt.Errorf("failed to install %s: %w", server.Command, err)
+	}
+	log.Printf("Installed %s %s in %s", server.Command, server.Version, time.Since(start).Round(time.Second))
+	return filepath.Join(dir, rel), nil
+}
+
+// verify returns the command installed in dir if its checksum matches the manifest
+func verify(dir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if err != nil {
+		return "", err
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return "", fmt.Errorf("invalid manifest: %w", err)
+	}
+	path := filepath.Join(dir, m.Path)
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return "", err
+	}
+	if sum != m.SHA256 {
+		return "", fmt.Errorf("checksum mismatch for %s", path)
+	}
+	return path, nil
+}
+
+// install installs server into dir and returns the command's path relative to it
+func install(ctx context.Context, server Server, dir string) (string, error) {
+	command := server.Command
+	if runtime.GOOS == "windows" {
+		command += ".exe"
+	}
+
+	switch server.Installer {
+	case Go:
+		for _, pkg := range server.Packages {
+			if err := checkGoModule(ctx, dir, pkg, server.Sums[pkg]); err != nil {
+				return "", err
+			}
+		}
+		bin := filepath.Join(dir, "bin")
+		args := append([]string{"install"}, server.Packages...)
+		if err := run(ctx, dir, []string{"GOBIN=" + bin}, "go", args...); err != nil {
+			return "", err
+		}
+		return filepath.Join("bin", command), nil
+	case Download:
+		path := filepath.Join("bin", command)
+		if err := download(ctx, server, filepath.Join(dir, path)); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+	return "", fmt.Errorf("unknown installer %q", server.Installer)
+}
+
+// checkGoModule downloads a module into the module cache, which go install then
+// builds from, and compares its hash with the pinned one
+func checkGoModule(ctx context.Context, dir, module, sum string) error {
+	if sum == "" {
+		return fmt.Errorf("no checksum is pinned for %s", module)
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		return fmt.Errorf("go is needed to install the server: %w", err)
+	}
+	cmd := exec.CommandContext(ctx, "go", "mod", "download", "-json", module)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	var downloaded struct {
+		Sum   string
+		Error string
+	}
+	if jsonErr := json.Unmarshal(output, &downloaded); jsonErr != nil && err == nil {
+		err = jsonErr
+	}
+	if downloaded.Error != "" {
+		err = errors.New(downloaded.Error)
+	}
+	if err != nil {
+		return fmt.Errorf("go mod download %s: %w", module, err)
+	}
+	if downloaded.Sum != sum {
+		return fmt.Errorf("checksum mismatch for %s: got %s, expected %s", module, downloaded.Sum, sum)
+	}
+	return nil
+}
+
+func run(ctx context.Context, dir string, env []string, name string, args ...string) error {
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("%s is needed to install the server: %w", name, err)
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s %s: %w\n%s", name, strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// download fetches the server's URL to path after checking its checksum
+func download(ctx context.Context, server Server, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("downloading %s: %s", server.URL, resp.Status)
+	}
+
+	archive := path + ".download"
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(archive)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(archive)
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, hash), resp.Body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("downloading %s: %w", server.URL, err)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != server.SHA256 {
+		return fmt.Errorf("checksum mismatch for %s: got %s, expected %s", server.URL, sum, server.SHA256)
+	}
+
+	if !strings.HasSuffix(server.URL, ".gz") {
+		if err := os.Rename(archive, path); err != nil {
+			return err
+		}
+		return os.Chmod(path, 0o755)
+	}
+	in, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return fmt.Errorf("decompressing %s: %w", server.URL, err)
+	}
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o755)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, gz)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("decompressing %s: %w", server.URL, err)
+	}
+	return os.Chmod(path, 0o755)
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,78 @@
+package lsp
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// compilationDatabaseDirs are searched for compile_commands.json, relative to the
+// workspace and in order, before any other top-level directory
+var compilationDatabaseDirs = []string{".", "build", "out", "builddir", "cmake-build-debug", "cmake-build-release"}
+
+// FindCompilationDatabase returns the directory of the workspace's
+// compile_commands.json: the workspace itself, a usual build directory, or else
+// the top-level directory with the most recently written one. It returns "" when
+// there is none.
+func FindCompilationDatabase(workspaceDir string) string {
+	for _, dir := range compilationDatabaseDirs {
+		path := filepath.Join(workspaceDir, dir)
+		if _, err := os.Stat(filepath.Join(path, "compile_commands.json")); err == nil {
+			return path
+		}
+	}
+
+	entries, err := os.ReadDir(workspaceDir)
+	if err != nil {
+		return ""
+	}
+	var newest string
+	var newestInfo os.FileInfo
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(workspaceDir, entry.Name())
+		info, err := os.Stat(filepath.Join(path, "compile_commands.json"))
+		if err != nil {
+			continue
+		}
+		if newestInfo == nil || info.ModTime().After(newestInfo.ModTime()) {
+			newest, newestInfo = path, info
+		}
+	}
+	return newest
+}
+
+// clangdInitializationOptions points clangd at the workspace's compilation
+// database, which it otherwise only looks for next to the sources and their
+// parents, missing the usual out-of-tree build directories. A
+// --compile-commands-dir argument given to clangd takes precedence.
+func clangdInitializationOptions(workspaceDir string, args []string) map[string]interface{} {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--compile-commands-dir") || strings.HasPrefix(arg, "-compile-commands-dir") {
+			return nil
+		}
+	}
+
+	dir := FindCompilationDatabase(workspaceDir)
+	if dir == "" {
+		log.Printf("No compile_commands.json found in %s, clangd will guess compile flags. Generate one with e.g. cmake -DCMAKE_EXPORT_COMPILE_COMMANDS=ON or bear.", workspaceDir)
+		return nil
+	}
+	log.Printf("Using the compilation database in %s", dir)
+	return map[string]interface{}{"compilationDatabasePath": dir}
+}
+
+// SwitchSourceHeader returns the header of a C or C++ source file or the source
+// file of a header, "" if clangd knows of none. It uses clangd's
+// textDocument/switchSourceHeader extension.
+func (c *Client) SwitchSourceHeader(ctx context.Context, uri protocol.DocumentUri) (protocol.DocumentUri, error) {
+	var result protocol.DocumentUri
+	err := c.Call(ctx, "textDocument/switchSourceHeader", protocol.TextDocumentIdentifier{URI: uri}, &result)
+	return result, err
+}
@@ -2,6 +2,7 @@ package lsp
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -14,6 +15,8 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/isaacphi/mcp-language-server/internal/memory"
+	"github.com/isaacphi/mcp-language-server/internal/metrics"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 )
 
@@ -23,13 +26,28 @@ type Client struct {
 	stdout *bufio.Reader
 	stderr io.ReadCloser
 
+	// Serializes writes so concurrent messages are not interleaved
+	writeMu sync.Mutex
+
 	// Request ID counter
 	nextID atomic.Int32
 
-	// Response handlers
+	// Response handlers, keyed by request ID
 	handlers   map[int32]chan *Message
 	handlersMu sync.RWMutex
 
+	// Semaphore bounding the number of outstanding requests
+	inFlight chan struct{}
+
+	// Write and request durations, see Load
+	load loadTracker
+
+	// Closed once the server's output ends
+	done chan struct{}
+
+	// Notifications waiting to be handled, in the order they were received
+	notifications *notificationQueue
+
 	// Server request handlers
 	serverRequestHandlers map[string]ServerRequestHandler
 	serverHandlersMu      sync.RWMutex
@@ -38,19 +56,89 @@ type Client struct {
 	notificationHandlers map[string]NotificationHandler
 	notificationMu       sync.RWMutex
 
-	// Diagnostic cache
-	diagnostics   map[protocol.DocumentUri][]protocol.Diagnostic
-	diagnosticsMu sync.RWMutex
+	// Diagnostic cache, with the memory.Tick each document's diagnostics were last
+	// stored or read at and the document version they apply to. Diagnostics for an
+	// older version than the open document's are stale and never returned.
+	diagnostics         map[protocol.DocumentUri][]protocol.Diagnostic
+	diagnosticsUsed     map[protocol.DocumentUri]uint64
+	diagnosticsVersions map[protocol.DocumentUri]int32
+	diagnosticsMu       sync.RWMutex
+
+	// Diagnostics are requested with textDocument/diagnostic instead of waiting for
+	// publishDiagnostics, see PullDiagnostics
+	pullDiagnostics bool
+	// Result ID of the last pulled report per document, and a counter so that only
+	// the response to the latest request is stored
+	resultIDs      map[protocol.DocumentUri]string
+	pullGeneration map[protocol.DocumentUri]uint64
+
+	// Channels waiting for the next diagnostics of a document, see NextDiagnostics
+	diagnosticsWaiters map[protocol.DocumentUri][]chan []protocol.Diagnostic
+
+	// Handlers notified when diagnostics are published
+	diagnosticsHandlers   []DiagnosticsHandler
+	diagnosticsHandlersMu sync.RWMutex
 
 	// Files are currently opened by the LSP
 	openFiles   map[string]*OpenFileInfo
 	openFilesMu sync.RWMutex
+
+	// Maximum number of open documents, least recently used ones are closed
+	// beyond it. Zero means no limit.
+	maxOpenFiles int
+
+	// Notebooks open in the server by the notebook file's URI, see OpenNotebook
+	notebooks   map[string]*openNotebook
+	notebooksMu sync.Mutex
+	// The server syncs notebook documents, set from its initialize result
+	notebookSync bool
+	// The server hovers over ranges, rust-analyzer's hoverRange extension
+	hoverRange bool
+
+	// How the server wants didChange content, set from its initialize result
+	syncKind protocol.TextDocumentSyncKind
+
+	// Decodes semantic tokens, set from the initialize result
+	tokensLegend *protocol.SemanticTokensLegend
+
+	// Serializes didOpen, didChange and didClose so they reach the server in the
+	// same order as the openFiles bookkeeping, openFilesMu is only held briefly
+	docSyncMu sync.Mutex
+
+	// Refuse edits requested by the server
+	readOnly bool
+
+	// Reported by the health and about tools
+	startTime         time.Time
+	initializeLatency time.Duration
+	lastError         serverError
+	serverInfo        *protocol.ServerInfo
+
+	// Work done progress the server reports, see WaitForProgressIdle
+	progress progressTracker
+
+	// Startup and project import status reported by jdtls, see JavaStatus
+	java javaTracker
+
+	// How the server process was started, see ProcessConfig
+	process ProcessConfig
 }
 
+// ClientVersion is sent to the language server in clientInfo
+var ClientVersion = "0.1.0"
+
+// DefaultMaxInFlight is the default number of requests that may be outstanding at once
+const DefaultMaxInFlight = 32
+
 func NewClient(command string, args ...string) (*Client, error) {
+	processConfigMu.RLock()
+	process := processConfig
+	processConfigMu.RUnlock()
+
 	cmd := exec.Command(command, args...)
-	// Copy env
-	cmd.Env = os.Environ()
+	cmd.Dir = process.Dir
+	// Copy env, later variables override earlier ones
+	cmd.Env = append(os.Environ(), process.Env...)
 
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
@@ -73,16 +161,31 @@ func NewClient(command string, args ...string) (*Client, error) {
 		stdout:                bufio.NewReader(stdout),
 		stderr:                stderr,
 		handlers:              make(map[int32]chan *Message),
+		inFlight:              make(chan struct{}, DefaultMaxInFlight),
+		done:                  make(chan struct{}),
+		notifications:         newNotificationQueue(),
 		notificationHandlers:  make(map[string]NotificationHandler),
 		serverRequestHandlers: make(map[string]ServerRequestHandler),
 		diagnostics:           make(map[protocol.DocumentUri][]protocol.Diagnostic),
+		diagnosticsUsed:       make(map[protocol.DocumentUri]uint64),
+		diagnosticsVersions:   make(map[protocol.DocumentUri]int32),
+		diagnosticsWaiters:    make(map[protocol.DocumentUri][]chan []protocol.Diagnostic),
+		resultIDs:             make(map[protocol.DocumentUri]string),
+		pullGeneration:        make(map[protocol.DocumentUri]uint64),
 		openFiles:             make(map[string]*OpenFileInfo),
+		notebooks:             make(map[string]*openNotebook),
+		maxOpenFiles:          DefaultMaxOpenFiles,
+		process:               process,
 	}
 
 	// Start the LSP server process
+	client.startTime = time.Now()
 	if err := cmd.Start(); err != nil {
 		return nil, fmt.Errorf("failed to start LSP server: %w", err)
 	}
+	if process.MaxMemory > 0 {
+		go client.watchMemory()
+	}
 
 	// Handle stderr in a separate goroutine
 	go func() {
@@ -95,12 +198,27 @@ func NewClient(command string, args ...string) (*Client, error) {
 		}
 	}()
 
-	// Start message handling loop
+	// Start message handling loops
 	go client.handleMessages()
+	go client.dispatchNotifications()
 
 	return client, nil
 }
 
+// SetMaxInFlight changes how many requests may be outstanding at once.
+// It must be called before the first request is made.
+func (c *Client) SetMaxInFlight(n int) {
+	if n < 1 {
+		n = 1
+	}
+	c.inFlight = make(chan struct{}, n)
+}
+
+// SetReadOnly makes the client refuse workspace/applyEdit requests from the server
+func (c *Client) SetReadOnly(readOnly bool) {
+	c.readOnly = readOnly
+}
+
 func (c *Client) RegisterNotificationHandler(method string, handler NotificationHandler) {
 	c.notificationMu.Lock()
 	defer c.notificationMu.Unlock()
@@ -128,7 +246,7 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 			ProcessID: int32(os.Getpid()),
 			ClientInfo: &protocol.ClientInfo{
 				Name:    "mcp-language-server",
-				Version: "0.1.0",
+				Version: ClientVersion,
 			},
 			RootPath: workspaceDir,
 			RootURI:  protocol.DocumentUri("file://" + workspaceDir),
@@ -157,16 +275,36 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 					DocumentSymbol: protocol.DocumentSymbolClientCapabilities{
 						HierarchicalDocumentSymbolSupport: true,
 					},
+					CallHierarchy: &protocol.CallHierarchyClientCapabilities{},
 					CodeAction: protocol.CodeActionClientCapabilities{
 						CodeActionLiteralSupport: protocol.ClientCodeActionLiteralOptions{
 							CodeActionKind: protocol.ClientCodeActionKindOptions{
-								ValueSet: []protocol.CodeActionKind{},
+								ValueSet: []protocol.CodeActionKind{
+									protocol.QuickFix,
+									protocol.Refactor,
+									protocol.RefactorExtract,
+									protocol.RefactorInline,
+									protocol.RefactorRewrite,
+									protocol.Source,
+									protocol.SourceOrganizeImports,
+									protocol.SourceFixAll,
+								},
 							},
 						},
+						IsPreferredSupport: true,
+						DisabledSupport:    true,
+						DataSupport:        true,
+						ResolveSupport: &protocol.ClientCodeActionResolveOptions{
+							Properties: []string{"edit"},
+						},
 					},
 					PublishDiagnostics: protocol.PublishDiagnosticsClientCapabilities{
 						VersionSupport: true,
 					},
+					Diagnostic: &protocol.DiagnosticClientCapabilities{
+						DynamicRegistration:    true,
+						RelatedDocumentSupport: true,
+					},
 					SemanticTokens: protocol.SemanticTokensClientCapabilities{
 						Requests: protocol.ClientSemanticTokensRequestOptions{
 							Range: &protocol.Or_ClientSemanticTokensRequestOptions_range{},
@@ -177,36 +315,70 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 						Formats:        []protocol.TokenFormat{},
 					},
 				},
-				Window: protocol.WindowClientCapabilities{},
-			},
-			InitializationOptions: map[string]interface{}{
-				"codelenses": map[string]bool{
-					"generate":           true,
-					"regenerate_cgo":     true,
-					"test":               true,
-					"tidy":               true,
-					"upgrade_dependency": true,
-					"vendor":             true,
-					"vulncheck":          false,
+				NotebookDocument: &protocol.NotebookDocumentClientCapabilities{
+					Synchronization: protocol.NotebookDocumentSyncClientCapabilities{},
+				},
+				Window: protocol.WindowClientCapabilities{
+					WorkDoneProgress: true,
+				},
+				Experimental: map[string]interface{}{
+					"hoverRange": true,
 				},
 			},
+			InitializationOptions: c.initializationOptions(workspaceDir),
 		},
 	}
 
+	// Servers may begin reporting progress as soon as they have the initialize request
+	c.RegisterServerRequestHandler("window/workDoneProgress/create", func(params json.RawMessage) (interface{}, error) {
+		return nil, nil
+	})
+	c.RegisterNotificationHandler("$/progress", c.handleProgress)
+	c.RegisterNotificationHandler("language/status", c.handleJavaStatus)
+	c.RegisterNotificationHandler("language/eventNotification", c.handleJavaEvent)
+	c.RegisterNotificationHandler("language/progressReport", c.handleJavaProgressReport)
+
 	var result protocol.InitializeResult
+	start := time.Now()
 	if err := c.Call(ctx, "initialize", initParams, &result); err != nil {
 		return nil, fmt.Errorf("initialize failed: %w", err)
 	}
+	c.initializeLatency = time.Since(start)
+	c.serverInfo = result.ServerInfo
+
+	c.syncKind = textDocumentSyncKind(result.Capabilities.TextDocumentSync)
+	c.tokensLegend = semanticTokensLegend(result.Capabilities.SemanticTokensProvider)
+	c.notebookSync = result.Capabilities.NotebookDocumentSync != nil
+	c.hoverRange = experimentalCapability(result.Capabilities.Experimental, "hoverRange")
+	if result.Capabilities.DiagnosticProvider != nil && result.Capabilities.DiagnosticProvider.Value != nil {
+		c.setPullDiagnostics()
+	}
 
 	if err := c.Notify(ctx, "initialized", struct{}{}); err != nil {
 		return nil, fmt.Errorf("initialized notification failed: %w", err)
 	}
 
 	// Register handlers
-	c.RegisterServerRequestHandler("workspace/applyEdit", HandleApplyEdit)
-	c.RegisterServerRequestHandler("workspace/configuration", HandleWorkspaceConfiguration)
-	c.RegisterServerRequestHandler("client/registerCapability", HandleRegisterCapability)
-	c.RegisterNotificationHandler("window/showMessage", HandleServerMessage)
+	c.RegisterServerRequestHandler("workspace/applyEdit", func(params json.RawMessage) (interface{}, error) {
+		if c.readOnly {
+			log.Printf("Rejected workspace edit from server in read-only mode")
+			return protocol.ApplyWorkspaceEditResult{Applied: false, FailureReason: "mcp-language-server is in read-only mode"}, nil
+		}
+		return HandleApplyEdit(params)
+	})
+	c.RegisterServerRequestHandler("workspace/configuration", func(params json.RawMessage) (interface{}, error) {
+		return HandleWorkspaceConfiguration(workspaceDir, params)
+	})
+	c.RegisterServerRequestHandler("client/registerCapability", func(params json.RawMessage) (interface{}, error) {
+		c.handleDiagnosticRegistration(params)
+		return HandleRegisterCapability(params)
+	})
+	c.RegisterServerRequestHandler("workspace/diagnostic/refresh", c.handleDiagnosticRefresh)
+	c.RegisterNotificationHandler("window/showMessage", func(params json.RawMessage) {
+		HandleServerMessage(params)
+		c.handleServerErrorMessage(params)
+	})
+	c.RegisterNotificationHandler("window/logMessage", c.handleServerErrorMessage)
 	c.RegisterNotificationHandler("textDocument/publishDiagnostics",
 		func(params json.RawMessage) { HandleDiagnostics(c, params) })
 
@@ -219,11 +391,10 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 	// LSP sepecific Initialization
 	path := strings.ToLower(c.Cmd.Path)
 	switch {
-	case strings.Contains(path, "typescript-language-server"):
-		// err := initializeTypescriptLanguageServer(ctx, c, workspaceDir)
-		// if err != nil {
-		// 	return nil, err
-		// }
+	case strings.Contains(path, "pylsp"):
+		if err := configurePylsp(ctx, c, workspaceDir); err != nil {
+			return nil, fmt.Errorf("failed to configure pylsp: %w", err)
+		}
 	}
 
 	return &result, nil
@@ -278,17 +449,39 @@ func (c *Client) WaitForServerReady(ctx context.Context) error {
 type OpenFileInfo struct {
 	Version int32
 	URI     protocol.DocumentUri
+	// Content is the text last sent to the server for this document
+	Content []byte
+	// Sessions that opened the document, "" is the server itself
+	Sessions map[string]bool
+	// memory.Tick of the last OpenFile call for the document, used for LRU eviction
+	lastUsed uint64
+	// Overlay documents hold text pushed by a client instead of the file on disk,
+	// see SetOverlay
+	Overlay bool
+	// Pinned documents stay open until closed explicitly, see PinFile
+	Pinned bool
+	// Tool calls still working on the document, see HoldDocuments
+	holds map[*documentHold]bool
 }
 
 func (c *Client) OpenFile(ctx context.Context, filepath string) error {
 	uri := fmt.Sprintf("file://%s", filepath)
+	sessionID := SessionFromContext(ctx)
+
+	c.docSyncMu.Lock()
+	defer c.docSyncMu.Unlock()
 
 	c.openFilesMu.Lock()
-	if _, exists := c.openFiles[uri]; exists {
+	if info, exists := c.openFiles[uri]; exists {
+		info.Sessions[sessionID] = true
+		info.lastUsed = memory.Tick()
+		c.holdDocument(ctx, info)
 		c.openFilesMu.Unlock()
+		metrics.Hit("open_documents")
 		return nil // Already open
 	}
 	c.openFilesMu.Unlock()
+	metrics.Miss("open_documents")
 
 	// Skip files that do not exist or cannot be read
 	content, err := os.ReadFile(filepath)
@@ -310,38 +503,75 @@ func (c *Client) OpenFile(ctx context.Context, filepath string) error {
 	}
 
 	c.openFilesMu.Lock()
-	c.openFiles[uri] = &OpenFileInfo{
-		Version: 1,
-		URI:     protocol.DocumentUri(uri),
+	info := &OpenFileInfo{
+		Version:  1,
+		URI:      protocol.DocumentUri(uri),
+		Content:  content,
+		Sessions: map[string]bool{sessionID: true},
+		lastUsed: memory.Tick(),
 	}
+	c.openFiles[uri] = info
+	c.holdDocument(ctx, info)
 	c.openFilesMu.Unlock()
+	c.pullAfterSync(protocol.DocumentUri(uri))
 
 	if debug {
 		log.Printf("Opened file: %s", filepath)
 	}
 
+	c.evictLeastRecentlyUsed(ctx)
+	memory.Default.Changed()
+
 	return nil
 }
 
 func (c *Client) NotifyChange(ctx context.Context, filepath string) error {
-	uri := fmt.Sprintf("file://%s", filepath)
-
+	if c.IsOverlay(filepath) {
+		return nil // The server keeps the overlay's text until it is discarded
+	}
 	content, err := os.ReadFile(filepath)
 	if err != nil {
 		return fmt.Errorf("error reading file: %w", err)
 	}
+	return c.SetContent(ctx, filepath, content)
+}
+
+// SetContent sends content to the server as the new text of an open document without
+// writing it to disk. NotifyChange brings the server back in sync with the file.
+func (c *Client) SetContent(ctx context.Context, filepath string, content []byte) error {
+	c.docSyncMu.Lock()
+	defer c.docSyncMu.Unlock()
+	return c.setContent(ctx, fmt.Sprintf("file://%s", filepath), content)
+}
 
+// setContent sends didChange for an open document, the caller must hold docSyncMu
+func (c *Client) setContent(ctx context.Context, uri string, content []byte) error {
 	c.openFilesMu.Lock()
 	fileInfo, isOpen := c.openFiles[uri]
 	if !isOpen {
 		c.openFilesMu.Unlock()
-		return fmt.Errorf("cannot notify change for unopened file: %s", filepath)
+		return fmt.Errorf("cannot notify change for unopened file: %s", strings.TrimPrefix(uri, "file://"))
+	}
+
+	if bytes.Equal(fileInfo.Content, content) {
+		c.openFilesMu.Unlock()
+		return nil // Nothing changed since the last sync
+	}
+
+	// Servers supporting incremental sync only get the changed range
+	var change protocol.TextDocumentContentChangeEvent
+	if c.syncKind == protocol.Incremental {
+		change.Value = incrementalChange(fileInfo.Content, content)
+	} else {
+		change.Value = protocol.TextDocumentContentChangeWholeDocument{Text: string(content)}
 	}
 
 	// Increment version
 	fileInfo.Version++
+	fileInfo.Content = content
 	version := fileInfo.Version
 	c.openFilesMu.Unlock()
+	memory.Default.Changed()
 
 	params := protocol.DidChangeTextDocumentParams{
 		TextDocument: protocol.VersionedTextDocumentIdentifier{
@@ -350,28 +580,44 @@ func (c *Client) NotifyChange(ctx context.Context, filepath string) error {
 			},
 			Version: version,
 		},
-		ContentChanges: []protocol.TextDocumentContentChangeEvent{
-			{
-				Value: protocol.TextDocumentContentChangeWholeDocument{
-					Text: string(content),
-				},
-			},
-		},
+		ContentChanges: []protocol.TextDocumentContentChangeEvent{change},
 	}
 
-	return c.Notify(ctx, "textDocument/didChange", params)
+	if err := c.Notify(ctx, "textDocument/didChange", params); err != nil {
+		return err
+	}
+	c.pullAfterSync(protocol.DocumentUri(uri))
+	return nil
 }
 
 func (c *Client) CloseFile(ctx context.Context, filepath string) error {
+	return c.closeFile(ctx, filepath, false)
+}
+
+// closeFile sends didClose for an open file. With onlyUnused set, files that
+// some session still uses or that are pinned are left open.
+func (c *Client) closeFile(ctx context.Context, filepath string, onlyUnused bool) error {
 	uri := fmt.Sprintf("file://%s", filepath)
 
-	c.openFilesMu.Lock()
-	if _, exists := c.openFiles[uri]; !exists {
-		c.openFilesMu.Unlock()
+	c.docSyncMu.Lock()
+	defer c.docSyncMu.Unlock()
+
+	c.openFilesMu.RLock()
+	info, exists := c.openFiles[uri]
+	inUse := exists && (len(info.Sessions) > 0 || info.Pinned)
+	c.openFilesMu.RUnlock()
+	if !exists {
 		return nil // Already closed
 	}
-	c.openFilesMu.Unlock()
+	if onlyUnused && inUse {
+		return nil // Reopened by another session in the meantime
+	}
 
+	return c.sendDidClose(ctx, uri)
+}
+
+// sendDidClose closes an open document, the caller must hold docSyncMu
+func (c *Client) sendDidClose(ctx context.Context, uri string) error {
 	params := protocol.DidCloseTextDocumentParams{
 		TextDocument: protocol.TextDocumentIdentifier{
 			URI: protocol.DocumentUri(uri),
@@ -385,10 +631,36 @@ func (c *Client) CloseFile(ctx context.Context, filepath string) error {
 	c.openFilesMu.Lock()
 	delete(c.openFiles, uri)
 	c.openFilesMu.Unlock()
+	c.forgetClosedDocument(protocol.DocumentUri(uri))
 
 	return nil
 }
 
+// documentVersion returns the version of an open document, false if it is not open
+func (c *Client) documentVersion(uri protocol.DocumentUri) (int32, bool) {
+	c.openFilesMu.RLock()
+	defer c.openFilesMu.RUnlock()
+	info, open := c.openFiles[string(uri)]
+	if !open {
+		return c.notebookCellVersion(uri)
+	}
+	return info.Version, true
+}
+
+// FileVersion returns the version of an open file and the text sent to the server
+// with it, false if the file is not open. Versions start again when a file is
+// closed and reopened.
+func (c *Client) FileVersion(filepath string) (int32, []byte, bool) {
+	uri := fmt.Sprintf("file://%s", filepath)
+	c.openFilesMu.RLock()
+	defer c.openFilesMu.RUnlock()
+	info, open := c.openFiles[uri]
+	if !open {
+		return 0, nil, false
+	}
+	return info.Version, info.Content, true
+}
+
 func (c *Client) IsFileOpen(filepath string) bool {
 	uri := fmt.Sprintf("file://%s", filepath)
 	c.openFilesMu.RLock()
@@ -397,6 +669,40 @@ func (c *Client) IsFileOpen(filepath string) bool {
 	return exists
 }
 
+// OpenFiles returns the paths of the documents currently open in the server
+func (c *Client) OpenFiles() []string {
+	c.openFilesMu.RLock()
+	defer c.openFilesMu.RUnlock()
+
+	paths := make([]string, 0, len(c.openFiles))
+	for uri := range c.openFiles {
+		paths = append(paths, strings.TrimPrefix(uri, "file://"))
+	}
+	return paths
+}
+
+// GetFileContent returns the content of a file as the language server sees it.
+// For open files this is the text last sent to the server, otherwise it is read from disk.
+func (c *Client) GetFileContent(filepath string) ([]byte, error) {
+	uri := fmt.Sprintf("file://%s", filepath)
+	c.openFilesMu.RLock()
+	var content []byte
+	if fileInfo, isOpen := c.openFiles[uri]; isOpen {
+		content = fileInfo.Content
+	}
+	c.openFilesMu.RUnlock()
+
+	if content != nil {
+		return content, nil
+	}
+
+	content, err := os.ReadFile(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	return content, nil
+}
+
 // CloseAllFiles closes all currently open files
 func (c *Client) CloseAllFiles(ctx context.Context) {
 	c.openFilesMu.Lock()
@@ -418,14 +724,139 @@ func (c *Client) CloseAllFiles(ctx context.Context) {
 		}
 	}
 
+	c.closeAllNotebooks(ctx)
+
 	if debug {
 		log.Printf("Closed %d files", len(filesToClose))
 	}
 }
 
+// GetFileDiagnostics returns the cached diagnostics of a document, nil when there
+// are none or they were reported for an older version of the open document
 func (c *Client) GetFileDiagnostics(uri protocol.DocumentUri) []protocol.Diagnostic {
+	openVersion, open := c.documentVersion(uri)
+
+	c.diagnosticsMu.Lock()
+	diagnostics, cached := c.diagnostics[uri]
+	if cached && open && c.diagnosticsVersions[uri] < openVersion {
+		diagnostics, cached = nil, false
+	}
+	if cached {
+		c.diagnosticsUsed[uri] = memory.Tick()
+	}
+	c.diagnosticsMu.Unlock()
+
+	if cached {
+		metrics.Hit("diagnostics")
+	} else {
+		metrics.Miss("diagnostics")
+	}
+	return diagnostics
+}
+
+// HasCurrentDiagnostics reports whether diagnostics are cached for the current
+// text of a document
+func (c *Client) HasCurrentDiagnostics(uri protocol.DocumentUri) bool {
+	openVersion, open := c.documentVersion(uri)
+
+	c.diagnosticsMu.RLock()
+	defer c.diagnosticsMu.RUnlock()
+	_, cached := c.diagnostics[uri]
+	return cached && (!open || c.diagnosticsVersions[uri] >= openVersion)
+}
+
+// GetAllDiagnostics returns a copy of the diagnostics cache for every document,
+// leaving out stale diagnostics of open documents
+func (c *Client) GetAllDiagnostics() map[protocol.DocumentUri][]protocol.Diagnostic {
+	c.openFilesMu.RLock()
+	openVersions := make(map[protocol.DocumentUri]int32, len(c.openFiles))
+	for _, info := range c.openFiles {
+		openVersions[info.URI] = info.Version
+	}
+	c.openFilesMu.RUnlock()
+
 	c.diagnosticsMu.RLock()
 	defer c.diagnosticsMu.RUnlock()
 
-	return c.diagnostics[uri]
+	all := make(map[protocol.DocumentUri][]protocol.Diagnostic, len(c.diagnostics))
+	for uri, diagnostics := range c.diagnostics {
+		if openVersion, open := openVersions[uri]; open && c.diagnosticsVersions[uri] < openVersion {
+			continue
+		}
+		all[uri] = diagnostics
+	}
+	return all
+}
+
+// storeDiagnostics caches the diagnostics of a document, whether published or
+// pulled, and passes them to waiters and handlers. version is the document version
+// they apply to, 0 when the server did not say, in which case they are taken to
+// describe the text the server has now. Reports for a version older than the open
+// document's or the cached report's are dropped. It reports whether the
+// diagnostics were stored.
+func (c *Client) storeDiagnostics(uri protocol.DocumentUri, diagnostics []protocol.Diagnostic, version int32) bool {
+	openVersion, open := c.documentVersion(uri)
+	if version == 0 {
+		version = openVersion
+	}
+
+	c.diagnosticsMu.Lock()
+	if (open && version < openVersion) || version < c.diagnosticsVersions[uri] {
+		c.diagnosticsMu.Unlock()
+		if debug {
+			log.Printf("Dropped stale diagnostics for %s: version %d, document is at %d", uri, version, openVersion)
+		}
+		return false
+	}
+	c.diagnostics[uri] = diagnostics
+	c.diagnosticsVersions[uri] = version
+	c.diagnosticsUsed[uri] = memory.Tick()
+	c.diagnosticsMu.Unlock()
+	memory.Default.Changed()
+
+	c.wakeDiagnosticsWaiters(uri, diagnostics)
+	c.notifyDiagnosticsHandlers(uri, diagnostics)
+	return true
+}
+
+// wakeDiagnosticsWaiters passes diagnostics to the channels returned by NextDiagnostics
+func (c *Client) wakeDiagnosticsWaiters(uri protocol.DocumentUri, diagnostics []protocol.Diagnostic) {
+	c.diagnosticsMu.Lock()
+	waiters := c.diagnosticsWaiters[uri]
+	delete(c.diagnosticsWaiters, uri)
+	c.diagnosticsMu.Unlock()
+
+	for _, waiter := range waiters {
+		waiter <- diagnostics
+	}
+}
+
+// NextDiagnostics returns a channel that receives the next diagnostics the server
+// publishes for uri. Call it before the change whose diagnostics are wanted.
+func (c *Client) NextDiagnostics(uri protocol.DocumentUri) <-chan []protocol.Diagnostic {
+	ch := make(chan []protocol.Diagnostic, 1)
+	c.diagnosticsMu.Lock()
+	c.diagnosticsWaiters[uri] = append(c.diagnosticsWaiters[uri], ch)
+	c.diagnosticsMu.Unlock()
+	return ch
+}
+
+// DiagnosticsHandler is called after the server publishes diagnostics for a document
+type DiagnosticsHandler func(uri protocol.DocumentUri, diagnostics []protocol.Diagnostic)
+
+// AddDiagnosticsHandler registers a handler for published diagnostics
+func (c *Client) AddDiagnosticsHandler(handler DiagnosticsHandler) {
+	c.diagnosticsHandlersMu.Lock()
+	defer c.diagnosticsHandlersMu.Unlock()
+	c.diagnosticsHandlers = append(c.diagnosticsHandlers, handler)
+}
+
+func (c *Client) notifyDiagnosticsHandlers(uri protocol.DocumentUri, diagnostics []protocol.Diagnostic) {
+	c.diagnosticsHandlersMu.RLock()
+	handlers := c.diagnosticsHandlers
+	c.diagnosticsHandlersMu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(uri, diagnostics)
+	}
 }
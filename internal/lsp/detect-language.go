@@ -1,14 +1,62 @@
 package lsp
 
 import (
+	"fmt"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 )
 
+var (
+	languageIDs   map[string]protocol.LanguageKind
+	languageIDsMu sync.RWMutex
+)
+
+// SetLanguageIDs sets language ids by file extension (".svelte") that take precedence
+// over the built-in table, for languages it does not know or names servers expect
+// differently. An empty id marks files with the extension as not source code.
+func SetLanguageIDs(ids map[string]protocol.LanguageKind) {
+	languageIDsMu.Lock()
+	defer languageIDsMu.Unlock()
+	languageIDs = ids
+}
+
+// ParseLanguageIDs parses a comma separated list of extension=languageId pairs, e.g.
+// ".svelte=svelte,.templ=templ"
+func ParseLanguageIDs(list string) (map[string]protocol.LanguageKind, error) {
+	ids := make(map[string]protocol.LanguageKind)
+	for _, pair := range strings.Split(list, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		ext, id, found := strings.Cut(pair, "=")
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if !found || ext == "" || ext == "." {
+			return nil, fmt.Errorf("invalid language id mapping %q, expected extension=languageId", pair)
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		ids[ext] = protocol.LanguageKind(strings.TrimSpace(id))
+	}
+	return ids, nil
+}
+
+// DetectLanguageID returns the language id sent in didOpen for a file, from its
+// extension. It is empty for unknown languages.
 func DetectLanguageID(uri string) protocol.LanguageKind {
 	ext := strings.ToLower(filepath.Ext(uri))
+
+	languageIDsMu.RLock()
+	id, configured := languageIDs[ext]
+	languageIDsMu.RUnlock()
+	if configured {
+		return id
+	}
+
 	switch ext {
 	case ".abap":
 		return protocol.LangABAP
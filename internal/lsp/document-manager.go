@@ -0,0 +1,183 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/isaacphi/mcp-language-server/internal/memory"
+)
+
+// DefaultMaxOpenFiles is the default number of documents kept open in the language server
+const DefaultMaxOpenFiles = 500
+
+// SetMaxOpenFiles changes how many documents are kept open at once. When the limit
+// is exceeded the least recently used documents are closed, tools reopen them on
+// demand. Zero disables the limit.
+func (c *Client) SetMaxOpenFiles(n int) {
+	c.openFilesMu.Lock()
+	c.maxOpenFiles = max(n, 0)
+	c.openFilesMu.Unlock()
+
+	c.docSyncMu.Lock()
+	defer c.docSyncMu.Unlock()
+	c.evictLeastRecentlyUsed(context.Background())
+}
+
+// OpenFileLimitReached reports whether opening another document would close one
+func (c *Client) OpenFileLimitReached() bool {
+	c.openFilesMu.RLock()
+	defer c.openFilesMu.RUnlock()
+	return c.maxOpenFiles > 0 && len(c.openFiles) >= c.maxOpenFiles
+}
+
+// evictLeastRecentlyUsed closes documents until at most maxOpenFiles are open.
+// Overlays and pinned documents are never closed. The caller must hold docSyncMu.
+func (c *Client) evictLeastRecentlyUsed(ctx context.Context) {
+	for {
+		c.openFilesMu.RLock()
+		if c.maxOpenFiles == 0 || len(c.openFiles) <= c.maxOpenFiles {
+			c.openFilesMu.RUnlock()
+			return
+		}
+		oldestURI, _ := c.leastRecentlyUsed()
+		c.openFilesMu.RUnlock()
+		if oldestURI == "" {
+			return // Only overlays, pinned and held documents are open
+		}
+
+		if err := c.sendDidClose(ctx, oldestURI); err != nil {
+			log.Printf("Error closing least recently used file %s: %v", oldestURI, err)
+			return
+		}
+		if debug {
+			log.Printf("Closed least recently used file: %s", oldestURI)
+		}
+	}
+}
+
+// leastRecentlyUsed returns the least recently used document that may be closed
+// and its tick, "" if there is none. The caller must hold openFilesMu.
+func (c *Client) leastRecentlyUsed() (string, uint64) {
+	var oldestURI string
+	var oldest uint64
+	for uri, info := range c.openFiles {
+		if info.Overlay {
+			continue // Closing an overlay would lose its text
+		}
+		if info.Pinned || len(info.holds) > 0 {
+			continue
+		}
+		if oldestURI == "" || info.lastUsed < oldest {
+			oldestURI, oldest = uri, info.lastUsed
+		}
+	}
+	return oldestURI, oldest
+}
+
+type documentHoldKey struct{}
+
+// documentHold is the set of documents opened under a HoldDocuments context
+type documentHold struct {
+	mu       sync.Mutex
+	clients  map[*Client]bool
+	released bool
+}
+
+// HoldDocuments returns a context under which opened documents stay open until
+// release is called. A tool call holds its documents, so neither the memory budget
+// nor the open file limit closes one it is still working on.
+func HoldDocuments(ctx context.Context) (context.Context, func()) {
+	hold := &documentHold{clients: make(map[*Client]bool)}
+	return context.WithValue(ctx, documentHoldKey{}, hold), hold.release
+}
+
+// WithDocumentHold returns ctx with the document hold of from, if it has one
+func WithDocumentHold(ctx, from context.Context) context.Context {
+	if hold, ok := from.Value(documentHoldKey{}).(*documentHold); ok {
+		return context.WithValue(ctx, documentHoldKey{}, hold)
+	}
+	return ctx
+}
+
+// holdDocument adds an open document to the hold of ctx. The caller must hold
+// openFilesMu.
+func (c *Client) holdDocument(ctx context.Context, info *OpenFileInfo) {
+	hold, ok := ctx.Value(documentHoldKey{}).(*documentHold)
+	if !ok {
+		return
+	}
+	hold.mu.Lock()
+	defer hold.mu.Unlock()
+	if hold.released {
+		return
+	}
+	if info.holds == nil {
+		info.holds = make(map[*documentHold]bool)
+	}
+	info.holds[hold] = true
+	hold.clients[c] = true
+}
+
+// release lets the documents of the hold be closed again, and closes those that
+// were kept open beyond the limits meanwhile
+func (h *documentHold) release() {
+	h.mu.Lock()
+	h.released = true
+	clients := h.clients
+	h.clients = nil
+	h.mu.Unlock()
+
+	for c := range clients {
+		c.openFilesMu.Lock()
+		for _, info := range c.openFiles {
+			delete(info.holds, h)
+		}
+		c.openFilesMu.Unlock()
+
+		c.docSyncMu.Lock()
+		c.evictLeastRecentlyUsed(context.Background())
+		c.docSyncMu.Unlock()
+	}
+	if len(clients) > 0 {
+		memory.Default.Changed()
+	}
+}
+
+// PinFile opens filepath if needed and keeps it open until CloseFile is called. Pinned
+// documents are not closed to stay under the open file limit or when the sessions
+// that opened them end, so the server keeps their diagnostics up to date. It reports
+// whether the document was already open.
+func (c *Client) PinFile(ctx context.Context, filepath string) (bool, error) {
+	wasOpen := c.IsFileOpen(filepath)
+	if err := c.OpenFile(ctx, filepath); err != nil {
+		return false, err
+	}
+
+	c.openFilesMu.Lock()
+	defer c.openFilesMu.Unlock()
+	info, isOpen := c.openFiles[fmt.Sprintf("file://%s", filepath)]
+	if !isOpen {
+		return false, fmt.Errorf("%s was closed while it was opened", filepath)
+	}
+	info.Pinned = true
+	return wasOpen, nil
+}
+
+// PinnedFiles returns the paths of the pinned documents, sorted
+func (c *Client) PinnedFiles() []string {
+	c.openFilesMu.RLock()
+	defer c.openFilesMu.RUnlock()
+
+	var paths []string
+	for uri, info := range c.openFiles {
+		if info.Pinned {
+			paths = append(paths, strings.TrimPrefix(uri, "file://"))
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
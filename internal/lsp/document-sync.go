@@ -0,0 +1,85 @@
+package lsp
+
+import (
+	"unicode/utf8"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// textDocumentSyncKind reads the change sync kind from the server's textDocumentSync
+// capability, which is either a TextDocumentSyncKind or a TextDocumentSyncOptions object
+func textDocumentSyncKind(capability interface{}) protocol.TextDocumentSyncKind {
+	switch v := capability.(type) {
+	case float64:
+		return protocol.TextDocumentSyncKind(v)
+	case map[string]interface{}:
+		if change, ok := v["change"].(float64); ok {
+			return protocol.TextDocumentSyncKind(change)
+		}
+	}
+	return protocol.None
+}
+
+// incrementalChange describes the edit turning oldText into newText as a single range
+// replacement, covering everything between their common prefix and common suffix
+func incrementalChange(oldText, newText []byte) protocol.TextDocumentContentChangePartial {
+	prefix := 0
+	for prefix < len(oldText) && prefix < len(newText) && oldText[prefix] == newText[prefix] {
+		prefix++
+	}
+	// Do not split a multi-byte character or a \r\n line ending
+	for prefix > 0 && (oldText[prefix-1] == '\r' || splitsRune(oldText, prefix) || splitsRune(newText, prefix)) {
+		prefix--
+	}
+
+	suffix := 0
+	for suffix < len(oldText)-prefix && suffix < len(newText)-prefix &&
+		oldText[len(oldText)-1-suffix] == newText[len(newText)-1-suffix] {
+		suffix++
+	}
+	for suffix > 0 && (oldText[len(oldText)-suffix] == '\n' || splitsRune(oldText, len(oldText)-suffix) || splitsRune(newText, len(newText)-suffix)) {
+		suffix--
+	}
+
+	oldEnd := len(oldText) - suffix
+	changeRange := protocol.Range{
+		Start: positionAt(oldText, prefix),
+		End:   positionAt(oldText, oldEnd),
+	}
+	return protocol.TextDocumentContentChangePartial{
+		Range: &changeRange,
+		Text:  string(newText[prefix : len(newText)-suffix]),
+	}
+}
+
+// splitsRune reports whether offset falls inside a multi-byte character
+func splitsRune(text []byte, offset int) bool {
+	return offset < len(text) && !utf8.RuneStart(text[offset])
+}
+
+// positionAt converts a byte offset into an LSP position, counting characters in
+// UTF-16 code units as the protocol requires by default
+func positionAt(text []byte, offset int) protocol.Position {
+	var pos protocol.Position
+	for i := 0; i < offset; {
+		r, size := utf8.DecodeRune(text[i:])
+		switch {
+		case r == '\n':
+			pos.Line++
+			pos.Character = 0
+		case r == '\r':
+			// \r\n is a single line ending, handled by the \n
+			if i+1 < len(text) && text[i+1] == '\n' {
+				break
+			}
+			pos.Line++
+			pos.Character = 0
+		case r >= 0x10000:
+			pos.Character += 2
+		default:
+			pos.Character++
+		}
+		i += size
+	}
+	return pos
+}
@@ -0,0 +1,98 @@
+package lsp
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// Error implements error, the message ends with the code so that it survives
+// being wrapped with %v, see ResponseErrorFrom
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%s (code: %d)", e.Message, e.Code)
+}
+
+// errorCodeInfo describes a JSON-RPC or LSP error code to the agent that got it
+type errorCodeInfo struct {
+	name        string
+	retryable   bool
+	explanation string
+}
+
+var errorCodes = map[int]errorCodeInfo{
+	int(protocol.ServerNotInitialized): {"ServerNotInitialized", true,
+		"The language server has not finished initializing. Wait a few seconds, or call warmup, and try again."},
+	int(protocol.RequestCancelled): {"RequestCancelled", true,
+		"The request was cancelled before the language server answered, usually because it took too long. Try again, with a narrower query if it keeps happening."},
+	int(protocol.ContentModified): {"ContentModified", true,
+		"The document changed while the language server was computing the result, so it was discarded. Try again once edits have settled."},
+	int(protocol.ServerCancelled): {"ServerCancelled", true,
+		"The language server cancelled the request, typically because it was busy or restarting. Try again."},
+	int(protocol.RequestFailed): {"RequestFailed", false,
+		"The language server understood the request but could not complete it. Check the arguments, e.g. that the position is on a symbol."},
+	int(protocol.MethodNotFound): {"MethodNotFound", false,
+		"The language server does not support this request. Use another tool for the same information."},
+	int(protocol.InvalidParams): {"InvalidParams", false,
+		"The language server rejected the request's parameters. Check the file path, line and column."},
+	int(protocol.InternalError): {"InternalError", false,
+		"The language server failed while handling the request. Retrying rarely helps, health may show the server's last error."},
+	int(protocol.UnknownErrorCode): {"UnknownErrorCode", false,
+		"The language server reported an unspecified error."},
+}
+
+// Name returns the name of the error's code, "" for codes the protocol does not
+// define
+func (e *ResponseError) Name() string {
+	return errorCodes[e.Code].name
+}
+
+// Retryable reports whether sending the same request again may succeed, because
+// the server was starting, the request was cancelled or the document changed
+func (e *ResponseError) Retryable() bool {
+	return errorCodes[e.Code].retryable
+}
+
+// Explanation says what the error means and what to do about it
+func (e *ResponseError) Explanation() string {
+	if info, ok := errorCodes[e.Code]; ok {
+		return info.explanation
+	}
+	return "The language server reported an error specific to it."
+}
+
+// requestFailedPrefix starts the message of the errors Call returns for error
+// responses
+const requestFailedPrefix = "request failed: "
+
+var errorCodePattern = regexp.MustCompile(`^(?s)(.*?) \(code: (-?\d+)\)`)
+
+// ResponseErrorFrom returns the language server error that caused err. Tools wrap
+// errors with %v as often as with %w, so when the chain is broken the error is
+// recovered from the message Call gave it.
+func ResponseErrorFrom(err error) (*ResponseError, bool) {
+	if err == nil {
+		return nil, false
+	}
+	var responseErr *ResponseError
+	if errors.As(err, &responseErr) {
+		return responseErr, true
+	}
+	message := err.Error()
+	i := strings.LastIndex(message, requestFailedPrefix)
+	if i < 0 {
+		return nil, false
+	}
+	match := errorCodePattern.FindStringSubmatch(message[i+len(requestFailedPrefix):])
+	if match == nil {
+		return nil, false
+	}
+	code, convErr := strconv.Atoi(match[2])
+	if convErr != nil {
+		return nil, false
+	}
+	return &ResponseError{Code: code, Message: match[1]}, true
+}
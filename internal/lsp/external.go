@@ -0,0 +1,138 @@
+package lsp
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// IsExternal reports whether uri names a document that is not a file on disk, such
+// as a class inside a jar that a definition led to
+func IsExternal(uri protocol.DocumentUri) bool {
+	return uri != "" && !strings.HasPrefix(string(uri), "file://")
+}
+
+// externalContentMethods are the requests servers answer with the text of the
+// documents behind their own URI schemes
+var externalContentMethods = map[string]string{
+	"jdt":  "java/classFileContents",   // jdtls
+	"kls":  "kotlin/jarClassContents",  // kotlin-language-server
+	"deno": "deno/virtualTextDocument", // deno lsp
+}
+
+// ReadDocument returns the text of the document at uri. Files are read like
+// GetFileContent does, archive entries from the archive and other documents are
+// requested from the server, see ExternalContent.
+func (c *Client) ReadDocument(ctx context.Context, uri protocol.DocumentUri) ([]byte, error) {
+	if !IsExternal(uri) {
+		filepath, err := url.PathUnescape(strings.TrimPrefix(string(uri), "file://"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to unescape URI: %w", err)
+		}
+		return c.GetFileContent(filepath)
+	}
+	return c.ExternalContent(ctx, uri)
+}
+
+// ExternalContent returns the text of a document that is not a file on disk.
+// jar: and zipfile: URIs are read from the archive they point into, notebook cells
+// from their notebook, documents of schemes a server serves itself, like jdtls'
+// jdt: class files, are requested from it.
+func (c *Client) ExternalContent(ctx context.Context, uri protocol.DocumentUri) ([]byte, error) {
+	if archive, entry, ok := archiveEntry(uri); ok {
+		return readArchiveEntry(archive, entry)
+	}
+	if _, _, ok := NotebookCellFromURI(uri); ok {
+		return c.notebookCellContent(uri)
+	}
+
+	scheme, _, _ := strings.Cut(string(uri), ":")
+	method, ok := externalContentMethods[scheme]
+	if !ok {
+		return nil, fmt.Errorf("cannot read %s: documents with the %s: scheme are not supported", uri, scheme)
+	}
+	var params interface{} = protocol.TextDocumentIdentifier{URI: uri}
+	if scheme == "deno" {
+		params = map[string]interface{}{"textDocument": protocol.TextDocumentIdentifier{URI: uri}}
+	}
+	var content *string
+	if err := c.Call(ctx, method, params, &content); err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", uri, err)
+	}
+	if content == nil || *content == "" {
+		return nil, fmt.Errorf("the language server has no source for %s", uri)
+	}
+	return []byte(*content), nil
+}
+
+// archiveEntry splits a jar:file:///lib.jar!/pkg/A.java or a
+// zipfile:///lib.zip::pkg/a.ts URI into the archive's path and the entry's name
+func archiveEntry(uri protocol.DocumentUri) (string, string, bool) {
+	var archive, entry string
+	var found bool
+	switch {
+	case strings.HasPrefix(string(uri), "jar:"):
+		archive, entry, found = strings.Cut(strings.TrimPrefix(string(uri), "jar:"), "!/")
+	case strings.HasPrefix(string(uri), "zipfile:"):
+		archive, entry, found = strings.Cut(strings.TrimPrefix(string(uri), "zipfile:"), "::")
+	}
+	if !found {
+		return "", "", false
+	}
+	archive = strings.TrimPrefix(strings.TrimPrefix(archive, "file:"), "//")
+	archive, err := url.PathUnescape(archive)
+	if err != nil {
+		return "", "", false
+	}
+	entry, err = url.PathUnescape(strings.TrimPrefix(entry, "/"))
+	if err != nil {
+		return "", "", false
+	}
+	return archive, entry, true
+}
+
+func readArchiveEntry(archive, entry string) ([]byte, error) {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, fmt.Errorf("error opening archive: %w", err)
+	}
+	defer r.Close()
+
+	f, err := r.Open(entry)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s from %s: %w", entry, archive, err)
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// ExternalName returns a readable name for a document that is not a file on disk,
+// such as "slf4j-api-2.0.9.jar!/org/slf4j/Logger.java". Names of archive entries
+// keep their extension, so code is still highlighted by language.
+func ExternalName(uri protocol.DocumentUri) string {
+	if archive, entry, ok := archiveEntry(uri); ok {
+		return fmt.Sprintf("%s!/%s", path.Base(archive), entry)
+	}
+	if notebookPath, index, ok := NotebookCellFromURI(uri); ok {
+		return fmt.Sprintf("%s [cell %d]", notebookPath, index+1)
+	}
+	if strings.HasPrefix(string(uri), "jdt://contents/") {
+		// jdt://contents/<jar or module>/<package>/<Class>.class?<project data>
+		rest, _, _ := strings.Cut(strings.TrimPrefix(string(uri), "jdt://contents/"), "?")
+		if rest, err := url.PathUnescape(rest); err == nil {
+			if parts := strings.Split(rest, "/"); len(parts) == 3 {
+				return fmt.Sprintf("%s.%s in %s", parts[1], parts[2], parts[0])
+			}
+		}
+	}
+	if name, err := url.PathUnescape(string(uri)); err == nil {
+		return name
+	}
+	return string(uri)
+}
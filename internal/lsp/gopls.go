@@ -0,0 +1,146 @@
+package lsp
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// bazelWorkspaceFiles mark the root of a Bazel workspace
+var bazelWorkspaceFiles = []string{"MODULE.bazel", "WORKSPACE.bazel", "WORKSPACE"}
+
+// bazelDriverScripts are where Bazel workspaces usually keep rules_go's packages
+// driver wrapper, relative to the workspace root
+var bazelDriverScripts = []string{"tools/gopackagesdriver.sh", "tools/gopackagesdriver"}
+
+var (
+	goplsEnv         map[string]string
+	goPackagesDriver string
+	goplsMu          sync.RWMutex
+)
+
+// ParseGoplsEnv parses a KEY=VALUE environment variable for gopls' go commands
+// and packages driver
+func ParseGoplsEnv(variable string) (string, string, error) {
+	key, value, ok := strings.Cut(variable, "=")
+	if !ok || strings.TrimSpace(key) == "" {
+		return "", "", fmt.Errorf("invalid gopls environment variable %q (expected KEY=VALUE)", variable)
+	}
+	return strings.TrimSpace(key), value, nil
+}
+
+// SetGoplsEnv sets the environment gopls runs go commands and the packages driver
+// with, on top of its own
+func SetGoplsEnv(env map[string]string) {
+	goplsMu.Lock()
+	defer goplsMu.Unlock()
+	goplsEnv = env
+}
+
+// SetGoPackagesDriver sets the program gopls loads packages with instead of go list,
+// such as rules_go's gopackagesdriver for Bazel. Relative paths are resolved against
+// the workspace.
+func SetGoPackagesDriver(driver string) {
+	goplsMu.Lock()
+	defer goplsMu.Unlock()
+	goPackagesDriver = driver
+}
+
+// bazelRoot returns the root of the Bazel workspace dir is in, "" if it is not in one
+func bazelRoot(dir string) string {
+	for {
+		for _, name := range bazelWorkspaceFiles {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				return dir
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// packagesDriver returns the packages driver for the workspace: the one set on the
+// command line, or in a Bazel workspace without GOPACKAGESDRIVER set, rules_go's
+// wrapper script if the workspace has one
+func packagesDriver(workspaceDir, bazelDir string) string {
+	if goPackagesDriver != "" {
+		if filepath.IsAbs(goPackagesDriver) {
+			return goPackagesDriver
+		}
+		return filepath.Join(workspaceDir, goPackagesDriver)
+	}
+	if bazelDir == "" || os.Getenv("GOPACKAGESDRIVER") != "" || goplsEnv["GOPACKAGESDRIVER"] != "" {
+		return ""
+	}
+	for _, script := range bazelDriverScripts {
+		if _, err := os.Stat(filepath.Join(bazelDir, script)); err == nil {
+			return filepath.Join(bazelDir, script)
+		}
+	}
+	return ""
+}
+
+// goplsSettings returns the gopls settings for the environment, packages driver and
+// Bazel output directories, nil when there are none. gopls reads them from
+// initializationOptions and again from the "gopls" section of
+// workspace/configuration.
+func goplsSettings(workspaceDir string) map[string]interface{} {
+	goplsMu.RLock()
+	defer goplsMu.RUnlock()
+
+	env := make(map[string]string, len(goplsEnv)+1)
+	for key, value := range goplsEnv {
+		env[key] = value
+	}
+	bazelDir := bazelRoot(workspaceDir)
+	if driver := packagesDriver(workspaceDir, bazelDir); driver != "" {
+		env["GOPACKAGESDRIVER"] = driver
+	}
+
+	settings := make(map[string]interface{})
+	if len(env) > 0 {
+		settings["env"] = env
+	}
+	if bazelDir == workspaceDir {
+		// gopls would otherwise load the copies of every package in the
+		// convenience symlinks to Bazel's output
+		settings["directoryFilters"] = []string{
+			"-**/node_modules",
+			"-bazel-bin",
+			"-bazel-out",
+			"-bazel-testlogs",
+			"-bazel-" + filepath.Base(workspaceDir),
+		}
+	}
+	if len(settings) == 0 {
+		return nil
+	}
+	return settings
+}
+
+// hasGoModule reports whether go list can load the workspace's packages by itself
+func hasGoModule(workspaceDir string) bool {
+	for _, name := range []string{"go.mod", "go.work"} {
+		if _, err := os.Stat(filepath.Join(workspaceDir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func goplsInitializationOptions(workspaceDir string, args []string) map[string]interface{} {
+	settings := goplsSettings(workspaceDir)
+	env, _ := settings["env"].(map[string]string)
+	if driver := env["GOPACKAGESDRIVER"]; driver != "" {
+		log.Printf("gopls loads packages with %s", driver)
+	} else if bazelRoot(workspaceDir) != "" && os.Getenv("GOPACKAGESDRIVER") == "" && !hasGoModule(workspaceDir) {
+		log.Printf("The workspace is in a Bazel workspace but gopls has no packages driver, set --go-packages-driver to rules_go's gopackagesdriver")
+	}
+	return settings
+}
@@ -0,0 +1,101 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// Health is a snapshot of the connection to the language server
+type Health struct {
+	PID     int
+	Running bool
+	// Exit status once the process has been waited for, empty otherwise
+	ExitState string
+
+	StartTime         time.Time
+	InitializeLatency time.Duration
+
+	OpenDocuments   int
+	InFlight        int
+	DiagnosticFiles int
+	Diagnostics     int
+
+	// Last error response or error message from the server
+	LastError     string
+	LastErrorTime time.Time
+}
+
+// serverError is the last error the server reported
+type serverError struct {
+	message string
+	time    time.Time
+	mu      sync.Mutex
+}
+
+func (e *serverError) record(format string, args ...interface{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.message = fmt.Sprintf(format, args...)
+	e.time = time.Now()
+}
+
+// Health reports the state of the language server process and the client's caches
+func (c *Client) Health() Health {
+	h := Health{
+		StartTime:         c.startTime,
+		InitializeLatency: c.initializeLatency,
+		InFlight:          len(c.inFlight),
+	}
+	if c.Cmd.Process != nil {
+		h.PID = c.Cmd.Process.Pid
+	}
+	select {
+	case <-c.done:
+	default:
+		h.Running = c.Cmd.Process != nil
+	}
+	if c.Cmd.ProcessState != nil {
+		h.Running = false
+		h.ExitState = c.Cmd.ProcessState.String()
+	}
+
+	c.openFilesMu.RLock()
+	h.OpenDocuments = len(c.openFiles)
+	c.openFilesMu.RUnlock()
+
+	c.diagnosticsMu.RLock()
+	h.DiagnosticFiles = len(c.diagnostics)
+	for _, diagnostics := range c.diagnostics {
+		h.Diagnostics += len(diagnostics)
+	}
+	c.diagnosticsMu.RUnlock()
+
+	c.lastError.mu.Lock()
+	h.LastError = c.lastError.message
+	h.LastErrorTime = c.lastError.time
+	c.lastError.mu.Unlock()
+
+	return h
+}
+
+// ServerInfo returns the name and version the server sent in its initialize
+// result, nil if it sent none
+func (c *Client) ServerInfo() *protocol.ServerInfo {
+	return c.serverInfo
+}
+
+// handleServerErrorMessage records window/showMessage and window/logMessage
+// notifications of type Error as the server's last error
+func (c *Client) handleServerErrorMessage(params json.RawMessage) {
+	var msg struct {
+		Type    protocol.MessageType `json:"type"`
+		Message string               `json:"message"`
+	}
+	if err := json.Unmarshal(params, &msg); err == nil && msg.Type == protocol.Error {
+		c.lastError.record("%s", msg.Message)
+	}
+}
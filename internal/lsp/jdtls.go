@@ -0,0 +1,230 @@
+package lsp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// jdtlsProjectsImported is the eventType of language/eventNotification sent once
+// jdtls has imported the workspace's Gradle, Maven or Eclipse projects
+const jdtlsProjectsImported = 200
+
+var jdtlsCleanData bool
+
+// SetJdtlsCleanData makes the next start of jdtls delete its data directory first,
+// for when the index in it is corrupt or out of date
+func SetJdtlsCleanData(clean bool) {
+	jdtlsCleanData = clean
+}
+
+// JdtlsDataDir returns the data directory jdtls keeps a workspace's index and
+// project settings in, unless it is given -data
+func JdtlsDataDir(workspaceDir string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(workspaceDir))
+	name := fmt.Sprintf("%s-%s", filepath.Base(workspaceDir), hex.EncodeToString(sum[:8]))
+	return filepath.Join(cacheDir, "mcp-language-server", "jdtls", name), nil
+}
+
+// jdtlsArgs gives jdtls a data directory of its own per workspace. Without -data
+// every workspace shares one and jdtls mixes up their projects, or refuses to start
+// while another instance holds it.
+func jdtlsArgs(workspaceDir string, args []string) []string {
+	for _, arg := range args {
+		if arg == "-data" || arg == "--data" {
+			return args
+		}
+	}
+	dataDir, err := JdtlsDataDir(workspaceDir)
+	if err != nil {
+		log.Printf("Failed to find a data directory for jdtls: %v", err)
+		return args
+	}
+	if jdtlsCleanData {
+		if err := os.RemoveAll(dataDir); err != nil {
+			log.Printf("Failed to clean the jdtls data directory: %v", err)
+		}
+	}
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		log.Printf("Failed to create the jdtls data directory: %v", err)
+		return args
+	}
+	log.Printf("jdtls data directory: %s", dataDir)
+	return append(append([]string{}, args...), "-data", dataDir)
+}
+
+// JavaStatus is what jdtls has reported about starting and importing the projects
+type JavaStatus struct {
+	// State is the last of Starting, Started and Error
+	State   string
+	Message string
+	// ServiceReady is set once jdtls answers requests for the imported projects
+	ServiceReady bool
+	// ProjectStatus is OK, or WARNING when a project could not be imported fully
+	ProjectStatus string
+	// URIs of the projects imported
+	ImportedProjects []string
+	Updated          time.Time
+}
+
+// javaTracker follows jdtls' language/status, language/eventNotification and
+// language/progressReport notifications
+type javaTracker struct {
+	mu      sync.Mutex
+	status  JavaStatus
+	reports map[string]bool // Progress report IDs seen
+	changed chan struct{}   // Closed and replaced whenever the status changes
+}
+
+// handleJavaStatus records language/status notifications
+func (c *Client) handleJavaStatus(params json.RawMessage) {
+	var msg struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(params, &msg); err != nil {
+		return
+	}
+
+	j := &c.java
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch msg.Type {
+	case "Starting", "Started", "Error":
+		j.status.State = msg.Type
+		j.status.Message = msg.Message
+		if msg.Type == "Error" {
+			c.lastError.record("jdtls: %s", msg.Message)
+		}
+	case "ServiceReady":
+		j.status.ServiceReady = true
+	case "ProjectStatus":
+		j.status.ProjectStatus = msg.Message
+	default:
+		return
+	}
+	j.status.Updated = time.Now()
+	j.notifyLocked()
+}
+
+// handleJavaEvent records the projects imported from language/eventNotification
+func (c *Client) handleJavaEvent(params json.RawMessage) {
+	var msg struct {
+		EventType int             `json:"eventType"`
+		Data      json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(params, &msg); err != nil || msg.EventType != jdtlsProjectsImported {
+		return
+	}
+	var projects []string
+	_ = json.Unmarshal(msg.Data, &projects)
+
+	j := &c.java
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.ImportedProjects = projects
+	j.status.Updated = time.Now()
+	j.notifyLocked()
+}
+
+// handleJavaProgressReport tracks language/progressReport, which older jdtls sends
+// instead of $/progress, as work done progress
+func (c *Client) handleJavaProgressReport(params json.RawMessage) {
+	var msg struct {
+		ID        string `json:"id"`
+		Task      string `json:"task"`
+		SubTask   string `json:"subTask"`
+		Status    string `json:"status"`
+		TotalWork int    `json:"totalWork"`
+		WorkDone  int    `json:"workDone"`
+		Complete  bool   `json:"complete"`
+	}
+	if err := json.Unmarshal(params, &msg); err != nil || msg.ID == "" {
+		return
+	}
+	message := msg.Status
+	if msg.SubTask != "" {
+		message = msg.SubTask
+	}
+	var percentage *uint32
+	if msg.TotalWork > 0 {
+		p := uint32(min(msg.WorkDone*100/msg.TotalWork, 100))
+		percentage = &p
+	}
+
+	j := &c.java
+	j.mu.Lock()
+	if j.reports == nil {
+		j.reports = make(map[string]bool)
+	}
+	seen := j.reports[msg.ID]
+	j.reports[msg.ID] = !msg.Complete
+	j.mu.Unlock()
+
+	token := "jdtls:" + msg.ID
+	switch {
+	case msg.Complete:
+		if seen {
+			c.progress.update(token, "end", "", "", nil)
+		}
+	case !seen:
+		c.progress.update(token, "begin", msg.Task, message, percentage)
+	default:
+		c.progress.update(token, "report", "", message, percentage)
+	}
+}
+
+// notifyLocked wakes the goroutines waiting for the status to change, the caller
+// must hold mu
+func (j *javaTracker) notifyLocked() {
+	if j.changed != nil {
+		close(j.changed)
+		j.changed = nil
+	}
+}
+
+// JavaStatus returns what jdtls has reported, false if it has reported nothing,
+// as other servers do
+func (c *Client) JavaStatus() (JavaStatus, bool) {
+	j := &c.java
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	status := j.status
+	status.ImportedProjects = append([]string(nil), j.status.ImportedProjects...)
+	return status, !status.Updated.IsZero()
+}
+
+// WaitForJavaReady waits until jdtls has imported the projects, or it reported an
+// error. It returns ctx's error if it is still importing when ctx is done.
+func (c *Client) WaitForJavaReady(ctx context.Context) error {
+	j := &c.java
+	for {
+		j.mu.Lock()
+		ready := j.status.ServiceReady || j.status.State == "Started" || j.status.State == "Error"
+		if j.changed == nil {
+			j.changed = make(chan struct{})
+		}
+		changed := j.changed
+		j.mu.Unlock()
+
+		if ready {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package lsp
+
+import (
+	"sync"
+	"time"
+)
+
+// loadWindow is how long an observed request latency counts towards the load, the
+// server may have caught up since
+const loadWindow = 10 * time.Second
+
+// Load describes how busy the language server is, for pacing bulk work such as
+// opening many files
+type Load struct {
+	// Requests waiting for a response
+	InFlight int
+	// Moving average of how long writes to the server's input blocked, which grows
+	// when the server stops reading
+	WriteStall time.Duration
+	// Moving average of request latency over the last loadWindow, zero if no request
+	// completed in that time
+	Latency time.Duration
+}
+
+// loadTracker keeps moving averages of write and request durations
+type loadTracker struct {
+	writeStall   time.Duration
+	latency      time.Duration
+	lastResponse time.Time
+	mu           sync.Mutex
+}
+
+// movingAverage weighs a new sample at one eighth, like TCP's smoothed RTT
+func movingAverage(avg, sample time.Duration) time.Duration {
+	return avg + (sample-avg)/8
+}
+
+func (t *loadTracker) observeWrite(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.writeStall = movingAverage(t.writeStall, d)
+}
+
+func (t *loadTracker) observeResponse(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if time.Since(t.lastResponse) > loadWindow {
+		t.latency = d // Start over, earlier samples are stale
+	} else {
+		t.latency = movingAverage(t.latency, d)
+	}
+	t.lastResponse = time.Now()
+}
+
+// Load reports how busy the server currently is
+func (c *Client) Load() Load {
+	c.load.mu.Lock()
+	defer c.load.mu.Unlock()
+	load := Load{
+		InFlight:   len(c.inFlight),
+		WriteStall: c.load.writeStall,
+	}
+	if time.Since(c.load.lastResponse) <= loadWindow {
+		load.Latency = c.load.latency
+	}
+	return load
+}
@@ -0,0 +1,154 @@
+package lsp
+
+import (
+	"context"
+	"log"
+
+	"github.com/isaacphi/mcp-language-server/internal/memory"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// Approximate sizes of the bookkeeping around cached values, so that many small
+// entries still count against the memory budget
+const (
+	documentOverhead   = 256
+	diagnosticOverhead = 128
+)
+
+// Caches returns the client's open documents and diagnostics as caches for the
+// memory manager. Overlays, pinned documents and documents held by a running tool
+// call are never evicted, nor are the diagnostics of open documents, which the
+// server only publishes again when the document changes.
+func (c *Client) Caches() []memory.Cache {
+	return []memory.Cache{documentCache{c}, diagnosticsCache{c}}
+}
+
+// documentCache evicts open documents by closing them, tools reopen them on demand
+type documentCache struct {
+	c *Client
+}
+
+func (d documentCache) Name() string {
+	return "documents"
+}
+
+func (d documentCache) Usage() (int, int64) {
+	d.c.openFilesMu.RLock()
+	defer d.c.openFilesMu.RUnlock()
+	bytes := int64(0)
+	for _, info := range d.c.openFiles {
+		bytes += documentSize(info)
+	}
+	return len(d.c.openFiles), bytes
+}
+
+func (d documentCache) Oldest() (uint64, bool) {
+	d.c.openFilesMu.RLock()
+	defer d.c.openFilesMu.RUnlock()
+	uri, oldest := d.c.leastRecentlyUsed()
+	return oldest, uri != ""
+}
+
+func (d documentCache) EvictOldest() (int64, bool) {
+	d.c.docSyncMu.Lock()
+	defer d.c.docSyncMu.Unlock()
+
+	d.c.openFilesMu.RLock()
+	uri, _ := d.c.leastRecentlyUsed()
+	var size int64
+	if uri != "" {
+		size = documentSize(d.c.openFiles[uri])
+	}
+	d.c.openFilesMu.RUnlock()
+	if uri == "" {
+		return 0, false
+	}
+
+	if err := d.c.sendDidClose(context.Background(), uri); err != nil {
+		log.Printf("Error closing file %s to free memory: %v", uri, err)
+		return 0, false
+	}
+	if debug {
+		log.Printf("Closed file to stay within the memory budget: %s", uri)
+	}
+	return size, true
+}
+
+func documentSize(info *OpenFileInfo) int64 {
+	return int64(len(info.Content)) + documentOverhead
+}
+
+// diagnosticsCache evicts the cached diagnostics of documents that are not open
+type diagnosticsCache struct {
+	c *Client
+}
+
+func (d diagnosticsCache) Name() string {
+	return "diagnostics"
+}
+
+func (d diagnosticsCache) Usage() (int, int64) {
+	d.c.diagnosticsMu.RLock()
+	defer d.c.diagnosticsMu.RUnlock()
+	bytes := int64(0)
+	for _, diagnostics := range d.c.diagnostics {
+		bytes += diagnosticsSize(diagnostics)
+	}
+	return len(d.c.diagnostics), bytes
+}
+
+func (d diagnosticsCache) Oldest() (uint64, bool) {
+	uri, oldest := d.oldest()
+	return oldest, uri != ""
+}
+
+func (d diagnosticsCache) EvictOldest() (int64, bool) {
+	uri, _ := d.oldest()
+	if uri == "" {
+		return 0, false
+	}
+	d.c.diagnosticsMu.Lock()
+	defer d.c.diagnosticsMu.Unlock()
+	size := diagnosticsSize(d.c.diagnostics[uri])
+	delete(d.c.diagnostics, uri)
+	delete(d.c.diagnosticsUsed, uri)
+	delete(d.c.diagnosticsVersions, uri)
+	delete(d.c.resultIDs, uri)
+	return size, true
+}
+
+// oldest returns the least recently used diagnostics of a document that is not
+// open and their tick, "" if there are none
+func (d diagnosticsCache) oldest() (protocol.DocumentUri, uint64) {
+	d.c.openFilesMu.RLock()
+	open := make(map[protocol.DocumentUri]bool, len(d.c.openFiles))
+	for _, info := range d.c.openFiles {
+		open[info.URI] = true
+	}
+	d.c.openFilesMu.RUnlock()
+
+	d.c.diagnosticsMu.RLock()
+	defer d.c.diagnosticsMu.RUnlock()
+	var oldestURI protocol.DocumentUri
+	var oldest uint64
+	for uri := range d.c.diagnostics {
+		if open[uri] {
+			continue
+		}
+		if used := d.c.diagnosticsUsed[uri]; oldestURI == "" || used < oldest {
+			oldestURI, oldest = uri, used
+		}
+	}
+	return oldestURI, oldest
+}
+
+func diagnosticsSize(diagnostics []protocol.Diagnostic) int64 {
+	bytes := int64(0)
+	for _, diagnostic := range diagnostics {
+		bytes += diagnosticOverhead + int64(len(diagnostic.Message)+len(diagnostic.Source))
+		for _, related := range diagnostic.RelatedInformation {
+			bytes += diagnosticOverhead + int64(len(related.Message)+len(related.Location.URI))
+		}
+	}
+	return bytes
+}
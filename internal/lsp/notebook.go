@@ -0,0 +1,408 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// notebookCellScheme is the URI scheme of notebook cells. It is the one VS Code
+// uses, which servers with notebook support are written against.
+const notebookCellScheme = "vscode-notebook-cell"
+
+// jupyterNotebookType is the notebookType of Jupyter notebooks in notebook selectors
+const jupyterNotebookType = "jupyter-notebook"
+
+// NotebookCell is a cell of a notebook as it was sent to the server
+type NotebookCell struct {
+	URI      protocol.DocumentUri
+	Kind     protocol.NotebookCellKind
+	Language string
+	Text     string
+	// Version of the cell's text document
+	version int32
+}
+
+// openNotebook is a notebook open in the server
+type openNotebook struct {
+	Version int32
+	Cells   []NotebookCell
+	// Content of the notebook file the cells were read from
+	Content  []byte
+	Sessions map[string]bool
+}
+
+// IsNotebook reports whether path is a Jupyter notebook
+func IsNotebook(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".ipynb")
+}
+
+// NotebookCellURI returns the URI of the cell at index in a notebook
+func NotebookCellURI(notebookPath string, index int) protocol.DocumentUri {
+	return protocol.DocumentUri(fmt.Sprintf("%s:%s#C%d", notebookCellScheme, notebookPath, index))
+}
+
+// NotebookCellFromURI returns the notebook and the index of the cell a cell URI
+// names, false for other URIs
+func NotebookCellFromURI(uri protocol.DocumentUri) (string, int, bool) {
+	rest, ok := strings.CutPrefix(string(uri), notebookCellScheme+":")
+	if !ok {
+		return "", 0, false
+	}
+	notebookPath, fragment, ok := strings.Cut(rest, "#C")
+	if !ok {
+		return "", 0, false
+	}
+	index, err := strconv.Atoi(fragment)
+	if err != nil || index < 0 {
+		return "", 0, false
+	}
+	return notebookPath, index, true
+}
+
+// parseNotebook reads the cells of a Jupyter notebook. Code cells are in the
+// kernel's language, Python when the notebook does not say.
+func parseNotebook(notebookPath string, content []byte) ([]NotebookCell, error) {
+	var nb struct {
+		Cells []struct {
+			CellType string          `json:"cell_type"`
+			Source   json.RawMessage `json:"source"`
+		} `json:"cells"`
+		Metadata struct {
+			LanguageInfo struct {
+				Name string `json:"name"`
+			} `json:"language_info"`
+			Kernelspec struct {
+				Language string `json:"language"`
+			} `json:"kernelspec"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(content, &nb); err != nil {
+		return nil, fmt.Errorf("invalid notebook %s: %w", notebookPath, err)
+	}
+
+	language := strings.ToLower(nb.Metadata.LanguageInfo.Name)
+	if language == "" {
+		language = strings.ToLower(nb.Metadata.Kernelspec.Language)
+	}
+	if language == "" {
+		language = "python"
+	}
+
+	cells := make([]NotebookCell, len(nb.Cells))
+	for i, cell := range nb.Cells {
+		// nbformat stores the source as a string or as a list of lines
+		var text string
+		if err := json.Unmarshal(cell.Source, &text); err != nil {
+			var lines []string
+			if err := json.Unmarshal(cell.Source, &lines); err != nil && len(cell.Source) > 0 {
+				return nil, fmt.Errorf("invalid source of cell %d in %s: %w", i+1, notebookPath, err)
+			}
+			text = strings.Join(lines, "")
+		}
+
+		cells[i] = NotebookCell{URI: NotebookCellURI(notebookPath, i), Kind: protocol.Code, Language: language, Text: text}
+		switch cell.CellType {
+		case "markdown":
+			cells[i].Kind, cells[i].Language = protocol.Markup, "markdown"
+		case "raw":
+			cells[i].Kind, cells[i].Language = protocol.Markup, "plaintext"
+		}
+	}
+	return cells, nil
+}
+
+// SupportsNotebooks reports whether the server syncs notebook documents
+func (c *Client) SupportsNotebooks() bool {
+	return c.notebookSync
+}
+
+// OpenNotebook opens a Jupyter notebook in the server cell by cell, or brings an
+// open one up to date with the file, and returns its cells
+func (c *Client) OpenNotebook(ctx context.Context, notebookPath string) ([]NotebookCell, error) {
+	if !c.notebookSync {
+		return nil, fmt.Errorf("the language server does not support notebook documents")
+	}
+	return c.syncNotebook(ctx, notebookPath, SessionFromContext(ctx), true)
+}
+
+// SyncNotebook sends the changes to a notebook file to the server if it is open
+func (c *Client) SyncNotebook(ctx context.Context, notebookPath string) error {
+	_, err := c.syncNotebook(ctx, notebookPath, "", false)
+	return err
+}
+
+// IsNotebookOpen reports whether a notebook is open in the server
+func (c *Client) IsNotebookOpen(notebookPath string) bool {
+	c.notebooksMu.Lock()
+	defer c.notebooksMu.Unlock()
+	_, open := c.notebooks["file://"+notebookPath]
+	return open
+}
+
+func (c *Client) syncNotebook(ctx context.Context, notebookPath string, sessionID string, open bool) ([]NotebookCell, error) {
+	uri := "file://" + notebookPath
+
+	c.docSyncMu.Lock()
+	defer c.docSyncMu.Unlock()
+
+	c.notebooksMu.Lock()
+	nb, isOpen := c.notebooks[uri]
+	c.notebooksMu.Unlock()
+	if !isOpen && !open {
+		return nil, nil
+	}
+
+	content, err := os.ReadFile(notebookPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading notebook: %w", err)
+	}
+	if isOpen && bytes.Equal(nb.Content, content) {
+		if open {
+			c.notebooksMu.Lock()
+			nb.Sessions[sessionID] = true
+			c.notebooksMu.Unlock()
+		}
+		return nb.Cells, nil
+	}
+	cells, err := parseNotebook(notebookPath, content)
+	if err != nil {
+		return nil, err
+	}
+
+	version := int32(1)
+	if isOpen {
+		version = nb.Version + 1
+	}
+	if !isOpen {
+		err = c.sendNotebookOpen(ctx, uri, version, cells)
+	} else if sameCellLayout(nb.Cells, cells) {
+		err = c.sendNotebookTextChanges(ctx, uri, version, nb.Cells, cells)
+	} else {
+		// Cell URIs are by index, so inserting or removing a cell renames the cells
+		// after it. The notebook is reopened rather than described as such a change.
+		if err = c.sendNotebookClose(ctx, uri, nb.Cells); err == nil {
+			err = c.sendNotebookOpen(ctx, uri, version, cells)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.notebooksMu.Lock()
+	if !isOpen {
+		nb = &openNotebook{Sessions: map[string]bool{}}
+		c.notebooks[uri] = nb
+	}
+	nb.Version = version
+	nb.Cells = cells
+	nb.Content = content
+	if open {
+		nb.Sessions[sessionID] = true
+	}
+	c.notebooksMu.Unlock()
+
+	if debug {
+		log.Printf("Synced notebook %s (%d cells)", notebookPath, len(cells))
+	}
+	return cells, nil
+}
+
+// sameCellLayout reports whether two versions of a notebook have the same cells,
+// only their text may differ
+func sameCellLayout(old, new []NotebookCell) bool {
+	if len(old) != len(new) {
+		return false
+	}
+	for i := range old {
+		if old[i].Kind != new[i].Kind || old[i].Language != new[i].Language {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Client) sendNotebookOpen(ctx context.Context, uri string, version int32, cells []NotebookCell) error {
+	params := protocol.DidOpenNotebookDocumentParams{
+		NotebookDocument: protocol.NotebookDocument{
+			URI:          protocol.URI(uri),
+			NotebookType: jupyterNotebookType,
+			Version:      version,
+			Cells:        make([]protocol.NotebookCell, len(cells)),
+		},
+		CellTextDocuments: make([]protocol.TextDocumentItem, len(cells)),
+	}
+	for i, cell := range cells {
+		cells[i].version = version
+		params.NotebookDocument.Cells[i] = protocol.NotebookCell{Kind: cell.Kind, Document: cell.URI}
+		params.CellTextDocuments[i] = protocol.TextDocumentItem{
+			URI:        cell.URI,
+			LanguageID: protocol.LanguageKind(cell.Language),
+			Version:    version,
+			Text:       cell.Text,
+		}
+	}
+	return c.Notify(ctx, "notebookDocument/didOpen", params)
+}
+
+// sendNotebookTextChanges sends the text of the cells that changed, which take the
+// notebook's new version. The others keep theirs.
+func (c *Client) sendNotebookTextChanges(ctx context.Context, uri string, version int32, old, new []NotebookCell) error {
+	var changes []protocol.NotebookDocumentCellContentChanges
+	for i := range new {
+		if old[i].Text == new[i].Text {
+			new[i].version = old[i].version
+			continue
+		}
+		new[i].version = version
+		var change protocol.TextDocumentContentChangeEvent
+		if c.syncKind == protocol.Incremental {
+			change.Value = incrementalChange([]byte(old[i].Text), []byte(new[i].Text))
+		} else {
+			change.Value = protocol.TextDocumentContentChangeWholeDocument{Text: new[i].Text}
+		}
+		changes = append(changes, protocol.NotebookDocumentCellContentChanges{
+			Document: protocol.VersionedTextDocumentIdentifier{
+				TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: new[i].URI},
+				Version:                version,
+			},
+			Changes: []protocol.TextDocumentContentChangeEvent{change},
+		})
+	}
+
+	params := protocol.DidChangeNotebookDocumentParams{
+		NotebookDocument: protocol.VersionedNotebookDocumentIdentifier{URI: protocol.URI(uri), Version: version},
+		Change: protocol.NotebookDocumentChangeEvent{
+			Cells: &protocol.NotebookDocumentCellChanges{TextContent: changes},
+		},
+	}
+	return c.Notify(ctx, "notebookDocument/didChange", params)
+}
+
+func (c *Client) sendNotebookClose(ctx context.Context, uri string, cells []NotebookCell) error {
+	params := protocol.DidCloseNotebookDocumentParams{
+		NotebookDocument:  protocol.NotebookDocumentIdentifier{URI: protocol.URI(uri)},
+		CellTextDocuments: make([]protocol.TextDocumentIdentifier, len(cells)),
+	}
+	for i, cell := range cells {
+		params.CellTextDocuments[i] = protocol.TextDocumentIdentifier{URI: cell.URI}
+	}
+	if err := c.Notify(ctx, "notebookDocument/didClose", params); err != nil {
+		return err
+	}
+	for _, cell := range cells {
+		c.forgetClosedDocument(cell.URI)
+	}
+	return nil
+}
+
+// CloseNotebook closes a notebook and its cells
+func (c *Client) CloseNotebook(ctx context.Context, notebookPath string) error {
+	uri := "file://" + notebookPath
+
+	c.docSyncMu.Lock()
+	defer c.docSyncMu.Unlock()
+
+	c.notebooksMu.Lock()
+	nb, open := c.notebooks[uri]
+	delete(c.notebooks, uri)
+	c.notebooksMu.Unlock()
+	if !open {
+		return nil
+	}
+	return c.sendNotebookClose(ctx, uri, nb.Cells)
+}
+
+// releaseNotebooks drops a session's claim on the notebooks it opened and closes
+// the ones no other session uses, see ReleaseSession
+func (c *Client) releaseNotebooks(ctx context.Context, sessionID string) {
+	var toClose []string
+	c.notebooksMu.Lock()
+	for uri, nb := range c.notebooks {
+		if !nb.Sessions[sessionID] {
+			continue
+		}
+		delete(nb.Sessions, sessionID)
+		if len(nb.Sessions) == 0 {
+			toClose = append(toClose, strings.TrimPrefix(uri, "file://"))
+		}
+	}
+	c.notebooksMu.Unlock()
+
+	for _, notebookPath := range toClose {
+		if err := c.CloseNotebook(ctx, notebookPath); err != nil {
+			log.Printf("Error closing notebook %s: %v", notebookPath, err)
+		}
+	}
+}
+
+// closeAllNotebooks closes every open notebook, see CloseAllFiles
+func (c *Client) closeAllNotebooks(ctx context.Context) {
+	c.notebooksMu.Lock()
+	paths := make([]string, 0, len(c.notebooks))
+	for uri := range c.notebooks {
+		paths = append(paths, strings.TrimPrefix(uri, "file://"))
+	}
+	c.notebooksMu.Unlock()
+
+	for _, notebookPath := range paths {
+		if err := c.CloseNotebook(ctx, notebookPath); err != nil && debug {
+			log.Printf("Error closing notebook %s: %v", notebookPath, err)
+		}
+	}
+}
+
+// notebookCellVersion returns the version of a cell of an open notebook, false if
+// uri is not one, see documentVersion
+func (c *Client) notebookCellVersion(uri protocol.DocumentUri) (int32, bool) {
+	notebookPath, index, ok := NotebookCellFromURI(uri)
+	if !ok {
+		return 0, false
+	}
+	c.notebooksMu.Lock()
+	defer c.notebooksMu.Unlock()
+	nb, open := c.notebooks["file://"+notebookPath]
+	if !open || index >= len(nb.Cells) {
+		return 0, false
+	}
+	return nb.Cells[index].version, true
+}
+
+// notebookCellContent returns the text of a cell as it was sent to the server, or
+// as it is on disk if its notebook is not open
+func (c *Client) notebookCellContent(uri protocol.DocumentUri) ([]byte, error) {
+	notebookPath, index, ok := NotebookCellFromURI(uri)
+	if !ok {
+		return nil, fmt.Errorf("not a notebook cell: %s", uri)
+	}
+
+	c.notebooksMu.Lock()
+	nb, open := c.notebooks["file://"+notebookPath]
+	var cells []NotebookCell
+	if open {
+		cells = nb.Cells
+	}
+	c.notebooksMu.Unlock()
+
+	if !open {
+		content, err := os.ReadFile(notebookPath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading notebook: %w", err)
+		}
+		if cells, err = parseNotebook(notebookPath, content); err != nil {
+			return nil, err
+		}
+	}
+	if index >= len(cells) {
+		return nil, fmt.Errorf("%s has no cell %d", notebookPath, index+1)
+	}
+	return []byte(cells[index].Text), nil
+}
@@ -0,0 +1,122 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/memory"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// SetOverlay makes the server see content as the text of filepath, whether or not
+// the file exists on disk, until DiscardOverlay is called. File changes on disk are
+// not sent for overlays and they are never closed to stay under the open file limit.
+// It reports whether the overlay is new.
+func (c *Client) SetOverlay(ctx context.Context, filepath string, content []byte) (bool, error) {
+	uri := fmt.Sprintf("file://%s", filepath)
+
+	c.docSyncMu.Lock()
+	defer c.docSyncMu.Unlock()
+
+	c.openFilesMu.Lock()
+	info, isOpen := c.openFiles[uri]
+	created := !isOpen || !info.Overlay
+	if isOpen {
+		info.Overlay = true
+		info.Sessions[SessionFromContext(ctx)] = true
+	}
+	c.openFilesMu.Unlock()
+
+	if isOpen {
+		return created, c.setContent(ctx, uri, content)
+	}
+
+	params := protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{
+			URI:        protocol.DocumentUri(uri),
+			LanguageID: DetectLanguageID(uri),
+			Version:    1,
+			Text:       string(content),
+		},
+	}
+	if err := c.Notify(ctx, "textDocument/didOpen", params); err != nil {
+		return false, err
+	}
+
+	c.openFilesMu.Lock()
+	c.openFiles[uri] = &OpenFileInfo{
+		Version:  1,
+		URI:      protocol.DocumentUri(uri),
+		Content:  content,
+		Sessions: map[string]bool{SessionFromContext(ctx): true},
+		lastUsed: memory.Tick(),
+		Overlay:  true,
+	}
+	c.openFilesMu.Unlock()
+	c.pullAfterSync(protocol.DocumentUri(uri))
+
+	if debug {
+		log.Printf("Opened overlay: %s", filepath)
+	}
+
+	c.evictLeastRecentlyUsed(ctx)
+	memory.Default.Changed()
+	return true, nil
+}
+
+// DiscardOverlay drops the overlay for filepath. The server is sent the file's text
+// from disk again, or the document is closed if the file does not exist. It reports
+// whether the document is still open.
+func (c *Client) DiscardOverlay(ctx context.Context, filepath string) (bool, error) {
+	uri := fmt.Sprintf("file://%s", filepath)
+
+	c.docSyncMu.Lock()
+	defer c.docSyncMu.Unlock()
+
+	c.openFilesMu.Lock()
+	info, isOpen := c.openFiles[uri]
+	if !isOpen || !info.Overlay {
+		c.openFilesMu.Unlock()
+		return false, fmt.Errorf("no overlay for %s", filepath)
+	}
+	info.Overlay = false
+	c.openFilesMu.Unlock()
+
+	content, err := os.ReadFile(filepath)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, c.sendDidClose(ctx, uri)
+	}
+	if err != nil {
+		return true, fmt.Errorf("error reading file: %w", err)
+	}
+	return true, c.setContent(ctx, uri, content)
+}
+
+// IsOverlay reports whether filepath is open as an overlay
+func (c *Client) IsOverlay(filepath string) bool {
+	uri := fmt.Sprintf("file://%s", filepath)
+	c.openFilesMu.RLock()
+	defer c.openFilesMu.RUnlock()
+	info, isOpen := c.openFiles[uri]
+	return isOpen && info.Overlay
+}
+
+// Overlays returns the paths of the open overlays, sorted
+func (c *Client) Overlays() []string {
+	c.openFilesMu.RLock()
+	defer c.openFilesMu.RUnlock()
+
+	var paths []string
+	for uri, info := range c.openFiles {
+		if info.Overlay {
+			paths = append(paths, strings.TrimPrefix(uri, "file://"))
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
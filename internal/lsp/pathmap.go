@@ -0,0 +1,177 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PathMapping maps a directory as this server sees it to the same directory as the
+// language server sees it, for servers running under WSL, in a container or on
+// another machine. Both are file URIs without a trailing slash.
+type PathMapping struct {
+	Local  string
+	Remote string
+}
+
+var (
+	pathMappings   []PathMapping
+	pathMappingsMu sync.RWMutex
+)
+
+// windowsPath matches a Windows path with a drive letter, with either separator
+var windowsPath = regexp.MustCompile(`^[A-Za-z]:([\\/]|$)`)
+
+// windowsURI matches the drive of a Windows file URI, with the colon percent
+// encoded as some servers send it
+var windowsURI = regexp.MustCompile(`^file:///([A-Za-z])(:|%3[Aa])`)
+
+// ParsePathMappings parses a path mapping from the command line: "local=remote",
+// "wsl" when this server runs on Windows and the language server under WSL, or
+// "windows" for the reverse
+func ParsePathMappings(spec string) ([]PathMapping, error) {
+	switch strings.ToLower(spec) {
+	case "wsl", "windows":
+		var mappings []PathMapping
+		for drive := 'a'; drive <= 'z'; drive++ {
+			windows := fmt.Sprintf("file:///%c:", drive-'a'+'A')
+			wsl := fmt.Sprintf("file:///mnt/%c", drive)
+			if strings.ToLower(spec) == "wsl" {
+				mappings = append(mappings, PathMapping{Local: windows, Remote: wsl})
+			} else {
+				mappings = append(mappings, PathMapping{Local: wsl, Remote: windows})
+			}
+		}
+		return mappings, nil
+	}
+
+	local, remote, ok := strings.Cut(spec, "=")
+	if !ok || local == "" || remote == "" {
+		return nil, fmt.Errorf("invalid path mapping %q (expected local=remote, wsl or windows)", spec)
+	}
+	return []PathMapping{{Local: pathURI(local), Remote: pathURI(remote)}}, nil
+}
+
+// pathURI returns the file URI of an absolute Unix or Windows path
+func pathURI(p string) string {
+	if windowsPath.MatchString(p) {
+		p = "/" + strings.ToUpper(p[:1]) + strings.ReplaceAll(p[1:], `\`, "/")
+	}
+	return "file://" + strings.TrimSuffix(path.Clean(p), "/")
+}
+
+// SetPathMappings sets the mappings applied to every URI sent to and received from
+// the language server
+func SetPathMappings(mappings []PathMapping) {
+	// Nested directories take precedence
+	mappings = append([]PathMapping(nil), mappings...)
+	sort.SliceStable(mappings, func(i, j int) bool {
+		return len(mappings[i].Local) > len(mappings[j].Local)
+	})
+
+	pathMappingsMu.Lock()
+	defer pathMappingsMu.Unlock()
+	pathMappings = mappings
+	if debug {
+		for _, m := range mappings {
+			log.Printf("Mapping %s to %s for the language server", m.Local, m.Remote)
+		}
+	}
+}
+
+// translateURI maps a URI to the language server's side of the mappings, or back
+// to this server's, false if it is under none of them
+func translateURI(uri string, toServer bool) (string, bool) {
+	// Notebook cells are named by their notebook's path
+	if rest, ok := strings.CutPrefix(uri, notebookCellScheme+":"); ok {
+		translated, ok := translateURI("file://"+rest, toServer)
+		return notebookCellScheme + ":" + strings.TrimPrefix(translated, "file://"), ok
+	}
+
+	normalized := uri
+	if m := windowsURI.FindStringSubmatch(uri); m != nil {
+		normalized = "file:///" + strings.ToUpper(m[1]) + ":" + uri[len(m[0]):]
+	}
+	for _, m := range pathMappings {
+		from, to := m.Remote, m.Local
+		if toServer {
+			from, to = m.Local, m.Remote
+		}
+		prefix := normalized[:min(len(from), len(normalized))]
+		matches := prefix == from || (windowsURI.MatchString(from) && strings.EqualFold(prefix, from))
+		if matches && (len(normalized) == len(from) || normalized[len(from)] == '/') {
+			return to + normalized[len(from):], true
+		}
+	}
+	return uri, false
+}
+
+// translateMessage rewrites the file URIs in a message's params and result, and in
+// the keys of maps like WorkspaceEdit.changes, for the other side of the mappings
+func translateMessage(msg *Message, toServer bool) {
+	pathMappingsMu.RLock()
+	defer pathMappingsMu.RUnlock()
+	if len(pathMappings) == 0 {
+		return
+	}
+	msg.Params = translateJSON(msg.Params, toServer)
+	msg.Result = translateJSON(msg.Result, toServer)
+}
+
+func translateJSON(raw json.RawMessage, toServer bool) json.RawMessage {
+	if !bytes.Contains(raw, []byte("file:")) && !bytes.Contains(raw, []byte(notebookCellScheme+":")) {
+		return raw
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return raw
+	}
+	translated, changed := translateValue(value, toServer)
+	if !changed {
+		return raw
+	}
+	data, err := json.Marshal(translated)
+	if err != nil {
+		return raw
+	}
+	return data
+}
+
+func translateValue(value interface{}, toServer bool) (interface{}, bool) {
+	switch v := value.(type) {
+	case string:
+		if strings.HasPrefix(v, "file:") || strings.HasPrefix(v, notebookCellScheme+":") {
+			return translateURI(v, toServer)
+		}
+	case []interface{}:
+		changed := false
+		for i, item := range v {
+			var itemChanged bool
+			v[i], itemChanged = translateValue(item, toServer)
+			changed = changed || itemChanged
+		}
+		return v, changed
+	case map[string]interface{}:
+		changed := false
+		translated := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			item, itemChanged := translateValue(item, toServer)
+			keyChanged := false
+			if strings.HasPrefix(key, "file:") || strings.HasPrefix(key, notebookCellScheme+":") {
+				key, keyChanged = translateURI(key, toServer)
+			}
+			translated[key] = item
+			changed = changed || itemChanged || keyChanged
+		}
+		return translated, changed
+	}
+	return value, false
+}
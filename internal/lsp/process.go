@@ -0,0 +1,164 @@
+package lsp
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryCheckInterval is how often the server's memory use is compared to its limit
+const memoryCheckInterval = 2 * time.Second
+
+// ProcessConfig is how the language server process is started
+type ProcessConfig struct {
+	// Working directory, the current directory when empty
+	Dir string
+	// KEY=VALUE variables set on top of this server's environment
+	Env []string
+	// Resident memory in bytes the server and its child processes may use before
+	// they are stopped, zero for no limit
+	MaxMemory int64
+}
+
+var (
+	processConfig   ProcessConfig
+	processConfigMu sync.RWMutex
+)
+
+// SetProcessConfig sets how the next language server process is started
+func SetProcessConfig(config ProcessConfig) {
+	processConfigMu.Lock()
+	defer processConfigMu.Unlock()
+	processConfig = config
+}
+
+// ProcessConfig returns the working directory, the environment variables added and
+// the memory limit the language server was started with. Dir is always set.
+func (c *Client) ProcessConfig() ProcessConfig {
+	config := c.process
+	if config.Dir == "" {
+		config.Dir, _ = os.Getwd()
+	}
+	return config
+}
+
+// MemoryUsage returns the resident memory of the language server and its child
+// processes in bytes, false where it cannot be measured
+func (c *Client) MemoryUsage() (int64, bool) {
+	if c.Cmd.Process == nil {
+		return 0, false
+	}
+	return processTreeRSS(c.Cmd.Process.Pid)
+}
+
+// watchMemory stops the server once it uses more than its memory limit, rather than
+// letting it take the machine's memory
+func (c *Client) watchMemory() {
+	ticker := time.NewTicker(memoryCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+		rss, ok := c.MemoryUsage()
+		if !ok {
+			log.Printf("Cannot measure the language server's memory on this system, the memory limit is not applied")
+			return
+		}
+		if rss <= c.process.MaxMemory {
+			continue
+		}
+		c.lastError.record("stopped after using %d MB, over the %d MB memory limit", rss>>20, c.process.MaxMemory>>20)
+		log.Printf("Stopping the language server, it uses %d MB, over the %d MB memory limit", rss>>20, c.process.MaxMemory>>20)
+		if err := c.Cmd.Process.Kill(); err != nil {
+			log.Printf("Failed to stop the language server: %v", err)
+		}
+		return
+	}
+}
+
+// processTreeRSS sums the resident memory of a process and its descendants from
+// /proc, servers like typescript-language-server do their work in child processes
+func processTreeRSS(pid int) (int64, bool) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0, false
+	}
+	children := make(map[int][]int)
+	for _, entry := range entries {
+		child, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		if parent, ok := parentPID(child); ok {
+			children[parent] = append(children[parent], child)
+		}
+	}
+
+	var total int64
+	measured := false
+	pending := []int{pid}
+	for len(pending) > 0 {
+		p := pending[len(pending)-1]
+		pending = append(pending[:len(pending)-1], children[p]...)
+		if rss, ok := processRSS(p); ok {
+			total += rss
+			measured = true
+		}
+	}
+	return total, measured
+}
+
+// parentPID reads the parent of a process from /proc/<pid>/stat
+func parentPID(pid int) (int, bool) {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return 0, false
+	}
+	// The command name in parentheses may contain spaces, the fields after it are
+	// state and parent PID
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return 0, false
+	}
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 2 {
+		return 0, false
+	}
+	parent, err := strconv.Atoi(fields[1])
+	return parent, err == nil
+}
+
+// processRSS reads the resident memory of a process from /proc/<pid>/statm
+func processRSS(pid int) (int64, bool) {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "statm"))
+	if err != nil {
+		return 0, false
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0, false
+	}
+	pages, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return pages * int64(os.Getpagesize()), true
+}
+
+// CheckEnvVariable validates a KEY=VALUE variable for the language server process
+func CheckEnvVariable(variable string) error {
+	key, _, ok := strings.Cut(variable, "=")
+	if !ok || strings.TrimSpace(key) == "" {
+		return fmt.Errorf("invalid environment variable %q (expected KEY=VALUE)", variable)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// ProgressTask is a work done progress operation the server has begun and not yet
+// ended, such as loading packages or indexing
+type ProgressTask struct {
+	Title      string
+	Message    string
+	Percentage uint32 // Only meaningful with HasPercent
+	HasPercent bool
+	Started    time.Time
+}
+
+// progressTracker follows the server's $/progress notifications
+type progressTracker struct {
+	mu      sync.Mutex
+	active  map[string]*ProgressTask // By token
+	titles  []string                 // Titles of every task begun, in order
+	lastEnd time.Time
+	changed chan struct{} // Closed and replaced whenever a task begins or ends
+}
+
+// handleProgress records the begin, report and end notifications of work done
+// progress. Other kinds of progress, like partial results, are ignored.
+func (c *Client) handleProgress(params json.RawMessage) {
+	var msg struct {
+		Token json.RawMessage `json:"token"`
+		Value struct {
+			Kind       string  `json:"kind"`
+			Title      string  `json:"title"`
+			Message    string  `json:"message"`
+			Percentage *uint32 `json:"percentage"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(params, &msg); err != nil {
+		return
+	}
+	c.progress.update(string(msg.Token), msg.Value.Kind, msg.Value.Title, msg.Value.Message, msg.Value.Percentage)
+}
+
+// update applies a begin, report or end of the task with the given token
+func (p *progressTracker) update(token, kind, title, message string, percentage *uint32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.active == nil {
+		p.active = make(map[string]*ProgressTask)
+	}
+
+	switch kind {
+	case "begin":
+		task := &ProgressTask{Title: title, Message: message, Started: time.Now()}
+		if percentage != nil {
+			task.Percentage, task.HasPercent = *percentage, true
+		}
+		p.active[token] = task
+		p.titles = append(p.titles, title)
+		p.notifyLocked()
+	case "report":
+		if task, ok := p.active[token]; ok {
+			if message != "" {
+				task.Message = message
+			}
+			if percentage != nil {
+				task.Percentage, task.HasPercent = *percentage, true
+			}
+		}
+	case "end":
+		if _, ok := p.active[token]; ok {
+			delete(p.active, token)
+			p.lastEnd = time.Now()
+			p.notifyLocked()
+		}
+	}
+}
+
+// notifyLocked wakes the goroutines waiting for progress to change, the caller must
+// hold mu
+func (p *progressTracker) notifyLocked() {
+	if p.changed != nil {
+		close(p.changed)
+		p.changed = nil
+	}
+}
+
+// ActiveProgress returns the progress operations the server is running
+func (c *Client) ActiveProgress() []ProgressTask {
+	p := &c.progress
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	tasks := make([]ProgressTask, 0, len(p.active))
+	for _, task := range p.active {
+		tasks = append(tasks, *task)
+	}
+	return tasks
+}
+
+// ProgressTitles returns the titles of every progress operation the server has
+// begun, in order
+func (c *Client) ProgressTitles() []string {
+	p := &c.progress
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.titles...)
+}
+
+// WaitForProgressIdle waits until the server has no progress operations running and
+// none has ended within quiet, since servers often start one task as another ends.
+// It returns ctx's error if the server is still busy when ctx is done.
+func (c *Client) WaitForProgressIdle(ctx context.Context, quiet time.Duration) error {
+	p := &c.progress
+	for {
+		p.mu.Lock()
+		idle := len(p.active) == 0
+		wait := quiet - time.Since(p.lastEnd)
+		if p.changed == nil {
+			p.changed = make(chan struct{})
+		}
+		changed := p.changed
+		p.mu.Unlock()
+
+		if idle && wait <= 0 {
+			return nil
+		}
+		var timer <-chan time.Time
+		if idle {
+			timer = time.After(wait)
+		}
+		select {
+		case <-changed:
+		case <-timer:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// pullDiagnosticsTimeout bounds the diagnostic requests sent after a document is
+// opened or changed
+const pullDiagnosticsTimeout = 30 * time.Second
+
+// pulledReport is a document diagnostic report, decoded leniently because servers
+// add fields the generated union type rejects
+type pulledReport struct {
+	// "full" or "unchanged"
+	Kind     string                `json:"kind"`
+	ResultID string                `json:"resultId,omitempty"`
+	Items    []protocol.Diagnostic `json:"items,omitempty"`
+	// Reports for documents whose diagnostics depend on this one
+	RelatedDocuments map[protocol.DocumentUri]pulledReport `json:"relatedDocuments,omitempty"`
+}
+
+// PullsDiagnostics reports whether the server provides diagnostics through
+// textDocument/diagnostic requests rather than publishDiagnostics notifications
+func (c *Client) PullsDiagnostics() bool {
+	c.diagnosticsMu.RLock()
+	defer c.diagnosticsMu.RUnlock()
+	return c.pullDiagnostics
+}
+
+// setPullDiagnostics switches the client to pulling diagnostics, once the server
+// declares a diagnostic provider at initialization or registers one later
+func (c *Client) setPullDiagnostics() {
+	c.diagnosticsMu.Lock()
+	defer c.diagnosticsMu.Unlock()
+	if !c.pullDiagnostics {
+		log.Printf("Language server provides pull diagnostics")
+	}
+	c.pullDiagnostics = true
+}
+
+// handleDiagnosticRegistration watches client/registerCapability for a dynamically
+// registered diagnostic provider
+func (c *Client) handleDiagnosticRegistration(params json.RawMessage) {
+	var registerParams protocol.RegistrationParams
+	if err := json.Unmarshal(params, &registerParams); err != nil {
+		return
+	}
+	for _, reg := range registerParams.Registrations {
+		if reg.Method == "textDocument/diagnostic" {
+			c.setPullDiagnostics()
+		}
+	}
+}
+
+// PullDiagnostics requests the diagnostics of an open document and stores them in
+// the cache shared with published diagnostics. The result ID of the previous report
+// is sent along, so the server can answer that nothing changed.
+func (c *Client) PullDiagnostics(ctx context.Context, uri protocol.DocumentUri) ([]protocol.Diagnostic, error) {
+	// The report describes the text the server has when the request is sent
+	version, _ := c.documentVersion(uri)
+	c.diagnosticsMu.Lock()
+	c.pullGeneration[uri]++
+	generation := c.pullGeneration[uri]
+	previous := c.resultIDs[uri]
+	c.diagnosticsMu.Unlock()
+
+	params := protocol.DocumentDiagnosticParams{
+		TextDocument:     protocol.TextDocumentIdentifier{URI: uri},
+		PreviousResultID: previous,
+	}
+	var report pulledReport
+	if err := c.Call(ctx, "textDocument/diagnostic", params, &report); err != nil {
+		return c.GetFileDiagnostics(uri), fmt.Errorf("diagnostic request failed: %w", err)
+	}
+
+	c.diagnosticsMu.Lock()
+	// A later request for the same document supersedes this one
+	current := c.pullGeneration[uri] == generation
+	c.diagnosticsMu.Unlock()
+	if !current {
+		return c.GetFileDiagnostics(uri), nil
+	}
+
+	for related, relatedReport := range report.RelatedDocuments {
+		c.storePulledReport(related, relatedReport, 0)
+	}
+	return c.storePulledReport(uri, report, version), nil
+}
+
+// storePulledReport caches a pulled report for a document version, see
+// storeDiagnostics, and returns the document's diagnostics. Unchanged reports keep
+// the cached diagnostics, mark them as applying to version and only wake waiters.
+func (c *Client) storePulledReport(uri protocol.DocumentUri, report pulledReport, version int32) []protocol.Diagnostic {
+	if report.Kind == "unchanged" {
+		c.diagnosticsMu.Lock()
+		if report.ResultID != "" {
+			c.resultIDs[uri] = report.ResultID
+		}
+		diagnostics, cached := c.diagnostics[uri]
+		if cached && version > c.diagnosticsVersions[uri] {
+			c.diagnosticsVersions[uri] = version
+		}
+		c.diagnosticsMu.Unlock()
+		if cached {
+			c.wakeDiagnosticsWaiters(uri, diagnostics)
+		}
+		return c.GetFileDiagnostics(uri)
+	}
+
+	diagnostics := report.Items
+	if diagnostics == nil {
+		diagnostics = []protocol.Diagnostic{}
+	}
+	if !c.storeDiagnostics(uri, diagnostics, version) {
+		return c.GetFileDiagnostics(uri)
+	}
+	// The result ID is only kept with the diagnostics it identifies
+	c.diagnosticsMu.Lock()
+	if report.ResultID != "" {
+		c.resultIDs[uri] = report.ResultID
+	} else {
+		delete(c.resultIDs, uri)
+	}
+	c.diagnosticsMu.Unlock()
+	return diagnostics
+}
+
+// pullAfterSync requests fresh diagnostics for a document that was just opened or
+// changed when the server does not publish them. Waiters such as NextDiagnostics
+// are woken by the response as they would be by a notification.
+func (c *Client) pullAfterSync(uri protocol.DocumentUri) {
+	if !c.PullsDiagnostics() {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), pullDiagnosticsTimeout)
+		defer cancel()
+		if _, err := c.PullDiagnostics(ctx, uri); err != nil && debug {
+			log.Printf("Failed to pull diagnostics for %s: %v", uri, err)
+		}
+	}()
+}
+
+// handleDiagnosticRefresh answers workspace/diagnostic/refresh by pulling the
+// diagnostics of every open document again
+func (c *Client) handleDiagnosticRefresh(params json.RawMessage) (interface{}, error) {
+	c.openFilesMu.RLock()
+	uris := make([]protocol.DocumentUri, 0, len(c.openFiles))
+	for _, info := range c.openFiles {
+		uris = append(uris, info.URI)
+	}
+	c.openFilesMu.RUnlock()
+
+	for _, uri := range uris {
+		c.pullAfterSync(uri)
+	}
+	return nil, nil
+}
+
+// forgetClosedDocument drops the result ID of a closed document, servers do not
+// keep reports for documents that are no longer open, and the version of its
+// diagnostics, since versions start over when it is opened again
+func (c *Client) forgetClosedDocument(uri protocol.DocumentUri) {
+	c.diagnosticsMu.Lock()
+	defer c.diagnosticsMu.Unlock()
+	delete(c.resultIDs, uri)
+	delete(c.diagnosticsVersions, uri)
+}
@@ -0,0 +1,170 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// poetryTimeout bounds asking poetry where a project's environment is
+const poetryTimeout = 10 * time.Second
+
+var (
+	pythonEnvironment string
+	// Interpreters found per workspace, "" when there is none
+	pythonInterpreters = make(map[string]string)
+	pythonMu           sync.Mutex
+)
+
+// SetPythonEnvironment sets the virtualenv, or the interpreter, that Python
+// servers resolve imports with instead of the detected one
+func SetPythonEnvironment(path string) {
+	pythonMu.Lock()
+	defer pythonMu.Unlock()
+	pythonEnvironment = path
+	pythonInterpreters = make(map[string]string)
+}
+
+// PythonInterpreter returns the interpreter of the workspace's Python environment:
+// the one set with SetPythonEnvironment, the active virtualenv ($VIRTUAL_ENV), a
+// .venv or venv directory in the workspace, poetry's environment for the project
+// or the active conda environment. It returns "" when there is none, servers then
+// use the python on the PATH.
+func PythonInterpreter(workspaceDir string) string {
+	pythonMu.Lock()
+	defer pythonMu.Unlock()
+	if interpreter, ok := pythonInterpreters[workspaceDir]; ok {
+		return interpreter
+	}
+
+	interpreter, source := detectPythonInterpreter(workspaceDir)
+	if interpreter != "" {
+		log.Printf("Using the Python interpreter %s (%s)", interpreter, source)
+	} else if debug {
+		log.Printf("No Python environment found for %s", workspaceDir)
+	}
+	pythonInterpreters[workspaceDir] = interpreter
+	return interpreter
+}
+
+func detectPythonInterpreter(workspaceDir string) (string, string) {
+	if pythonEnvironment != "" {
+		interpreter := interpreterIn(pythonEnvironment)
+		if interpreter == "" {
+			log.Printf("No Python interpreter in %s", pythonEnvironment)
+		}
+		return interpreter, "--python-env"
+	}
+	if env := os.Getenv("VIRTUAL_ENV"); env != "" {
+		if interpreter := interpreterIn(env); interpreter != "" {
+			return interpreter, "$VIRTUAL_ENV"
+		}
+	}
+	for _, dir := range []string{".venv", "venv"} {
+		if interpreter := interpreterIn(filepath.Join(workspaceDir, dir)); interpreter != "" {
+			return interpreter, dir + " in the workspace"
+		}
+	}
+	if env := poetryEnvironment(workspaceDir); env != "" {
+		if interpreter := interpreterIn(env); interpreter != "" {
+			return interpreter, "poetry"
+		}
+	}
+	if env := os.Getenv("CONDA_PREFIX"); env != "" {
+		if interpreter := interpreterIn(env); interpreter != "" {
+			return interpreter, "$CONDA_PREFIX"
+		}
+	}
+	return "", ""
+}
+
+// interpreterIn returns the python executable of an environment, or path itself
+// when it is an interpreter, "" if there is none
+func interpreterIn(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	if !info.IsDir() {
+		return path
+	}
+	candidates := []string{filepath.Join(path, "bin", "python3"), filepath.Join(path, "bin", "python")}
+	if runtime.GOOS == "windows" {
+		candidates = []string{filepath.Join(path, "Scripts", "python.exe"), filepath.Join(path, "python.exe")}
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// poetryEnvironment asks poetry for the environment of a poetry project, "" for
+// other workspaces or when poetry is not installed
+func poetryEnvironment(workspaceDir string) string {
+	pyproject, err := os.ReadFile(filepath.Join(workspaceDir, "pyproject.toml"))
+	if err != nil || !bytes.Contains(pyproject, []byte("[tool.poetry")) {
+		return ""
+	}
+	if _, err := exec.LookPath("poetry"); err != nil {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), poetryTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "poetry", "env", "info", "--path")
+	cmd.Dir = workspaceDir
+	out, err := cmd.Output()
+	if err != nil {
+		log.Printf("Failed to find the poetry environment: %v", err)
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// pythonSettings answers the "python" section pyright and basedpyright ask for
+func pythonSettings(workspaceDir string) map[string]interface{} {
+	interpreter := PythonInterpreter(workspaceDir)
+	if interpreter == "" {
+		return nil
+	}
+	return map[string]interface{}{"pythonPath": interpreter}
+}
+
+// pythonAnalysisSettings answers the "python.analysis" section. Without them
+// pyright neither adds src directories to the search paths nor infers types
+// from libraries that have no stubs.
+func pythonAnalysisSettings(workspaceDir string) map[string]interface{} {
+	return map[string]interface{}{
+		"autoSearchPaths":        true,
+		"useLibraryCodeForTypes": true,
+	}
+}
+
+// configurePylsp points pylsp's jedi at the workspace's environment. pylsp does not
+// ask for its configuration, it has to be sent.
+func configurePylsp(ctx context.Context, c *Client, workspaceDir string) error {
+	interpreter := PythonInterpreter(workspaceDir)
+	if interpreter == "" {
+		return nil
+	}
+	return c.DidChangeConfiguration(ctx, protocol.DidChangeConfigurationParams{
+		Settings: map[string]interface{}{
+			"pylsp": map[string]interface{}{
+				"plugins": map[string]interface{}{
+					"jedi": map[string]interface{}{"environment": interpreter},
+				},
+			},
+		},
+	})
+}
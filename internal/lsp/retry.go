@@ -0,0 +1,66 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// staleMessages are found in the messages of servers that reject a request made
+// against an older version of a document without using the ContentModified code
+var staleMessages = []string{"content modified", "stale", "version mismatch", "outdated version"}
+
+// isStaleDocumentError reports whether the server discarded a request because the
+// document changed while it was computing the result, or because the request was
+// made against a version of the document it no longer has
+func isStaleDocumentError(err error) bool {
+	responseErr, ok := ResponseErrorFrom(err)
+	if !ok {
+		return false
+	}
+	if responseErr.Code == int(protocol.ContentModified) {
+		return true
+	}
+	message := strings.ToLower(responseErr.Message)
+	for _, stale := range staleMessages {
+		if strings.Contains(message, stale) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestDocument returns the URI of the document a request is about, "" when its
+// params have no textDocument
+func requestDocument(params interface{}) protocol.DocumentUri {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return ""
+	}
+	var request struct {
+		TextDocument struct {
+			URI protocol.DocumentUri `json:"uri"`
+		} `json:"textDocument"`
+	}
+	if err := json.Unmarshal(data, &request); err != nil {
+		return ""
+	}
+	return request.TextDocument.URI
+}
+
+// resyncDocument sends the server the current text of the request's document, so
+// that a request rejected as stale can be retried against it. Overlays are left
+// alone, their text is what the server already has.
+func (c *Client) resyncDocument(ctx context.Context, params interface{}) error {
+	uri := requestDocument(params)
+	if uri == "" {
+		return nil
+	}
+	filepath := strings.TrimPrefix(string(uri), "file://")
+	if !c.IsFileOpen(filepath) {
+		return nil
+	}
+	return c.NotifyChange(ctx, filepath)
+}
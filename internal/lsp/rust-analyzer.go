@@ -0,0 +1,153 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+var (
+	cargoFeatures          []string
+	cargoNoDefaultFeatures bool
+	cargoFeaturesMu        sync.RWMutex
+)
+
+// SetCargoFeatures sets the cargo features rust-analyzer analyzes the workspace
+// with, "all" for every feature, and whether the default features are left out
+func SetCargoFeatures(features []string, noDefaultFeatures bool) {
+	cargoFeaturesMu.Lock()
+	defer cargoFeaturesMu.Unlock()
+	cargoFeatures = features
+	cargoNoDefaultFeatures = noDefaultFeatures
+}
+
+// rustAnalyzerSettings returns the rust-analyzer settings set on the command line,
+// nil when there are none. rust-analyzer reads them from initializationOptions and
+// again from the "rust-analyzer" section of workspace/configuration, where an
+// empty answer would reset them.
+func rustAnalyzerSettings(workspaceDir string) map[string]interface{} {
+	cargoFeaturesMu.RLock()
+	defer cargoFeaturesMu.RUnlock()
+
+	cargo := make(map[string]interface{})
+	if len(cargoFeatures) == 1 && cargoFeatures[0] == "all" {
+		cargo["features"] = "all"
+	} else if len(cargoFeatures) > 0 {
+		cargo["features"] = cargoFeatures
+	}
+	if cargoNoDefaultFeatures {
+		cargo["noDefaultFeatures"] = true
+	}
+	if len(cargo) == 0 {
+		return nil
+	}
+	return map[string]interface{}{"cargo": cargo}
+}
+
+func rustAnalyzerInitializationOptions(workspaceDir string, args []string) map[string]interface{} {
+	return rustAnalyzerSettings(workspaceDir)
+}
+
+// ExpandedMacro is the result of rust-analyzer/expandMacro
+type ExpandedMacro struct {
+	Name      string `json:"name"`
+	Expansion string `json:"expansion"`
+}
+
+// ExpandMacro returns the recursive expansion of the macro call at a position, nil
+// if there is no macro call there
+func (c *Client) ExpandMacro(ctx context.Context, params protocol.TextDocumentPositionParams) (*ExpandedMacro, error) {
+	var result *ExpandedMacro
+	err := c.Call(ctx, "rust-analyzer/expandMacro", params, &result)
+	return result, err
+}
+
+// Runnable is a test, benchmark or binary rust-analyzer knows how to run
+type Runnable struct {
+	Label    string                 `json:"label"`
+	Location *protocol.LocationLink `json:"location,omitempty"`
+	// "cargo" runs cargo with Args' cargoArgs, "shell" runs Args' program
+	Kind string       `json:"kind"`
+	Args RunnableArgs `json:"args"`
+}
+
+// RunnableArgs holds the fields of both kinds of runnables
+type RunnableArgs struct {
+	WorkspaceRoot  string            `json:"workspaceRoot,omitempty"`
+	Cwd            string            `json:"cwd,omitempty"`
+	Environment    map[string]string `json:"environment,omitempty"`
+	OverrideCargo  string            `json:"overrideCargo,omitempty"`
+	CargoArgs      []string          `json:"cargoArgs,omitempty"`
+	CargoExtraArgs []string          `json:"cargoExtraArgs,omitempty"` // Older servers
+	ExecutableArgs []string          `json:"executableArgs,omitempty"`
+	Program        string            `json:"program,omitempty"`
+	ProgramArgs    []string          `json:"args,omitempty"`
+}
+
+// Command returns the program and arguments that run the runnable
+func (r Runnable) Command() (string, []string) {
+	if r.Kind == "shell" {
+		return r.Args.Program, r.Args.ProgramArgs
+	}
+	program := "cargo"
+	if r.Args.OverrideCargo != "" {
+		program = r.Args.OverrideCargo
+	}
+	args := append(append([]string{}, r.Args.CargoArgs...), r.Args.CargoExtraArgs...)
+	if len(r.Args.ExecutableArgs) > 0 {
+		args = append(append(args, "--"), r.Args.ExecutableArgs...)
+	}
+	return program, args
+}
+
+// RunnablesParams are the params of experimental/runnables. Without a position all
+// runnables of the document are returned.
+type RunnablesParams struct {
+	TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
+	Position     *protocol.Position              `json:"position,omitempty"`
+}
+
+// Runnables lists the tests, benchmarks and binaries of a document, or those at a
+// position, with rust-analyzer's experimental/runnables extension
+func (c *Client) Runnables(ctx context.Context, params RunnablesParams) ([]Runnable, error) {
+	var result []Runnable
+	err := c.Call(ctx, "experimental/runnables", params, &result)
+	return result, err
+}
+
+// hoverRangeParams are textDocument/hover params with a range in place of the
+// position, accepted by servers with the hoverRange extension
+type hoverRangeParams struct {
+	TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
+	Position     protocol.Range                  `json:"position"`
+}
+
+// SupportsHoverRange reports whether the server hovers over a selected range, which
+// rust-analyzer answers with the type of the expression in it
+func (c *Client) SupportsHoverRange() bool {
+	return c.hoverRange
+}
+
+// HoverRange requests hover information for a range of a document, see
+// SupportsHoverRange. The result is the raw hover, for protocol.HoverContents.
+func (c *Client) HoverRange(ctx context.Context, uri protocol.DocumentUri, rng protocol.Range) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := c.Call(ctx, "textDocument/hover", hoverRangeParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Position:     rng,
+	}, &result)
+	return result, err
+}
+
+// experimentalCapability reports whether a server's experimental capabilities set
+// name to true
+func experimentalCapability(experimental interface{}, name string) bool {
+	capabilities, ok := experimental.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	enabled, _ := capabilities[name].(bool)
+	return enabled
+}
@@ -0,0 +1,30 @@
+package lsp
+
+import (
+	"encoding/json"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// semanticTokensLegend extracts the legend from the semanticTokensProvider server
+// capability, nil if the server provides no semantic tokens
+func semanticTokensLegend(capability interface{}) *protocol.SemanticTokensLegend {
+	if capability == nil {
+		return nil
+	}
+	data, err := json.Marshal(capability)
+	if err != nil {
+		return nil
+	}
+	var options protocol.SemanticTokensOptions
+	if err := json.Unmarshal(data, &options); err != nil || len(options.Legend.TokenTypes) == 0 {
+		return nil
+	}
+	return &options.Legend
+}
+
+// SemanticTokensLegend returns the token types and modifiers the server's semantic
+// tokens are encoded with, nil if it provides none
+func (c *Client) SemanticTokensLegend() *protocol.SemanticTokensLegend {
+	return c.tokensLegend
+}
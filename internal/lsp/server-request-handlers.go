@@ -3,6 +3,7 @@ package lsp
 import (
 	"encoding/json"
 	"log"
+	"sync"
 
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 	"github.com/isaacphi/mcp-language-server/internal/utilities"
@@ -10,8 +11,24 @@ import (
 
 // Requests
 
-func HandleWorkspaceConfiguration(params json.RawMessage) (interface{}, error) {
-	return []map[string]interface{}{{}}, nil
+// HandleWorkspaceConfiguration answers each requested item with the settings of its
+// section, see configurationSections, or an empty object for the defaults
+func HandleWorkspaceConfiguration(workspaceDir string, params json.RawMessage) (interface{}, error) {
+	var configParams protocol.ConfigurationParams
+	if err := json.Unmarshal(params, &configParams); err != nil || len(configParams.Items) == 0 {
+		return []map[string]interface{}{{}}, nil
+	}
+
+	result := make([]map[string]interface{}, len(configParams.Items))
+	for i, item := range configParams.Items {
+		result[i] = map[string]interface{}{}
+		if settings, ok := configurationSections[item.Section]; ok {
+			if section := settings(workspaceDir); section != nil {
+				result[i] = section
+			}
+		}
+	}
+	return result, nil
 }
 
 func HandleRegisterCapability(params json.RawMessage) (interface{}, error) {
@@ -66,17 +83,26 @@ func HandleApplyEdit(params json.RawMessage) (interface{}, error) {
 type FileWatchRegistrationHandler func(id string, watchers []protocol.FileSystemWatcher)
 
 // fileWatchHandler holds the current handler for file watch registrations
-var fileWatchHandler FileWatchRegistrationHandler
+var (
+	fileWatchHandler   FileWatchRegistrationHandler
+	fileWatchHandlerMu sync.RWMutex
+)
 
 // RegisterFileWatchHandler sets the handler for file watch registrations
 func RegisterFileWatchHandler(handler FileWatchRegistrationHandler) {
+	fileWatchHandlerMu.Lock()
+	defer fileWatchHandlerMu.Unlock()
 	fileWatchHandler = handler
 }
 
 // notifyFileWatchRegistration notifies the handler about new file watch registrations
 func notifyFileWatchRegistration(id string, watchers []protocol.FileSystemWatcher) {
-	if fileWatchHandler != nil {
-		fileWatchHandler(id, watchers)
+	fileWatchHandlerMu.RLock()
+	handler := fileWatchHandler
+	fileWatchHandlerMu.RUnlock()
+
+	if handler != nil {
+		handler(id, watchers)
 	}
 }
 
@@ -99,10 +125,7 @@ func HandleDiagnostics(client *Client, params json.RawMessage) {
 		return
 	}
 
-	client.diagnosticsMu.Lock()
-	defer client.diagnosticsMu.Unlock()
-
-	client.diagnostics[diagParams.URI] = diagParams.Diagnostics
-
 	log.Printf("Received diagnostics for %s: %d items", diagParams.URI, len(diagParams.Diagnostics))
+
+	client.storeDiagnostics(diagParams.URI, diagParams.Diagnostics, diagParams.Version)
 }
@@ -0,0 +1,116 @@
+package lsp
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// IsServer reports whether the language server's command name starts with name,
+// e.g. "clangd" for clangd-17
+func (c *Client) IsServer(name string) bool {
+	return isServerCommand(c.Cmd.Path, name)
+}
+
+func isServerCommand(command, name string) bool {
+	return strings.HasPrefix(strings.ToLower(filepath.Base(command)), name)
+}
+
+// serverLanguages names the languages of well-known servers, keyed by command name
+// prefix
+var serverLanguages = map[string]string{
+	"gopls":                      "Go",
+	"rust-analyzer":              "Rust",
+	"pyright":                    "Python",
+	"basedpyright":               "Python",
+	"pylsp":                      "Python",
+	"jedi-language-server":       "Python",
+	"typescript-language-server": "TypeScript",
+	"vtsls":                      "TypeScript",
+	"clangd":                     "C/C++",
+	"jdtls":                      "Java",
+	"kotlin-language-server":     "Kotlin",
+	"csharp-ls":                  "C#",
+	"omnisharp":                  "C#",
+	"solargraph":                 "Ruby",
+	"ruby-lsp":                   "Ruby",
+	"intelephense":               "PHP",
+	"lua-language-server":        "Lua",
+	"zls":                        "Zig",
+	"sourcekit-lsp":              "Swift",
+}
+
+// ServerLanguage returns the language a language server command is for, or the
+// command's name if it is not a server it knows
+func ServerLanguage(command string) string {
+	for server, language := range serverLanguages {
+		if isServerCommand(command, server) {
+			return language
+		}
+	}
+	return filepath.Base(command)
+}
+
+// serverArgs add to the command line of servers that need more arguments than
+// given, keyed by command name prefix
+var serverArgs = map[string]func(workspaceDir string, args []string) []string{
+	"jdtls": jdtlsArgs,
+}
+
+// ServerArgs returns the arguments to start the language server with
+func ServerArgs(command, workspaceDir string, args []string) []string {
+	for server, withArgs := range serverArgs {
+		if isServerCommand(command, server) {
+			args = withArgs(workspaceDir, args)
+		}
+	}
+	return args
+}
+
+// serverInitializationOptions add the initializationOptions of servers that need
+// more than the common ones, keyed by command name prefix. They get the workspace
+// and the server's arguments.
+var serverInitializationOptions = map[string]func(workspaceDir string, args []string) map[string]interface{}{
+	"clangd":                     clangdInitializationOptions,
+	"gopls":                      goplsInitializationOptions,
+	"rust-analyzer":              rustAnalyzerInitializationOptions,
+	"typescript-language-server": typescriptInitializationOptions,
+}
+
+// configurationSections answer workspace/configuration requests for a section with
+// the settings for the workspace, nil for the server's defaults
+var configurationSections = map[string]func(workspaceDir string) map[string]interface{}{
+	"rust-analyzer":         rustAnalyzerSettings,
+	"gopls":                 goplsSettings,
+	"python":                pythonSettings,
+	"python.analysis":       pythonAnalysisSettings,
+	"basedpyright.analysis": pythonAnalysisSettings,
+}
+
+// initializationOptions returns the initializationOptions sent to the server
+func (c *Client) initializationOptions(workspaceDir string) map[string]interface{} {
+	options := map[string]interface{}{
+		"codelenses": map[string]bool{
+			"generate":           true,
+			"regenerate_cgo":     true,
+			"test":               true,
+			"tidy":               true,
+			"upgrade_dependency": true,
+			"vendor":             true,
+			"vulncheck":          false,
+		},
+		// jdtls only returns definitions in libraries as jdt: URIs when the
+		// client can fetch their source, see ExternalContent
+		"extendedClientCapabilities": map[string]bool{
+			"classFileContentsSupport": true,
+		},
+	}
+	for server, serverOptions := range serverInitializationOptions {
+		if !c.IsServer(server) {
+			continue
+		}
+		for key, value := range serverOptions(workspaceDir, c.Cmd.Args[1:]) {
+			options[key] = value
+		}
+	}
+	return options
+}
@@ -0,0 +1,55 @@
+package lsp
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+type sessionKey struct{}
+
+// WithSession returns a context whose requests are attributed to an MCP session.
+// Files opened with such a context stay open until every session that opened them
+// is released.
+func WithSession(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, sessionID)
+}
+
+// SessionFromContext returns the MCP session of a context, or "" for requests made
+// by the server itself
+func SessionFromContext(ctx context.Context) string {
+	sessionID, _ := ctx.Value(sessionKey{}).(string)
+	return sessionID
+}
+
+// ReleaseSession drops a session's claim on the files it opened and closes the
+// files no other session, nor the server itself, still uses. Pinned files stay open.
+func (c *Client) ReleaseSession(ctx context.Context, sessionID string) {
+	if sessionID == "" {
+		return
+	}
+
+	var toClose []string
+	c.openFilesMu.Lock()
+	for uri, info := range c.openFiles {
+		if !info.Sessions[sessionID] {
+			continue
+		}
+		delete(info.Sessions, sessionID)
+		if len(info.Sessions) == 0 && !info.Pinned {
+			toClose = append(toClose, strings.TrimPrefix(uri, "file://"))
+		}
+	}
+	c.openFilesMu.Unlock()
+
+	for _, filePath := range toClose {
+		if err := c.closeFile(ctx, filePath, true); err != nil {
+			log.Printf("Error closing file %s: %v", filePath, err)
+		}
+	}
+	c.releaseNotebooks(ctx, sessionID)
+
+	if debug {
+		log.Printf("Released session %s, closed %d files", sessionID, len(toClose))
+	}
+}
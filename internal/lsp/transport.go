@@ -4,15 +4,24 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/metrics"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
 )
 
 var debug = os.Getenv("DEBUG") != ""
 
+// errConnectionClosed is returned by requests once the server's output has ended
+var errConnectionClosed = errors.New("language server connection closed")
+
 // Write writes an LSP message to the given writer
 func WriteMessage(w io.Writer, msg *Message) error {
 	data, err := json.Marshal(msg)
@@ -97,87 +106,47 @@ func (c *Client) handleMessages() {
 			if debug {
 				log.Printf("Error reading message: %v", err)
 			}
+			// Fail pending and future requests instead of leaving them waiting
+			close(c.done)
+			c.notifications.close()
 			return
 		}
+		translateMessage(msg, false)
 
-		// Handle server->client request (has both Method and ID)
+		// Handle server->client request (has both Method and ID).
+		// Handlers run on their own goroutine so one that calls back into the
+		// server cannot deadlock the reader.
 		if msg.Method != "" && msg.ID != 0 {
 			if debug {
 				log.Printf("Received request from server: method=%s id=%d", msg.Method, msg.ID)
 			}
-
-			response := &Message{
-				JSONRPC: "2.0",
-				ID:      msg.ID,
-			}
-
-			// Look up handler for this method
-			c.serverHandlersMu.RLock()
-			handler, ok := c.serverRequestHandlers[msg.Method]
-			c.serverHandlersMu.RUnlock()
-
-			if ok {
-				result, err := handler(msg.Params)
-				if err != nil {
-					response.Error = &ResponseError{
-						Code:    -32603,
-						Message: err.Error(),
-					}
-				} else {
-					rawJSON, err := json.Marshal(result)
-					if err != nil {
-						response.Error = &ResponseError{
-							Code:    -32603,
-							Message: fmt.Sprintf("failed to marshal response: %v", err),
-						}
-					} else {
-						response.Result = rawJSON
-					}
-				}
-			} else {
-				response.Error = &ResponseError{
-					Code:    -32601,
-					Message: fmt.Sprintf("method not found: %s", msg.Method),
-				}
-			}
-
-			// Send response back to server
-			if err := WriteMessage(c.stdin, response); err != nil {
-				log.Printf("Error sending response to server: %v", err)
-			}
-
+			go c.handleServerRequest(msg)
 			continue
 		}
 
-		// Handle notification (has Method but no ID)
+		// Handle notification (has Method but no ID). Notifications are queued and
+		// handled in the order they were received, so an older publishDiagnostics
+		// never overwrites a newer one. The queue never blocks, a slow handler must
+		// not keep responses from being read.
 		if msg.Method != "" && msg.ID == 0 {
-			c.notificationMu.RLock()
-			handler, ok := c.notificationHandlers[msg.Method]
-			c.notificationMu.RUnlock()
-
-			if ok {
-				if debug {
-					log.Printf("Handling notification: %s", msg.Method)
-				}
-				go handler(msg.Params)
-			} else if debug {
-				log.Printf("No handler for notification: %s", msg.Method)
-			}
+			c.notifications.push(msg)
 			continue
 		}
 
 		// Handle response to our request (has ID but no Method)
 		if msg.ID != 0 && msg.Method == "" {
-			c.handlersMu.RLock()
+			// Remove the handler as the response is delivered, a duplicate or late
+			// response for the same ID is then dropped instead of reaching a closed channel
+			c.handlersMu.Lock()
 			ch, ok := c.handlers[msg.ID]
-			c.handlersMu.RUnlock()
+			delete(c.handlers, msg.ID)
+			c.handlersMu.Unlock()
 
 			if ok {
 				if debug {
 					log.Printf("Sending response for ID %d to handler", msg.ID)
 				}
 				ch <- msg
-				close(ch)
 			} else if debug {
 				log.Printf("No handler for response ID: %d", msg.ID)
 			}
@@ -185,8 +154,154 @@ func (c *Client) handleMessages() {
 	}
 }
 
-// Call makes a request and waits for the response
+// handleServerRequest runs the handler for a server->client request and sends its response
+func (c *Client) handleServerRequest(msg *Message) {
+	response := &Message{
+		JSONRPC: "2.0",
+		ID:      msg.ID,
+	}
+
+	// Look up handler for this method
+	c.serverHandlersMu.RLock()
+	handler, ok := c.serverRequestHandlers[msg.Method]
+	c.serverHandlersMu.RUnlock()
+
+	if ok {
+		result, err := handler(msg.Params)
+		if err != nil {
+			response.Error = &ResponseError{
+				Code:    -32603,
+				Message: err.Error(),
+			}
+		} else {
+			rawJSON, err := json.Marshal(result)
+			if err != nil {
+				response.Error = &ResponseError{
+					Code:    -32603,
+					Message: fmt.Sprintf("failed to marshal response: %v", err),
+				}
+			} else {
+				response.Result = rawJSON
+			}
+		}
+	} else {
+		response.Error = &ResponseError{
+			Code:    -32601,
+			Message: fmt.Sprintf("method not found: %s", msg.Method),
+		}
+	}
+
+	// Send response back to server
+	if err := c.writeMessage(response); err != nil {
+		log.Printf("Error sending response to server: %v", err)
+	}
+}
+
+// notificationQueue holds the notifications waiting to be handled, in the order they
+// were received. It grows as needed instead of making the reader wait.
+type notificationQueue struct {
+	mu       sync.Mutex
+	ready    *sync.Cond
+	messages []*Message
+	closed   bool
+}
+
+func newNotificationQueue() *notificationQueue {
+	q := &notificationQueue{}
+	q.ready = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *notificationQueue) push(msg *Message) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.messages = append(q.messages, msg)
+	q.ready.Signal()
+}
+
+// close lets pop return once the queued notifications are handled
+func (q *notificationQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.ready.Broadcast()
+}
+
+// pop waits for the next notification, false once the queue is closed and empty
+func (q *notificationQueue) pop() (*Message, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.messages) == 0 && !q.closed {
+		q.ready.Wait()
+	}
+	if len(q.messages) == 0 {
+		return nil, false
+	}
+	msg := q.messages[0]
+	q.messages[0] = nil
+	q.messages = q.messages[1:]
+	return msg, true
+}
+
+// dispatchNotifications handles queued notifications one at a time
+func (c *Client) dispatchNotifications() {
+	for {
+		msg, ok := c.notifications.pop()
+		if !ok {
+			return
+		}
+		c.notificationMu.RLock()
+		handler, ok := c.notificationHandlers[msg.Method]
+		c.notificationMu.RUnlock()
+
+		if ok {
+			if debug {
+				log.Printf("Handling notification: %s", msg.Method)
+			}
+			handler(msg.Params)
+		} else if debug {
+			log.Printf("No handler for notification: %s", msg.Method)
+		}
+	}
+}
+
+// Call makes a request and waits for the response. At most maxInFlight requests
+// are outstanding at once, further calls wait for a slot. If ctx is cancelled
+// while waiting for the response, the server is sent $/cancelRequest. A request the
+// server rejects because its document changed meanwhile is retried once, after
+// sending the server the document's current text.
 func (c *Client) Call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	err := c.timedCall(ctx, method, params, result)
+	if !isStaleDocumentError(err) || ctx.Err() != nil {
+		return err
+	}
+
+	if debug {
+		log.Printf("Retrying %s after the document changed: %v", method, err)
+	}
+	if syncErr := c.resyncDocument(ctx, params); syncErr != nil {
+		log.Printf("Error syncing the document before retrying %s: %v", method, syncErr)
+	}
+	return c.timedCall(ctx, method, params, result)
+}
+
+func (c *Client) timedCall(ctx context.Context, method string, params interface{}, result interface{}) error {
+	start := time.Now()
+	err := c.call(ctx, method, params, result)
+	metrics.ObserveRequest(method, time.Since(start), err != nil)
+	return err
+}
+
+func (c *Client) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	select {
+	case c.inFlight <- struct{}{}:
+		defer func() { <-c.inFlight }()
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.done:
+		return errConnectionClosed
+	}
+
 	id := c.nextID.Add(1)
 
 	if debug {
@@ -198,7 +313,7 @@ func (c *Client) Call(ctx context.Context, method string, params interface{}, re
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	// Create response channel
+	// Create response channel, buffered so the reader never blocks on a caller that gave up
 	ch := make(chan *Message, 1)
 	c.handlersMu.Lock()
 	c.handlers[id] = ch
@@ -211,7 +326,7 @@ func (c *Client) Call(ctx context.Context, method string, params interface{}, re
 	}()
 
 	// Send request
-	if err := WriteMessage(c.stdin, msg); err != nil {
+	if err := c.writeMessage(msg); err != nil {
 		return fmt.Errorf("failed to send request: %w", err)
 	}
 
@@ -220,14 +335,29 @@ func (c *Client) Call(ctx context.Context, method string, params interface{}, re
 	}
 
 	// Wait for response
-	resp := <-ch
+	sent := time.Now()
+	var resp *Message
+	select {
+	case resp = <-ch:
+		c.load.observeResponse(time.Since(sent))
+	case <-ctx.Done():
+		// The server may still answer, the response is then dropped by handleMessages
+		if err := c.Notify(context.Background(), "$/cancelRequest", protocol.CancelParams{ID: id}); err != nil && debug {
+			log.Printf("Failed to cancel request %d: %v", id, err)
+		}
+		return fmt.Errorf("%s cancelled: %w", method, ctx.Err())
+	case <-c.done:
+		c.lastError.record("%s: %v", method, errConnectionClosed)
+		return errConnectionClosed
+	}
 
 	if debug {
 		log.Printf("Received response for request ID: %d", id)
 	}
 
 	if resp.Error != nil {
-		return fmt.Errorf("request failed: %s (code: %d)", resp.Error.Message, resp.Error.Code)
+		c.lastError.record("%s: %s (code: %d)", method, resp.Error.Message, resp.Error.Code)
+		return fmt.Errorf("%s%w", requestFailedPrefix, resp.Error)
 	}
 
 	if result != nil {
@@ -256,12 +386,25 @@ func (c *Client) Notify(ctx context.Context, method string, params interface{})
 		return fmt.Errorf("failed to create notification: %w", err)
 	}
 
-	if err := WriteMessage(c.stdin, msg); err != nil {
+	if err := c.writeMessage(msg); err != nil {
 		return fmt.Errorf("failed to send notification: %w", err)
 	}
 
 	return nil
 }
 
+// writeMessage writes a message to the server's stdin, one message at a time
+func (c *Client) writeMessage(msg *Message) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	// Requests are resent on retries, the original must keep this server's URIs
+	translated := *msg
+	translateMessage(&translated, true)
+	start := time.Now()
+	err := WriteMessage(c.stdin, &translated)
+	c.load.observeWrite(time.Since(start))
+	return err
+}
+
 type NotificationHandler func(params json.RawMessage)
 type ServerRequestHandler func(params json.RawMessage) (interface{}, error)
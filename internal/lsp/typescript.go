@@ -0,0 +1,51 @@
+package lsp
+
+import (
+	"strings"
+	"sync"
+)
+
+// TypeScriptPlugin is a tsserver plugin typescript-language-server loads for
+// every project, next to those listed in the projects' tsconfig.json
+type TypeScriptPlugin struct {
+	Name string `json:"name"`
+	// Location is the directory whose node_modules holds the plugin
+	Location string `json:"location"`
+}
+
+var (
+	typescriptPlugins   []TypeScriptPlugin
+	typescriptPluginsMu sync.RWMutex
+)
+
+// ParseTypeScriptPlugin parses a plugin given as "name" or "name=location"
+func ParseTypeScriptPlugin(spec string) TypeScriptPlugin {
+	name, location, _ := strings.Cut(spec, "=")
+	return TypeScriptPlugin{Name: strings.TrimSpace(name), Location: strings.TrimSpace(location)}
+}
+
+// SetTypeScriptPlugins sets the tsserver plugins passed to typescript-language-server
+func SetTypeScriptPlugins(plugins []TypeScriptPlugin) {
+	typescriptPluginsMu.Lock()
+	defer typescriptPluginsMu.Unlock()
+	typescriptPlugins = plugins
+}
+
+// typescriptInitializationOptions passes the plugins set on the command line.
+// Plugins without a location are looked up in the workspace's node_modules.
+func typescriptInitializationOptions(workspaceDir string, args []string) map[string]interface{} {
+	typescriptPluginsMu.RLock()
+	defer typescriptPluginsMu.RUnlock()
+	if len(typescriptPlugins) == 0 {
+		return nil
+	}
+
+	plugins := make([]TypeScriptPlugin, len(typescriptPlugins))
+	for i, plugin := range typescriptPlugins {
+		if plugin.Location == "" {
+			plugin.Location = workspaceDir
+		}
+		plugins[i] = plugin
+	}
+	return map[string]interface{}{"plugins": plugins}
+}
@@ -0,0 +1,233 @@
+// Package memory keeps the server's caches within a shared byte budget. Caches
+// register with a Manager, which evicts their least recently used entries, oldest
+// first across all caches, whenever their combined size exceeds the budget.
+package memory
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Cache is a cache whose entries the manager may evict
+type Cache interface {
+	// Name identifies the cache in statistics and clear requests
+	Name() string
+	// Usage returns the number of entries and their approximate size in bytes
+	Usage() (entries int, bytes int64)
+	// Oldest returns the tick of the least recently used entry that may be
+	// evicted, false when there is none
+	Oldest() (uint64, bool)
+	// EvictOldest removes the least recently used entry that may be evicted and
+	// returns its size, false when there is none
+	EvictOldest() (int64, bool)
+}
+
+var tick atomic.Uint64
+
+// Tick returns a counter that increases on every call. Caches record it when an
+// entry is used, so recency can be compared between caches.
+func Tick() uint64 {
+	return tick.Add(1)
+}
+
+// Stats describes one registered cache
+type Stats struct {
+	Name      string
+	Entries   int
+	Bytes     int64
+	Evictions uint64
+}
+
+// Manager evicts entries from registered caches to keep them under a budget
+type Manager struct {
+	mu        sync.Mutex
+	budget    int64
+	caches    []Cache
+	evictions map[string]uint64
+
+	// Signals the enforcing goroutine, see Changed
+	wake  chan struct{}
+	start sync.Once
+	// Held while evicting, so a clear and an enforcement do not interleave
+	evictMu sync.Mutex
+}
+
+// NewManager creates a manager without a budget
+func NewManager() *Manager {
+	return &Manager{
+		evictions: make(map[string]uint64),
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Default is the manager the server's caches register with
+var Default = NewManager()
+
+// SetBudget sets the combined size the caches are kept under, 0 for no limit
+func (m *Manager) SetBudget(bytes int64) {
+	m.mu.Lock()
+	m.budget = max(bytes, 0)
+	m.mu.Unlock()
+	m.Changed()
+}
+
+// Budget returns the budget in bytes, 0 when there is no limit
+func (m *Manager) Budget() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.budget
+}
+
+// Register adds a cache, replacing a registered cache of the same name
+func (m *Manager) Register(cache Cache) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, registered := range m.caches {
+		if registered.Name() == cache.Name() {
+			m.caches[i] = cache
+			return
+		}
+	}
+	m.caches = append(m.caches, cache)
+}
+
+// Unregister removes the cache with the given name
+func (m *Manager) Unregister(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, registered := range m.caches {
+		if registered.Name() == name {
+			m.caches = append(m.caches[:i], m.caches[i+1:]...)
+			return
+		}
+	}
+}
+
+// Changed tells the manager that a cache grew. The budget is enforced in the
+// background, so caches may call it while holding their own locks.
+func (m *Manager) Changed() {
+	m.start.Do(func() {
+		go func() {
+			for range m.wake {
+				m.Enforce()
+			}
+		}()
+	})
+	select {
+	case m.wake <- struct{}{}:
+	default: // An enforcement is already pending
+	}
+}
+
+// Enforce evicts the least recently used entries across caches until their
+// combined size is within the budget, or nothing more can be evicted. The caller
+// must not hold any registered cache's locks.
+func (m *Manager) Enforce() {
+	m.evictMu.Lock()
+	defer m.evictMu.Unlock()
+
+	budget, caches := m.snapshot()
+	if budget == 0 {
+		return
+	}
+	used := int64(0)
+	for _, cache := range caches {
+		_, bytes := cache.Usage()
+		used += bytes
+	}
+	for used > budget {
+		var oldestCache Cache
+		var oldest uint64
+		for _, cache := range caches {
+			if t, ok := cache.Oldest(); ok && (oldestCache == nil || t < oldest) {
+				oldestCache, oldest = cache, t
+			}
+		}
+		if oldestCache == nil {
+			return // Everything left is in use
+		}
+		freed, ok := oldestCache.EvictOldest()
+		if !ok {
+			return
+		}
+		m.countEviction(oldestCache.Name(), 1)
+		used -= freed
+	}
+}
+
+// Clear evicts every entry that may be evicted from the named caches, or from all
+// caches when no names are given, and returns the statistics of what was removed
+func (m *Manager) Clear(names ...string) []Stats {
+	m.evictMu.Lock()
+	defer m.evictMu.Unlock()
+
+	_, caches := m.snapshot()
+	var cleared []Stats
+	for _, cache := range caches {
+		if len(names) > 0 && !contains(names, cache.Name()) {
+			continue
+		}
+		stats := Stats{Name: cache.Name()}
+		for {
+			freed, ok := cache.EvictOldest()
+			if !ok {
+				break
+			}
+			stats.Entries++
+			stats.Bytes += freed
+		}
+		m.countEviction(cache.Name(), uint64(stats.Entries))
+		cleared = append(cleared, stats)
+	}
+	return cleared
+}
+
+// Stats returns the usage of every registered cache, sorted by name
+func (m *Manager) Stats() []Stats {
+	_, caches := m.snapshot()
+	stats := make([]Stats, 0, len(caches))
+	for _, cache := range caches {
+		entries, bytes := cache.Usage()
+		m.mu.Lock()
+		evictions := m.evictions[cache.Name()]
+		m.mu.Unlock()
+		stats = append(stats, Stats{Name: cache.Name(), Entries: entries, Bytes: bytes, Evictions: evictions})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// Names returns the names of the registered caches
+func (m *Manager) Names() []string {
+	_, caches := m.snapshot()
+	names := make([]string, len(caches))
+	for i, cache := range caches {
+		names[i] = cache.Name()
+	}
+	sort.Strings(names)
+	return names
+}
+
+// snapshot returns the budget and a copy of the registered caches, so caches are
+// called without holding mu
+func (m *Manager) snapshot() (int64, []Cache) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.budget, append([]Cache(nil), m.caches...)
+}
+
+func (m *Manager) countEviction(name string, n uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.evictions[name] += n
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
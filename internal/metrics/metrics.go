@@ -0,0 +1,219 @@
+// Package metrics keeps in-process counters and latency totals for tool calls,
+// language server requests, caches and watcher events.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Families of timed operations
+const (
+	Tool       = "tool"        // MCP tool calls, by tool name
+	LSPRequest = "lsp_request" // Requests to the language server, by method
+)
+
+// Families of counters
+const (
+	CacheHit     = "cache_hit"     // Lookups answered from a cache, by cache name
+	CacheMiss    = "cache_miss"    // Lookups a cache could not answer, by cache name
+	WatcherEvent = "watcher_event" // File events sent to handlers, by change type
+)
+
+// Timing accumulates the latency of one kind of operation
+type Timing struct {
+	Count  uint64
+	Errors uint64
+	Total  time.Duration
+	Max    time.Duration
+}
+
+// Mean returns the average latency, zero if nothing was observed
+func (t Timing) Mean() time.Duration {
+	if t.Count == 0 {
+		return 0
+	}
+	return t.Total / time.Duration(t.Count)
+}
+
+// Registry holds timings and counters keyed by family and label
+type Registry struct {
+	start time.Time
+
+	mu       sync.Mutex
+	timings  map[string]map[string]*Timing
+	counters map[string]map[string]uint64
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		start:    time.Now(),
+		timings:  make(map[string]map[string]*Timing),
+		counters: make(map[string]map[string]uint64),
+	}
+}
+
+// Default is the registry the package level functions record to
+var Default = NewRegistry()
+
+// Observe records one operation of a family taking d
+func (r *Registry) Observe(family, label string, d time.Duration, failed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.timings[family] == nil {
+		r.timings[family] = make(map[string]*Timing)
+	}
+	t := r.timings[family][label]
+	if t == nil {
+		t = &Timing{}
+		r.timings[family][label] = t
+	}
+	t.Count++
+	if failed {
+		t.Errors++
+	}
+	t.Total += d
+	t.Max = max(t.Max, d)
+}
+
+// Add increments a counter
+func (r *Registry) Add(family, label string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.counters[family] == nil {
+		r.counters[family] = make(map[string]uint64)
+	}
+	r.counters[family][label]++
+}
+
+// Snapshot is a copy of a registry's values
+type Snapshot struct {
+	Uptime   time.Duration
+	Timings  map[string]map[string]Timing
+	Counters map[string]map[string]uint64
+}
+
+// Snapshot copies the current values
+func (r *Registry) Snapshot() Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := Snapshot{
+		Uptime:   time.Since(r.start),
+		Timings:  make(map[string]map[string]Timing),
+		Counters: make(map[string]map[string]uint64),
+	}
+	for family, labels := range r.timings {
+		s.Timings[family] = make(map[string]Timing)
+		for label, t := range labels {
+			s.Timings[family][label] = *t
+		}
+	}
+	for family, labels := range r.counters {
+		s.Counters[family] = make(map[string]uint64)
+		for label, n := range labels {
+			s.Counters[family][label] = n
+		}
+	}
+	return s
+}
+
+// Labels returns the labels of a family in a snapshot, sorted
+func Labels[V any](values map[string]V) []string {
+	labels := make([]string, 0, len(values))
+	for label := range values {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// WritePrometheus writes the registry in the Prometheus text exposition format
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	s := r.Snapshot()
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# TYPE mcp_language_server_uptime_seconds gauge\n")
+	fmt.Fprintf(&b, "mcp_language_server_uptime_seconds %g\n", s.Uptime.Seconds())
+
+	for _, family := range Labels(s.Timings) {
+		name := "mcp_language_server_" + family
+		labelName := timingLabelNames[family]
+		if labelName == "" {
+			labelName = "name"
+		}
+		labels := Labels(s.Timings[family])
+		writeFamily := func(metric, kind string, value func(t Timing) string) {
+			fmt.Fprintf(&b, "# TYPE %s %s\n", metric, kind)
+			for _, label := range labels {
+				fmt.Fprintf(&b, "%s{%s=%q} %s\n", metric, labelName, label, value(s.Timings[family][label]))
+			}
+		}
+		writeFamily(name+"_total", "counter", func(t Timing) string { return fmt.Sprint(t.Count) })
+		writeFamily(name+"_errors_total", "counter", func(t Timing) string { return fmt.Sprint(t.Errors) })
+		fmt.Fprintf(&b, "# TYPE %s_duration_seconds summary\n", name)
+		for _, label := range labels {
+			t := s.Timings[family][label]
+			fmt.Fprintf(&b, "%s_duration_seconds_sum{%s=%q} %g\n", name, labelName, label, t.Total.Seconds())
+			fmt.Fprintf(&b, "%s_duration_seconds_count{%s=%q} %d\n", name, labelName, label, t.Count)
+		}
+	}
+
+	for _, family := range Labels(s.Counters) {
+		name := "mcp_language_server_" + family + "_total"
+		labelName := counterLabelNames[family]
+		if labelName == "" {
+			labelName = "name"
+		}
+		fmt.Fprintf(&b, "# TYPE %s counter\n", name)
+		for _, label := range Labels(s.Counters[family]) {
+			fmt.Fprintf(&b, "%s{%s=%q} %d\n", name, labelName, label, s.Counters[family][label])
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var timingLabelNames = map[string]string{
+	Tool:       "tool",
+	LSPRequest: "method",
+}
+
+var counterLabelNames = map[string]string{
+	CacheHit:     "cache",
+	CacheMiss:    "cache",
+	WatcherEvent: "type",
+}
+
+// ObserveTool records an MCP tool call
+func ObserveTool(name string, d time.Duration, failed bool) {
+	Default.Observe(Tool, name, d, failed)
+}
+
+// ObserveRequest records a request to the language server
+func ObserveRequest(method string, d time.Duration, failed bool) {
+	Default.Observe(LSPRequest, method, d, failed)
+}
+
+// Hit records a lookup answered by a cache
+func Hit(cache string) {
+	Default.Add(CacheHit, cache)
+}
+
+// Miss records a lookup a cache could not answer
+func Miss(cache string) {
+	Default.Add(CacheMiss, cache)
+}
+
+// Event records a file event of the given type from the watcher
+func Event(changeType string) {
+	Default.Add(WatcherEvent, changeType)
+}
@@ -1,15 +1,23 @@
 package protocol
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
 
 // TextEditResult is an interface for types that represent workspace symbols
 type WorkspaceSymbolResult interface {
 	GetName() string
 	GetLocation() Location
+	GetKind() SymbolKind
+	GetContainerName() string
 	isWorkspaceSymbol() // marker method
 }
 
-func (ws *WorkspaceSymbol) GetName() string { return ws.Name }
+func (ws *WorkspaceSymbol) GetName() string          { return ws.Name }
+func (ws *WorkspaceSymbol) GetKind() SymbolKind      { return ws.Kind }
+func (ws *WorkspaceSymbol) GetContainerName() string { return ws.ContainerName }
 func (ws *WorkspaceSymbol) GetLocation() Location {
 	switch v := ws.Location.Value.(type) {
 	case Location:
@@ -21,9 +29,11 @@ func (ws *WorkspaceSymbol) GetLocation() Location {
 }
 func (ws *WorkspaceSymbol) isWorkspaceSymbol() {}
 
-func (si *SymbolInformation) GetName() string       { return si.Name }
-func (si *SymbolInformation) GetLocation() Location { return si.Location }
-func (si *SymbolInformation) isWorkspaceSymbol()    {}
+func (si *SymbolInformation) GetName() string          { return si.Name }
+func (si *SymbolInformation) GetLocation() Location    { return si.Location }
+func (si *SymbolInformation) GetKind() SymbolKind      { return si.Kind }
+func (si *SymbolInformation) GetContainerName() string { return si.ContainerName }
+func (si *SymbolInformation) isWorkspaceSymbol()       {}
 
 // Results converts the Value to a slice of WorkspaceSymbolResult
 func (r Or_Result_workspace_symbol) Results() ([]WorkspaceSymbolResult, error) {
@@ -52,16 +62,18 @@ func (r Or_Result_workspace_symbol) Results() ([]WorkspaceSymbolResult, error) {
 type DocumentSymbolResult interface {
 	GetRange() Range
 	GetName() string
+	GetKind() SymbolKind
 	isDocumentSymbol() // marker method
 }
 
-func (ds *DocumentSymbol) GetRange() Range   { return ds.Range }
-func (ds *DocumentSymbol) GetName() string   { return ds.Name }
-func (ds *DocumentSymbol) isDocumentSymbol() {}
+func (ds *DocumentSymbol) GetRange() Range     { return ds.Range }
+func (ds *DocumentSymbol) GetName() string     { return ds.Name }
+func (ds *DocumentSymbol) GetKind() SymbolKind { return ds.Kind }
+func (ds *DocumentSymbol) isDocumentSymbol()   {}
 
 func (si *SymbolInformation) GetRange() Range { return si.Location.Range }
 
-// Note: SymbolInformation already has GetName() implemented above
+// Note: SymbolInformation already has GetName() and GetKind() implemented above
 func (si *SymbolInformation) isDocumentSymbol() {}
 
 // Results converts the Value to a slice of DocumentSymbolResult
@@ -77,9 +89,11 @@ func (r Or_Result_textDocument_documentSymbol) Results() ([]DocumentSymbolResult
 		}
 		return results, nil
 	case []SymbolInformation:
-		results := make([]DocumentSymbolResult, len(v))
-		for i := range v {
-			results[i] = &v[i]
+		// Flat results are nested so callers can rely on the hierarchy
+		symbols := BuildSymbolHierarchy(v)
+		results := make([]DocumentSymbolResult, len(symbols))
+		for i := range symbols {
+			results[i] = &symbols[i]
 		}
 		return results, nil
 	default:
@@ -115,3 +129,127 @@ func (e Or_TextDocumentEdit_edits_Elem) AsTextEdit() (TextEdit, error) {
 		return TextEdit{}, fmt.Errorf("unknown text edit type: %T", e.Value)
 	}
 }
+
+// locationsFromValue flattens the possible shapes of a definition-like result into locations.
+// LocationLinks are converted using their target selection range when it is set.
+func locationsFromValue(value interface{}) ([]Location, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case Location:
+		return []Location{v}, nil
+	case []Location:
+		return v, nil
+	case Or_Definition:
+		return locationsFromValue(v.Value)
+	case Or_Declaration:
+		return locationsFromValue(v.Value)
+	case []LocationLink:
+		locations := make([]Location, 0, len(v))
+		for _, link := range v {
+			if link.TargetURI == "" {
+				continue
+			}
+			targetRange := link.TargetSelectionRange
+			if targetRange == (Range{}) {
+				targetRange = link.TargetRange
+			}
+			locations = append(locations, Location{URI: link.TargetURI, Range: targetRange})
+		}
+		return locations, nil
+	default:
+		return nil, fmt.Errorf("unknown location type: %T", value)
+	}
+}
+
+// Locations converts the Value to a slice of Location
+func (r Or_Result_textDocument_definition) Locations() ([]Location, error) {
+	return locationsFromValue(r.Value)
+}
+
+// Locations converts the Value to a slice of Location
+func (r Or_Result_textDocument_implementation) Locations() ([]Location, error) {
+	return locationsFromValue(r.Value)
+}
+
+// Locations converts the Value to a slice of Location
+func (r Or_Result_textDocument_typeDefinition) Locations() ([]Location, error) {
+	return locationsFromValue(r.Value)
+}
+
+// Locations converts the Value to a slice of Location
+func (r Or_Result_textDocument_declaration) Locations() ([]Location, error) {
+	return locationsFromValue(r.Value)
+}
+
+// HoverContents decodes the contents of a textDocument/hover result to a single
+// MarkupContent, empty when the result is null. Besides the MarkupContent, MarkedString
+// and MarkedString[] shapes of the protocol, it accepts the lists mixing MarkupContent
+// and MarkedStrings and the extra fields some servers send. Several parts are joined
+// as markdown, with language-tagged strings rendered as fenced code blocks.
+func HoverContents(result json.RawMessage) (MarkupContent, error) {
+	var hover struct {
+		Contents interface{} `json:"contents"`
+	}
+	if len(result) == 0 || string(result) == "null" {
+		return MarkupContent{}, nil
+	}
+	if err := json.Unmarshal(result, &hover); err != nil {
+		return MarkupContent{}, fmt.Errorf("invalid hover result: %w", err)
+	}
+
+	parts, err := hoverParts(hover.Contents)
+	if err != nil {
+		return MarkupContent{}, err
+	}
+	switch len(parts) {
+	case 0:
+		return MarkupContent{}, nil
+	case 1:
+		return parts[0], nil
+	}
+	values := make([]string, len(parts))
+	for i, part := range parts {
+		values[i] = part.Value
+	}
+	return MarkupContent{Kind: Markdown, Value: strings.Join(values, "\n\n")}, nil
+}
+
+// hoverParts flattens hover contents to their non-empty parts
+func hoverParts(contents interface{}) ([]MarkupContent, error) {
+	switch v := contents.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		// A MarkedString without a language is markdown
+		if strings.TrimSpace(v) == "" {
+			return nil, nil
+		}
+		return []MarkupContent{{Kind: Markdown, Value: v}}, nil
+	case []interface{}:
+		var parts []MarkupContent
+		for _, item := range v {
+			itemParts, err := hoverParts(item)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, itemParts...)
+		}
+		return parts, nil
+	case map[string]interface{}:
+		value, _ := v["value"].(string)
+		if strings.TrimSpace(value) == "" {
+			return nil, nil
+		}
+		if kind, ok := v["kind"].(string); ok {
+			if kind != string(PlainText) {
+				kind = string(Markdown)
+			}
+			return []MarkupContent{{Kind: MarkupKind(kind), Value: value}}, nil
+		}
+		language, _ := v["language"].(string)
+		return []MarkupContent{{Kind: Markdown, Value: "```" + language + "\n" + value + "\n```"}}, nil
+	default:
+		return nil, fmt.Errorf("unknown hover contents type: %T", contents)
+	}
+}
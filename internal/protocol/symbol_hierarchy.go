@@ -0,0 +1,124 @@
+package protocol
+
+import (
+	"sort"
+	"strings"
+)
+
+// symbolNode is a DocumentSymbol under construction
+type symbolNode struct {
+	symbol   DocumentSymbol
+	children []*symbolNode
+}
+
+// BuildSymbolHierarchy nests flat SymbolInformation results into DocumentSymbols.
+// A symbol's parent is the innermost symbol whose range contains it, or the nearest
+// symbol named by its containerName. Servers that report only the range of the
+// name have their parents' ranges extended to cover their children.
+func BuildSymbolHierarchy(infos []SymbolInformation) []DocumentSymbol {
+	sorted := make([]SymbolInformation, len(infos))
+	copy(sorted, infos)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].Location.Range, sorted[j].Location.Range
+		if a.Start != b.Start {
+			return positionBefore(a.Start, b.Start)
+		}
+		// Enclosing symbols come before the symbols they contain
+		return positionBefore(b.End, a.End)
+	})
+
+	var roots []*symbolNode
+	var stack []*symbolNode                // Symbols whose range may contain the next symbol
+	byName := make(map[string]*symbolNode) // Most recent symbol with each name
+
+	for _, info := range sorted {
+		node := &symbolNode{symbol: DocumentSymbol{
+			Name:           info.Name,
+			Kind:           info.Kind,
+			Tags:           info.Tags,
+			Deprecated:     info.Deprecated,
+			Range:          info.Location.Range,
+			SelectionRange: info.Location.Range,
+		}}
+
+		for len(stack) > 0 && !rangeContains(stack[len(stack)-1].symbol.Range, node.symbol.Range) {
+			stack = stack[:len(stack)-1]
+		}
+
+		var parent *symbolNode
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1]
+		}
+		if info.ContainerName != "" && (parent == nil || !containerMatches(info.ContainerName, parent.symbol.Name)) {
+			// Prefer the container the server named over plain range containment
+			for i := len(stack) - 1; i >= 0; i-- {
+				if containerMatches(info.ContainerName, stack[i].symbol.Name) {
+					parent = stack[i]
+					stack = stack[:i+1]
+					break
+				}
+			}
+			if parent == nil || !containerMatches(info.ContainerName, parent.symbol.Name) {
+				if named, ok := byName[containerKey(info.ContainerName)]; ok {
+					parent = named
+				}
+			}
+		}
+
+		if parent != nil {
+			parent.children = append(parent.children, node)
+		} else {
+			roots = append(roots, node)
+		}
+		stack = append(stack, node)
+		byName[info.Name] = node
+	}
+
+	result := make([]DocumentSymbol, len(roots))
+	for i, root := range roots {
+		result[i] = root.build()
+	}
+	return result
+}
+
+// build converts the node and its children, extending its range over its children
+func (n *symbolNode) build() DocumentSymbol {
+	symbol := n.symbol
+	for _, child := range n.children {
+		built := child.build()
+		if positionBefore(built.Range.Start, symbol.Range.Start) {
+			symbol.Range.Start = built.Range.Start
+		}
+		if positionBefore(symbol.Range.End, built.Range.End) {
+			symbol.Range.End = built.Range.End
+		}
+		symbol.Children = append(symbol.Children, built)
+	}
+	return symbol
+}
+
+// containerMatches reports whether a containerName refers to a symbol name.
+// Container names may be qualified (e.g. "pkg.Outer.Inner").
+func containerMatches(containerName, name string) bool {
+	return containerName == name || containerKey(containerName) == name
+}
+
+// containerKey returns the last component of a qualified container name
+func containerKey(containerName string) string {
+	if i := strings.LastIndexAny(containerName, ".:/"); i >= 0 && i < len(containerName)-1 {
+		return containerName[i+1:]
+	}
+	return containerName
+}
+
+func positionBefore(a, b Position) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Character < b.Character
+}
+
+// rangeContains reports whether outer contains inner, boundaries included
+func rangeContains(outer, inner Range) bool {
+	return !positionBefore(inner.Start, outer.Start) && !positionBefore(outer.End, inner.End)
+}
@@ -58,8 +58,8 @@ type URI = string
 // where there is no pointer of type *K or *V on which to call
 // UnmarshalJSON. (See Go issue #28189 for more detail.)
 //
-// Non-empty DocumentUris are valid "file"-scheme URIs.
-// The empty DocumentUri is valid.
+// Non-empty DocumentUris are valid "file"-scheme URIs, or URIs of other
+// schemes, which are left unchanged. The empty DocumentUri is valid.
 func (uri *DocumentUri) UnmarshalText(data []byte) (err error) {
 	*uri, err = ParseDocumentUri(string(data))
 	return
@@ -145,7 +145,12 @@ func ParseDocumentUri(s string) (DocumentUri, error) {
 	}
 
 	if !strings.HasPrefix(s, "file://") {
-		return "", fmt.Errorf("DocumentUri scheme is not 'file': %s", s)
+		// Documents outside the file system, such as classes inside a jar, are
+		// kept as they are, see lsp.IsExternal
+		if u, err := url.Parse(s); err == nil && u.Scheme != "" {
+			return DocumentUri(s), nil
+		}
+		return "", fmt.Errorf("DocumentUri has no scheme: %s", s)
 	}
 
 	// VS Code sends URLs with only two slashes,
@@ -0,0 +1,128 @@
+// Package text extracts the text of documents addressed by LSP positions. Lines
+// end with \n or \r\n, and columns count UTF-16 code units as the protocol
+// requires by default.
+package text
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// Lines splits content into lines without their line terminators
+func Lines(content []byte) []string {
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
+// ByteOffset converts a UTF-16 column into a byte offset in line. Columns past the
+// end of the line give its length, and a column inside a surrogate pair gives the
+// offset of its character.
+func ByteOffset(line string, character uint32) int {
+	column := uint32(0)
+	for i, r := range line {
+		width := uint32(1)
+		if r >= 0x10000 {
+			width = 2
+		}
+		if column+width > character {
+			return i
+		}
+		column += width
+	}
+	return len(line)
+}
+
+// Column converts a byte offset in line into a UTF-16 column. Offsets past the end
+// of the line give the column after its last character.
+func Column(line string, offset int) uint32 {
+	column := uint32(0)
+	for i, r := range line {
+		if i >= offset {
+			break
+		}
+		if r >= 0x10000 {
+			column += 2
+		} else {
+			column++
+		}
+	}
+	return column
+}
+
+// Range returns the text of rng in lines, joined with \n. Lines outside the
+// document are an error, columns past the end of their line are clamped to it.
+func Range(lines []string, rng protocol.Range) (string, error) {
+	startLine, endLine := int(rng.Start.Line), int(rng.End.Line)
+	if startLine >= len(lines) || endLine >= len(lines) || startLine > endLine {
+		return "", fmt.Errorf("invalid range: lines %d-%d (document has %d lines)", startLine+1, endLine+1, len(lines))
+	}
+
+	start := ByteOffset(lines[startLine], rng.Start.Character)
+	end := ByteOffset(lines[endLine], rng.End.Character)
+	if startLine == endLine {
+		return lines[startLine][min(start, end):end], nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(lines[startLine][start:])
+	for i := startLine + 1; i < endLine; i++ {
+		sb.WriteString("\n")
+		sb.WriteString(lines[i])
+	}
+	sb.WriteString("\n")
+	sb.WriteString(lines[endLine][:end])
+	return sb.String(), nil
+}
+
+// Extract returns the text of rng in content, see Range
+func Extract(content []byte, rng protocol.Range) (string, error) {
+	return Range(Lines(content), rng)
+}
+
+// ReadRange returns the text of rng in a file, see Range. The file is read line
+// by line and only up to the end of the range, so ranges near the start of large
+// files are cheap.
+func ReadRange(path string, rng protocol.Range) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	defer file.Close()
+
+	var lines []string
+	reader := bufio.NewReader(file)
+	for len(lines) <= int(rng.End.Line) {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", fmt.Errorf("failed to read file: %w", err)
+		}
+		lines = append(lines, strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"))
+		if err == io.EOF {
+			break
+		}
+	}
+	return Range(lines, rng)
+}
+
+// Around returns the lines from context lines before line to context lines after
+// it, clamped to the document, and the range they cover
+func Around(lines []string, line int, context int) (string, protocol.Range, error) {
+	if line < 0 || line >= len(lines) {
+		return "", protocol.Range{}, fmt.Errorf("line %d is out of bounds (document has %d lines)", line+1, len(lines))
+	}
+	start := max(line-context, 0)
+	end := min(line+context, len(lines)-1)
+	rng := protocol.Range{
+		Start: protocol.Position{Line: uint32(start)},
+		End:   protocol.Position{Line: uint32(end), Character: Column(lines[end], len(lines[end]))},
+	}
+	return strings.Join(lines[start:end+1], "\n"), rng, nil
+}
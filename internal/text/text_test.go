@@ -0,0 +1,120 @@
+package text
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+func rng(startLine, startChar, endLine, endChar uint32) protocol.Range {
+	return protocol.Range{
+		Start: protocol.Position{Line: startLine, Character: startChar},
+		End:   protocol.Position{Line: endLine, Character: endChar},
+	}
+}
+
+func TestLines(t *testing.T) {
+	got := Lines([]byte("a\r\nb\nc\r\n"))
+	want := []string{"a", "b", "c", ""}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("Lines = %q, want %q", got, want)
+	}
+}
+
+func TestByteOffsetAndColumn(t *testing.T) {
+	// "é" is two bytes and one UTF-16 unit, "😀" four bytes and two units
+	line := "aé😀b"
+	tests := []struct {
+		character uint32
+		offset    int
+	}{
+		{0, 0},
+		{1, 1},
+		{2, 3},
+		{4, 7},
+		{5, 8},
+		{9, 8},
+	}
+	for _, tt := range tests {
+		if got := ByteOffset(line, tt.character); got != tt.offset {
+			t.Errorf("ByteOffset(%q, %d) = %d, want %d", line, tt.character, got, tt.offset)
+		}
+		if tt.character <= 5 {
+			if got := Column(line, tt.offset); got != tt.character {
+				t.Errorf("Column(%q, %d) = %d, want %d", line, tt.offset, got, tt.character)
+			}
+		}
+	}
+	// Inside the surrogate pair of 😀
+	if got := ByteOffset(line, 3); got != 3 {
+		t.Errorf("ByteOffset(%q, 3) = %d, want 3", line, got)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	content := []byte("func é() {\r\n\treturn 😀x\r\n}\n")
+	tests := []struct {
+		name    string
+		rng     protocol.Range
+		want    string
+		wantErr bool
+	}{
+		{"single line", rng(0, 5, 0, 6), "é", false},
+		{"after surrogate pair", rng(1, 10, 1, 11), "x", false},
+		{"multi-line", rng(0, 9, 2, 1), "{\n\treturn 😀x\n}", false},
+		{"columns clamped", rng(2, 0, 2, 40), "}", false},
+		{"reversed columns", rng(0, 4, 0, 2), "", false},
+		{"line past end", rng(0, 0, 9, 0), "", true},
+		{"reversed lines", rng(2, 0, 1, 0), "", true},
+	}
+	for _, tt := range tests {
+		got, err := Extract(content, tt.rng)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Extract error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: Extract = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadRange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.go")
+	content := "package a\r\n\r\nvar x = \"é\"\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range []protocol.Range{rng(2, 4, 2, 11), rng(0, 0, 2, 3), rng(3, 0, 3, 0)} {
+		got, err := ReadRange(path, r)
+		if err != nil {
+			t.Fatalf("ReadRange(%v): %v", r, err)
+		}
+		want, _ := Extract([]byte(content), r)
+		if got != want {
+			t.Errorf("ReadRange(%v) = %q, want %q", r, got, want)
+		}
+	}
+	if _, err := ReadRange(path, rng(4, 0, 4, 0)); err == nil {
+		t.Errorf("ReadRange past the end of the file succeeded")
+	}
+}
+
+func TestAround(t *testing.T) {
+	lines := []string{"a", "b", "c", "d😀"}
+	got, r, err := Around(lines, 2, 1)
+	if err != nil || got != "b\nc\nd😀" || r != rng(1, 0, 3, 3) {
+		t.Errorf("Around(2, 1) = %q, %v, %v", got, r, err)
+	}
+	got, r, err = Around(lines, 0, 10)
+	if err != nil || got != "a\nb\nc\nd😀" || r != rng(0, 0, 3, 3) {
+		t.Errorf("Around(0, 10) = %q, %v, %v", got, r, err)
+	}
+	if _, _, err := Around(lines, 4, 1); err == nil {
+		t.Errorf("Around past the end succeeded")
+	}
+}
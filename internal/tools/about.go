@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+// AboutInfo describes this server's build and configuration
+type AboutInfo struct {
+	Version      string
+	WorkspaceDir string
+	LSPCommand   string
+}
+
+// versionFlagTimeout bounds running the language server binary to ask its version
+const versionFlagTimeout = 3 * time.Second
+
+var (
+	binaryVersions   = make(map[string]string)
+	binaryVersionsMu sync.Mutex
+)
+
+// About reports the versions of this server and the language server, the Go runtime
+// and the workspace, for bug reports
+func About(ctx context.Context, client *lsp.Client, info AboutInfo) (string, error) {
+	var result strings.Builder
+
+	fmt.Fprintf(&result, "mcp-language-server %s\n", info.Version)
+	fmt.Fprintf(&result, "  Go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&result, "  Workspace: %s\n", info.WorkspaceDir)
+
+	result.WriteString("\nLanguage server\n")
+	path, err := exec.LookPath(info.LSPCommand)
+	if err != nil {
+		path = info.LSPCommand
+	}
+	fmt.Fprintf(&result, "  Binary: %s\n", path)
+	// The arguments the server was started with, including those added for it
+	if args := client.Cmd.Args[1:]; len(args) > 0 {
+		fmt.Fprintf(&result, "  Arguments: %s\n", strings.Join(args, " "))
+	}
+	process := client.ProcessConfig()
+	fmt.Fprintf(&result, "  Working directory: %s\n", process.Dir)
+	for _, variable := range process.Env {
+		fmt.Fprintf(&result, "  Environment: %s\n", variable)
+	}
+	rss, measured := client.MemoryUsage()
+	switch {
+	case process.MaxMemory > 0 && measured:
+		fmt.Fprintf(&result, "  Memory: %d MB of the %d MB limit\n", rss>>20, process.MaxMemory>>20)
+	case process.MaxMemory > 0:
+		fmt.Fprintf(&result, "  Memory limit: %d MB\n", process.MaxMemory>>20)
+	case measured:
+		fmt.Fprintf(&result, "  Memory: %d MB, no limit\n", rss>>20)
+	}
+
+	serverInfo := client.ServerInfo()
+	if serverInfo != nil {
+		fmt.Fprintf(&result, "  Name: %s\n", serverInfo.Name)
+	}
+	switch {
+	case serverInfo != nil && serverInfo.Version != "":
+		fmt.Fprintf(&result, "  Version: %s\n", serverInfo.Version)
+	default:
+		if version := binaryVersion(ctx, path); version != "" {
+			fmt.Fprintf(&result, "  Version: %s\n", version)
+		} else {
+			result.WriteString("  Version: unknown (not in serverInfo and the binary did not report one)\n")
+		}
+	}
+
+	return result.String(), nil
+}
+
+// binaryVersion asks a language server binary for its version with --version,
+// or the version subcommand some servers such as gopls use instead. The result
+// is cached per binary.
+func binaryVersion(ctx context.Context, path string) string {
+	binaryVersionsMu.Lock()
+	version, ok := binaryVersions[path]
+	binaryVersionsMu.Unlock()
+	if ok {
+		return version
+	}
+
+	for _, args := range [][]string{{"--version"}, {"version"}} {
+		ctx, cancel := context.WithTimeout(ctx, versionFlagTimeout)
+		out, err := exec.CommandContext(ctx, path, args...).Output()
+		cancel()
+		if err != nil {
+			continue
+		}
+		// The first line is usually enough, e.g. "golang.org/x/tools/gopls v0.16.1"
+		if line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n"); line != "" {
+			version = strings.TrimSpace(line)
+			break
+		}
+	}
+
+	binaryVersionsMu.Lock()
+	binaryVersions[path] = version
+	binaryVersionsMu.Unlock()
+	return version
+}
@@ -0,0 +1,168 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/text"
+)
+
+// maxAliasDepth caps how many aliases in a row read_definition follows
+const maxAliasDepth = 5
+
+// maxAliasLines is the longest definition checked for being an alias, longer ones
+// are taken to be real declarations
+const maxAliasLines = 20
+
+// aliasIdentifier matches a possibly qualified name such as pkg.Bar or a::b::Bar
+const aliasIdentifier = `[A-Za-z_$][\w$]*(?:(?:\.|::)[A-Za-z_$][\w$]*)*`
+
+// aliasPatterns recognize declarations that only give another name to a symbol.
+// NAME stands for the alias, and the first group matches the symbol it names.
+var aliasPatterns = []string{
+	// Type aliases: Go "type Foo = Bar", TypeScript and Rust "type Foo<T> = Bar<T>"
+	`\btype\s+NAME\s*(?:\[[^\]]*\]|<[^>]*>)?\s*=\s*(?:typeof\s+)?(` + aliasIdentifier + `)`,
+	// Values bound to another symbol: "var Foo = pkg.Bar", "export const Foo = Bar", "Foo = Bar"
+	`(?m)^\s*(?:export\s+)?(?:(?:pub(?:\([^)]*\))?\s+)?(?:const|let|var|static)\s+)?NAME\s*(?::[^=\n]*)?=\s*(` + aliasIdentifier + `)\s*;?\s*(?://.*|#.*)?$`,
+	// TypeScript and JavaScript re-exports: "export { Bar as Foo } from './bar'"
+	`\bexport\s*(?:type\s*)?\{[^}]*?\b([A-Za-z_$][\w$]*)\s+as\s+NAME\b[^}]*\}\s*from\b`,
+	`\bexport\s*(?:type\s*)?\{[^}]*?(?:^|[\s,{])(NAME)\b[^}]*\}\s*from\b`,
+	// Rust re-exports: "pub use a::b::Bar as Foo;", "pub use a::b::Foo;"
+	`\buse\s+(?:[\w]+::)*(\w+)\s+as\s+NAME\s*;`,
+	`\buse\s+(?:[\w]+::)+(NAME)\s*;`,
+	// Python re-exports: "from a.b import Bar as Foo", "from a.b import Foo"
+	`\bfrom\s+\S+\s+import\s+[^\n]*?\b(\w+)\s+as\s+NAME\b`,
+	`\bfrom\s+\S+\s+import\s+[^\n]*?\b(NAME)\b`,
+}
+
+// aliasLiterals are names bound by value patterns that are not symbols to follow
+var aliasLiterals = map[string]bool{
+	"true": true, "false": true, "nil": true, "null": true, "undefined": true,
+	"None": true, "True": true, "False": true, "iota": true, "self": true, "this": true,
+}
+
+// followAliasChains returns the definitions with, after each one that is an alias,
+// the definitions it leads to, following up to depth aliases in a row. Targets
+// already in the list are not repeated.
+func followAliasChains(ctx context.Context, client *lsp.Client, definitions []DefinitionInfo, depth int) []DefinitionInfo {
+	depth = min(depth, maxAliasDepth)
+	seen := make(map[string]bool)
+	for _, def := range definitions {
+		seen[definitionKey(def)] = true
+	}
+
+	var result []DefinitionInfo
+	for _, def := range definitions {
+		result = append(result, def)
+		current := def
+		for i := 0; i < depth; i++ {
+			target, ok := followAlias(ctx, client, current)
+			if !ok || seen[definitionKey(target)] {
+				break
+			}
+			seen[definitionKey(target)] = true
+			result = append(result, target)
+			current = target
+		}
+	}
+	return result
+}
+
+// followAlias resolves the definition of the symbol an alias names, false if the
+// definition is not an alias or its target cannot be found
+func followAlias(ctx context.Context, client *lsp.Client, alias DefinitionInfo) (DefinitionInfo, bool) {
+	content, err := client.GetFileContent(alias.FilePath)
+	if err != nil {
+		return DefinitionInfo{}, false
+	}
+	lines := text.Lines(content)
+	position, targetName, ok := aliasTarget(lines, alias.Range, alias.SymbolName)
+	if !ok {
+		return DefinitionInfo{}, false
+	}
+	debugLogger.Printf("Definition of %s is an alias of %s, following it\n", alias.SymbolName, targetName)
+
+	uri := protocol.DocumentUri("file://" + alias.FilePath)
+	defResult, err := client.Definition(ctx, protocol.DefinitionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     position,
+		},
+	})
+	if err != nil {
+		debugLogger.Printf("Warning: textDocument/definition failed for alias target %s: %v\n", targetName, err)
+		return DefinitionInfo{}, false
+	}
+	value := defResult.Value
+	if nested, ok := value.(protocol.Or_Definition); ok {
+		value = nested.Value
+	}
+	locations, _ := extractDefinitionLocations(value)
+
+	for _, loc := range locations {
+		// A definition inside the alias itself, as servers give for unresolved or
+		// builtin names, leads nowhere
+		if loc.URI == "" || loc.URI == uri && containsPosition(alias.Range, loc.Range.Start) {
+			continue
+		}
+		target, ok := resolveDefinition(ctx, client, loc, targetName)
+		if !ok {
+			continue
+		}
+		target.AliasedBy = fmt.Sprintf("%s (%s:%d)", alias.SymbolName, alias.FilePath, alias.Range.Start.Line+1)
+		return target, true
+	}
+	return DefinitionInfo{}, false
+}
+
+// aliasTarget finds the symbol an alias definition names, returning the position
+// of its name and the name itself
+func aliasTarget(lines []string, rng protocol.Range, symbolName string) (protocol.Position, string, bool) {
+	start, end := int(rng.Start.Line), int(rng.End.Line)
+	if end >= len(lines) || end-start+1 > maxAliasLines {
+		return protocol.Position{}, "", false
+	}
+	source := strings.Join(lines[start:end+1], "\n")
+
+	name := normalizeReceiver(symbolName)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, pattern := range aliasPatterns {
+		re, err := regexp.Compile(strings.ReplaceAll(pattern, "NAME", regexp.QuoteMeta(name)))
+		if err != nil {
+			continue
+		}
+		match := re.FindStringSubmatchIndex(source)
+		if match == nil {
+			continue
+		}
+		// Point at the last part of a qualified target, pkg.Bar at Bar
+		target := source[match[2]:match[3]]
+		offset := match[2]
+		if i := strings.LastIndexAny(target, ".:"); i >= 0 {
+			offset += i + 1
+			target = target[i+1:]
+		}
+		if target == "" || aliasLiterals[target] {
+			continue
+		}
+
+		line := start + strings.Count(source[:offset], "\n")
+		lineStart := strings.LastIndex(source[:offset], "\n") + 1
+		return protocol.Position{
+			Line:      uint32(line),
+			Character: text.Column(lines[line], offset-lineStart),
+		}, target, true
+	}
+	return protocol.Position{}, "", false
+}
+
+// definitionKey identifies a definition by its file and start
+func definitionKey(def DefinitionInfo) string {
+	return fmt.Sprintf("%s:%d:%d", def.FilePath, def.Range.Start.Line, def.Range.Start.Character)
+}
@@ -34,6 +34,26 @@ func ApplyTextEdits(ctx context.Context, client *lsp.Client, filePath string, ed
 		return "", fmt.Errorf("could not open file: %v", err)
 	}
 
+	textEdits, err := toProtocolEdits(filePath, edits)
+	if err != nil {
+		return "", err
+	}
+
+	edit := protocol.WorkspaceEdit{
+		Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+			protocol.DocumentUri(filePath): textEdits,
+		},
+	}
+
+	if err := utilities.ApplyWorkspaceEdit(edit); err != nil {
+		return "", fmt.Errorf("failed to apply text edits: %v", err)
+	}
+
+	return "Successfully applied text edits.\nWARNING: line numbers may have changed. Re-read code before applying additional edits.", nil
+}
+
+// toProtocolEdits converts line based edits to LSP text edits against the file on disk
+func toProtocolEdits(filePath string, edits []TextEdit) ([]protocol.TextEdit, error) {
 	// Sort edits by line number in descending order to process from bottom to top
 	// This way line numbers don't shift under us as we make edits
 	sort.Slice(edits, func(i, j int) bool {
@@ -45,7 +65,7 @@ func ApplyTextEdits(ctx context.Context, client *lsp.Client, filePath string, ed
 	for _, edit := range edits {
 		rng, err := getRange(edit.StartLine, edit.EndLine, filePath)
 		if err != nil {
-			return "", fmt.Errorf("invalid position: %v", err)
+			return nil, fmt.Errorf("invalid position: %v", err)
 		}
 
 		switch edit.Type {
@@ -64,18 +84,7 @@ func ApplyTextEdits(ctx context.Context, client *lsp.Client, filePath string, ed
 			NewText: edit.NewText,
 		})
 	}
-
-	edit := protocol.WorkspaceEdit{
-		Changes: map[protocol.DocumentUri][]protocol.TextEdit{
-			protocol.DocumentUri(filePath): textEdits,
-		},
-	}
-
-	if err := utilities.ApplyWorkspaceEdit(edit); err != nil {
-		return "", fmt.Errorf("failed to apply text edits: %v", err)
-	}
-
-	return "Successfully applied text edits.\nWARNING: line numbers may have changed. Re-read code before applying additional edits.", nil
+	return textEdits, nil
 }
 
 // getRange now handles EOF insertions and is more precise about character positions
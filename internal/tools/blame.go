@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/git"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+// blameAuthorWidth is how much of an author's name is shown in blame annotations
+const blameAuthorWidth = 12
+
+// blameWidth is the width of a blame annotation: an 8 character hash, the author
+// and a date, each followed by a space
+const blameWidth = 8 + 1 + blameAuthorWidth + 1 + 10 + 1
+
+// blameAnnotator prefixes lines of a file with the commit that last changed them
+type blameAnnotator struct {
+	lines map[int]git.BlameLine // By 1-based line number
+	err   error
+}
+
+// newBlameAnnotator blames lines start to end (1-based, inclusive) of filePath. The
+// text the language server has for the file is blamed, so lines of unsaved edits
+// and overlays show as uncommitted rather than shifting the lines after them.
+func newBlameAnnotator(ctx context.Context, client *lsp.Client, filePath string, start, end int) *blameAnnotator {
+	content, err := client.GetFileContent(filePath)
+	if err != nil {
+		return &blameAnnotator{err: err}
+	}
+	lines, err := git.Blame(ctx, filePath, content, start, end)
+	if err != nil {
+		debugLogger.Printf("Warning: git blame failed for %s: %v\n", filePath, err)
+	}
+	return &blameAnnotator{lines: lines, err: err}
+}
+
+// prefix returns the annotation for a line, or blank space when it was not blamed
+func (b *blameAnnotator) prefix(line int) string {
+	info, ok := b.lines[line]
+	if !ok {
+		return b.blank()
+	}
+	if info.Uncommitted() {
+		return fmt.Sprintf("%-*s", blameWidth, "(uncommitted)")
+	}
+
+	author := []rune(info.Author)
+	if len(author) > blameAuthorWidth {
+		author = append(author[:blameAuthorWidth-1], '…')
+	}
+	return fmt.Sprintf("%.8s %-*s %s ", info.Hash, blameAuthorWidth, string(author), info.Time.Format("2006-01-02"))
+}
+
+// blank returns space as wide as an annotation, for lines that are not code
+func (b *blameAnnotator) blank() string {
+	return strings.Repeat(" ", blameWidth)
+}
+
+// note explains why lines have no annotations, or returns "" if blame succeeded
+func (b *blameAnnotator) note() string {
+	if b.err == nil {
+		return ""
+	}
+	return fmt.Sprintf("Blame unavailable: %v\n", b.err)
+}
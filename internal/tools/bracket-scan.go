@@ -0,0 +1,233 @@
+package tools
+
+import (
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/text"
+)
+
+// bracketSyntax is what a bracket scanner needs to know about a language to skip
+// brackets that are not code: comments and string literals
+type bracketSyntax struct {
+	lineComments  []string
+	blockComments [][2]string
+	// Quotes of strings that end at the end of the line and allow escapes
+	quotes string
+	// Delimiters of strings that may span lines, and whether they allow escapes
+	multilineQuotes []string
+	multilineEscape bool
+	// Whether ' only quotes single characters, as in Rust where it also starts lifetimes
+	charLiterals bool
+}
+
+var (
+	cLikeSyntax = bracketSyntax{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        `"'`,
+	}
+	hashCommentSyntax = bracketSyntax{
+		lineComments: []string{"#"},
+		quotes:       `"'`,
+	}
+)
+
+// bracketSyntaxes are the syntaxes of languages that differ from C
+var bracketSyntaxes = map[protocol.LanguageKind]bracketSyntax{
+	protocol.LangGo: {
+		lineComments:    []string{"//"},
+		blockComments:   [][2]string{{"/*", "*/"}},
+		quotes:          `"'`,
+		multilineQuotes: []string{"`"},
+	},
+	protocol.LangJavaScript:      jsSyntax(),
+	protocol.LangJavaScriptReact: jsSyntax(),
+	protocol.LangTypeScript:      jsSyntax(),
+	protocol.LangTypeScriptReact: jsSyntax(),
+	protocol.LangPython: {
+		lineComments:    []string{"#"},
+		quotes:          `"'`,
+		multilineQuotes: []string{`"""`, `'''`},
+		multilineEscape: true,
+	},
+	protocol.LangRust: {
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        `"`,
+		charLiterals:  true,
+	},
+	protocol.LangRuby:        hashCommentSyntax,
+	protocol.LangShellScript: hashCommentSyntax,
+	protocol.LangPerl:        hashCommentSyntax,
+	protocol.LangR:           hashCommentSyntax,
+	protocol.LangYAML:        hashCommentSyntax,
+	protocol.LangElixir:      hashCommentSyntax,
+	protocol.LangLua: {
+		lineComments:  []string{"--"},
+		blockComments: [][2]string{{"--[[", "]]"}},
+		quotes:        `"'`,
+	},
+	protocol.LangSQL: {
+		lineComments:  []string{"--"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        `'"`,
+	},
+	protocol.LangHaskell: {
+		lineComments:  []string{"--"},
+		blockComments: [][2]string{{"{-", "-}"}},
+		quotes:        `"`,
+	},
+}
+
+func jsSyntax() bracketSyntax {
+	return bracketSyntax{
+		lineComments:    []string{"//"},
+		blockComments:   [][2]string{{"/*", "*/"}},
+		quotes:          `"'`,
+		multilineQuotes: []string{"`"},
+		multilineEscape: true,
+	}
+}
+
+// syntaxForLanguage returns the comment and string syntax of a language, C's for
+// languages without their own
+func syntaxForLanguage(language protocol.LanguageKind) bracketSyntax {
+	if syntax, ok := bracketSyntaxes[language]; ok {
+		return syntax
+	}
+	return cLikeSyntax
+}
+
+// bracketPairs maps closing brackets to their opening brackets. Angle brackets are
+// left out, "<" and ">" are comparisons as often as they are generics.
+var bracketPairs = map[rune]rune{')': '(', ']': '[', '}': '{'}
+
+// findBalancedEnd scans from start to the end of the brackets opened between start
+// and end, the end of the range when they are balanced there. Brackets in comments
+// and strings are ignored, as are closing brackets that do not match the last
+// open one. It returns false if brackets are still open at the end of the file.
+func findBalancedEnd(lines []string, start, end protocol.Position, syntax bracketSyntax) (protocol.Position, bool) {
+	var stack []rune
+	var blockEnd, multilineQuote string
+	endOffset := 0
+	if int(end.Line) < len(lines) {
+		endOffset = text.ByteOffset(lines[end.Line], end.Character)
+	}
+
+	for lineNum := int(start.Line); lineNum < len(lines); lineNum++ {
+		line := lines[lineNum]
+		i := 0
+		if lineNum == int(start.Line) {
+			i = text.ByteOffset(line, start.Character)
+		}
+		if lineNum > int(end.Line) && len(stack) == 0 && blockEnd == "" && multilineQuote == "" {
+			return end, true
+		}
+
+	scan:
+		for i < len(line) {
+			rest := line[i:]
+			switch {
+			case blockEnd != "":
+				j := strings.Index(rest, blockEnd)
+				if j < 0 {
+					break scan
+				}
+				i += j + len(blockEnd)
+				blockEnd = ""
+				continue
+			case multilineQuote != "":
+				j := closingQuote(rest, multilineQuote, syntax.multilineEscape)
+				if j < 0 {
+					break scan
+				}
+				i += j + len(multilineQuote)
+				multilineQuote = ""
+				continue
+			}
+
+			for _, comment := range syntax.blockComments {
+				if strings.HasPrefix(rest, comment[0]) {
+					blockEnd = comment[1]
+					i += len(comment[0])
+					continue scan
+				}
+			}
+			for _, comment := range syntax.lineComments {
+				if strings.HasPrefix(rest, comment) {
+					break scan
+				}
+			}
+			for _, quote := range syntax.multilineQuotes {
+				if strings.HasPrefix(rest, quote) {
+					multilineQuote = quote
+					i += len(quote)
+					continue scan
+				}
+			}
+			c := rune(line[i])
+			if strings.ContainsRune(syntax.quotes, c) {
+				j := closingQuote(rest[1:], string(c), true)
+				if j < 0 {
+					break scan // Unterminated, the string runs to the end of the line
+				}
+				i += j + 2
+				continue
+			}
+
+			if c == '\'' && syntax.charLiterals {
+				if n := charLiteralLength(rest); n > 0 {
+					i += n
+					continue
+				}
+			}
+
+			switch c {
+			case '(', '[', '{':
+				stack = append(stack, c)
+			case ')', ']', '}':
+				if len(stack) > 0 && stack[len(stack)-1] == bracketPairs[c] {
+					stack = stack[:len(stack)-1]
+					if len(stack) == 0 && (lineNum > int(end.Line) || lineNum == int(end.Line) && i >= endOffset) {
+						return protocol.Position{Line: uint32(lineNum), Character: text.Column(line, i+1)}, true
+					}
+				}
+			}
+			i++
+		}
+	}
+	if len(stack) == 0 {
+		return end, true
+	}
+	return end, false
+}
+
+// charLiteralLength returns the length of the character literal text starts
+// with, such as 'x' or '\n', or 0 if the quote starts something else
+func charLiteralLength(text string) int {
+	if len(text) >= 3 && text[1] != '\\' && text[2] == '\'' {
+		return 3
+	}
+	if len(text) >= 4 && text[1] == '\\' {
+		if j := strings.IndexByte(text[2:], '\''); j >= 0 && j <= 8 {
+			return j + 3
+		}
+	}
+	return 0
+}
+
+// closingQuote returns the index of the quote ending a string in text, which
+// starts just after the opening quote, or -1 if it does not end in text
+func closingQuote(text string, quote string, escapes bool) int {
+	for i := 0; i < len(text); i++ {
+		if escapes && text[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(text[i:], quote) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,58 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// GetBreadcrumbs returns the chain of symbols enclosing a position, outermost first
+func GetBreadcrumbs(ctx context.Context, client *lsp.Client, filePath string, line, column int) (string, error) {
+	err := client.OpenFile(ctx, filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+
+	uri := protocol.DocumentUri("file://" + filePath)
+	position := protocol.Position{
+		Line:      uint32(line - 1),
+		Character: uint32(column - 1),
+	}
+
+	symResult, err := client.DocumentSymbol(ctx, protocol.DocumentSymbolParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get document symbols: %v", err)
+	}
+	symbols, err := symResult.Results()
+	if err != nil {
+		return "", fmt.Errorf("failed to process document symbols: %v", err)
+	}
+
+	chain := findSymbolsContainingPosition(symbols, position)
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("File: %s\n", filePath))
+	result.WriteString(fmt.Sprintf("Position: L%d:C%d\n\n", line, column))
+
+	if len(chain) == 0 {
+		result.WriteString("Position is not inside any symbol")
+		return result.String(), nil
+	}
+
+	for i, crumb := range chain {
+		indent := strings.Repeat("  ", i)
+		result.WriteString(fmt.Sprintf("%s%s (L%d:C%d - L%d:C%d)\n",
+			indent,
+			utilities.FormatSymbolWithKind(utilities.GetSymbolKindString(crumb.Kind), crumb.Name),
+			crumb.Range.Start.Line+1, crumb.Range.Start.Character+1,
+			crumb.Range.End.Line+1, crumb.Range.End.Character+1))
+	}
+
+	return result.String(), nil
+}
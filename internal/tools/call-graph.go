@@ -0,0 +1,356 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/text"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+const (
+	// defaultCallGraphDepth and maxCallGraphDepth bound how many calls away from
+	// the root the graph is expanded
+	defaultCallGraphDepth = 2
+	maxCallGraphDepth     = 6
+	// defaultCallGraphNodes caps the symbols in a graph, a few levels of a widely
+	// used function reach most of a codebase
+	defaultCallGraphNodes = 200
+)
+
+// CallGraphFormats are the formats call_graph emits
+var CallGraphFormats = []string{"dot", "json"}
+
+// CallGraphOptions controls how far call_graph expands and what it emits
+type CallGraphOptions struct {
+	// Direction is "outgoing" for the calls the root makes, "incoming" for its
+	// callers or "both"
+	Direction string
+	Depth     int
+	MaxNodes  int
+	// Format is "dot" or "json"
+	Format    string
+	ScopePath string
+	MatchMode string
+}
+
+// callGraphNode is a symbol in the graph
+type callGraphNode struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	// Dependency nodes are not expanded
+	Dependency bool `json:"dependency,omitempty"`
+	Depth      int  `json:"depth"`
+
+	item protocol.CallHierarchyItem
+}
+
+// callSite is where a call is made, 1-indexed
+type callSite struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// callGraphEdge is a call from one symbol to another, with each place it is made
+type callGraphEdge struct {
+	From      string     `json:"from"`
+	To        string     `json:"to"`
+	CallSites []callSite `json:"callSites"`
+}
+
+type callGraph struct {
+	Roots     []string         `json:"roots"`
+	Direction string           `json:"direction"`
+	Depth     int              `json:"depth"`
+	Nodes     []*callGraphNode `json:"nodes"`
+	Edges     []*callGraphEdge `json:"edges"`
+	// Truncated is set when nodes were left out to stay under MaxNodes
+	Truncated bool `json:"truncated"`
+
+	byKey   map[string]*callGraphNode
+	edgeIDs map[[2]string]*callGraphEdge
+}
+
+// GetCallGraph expands the call hierarchy of a symbol to a bounded depth and returns
+// the graph in DOT or JSON. Symbols in dependencies are included but not expanded.
+func GetCallGraph(ctx context.Context, client *lsp.Client, symbolName string, opts CallGraphOptions) (string, error) {
+	if opts.Direction == "" {
+		opts.Direction = "outgoing"
+	}
+	if opts.Direction != "outgoing" && opts.Direction != "incoming" && opts.Direction != "both" {
+		return "", fmt.Errorf("invalid direction %q, expected outgoing, incoming or both", opts.Direction)
+	}
+	if opts.Format == "" {
+		opts.Format = "dot"
+	}
+	if !slices.Contains(CallGraphFormats, opts.Format) {
+		return "", fmt.Errorf("invalid format %q, expected dot or json", opts.Format)
+	}
+	if opts.Depth <= 0 {
+		opts.Depth = defaultCallGraphDepth
+	}
+	opts.Depth = min(opts.Depth, maxCallGraphDepth)
+	if opts.MaxNodes <= 0 {
+		opts.MaxNodes = defaultCallGraphNodes
+	}
+	if err := validateMatchMode(opts.MatchMode); err != nil {
+		return "", err
+	}
+	if _, err := symbolRegex(symbolName); err != nil {
+		return "", err
+	}
+	scope, err := newPathScope(opts.ScopePath)
+	if err != nil {
+		return "", err
+	}
+
+	roots, err := callHierarchyRoots(ctx, client, symbolName, opts.MatchMode, scope)
+	if err != nil {
+		return "", err
+	}
+	if len(roots) == 0 {
+		return fmt.Sprintf("No function or method named '%s' found for the call hierarchy.", symbolName), nil
+	}
+
+	graph := &callGraph{
+		Direction: opts.Direction,
+		Depth:     opts.Depth,
+		byKey:     make(map[string]*callGraphNode),
+		edgeIDs:   make(map[[2]string]*callGraphEdge),
+	}
+	var queue []*callGraphNode
+	for _, item := range roots {
+		node, _ := graph.addNode(item, 0, opts.MaxNodes)
+		if node != nil {
+			graph.Roots = append(graph.Roots, node.ID)
+			queue = append(queue, node)
+		}
+	}
+
+	// Breadth first, so a node cap keeps the symbols closest to the roots
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if node.Depth >= opts.Depth || node.Dependency {
+			continue
+		}
+		if opts.Direction != "incoming" {
+			calls, err := client.OutgoingCalls(ctx, protocol.CallHierarchyOutgoingCallsParams{Item: node.item})
+			if err != nil {
+				return "", fmt.Errorf("failed to get calls from %s: %v", node.Name, err)
+			}
+			for _, call := range calls {
+				callee, added := graph.addNode(call.To, node.Depth+1, opts.MaxNodes)
+				if callee == nil {
+					continue
+				}
+				if added {
+					queue = append(queue, callee)
+				}
+				graph.addEdge(node, callee, node.item.URI, call.FromRanges)
+			}
+		}
+		if opts.Direction != "outgoing" {
+			calls, err := client.IncomingCalls(ctx, protocol.CallHierarchyIncomingCallsParams{Item: node.item})
+			if err != nil {
+				return "", fmt.Errorf("failed to get callers of %s: %v", node.Name, err)
+			}
+			for _, call := range calls {
+				caller, added := graph.addNode(call.From, node.Depth+1, opts.MaxNodes)
+				if caller == nil {
+					continue
+				}
+				if added {
+					queue = append(queue, caller)
+				}
+				graph.addEdge(caller, node, call.From.URI, call.FromRanges)
+			}
+		}
+	}
+
+	if opts.Format == "json" {
+		data, err := json.MarshalIndent(graph, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+	return graph.dot(), nil
+}
+
+// callHierarchyRoots prepares the call hierarchy items of the symbols matching name
+func callHierarchyRoots(ctx context.Context, client *lsp.Client, symbolName string, matchMode string, scope *pathScope) ([]protocol.CallHierarchyItem, error) {
+	wsSymbols, err := workspaceSymbols(ctx, client, serverSymbolQuery(symbolName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch workspace symbols for '%s': %w", symbolName, err)
+	}
+
+	var roots []protocol.CallHierarchyItem
+	seen := make(map[protocol.Location]bool)
+	for _, symbol := range wsSymbols {
+		loc := symbol.GetLocation()
+		if loc.URI == "" || seen[loc] || !scope.contains(loc.URI) || !symbolMatches(ctx, client, symbol, symbolName, matchMode) {
+			continue
+		}
+		seen[loc] = true
+
+		if !lsp.IsExternal(loc.URI) {
+			if err := client.OpenFile(ctx, documentPath(loc.URI)); err != nil {
+				debugLogger.Printf("Warning: could not open %s: %v\n", loc.URI, err)
+				continue
+			}
+		}
+		content, err := client.ReadDocument(ctx, loc.URI)
+		if err != nil {
+			debugLogger.Printf("Warning: failed to read %s: %v\n", loc.URI, err)
+			continue
+		}
+		lines := text.Lines(content)
+		line, column, err := symbolNamePosition(lines, symbol)
+		if err != nil {
+			debugLogger.Printf("Warning: %v\n", err)
+			continue
+		}
+
+		items, err := client.PrepareCallHierarchy(ctx, protocol.CallHierarchyPrepareParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: loc.URI},
+				Position:     protocol.Position{Line: uint32(line), Character: text.Column(lines[line], column)},
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare the call hierarchy of %s: %v", symbol.GetName(), err)
+		}
+		roots = append(roots, items...)
+	}
+	return roots, nil
+}
+
+// addNode adds a symbol to the graph, or returns the node it already has. It
+// returns nil when the graph is full.
+func (g *callGraph) addNode(item protocol.CallHierarchyItem, depth int, maxNodes int) (*callGraphNode, bool) {
+	key := fmt.Sprintf("%s:%d:%d", item.URI, item.SelectionRange.Start.Line, item.SelectionRange.Start.Character)
+	if node, ok := g.byKey[key]; ok {
+		return node, false
+	}
+	if len(g.Nodes) >= maxNodes {
+		g.Truncated = true
+		return nil, false
+	}
+
+	node := &callGraphNode{
+		ID:         fmt.Sprintf("n%d", len(g.Nodes)+1),
+		Name:       item.Name,
+		Kind:       utilities.GetSymbolKindString(item.Kind),
+		Detail:     item.Detail,
+		File:       documentPath(item.URI),
+		Line:       int(item.SelectionRange.Start.Line) + 1,
+		Column:     int(item.SelectionRange.Start.Character) + 1,
+		Dependency: isDependency(item.URI),
+		Depth:      depth,
+		item:       item,
+	}
+	g.byKey[key] = node
+	g.Nodes = append(g.Nodes, node)
+	return node, true
+}
+
+// addEdge records the calls from one node to another made at ranges of uri
+func (g *callGraph) addEdge(from, to *callGraphNode, uri protocol.DocumentUri, ranges []protocol.Range) {
+	id := [2]string{from.ID, to.ID}
+	edge, ok := g.edgeIDs[id]
+	if !ok {
+		edge = &callGraphEdge{From: from.ID, To: to.ID, CallSites: []callSite{}}
+		g.edgeIDs[id] = edge
+		g.Edges = append(g.Edges, edge)
+	}
+	for _, r := range ranges {
+		site := callSite{File: documentPath(uri), Line: int(r.Start.Line) + 1, Column: int(r.Start.Character) + 1}
+		if !containsCallSite(edge.CallSites, site) {
+			edge.CallSites = append(edge.CallSites, site)
+		}
+	}
+	sort.Slice(edge.CallSites, func(i, j int) bool {
+		if edge.CallSites[i].Line != edge.CallSites[j].Line {
+			return edge.CallSites[i].Line < edge.CallSites[j].Line
+		}
+		return edge.CallSites[i].Column < edge.CallSites[j].Column
+	})
+}
+
+func containsCallSite(sites []callSite, site callSite) bool {
+	for _, s := range sites {
+		if s == site {
+			return true
+		}
+	}
+	return false
+}
+
+// dot renders the graph in Graphviz DOT. Roots are drawn bold and dependencies
+// dashed, edges are labelled with the lines of their call sites.
+func (g *callGraph) dot() string {
+	var b strings.Builder
+	b.WriteString("digraph calls {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, fontname=\"monospace\"];\n")
+	if g.Truncated {
+		fmt.Fprintf(&b, "  // Truncated at %d nodes, pass a larger maxNodes or a smaller depth\n", len(g.Nodes))
+	}
+
+	roots := make(map[string]bool)
+	for _, id := range g.Roots {
+		roots[id] = true
+	}
+	for _, node := range g.Nodes {
+		label := fmt.Sprintf("%s\n%s:%d", node.Name, shortPath(node.File), node.Line)
+		var attrs []string
+		attrs = append(attrs, fmt.Sprintf("label=%s", dotQuote(label)))
+		attrs = append(attrs, fmt.Sprintf("tooltip=%s", dotQuote(fmt.Sprintf("%s:%d:%d", node.File, node.Line, node.Column))))
+		if roots[node.ID] {
+			attrs = append(attrs, "style=bold")
+		} else if node.Dependency {
+			attrs = append(attrs, "style=dashed")
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", node.ID, strings.Join(attrs, ", "))
+	}
+	for _, edge := range g.Edges {
+		lines := make([]string, len(edge.CallSites))
+		for i, site := range edge.CallSites {
+			lines[i] = fmt.Sprintf("L%d", site.Line)
+		}
+		fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", edge.From, edge.To, dotQuote(strings.Join(lines, ", ")))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotQuote returns s as a quoted DOT string
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// shortPath keeps the last two elements of a path, enough to tell files apart in
+// a graph's labels
+func shortPath(path string) string {
+	parts := strings.Split(path, "/")
+	if len(parts) <= 2 {
+		return path
+	}
+	return strings.Join(parts[len(parts)-2:], "/")
+}
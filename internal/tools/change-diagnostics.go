@@ -0,0 +1,159 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/git"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/text"
+)
+
+const (
+	// changeDiagnosticsTimeout is how long to wait for the server to publish
+	// diagnostics for changed files it did not have open
+	changeDiagnosticsTimeout = 5 * time.Second
+
+	// maxChangedFiles caps the number of changed files opened to get diagnostics
+	maxChangedFiles = 100
+)
+
+// GetDiagnosticsForChanges reports the diagnostics on lines of workspace files that
+// changed since git revision base, so pre-existing problems elsewhere are left out
+func GetDiagnosticsForChanges(ctx context.Context, client *lsp.Client, workspaceDir string, base string) (string, error) {
+	if base == "" {
+		base = "HEAD"
+	}
+	files, err := git.ChangedLines(ctx, workspaceDir, base)
+	if err != nil {
+		return "", fmt.Errorf("failed to diff against %s: %v", base, err)
+	}
+	if len(files) == 0 {
+		return fmt.Sprintf("No files changed since %s.", base), nil
+	}
+
+	omittedFiles := 0
+	if len(files) > maxChangedFiles {
+		omittedFiles = len(files) - maxChangedFiles
+		files = files[:maxChangedFiles]
+	}
+
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.Path
+	}
+	if err := openForDiagnostics(ctx, client, paths, changeDiagnosticsTimeout); err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	var totals []protocol.Diagnostic
+	filesWithDiagnostics, outside := 0, 0
+	for _, file := range files {
+		uri := protocol.DocumentUri("file://" + file.Path)
+		var onChangedLines []protocol.Diagnostic
+		for _, diag := range client.GetFileDiagnostics(uri) {
+			if file.Untracked || touchesChanges(diag.Range, file.Ranges) {
+				onChangedLines = append(onChangedLines, diag)
+			} else {
+				outside++
+			}
+		}
+		if len(onChangedLines) == 0 {
+			continue
+		}
+		filesWithDiagnostics++
+		totals = append(totals, onChangedLines...)
+
+		path := file.Path
+		if rel, err := filepath.Rel(workspaceDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+		changed := "new file"
+		if !file.Untracked {
+			changed = "changed lines " + formatLineRanges(file.Ranges)
+		}
+		result.WriteString(fmt.Sprintf("\n%s (%s)\n", path, changed))
+
+		content, _ := client.GetFileContent(file.Path)
+		lines := text.Lines(content)
+		for _, diag := range onChangedLines {
+			result.WriteString(formatDiagnosticLine(diag))
+			if line := int(diag.Range.Start.Line); line < len(lines) {
+				result.WriteString(fmt.Sprintf("    > %s\n", strings.TrimSpace(lines[line])))
+			}
+		}
+	}
+
+	errors, warnings := countSeverities(totals)
+	var header strings.Builder
+	header.WriteString(fmt.Sprintf("Diagnostics on lines changed since %s: %s and %s in %d of %s\n",
+		base, pluralize(errors, "error"), pluralize(warnings, "warning"), filesWithDiagnostics, pluralize(len(files), "changed file")))
+	if outside > 0 {
+		header.WriteString(fmt.Sprintf("%s on unchanged lines of these files left out.\n", pluralize(outside, "diagnostic")))
+	}
+	if omittedFiles > 0 {
+		header.WriteString(fmt.Sprintf("%d more changed files were not checked.\n", omittedFiles))
+	}
+	if len(totals) == 0 {
+		header.WriteString("\nThe changes introduce no diagnostics.\n")
+	}
+	return AddFooter(header.String()+result.String(), ResponseHints{Omitted: omittedFiles, OmittedKind: "files"}), nil
+}
+
+// touchesChanges reports whether a diagnostic overlaps any of the changed ranges
+func touchesChanges(r protocol.Range, ranges []git.LineRange) bool {
+	start, end := int(r.Start.Line)+1, int(r.End.Line)+1
+	for _, changed := range ranges {
+		if changed.Overlaps(start, end) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatLineRanges formats ranges compactly, e.g. "3-5, 10"
+func formatLineRanges(ranges []git.LineRange) string {
+	parts := make([]string, len(ranges))
+	for i, r := range ranges {
+		if r.Start == r.End {
+			parts[i] = fmt.Sprintf("%d", r.Start)
+		} else {
+			parts[i] = fmt.Sprintf("%d-%d", r.Start, r.End)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// openForDiagnostics opens files and waits up to timeout for the server to publish
+// diagnostics for those that were not open yet. The files are opened first and then
+// waited for, so the server checks them concurrently.
+func openForDiagnostics(ctx context.Context, client *lsp.Client, paths []string, timeout time.Duration) error {
+	published := make(map[protocol.DocumentUri]<-chan []protocol.Diagnostic)
+	for _, path := range paths {
+		uri := protocol.DocumentUri("file://" + path)
+		if !client.IsFileOpen(path) {
+			published[uri] = client.NextDiagnostics(uri)
+		}
+		if err := client.OpenFile(ctx, path); err != nil {
+			debugLogger.Printf("Warning: could not open %s for diagnostics: %v\n", path, err)
+			delete(published, uri)
+		}
+	}
+
+	deadline := time.After(timeout)
+	for _, ch := range published {
+		select {
+		case <-ch:
+		case <-deadline:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
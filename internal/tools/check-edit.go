@@ -0,0 +1,196 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+const (
+	// checkEditTimeout is how long to wait for the server to publish diagnostics for
+	// the edited document
+	checkEditTimeout = 10 * time.Second
+
+	// checkEditSettleDelay is how long to wait for further diagnostics after the first,
+	// some servers publish fast syntax errors before slower type errors
+	checkEditSettleDelay = 750 * time.Millisecond
+)
+
+// documentSwapMu serializes tools that temporarily give the server other text for
+// a document, so one never restores over another
+var documentSwapMu sync.Mutex
+
+// CheckEdit applies edits to the language server's copy of a file without writing it
+// to disk, reports the diagnostics the server publishes for the edited text, and then
+// restores the server's copy from disk.
+func CheckEdit(ctx context.Context, client *lsp.Client, filePath string, edits []TextEdit) (string, error) {
+	documentSwapMu.Lock()
+	defer documentSwapMu.Unlock()
+
+	if client.IsOverlay(filePath) {
+		return "", fmt.Errorf("%s is an overlay, update the overlay to try edits", filePath)
+	}
+
+	uri := protocol.DocumentUri("file://" + filePath)
+
+	// For a file that is not open yet, the diagnostics for the unedited text arrive
+	// after opening it
+	var initial <-chan []protocol.Diagnostic
+	if !client.IsFileOpen(filePath) {
+		initial = client.NextDiagnostics(uri)
+	}
+	err := client.OpenFile(ctx, filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	textEdits, err := toProtocolEdits(filePath, edits)
+	if err != nil {
+		return "", err
+	}
+	edited, err := utilities.EditContent(content, textEdits)
+	if err != nil {
+		return "", fmt.Errorf("failed to apply edits: %v", err)
+	}
+
+	var before []protocol.Diagnostic
+	if initial != nil {
+		before, _ = waitForDiagnostics(ctx, client, uri, initial, checkEditSettleDelay)
+	} else {
+		before = client.GetFileDiagnostics(uri)
+	}
+
+	published := client.NextDiagnostics(uri)
+	if err := client.SetContent(ctx, filePath, edited); err != nil {
+		return "", fmt.Errorf("failed to send edited content: %v", err)
+	}
+	// Revert even if the request was cancelled, the server must not keep the edit
+	defer func() {
+		if err := client.NotifyChange(context.WithoutCancel(ctx), filePath); err != nil {
+			debugLogger.Printf("Failed to restore %s after check_edit: %v", filePath, err)
+		}
+	}()
+
+	after, received := waitForDiagnostics(ctx, client, uri, published, checkEditTimeout)
+	if !received {
+		return fmt.Sprintf("The language server published no diagnostics for %s within %s, the edit could not be checked.", filePath, checkEditTimeout), nil
+	}
+
+	return formatEditCheck(filePath, before, after), nil
+}
+
+// waitForDiagnostics waits up to timeout for the first diagnostics published on ch,
+// then keeps the latest of any that follow within checkEditSettleDelay
+func waitForDiagnostics(ctx context.Context, client *lsp.Client, uri protocol.DocumentUri, ch <-chan []protocol.Diagnostic, timeout time.Duration) ([]protocol.Diagnostic, bool) {
+	var diagnostics []protocol.Diagnostic
+	select {
+	case diagnostics = <-ch:
+	case <-time.After(timeout):
+		return nil, false
+	case <-ctx.Done():
+		return nil, false
+	}
+
+	for {
+		ch = client.NextDiagnostics(uri)
+		select {
+		case diagnostics = <-ch:
+		case <-time.After(checkEditSettleDelay):
+			return diagnostics, true
+		case <-ctx.Done():
+			return diagnostics, true
+		}
+	}
+}
+
+// formatEditCheck summarizes the diagnostics for the edited text and how they differ
+// from those for the file on disk. Diagnostics are matched by severity and message,
+// since the edit may have moved them to other lines.
+func formatEditCheck(filePath string, before, after []protocol.Diagnostic) string {
+	key := func(diag protocol.Diagnostic) string {
+		return fmt.Sprintf("%d|%s|%s", diag.Severity, diag.Source, diag.Message)
+	}
+	remaining := make(map[string]int)
+	for _, diag := range before {
+		remaining[key(diag)]++
+	}
+	var introduced []protocol.Diagnostic
+	for _, diag := range after {
+		if remaining[key(diag)] > 0 {
+			remaining[key(diag)]--
+			continue
+		}
+		introduced = append(introduced, diag)
+	}
+	var resolved []protocol.Diagnostic
+	for _, diag := range before {
+		if remaining[key(diag)] > 0 {
+			remaining[key(diag)]--
+			resolved = append(resolved, diag)
+		}
+	}
+
+	var sb strings.Builder
+	errors, warnings := countSeverities(after)
+	errorsBefore, warningsBefore := countSeverities(before)
+	sb.WriteString(fmt.Sprintf("Checked edit to %s without writing it: %s and %s (before: %s and %s)\n",
+		filePath, pluralize(errors, "error"), pluralize(warnings, "warning"),
+		pluralize(errorsBefore, "error"), pluralize(warningsBefore, "warning")))
+
+	if len(introduced) == 0 && len(resolved) == 0 {
+		sb.WriteString("The edit introduces no new diagnostics.\n")
+		return sb.String()
+	}
+	if len(introduced) > 0 {
+		sb.WriteString(fmt.Sprintf("\nIntroduced by the edit (%d), lines refer to the edited file:\n", len(introduced)))
+		for _, diag := range introduced {
+			sb.WriteString(formatDiagnosticLine(diag))
+		}
+	}
+	if len(resolved) > 0 {
+		sb.WriteString(fmt.Sprintf("\nResolved by the edit (%d), lines refer to the file on disk:\n", len(resolved)))
+		for _, diag := range resolved {
+			sb.WriteString(formatDiagnosticLine(diag))
+		}
+	}
+	return sb.String()
+}
+
+func formatDiagnosticLine(diag protocol.Diagnostic) string {
+	source := ""
+	if diag.Source != "" {
+		source = fmt.Sprintf(" (%s)", diag.Source)
+	}
+	return fmt.Sprintf("  %s L%d:C%d: %s%s\n", getSeverityString(diag.Severity),
+		diag.Range.Start.Line+1, diag.Range.Start.Character+1, diag.Message, source)
+}
+
+func countSeverities(diagnostics []protocol.Diagnostic) (errors, warnings int) {
+	for _, diag := range diagnostics {
+		switch diag.Severity {
+		case protocol.SeverityError:
+			errors++
+		case protocol.SeverityWarning:
+			warnings++
+		}
+	}
+	return errors, warnings
+}
+
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
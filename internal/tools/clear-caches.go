@@ -0,0 +1,33 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/memory"
+)
+
+// ClearCaches empties the named caches, or all of them when none are named, and
+// reports how much each one freed. Overlays, pinned documents and the diagnostics
+// of open documents are kept.
+func ClearCaches(names []string) (string, error) {
+	known := memory.Default.Names()
+	for _, name := range names {
+		if !containsString(known, name) {
+			return "", fmt.Errorf("unknown cache %q, the caches are %s", name, strings.Join(known, ", "))
+		}
+	}
+
+	cleared := memory.Default.Clear(names...)
+	if len(cleared) == 0 {
+		return "No caches to clear.", nil
+	}
+	var result strings.Builder
+	freed := int64(0)
+	for _, cache := range cleared {
+		freed += cache.Bytes
+		fmt.Fprintf(&result, "%s: removed %d entries, %s\n", cache.Name, cache.Entries, formatBytes(cache.Bytes))
+	}
+	fmt.Fprintf(&result, "\nFreed %s. Overlays, pinned documents and the diagnostics of open documents were kept.\n", formatBytes(freed))
+	return result.String(), nil
+}
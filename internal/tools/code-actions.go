@@ -0,0 +1,281 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/text"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// codeActionDiagnosticsTimeout is how long to wait for the diagnostics of a file that
+// was not open, so quick fixes can be requested for them
+const codeActionDiagnosticsTimeout = 3 * time.Second
+
+// GetCodeActions lists the code actions the server offers for a range of a file.
+// Lines and columns are 1-indexed, a zero end line means the start line and zero
+// columns mean the whole line. kinds keeps only actions of those kinds or their
+// sub-kinds, e.g. "refactor" includes "refactor.extract.function". With
+// diagnosticsOnly set, only actions that fix a diagnostic in the range are listed.
+func GetCodeActions(ctx context.Context, client *lsp.Client, filePath string, startLine, startColumn, endLine, endColumn int, kinds []string, diagnosticsOnly bool) (string, error) {
+	if err := openForDiagnostics(ctx, client, []string{filePath}, codeActionDiagnosticsTimeout); err != nil {
+		return "", err
+	}
+	uri := protocol.DocumentUri("file://" + filePath)
+
+	rng, err := lineRange(client, filePath, startLine, startColumn, endLine, endColumn)
+	if err != nil {
+		return "", err
+	}
+	diagnostics := diagnosticsInRange(client.GetFileDiagnostics(uri), rng)
+	if diagnosticsOnly && len(diagnostics) == 0 {
+		return fmt.Sprintf("No diagnostics in %s, so there are no quick fixes.", formatRange(filePath, rng)), nil
+	}
+
+	actions, err := codeActions(ctx, client, uri, rng, diagnostics, kinds)
+	if err != nil {
+		return "", err
+	}
+	if diagnosticsOnly {
+		var fixes []protocol.CodeAction
+		for _, action := range actions {
+			if len(action.Diagnostics) > 0 || action.Kind == protocol.QuickFix {
+				fixes = append(fixes, action)
+			}
+		}
+		actions = fixes
+	}
+
+	if len(actions) == 0 {
+		if len(kinds) > 0 {
+			return fmt.Sprintf("No code actions of kind %s for %s.", strings.Join(kinds, ", "), formatRange(filePath, rng)), nil
+		}
+		return fmt.Sprintf("No code actions for %s.", formatRange(filePath, rng)), nil
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("%s for %s\n", pluralize(len(actions), "code action"), formatRange(filePath, rng)))
+	if len(diagnostics) > 0 {
+		errors, warnings := countSeverities(diagnostics)
+		result.WriteString(fmt.Sprintf("Diagnostics in range: %s and %s\n", pluralize(errors, "error"), pluralize(warnings, "warning")))
+	}
+	result.WriteString("\n")
+	for i, action := range actions {
+		kind := string(action.Kind)
+		if kind == "" {
+			kind = "command"
+		}
+		result.WriteString(fmt.Sprintf("%d. [%s] %s", i+1, kind, action.Title))
+		if action.IsPreferred {
+			result.WriteString(" (preferred)")
+		}
+		result.WriteString("\n")
+		for _, diag := range action.Diagnostics {
+			result.WriteString(fmt.Sprintf("   Fixes: L%d: %s\n", diag.Range.Start.Line+1, firstLine(diag.Message)))
+		}
+		if action.Disabled != nil {
+			result.WriteString(fmt.Sprintf("   Disabled: %s\n", action.Disabled.Reason))
+		}
+	}
+	return result.String(), nil
+}
+
+// codeActions requests the code actions for a range, passing the diagnostics in it
+// as context. Bare commands are returned as actions without a kind. The server is
+// asked for kinds only, and actions of other kinds are dropped in case it ignores that.
+func codeActions(ctx context.Context, client *lsp.Client, uri protocol.DocumentUri, rng protocol.Range, diagnostics []protocol.Diagnostic, kinds []string) ([]protocol.CodeAction, error) {
+	trigger := protocol.CodeActionInvoked
+	params := protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Range:        rng,
+		Context: protocol.CodeActionContext{
+			Diagnostics: diagnostics,
+			TriggerKind: &trigger,
+		},
+	}
+	if params.Context.Diagnostics == nil {
+		params.Context.Diagnostics = []protocol.Diagnostic{}
+	}
+	for _, kind := range kinds {
+		params.Context.Only = append(params.Context.Only, protocol.CodeActionKind(kind))
+	}
+
+	results, err := client.CodeAction(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get code actions: %v", err)
+	}
+
+	var actions []protocol.CodeAction
+	for _, result := range results {
+		var action protocol.CodeAction
+		switch value := result.Value.(type) {
+		case protocol.CodeAction:
+			action = value
+		case protocol.Command:
+			action = protocol.CodeAction{Title: value.Title, Command: &value}
+		default:
+			continue
+		}
+		if len(kinds) > 0 && !matchesKind(action.Kind, kinds) {
+			continue
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+// applyCodeAction applies a code action's edit and then runs its command, resolving
+// the action first if the server left its edit to be computed on demand. It returns
+// the files the edit changed.
+func applyCodeAction(ctx context.Context, client *lsp.Client, action protocol.CodeAction) ([]string, error) {
+	if action.Disabled != nil {
+		return nil, fmt.Errorf("%q is disabled: %s", action.Title, action.Disabled.Reason)
+	}
+	if action.Edit == nil && action.Command == nil {
+		resolved, err := client.ResolveCodeAction(ctx, action)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %q: %v", action.Title, err)
+		}
+		action = resolved
+	}
+	if action.Edit == nil && action.Command == nil {
+		return nil, fmt.Errorf("%q has neither an edit nor a command", action.Title)
+	}
+
+	var changed []string
+	if action.Edit != nil {
+		if err := utilities.ApplyWorkspaceEdit(*action.Edit); err != nil {
+			return nil, fmt.Errorf("failed to apply %q: %v", action.Title, err)
+		}
+		changed = editedFiles(*action.Edit)
+		syncEditedFiles(ctx, client, changed)
+	}
+	if action.Command != nil {
+		// Edits of the command come back as workspace/applyEdit requests
+		_, err := client.ExecuteCommand(ctx, protocol.ExecuteCommandParams{
+			Command:   action.Command.Command,
+			Arguments: action.Command.Arguments,
+		})
+		if err != nil {
+			return changed, fmt.Errorf("failed to run the command of %q: %v", action.Title, err)
+		}
+	}
+	return changed, nil
+}
+
+// editedFiles returns the paths of the files a workspace edit changes or creates
+func editedFiles(edit protocol.WorkspaceEdit) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	add := func(uri protocol.DocumentUri) {
+		path := strings.TrimPrefix(string(uri), "file://")
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	for uri := range edit.Changes {
+		add(uri)
+	}
+	for _, change := range edit.DocumentChanges {
+		switch {
+		case change.TextDocumentEdit != nil:
+			add(change.TextDocumentEdit.TextDocument.URI)
+		case change.CreateFile != nil:
+			add(change.CreateFile.URI)
+		case change.RenameFile != nil:
+			add(change.RenameFile.NewURI)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// syncEditedFiles sends the new text of edited files that are open to the server
+// right away, rather than when the watcher notices the write
+func syncEditedFiles(ctx context.Context, client *lsp.Client, paths []string) {
+	for _, path := range paths {
+		if !client.IsFileOpen(path) {
+			continue
+		}
+		if err := client.NotifyChange(ctx, path); err != nil {
+			debugLogger.Printf("Warning: could not sync %s after an edit: %v\n", path, err)
+		}
+	}
+}
+
+// matchesKind reports whether kind is one of kinds or a sub-kind of one, code
+// action kinds being hierarchical like "refactor.extract.function"
+func matchesKind(kind protocol.CodeActionKind, kinds []string) bool {
+	for _, want := range kinds {
+		if string(kind) == want || strings.HasPrefix(string(kind), want+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// lineRange converts 1-indexed lines and columns to a protocol range. A zero end
+// line means the start line, a zero start column the start of the line and a zero
+// end column the end of the line.
+func lineRange(client *lsp.Client, filePath string, startLine, startColumn, endLine, endColumn int) (protocol.Range, error) {
+	if startLine < 1 {
+		return protocol.Range{}, fmt.Errorf("invalid start line %d, lines are 1-indexed", startLine)
+	}
+	if endLine == 0 {
+		endLine = startLine
+	}
+	if endLine < startLine {
+		return protocol.Range{}, fmt.Errorf("end line %d is before start line %d", endLine, startLine)
+	}
+	if startColumn < 1 {
+		startColumn = 1
+	}
+
+	rng := protocol.Range{
+		Start: protocol.Position{Line: uint32(startLine - 1), Character: uint32(startColumn - 1)},
+		End:   protocol.Position{Line: uint32(endLine - 1), Character: uint32(max(endColumn-1, 0))},
+	}
+	if endColumn < 1 {
+		content, err := client.GetFileContent(filePath)
+		if err != nil {
+			return protocol.Range{}, fmt.Errorf("could not read file: %v", err)
+		}
+		lines := text.Lines(content)
+		if endLine <= len(lines) {
+			rng.End.Character = uint32(len(strings.TrimRight(lines[endLine-1], "\r")))
+		}
+	}
+	return rng, nil
+}
+
+// diagnosticsInRange returns the diagnostics that overlap rng
+func diagnosticsInRange(diagnostics []protocol.Diagnostic, rng protocol.Range) []protocol.Diagnostic {
+	var overlapping []protocol.Diagnostic
+	for _, diag := range diagnostics {
+		if diag.Range.End.Line < rng.Start.Line || diag.Range.Start.Line > rng.End.Line {
+			continue
+		}
+		overlapping = append(overlapping, diag)
+	}
+	return overlapping
+}
+
+// formatRange describes a 0-indexed range as 1-indexed lines of a file
+func formatRange(filePath string, rng protocol.Range) string {
+	if rng.Start.Line == rng.End.Line {
+		return fmt.Sprintf("%s:%d", filePath, rng.Start.Line+1)
+	}
+	return fmt.Sprintf("%s:%d-%d", filePath, rng.Start.Line+1, rng.End.Line+1)
+}
+
+// firstLine returns the first line of a possibly multi-line message
+func firstLine(message string) string {
+	line, _, _ := strings.Cut(message, "\n")
+	return line
+}
@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// codeLensCache keeps the code lenses get_codelens listed for each file with the
+// document they were computed for. execute_codelens runs lenses from it, so an
+// index always refers to the lens that was listed.
+var codeLensCache = struct {
+	mu      sync.Mutex
+	entries map[string]codeLensEntry
+}{entries: make(map[string]codeLensEntry)}
+
+type codeLensEntry struct {
+	document documentState
+	lenses   []protocol.CodeLens
+}
+
+// documentState identifies the text of an open file. The version alone does not,
+// versions start again when a file is closed and reopened.
+type documentState struct {
+	version int32
+	sum     [sha256.Size]byte
+}
+
+// currentDocumentState returns the state of an open file, false if it is not open
+func currentDocumentState(client *lsp.Client, filePath string) (documentState, bool) {
+	version, content, open := client.FileVersion(filePath)
+	if !open {
+		return documentState{}, false
+	}
+	return documentState{version: version, sum: sha256.Sum256(content)}, true
+}
+
+// ClearCodeLensCache drops every cached code lens list, e.g. when the language
+// server is replaced
+func ClearCodeLensCache() {
+	codeLensCache.mu.Lock()
+	defer codeLensCache.mu.Unlock()
+	codeLensCache.entries = make(map[string]codeLensEntry)
+}
+
+// fileCodeLenses returns the code lenses of an open file and the version of the
+// document they are for. They are reused while the document is unchanged, otherwise
+// the language server is asked again and the cache updated.
+func fileCodeLenses(ctx context.Context, client *lsp.Client, filePath string) ([]protocol.CodeLens, int32, error) {
+	state, open := currentDocumentState(client, filePath)
+	codeLensCache.mu.Lock()
+	entry, cached := codeLensCache.entries[filePath]
+	codeLensCache.mu.Unlock()
+	if open && cached && entry.document == state {
+		return entry.lenses, state.version, nil
+	}
+
+	// TODO: find a more appropriate way to wait
+	time.Sleep(time.Second)
+	state, open = currentDocumentState(client, filePath)
+	lenses, err := client.CodeLens(ctx, protocol.CodeLensParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentUri("file://" + filePath)},
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	codeLensCache.mu.Lock()
+	defer codeLensCache.mu.Unlock()
+	if open {
+		codeLensCache.entries[filePath] = codeLensEntry{document: state, lenses: lenses}
+	} else {
+		delete(codeLensCache.entries, filePath)
+	}
+	return lenses, state.version, nil
+}
+
+// listedCodeLenses returns the code lenses get_codelens last listed for an open file,
+// after checking that the document has not changed since. Without a listing the
+// language server is asked for the lenses of the current document.
+func listedCodeLenses(ctx context.Context, client *lsp.Client, filePath string) ([]protocol.CodeLens, error) {
+	codeLensCache.mu.Lock()
+	entry, cached := codeLensCache.entries[filePath]
+	codeLensCache.mu.Unlock()
+	if !cached {
+		lenses, _, err := fileCodeLenses(ctx, client, filePath)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get code lenses: %v", err)
+		}
+		return lenses, nil
+	}
+
+	state, open := currentDocumentState(client, filePath)
+	// The listing is kept, so retries fail the same way until the lenses are listed again
+	if !open || state != entry.document {
+		return nil, fmt.Errorf("%s changed since get_codelens listed its code lenses for document version %d, the indexes may point to other lenses now. Call get_codelens again and use its indexes", filePath, entry.document.version)
+	}
+	return entry.lenses, nil
+}
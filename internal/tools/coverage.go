@@ -0,0 +1,141 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/coverage"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// fileCoverage is the coverage of one file in a report
+type fileCoverage struct {
+	path    string
+	blocks  []coverage.Block
+	summary coverage.Summary
+}
+
+// GetCoverage reports the statement coverage of each function in the files of a
+// coverage report, least covered files first. With filePath set only that file is
+// reported, otherwise at most maxFiles files.
+func GetCoverage(ctx context.Context, client *lsp.Client, workspaceDir string, profilePath string, filePath string, maxFiles int) (string, error) {
+	if maxFiles <= 0 {
+		maxFiles = 20
+	}
+	profile, err := coverage.Load(profilePath, workspaceDir)
+	if err != nil {
+		return "", err
+	}
+
+	var files []fileCoverage
+	var total coverage.Summary
+	for path, blocks := range profile.Files {
+		summary := coverage.Summarize(blocks, 1, int(^uint(0)>>1))
+		total.Covered += summary.Covered
+		total.Total += summary.Total
+		if filePath == "" || path == filePath {
+			files = append(files, fileCoverage{path: path, blocks: blocks, summary: summary})
+		}
+	}
+	if len(files) == 0 {
+		return fmt.Sprintf("No coverage recorded for %s in %s.", filePath, profilePath), nil
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].summary.Percent() != files[j].summary.Percent() {
+			return files[i].summary.Percent() < files[j].summary.Percent()
+		}
+		return files[i].path < files[j].path
+	})
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Coverage from %s: %s in %s\n", profilePath, formatCoverage(total), pluralize(len(profile.Files), "file")))
+
+	var hints ResponseHints
+	if len(files) > maxFiles {
+		hints.Omitted = len(files) - maxFiles
+		hints.OmittedKind = "files"
+		hints.Suggestions = append(hints.Suggestions, "filePath=<file> to see one file", fmt.Sprintf("maxFiles=%d to see every file", len(files)))
+		files = files[:maxFiles]
+	}
+
+	for _, file := range files {
+		path := file.path
+		if rel, err := filepath.Rel(workspaceDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+		result.WriteString(fmt.Sprintf("\n%s: %s\n", path, formatCoverage(file.summary)))
+
+		if err := client.OpenFile(ctx, file.path); err != nil {
+			debugLogger.Printf("Warning: could not open %s: %v\n", file.path, err)
+			continue
+		}
+		symbols, err := documentSymbols(ctx, client, file.path)
+		if err != nil {
+			debugLogger.Printf("Warning: %v for %s\n", err, file.path)
+			continue
+		}
+		writeFunctionCoverage(&result, symbols, "", file.blocks)
+	}
+
+	return AddFooter(result.String(), hints), nil
+}
+
+// writeFunctionCoverage lists the coverage of the functions among symbols and
+// their children, qualifying members with their container
+func writeFunctionCoverage(sb *strings.Builder, symbols []protocol.DocumentSymbolResult, container string, blocks []coverage.Block) {
+	for _, sym := range symbols {
+		name := sym.GetName()
+		if container != "" {
+			name = container + "." + name
+		}
+		switch sym.GetKind() {
+		case protocol.Function, protocol.Method, protocol.Constructor:
+			r := sym.GetRange()
+			summary := coverage.Summarize(blocks, int(r.Start.Line)+1, int(r.End.Line)+1)
+			if summary.Total > 0 {
+				sb.WriteString(fmt.Sprintf("  %s %s (L%d-%d): %s\n", utilities.GetSymbolKindString(sym.GetKind()), name,
+					r.Start.Line+1, r.End.Line+1, formatCoverage(summary)))
+			}
+		}
+		writeFunctionCoverage(sb, symbolChildren(sym), name, blocks)
+	}
+}
+
+func formatCoverage(summary coverage.Summary) string {
+	if summary.Total == 0 {
+		return "no statements"
+	}
+	text := fmt.Sprintf("%.1f%% of %s covered", summary.Percent(), pluralize(summary.Total, "statement"))
+	if summary.Covered == 0 {
+		text += ", never run"
+	}
+	return text
+}
+
+// coverageSummaryLine describes the coverage of lines start to end of a file
+func coverageSummaryLine(profile *coverage.Profile, filePath string, start, end int) string {
+	blocks, ok := profile.Files[filePath]
+	if !ok {
+		return "Coverage: not in the coverage report\n"
+	}
+	return fmt.Sprintf("Coverage: %s (+ ran, ! did not run)\n", formatCoverage(coverage.Summarize(blocks, start, end)))
+}
+
+// coverageMarker returns a function marking lines of a file with whether they ran
+func coverageMarker(profile *coverage.Profile, filePath string) func(line int) string {
+	lines := coverage.Lines(profile.Files[filePath])
+	return func(line int) string {
+		switch lines[line] {
+		case coverage.Covered:
+			return "+ "
+		case coverage.Uncovered:
+			return "! "
+		}
+		return "  "
+	}
+}
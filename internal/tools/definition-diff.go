@@ -0,0 +1,186 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/git"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/text"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// definitionSource is the text of one side of a definition diff
+type definitionSource struct {
+	symbol    string
+	rev       string // "" for the file as the language server sees it
+	filePath  string
+	startLine int // 1-based
+	text      string
+}
+
+func (d definitionSource) label() string {
+	rev := d.rev
+	if rev == "" {
+		rev = "working tree"
+	}
+	return fmt.Sprintf("%s (%s:%d, %s)", d.symbol, d.filePath, d.startLine, rev)
+}
+
+// DiffDefinitions returns a unified diff between the definitions of two symbols, or
+// of one symbol at two git revisions. An empty revision is the current text. The
+// symbol is located in the current workspace and looked up in the same file at the
+// revision, so definitions that moved to another file are not found.
+func DiffDefinitions(ctx context.Context, client *lsp.Client, symbolName, otherSymbolName, baseRef, otherRef string) (string, error) {
+	if otherSymbolName == "" {
+		otherSymbolName = symbolName
+	}
+	if otherSymbolName == symbolName && baseRef == otherRef {
+		return "", fmt.Errorf("give another symbol or a git revision to compare with")
+	}
+
+	base, err := definitionAt(ctx, client, symbolName, baseRef)
+	if err != nil {
+		return "", err
+	}
+	other, err := definitionAt(ctx, client, otherSymbolName, otherRef)
+	if err != nil {
+		return "", err
+	}
+
+	diff, err := utilities.UnifiedDiff(base.label(), other.label(), base.text, other.text, 3)
+	if err != nil {
+		return "", err
+	}
+	header := fmt.Sprintf("Comparing %s\n     with %s\n", base.label(), other.label())
+	if diff == "" {
+		return header + "\nThe definitions are identical.\n", nil
+	}
+	return header + "\n" + fenceCodeAs(diff, "diff"), nil
+}
+
+// definitionAt finds the text of a symbol's definition at a git revision
+func definitionAt(ctx context.Context, client *lsp.Client, symbolName string, rev string) (definitionSource, error) {
+	source := definitionSource{symbol: symbolName, rev: rev}
+
+	results, err := workspaceSymbols(ctx, client, symbolName)
+	if err != nil {
+		return source, fmt.Errorf("failed to fetch symbol: %v", err)
+	}
+	for _, symbol := range results {
+		if symbolMatches(ctx, client, symbol, symbolName, matchExact) {
+			source.filePath, err = url.PathUnescape(strings.TrimPrefix(string(symbol.GetLocation().URI), "file://"))
+			if err != nil {
+				return source, fmt.Errorf("failed to unescape URI: %w", err)
+			}
+			break
+		}
+	}
+	if source.filePath == "" {
+		return source, fmt.Errorf("symbol %s not found in the workspace", symbolName)
+	}
+
+	if err := client.OpenFile(ctx, source.filePath); err != nil {
+		return source, fmt.Errorf("could not open file: %v", err)
+	}
+	content, err := client.GetFileContent(source.filePath)
+	if err != nil {
+		return source, err
+	}
+
+	var symbols []protocol.DocumentSymbolResult
+	findSymbols := func() error {
+		symbols, err = documentSymbols(ctx, client, source.filePath)
+		return err
+	}
+	if rev == "" {
+		err = findSymbols()
+	} else {
+		content, err = git.Show(ctx, source.filePath, rev)
+		if err != nil {
+			return source, fmt.Errorf("failed to read %s at %s: %v", source.filePath, rev, err)
+		}
+		err = withDocumentContent(ctx, client, source.filePath, content, findSymbols)
+	}
+	if err != nil {
+		return source, err
+	}
+
+	symbol := findDocumentSymbol(symbols, symbolName, "")
+	if symbol == nil {
+		where := "the working tree"
+		if rev != "" {
+			where = rev
+		}
+		return source, fmt.Errorf("symbol %s not found in %s at %s", symbolName, source.filePath, where)
+	}
+
+	lines := text.Lines(content)
+	start := int(symbol.Range.Start.Line)
+	end := min(int(symbol.Range.End.Line), len(lines)-1)
+	if start > end {
+		return source, fmt.Errorf("invalid range for %s: lines %d-%d", symbolName, start+1, end+1)
+	}
+	source.startLine = start + 1
+	source.text = strings.Join(lines[start:end+1], "\n")
+	return source, nil
+}
+
+// documentSymbols requests the symbols of an open document
+func documentSymbols(ctx context.Context, client *lsp.Client, filePath string) ([]protocol.DocumentSymbolResult, error) {
+	result, err := client.DocumentSymbol(ctx, protocol.DocumentSymbolParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentUri("file://" + filePath)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document symbols: %v", err)
+	}
+	return result.Results()
+}
+
+// withDocumentContent gives the server content as the text of filePath while fn
+// runs, then restores the text it had before. The file on disk is not touched.
+func withDocumentContent(ctx context.Context, client *lsp.Client, filePath string, content []byte, fn func() error) error {
+	documentSwapMu.Lock()
+	defer documentSwapMu.Unlock()
+
+	original, err := client.GetFileContent(filePath)
+	if err != nil {
+		return err
+	}
+	if err := client.SetContent(ctx, filePath, content); err != nil {
+		return fmt.Errorf("failed to send content: %v", err)
+	}
+	defer func() {
+		if err := client.SetContent(context.WithoutCancel(ctx), filePath, original); err != nil {
+			debugLogger.Printf("Failed to restore %s: %v", filePath, err)
+		}
+	}()
+	return fn()
+}
+
+// findDocumentSymbol finds the document symbol named by query, which may be
+// qualified by its container as in "Foo.Name"
+func findDocumentSymbol(symbols []protocol.DocumentSymbolResult, query string, parent string) *protocol.DocumentSymbol {
+	container, member := splitQualifiedName(query)
+	for _, sym := range symbols {
+		ds, ok := sym.(*protocol.DocumentSymbol)
+		if !ok {
+			continue
+		}
+		if ds.Name == query || normalizeReceiver(ds.Name) == query ||
+			(container != "" && ds.Name == member && parent != "" && containerNameMatches(parent, container)) {
+			return ds
+		}
+		children := make([]protocol.DocumentSymbolResult, len(ds.Children))
+		for i := range ds.Children {
+			children[i] = &ds.Children[i]
+		}
+		if found := findDocumentSymbol(children, query, ds.Name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
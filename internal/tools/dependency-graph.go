@@ -0,0 +1,603 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+)
+
+// DependencyGraphFormats are the formats dependency_graph emits
+var DependencyGraphFormats = []string{"tree", "dot"}
+
+// DependencyGraphOptions controls which imports dependency_graph follows and what
+// it emits
+type DependencyGraphOptions struct {
+	// Directory limits the packages scanned to those below it, the workspace when
+	// empty
+	Directory string
+	// IncludeExternal adds the packages imported from outside the workspace as leaves
+	IncludeExternal bool
+	IncludeTests    bool
+	// Format is "tree" or "dot"
+	Format string
+}
+
+// depPackage is a directory of the workspace, or an external package
+type depPackage struct {
+	// Directory relative to the workspace, or the external package's name
+	name     string
+	external bool
+	// Go import path, for directories of a Go module
+	importPath string
+	imports    map[*depPackage][]importSite
+	cycle      bool
+}
+
+// depGraph is the import graph between the packages of a workspace
+type depGraph struct {
+	root     string
+	packages map[string]*depPackage
+	// Go modules and npm workspace packages by name, with their directories
+	goModules   map[string]string
+	npmPackages map[string]string
+	// Directories with Go files, the ones given import paths
+	goDirs map[string]bool
+	cycles [][]*depPackage
+}
+
+// GetDependencyGraph builds the graph of imports between the packages (directories)
+// of the workspace and returns it as an indented tree or in DOT, with import cycles
+// listed along with the imports that close them. Imports are found like who_imports
+// finds them, and resolved to directories through go.mod module paths, relative
+// paths, package.json names and Python package directories.
+func GetDependencyGraph(ctx context.Context, w *watcher.WorkspaceWatcher, opts DependencyGraphOptions) (string, error) {
+	if opts.Format == "" {
+		opts.Format = "tree"
+	}
+	if !slices.Contains(DependencyGraphFormats, opts.Format) {
+		return "", fmt.Errorf("invalid format %q, expected tree or dot", opts.Format)
+	}
+
+	root := w.WorkspacePath()
+	scanDir := root
+	if opts.Directory != "" {
+		abs, err := filepath.Abs(opts.Directory)
+		if err != nil {
+			return "", fmt.Errorf("invalid directory: %v", err)
+		}
+		if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+			return "", fmt.Errorf("%s is not a directory", opts.Directory)
+		}
+		scanDir = abs
+	}
+
+	g := &depGraph{
+		root:        root,
+		packages:    make(map[string]*depPackage),
+		goModules:   make(map[string]string),
+		npmPackages: make(map[string]string),
+		goDirs:      make(map[string]bool),
+	}
+	var dirs []string
+	var sites []importSite
+	err := w.WalkWorkspace(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip unreadable entries
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			if w.IsExcluded(path, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if w.IsExcluded(path, false) {
+			return nil
+		}
+
+		// Module metadata is read from the whole workspace, imports only below scanDir
+		switch filepath.Base(path) {
+		case "go.mod":
+			if module := goModulePath(path); module != "" {
+				g.goModules[module] = filepath.Dir(path)
+			}
+		case "package.json":
+			if name := npmPackageName(path); name != "" {
+				g.npmPackages[name] = filepath.Dir(path)
+			}
+		}
+		if path != scanDir && !strings.HasPrefix(path, scanDir+string(filepath.Separator)) {
+			return nil
+		}
+		if !opts.IncludeTests && isTestFile(path) {
+			return nil
+		}
+
+		var imports []importSite
+		switch filepath.Ext(path) {
+		case ".go":
+			imports = goImports(path)
+			g.goDirs[filepath.Dir(path)] = true
+		case ".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx", ".mts", ".cts":
+			imports = patternImports(path, jsImportPattern)
+		case ".py", ".pyi":
+			imports = patternImports(path, pyImportPattern)
+		default:
+			return nil
+		}
+		dirs = append(dirs, filepath.Dir(path))
+		sites = append(sites, imports...)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to scan workspace: %v", err)
+	}
+	if len(dirs) == 0 {
+		return fmt.Sprintf("No Go, JavaScript, TypeScript or Python files found in %s", scanDir), nil
+	}
+
+	// Packages are added once every go.mod is known, for their import paths
+	for _, dir := range dirs {
+		g.directory(dir)
+	}
+	for _, site := range sites {
+		from := g.directory(filepath.Dir(site.path))
+		var to *depPackage
+		if dir, ok := g.resolve(site); ok {
+			to = g.directory(dir)
+		} else if opts.IncludeExternal {
+			to = g.externalPackage(site)
+		}
+		if to != nil && to != from {
+			from.imports[to] = append(from.imports[to], site)
+		}
+	}
+	g.findCycles()
+
+	if opts.Format == "dot" {
+		return g.dot(), nil
+	}
+	return g.tree(), nil
+}
+
+// directory returns the package of a workspace directory, adding it if needed
+func (g *depGraph) directory(dir string) *depPackage {
+	name, err := filepath.Rel(g.root, dir)
+	if err != nil || strings.HasPrefix(name, "..") {
+		name = dir
+	}
+	name = filepath.ToSlash(name)
+	if pkg, ok := g.packages[name]; ok {
+		return pkg
+	}
+	pkg := &depPackage{name: name, imports: make(map[*depPackage][]importSite)}
+	// The innermost module a directory is in is the one it belongs to
+	moduleDepth := -1
+	for module, moduleDir := range g.goModules {
+		rel, err := filepath.Rel(moduleDir, dir)
+		if !g.goDirs[dir] || err != nil || strings.HasPrefix(rel, "..") || len(moduleDir) <= moduleDepth {
+			continue
+		}
+		moduleDepth = len(moduleDir)
+		if rel == "." {
+			pkg.importPath = module
+		} else {
+			pkg.importPath = module + "/" + filepath.ToSlash(rel)
+		}
+	}
+	g.packages[name] = pkg
+	return pkg
+}
+
+// externalPackage returns the package an import from outside the workspace belongs to
+func (g *depGraph) externalPackage(site importSite) *depPackage {
+	name := site.importPath
+	switch filepath.Ext(site.path) {
+	case ".go":
+	case ".py", ".pyi":
+		name, _, _ = strings.Cut(name, ".")
+	default:
+		// npm packages are the first element of the path, or two for scoped ones
+		parts := strings.SplitN(name, "/", 3)
+		if strings.HasPrefix(name, "@") && len(parts) > 1 {
+			name = parts[0] + "/" + parts[1]
+		} else {
+			name = parts[0]
+		}
+	}
+	key := "external:" + name
+	if pkg, ok := g.packages[key]; ok {
+		return pkg
+	}
+	pkg := &depPackage{name: name, external: true, imports: make(map[*depPackage][]importSite)}
+	g.packages[key] = pkg
+	return pkg
+}
+
+// resolve returns the workspace directory an import refers to
+func (g *depGraph) resolve(site importSite) (string, bool) {
+	imp := site.importPath
+	switch filepath.Ext(site.path) {
+	case ".go":
+		// Nested modules take the imports below their path
+		resolved, longest := "", 0
+		for module, dir := range g.goModules {
+			if len(module) <= longest {
+				continue
+			}
+			if imp == module {
+				resolved, longest = dir, len(module)
+			} else if rest, ok := strings.CutPrefix(imp, module+"/"); ok {
+				resolved, longest = filepath.Join(dir, filepath.FromSlash(rest)), len(module)
+			}
+		}
+		return resolved, resolved != ""
+
+	case ".py", ".pyi":
+		if strings.HasPrefix(imp, ".") {
+			// Each dot after the first goes up a package
+			rest := strings.TrimLeft(imp, ".")
+			base := filepath.Dir(site.path)
+			for i := 1; i < len(imp)-len(rest); i++ {
+				base = filepath.Dir(base)
+			}
+			return g.pythonModuleDir(base, rest)
+		}
+		for _, base := range []string{g.root, filepath.Join(g.root, "src")} {
+			if dir, ok := g.pythonModuleDir(base, imp); ok {
+				return dir, true
+			}
+		}
+		return "", false
+
+	default:
+		if strings.HasPrefix(imp, ".") {
+			return g.workspaceDir(filepath.Join(filepath.Dir(site.path), filepath.FromSlash(imp)))
+		}
+		for name, dir := range g.npmPackages {
+			if imp == name {
+				return dir, true
+			}
+			if rest, ok := strings.CutPrefix(imp, name+"/"); ok {
+				return g.workspaceDir(filepath.Join(dir, filepath.FromSlash(rest)))
+			}
+		}
+		return "", false
+	}
+}
+
+// pythonModuleDir returns the package directory of a dotted module below base, the
+// directory containing it for a module file
+func (g *depGraph) pythonModuleDir(base, module string) (string, bool) {
+	path := filepath.Join(base, filepath.FromSlash(strings.ReplaceAll(module, ".", "/")))
+	if module == "" {
+		path = base
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return g.workspaceDir(path)
+	}
+	for _, ext := range []string{".py", ".pyi"} {
+		if _, err := os.Stat(path + ext); err == nil {
+			return g.workspaceDir(filepath.Dir(path))
+		}
+	}
+	return "", false
+}
+
+// workspaceDir returns the directory of an imported file or directory, if it is in
+// the workspace. The file may be named without its extension.
+func (g *depGraph) workspaceDir(path string) (string, bool) {
+	if rel, err := filepath.Rel(g.root, path); err != nil || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return path, true
+	}
+	return filepath.Dir(path), true
+}
+
+// sorted returns the packages in name order, workspace packages first
+func (g *depGraph) sorted() []*depPackage {
+	pkgs := make([]*depPackage, 0, len(g.packages))
+	for _, pkg := range g.packages {
+		pkgs = append(pkgs, pkg)
+	}
+	sortPackages(pkgs)
+	return pkgs
+}
+
+func sortPackages(pkgs []*depPackage) {
+	sort.Slice(pkgs, func(i, j int) bool {
+		if pkgs[i].external != pkgs[j].external {
+			return !pkgs[i].external
+		}
+		return pkgs[i].name < pkgs[j].name
+	})
+}
+
+// importsOf returns the packages a package imports in name order
+func importsOf(pkg *depPackage) []*depPackage {
+	deps := make([]*depPackage, 0, len(pkg.imports))
+	for dep := range pkg.imports {
+		deps = append(deps, dep)
+	}
+	sortPackages(deps)
+	return deps
+}
+
+// findCycles finds the strongly connected components of more than one package with
+// Tarjan's algorithm, and a cycle through each of them
+func (g *depGraph) findCycles() {
+	index := make(map[*depPackage]int)
+	low := make(map[*depPackage]int)
+	onStack := make(map[*depPackage]bool)
+	var stack []*depPackage
+
+	var visit func(pkg *depPackage)
+	visit = func(pkg *depPackage) {
+		index[pkg] = len(index)
+		low[pkg] = index[pkg]
+		stack = append(stack, pkg)
+		onStack[pkg] = true
+		for _, dep := range importsOf(pkg) {
+			if _, seen := index[dep]; !seen {
+				visit(dep)
+				low[pkg] = min(low[pkg], low[dep])
+			} else if onStack[dep] {
+				low[pkg] = min(low[pkg], index[dep])
+			}
+		}
+		if low[pkg] != index[pkg] {
+			return
+		}
+		component := make(map[*depPackage]bool)
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component[top] = true
+			if top == pkg {
+				break
+			}
+		}
+		if len(component) > 1 {
+			for member := range component {
+				member.cycle = true
+			}
+			g.cycles = append(g.cycles, shortestCycle(pkg, component))
+		}
+	}
+	for _, pkg := range g.sorted() {
+		if _, seen := index[pkg]; !seen {
+			visit(pkg)
+		}
+	}
+	sort.Slice(g.cycles, func(i, j int) bool {
+		return g.cycles[i][0].name < g.cycles[j][0].name
+	})
+}
+
+// shortestCycle finds the shortest cycle from start back to it within a component,
+// listed from its first package in name order
+func shortestCycle(start *depPackage, component map[*depPackage]bool) []*depPackage {
+	prev := map[*depPackage]*depPackage{}
+	queue := []*depPackage{start}
+	var last *depPackage
+	for len(queue) > 0 && last == nil {
+		pkg := queue[0]
+		queue = queue[1:]
+		for _, dep := range importsOf(pkg) {
+			if dep == start {
+				last = pkg
+				break
+			}
+			if _, seen := prev[dep]; !seen && component[dep] {
+				prev[dep] = pkg
+				queue = append(queue, dep)
+			}
+		}
+	}
+
+	var cycle []*depPackage
+	for pkg := last; pkg != start; pkg = prev[pkg] {
+		cycle = append([]*depPackage{pkg}, cycle...)
+	}
+	cycle = append([]*depPackage{start}, cycle...)
+
+	first := 0
+	for i, pkg := range cycle {
+		if pkg.name < cycle[first].name {
+			first = i
+		}
+	}
+	return append(cycle[first:], cycle[:first]...)
+}
+
+// label names a package in the output, with the Go import path of module directories
+func (p *depPackage) label() string {
+	if p.external {
+		return p.name + " (external)"
+	}
+	if p.importPath != "" && p.importPath != p.name {
+		return fmt.Sprintf("%s [%s]", p.name, p.importPath)
+	}
+	return p.name
+}
+
+func (g *depGraph) counts() (int, int, int) {
+	packages, edges, external := 0, 0, 0
+	for _, pkg := range g.packages {
+		if pkg.external {
+			external++
+			continue
+		}
+		packages++
+		for dep := range pkg.imports {
+			if !dep.external {
+				edges++
+			}
+		}
+	}
+	return packages, edges, external
+}
+
+// tree prints the graph from the packages nothing imports, each package's imports
+// indented below it. Packages already printed are not expanded again.
+func (g *depGraph) tree() string {
+	packages, edges, external := g.counts()
+	var b strings.Builder
+	fmt.Fprintf(&b, "Dependency graph of %s: %d packages, %d imports between packages", g.root, packages, edges)
+	if external > 0 {
+		fmt.Fprintf(&b, ", %d external packages", external)
+	}
+	fmt.Fprintf(&b, ", %s\n", pluralize(len(g.cycles), "import cycle"))
+
+	for _, cycle := range g.cycles {
+		names := make([]string, 0, len(cycle)+1)
+		for _, pkg := range cycle {
+			names = append(names, pkg.name)
+		}
+		names = append(names, cycle[0].name)
+		fmt.Fprintf(&b, "\nCycle: %s\n", strings.Join(names, " -> "))
+		for i, pkg := range cycle {
+			next := cycle[(i+1)%len(cycle)]
+			for _, site := range pkg.imports[next] {
+				fmt.Fprintf(&b, "  %s:%d imports %s\n", site.path, site.line, site.importPath)
+			}
+		}
+	}
+
+	imported := make(map[*depPackage]bool)
+	for _, pkg := range g.packages {
+		for dep := range pkg.imports {
+			imported[dep] = true
+		}
+	}
+	printed := make(map[*depPackage]bool)
+	var print func(pkg *depPackage, depth int, path map[*depPackage]bool)
+	print = func(pkg *depPackage, depth int, path map[*depPackage]bool) {
+		line := strings.Repeat("  ", depth) + pkg.label()
+		switch {
+		case path[pkg]:
+			b.WriteString(line + " (cycle)\n")
+			return
+		case printed[pkg] && len(pkg.imports) > 0:
+			b.WriteString(line + " (see above)\n")
+			return
+		}
+		b.WriteString(line + "\n")
+		printed[pkg] = true
+		path[pkg] = true
+		for _, dep := range importsOf(pkg) {
+			print(dep, depth+1, path)
+		}
+		delete(path, pkg)
+	}
+
+	b.WriteString("\n")
+	// Packages that are only reachable through a cycle have no importer outside it
+	for _, pkg := range g.sorted() {
+		if !pkg.external && !imported[pkg] {
+			print(pkg, 0, make(map[*depPackage]bool))
+		}
+	}
+	for _, pkg := range g.sorted() {
+		if !pkg.external && !printed[pkg] {
+			print(pkg, 0, make(map[*depPackage]bool))
+		}
+	}
+	return b.String()
+}
+
+// dot prints the graph for Graphviz, with the packages and imports of cycles in red
+func (g *depGraph) dot() string {
+	packages, edges, _ := g.counts()
+	var b strings.Builder
+	b.WriteString("digraph dependencies {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, fontname=\"monospace\"];\n")
+	fmt.Fprintf(&b, "  // %d packages, %d imports between packages, %s\n", packages, edges, pluralize(len(g.cycles), "import cycle"))
+
+	ids := make(map[*depPackage]string)
+	for i, pkg := range g.sorted() {
+		ids[pkg] = fmt.Sprintf("p%d", i+1)
+		attrs := []string{fmt.Sprintf("label=%s", dotQuote(pkg.name))}
+		if pkg.importPath != "" {
+			attrs = append(attrs, fmt.Sprintf("tooltip=%s", dotQuote(pkg.importPath)))
+		}
+		if pkg.external {
+			attrs = append(attrs, "shape=ellipse", "style=dashed")
+		}
+		if pkg.cycle {
+			attrs = append(attrs, "color=red")
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", ids[pkg], strings.Join(attrs, ", "))
+	}
+
+	inCycle := make(map[[2]*depPackage]bool)
+	for _, cycle := range g.cycles {
+		for i, pkg := range cycle {
+			inCycle[[2]*depPackage{pkg, cycle[(i+1)%len(cycle)]}] = true
+		}
+	}
+	for _, pkg := range g.sorted() {
+		for _, dep := range importsOf(pkg) {
+			var attrs []string
+			if n := len(pkg.imports[dep]); n > 1 {
+				attrs = append(attrs, fmt.Sprintf("label=\"%d\"", n))
+			}
+			if inCycle[[2]*depPackage{pkg, dep}] {
+				attrs = append(attrs, "color=red", "penwidth=2")
+			} else if pkg.cycle && dep.cycle {
+				attrs = append(attrs, "color=red")
+			}
+			if len(attrs) > 0 {
+				fmt.Fprintf(&b, "  %s -> %s [%s];\n", ids[pkg], ids[dep], strings.Join(attrs, ", "))
+			} else {
+				fmt.Fprintf(&b, "  %s -> %s;\n", ids[pkg], ids[dep])
+			}
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// goModulePath reads the module path of a go.mod file
+func goModulePath(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+	return ""
+}
+
+// npmPackageName reads the name of a package.json, so imports of packages of an npm
+// workspace resolve to their directories
+func npmPackageName(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	var pkg struct {
+		Name string `json:"name"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return ""
+	}
+	return pkg.Name
+}
@@ -0,0 +1,181 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+)
+
+const (
+	// batchDiagnosticsTimeout is how long to wait for the server to publish
+	// diagnostics for the files it did not have open
+	batchDiagnosticsTimeout = 5 * time.Second
+
+	// maxDiagnosticsFiles caps the number of files opened by one get_diagnostics call
+	maxDiagnosticsFiles = 100
+)
+
+// GetDiagnosticsForFiles reports the diagnostics of several files in one grouped
+// report. The files are the given paths and, when directory or glob is set, the
+// workspace files under directory whose path relative to the workspace matches glob.
+// All files are opened before waiting, so the server checks them concurrently.
+func GetDiagnosticsForFiles(ctx context.Context, client *lsp.Client, w *watcher.WorkspaceWatcher, filePaths []string, directory string, glob string, includeContext bool, showLineNumbers bool) (string, error) {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, filePath := range filePaths {
+		path, err := filepath.Abs(filePath)
+		if err != nil {
+			return "", fmt.Errorf("invalid path %s: %v", filePath, err)
+		}
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+
+	if directory != "" || glob != "" {
+		found, err := findDiagnosticsFiles(ctx, w, directory, glob)
+		if err != nil {
+			return "", err
+		}
+		for _, path := range found {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return "No files to get diagnostics for.", nil
+	}
+	sort.Strings(paths)
+
+	var hints ResponseHints
+	if len(paths) > maxDiagnosticsFiles {
+		hints.Omitted = len(paths) - maxDiagnosticsFiles
+		hints.OmittedKind = "files"
+		hints.Suggestions = []string{`a narrower directory or glob, e.g. glob="internal/**/*.go"`}
+		paths = paths[:maxDiagnosticsFiles]
+	}
+
+	if err := openForDiagnostics(ctx, client, paths, batchDiagnosticsTimeout); err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	var totals []protocol.Diagnostic
+	var clean []string
+	for _, path := range paths {
+		uri := protocol.DocumentUri("file://" + path)
+		diagnostics := client.GetFileDiagnostics(uri)
+		if len(diagnostics) == 0 {
+			clean = append(clean, path)
+			continue
+		}
+		totals = append(totals, diagnostics...)
+		result.WriteString("\n")
+		result.WriteString(formatFileDiagnostics(ctx, client, uri, diagnostics, includeContext, showLineNumbers))
+	}
+
+	var header strings.Builder
+	errors, warnings := countSeverities(totals)
+	header.WriteString(fmt.Sprintf("%s checked: %s and %s, %s in %s\n",
+		pluralize(len(paths), "file"), pluralize(errors, "error"), pluralize(warnings, "warning"),
+		pluralize(len(totals), "diagnostic"), pluralize(len(paths)-len(clean), "file")))
+
+	if len(clean) > 0 {
+		result.WriteString(fmt.Sprintf("\nNo diagnostics in %s", pluralize(len(clean), "file")))
+		if len(clean) <= 10 {
+			relative := make([]string, len(clean))
+			for i, path := range clean {
+				relative[i] = relativeTo(w, path)
+			}
+			result.WriteString(": " + strings.Join(relative, ", "))
+		}
+		result.WriteString("\n")
+	}
+	return AddFooter(header.String()+result.String(), hints), nil
+}
+
+// findDiagnosticsFiles walks the workspace files under directory, or the whole
+// workspace if it is empty, that are not excluded and match glob. Files of unknown
+// languages are left out unless glob selects them.
+func findDiagnosticsFiles(ctx context.Context, w *watcher.WorkspaceWatcher, directory string, glob string) ([]string, error) {
+	root := w.WorkspacePath()
+	if directory == "" {
+		directory = root
+	}
+	directory, err := filepath.Abs(directory)
+	if err != nil {
+		return nil, fmt.Errorf("invalid directory: %v", err)
+	}
+	info, err := os.Stat(directory)
+	if err != nil {
+		return nil, fmt.Errorf("invalid directory: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", directory)
+	}
+	if directory != root && !strings.HasPrefix(directory, root+string(filepath.Separator)) {
+		return nil, fmt.Errorf("%s is outside the workspace %s", directory, root)
+	}
+
+	var paths []string
+	err = w.WalkWorkspace(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip unreadable entries
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if d.IsDir() {
+			// Only descend into directory and the directories leading to it
+			inside := path == directory || strings.HasPrefix(path, directory+string(filepath.Separator))
+			above := strings.HasPrefix(directory, path+string(filepath.Separator))
+			if (!inside && !above) || w.IsExcluded(path, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasPrefix(path, directory+string(filepath.Separator)) || w.IsExcluded(path, false) {
+			return nil
+		}
+
+		if glob != "" {
+			relPath, err := filepath.Rel(root, path)
+			if err != nil {
+				relPath = path
+			}
+			if !matchesAnyGlob([]string{glob}, relPath) {
+				return nil
+			}
+		} else if lsp.DetectLanguageID(path) == "" {
+			return nil
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// relativeTo returns path relative to the workspace, or unchanged if it is outside
+func relativeTo(w *watcher.WorkspaceWatcher, path string) string {
+	relPath, err := filepath.Rel(w.WorkspacePath(), path)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return path
+	}
+	return relPath
+}
@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// defaultSummaryFiles is how many files the diagnostics summary lists by default
+const defaultSummaryFiles = 50
+
+// fileSeverityCounts is the number of diagnostics of each severity in one file
+type fileSeverityCounts struct {
+	path     string
+	errors   int
+	warnings int
+	others   int // Information and hints
+}
+
+// GetDiagnosticsSummary lists the number of errors and warnings per file across the
+// workspace, without messages, files with the most errors first. It reports the
+// diagnostics the language server has published so far and opens no files.
+func GetDiagnosticsSummary(client *lsp.Client, workspaceDir string, errorsOnly bool, maxFiles int) string {
+	if maxFiles <= 0 {
+		maxFiles = defaultSummaryFiles
+	}
+
+	var files []fileSeverityCounts
+	var totals fileSeverityCounts
+	for uri, diagnostics := range client.GetAllDiagnostics() {
+		path := strings.TrimPrefix(string(uri), "file://")
+		counts := fileSeverityCounts{path: path}
+		if relPath, err := filepath.Rel(workspaceDir, path); err == nil && !strings.HasPrefix(relPath, "..") {
+			counts.path = relPath
+		}
+		for _, diag := range diagnostics {
+			switch diag.Severity {
+			case protocol.SeverityError:
+				counts.errors++
+			case protocol.SeverityWarning:
+				counts.warnings++
+			default:
+				counts.others++
+			}
+		}
+		if counts.errors == 0 && (errorsOnly || counts.warnings+counts.others == 0) {
+			continue
+		}
+		totals.errors += counts.errors
+		totals.warnings += counts.warnings
+		totals.others += counts.others
+		files = append(files, counts)
+	}
+
+	if len(files) == 0 {
+		if errorsOnly {
+			return "No errors in the workspace"
+		}
+		return "No diagnostics in the workspace"
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		a, b := files[i], files[j]
+		if a.errors != b.errors {
+			return a.errors > b.errors
+		}
+		if a.warnings != b.warnings {
+			return a.warnings > b.warnings
+		}
+		if a.others != b.others {
+			return a.others > b.others
+		}
+		return a.path < b.path
+	})
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Workspace diagnostics: %s and %s", pluralize(totals.errors, "error"), pluralize(totals.warnings, "warning")))
+	if totals.others > 0 && !errorsOnly {
+		result.WriteString(fmt.Sprintf(", %d info/hint", totals.others))
+	}
+	result.WriteString(fmt.Sprintf(" in %s\n\n", pluralize(len(files), "file")))
+
+	var hints ResponseHints
+	if len(files) > maxFiles {
+		hints.Omitted = len(files) - maxFiles
+		hints.OmittedKind = "files"
+		if !errorsOnly {
+			hints.Suggestions = []string{"errorsOnly=true to list only files with errors"}
+		}
+		files = files[:maxFiles]
+	}
+
+	for _, file := range files {
+		result.WriteString(fmt.Sprintf("%s: %s, %s", file.path, pluralize(file.errors, "error"), pluralize(file.warnings, "warning")))
+		if file.others > 0 && !errorsOnly {
+			result.WriteString(fmt.Sprintf(", %d info/hint", file.others))
+		}
+		result.WriteString("\n")
+	}
+	return AddFooter(result.String(), hints)
+}
@@ -3,44 +3,68 @@ package tools
 import (
 	"context"
 	"fmt"
-	"log"
-	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/text"
 )
 
-// GetDiagnostics retrieves diagnostics for a specific file from the language server
-func GetDiagnosticsForFile(ctx context.Context, client *lsp.Client, filePath string, includeContext bool, showLineNumbers bool) (string, error) {
-	err := client.OpenFile(ctx, filePath)
-	if err != nil {
-		return "", fmt.Errorf("could not open file: %v", err)
-	}
-
-	// Wait for diagnostics
-	// TODO: wait for notification
-	time.Sleep(time.Second * 3)
+// fileDiagnosticsTimeout is how long get_diagnostics waits for a server that
+// publishes diagnostics to report on a file it did not have open
+const fileDiagnosticsTimeout = 3 * time.Second
 
-	// Convert the file path to URI format
+// GetDiagnostics retrieves diagnostics for a specific file from the language server.
+// Servers with pull diagnostics are asked for a fresh report, the others are given
+// time to publish diagnostics for a file they did not have open or whose cached
+// diagnostics describe an earlier version of its text.
+func GetDiagnosticsForFile(ctx context.Context, client *lsp.Client, filePath string, includeContext bool, showLineNumbers bool) (string, error) {
 	uri := protocol.DocumentUri("file://" + filePath)
 
-	// Request fresh diagnostics
-	diagParams := protocol.DocumentDiagnosticParams{
-		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	var published <-chan []protocol.Diagnostic
+	if !client.PullsDiagnostics() && (!client.IsFileOpen(filePath) || !client.HasCurrentDiagnostics(uri)) {
+		published = client.NextDiagnostics(uri)
 	}
-	_, err = client.Diagnostic(ctx, diagParams)
+	err := client.OpenFile(ctx, filePath)
 	if err != nil {
-		log.Printf("failed to get diagnostics: %v", err)
+		return "", fmt.Errorf("could not open file: %v", err)
 	}
 
-	// Get diagnostics from the cache
-	diagnostics := client.GetFileDiagnostics(uri)
+	var diagnostics []protocol.Diagnostic
+	if client.PullsDiagnostics() {
+		diagnostics, err = client.PullDiagnostics(ctx, uri)
+		if err != nil {
+			return "", fmt.Errorf("failed to pull diagnostics: %v", err)
+		}
+	} else {
+		var received bool
+		if published != nil {
+			diagnostics, received = waitForDiagnostics(ctx, client, uri, published, fileDiagnosticsTimeout)
+		}
+		if !received {
+			diagnostics = client.GetFileDiagnostics(uri)
+		}
+	}
 
 	if len(diagnostics) == 0 {
+		if !client.PullsDiagnostics() && !client.HasCurrentDiagnostics(uri) {
+			return fmt.Sprintf("No diagnostics found for %s. The language server has not reported on its current text yet, try again shortly.", filePath), nil
+		}
 		return "No diagnostics found for " + filePath, nil
 	}
+	return formatFileDiagnostics(ctx, client, uri, diagnostics, includeContext, showLineNumbers), nil
+}
+
+// formatFileDiagnostics lists a document's diagnostics with the line each starts on,
+// or with the enclosing definition when includeContext is set or the verbosity is
+// full. The minimal verbosity leaves the line out.
+func formatFileDiagnostics(ctx context.Context, client *lsp.Client, uri protocol.DocumentUri, diagnostics []protocol.Diagnostic, includeContext bool, showLineNumbers bool) string {
+	filePath := documentPath(uri)
+	verbosity := VerbosityFromContext(ctx)
+	includeContext = includeContext || verbosity == VerbosityFull
+	showCode := includeContext || verbosity != VerbosityMinimal
 
 	// Create a summary header
 	summary := fmt.Sprintf("Diagnostics for %s (%d issues)\n",
@@ -61,10 +85,9 @@ func GetDiagnosticsForFile(ctx context.Context, client *lsp.Client, filePath str
 		var codeContext string
 		var startLine uint32
 
-		// Always get at least the line with the diagnostic
-		content, err := os.ReadFile(filePath)
-		if err == nil {
-			lines := strings.Split(string(content), "\n")
+		// Get at least the line with the diagnostic
+		if content, err := client.ReadDocument(ctx, uri); err == nil && showCode {
+			lines := text.Lines(content)
 			if int(diag.Range.Start.Line) < len(lines) {
 				codeContext = strings.TrimSpace(lines[diag.Range.Start.Line])
 
@@ -118,15 +141,19 @@ func GetDiagnosticsForFile(ctx context.Context, client *lsp.Client, filePath str
 			formattedDiag.WriteString(fmt.Sprintf("   %s\n", strings.Join(details, ", ")))
 		}
 
-		// Add code context
+		// Add code context, a single line inline and the full definition as a block
 		if codeContext != "" {
-			formattedDiag.WriteString(fmt.Sprintf("   > %s\n", codeContext))
+			if includeContext {
+				formattedDiag.WriteString(indentBlock(fenceCode(codeContext, filePath), "   ") + "\n")
+			} else {
+				formattedDiag.WriteString(fmt.Sprintf("   > %s\n", codeContext))
+			}
 		}
 
 		formattedDiagnostics = append(formattedDiagnostics, formattedDiag.String())
 	}
 
-	return strings.Join(formattedDiagnostics, ""), nil
+	return strings.Join(formattedDiagnostics, "")
 }
 
 func getSeverityString(severity protocol.DiagnosticSeverity) string {
@@ -143,3 +170,63 @@ func getSeverityString(severity protocol.DiagnosticSeverity) string {
 		return "UNKNOWN"
 	}
 }
+
+// FormatDiagnostics formats cached diagnostics for a file without requesting fresh ones
+func FormatDiagnostics(filePath string, diagnostics []protocol.Diagnostic) string {
+	if len(diagnostics) == 0 {
+		return "No diagnostics found for " + filePath
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Diagnostics for %s (%d issues)\n", filePath, len(diagnostics)))
+	for i, diag := range diagnostics {
+		result.WriteString(fmt.Sprintf("%d. [%s] L%d:C%d - %s\n",
+			i+1,
+			getSeverityString(diag.Severity),
+			diag.Range.Start.Line+1,
+			diag.Range.Start.Character+1,
+			diag.Message))
+		if diag.Source != "" {
+			result.WriteString(fmt.Sprintf("   Source: %s\n", diag.Source))
+		}
+	}
+	return result.String()
+}
+
+// FormatDiagnosticsSummary lists the number of diagnostics of each severity per file
+func FormatDiagnosticsSummary(all map[protocol.DocumentUri][]protocol.Diagnostic) string {
+	uris := make([]string, 0, len(all))
+	for uri, diagnostics := range all {
+		if len(diagnostics) > 0 {
+			uris = append(uris, string(uri))
+		}
+	}
+	sort.Strings(uris)
+
+	if len(uris) == 0 {
+		return "No diagnostics in the workspace"
+	}
+
+	totals := make(map[protocol.DiagnosticSeverity]int)
+	var files strings.Builder
+	for _, uri := range uris {
+		counts := make(map[protocol.DiagnosticSeverity]int)
+		for _, diag := range all[protocol.DocumentUri(uri)] {
+			counts[diag.Severity]++
+			totals[diag.Severity]++
+		}
+		files.WriteString(fmt.Sprintf("%s: %s\n", strings.TrimPrefix(uri, "file://"), formatSeverityCounts(counts)))
+	}
+
+	return fmt.Sprintf("Workspace diagnostics: %s in %d files\n\n%s", formatSeverityCounts(totals), len(uris), files.String())
+}
+
+func formatSeverityCounts(counts map[protocol.DiagnosticSeverity]int) string {
+	var parts []string
+	for _, severity := range []protocol.DiagnosticSeverity{protocol.SeverityError, protocol.SeverityWarning, protocol.SeverityInformation, protocol.SeverityHint, 0} {
+		if counts[severity] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[severity], getSeverityString(severity)))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
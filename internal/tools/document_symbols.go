@@ -10,10 +10,24 @@ import (
 	"github.com/isaacphi/mcp-language-server/internal/utilities"
 )
 
-// GetDocumentSymbols retrieves all symbols in a document and formats them in a hierarchical structure
-func GetDocumentSymbols(ctx context.Context, client *lsp.Client, filePath string, showLineNumbers bool) (string, error) {
+// symbolFilter restricts which document symbols are listed
+type symbolFilter struct {
+	kinds    map[protocol.SymbolKind]bool // empty means all kinds
+	maxDepth int                          // 0 means unlimited
+}
+
+// GetDocumentSymbols retrieves all symbols in a document and formats them in a hierarchical structure.
+// kinds restricts the listed symbols to those kinds, keeping their ancestors for context,
+// and maxDepth limits how many levels of nesting are shown. Omitted symbols are counted.
+func GetDocumentSymbols(ctx context.Context, client *lsp.Client, filePath string, showLineNumbers bool, kinds []string, maxDepth int) (string, error) {
+	kindFilter, err := utilities.ParseSymbolKinds(kinds)
+	if err != nil {
+		return "", err
+	}
+	filter := symbolFilter{kinds: kindFilter, maxDepth: maxDepth}
+
 	// Open the file if not already open
-	err := client.OpenFile(ctx, filePath)
+	err = client.OpenFile(ctx, filePath)
 	if err != nil {
 		return "", fmt.Errorf("could not open file: %v", err)
 	}
@@ -47,16 +61,26 @@ func GetDocumentSymbols(ctx context.Context, client *lsp.Client, filePath string
 	result.WriteString(fmt.Sprintf("Symbols in %s\n\n", filePath))
 
 	// Format symbols hierarchically
-	formatSymbols(&result, symbols, 0, showLineNumbers)
+	formatSymbols(&result, symbols, 0, showLineNumbers, filter)
 
-	return result.String(), nil
+	var hints ResponseHints
+	if len(kinds) == 0 && maxDepth == 0 {
+		hints.Suggestions = []string{"maxDepth=1 for top-level symbols only", `kinds=["Function","Method"] to list only functions and methods`}
+	}
+	return AddFooter(result.String(), hints), nil
 }
 
 // formatSymbols recursively formats symbols with proper indentation
-func formatSymbols(sb *strings.Builder, symbols []protocol.DocumentSymbolResult, level int, showLineNumbers bool) {
+func formatSymbols(sb *strings.Builder, symbols []protocol.DocumentSymbolResult, level int, showLineNumbers bool, filter symbolFilter) {
 	indent := strings.Repeat("  ", level)
+	omitted := 0
 
 	for _, sym := range symbols {
+		if !filter.visible(sym) {
+			omitted += countSymbols(sym)
+			continue
+		}
+
 		// Get symbol information
 		name := sym.GetName()
 
@@ -74,20 +98,67 @@ func formatSymbols(sb *strings.Builder, symbols []protocol.DocumentSymbolResult,
 		// Use the shared utility to extract kind information
 		kindStr := utilities.ExtractSymbolKind(sym)
 
+		children := symbolChildren(sym)
+
+		// Children below the depth limit are summarized on the parent's line
+		hidden := ""
+		if filter.maxDepth > 0 && level+1 >= filter.maxDepth && len(children) > 0 {
+			count := 0
+			for _, child := range children {
+				count += countSymbols(child)
+			}
+			hidden = fmt.Sprintf(" [%d nested symbols omitted]", count)
+			children = nil
+		}
+
 		// Format the symbol entry
 		if location != "" {
-			sb.WriteString(fmt.Sprintf("%s%s %s (%s)\n", indent, kindStr, name, location))
+			sb.WriteString(fmt.Sprintf("%s%s %s (%s)%s\n", indent, kindStr, name, location, hidden))
 		} else {
-			sb.WriteString(fmt.Sprintf("%s%s %s\n", indent, kindStr, name))
+			sb.WriteString(fmt.Sprintf("%s%s %s%s\n", indent, kindStr, name, hidden))
 		}
 
-		// Format children if it's a DocumentSymbol
-		if ds, ok := sym.(*protocol.DocumentSymbol); ok && len(ds.Children) > 0 {
-			childSymbols := make([]protocol.DocumentSymbolResult, len(ds.Children))
-			for i := range ds.Children {
-				childSymbols[i] = &ds.Children[i]
-			}
-			formatSymbols(sb, childSymbols, level+1, showLineNumbers)
+		if len(children) > 0 {
+			formatSymbols(sb, children, level+1, showLineNumbers, filter)
 		}
 	}
+
+	if omitted > 0 {
+		sb.WriteString(fmt.Sprintf("%s... %d symbols of other kinds omitted\n", indent, omitted))
+	}
+}
+
+// visible reports whether a symbol matches the kind filter or contains a symbol that does
+func (f symbolFilter) visible(sym protocol.DocumentSymbolResult) bool {
+	if len(f.kinds) == 0 || f.kinds[sym.GetKind()] {
+		return true
+	}
+	for _, child := range symbolChildren(sym) {
+		if f.visible(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// symbolChildren returns the nested symbols of a DocumentSymbol
+func symbolChildren(sym protocol.DocumentSymbolResult) []protocol.DocumentSymbolResult {
+	ds, ok := sym.(*protocol.DocumentSymbol)
+	if !ok || len(ds.Children) == 0 {
+		return nil
+	}
+	children := make([]protocol.DocumentSymbolResult, len(ds.Children))
+	for i := range ds.Children {
+		children[i] = &ds.Children[i]
+	}
+	return children
+}
+
+// countSymbols counts a symbol and all of its descendants
+func countSymbols(sym protocol.DocumentSymbolResult) int {
+	count := 1
+	for _, child := range symbolChildren(sym) {
+		count += countSymbols(child)
+	}
+	return count
 }
@@ -3,33 +3,25 @@ package tools
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 )
 
-// ExecuteCodeLens executes a specific code lens command from a file.
+// ExecuteCodeLens executes a specific code lens command from a file. The index
+// refers to the lenses get_codelens listed, which fails if the file has changed
+// since.
 func ExecuteCodeLens(ctx context.Context, client *lsp.Client, filePath string, index int) (string, error) {
 	// Open the file
 	err := client.OpenFile(ctx, filePath)
 	if err != nil {
 		return "", fmt.Errorf("could not open file: %v", err)
 	}
-	// TODO: find a more appropriate way to wait
-	time.Sleep(time.Second)
 
 	// Get code lenses
-	docIdentifier := protocol.TextDocumentIdentifier{
-		URI: protocol.DocumentUri("file://" + filePath),
-	}
-
-	params := protocol.CodeLensParams{
-		TextDocument: docIdentifier,
-	}
-	codeLenses, err := client.CodeLens(ctx, params)
+	codeLenses, err := listedCodeLenses(ctx, client, filePath)
 	if err != nil {
-		return "", fmt.Errorf("Failed to get code lenses: %v", err)
+		return "", err
 	}
 
 	if len(codeLenses) == 0 {
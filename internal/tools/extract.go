@@ -0,0 +1,218 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// extractTarget is what an extract refactoring creates
+type extractTarget struct {
+	noun string
+	// Sub-kinds of refactor.extract servers use for it
+	kinds []string
+	// Words in the titles of actions that only have the refactor.extract kind
+	titleWords []string
+}
+
+var (
+	extractFunctionTarget = extractTarget{
+		noun:       "function",
+		kinds:      []string{"refactor.extract.function", "refactor.extract.method"},
+		titleWords: []string{"function", "method"},
+	}
+	extractVariableTarget = extractTarget{
+		noun:       "variable",
+		kinds:      []string{"refactor.extract.variable", "refactor.extract.constant"},
+		titleWords: []string{"variable", "constant", "local"},
+	}
+)
+
+var identifierPattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// ExtractFunction moves the code in a range of a file into a new function, using
+// the server's refactor.extract code action. If newName is set, the name the server
+// chose is then renamed to it.
+func ExtractFunction(ctx context.Context, client *lsp.Client, filePath string, startLine, startColumn, endLine, endColumn int, newName string) (string, error) {
+	return extract(ctx, client, filePath, startLine, startColumn, endLine, endColumn, newName, extractFunctionTarget)
+}
+
+// ExtractVariable replaces the expression in a range of a file with a new variable,
+// using the server's refactor.extract code action. If newName is set, the name the
+// server chose is then renamed to it.
+func ExtractVariable(ctx context.Context, client *lsp.Client, filePath string, startLine, startColumn, endLine, endColumn int, newName string) (string, error) {
+	return extract(ctx, client, filePath, startLine, startColumn, endLine, endColumn, newName, extractVariableTarget)
+}
+
+func extract(ctx context.Context, client *lsp.Client, filePath string, startLine, startColumn, endLine, endColumn int, newName string, target extractTarget) (string, error) {
+	if err := client.OpenFile(ctx, filePath); err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+	uri := protocol.DocumentUri("file://" + filePath)
+
+	rng, err := lineRange(client, filePath, startLine, startColumn, endLine, endColumn)
+	if err != nil {
+		return "", err
+	}
+	actions, err := codeActions(ctx, client, uri, rng, diagnosticsInRange(client.GetFileDiagnostics(uri), rng), []string{string(protocol.RefactorExtract)})
+	if err != nil {
+		return "", err
+	}
+
+	var candidates, disabled []protocol.CodeAction
+	for _, action := range actions {
+		if !target.matches(action) {
+			continue
+		}
+		if action.Disabled != nil {
+			disabled = append(disabled, action)
+			continue
+		}
+		candidates = append(candidates, action)
+	}
+	if len(candidates) == 0 {
+		if len(disabled) > 0 {
+			return "", fmt.Errorf("cannot extract a %s from %s: %s", target.noun, formatRange(filePath, rng), disabled[0].Disabled.Reason)
+		}
+		return "", fmt.Errorf("the language server offers no extract %s action for %s, check that the range covers complete statements or an expression", target.noun, formatRange(filePath, rng))
+	}
+	action := candidates[0]
+
+	before, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not read file: %v", err)
+	}
+	changed, err := applyCodeAction(ctx, client, action)
+	if err != nil {
+		return "", err
+	}
+	after, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not read file after extracting: %v", err)
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Applied %q.\n", action.Title))
+
+	// Servers name the new function or variable themselves, rename it when asked to
+	if newName != "" {
+		generated, position, found := introducedIdentifier(string(before), string(after))
+		if !found {
+			result.WriteString(fmt.Sprintf("Could not find the name the server gave the new %s, it was not renamed to %s.\n", target.noun, newName))
+		} else if generated != newName {
+			renamed, err := renameAt(ctx, client, filePath, position, newName)
+			if err != nil {
+				result.WriteString(fmt.Sprintf("Renaming %s to %s failed: %v\n", generated, newName, err))
+			} else {
+				result.WriteString(fmt.Sprintf("Renamed %s to %s.\n", generated, newName))
+				changed = append(changed, withoutPath(renamed, filePath)...)
+				if after, err = os.ReadFile(filePath); err != nil {
+					return "", fmt.Errorf("could not read file after renaming: %v", err)
+				}
+			}
+		}
+	}
+
+	diff, err := utilities.UnifiedDiff(filePath, filePath, string(before), string(after), 3)
+	if err != nil {
+		return "", err
+	}
+	if diff == "" {
+		result.WriteString("The file did not change.\n")
+	} else {
+		result.WriteString("\n" + diff)
+	}
+	if others := withoutPath(changed, filePath); len(others) > 0 {
+		result.WriteString(fmt.Sprintf("\nOther files changed: %s\n", strings.Join(others, ", ")))
+	}
+	if len(candidates) > 1 {
+		result.WriteString("\nOther extract actions offered:\n")
+		for _, other := range candidates[1:] {
+			result.WriteString(fmt.Sprintf("- %s\n", other.Title))
+		}
+	}
+	result.WriteString("\nWARNING: line numbers may have changed. Re-read code before applying additional edits.\n")
+	return result.String(), nil
+}
+
+// matches reports whether an action extracts this target, by its kind or, for
+// servers that only use refactor.extract, by its title
+func (t extractTarget) matches(action protocol.CodeAction) bool {
+	if matchesKind(action.Kind, t.kinds) {
+		return true
+	}
+	if action.Kind != protocol.RefactorExtract {
+		return false
+	}
+	title := strings.ToLower(action.Title)
+	for _, word := range t.titleWords {
+		if strings.Contains(title, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// introducedIdentifier finds the name a refactoring added: the identifier absent
+// from before that occurs most often in after. It returns the position of its first
+// occurrence.
+func introducedIdentifier(before, after string) (string, protocol.Position, bool) {
+	existing := make(map[string]bool)
+	for _, name := range identifierPattern.FindAllString(before, -1) {
+		existing[name] = true
+	}
+
+	counts := make(map[string]int)
+	first := make(map[string]int)
+	var order []string
+	for _, loc := range identifierPattern.FindAllStringIndex(after, -1) {
+		name := after[loc[0]:loc[1]]
+		if existing[name] {
+			continue
+		}
+		if counts[name] == 0 {
+			first[name] = loc[0]
+			order = append(order, name)
+		}
+		counts[name]++
+	}
+
+	best := ""
+	for _, name := range order {
+		if best == "" || counts[name] > counts[best] {
+			best = name
+		}
+	}
+	if best == "" {
+		return "", protocol.Position{}, false
+	}
+	prefix := after[:first[best]]
+	line := strings.Count(prefix, "\n")
+	character := len(prefix) - strings.LastIndex(prefix, "\n") - 1
+	return best, protocol.Position{Line: uint32(line), Character: uint32(character)}, true
+}
+
+// renameAt renames the symbol at a position and applies the edit, returning the
+// files it changed
+func renameAt(ctx context.Context, client *lsp.Client, filePath string, position protocol.Position, newName string) ([]string, error) {
+	edit, err := client.Rename(ctx, protocol.RenameParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentUri("file://" + filePath)},
+		Position:     position,
+		NewName:      newName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := utilities.ApplyWorkspaceEdit(edit); err != nil {
+		return nil, err
+	}
+	changed := editedFiles(edit)
+	syncEditedFiles(ctx, client, changed)
+	return changed, nil
+}
@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+// codeFences controls whether code in tool output is wrapped in fenced blocks
+var codeFences = true
+
+// SetCodeFences turns fenced code blocks in tool output on or off. It must be
+// called before tools are used.
+func SetCodeFences(enabled bool) {
+	codeFences = enabled
+}
+
+// fenceLanguages maps LSP language ids to the names markdown renderers know
+var fenceLanguages = map[string]string{
+	"typescriptreact": "tsx",
+	"javascriptreact": "jsx",
+	"shellscript":     "bash",
+	"objective-c":     "objc",
+	"objective-cpp":   "objcpp",
+}
+
+// fenceCode wraps code taken from path in a fenced block tagged with the file's
+// language. The fence is longer than any run of backticks in the code, so fences
+// inside the code do not end the block. Code is returned unchanged when fences
+// are disabled. The result always ends with a newline.
+func fenceCode(code string, path string) string {
+	return fenceCodeAs(code, fenceLanguage(path))
+}
+
+// fenceCodeAs is fenceCode with an explicit info string, e.g. "diff"
+func fenceCodeAs(code string, language string) string {
+	code = strings.TrimRight(code, "\n")
+	if !codeFences {
+		return code + "\n"
+	}
+
+	fence := strings.Repeat("`", max(3, longestBacktickRun(code)+1))
+	return fence + language + "\n" + code + "\n" + fence + "\n"
+}
+
+// fenceLanguage returns the info string for code from path, empty if unknown
+func fenceLanguage(path string) string {
+	language := string(lsp.DetectLanguageID(path))
+	if name, ok := fenceLanguages[language]; ok {
+		return name
+	}
+	return language
+}
+
+func longestBacktickRun(text string) int {
+	longest, run := 0, 0
+	for _, c := range text {
+		if c == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return longest
+}
+
+// indentBlock indents every line of a block of text
+func indentBlock(text string, indent string) string {
+	text = strings.TrimRight(text, "\n")
+	return indent + strings.ReplaceAll(text, "\n", "\n"+indent)
+}
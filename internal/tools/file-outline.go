@@ -0,0 +1,189 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/text"
+)
+
+// elidedSpan is a 0-indexed, inclusive range of lines hidden from the outline
+type elidedSpan struct {
+	start int
+	end   int
+}
+
+// GetFileOutline returns the source of a file with function and method bodies elided,
+// keeping signatures, type declarations and doc comments
+func GetFileOutline(ctx context.Context, client *lsp.Client, filePath string, showLineNumbers bool) (string, error) {
+	err := client.OpenFile(ctx, filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+
+	content, err := client.GetFileContent(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	lines := text.Lines(content)
+
+	uri := protocol.DocumentUri("file://" + filePath)
+
+	symResult, err := client.DocumentSymbol(ctx, protocol.DocumentSymbolParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get document symbols: %v", err)
+	}
+	symbols, err := symResult.Results()
+	if err != nil {
+		return "", fmt.Errorf("failed to process document symbols: %v", err)
+	}
+
+	// Folding ranges are optional, we fall back to symbol ranges without them
+	foldingRanges, err := client.FoldingRange(ctx, protocol.FoldingRangeParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	})
+	if err != nil {
+		debugLogger.Printf("Warning: failed to get folding ranges for %s: %v\n", filePath, err)
+	}
+
+	var spans []elidedSpan
+	var collect func(symbols []protocol.DocumentSymbolResult)
+	collect = func(symbols []protocol.DocumentSymbolResult) {
+		for _, sym := range symbols {
+			ds, ok := sym.(*protocol.DocumentSymbol)
+			if !ok {
+				// SymbolInformation only has one range, use it as both declaration and body
+				if si, ok := sym.(*protocol.SymbolInformation); ok && hasBody(si.Kind) {
+					if span, ok := bodySpan(si.Location.Range, si.Location.Range, foldingRanges); ok {
+						spans = append(spans, span)
+					}
+				}
+				continue
+			}
+
+			if hasBody(ds.Kind) {
+				if span, ok := bodySpan(ds.Range, ds.SelectionRange, foldingRanges); ok {
+					spans = append(spans, span)
+					// Nested symbols are inside the elided body
+					continue
+				}
+			}
+
+			if len(ds.Children) > 0 {
+				children := make([]protocol.DocumentSymbolResult, len(ds.Children))
+				for i := range ds.Children {
+					children[i] = &ds.Children[i]
+				}
+				collect(children)
+			}
+		}
+	}
+	collect(symbols)
+
+	spans = mergeSpans(spans)
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Outline of %s\n\n", filePath))
+	result.WriteString(formatOutline(lines, spans, showLineNumbers))
+
+	return result.String(), nil
+}
+
+// hasBody reports whether symbols of this kind have a body worth eliding
+func hasBody(kind protocol.SymbolKind) bool {
+	switch kind {
+	case protocol.Function, protocol.Method, protocol.Constructor:
+		return true
+	}
+	return false
+}
+
+// bodySpan finds the lines of a symbol's body that can be elided. It prefers the
+// first folding range that starts at or after the symbol's name, and falls back
+// to everything between the name and the last line of the symbol.
+func bodySpan(symRange, selRange protocol.Range, foldingRanges []protocol.FoldingRange) (elidedSpan, bool) {
+	startLine := int(selRange.Start.Line)
+	endLine := int(symRange.End.Line)
+
+	bodyStart := -1
+	for _, fr := range foldingRanges {
+		if fr.Kind == string(protocol.Comment) || fr.Kind == string(protocol.Imports) {
+			continue
+		}
+		if int(fr.StartLine) < startLine || int(fr.EndLine) > endLine {
+			continue
+		}
+		if bodyStart == -1 || int(fr.StartLine) < bodyStart {
+			bodyStart = int(fr.StartLine)
+		}
+	}
+	if bodyStart == -1 {
+		bodyStart = int(selRange.End.Line)
+	}
+
+	// Keep the line that opens the body and the line that closes it
+	span := elidedSpan{start: bodyStart + 1, end: endLine - 1}
+	if span.start > span.end {
+		return elidedSpan{}, false
+	}
+	return span, true
+}
+
+// mergeSpans sorts spans and merges overlapping ones
+func mergeSpans(spans []elidedSpan) []elidedSpan {
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var merged []elidedSpan
+	for _, span := range spans {
+		if len(merged) > 0 && span.start <= merged[len(merged)-1].end+1 {
+			if span.end > merged[len(merged)-1].end {
+				merged[len(merged)-1].end = span.end
+			}
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged
+}
+
+// formatOutline renders lines, replacing each elided span with a single marker line
+func formatOutline(lines []string, spans []elidedSpan, showLineNumbers bool) string {
+	padding := len(strconv.Itoa(len(lines)))
+
+	var result strings.Builder
+	spanIdx := 0
+	for i := 0; i < len(lines); i++ {
+		if spanIdx < len(spans) && i == spans[spanIdx].start {
+			span := spans[spanIdx]
+			spanIdx++
+
+			indent := leadingWhitespace(lines[span.start])
+			marker := fmt.Sprintf("%s... (%d lines elided)", indent, span.end-span.start+1)
+			if showLineNumbers {
+				marker = fmt.Sprintf("%s %s", strings.Repeat(" ", padding+1), marker)
+			}
+			result.WriteString(marker + "\n")
+			i = span.end
+			continue
+		}
+
+		if showLineNumbers {
+			lineNumStr := strconv.Itoa(i + 1)
+			result.WriteString(fmt.Sprintf("%s%s| %s\n", strings.Repeat(" ", padding-len(lineNumStr)), lineNumStr, lines[i]))
+		} else {
+			result.WriteString(lines[i] + "\n")
+		}
+	}
+	return result.String()
+}
+
+func leadingWhitespace(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
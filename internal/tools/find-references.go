@@ -11,6 +11,7 @@ import (
 
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/text"
 	"github.com/isaacphi/mcp-language-server/internal/utilities"
 	// "github.com/davecgh/go-spew/spew" // Useful for debugging complex structs
 )
@@ -18,6 +19,9 @@ import (
 // --- At the top of your tools package ---
 var debugLogger *log.Logger
 
+// debugLogFile is the file debugLogger writes to when MCP_DEBUG_LOG is set
+var debugLogFile *os.File
+
 // ScopeIdentifier uniquely identifies a scope (function, method, etc.) in a file
 type ScopeIdentifier struct {
 	URI       protocol.DocumentUri
@@ -30,15 +34,19 @@ type ScopeIdentifier struct {
 
 // ReferencePosition represents a single reference position within a scope
 type ReferencePosition struct {
-	Line      uint32
-	Character uint32
+	Line         uint32
+	Character    uint32
+	EndCharacter uint32 // End of the reference on the same line, 0 if it spans lines
+	Role         string // "read", "write" or "call", empty if the server could not tell
 }
 
 // ScopeInfo stores information about a code scope including its name and kind
 type ScopeInfo struct {
-	Name    string              // Name of the scope (from DocumentSymbol)
-	Kind    protocol.SymbolKind // Kind of the symbol (from DocumentSymbol)
-	HasKind bool                // Whether we have kind information (always true if found via symbol)
+	Name          string              // Name of the scope (from DocumentSymbol)
+	Kind          protocol.SymbolKind // Kind of the symbol (from DocumentSymbol)
+	HasKind       bool                // Whether we have kind information (always true if found via symbol)
+	Detail        string              // Signature or type from DocumentSymbol.Detail, may be empty
+	SelectionLine uint32              // Line of the symbol's name (from DocumentSymbol.SelectionRange)
 }
 
 func init() {
@@ -54,196 +62,148 @@ func init() {
 			debugLogger.Printf("!!! FAILED TO OPEN DEBUG LOG FILE '%s': %v - Logging to Stderr !!!\n", logFilePath, err)
 		} else {
 			debugLogger.SetOutput(logFileHandle) // Change output to the file
-			// Optionally close the file handle gracefully on server shutdown
+			debugLogFile = logFileHandle
 			debugLogger.Printf("--- Debug logging explicitly enabled to file: %s ---", logFilePath)
 		}
 	}
 }
 
-// Helper function to find the smallest DocumentSymbol containing the target position
-// Returns the symbol and a boolean indicating if found.
-func findSymbolContainingPosition(symbols []protocol.DocumentSymbolResult, targetPos protocol.Position, level int) (*protocol.DocumentSymbol, bool) {
-	indent := strings.Repeat("  ", level)
-	debugLogger.Printf("%sDEBUG: [%d] findSymbolContainingPosition called for TargetPos: L%d:C%d (0-based)\n", indent, level, targetPos.Line, targetPos.Character)
-
-	var bestMatch *protocol.DocumentSymbol = nil
-
-	for i, symResult := range symbols {
-		debugLogger.Printf("%sDEBUG: [%d] Checking symbol #%d: Name='%s'\n", indent, level, i, symResult.GetName())
-
-		ds, ok := symResult.(*protocol.DocumentSymbol)
-		if !ok {
-			debugLogger.Printf("%sDEBUG: [%d]   Skipping symbol '%s' - not *protocol.DocumentSymbol\n", indent, level, symResult.GetName())
-			continue // Skip if it's not the expected type
-		}
-
-		symRange := ds.GetRange()
-		debugLogger.Printf("%sDEBUG: [%d]   Symbol: '%s', Kind: %d, Range: L%d:C%d - L%d:C%d (0-based)\n",
-			indent, level, ds.Name, ds.Kind,
-			symRange.Start.Line, symRange.Start.Character,
-			symRange.End.Line, symRange.End.Character)
-
-		// Check if the symbol's range contains the target position
-		posInLineRange := targetPos.Line >= symRange.Start.Line && targetPos.Line <= symRange.End.Line
-		posInRange := false
-		if posInLineRange {
-			// Must be strictly *after* start on start line, and strictly *before* end on end line? No, LSP range includes boundaries.
-			// Check: Not before start on start line AND not after end on end line.
-			if targetPos.Line == symRange.Start.Line && targetPos.Character < symRange.Start.Character {
-				// Before start char on start line - NO MATCH
-				debugLogger.Printf("%sDEBUG: [%d]   RangeCheck: posInLineRange=true. Target char %d < Start char %d on Start Line %d.\n", indent, level, targetPos.Character, symRange.Start.Character, targetPos.Line)
-			} else if targetPos.Line == symRange.End.Line && targetPos.Character > symRange.End.Character {
-				// After end char on end line - NO MATCH
-				debugLogger.Printf("%sDEBUG: [%d]   RangeCheck: posInLineRange=true. Target char %d > End char %d on End Line %d.\n", indent, level, targetPos.Character, symRange.End.Character, targetPos.Line)
-			} else {
-				posInRange = true
-			}
-		}
-		debugLogger.Printf("%sDEBUG: [%d]   RangeCheck Result: posInLineRange=%v, posInRange=%v\n", indent, level, posInLineRange, posInRange) // Log the crucial check result
-
-		if posInRange {
-			debugLogger.Printf("%sDEBUG: [%d]   Position IS within '%s' range. Checking children...\n", indent, level, ds.Name)
-			// This symbol contains the position. Check children for a more specific match.
-			var childMatch *protocol.DocumentSymbol = nil
-			var childFound bool = false
-			if len(ds.Children) > 0 {
-				childSymbols := make([]protocol.DocumentSymbolResult, len(ds.Children))
-				for i := range ds.Children {
-					childSymbols[i] = &ds.Children[i]
-				}
-				// Pass level + 1 for indentation
-				childMatch, childFound = findSymbolContainingPosition(childSymbols, targetPos, level+1)
-				debugLogger.Printf("%sDEBUG: [%d]   Recursive call for children of '%s' returned: found=%v, childMatch=%p\n", indent, level, ds.Name, childFound, childMatch)
-				if childFound {
-					debugLogger.Printf("%sDEBUG: [%d]     Child match name: '%s'\n", indent, level, childMatch.Name)
-				}
-			} else {
-				debugLogger.Printf("%sDEBUG: [%d]   Symbol '%s' has no children.\n", indent, level, ds.Name)
-			}
+// CloseDebugLog flushes and closes the debug log file, if one is open. Later debug
+// output is discarded.
+func CloseDebugLog() error {
+	if debugLogFile == nil {
+		return nil
+	}
+	debugLogger.SetOutput(io.Discard)
+	file := debugLogFile
+	debugLogFile = nil
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
 
-			if childFound {
-				// A child is a more specific match
-				debugLogger.Printf("%sDEBUG: [%d]   Updating bestMatch to child: '%s' (%p)\n", indent, level, childMatch.Name, childMatch)
-				bestMatch = childMatch // Use the child match
-			} else {
-				// This symbol is the best match found so far *at or below this level*.
-				// Compare its size with any existing bestMatch (which could be from a sibling branch's child).
-				debugLogger.Printf("%sDEBUG: [%d]   No better child found for '%s'. Comparing with current bestMatch (%p).\n", indent, level, ds.Name, bestMatch)
-
-				// Determine if this symbol (ds) is better (smaller) than the current bestMatch
-				isBetter := bestMatch == nil ||
-					// Smaller line range is better
-					(symRange.End.Line-symRange.Start.Line < bestMatch.Range.End.Line-bestMatch.Range.Start.Line) ||
-					// Same line range, smaller character range is better (more specific)
-					((symRange.End.Line-symRange.Start.Line == bestMatch.Range.End.Line-bestMatch.Range.Start.Line) &&
-						(symRange.End.Character-symRange.Start.Character < bestMatch.Range.End.Character-bestMatch.Range.Start.Character))
-
-				if isBetter {
-					debugLogger.Printf("%sDEBUG: [%d]   Current symbol '%s' IS better than bestMatch (%p). Updating bestMatch.\n", indent, level, ds.Name, bestMatch)
-					bestMatch = ds // Update bestMatch to this symbol
-				} else {
-					debugLogger.Printf("%sDEBUG: [%d]   Current symbol '%s' is NOT better than bestMatch ('%s').\n", indent, level, ds.Name, bestMatch.Name)
+// findSymbolsContainingPosition returns every symbol whose range contains the
+// position, outermost first, so the last one is the smallest containing symbol.
+// Flat SymbolInformation lists are handled too, ordered by the size of their
+// ranges, and returned as DocumentSymbols without children.
+func findSymbolsContainingPosition(symbols []protocol.DocumentSymbolResult, targetPos protocol.Position) []*protocol.DocumentSymbol {
+	var chain []*protocol.DocumentSymbol
+
+	var collect func(symbols []protocol.DocumentSymbolResult)
+	collect = func(symbols []protocol.DocumentSymbolResult) {
+		for _, sym := range symbols {
+			if !containsPosition(sym.GetRange(), targetPos) {
+				continue
+			}
+			switch s := sym.(type) {
+			case *protocol.DocumentSymbol:
+				chain = append(chain, s)
+				if len(s.Children) > 0 {
+					children := make([]protocol.DocumentSymbolResult, len(s.Children))
+					for i := range s.Children {
+						children[i] = &s.Children[i]
+					}
+					collect(children)
 				}
+			case *protocol.SymbolInformation:
+				chain = append(chain, &protocol.DocumentSymbol{
+					Name:           s.Name,
+					Kind:           s.Kind,
+					Range:          s.Location.Range,
+					SelectionRange: s.Location.Range,
+				})
 			}
-		} else {
-			debugLogger.Printf("%sDEBUG: [%d]   Position is NOT within '%s' range.\n", indent, level, ds.Name)
 		}
-		debugLogger.Printf("%sDEBUG: [%d] --- End Check for Symbol '%s' ---\n", indent, level, ds.Name)
-	} // End loop through symbols at this level
-
-	debugLogger.Printf("%sDEBUG: [%d] findSymbolContainingPosition returning: found=%v, bestMatch=%p\n", indent, level, bestMatch != nil, bestMatch)
-	if bestMatch != nil {
-		debugLogger.Printf("%sDEBUG: [%d]   Return Symbol Name: '%s'\n", indent, level, bestMatch.Name)
 	}
-	return bestMatch, bestMatch != nil
-}
+	collect(symbols)
 
-// Helper function to get text content for a specific range (implementation needed)
-// This might use file reading or potentially a custom LSP request if available.
-// For simplicity, we'll read the file content here. Could be optimized.
-func getTextForRange(ctx context.Context, uri protocol.DocumentUri, fileContent []byte, targetRange protocol.Range) (string, error) {
-	lines := strings.Split(string(fileContent), "\n") // Assumes LF endings for simplicity here
+	// Every range in the chain contains the position, so larger ranges enclose smaller ones
+	sort.SliceStable(chain, func(i, j int) bool {
+		return rangeLarger(chain[i].Range, chain[j].Range)
+	})
 
-	startLine := int(targetRange.Start.Line)
-	endLine := int(targetRange.End.Line)
-	startChar := int(targetRange.Start.Character)
-	endChar := int(targetRange.End.Character)
+	debugLogger.Printf("DEBUG: %d symbols contain L%d:C%d (0-based)\n", len(chain), targetPos.Line, targetPos.Character)
+	return chain
+}
 
-	if startLine < 0 || startLine >= len(lines) || endLine < 0 || endLine >= len(lines) || startLine > endLine {
-		return "", fmt.Errorf("invalid range for file content: lines %d-%d (file has %d lines)", startLine+1, endLine+1, len(lines))
+// rangeLarger reports whether a spans more text than b, comparing lines first
+func rangeLarger(a, b protocol.Range) bool {
+	aLines, bLines := a.End.Line-a.Start.Line, b.End.Line-b.Start.Line
+	if aLines != bLines {
+		return aLines > bLines
 	}
+	if a.Start.Character != b.Start.Character {
+		return a.Start.Character < b.Start.Character
+	}
+	return a.End.Character > b.End.Character
+}
 
-	var sb strings.Builder
-
-	if startLine == endLine {
-		// Single line range
-		line := lines[startLine]
-		if startChar > len(line) {
-			startChar = len(line)
-		}
-		if endChar > len(line) {
-			endChar = len(line)
-		}
-		if startChar < 0 {
-			startChar = 0
-		}
-		if endChar < 0 {
-			endChar = 0
-		}
-		if startChar > endChar {
-			startChar = endChar
-		} // Ensure start <= end
-		sb.WriteString(line[startChar:endChar])
-	} else {
-		// Multi-line range
-		// Start line: from startChar to end
-		firstLine := lines[startLine]
-		if startChar > len(firstLine) {
-			startChar = len(firstLine)
-		}
-		if startChar < 0 {
-			startChar = 0
-		}
-		sb.WriteString(firstLine[startChar:])
-		sb.WriteString("\n") // Add newline separator
-
-		// Middle lines: entire lines
-		for i := startLine + 1; i < endLine; i++ {
-			sb.WriteString(lines[i])
-			sb.WriteString("\n")
-		}
-
-		// End line: from beginning to endChar
-		lastLine := lines[endLine]
-		if endChar > len(lastLine) {
-			endChar = len(lastLine)
-		}
-		if endChar < 0 {
-			endChar = 0
-		}
-		sb.WriteString(lastLine[:endChar])
+// FindReferences finds the references to a symbol and formats them as one block of text
+func FindReferences(ctx context.Context, client *lsp.Client, symbolName string, showLineNumbers bool, blame bool, opts ReferenceOptions) (string, error) {
+	parts, err := FindReferenceParts(ctx, client, symbolName, showLineNumbers, blame, opts)
+	if err != nil {
+		return "", err
 	}
+	return JoinReferenceParts(parts), nil
+}
 
-	return sb.String(), nil
+// JoinReferenceParts joins the parts returned by FindReferenceParts into the single
+// block FindReferences returns, with a blank line between files
+func JoinReferenceParts(parts []string) string {
+	if len(parts) <= 1 {
+		return strings.Join(parts, "")
+	}
+	return parts[0] + "\n" + strings.Join(parts[1:], "\n\n")
 }
 
-func FindReferences(ctx context.Context, client *lsp.Client, symbolName string, showLineNumbers bool) (string, error) {
-	// --- Stage 1: Find Symbol Definitions ---
-	symbolResult, err := client.Symbol(ctx, protocol.WorkspaceSymbolParams{Query: symbolName})
+// FindReferenceParts finds the references to a symbol and returns them in parts: a
+// summary line followed by one part per file, or per package or scope kind as opts
+// groups them, in the order opts sorts them. Each file starts with its "File:"
+// header, so clients can show, page or drop files independently. When nothing is
+// found the only part is a message saying so. With blame set, code lines are
+// prefixed with the commit that last changed them. The minimal verbosity lists
+// the scopes and positions without their code, full shows long scopes whole.
+func FindReferenceParts(ctx context.Context, client *lsp.Client, symbolName string, showLineNumbers bool, blame bool, opts ReferenceOptions) ([]string, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if _, err := symbolRegex(symbolName); err != nil {
+		return nil, err
+	}
+	scope, err := newPathScope(opts.ScopePath)
 	if err != nil {
-		return "", fmt.Errorf("Failed to fetch symbol: %v", err)
+		return nil, err
 	}
-	results, err := symbolResult.Results()
+
+	// --- Stage 1: Find Symbol Definitions ---
+	results, err := workspaceSymbols(ctx, client, serverSymbolQuery(symbolName))
 	if err != nil {
-		return "", fmt.Errorf("Failed to parse results: %v", err)
+		return nil, fmt.Errorf("Failed to fetch symbol: %v", err)
+	}
+
+	// Servers that report bare member names may not match "Foo.Name" at all
+	if container, member := splitQualifiedName(symbolName); container != "" && !strings.HasPrefix(symbolName, regexQueryPrefix) && !anySymbolNamed(results, symbolName) {
+		if memberResult, err := client.Symbol(ctx, protocol.WorkspaceSymbolParams{Query: member}); err == nil {
+			if memberResults, err := memberResult.Results(); err == nil {
+				results = append(results, memberResults...)
+			}
+		}
 	}
 
 	processedLocations := make(map[protocol.Location]struct{})
 	var uniqueLocations []protocol.Location
+	containers := make(map[string]bool) // Containers of the matched definitions
+	var outOfScopeLocations []protocol.Location
+	matchedNames := make(map[string]bool)
 	for _, symbol := range results {
-		if symbol.GetName() != symbolName {
+		if !symbolMatches(ctx, client, symbol, symbolName, opts.MatchMode) {
 			continue
 		}
+		if isMemberKind(symbol.GetKind()) && symbol.GetContainerName() != "" {
+			containers[symbol.GetContainerName()] = true
+		}
 		loc := symbol.GetLocation()
 		// Ensure loc is valid (sometimes workspace/symbol might return incomplete info)
 		if loc.URI == "" || loc.Range.Start.Line == 0 && loc.Range.Start.Character == 0 && loc.Range.End.Line == 0 && loc.Range.End.Character == 0 {
@@ -252,15 +212,34 @@ func FindReferences(ctx context.Context, client *lsp.Client, symbolName string,
 		}
 		if _, exists := processedLocations[loc]; !exists {
 			processedLocations[loc] = struct{}{}
-			uniqueLocations = append(uniqueLocations, loc)
+			matchedNames[symbol.GetName()] = true
+			if scope.contains(loc.URI) {
+				uniqueLocations = append(uniqueLocations, loc)
+			} else {
+				outOfScopeLocations = append(outOfScopeLocations, loc)
+			}
 		}
 	}
+	// A symbol declared elsewhere still has its references in scope listed
+	definedOutsideScope := len(uniqueLocations) == 0 && len(outOfScopeLocations) > 0
+	if definedOutsideScope {
+		uniqueLocations, outOfScopeLocations = outOfScopeLocations, nil
+	}
 	if len(uniqueLocations) == 0 {
-		return fmt.Sprintf("Symbol definition not found for: %s", symbolName), nil
+		message := fmt.Sprintf("Symbol definition not found for: %s", symbolName)
+		if hint := caseMismatchHint(results, symbolName, opts.MatchMode); hint != "" {
+			message += "." + hint
+		}
+		return []string{message}, nil
 	}
 
 	// --- Stage 2: Find All References ---
+	// Definitions of the same symbol (e.g. an interface method and its implementations)
+	// often return overlapping reference sets, each location is kept once
 	var allFoundRefs []protocol.Location
+	seenRefs := make(map[protocol.Location]bool)
+	contributingDefs := 0
+	outOfScopeRefs := 0
 	for _, loc := range uniqueLocations {
 		refsParams := protocol.ReferenceParams{ /* ... as before ... */
 			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
@@ -276,11 +255,30 @@ func FindReferences(ctx context.Context, client *lsp.Client, symbolName string,
 				loc.URI, loc.Range.Start.Line+1, err)
 			continue
 		}
-		allFoundRefs = append(allFoundRefs, refs...)
+		contributed := false
+		for _, ref := range refs {
+			if seenRefs[ref] {
+				continue
+			}
+			seenRefs[ref] = true
+			if !scope.contains(ref.URI) {
+				outOfScopeRefs++
+				continue
+			}
+			allFoundRefs = append(allFoundRefs, ref)
+			contributed = true
+		}
+		if contributed {
+			contributingDefs++
+		}
 	}
 	totalRefs := len(allFoundRefs)
+	recordReferences(symbolName, totalRefs)
 	if totalRefs == 0 {
-		return fmt.Sprintf("No references found for symbol: %s (definition found at %d location(s))", symbolName, len(uniqueLocations)), nil
+		if outOfScopeRefs > 0 {
+			return []string{fmt.Sprintf("No references found for symbol: %s in %s (%s outside it)", symbolName, scope, pluralize(outOfScopeRefs, "reference"))}, nil
+		}
+		return []string{fmt.Sprintf("No references found for symbol: %s (definition found at %d location(s))", symbolName, len(uniqueLocations))}, nil
 	}
 
 	// --- Stage 3: Group References by File and Scope ---
@@ -289,12 +287,79 @@ func FindReferences(ctx context.Context, client *lsp.Client, symbolName string,
 		refsByFile[ref.URI] = append(refsByFile[ref.URI], ref)
 	}
 
-	allReferences := []string{fmt.Sprintf("Symbol: %s (%d references in %d files)", symbolName, totalRefs, len(refsByFile))}
+	header := fmt.Sprintf("Symbol: %s (%d references in %d files)", symbolName, totalRefs, len(refsByFile))
+	if len(uniqueLocations) > 1 {
+		header = fmt.Sprintf("Symbol: %s (%d references in %d files, from %d of %d definitions)",
+			symbolName, totalRefs, len(refsByFile), contributingDefs, len(uniqueLocations))
+	}
+	if len(matchedNames) > 1 {
+		names := make([]string, 0, len(matchedNames))
+		for name := range matchedNames {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		header += fmt.Sprintf("\nMatched symbols: %s", strings.Join(names, ", "))
+	}
+	if scope != nil {
+		var omitted []string
+		if outOfScopeRefs > 0 {
+			omitted = append(omitted, pluralize(outOfScopeRefs, "reference"))
+		}
+		if len(outOfScopeLocations) > 0 {
+			omitted = append(omitted, pluralize(len(outOfScopeLocations), "definition"))
+		}
+		header += fmt.Sprintf("\nScope: %s", scope)
+		if len(omitted) > 0 {
+			header += fmt.Sprintf(" (%s outside it omitted)", strings.Join(omitted, " and "))
+		}
+		if definedOutsideScope {
+			header += fmt.Sprintf("\nNote: %s is not declared in %s, the references to its declarations outside it are listed.", symbolName, scope)
+		}
+	}
+	if container, _ := splitQualifiedName(symbolName); container == "" && len(containers) > 1 {
+		names := make([]string, 0, len(containers))
+		for name := range containers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		header += fmt.Sprintf("\nNote: %s is a member of %s, their references are listed together. Qualify the name, e.g. %s.%s, to see one.",
+			symbolName, strings.Join(names, ", "), containerKeyName(names[0]), symbolName)
+	}
+	totalRoles := make(roleCounts)
+	var files []referenceFile
+	generatedRefs := make(map[string]int)
+	var generatedFiles []string
+
+	uris := make([]protocol.DocumentUri, 0, len(refsByFile))
+	for uri := range refsByFile {
+		uris = append(uris, uri)
+	}
+	sort.Slice(uris, func(i, j int) bool { return uris[i] < uris[j] })
+	verbosity := VerbosityFromContext(ctx)
+	stream := opts.Emit != nil && (opts.GroupBy == "" || opts.GroupBy == "file")
+	if stream {
+		// Emitted files cannot be sorted afterwards, so they are ordered up front
+		order := make([]referenceFile, len(uris))
+		uriByPath := make(map[string]protocol.DocumentUri, len(uris))
+		for i, uri := range uris {
+			path := documentPath(uri)
+			uriByPath[path] = uri
+			order[i] = referenceFile{path: path, refs: len(refsByFile[uri]), distance: definitionDistance(path, uniqueLocations)}
+		}
+		sortReferenceFiles(order, opts.SortBy)
+		for i, file := range order {
+			uris[i] = uriByPath[file.path]
+		}
+	}
 
-	filesProcessed := 0
-	for uri, fileRefs := range refsByFile {
-		filesProcessed++
-		filePath := strings.TrimPrefix(string(uri), "file://")
+	for _, uri := range uris {
+		fileRefs := refsByFile[uri]
+		filePath := documentPath(uri)
+		if isGeneratedFile(filePath) {
+			generatedRefs[filePath] = len(fileRefs)
+			generatedFiles = append(generatedFiles, filePath)
+			continue
+		}
 		// Sort refs by position within the file
 		sort.Slice(fileRefs, func(i, j int) bool { /* ... as before ... */
 			if fileRefs[i].Range.Start.Line != fileRefs[j].Range.Start.Line {
@@ -302,32 +367,33 @@ func FindReferences(ctx context.Context, client *lsp.Client, symbolName string,
 			}
 			return fileRefs[i].Range.Start.Character < fileRefs[j].Range.Start.Character
 		})
-		allReferences = append(allReferences, fmt.Sprintf("File: %s (%d references)", filePath, len(fileRefs)))
+		allReferences := []string{fmt.Sprintf("File: %s (%d references)", filePath, len(fileRefs))}
+		if isDependency(uri) {
+			allReferences[0] = fmt.Sprintf("File: %s (external dependency, read-only, %d references)", filePath, len(fileRefs))
+		}
 
 		// --- Sub-Stage 3a: Get Symbols and File Content Once Per File ---
 		var docSymbols []protocol.DocumentSymbolResult
 		symParams := protocol.DocumentSymbolParams{TextDocument: protocol.TextDocumentIdentifier{URI: uri}}
 		symResult, symErr := client.DocumentSymbol(ctx, symParams)
 		if symErr == nil {
+			// Flat SymbolInformation results are already nested by Results()
 			docSymbols, _ = symResult.Results()
-			// Check if we got DocumentSymbol, not SymbolInformation
-			if len(docSymbols) > 0 {
-				if _, ok := docSymbols[0].(*protocol.DocumentSymbol); !ok {
-					debugLogger.Printf("Warning: Received SymbolInformation instead of DocumentSymbol for %s, scope identification might be limited.\n", uri)
-					docSymbols = nil // Treat as no symbols found for our purpose
-				}
-			}
 		} else {
 			debugLogger.Printf("Warning: Failed to get document symbols for %s: %v\n", uri, symErr)
 		}
 
 		// Read file content once for fetching scope text later
-		fileContent, readErr := os.ReadFile(filePath)
+		fileContent, readErr := client.ReadDocument(ctx, uri)
 		if readErr != nil {
 			debugLogger.Printf("Warning: Failed to read file content for %s: %v. Scope text will be unavailable.\n", filePath, readErr)
 			fileContent = nil // Mark content as unavailable
 		}
 
+		// Reads, writes and calls, as far as the server's highlights or tokens tell
+		roles := classifyReferences(ctx, client, uri, fileRefs, fileContent)
+		fileRoles := make(roleCounts)
+
 		// --- Sub-Stage 3b: Group References by Symbol Scope ---
 		scopeRefs := make(map[ScopeIdentifier][]ReferencePosition)
 		scopeInfos := make(map[ScopeIdentifier]ScopeInfo)
@@ -341,7 +407,9 @@ func FindReferences(ctx context.Context, client *lsp.Client, symbolName string,
 			if len(docSymbols) > 0 {
 				// Call the debugged function with initial level 0
 				debugLogger.Printf("\n--- Searching for symbol containing reference at L%d:C%d (0-based Line %d) ---\n", ref.Range.Start.Line+1, ref.Range.Start.Character+1, ref.Range.Start.Line)
-				containingSymbol, foundSymbol = findSymbolContainingPosition(docSymbols, ref.Range.Start, 0) // Start recursion level at 0
+				if chain := findSymbolsContainingPosition(docSymbols, ref.Range.Start); len(chain) > 0 {
+					containingSymbol, foundSymbol = chain[len(chain)-1], true
+				}
 				debugLogger.Printf("--- Search complete for L%d:C%d. Found: %v ---\n\n", ref.Range.Start.Line+1, ref.Range.Start.Character+1, foundSymbol)
 			}
 
@@ -363,15 +431,17 @@ func FindReferences(ctx context.Context, client *lsp.Client, symbolName string,
 				// Store scope info only once per symbol
 				if _, exists := scopeInfos[scopeID]; !exists {
 					scopeInfos[scopeID] = ScopeInfo{
-						Name:    containingSymbol.Name,
-						Kind:    containingSymbol.Kind,
-						HasKind: true, // We got it from a symbol
+						Name:          containingSymbol.Name,
+						Kind:          containingSymbol.Kind,
+						HasKind:       true, // We got it from a symbol
+						Detail:        containingSymbol.Detail,
+						SelectionLine: containingSymbol.SelectionRange.Start.Line,
 					}
 					// Fetch and store text for this symbol's range
 					if fileContent != nil {
-						text, err := getTextForRange(ctx, uri, fileContent, scopeRange)
+						scopeText, err := text.Extract(fileContent, scopeRange)
 						if err == nil {
-							scopeTexts[scopeID] = text
+							scopeTexts[scopeID] = scopeText
 						} else {
 							debugLogger.Printf("Warning: Failed to get text for symbol %s range (%d-%d): %v\n", containingSymbol.Name, scopeRange.Start.Line+1, scopeRange.End.Line+1, err)
 							scopeTexts[scopeID] = fmt.Sprintf("Error fetching text for symbol '%s'", containingSymbol.Name)
@@ -415,6 +485,12 @@ func FindReferences(ctx context.Context, client *lsp.Client, symbolName string,
 				Line:      ref.Range.Start.Line,
 				Character: ref.Range.Start.Character,
 			}
+			if ref.Range.End.Line == ref.Range.Start.Line {
+				position.EndCharacter = ref.Range.End.Character
+			}
+			position.Role = roles[ref.Range.Start]
+			fileRoles.add(position.Role)
+			totalRoles.add(position.Role)
 			scopeRefs[scopeID] = append(scopeRefs[scopeID], position)
 
 		} // End loop through references in file
@@ -430,6 +506,7 @@ func FindReferences(ctx context.Context, client *lsp.Client, symbolName string,
 		})
 
 		// Loop through sorted scopes and format output
+		var scopes []referenceScope
 		for _, scopeID := range scopeIDs {
 			positions := scopeRefs[scopeID]
 			scopeInfo := scopeInfos[scopeID]
@@ -441,14 +518,26 @@ func FindReferences(ctx context.Context, client *lsp.Client, symbolName string,
 			// allReferences = append(allReferences, "  "+debugInfo)
 
 			// Format scope header (using Kind if HasKind is true)
+			scopeStart := len(allReferences)
+			scopeKind := "Other"
 			var scopeHeader string
 			if scopeInfo.HasKind {
 				kindStr := utilities.GetSymbolKindString(scopeInfo.Kind)
+				if kindStr != "" && kindStr != "Unknown" {
+					scopeKind = strings.Trim(kindStr, "[]")
+				}
 				displayName := scopeInfo.Name
 				if kindStr != "" && kindStr != "Unknown" {
 					displayName = fmt.Sprintf("%s %s", kindStr, scopeInfo.Name)
 				}
-				scopeHeader = fmt.Sprintf("  %s (lines %d-%d, %d references)", displayName, scopeID.StartLine+1, scopeID.EndLine+1, len(positions))
+				if detail := formatSymbolDetail(scopeInfo.Detail); detail != "" {
+					displayName += " " + detail
+				}
+				declared := ""
+				if scopeInfo.SelectionLine != scopeID.StartLine {
+					declared = fmt.Sprintf(", declared at L%d", scopeInfo.SelectionLine+1)
+				}
+				scopeHeader = fmt.Sprintf("  %s (lines %d-%d%s, %d references)", displayName, scopeID.StartLine+1, scopeID.EndLine+1, declared, len(positions))
 			} else {
 				scopeHeader = fmt.Sprintf("  Scope: %s (lines %d-%d, %d references)", scopeInfo.Name, scopeID.StartLine+1, scopeID.EndLine+1, len(positions))
 			}
@@ -458,7 +547,11 @@ func FindReferences(ctx context.Context, client *lsp.Client, symbolName string,
 			var positionStrs []string
 			var highlightLineIndices []int // Relative to the start of the scopeText
 			for _, pos := range positions {
-				positionStrs = append(positionStrs, fmt.Sprintf("L%d:C%d", pos.Line+1, pos.Character+1))
+				positionStr := fmt.Sprintf("L%d:C%d", pos.Line+1, pos.Character+1)
+				if pos.Role != "" {
+					positionStr += fmt.Sprintf(" (%s)", pos.Role)
+				}
+				positionStrs = append(positionStrs, positionStr)
 				// Calculate highlight index relative to scope start
 				highlightLineIndices = append(highlightLineIndices, int(pos.Line-scopeID.StartLine))
 			}
@@ -472,6 +565,10 @@ func FindReferences(ctx context.Context, client *lsp.Client, symbolName string,
 				positionChunk := positionStrs[i:end]
 				allReferences = append(allReferences, fmt.Sprintf("    References: %s", strings.Join(positionChunk, ", ")))
 			}
+			if verbosity == VerbosityMinimal {
+				scopes = append(scopes, referenceScope{kind: scopeKind, refs: len(positions), lines: allReferences[scopeStart:]})
+				continue
+			}
 
 			// Format scope text (truncation, line numbers, highlighting)
 			scopeLines := strings.Split(scopeText, "\n") // Use the stored text
@@ -479,7 +576,7 @@ func FindReferences(ctx context.Context, client *lsp.Client, symbolName string,
 			// --- Truncation Logic --- (needs adjustment for highlightLineIndices)
 			finalScopeLines := scopeLines                 // Start with original lines
 			finalHighlightIndices := highlightLineIndices // Start with original indices
-			if len(scopeLines) > 50 {
+			if len(scopeLines) > 50 && verbosity != VerbosityFull {
 				// ... (Existing truncation logic, BUT ensure it correctly maps original highlightLineIndices to the indices in the *truncated* output) ...
 
 				// Simplified recalculation (can be improved for precision)
@@ -487,6 +584,15 @@ func FindReferences(ctx context.Context, client *lsp.Client, symbolName string,
 				for i := 0; i < 5 && i < len(scopeLines); i++ {
 					importantLines[i] = true
 				}
+				// Keep the declaration, which follows doc comments and attributes
+				if scopeInfo.HasKind {
+					declLine := int(scopeInfo.SelectionLine) - int(scopeID.StartLine)
+					for offset := 0; offset <= 1; offset++ {
+						if lineIdx := declLine + offset; lineIdx >= 0 && lineIdx < len(scopeLines) {
+							importantLines[lineIdx] = true
+						}
+					}
+				}
 				for i := len(scopeLines) - 3; i < len(scopeLines) && i >= 0; i++ {
 					importantLines[i] = true
 				}
@@ -544,6 +650,23 @@ func FindReferences(ctx context.Context, client *lsp.Client, symbolName string,
 			var formattedScope strings.Builder
 			lineNum := int(scopeID.StartLine) + 1 // Start numbering from original scope start
 
+			// Blame annotations go before the gutter, caret lines get blank space instead
+			annotate := func(int) string { return "" }
+			blankAnnotation := ""
+			if blame {
+				annotator := newBlameAnnotator(ctx, client, filePath, lineNum, lineNum+len(scopeLines)-1)
+				formattedScope.WriteString(annotator.note())
+				annotate = annotator.prefix
+				blankAnnotation = annotator.blank()
+			}
+
+			// Reference columns by line, marked with carets under the line
+			refsByLine := make(map[int][]ReferencePosition)
+			for _, pos := range positions {
+				refsByLine[int(pos.Line)+1] = append(refsByLine[int(pos.Line)+1], pos)
+			}
+			carets := opts.Highlight == "" || opts.Highlight == highlightCarets
+
 			for i, line := range finalScopeLines {
 				isRef := false
 				for _, hl := range finalHighlightIndices { // Use potentially recalculated indices
@@ -555,16 +678,16 @@ func FindReferences(ctx context.Context, client *lsp.Client, symbolName string,
 
 				if strings.Contains(line, "lines skipped") {
 					// Handle skip marker line
-					if showLineNumbers {
-						var skipped int
-						fmt.Sscanf(line, "    ... %d lines skipped ...", &skipped) // Ignore error, default skip is 1 line display adjust
-						formattedScope.WriteString(line + "\n")
-						lineNum += skipped // Adjust line number count
-					} else {
-						formattedScope.WriteString(line + "\n") // Show skip marker even without line nums
-					}
+					var skipped int
+					fmt.Sscanf(line, "    ... %d lines skipped ...", &skipped) // Ignore error, default skip is 1 line display adjust
+					formattedScope.WriteString(blankAnnotation + line + "\n")
+					lineNum += skipped // Adjust line number count
 				} else {
 					// Handle regular code line
+					code := line
+					if isRef && opts.Highlight == highlightInline {
+						code = markInline(line, refsByLine[lineNum])
+					}
 					if showLineNumbers {
 						numStr := fmt.Sprintf("%d", lineNum)
 						padding := strings.Repeat(" ", 5-len(numStr))
@@ -572,31 +695,118 @@ func FindReferences(ctx context.Context, client *lsp.Client, symbolName string,
 						if isRef {
 							marker = ">"
 						}
-						formattedScope.WriteString(fmt.Sprintf("%s%s%s %s\n", padding, numStr, marker, line))
+						gutter := fmt.Sprintf("%s%s%s ", padding, numStr, marker)
+						formattedScope.WriteString(annotate(lineNum) + gutter + code + "\n")
+						if isRef && carets {
+							formattedScope.WriteString(caretLine(blankAnnotation+strings.Repeat(" ", len(gutter)), line, refsByLine[lineNum]))
+						}
 					} else {
 						// Add simple marker even without line numbers
 						marker := "  " // Indent non-ref lines
 						if isRef {
 							marker = "> "
 						}
-						formattedScope.WriteString(marker + line + "\n")
+						formattedScope.WriteString(annotate(lineNum) + marker + code + "\n")
+						if isRef && carets {
+							formattedScope.WriteString(caretLine(blankAnnotation+"  ", line, refsByLine[lineNum]))
+						}
 					}
 					lineNum++ // Increment for the next actual code line
 				}
 			}
 
-			// Add the formatted scope with indentation
+			// Add the formatted scope with indentation. Fenced blocks are indented less,
+			// four spaces would turn the fence itself into an indented code block
 			trimmedFormattedScope := strings.TrimRight(formattedScope.String(), " \n\t")
-			allReferences = append(allReferences, "    "+strings.ReplaceAll(trimmedFormattedScope, "\n", "\n    "))
+			if codeFences {
+				allReferences = append(allReferences, indentBlock(fenceCode(trimmedFormattedScope, filePath), "  "))
+			} else {
+				allReferences = append(allReferences, indentBlock(trimmedFormattedScope, "    "))
+			}
+			scopes = append(scopes, referenceScope{
+				kind:  scopeKind,
+				refs:  len(positions),
+				lines: allReferences[scopeStart:],
+			})
 
 		} // End loop through scopes
 
-		// Add blank line between files
-		if filesProcessed < len(refsByFile) {
-			allReferences = append(allReferences, "")
+		if fileRoles.classified() {
+			allReferences[0] = fmt.Sprintf("File: %s (%d references: %s)", filePath, len(fileRefs), fileRoles)
+		}
+		file := referenceFile{
+			path:     filePath,
+			header:   allReferences[0],
+			refs:     len(fileRefs),
+			distance: definitionDistance(filePath, uniqueLocations),
+			scopes:   scopes,
 		}
+		if stream {
+			opts.Emit(file.text())
+			continue
+		}
+		files = append(files, file)
 
 	} // End loop through files
 
-	return strings.Join(allReferences, "\n"), nil
+	if totalRoles.classified() {
+		header += "\nRoles: " + totalRoles.String()
+	}
+	if len(generatedFiles) > 0 {
+		header += "\nGenerated: " + formatGeneratedCount("reference", generatedRefs, generatedFiles)
+	}
+	return append([]string{header}, arrangeReferenceFiles(files, opts)...), nil
+}
+
+// caretLine underlines the references on a line with carets, copying the line's tabs
+// so the carets line up. Columns are LSP characters, treated as one per rune. It
+// returns "" when no reference is on the line.
+func caretLine(gutter string, line string, refs []ReferencePosition) string {
+	runes := []rune(line)
+	marked := make([]bool, len(runes)+1)
+	found := false
+	for _, ref := range refs {
+		start := int(ref.Character)
+		end := int(ref.EndCharacter)
+		if end <= start {
+			end = start + 1
+		}
+		for i := start; i < end && i < len(marked); i++ {
+			marked[i] = true
+			found = true
+		}
+	}
+	if !found {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(gutter)
+	last := len(marked) - 1
+	for last >= 0 && !marked[last] {
+		last--
+	}
+	for i := 0; i <= last; i++ {
+		switch {
+		case marked[i]:
+			sb.WriteByte('^')
+		case i < len(runes) && runes[i] == '\t':
+			sb.WriteByte('\t')
+		default:
+			sb.WriteByte(' ')
+		}
+	}
+	sb.WriteByte('\n')
+	return sb.String()
+}
+
+// formatSymbolDetail shortens a document symbol's detail, usually a signature or
+// type, to one line for a scope header
+func formatSymbolDetail(detail string) string {
+	const maxDetailLength = 100
+	detail = strings.Join(strings.Fields(detail), " ")
+	if runes := []rune(detail); len(runes) > maxDetailLength {
+		detail = string(runes[:maxDetailLength]) + "..."
+	}
+	return detail
 }
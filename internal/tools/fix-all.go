@@ -0,0 +1,220 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// fixAllDiagnosticsTimeout is how long to wait for the diagnostics of a file after
+// its fixes are applied
+const fixAllDiagnosticsTimeout = 5 * time.Second
+
+// FixAll applies the server's source.fixAll action to a file or, when it has none,
+// the preferred quick fixes of the file's diagnostics whose edits do not overlap.
+// It reports the diff of the file and the diagnostics that remain.
+func FixAll(ctx context.Context, client *lsp.Client, filePath string) (string, error) {
+	if err := openForDiagnostics(ctx, client, []string{filePath}, codeActionDiagnosticsTimeout); err != nil {
+		return "", err
+	}
+	uri := protocol.DocumentUri("file://" + filePath)
+
+	before, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not read file: %v", err)
+	}
+	lines := strings.Split(string(before), "\n")
+	whole := protocol.Range{
+		End: protocol.Position{Line: uint32(len(lines) - 1), Character: uint32(len(lines[len(lines)-1]))},
+	}
+	diagnostics := client.GetFileDiagnostics(uri)
+
+	var action protocol.CodeAction
+	var note string
+	fixAll, err := codeActions(ctx, client, uri, whole, diagnostics, []string{string(protocol.SourceFixAll)})
+	if err != nil {
+		return "", err
+	}
+	fixAll = enabledActions(fixAll)
+	if len(fixAll) > 0 {
+		action = fixAll[0]
+		note = fmt.Sprintf("Applied %q.", action.Title)
+		if len(fixAll) > 1 {
+			note += fmt.Sprintf(" %d other fix-all actions were not applied, run fix_all again for them.", len(fixAll)-1)
+		}
+	} else {
+		if len(diagnostics) == 0 {
+			return fmt.Sprintf("No diagnostics in %s and the server offers no fix-all action, nothing to fix.", filePath), nil
+		}
+		quickFixes, err := codeActions(ctx, client, uri, whole, diagnostics, []string{string(protocol.QuickFix)})
+		if err != nil {
+			return "", err
+		}
+		var applied, skipped int
+		action, applied, skipped = mergePreferredFixes(ctx, client, enabledActions(quickFixes))
+		if applied == 0 {
+			return fmt.Sprintf("The server offers no fix-all action and no preferred quick fixes for the %s in %s.", pluralize(len(diagnostics), "diagnostic"), filePath), nil
+		}
+		note = "Applied 1 preferred quick fix."
+		if applied > 1 {
+			note = fmt.Sprintf("Applied %d preferred quick fixes.", applied)
+		}
+		if skipped > 0 {
+			note += fmt.Sprintf(" %d overlapping or command-only fixes were skipped, run fix_all again for them.", skipped)
+		}
+	}
+
+	published := client.NextDiagnostics(uri)
+	changed, err := applyCodeAction(ctx, client, action)
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	result.WriteString(note + "\n")
+
+	after, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not read file after fixing: %v", err)
+	}
+	diff, err := utilities.UnifiedDiff(filePath, filePath, string(before), string(after), 3)
+	if err != nil {
+		return "", err
+	}
+	if diff == "" {
+		result.WriteString("The file did not change.\n")
+	} else {
+		result.WriteString("\n" + diff)
+	}
+	if others := withoutPath(changed, filePath); len(others) > 0 {
+		result.WriteString(fmt.Sprintf("\nOther files changed: %s\n", strings.Join(others, ", ")))
+	}
+
+	remaining, received := waitForDiagnostics(ctx, client, uri, published, fixAllDiagnosticsTimeout)
+	if !received {
+		result.WriteString(fmt.Sprintf("\nThe language server published no diagnostics within %s.\n", fixAllDiagnosticsTimeout))
+		return result.String(), nil
+	}
+	errors, warnings := countSeverities(remaining)
+	result.WriteString(fmt.Sprintf("\nRemaining diagnostics: %s and %s\n", pluralize(errors, "error"), pluralize(warnings, "warning")))
+	for _, diag := range remaining {
+		result.WriteString(formatDiagnosticLine(diag))
+	}
+	return result.String(), nil
+}
+
+// mergePreferredFixes combines the text edits of the preferred quick fixes into one
+// action. Fixes are resolved if needed, and those that overlap an earlier fix, run
+// a command or create, rename or delete files are skipped.
+func mergePreferredFixes(ctx context.Context, client *lsp.Client, actions []protocol.CodeAction) (protocol.CodeAction, int, int) {
+	merged := protocol.CodeAction{
+		Title: "preferred quick fixes",
+		Edit:  &protocol.WorkspaceEdit{Changes: make(map[protocol.DocumentUri][]protocol.TextEdit)},
+	}
+	applied, skipped := 0, 0
+	for _, action := range actions {
+		if !action.IsPreferred {
+			continue
+		}
+		if action.Edit == nil && action.Command == nil {
+			resolved, err := client.ResolveCodeAction(ctx, action)
+			if err != nil {
+				skipped++
+				continue
+			}
+			action = resolved
+		}
+		edits, ok := textEditsOf(action)
+		if !ok || overlapsEdits(merged.Edit.Changes, edits) {
+			skipped++
+			continue
+		}
+		for uri, fileEdits := range edits {
+			merged.Edit.Changes[uri] = append(merged.Edit.Changes[uri], fileEdits...)
+		}
+		applied++
+	}
+	return merged, applied, skipped
+}
+
+// textEditsOf returns the text edits of an action per document, and false if the
+// action does more than edit text
+func textEditsOf(action protocol.CodeAction) (map[protocol.DocumentUri][]protocol.TextEdit, bool) {
+	if action.Edit == nil || action.Command != nil {
+		return nil, false
+	}
+	edits := make(map[protocol.DocumentUri][]protocol.TextEdit)
+	for uri, fileEdits := range action.Edit.Changes {
+		edits[uri] = append(edits[uri], fileEdits...)
+	}
+	for _, change := range action.Edit.DocumentChanges {
+		if change.TextDocumentEdit == nil {
+			return nil, false
+		}
+		for _, edit := range change.TextDocumentEdit.Edits {
+			textEdit, err := edit.AsTextEdit()
+			if err != nil {
+				return nil, false
+			}
+			uri := change.TextDocumentEdit.TextDocument.URI
+			edits[uri] = append(edits[uri], textEdit)
+		}
+	}
+	return edits, len(edits) > 0
+}
+
+// overlapsEdits reports whether any of edits touches a range already edited
+func overlapsEdits(existing map[protocol.DocumentUri][]protocol.TextEdit, edits map[protocol.DocumentUri][]protocol.TextEdit) bool {
+	for uri, fileEdits := range edits {
+		for _, edit := range fileEdits {
+			for _, other := range existing[uri] {
+				if rangesTouch(edit.Range, other.Range) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// rangesTouch reports whether two ranges overlap or are adjacent on the same line
+func rangesTouch(a, b protocol.Range) bool {
+	if a.End.Line < b.Start.Line || b.End.Line < a.Start.Line {
+		return false
+	}
+	if a.End.Line == b.Start.Line && a.End.Character < b.Start.Character {
+		return false
+	}
+	if b.End.Line == a.Start.Line && b.End.Character < a.Start.Character {
+		return false
+	}
+	return true
+}
+
+// enabledActions drops the actions the server marked as disabled
+func enabledActions(actions []protocol.CodeAction) []protocol.CodeAction {
+	var enabled []protocol.CodeAction
+	for _, action := range actions {
+		if action.Disabled == nil {
+			enabled = append(enabled, action)
+		}
+	}
+	return enabled
+}
+
+// withoutPath returns paths without path
+func withoutPath(paths []string, path string) []string {
+	var others []string
+	for _, p := range paths {
+		if p != path {
+			others = append(others, p)
+		}
+	}
+	return others
+}
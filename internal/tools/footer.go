@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// LargeResponseTokens is the estimated size at which responses get a footer, even
+// when nothing was omitted
+const LargeResponseTokens = 2000
+
+// footerMarker starts every footer, so one is never added twice
+const footerMarker = "\n---\n[response: "
+
+// ResponseHints describes what a response left out and how the query could be narrowed
+type ResponseHints struct {
+	Omitted     int      // Number of items left out of the response
+	OmittedKind string   // What the omitted items are, e.g. "matches" or "files"
+	Suggestions []string // Arguments for a cheaper follow-up, e.g. `includeGlobs=["internal/**"]`
+}
+
+// EstimateTokens approximates the number of tokens in text at four bytes per token,
+// which is close enough for code and English to judge whether a response is large
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// AddFooter appends a footer with the estimated token count, the omitted items and
+// suggested follow-up arguments. Small, complete responses are returned unchanged.
+func AddFooter(text string, hints ResponseHints) string {
+	tokens := EstimateTokens(text)
+	if hints.Omitted == 0 && tokens < LargeResponseTokens {
+		return text
+	}
+	return strings.TrimRight(text, "\n") + Footer(tokens, hints)
+}
+
+// Footer formats a footer for a response of the given estimated size
+func Footer(tokens int, hints ResponseHints) string {
+	var sb strings.Builder
+	sb.WriteString(footerMarker)
+	sb.WriteString(fmt.Sprintf("~%d tokens", tokens))
+	if hints.Omitted > 0 {
+		sb.WriteString(fmt.Sprintf(", %d %s omitted", hints.Omitted, hints.OmittedKind))
+	}
+	sb.WriteString("]\n")
+	for _, suggestion := range hints.Suggestions {
+		sb.WriteString(fmt.Sprintf("Suggested follow-up: re-run with %s\n", suggestion))
+	}
+	return sb.String()
+}
+
+// HasFooter reports whether text already ends with a footer
+func HasFooter(text string) bool {
+	return strings.Contains(text, footerMarker)
+}
+
+// TruncateToTokens cuts text after the last whole line that fits in about maxTokens
+// and returns what is kept with the number of lines left out. A fenced code block
+// left open by the cut is closed.
+func TruncateToTokens(text string, maxTokens int) (string, int) {
+	limit := maxTokens * 4
+	if len(text) <= limit {
+		return text, 0
+	}
+	cut := strings.LastIndex(text[:limit], "\n") + 1
+	if cut == 0 {
+		// The first line alone is too long, it is cut short
+		cut = limit
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+	}
+	kept, rest := text[:cut], text[cut:]
+	omitted := len(strings.Split(strings.TrimSuffix(rest, "\n"), "\n"))
+
+	fence := "" // Of the code block open at the cut
+	for _, line := range strings.Split(kept, "\n") {
+		trimmed := strings.TrimSpace(line)
+		run := trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, "`"))]
+		switch {
+		case len(run) < 3:
+		case fence == "":
+			fence = run
+		case run == fence && trimmed == run:
+			fence = ""
+		}
+	}
+	if fence != "" {
+		kept = strings.TrimSuffix(kept, "\n") + "\n" + fence + "\n"
+	}
+	return kept, omitted
+}
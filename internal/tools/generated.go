@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultGeneratedPatterns are the globs of files treated as generated unless
+// configured otherwise
+var DefaultGeneratedPatterns = []string{"*.pb.go", "*_gen.go", "*.min.js", "dist/**"}
+
+// generatedPatterns match the files whose references and definitions are reduced
+// to a count instead of being shown in full
+var generatedPatterns = DefaultGeneratedPatterns
+
+// SetGeneratedPatterns sets the globs of generated files, none to show every file
+// in full. It must be called before tools are used.
+func SetGeneratedPatterns(patterns []string) {
+	generatedPatterns = patterns
+}
+
+// isGeneratedFile reports whether a file matches a generated file pattern. Patterns
+// are matched against the path relative to the workspace, its base name, and the
+// path below each directory, so "dist/**" also matches a nested dist directory.
+func isGeneratedFile(path string) bool {
+	if len(generatedPatterns) == 0 {
+		return false
+	}
+	relPath := path
+	if root, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+			relPath = rel
+		}
+	}
+	relPath = filepath.ToSlash(relPath)
+	for {
+		if matchesAnyGlob(generatedPatterns, relPath) {
+			return true
+		}
+		_, rest, found := strings.Cut(relPath, "/")
+		if !found {
+			return false
+		}
+		relPath = rest
+	}
+}
+
+// formatGeneratedCount is the one line standing in for what was found in generated
+// files, e.g. "12 references in 2 generated files not shown (a.pb.go: 10, b.pb.go: 2)"
+func formatGeneratedCount(noun string, counts map[string]int, paths []string) string {
+	total := 0
+	files := make([]string, len(paths))
+	for i, path := range paths {
+		total += counts[path]
+		files[i] = fmt.Sprintf("%s: %d", path, counts[path])
+	}
+	return fmt.Sprintf("%s in %s not shown (%s)", pluralize(total, noun), pluralize(len(paths), "generated file"), strings.Join(files, ", "))
+}
@@ -4,31 +4,19 @@ import (
 	"context"
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
-	"github.com/isaacphi/mcp-language-server/internal/protocol"
 )
 
-// GetCodeLens retrieves code lens hints for a given file location
+// GetCodeLens retrieves code lens hints for a given file location. The lenses are
+// kept with the document version they were listed for, see listedCodeLenses.
 func GetCodeLens(ctx context.Context, client *lsp.Client, filePath string) (string, error) {
 	err := client.OpenFile(ctx, filePath)
 	if err != nil {
 		return "", fmt.Errorf("could not open file: %v", err)
 	}
-	// TODO: find a more appropriate way to wait
-	time.Sleep(time.Second)
 
-	// Create document identifier
-	docIdentifier := protocol.TextDocumentIdentifier{
-		URI: protocol.DocumentUri("file://" + filePath),
-	}
-
-	// Request code lens from LSP
-	params := protocol.CodeLensParams{
-		TextDocument: docIdentifier,
-	}
-	codeLensResult, err := client.CodeLens(ctx, params)
+	codeLensResult, version, err := fileCodeLenses(ctx, client, filePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to get code lens: %w", err)
 	}
@@ -39,7 +27,7 @@ func GetCodeLens(ctx context.Context, client *lsp.Client, filePath string) (stri
 
 	// Format the code lens results
 	var output strings.Builder
-	output.WriteString(fmt.Sprintf("Code Lens results for %s:\n\n", filePath))
+	output.WriteString(fmt.Sprintf("Code Lens results for %s (document version %d):\n\n", filePath, version))
 
 	for i, lens := range codeLensResult {
 		output.WriteString(fmt.Sprintf("[%d] Location: Lines %d-%d\n",
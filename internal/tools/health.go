@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+)
+
+// Health reports the state of the language server, the open documents and the
+// workspace watcher, for debugging tools that return nothing
+func Health(client *lsp.Client, w *watcher.WorkspaceWatcher, lspCommand string) (string, error) {
+	h := client.Health()
+	stats := w.Stats()
+
+	var result strings.Builder
+	result.WriteString("Language server\n")
+	state := "running"
+	switch {
+	case h.ExitState != "":
+		state = "exited (" + h.ExitState + ")"
+	case !h.Running:
+		state = "not responding (connection closed)"
+	}
+	fmt.Fprintf(&result, "  Command: %s\n", lspCommand)
+	fmt.Fprintf(&result, "  State: %s\n", state)
+	fmt.Fprintf(&result, "  PID: %d\n", h.PID)
+	if !h.StartTime.IsZero() {
+		fmt.Fprintf(&result, "  Uptime: %s\n", time.Since(h.StartTime).Round(time.Second))
+	}
+	fmt.Fprintf(&result, "  Initialize latency: %s\n", h.InitializeLatency.Round(time.Millisecond))
+	fmt.Fprintf(&result, "  Requests in flight: %d\n", h.InFlight)
+	if h.LastError != "" {
+		fmt.Fprintf(&result, "  Last error (%s ago): %s\n", time.Since(h.LastErrorTime).Round(time.Second), h.LastError)
+	} else {
+		result.WriteString("  Last error: none\n")
+	}
+
+	for _, task := range client.ActiveProgress() {
+		fmt.Fprintf(&result, "  In progress: %s (for %s)\n", task.Title, time.Since(task.Started).Round(time.Second))
+	}
+
+	writeJavaStatus(&result, client)
+
+	result.WriteString("\nDocuments\n")
+	fmt.Fprintf(&result, "  Open: %d\n", h.OpenDocuments)
+	if pinned := client.PinnedFiles(); len(pinned) > 0 {
+		fmt.Fprintf(&result, "  Pinned: %d\n", len(pinned))
+	}
+	fmt.Fprintf(&result, "  Diagnostics cached: %d in %d files\n", h.Diagnostics, h.DiagnosticFiles)
+	if client.PullsDiagnostics() {
+		result.WriteString("  Diagnostics mode: pull (textDocument/diagnostic)\n")
+	} else {
+		result.WriteString("  Diagnostics mode: push (publishDiagnostics)\n")
+	}
+
+	result.WriteString("\nWatcher\n")
+	fmt.Fprintf(&result, "  Server watch registrations: %d\n", stats.Registrations)
+	fmt.Fprintf(&result, "  Watched directories: %d\n", stats.WatchedDirs)
+	if stats.PolledDirs > 0 {
+		fmt.Fprintf(&result, "  Polled directories: %d\n", stats.PolledDirs)
+	}
+	fmt.Fprintf(&result, "  Pending events: %d\n", stats.PendingEvents)
+	if stats.SkippedLarge > 0 || stats.SkippedBinary > 0 {
+		fmt.Fprintf(&result, "  Skipped files: %d too large, %d binary\n", stats.SkippedLarge, stats.SkippedBinary)
+	}
+	fmt.Fprintf(&result, "  Change journal: %d entries, %d events total\n", stats.JournalEntries, stats.LastJournalSeq)
+	if stats.EagerOpenMaxFiles > 0 {
+		fmt.Fprintf(&result, "  Eager open: %s (max %d files)\n", stats.EagerOpen, stats.EagerOpenMaxFiles)
+	} else {
+		fmt.Fprintf(&result, "  Eager open: %s\n", stats.EagerOpen)
+	}
+
+	if symbolIndex != nil {
+		indexStats := symbolIndex.Stats()
+		result.WriteString("\nSymbol index\n")
+		fmt.Fprintf(&result, "  File: %s\n", indexStats.Path)
+		fmt.Fprintf(&result, "  Indexed: %d symbols in %d files\n", indexStats.Symbols, indexStats.Files)
+		switch {
+		case indexStats.Reconciled:
+			fmt.Fprintf(&result, "  State: up to date with the language server (%s ago)\n", time.Since(indexStats.Built).Round(time.Second))
+		case indexStats.Built.IsZero():
+			result.WriteString("  State: building\n")
+		default:
+			fmt.Fprintf(&result, "  State: from the previous run (built %s), being reconciled\n", indexStats.Built.Format(time.DateTime))
+		}
+	}
+
+	// Hints for the usual causes of empty results
+	var hints []string
+	if !h.Running {
+		hints = append(hints, "The language server is not running, restart the MCP server.")
+	}
+	if h.Running && stats.Registrations == 0 {
+		hints = append(hints, "The server registered no file watchers, it may still be loading the workspace or may not support dynamic registration.")
+	}
+	if h.Running && h.OpenDocuments == 0 && stats.EagerOpen != watcher.EagerOpenNever {
+		hints = append(hints, "No documents are open. Some servers only return results for open files, try --eager-open=always.")
+	}
+	if len(hints) > 0 {
+		result.WriteString("\nHints\n")
+		for _, hint := range hints {
+			fmt.Fprintf(&result, "  - %s\n", hint)
+		}
+	}
+
+	return result.String(), nil
+}
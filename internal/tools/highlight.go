@@ -0,0 +1,194 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/text"
+)
+
+// How occurrences of a symbol are marked in the code blocks of read_definition and
+// find_references, besides the '>' on their lines
+const (
+	// highlightCarets underlines occurrences with a line of carets
+	highlightCarets = "carets"
+	// highlightInline wraps occurrences in « and »
+	highlightInline = "inline"
+	// highlightNone only marks the lines
+	highlightNone = "none"
+)
+
+func validateHighlight(mode string) error {
+	switch mode {
+	case "", highlightCarets, highlightInline, highlightNone:
+		return nil
+	}
+	return fmt.Errorf("invalid highlight %q, expected carets, inline or none", mode)
+}
+
+// markInline wraps the references on a line in « and ». Columns are LSP
+// characters, treated as one per rune like caretLine does.
+func markInline(line string, refs []ReferencePosition) string {
+	runes := []rune(line)
+	marked := make([]bool, len(runes))
+	for _, ref := range refs {
+		end := int(ref.EndCharacter)
+		if end <= int(ref.Character) {
+			end = int(ref.Character) + 1
+		}
+		for i := int(ref.Character); i < end && i < len(marked); i++ {
+			marked[i] = true
+		}
+	}
+
+	var sb strings.Builder
+	for i, r := range runes {
+		if marked[i] && (i == 0 || !marked[i-1]) {
+			sb.WriteRune('«')
+		}
+		sb.WriteRune(r)
+		if marked[i] && (i == len(runes)-1 || !marked[i+1]) {
+			sb.WriteRune('»')
+		}
+	}
+	return sb.String()
+}
+
+// symbolOccurrences finds where a symbol's name occurs in a range of a document,
+// keyed by 1-based line with rune columns. The server's document highlights of the
+// first occurrence are used, so other symbols with the same name are left out;
+// without them every whole-word occurrence of the name counts.
+func symbolOccurrences(ctx context.Context, client *lsp.Client, uri protocol.DocumentUri, content []byte, rng protocol.Range, symbolName string) map[int][]ReferencePosition {
+	name := symbolName
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	lines := text.Lines(content)
+	if name == "" || int(rng.End.Line) >= len(lines) {
+		return nil
+	}
+
+	occurrences := make(map[int][]ReferencePosition)
+	var first *protocol.Position
+	for l := int(rng.Start.Line); l <= int(rng.End.Line); l++ {
+		line := lines[l]
+		for offset := 0; offset < len(line); {
+			i := strings.Index(line[offset:], name)
+			if i < 0 {
+				break
+			}
+			start, end := offset+i, offset+i+len(name)
+			offset = end
+			if !isWordBoundary(line, start, end) {
+				continue
+			}
+			if first == nil {
+				first = &protocol.Position{Line: uint32(l), Character: text.Column(line, start)}
+			}
+			column := uint32(utf8.RuneCountInString(line[:start]))
+			occurrences[l+1] = append(occurrences[l+1], ReferencePosition{
+				Line:         uint32(l),
+				Character:    column,
+				EndCharacter: column + uint32(utf8.RuneCountInString(name)),
+			})
+		}
+	}
+	if first == nil {
+		return nil
+	}
+
+	highlights, err := client.DocumentHighlight(ctx, protocol.DocumentHighlightParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     *first,
+		},
+	})
+	if err != nil || len(highlights) == 0 {
+		return occurrences
+	}
+	highlighted := make(map[int][]ReferencePosition)
+	for _, highlight := range highlights {
+		r := highlight.Range
+		if r.Start.Line != r.End.Line || r.Start.Line < rng.Start.Line || r.Start.Line > rng.End.Line {
+			continue
+		}
+		line := lines[r.Start.Line]
+		start, end := text.ByteOffset(line, r.Start.Character), text.ByteOffset(line, r.End.Character)
+		highlighted[int(r.Start.Line)+1] = append(highlighted[int(r.Start.Line)+1], ReferencePosition{
+			Line:         r.Start.Line,
+			Character:    uint32(utf8.RuneCountInString(line[:start])),
+			EndCharacter: uint32(utf8.RuneCountInString(line[:end])),
+		})
+	}
+	return highlighted
+}
+
+// isWordBoundary reports whether line[start:end] is a whole identifier
+func isWordBoundary(line string, start, end int) bool {
+	isIdent := func(r rune) bool {
+		return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+	if before, _ := utf8.DecodeLastRuneInString(line[:start]); start > 0 && isIdent(before) {
+		return false
+	}
+	if after, _ := utf8.DecodeRuneInString(line[end:]); end < len(line) && isIdent(after) {
+		return false
+	}
+	return true
+}
+
+// highlightBlock marks the occurrences in a code block whose lines are the lines
+// of a document from startLine (1-based), each possibly prefixed with a gutter.
+// The first line starts at firstColumn (in runes) of its document line. With
+// carets a line of carets follows each line with occurrences, indented like the
+// code by blanking the line's gutter; inline, occurrences are wrapped in « ».
+func highlightBlock(block string, code []string, startLine int, firstColumn int, occurrences map[int][]ReferencePosition, mode string) string {
+	if mode != highlightCarets && mode != highlightInline || len(occurrences) == 0 {
+		return block
+	}
+	trailingNewline := strings.HasSuffix(block, "\n")
+	lines := strings.Split(strings.TrimSuffix(block, "\n"), "\n")
+	if len(lines) != len(code) {
+		return block
+	}
+
+	var out []string
+	for i, line := range lines {
+		refs := occurrences[startLine+i]
+		if i == 0 && firstColumn > 0 {
+			// Columns are of the document line, the block's first line starts later
+			var shifted []ReferencePosition
+			for _, ref := range refs {
+				if int(ref.Character) >= firstColumn {
+					ref.Character -= uint32(firstColumn)
+					ref.EndCharacter -= uint32(firstColumn)
+					shifted = append(shifted, ref)
+				}
+			}
+			refs = shifted
+		}
+		gutter, ok := strings.CutSuffix(line, code[i])
+		if len(refs) == 0 || !ok {
+			out = append(out, line)
+			continue
+		}
+		if mode == highlightInline {
+			out = append(out, gutter+markInline(code[i], refs))
+			continue
+		}
+		out = append(out, line)
+		if carets := caretLine(strings.Repeat(" ", utf8.RuneCountInString(gutter)), code[i], refs); carets != "" {
+			out = append(out, strings.TrimSuffix(carets, "\n"))
+		}
+	}
+	result := strings.Join(out, "\n")
+	if trailingNewline {
+		result += "\n"
+	}
+	return result
+}
@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/text"
+)
+
+// HoverPosition is a position to get hover information for
+type HoverPosition struct {
+	FilePath string `json:"filePath,omitempty" jsonschema:"description=The file containing the position. Defaults to the filePath of the request"`
+	Line     int    `json:"line" jsonschema:"required,description=The line number (1-indexed)"`
+	Column   int    `json:"column" jsonschema:"required,description=The column number (1-indexed)"`
+}
+
+// GetHoverInfoBatch retrieves hover information for several positions in one response.
+// Positions without a file path use defaultFilePath. A failure at one position is
+// reported in its entry rather than failing the whole batch.
+func GetHoverInfoBatch(ctx context.Context, client *lsp.Client, defaultFilePath string, positions []HoverPosition, opts HoverOptions) (string, error) {
+	opts = opts.withVerbosity(ctx)
+	if len(positions) == 0 {
+		return "", fmt.Errorf("no positions given")
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Hover Information (%d positions)\n", len(positions)))
+
+	seen := make(map[HoverPosition]bool)
+	lines := make(map[string][]string)
+	for _, pos := range positions {
+		if pos.FilePath == "" {
+			pos.FilePath = defaultFilePath
+		}
+		if pos.FilePath == "" {
+			return "", fmt.Errorf("position L%d:C%d has no file path", pos.Line, pos.Column)
+		}
+		if seen[pos] {
+			continue
+		}
+		seen[pos] = true
+
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		if _, ok := lines[pos.FilePath]; !ok {
+			if err := client.OpenFile(ctx, pos.FilePath); err != nil {
+				debugLogger.Printf("Warning: could not open file %s: %v\n", pos.FilePath, err)
+			}
+			content, err := client.GetFileContent(pos.FilePath)
+			if err != nil {
+				debugLogger.Printf("Warning: failed to read file %s: %v\n", pos.FilePath, err)
+			}
+			lines[pos.FilePath] = text.Lines(content)
+		}
+
+		header := fmt.Sprintf("\n%s:%d:%d", pos.FilePath, pos.Line, pos.Column)
+		if word := wordAt(lines[pos.FilePath], pos.Line, pos.Column); word != "" {
+			header += fmt.Sprintf(" (%s)", word)
+		}
+		result.WriteString(header + "\n")
+
+		contents, err := hoverContents(ctx, client, pos.FilePath, pos.Line, pos.Column)
+		if err != nil {
+			result.WriteString(fmt.Sprintf("Error: %v\n", err))
+			continue
+		}
+		if contents.Value == "" {
+			result.WriteString("No hover information available for this position\n")
+			continue
+		}
+		text, _ := renderHover(contents, opts)
+		result.WriteString(text + "\n")
+	}
+
+	return result.String(), nil
+}
+
+// wordAt returns the identifier at a 1-indexed position, if any
+func wordAt(lines []string, line, column int) string {
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	runes := []rune(lines[line-1])
+	col := column - 1
+	if col < 0 || col >= len(runes) {
+		return ""
+	}
+
+	isWordRune := func(r rune) bool {
+		return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+	if !isWordRune(runes[col]) {
+		return ""
+	}
+	start, end := col, col
+	for start > 0 && isWordRune(runes[start-1]) {
+		start--
+	}
+	for end < len(runes) && isWordRune(runes[end]) {
+		end++
+	}
+	return string(runes[start:end])
+}
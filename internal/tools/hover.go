@@ -2,23 +2,80 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 )
 
+// HoverOptions controls how hover contents are rendered
+type HoverOptions struct {
+	// PlainText renders markdown contents as plain text
+	PlainText bool
+	// StripLinks replaces markdown links with their text
+	StripLinks bool
+	// SignatureOnly keeps only the first code block, or the first paragraph if there is none
+	SignatureOnly bool
+}
+
+var (
+	markdownLinkPattern     = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	markdownAutolinkPattern = regexp.MustCompile(`<(?:https?|file)://[^>]*>`)
+	markdownBoldPattern     = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	markdownEmphasisPattern = regexp.MustCompile(`\*([^*\s][^*]*)\*`)
+	markdownCodePattern     = regexp.MustCompile("`([^`]*)`")
+	markdownHeadingPattern  = regexp.MustCompile(`^#{1,6}\s+`)
+	markdownEscapePattern   = regexp.MustCompile(`\\([\\` + "`" + `*_{}\[\]()#+\-.!<>|])`)
+	markdownRulePattern     = regexp.MustCompile(`^\s*(?:-{3,}|\*{3,}|_{3,})\s*$`)
+	blankLinesPattern       = regexp.MustCompile(`\n{3,}`)
+)
+
 // GetHoverInfo retrieves hover information (type, documentation) for a symbol at the specified position
-func GetHoverInfo(ctx context.Context, client *lsp.Client, filePath string, line, column int) (string, error) {
+func GetHoverInfo(ctx context.Context, client *lsp.Client, filePath string, line, column int, opts HoverOptions) (string, error) {
+	opts = opts.withVerbosity(ctx)
 	// Open the file if not already open
 	err := client.OpenFile(ctx, filePath)
 	if err != nil {
 		return "", fmt.Errorf("could not open file: %v", err)
 	}
 
+	contents, err := hoverContents(ctx, client, filePath, line, column)
+	if err != nil {
+		return "", err
+	}
+	return formatHover(contents, opts), nil
+}
+
+// formatHover renders the result of the hover tool
+func formatHover(contents protocol.MarkupContent, opts HoverOptions) string {
+	var result strings.Builder
+	result.WriteString("Hover Information\n")
+
+	// Process the hover contents based on Markup content
+	if contents.Value == "" {
+		result.WriteString("No hover information available for this position")
+	} else {
+		text, kind := renderHover(contents, opts)
+		if kind != "" {
+			result.WriteString(fmt.Sprintf("Kind: %s\n\n", kind))
+		}
+		result.WriteString(text)
+	}
+
+	return result.String()
+}
+
+// hoverContents requests hover information for a 1-indexed position, normalized to MarkupContent
+func hoverContents(ctx context.Context, client *lsp.Client, filePath string, line, column int) (protocol.MarkupContent, error) {
+	return hoverContentsAt(ctx, client, protocol.DocumentUri("file://"+filePath), line, column)
+}
+
+// hoverContentsAt is hoverContents for any document, such as a notebook cell
+func hoverContentsAt(ctx context.Context, client *lsp.Client, uri protocol.DocumentUri, line, column int) (protocol.MarkupContent, error) {
 	// Convert 1-indexed line/column to 0-indexed for LSP protocol
-	uri := protocol.DocumentUri("file://" + filePath)
 	position := protocol.Position{
 		Line:      uint32(line - 1),
 		Character: uint32(column - 1),
@@ -33,24 +90,122 @@ func GetHoverInfo(ctx context.Context, client *lsp.Client, filePath string, line
 	}
 	params.Position = position
 
-	// Execute the hover request
-	hoverResult, err := client.Hover(ctx, params)
+	// Execute the hover request. The result is decoded here rather than into
+	// protocol.Hover, as servers send contents in shapes its decoding rejects.
+	var hoverResult json.RawMessage
+	if err := client.Call(ctx, "textDocument/hover", params, &hoverResult); err != nil {
+		return protocol.MarkupContent{}, fmt.Errorf("failed to get hover information: %v", err)
+	}
+
+	// Servers may send MarkupContent, a MarkedString or a list of MarkedStrings
+	contents, err := protocol.HoverContents(hoverResult)
 	if err != nil {
-		return "", fmt.Errorf("failed to get hover information: %v", err)
+		return protocol.MarkupContent{}, fmt.Errorf("failed to get hover information: %v", err)
 	}
+	return contents, nil
+}
 
-	var result strings.Builder
-	result.WriteString("Hover Information\n")
+// renderHover applies the rendering options to hover contents and returns the text and its kind
+func renderHover(contents protocol.MarkupContent, opts HoverOptions) (string, protocol.MarkupKind) {
+	text := contents.Value
+	kind := contents.Kind
+	isMarkdown := kind == protocol.Markdown
 
-	// Process the hover contents based on Markup content
-	if hoverResult.Contents.Value == "" {
-		result.WriteString("No hover information available for this position")
-	} else {
-		if hoverResult.Contents.Kind != "" {
-			result.WriteString(fmt.Sprintf("Kind: %s\n\n", hoverResult.Contents.Kind))
+	if opts.SignatureOnly {
+		text = hoverSignature(text, isMarkdown)
+	}
+	if isMarkdown && opts.StripLinks {
+		text = stripMarkdownLinks(text)
+	}
+	if isMarkdown && opts.PlainText {
+		text = markdownToPlainText(text)
+		kind = protocol.PlainText
+	}
+
+	return strings.TrimSpace(text), kind
+}
+
+// hoverSignature returns the first fenced code block of markdown contents, or the
+// first paragraph if there is no code block
+func hoverSignature(text string, isMarkdown bool) string {
+	lines := strings.Split(text, "\n")
+
+	if isMarkdown {
+		start := -1
+		for i, line := range lines {
+			if !strings.HasPrefix(strings.TrimSpace(line), "```") {
+				continue
+			}
+			if start == -1 {
+				start = i
+				continue
+			}
+			return strings.Join(lines[start:i+1], "\n")
 		}
-		result.WriteString(hoverResult.Contents.Value)
 	}
 
-	return result.String(), nil
+	var paragraph []string
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			if len(paragraph) > 0 {
+				break
+			}
+			continue
+		}
+		paragraph = append(paragraph, line)
+	}
+	return strings.Join(paragraph, "\n")
+}
+
+// stripMarkdownLinks replaces links with their text and removes autolinks
+func stripMarkdownLinks(text string) string {
+	return mapMarkdownProse(text, func(line string) string {
+		line = markdownLinkPattern.ReplaceAllString(line, "$1")
+		return markdownAutolinkPattern.ReplaceAllString(line, "")
+	})
+}
+
+// markdownToPlainText removes markdown syntax, keeping the contents of code blocks as is
+func markdownToPlainText(text string) string {
+	var lines []string
+	inCode := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			lines = append(lines, line)
+			continue
+		}
+		if markdownRulePattern.MatchString(line) {
+			lines = append(lines, "")
+			continue
+		}
+		line = markdownHeadingPattern.ReplaceAllString(line, "")
+		line = markdownLinkPattern.ReplaceAllString(line, "$1")
+		line = markdownAutolinkPattern.ReplaceAllString(line, "")
+		line = markdownCodePattern.ReplaceAllString(line, "$1")
+		line = markdownBoldPattern.ReplaceAllString(line, "$1$2")
+		line = markdownEmphasisPattern.ReplaceAllString(line, "$1")
+		line = markdownEscapePattern.ReplaceAllString(line, "$1")
+		lines = append(lines, line)
+	}
+	return blankLinesPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+}
+
+// mapMarkdownProse applies fn to every line outside of fenced code blocks
+func mapMarkdownProse(text string, fn func(string) string) string {
+	lines := strings.Split(text, "\n")
+	inCode := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if !inCode {
+			lines[i] = fn(line)
+		}
+	}
+	return strings.Join(lines, "\n")
 }
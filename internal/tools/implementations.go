@@ -0,0 +1,278 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// receiverPattern matches method names like "(*Client).Call" or "(Client).Call"
+var receiverPattern = regexp.MustCompile(`^\(\*?([^)]+)\)\.`)
+
+// implementingType is a type that implements an interface
+type implementingType struct {
+	Name     string
+	Kind     protocol.SymbolKind
+	Location protocol.Location
+	Methods  map[string]bool
+}
+
+// FindImplementations lists the types implementing an interface, with how many of
+// the interface's methods each type implements. matchMode sets how symbol names are
+// compared with interfaceName (see nameMatches).
+func FindImplementations(ctx context.Context, client *lsp.Client, interfaceName string, showLineNumbers bool, matchMode string) (string, error) {
+	if err := validateMatchMode(matchMode); err != nil {
+		return "", err
+	}
+	if _, err := symbolRegex(interfaceName); err != nil {
+		return "", err
+	}
+	symbolResult, err := client.Symbol(ctx, protocol.WorkspaceSymbolParams{Query: serverSymbolQuery(interfaceName)})
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch workspace symbols: %v", err)
+	}
+	results, err := symbolResult.Results()
+	if err != nil {
+		return "", fmt.Errorf("failed to parse workspace symbols: %v", err)
+	}
+
+	var interfaces []protocol.WorkspaceSymbolResult
+	var fallbacks []protocol.WorkspaceSymbolResult
+	for _, symbol := range results {
+		if !nameMatches(symbol.GetName(), interfaceName, matchMode) || symbol.GetLocation().URI == "" {
+			continue
+		}
+		if symbol.GetKind() == protocol.Interface {
+			interfaces = append(interfaces, symbol)
+		} else {
+			fallbacks = append(fallbacks, symbol)
+		}
+	}
+	if len(interfaces) == 0 {
+		interfaces = fallbacks
+	}
+	if len(interfaces) == 0 {
+		return fmt.Sprintf("Interface '%s' not found in workspace.", interfaceName), nil
+	}
+
+	var output strings.Builder
+	for i, symbol := range interfaces {
+		if i > 0 {
+			output.WriteString("\n---\n\n")
+		}
+		text, err := formatImplementations(ctx, client, symbol.GetName(), symbol.GetLocation(), showLineNumbers)
+		if err != nil {
+			return "", err
+		}
+		output.WriteString(text)
+	}
+	return output.String(), nil
+}
+
+func formatImplementations(ctx context.Context, client *lsp.Client, interfaceName string, loc protocol.Location, showLineNumbers bool) (string, error) {
+	filePath := strings.TrimPrefix(string(loc.URI), "file://")
+
+	if !lsp.IsExternal(loc.URI) {
+		if err := client.OpenFile(ctx, documentPath(loc.URI)); err != nil {
+			return "", fmt.Errorf("could not open file: %v", err)
+		}
+	}
+
+	// Find the interface's methods from its document symbol children
+	var methods []protocol.DocumentSymbol
+	position := loc.Range.Start
+	if symbols, err := getDocumentSymbols(ctx, client, loc.URI); err == nil {
+		if sym, found := findNamedDocumentSymbol(symbols, interfaceName, loc.Range.Start); found {
+			position = sym.SelectionRange.Start
+			for _, child := range sym.Children {
+				if child.Kind == protocol.Method || child.Kind == protocol.Function {
+					methods = append(methods, child)
+				}
+			}
+		}
+	}
+
+	implResult, err := client.Implementation(ctx, protocol.ImplementationParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: loc.URI},
+			Position:     position,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get implementations: %v", err)
+	}
+	implLocations, err := implResult.Locations()
+	if err != nil {
+		return "", fmt.Errorf("failed to parse implementations: %v", err)
+	}
+
+	types := make(map[string]*implementingType)
+	symbolCache := make(map[protocol.DocumentUri][]protocol.DocumentSymbolResult)
+	symbolsFor := func(uri protocol.DocumentUri) []protocol.DocumentSymbolResult {
+		if symbols, ok := symbolCache[uri]; ok {
+			return symbols
+		}
+		if !lsp.IsExternal(uri) {
+			if err := client.OpenFile(ctx, documentPath(uri)); err != nil {
+				debugLogger.Printf("Warning: could not open %s: %v\n", uri, err)
+				symbolCache[uri] = nil
+				return nil
+			}
+		}
+		symbols, err := getDocumentSymbols(ctx, client, uri)
+		if err != nil {
+			debugLogger.Printf("Warning: %v\n", err)
+		}
+		symbolCache[uri] = symbols
+		return symbols
+	}
+
+	for _, implLoc := range implLocations {
+		name, kind := typeAtLocation(symbolsFor(implLoc.URI), implLoc)
+		if name == "" {
+			continue
+		}
+		key := string(implLoc.URI) + "#" + name
+		if _, exists := types[key]; !exists {
+			types[key] = &implementingType{Name: name, Kind: kind, Location: implLoc, Methods: make(map[string]bool)}
+		}
+	}
+
+	// Resolve which types implement each interface method
+	for _, method := range methods {
+		methodImpls, err := client.Implementation(ctx, protocol.ImplementationParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: loc.URI},
+				Position:     method.SelectionRange.Start,
+			},
+		})
+		if err != nil {
+			debugLogger.Printf("Warning: failed to get implementations of method %s: %v\n", method.Name, err)
+			continue
+		}
+		methodLocations, _ := methodImpls.Locations()
+		for _, methodLoc := range methodLocations {
+			receiver := receiverAtLocation(symbolsFor(methodLoc.URI), methodLoc)
+			if t, ok := types[string(methodLoc.URI)+"#"+receiver]; ok {
+				t.Methods[method.Name] = true
+				continue
+			}
+			// Methods may be declared in a different file than their type
+			for _, t := range types {
+				if t.Name == receiver {
+					t.Methods[method.Name] = true
+				}
+			}
+		}
+	}
+
+	sorted := make([]*implementingType, 0, len(types))
+	for _, t := range types {
+		sorted = append(sorted, t)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Location.URI != sorted[j].Location.URI {
+			return sorted[i].Location.URI < sorted[j].Location.URI
+		}
+		return sorted[i].Location.Range.Start.Line < sorted[j].Location.Range.Start.Line
+	})
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Interface: %s\n", interfaceName))
+	result.WriteString(fmt.Sprintf("File: %s\n", filePath))
+	if len(methods) > 0 {
+		methodNames := make([]string, len(methods))
+		for i, m := range methods {
+			methodNames[i] = m.Name
+		}
+		result.WriteString(fmt.Sprintf("Methods: %s\n", strings.Join(methodNames, ", ")))
+	}
+	result.WriteString(fmt.Sprintf("Implementations: %d\n\n", len(sorted)))
+
+	if len(sorted) == 0 {
+		result.WriteString("No implementations found.\n")
+		return result.String(), nil
+	}
+
+	for _, t := range sorted {
+		implPath := strings.TrimPrefix(string(t.Location.URI), "file://")
+		location := implPath
+		if showLineNumbers {
+			location = fmt.Sprintf("%s:%d", implPath, t.Location.Range.Start.Line+1)
+		}
+		completeness := ""
+		if len(methods) > 0 {
+			completeness = fmt.Sprintf(" - %d/%d methods", len(t.Methods), len(methods))
+		}
+		result.WriteString(fmt.Sprintf("%s (%s)%s\n",
+			utilities.FormatSymbolWithKind(utilities.GetSymbolKindString(t.Kind), t.Name), location, completeness))
+
+		for _, method := range methods {
+			status := "not found (may be promoted from an embedded type)"
+			if t.Methods[method.Name] {
+				status = "implemented"
+			}
+			result.WriteString(fmt.Sprintf("  [Method] %s: %s\n", method.Name, status))
+		}
+	}
+
+	return result.String(), nil
+}
+
+// findNamedDocumentSymbol finds a document symbol with the given name whose range contains the position
+func findNamedDocumentSymbol(symbols []protocol.DocumentSymbolResult, name string, pos protocol.Position) (*protocol.DocumentSymbol, bool) {
+	for _, sym := range symbols {
+		ds, ok := sym.(*protocol.DocumentSymbol)
+		if !ok || !containsPosition(ds.Range, pos) {
+			continue
+		}
+		if ds.Name == name {
+			return ds, true
+		}
+		children := make([]protocol.DocumentSymbolResult, len(ds.Children))
+		for i := range ds.Children {
+			children[i] = &ds.Children[i]
+		}
+		if found, ok := findNamedDocumentSymbol(children, name, pos); ok {
+			return found, true
+		}
+	}
+	return nil, false
+}
+
+// typeAtLocation returns the innermost type-like symbol enclosing a location
+func typeAtLocation(symbols []protocol.DocumentSymbolResult, loc protocol.Location) (string, protocol.SymbolKind) {
+	chain := findSymbolsContainingPosition(symbols, loc.Range.Start)
+	for i := len(chain) - 1; i >= 0; i-- {
+		switch chain[i].Kind {
+		case protocol.Class, protocol.Struct, protocol.Interface, protocol.Enum, protocol.Object:
+			return chain[i].Name, chain[i].Kind
+		}
+	}
+	if len(chain) > 0 {
+		return chain[len(chain)-1].Name, chain[len(chain)-1].Kind
+	}
+	return "", 0
+}
+
+// receiverAtLocation returns the name of the type a method at the location belongs to
+func receiverAtLocation(symbols []protocol.DocumentSymbolResult, loc protocol.Location) string {
+	chain := findSymbolsContainingPosition(symbols, loc.Range.Start)
+	if len(chain) == 0 {
+		return ""
+	}
+	leaf := chain[len(chain)-1]
+	if m := receiverPattern.FindStringSubmatch(leaf.Name); m != nil {
+		return m[1]
+	}
+	if len(chain) > 1 {
+		return chain[len(chain)-2].Name
+	}
+	return ""
+}
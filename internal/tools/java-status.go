@@ -0,0 +1,47 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// writeJavaStatus describes what jdtls reported about importing the Gradle, Maven
+// and Eclipse projects, nothing for other servers
+func writeJavaStatus(result *strings.Builder, client *lsp.Client) {
+	status, ok := client.JavaStatus()
+	if !ok {
+		return
+	}
+
+	result.WriteString("\nJava projects\n")
+	if status.State != "" {
+		line := "  State: " + status.State
+		if status.Message != "" {
+			line += " - " + status.Message
+		}
+		result.WriteString(line + "\n")
+	}
+	if status.ServiceReady {
+		result.WriteString("  Services: ready\n")
+	} else {
+		result.WriteString("  Services: not ready yet\n")
+	}
+	switch status.ProjectStatus {
+	case "":
+	case "WARNING":
+		result.WriteString("  Import: WARNING, some projects were not imported fully, check the diagnostics of their build files\n")
+	default:
+		fmt.Fprintf(result, "  Import: %s\n", status.ProjectStatus)
+	}
+	if len(status.ImportedProjects) > 0 {
+		names := make([]string, len(status.ImportedProjects))
+		for i, uri := range status.ImportedProjects {
+			names[i] = filepath.Base(documentPath(protocol.DocumentUri(uri)))
+		}
+		fmt.Fprintf(result, "  Imported: %s\n", strings.Join(names, ", "))
+	}
+}
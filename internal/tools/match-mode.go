@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// How a symbol name given to a tool is compared with the names of workspace symbols
+const (
+	matchExact           = "exact"
+	matchPrefix          = "prefix"
+	matchSubstring       = "substring"
+	matchCaseInsensitive = "caseInsensitive"
+)
+
+// regexQueryPrefix marks a symbol query as a regular expression, e.g.
+// "regex:Handle.*Request", matched against symbol names here rather than by the
+// server. It takes precedence over the match mode.
+const regexQueryPrefix = "regex:"
+
+// symbolRegexes caches compiled regex queries, keyed by the query
+var symbolRegexes sync.Map
+
+// symbolRegex returns the compiled pattern of a "regex:" query, nil for other
+// queries. Patterns are unanchored, use ^ and $ to match whole names.
+func symbolRegex(query string) (*regexp.Regexp, error) {
+	pattern, ok := strings.CutPrefix(query, regexQueryPrefix)
+	if !ok {
+		return nil, nil
+	}
+	if re, ok := symbolRegexes.Load(query); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid symbol regex %q: %v", pattern, err)
+	}
+	symbolRegexes.Store(query, re)
+	return re, nil
+}
+
+// serverSymbolQuery is the workspace/symbol query for a symbol query: the literal
+// prefix every match of a regex starts with, or the query itself. Servers match
+// queries fuzzily, so the prefix narrows their results without losing matches.
+func serverSymbolQuery(query string) string {
+	re, err := symbolRegex(query)
+	if err != nil || re == nil {
+		return query
+	}
+	prefix, _ := re.LiteralPrefix()
+	return prefix
+}
+
+// validateMatchMode checks a matchMode argument, empty meaning exact
+func validateMatchMode(mode string) error {
+	switch mode {
+	case "", matchExact, matchPrefix, matchSubstring, matchCaseInsensitive:
+		return nil
+	}
+	return fmt.Errorf("invalid matchMode %q, expected exact, prefix, substring or caseInsensitive", mode)
+}
+
+// nameMatches compares a symbol name with a query. Prefix and substring matches
+// are case-sensitive, caseInsensitive is an exact match ignoring case. "regex:"
+// queries are matched as regular expressions whatever the mode.
+func nameMatches(name, query, mode string) bool {
+	if re, err := symbolRegex(query); err == nil && re != nil {
+		return re.MatchString(name)
+	}
+	switch mode {
+	case matchPrefix:
+		return strings.HasPrefix(name, query)
+	case matchSubstring:
+		return strings.Contains(name, query)
+	case matchCaseInsensitive:
+		return strings.EqualFold(name, query)
+	}
+	return name == query
+}
+
+// caseMismatchHint suggests the caseInsensitive mode when an exact query found
+// nothing but symbols whose names differ from it only in case exist, "" otherwise
+func caseMismatchHint(results []protocol.WorkspaceSymbolResult, query string, mode string) string {
+	if mode != "" && mode != matchExact {
+		return ""
+	}
+	seen := make(map[string]bool)
+	var names []string
+	for _, symbol := range results {
+		name := symbol.GetName()
+		if name != query && strings.EqualFold(name, query) && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" Symbols differing only in case: %s (pass matchMode 'caseInsensitive' to match them).", strings.Join(names, ", "))
+}
@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/memory"
+	"github.com/isaacphi/mcp-language-server/internal/metrics"
+)
+
+// GetMetrics reports tool call and language server request latencies, cache hit
+// rates and watcher event counts since the server started, and the memory held by
+// caches
+func GetMetrics() (string, error) {
+	s := metrics.Default.Snapshot()
+
+	var result strings.Builder
+	fmt.Fprintf(&result, "Uptime: %s\n", s.Uptime.Round(time.Second))
+
+	writeTimings := func(title string, timings map[string]metrics.Timing) {
+		fmt.Fprintf(&result, "\n%s\n", title)
+		if len(timings) == 0 {
+			result.WriteString("  none\n")
+			return
+		}
+		for _, label := range metrics.Labels(timings) {
+			t := timings[label]
+			fmt.Fprintf(&result, "  %s: %d calls, %d errors, mean %s, max %s\n",
+				label, t.Count, t.Errors, t.Mean().Round(time.Microsecond), t.Max.Round(time.Microsecond))
+		}
+	}
+	writeTimings("Tool calls", s.Timings[metrics.Tool])
+	writeTimings("Language server requests", s.Timings[metrics.LSPRequest])
+
+	result.WriteString("\nCaches\n")
+	hits, misses := s.Counters[metrics.CacheHit], s.Counters[metrics.CacheMiss]
+	caches := make(map[string]bool)
+	for cache := range hits {
+		caches[cache] = true
+	}
+	for cache := range misses {
+		caches[cache] = true
+	}
+	if len(caches) == 0 {
+		result.WriteString("  none\n")
+	}
+	for _, cache := range metrics.Labels(caches) {
+		total := hits[cache] + misses[cache]
+		fmt.Fprintf(&result, "  %s: %d hits, %d misses (%.1f%% hit rate)\n",
+			cache, hits[cache], misses[cache], 100*float64(hits[cache])/float64(total))
+	}
+
+	result.WriteString("\nWatcher events\n")
+	events := s.Counters[metrics.WatcherEvent]
+	if len(events) == 0 {
+		result.WriteString("  none\n")
+	}
+	minutes := s.Uptime.Minutes()
+	for _, changeType := range metrics.Labels(events) {
+		fmt.Fprintf(&result, "  %s: %d (%.1f per minute)\n", changeType, events[changeType], float64(events[changeType])/minutes)
+	}
+
+	budget := memory.Default.Budget()
+	if budget > 0 {
+		fmt.Fprintf(&result, "\nMemory (budget %s)\n", formatBytes(budget))
+	} else {
+		result.WriteString("\nMemory (no budget)\n")
+	}
+	stats := memory.Default.Stats()
+	if len(stats) == 0 {
+		result.WriteString("  none\n")
+	}
+	used := int64(0)
+	for _, cache := range stats {
+		used += cache.Bytes
+		fmt.Fprintf(&result, "  %s: %d entries, %s, %d evictions\n", cache.Name, cache.Entries, formatBytes(cache.Bytes), cache.Evictions)
+	}
+	if len(stats) > 0 {
+		fmt.Fprintf(&result, "  total: %s\n", formatBytes(used))
+	}
+
+	return result.String(), nil
+}
+
+// formatBytes formats a size in bytes with a binary unit
+func formatBytes(bytes int64) string {
+	switch {
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/(1<<20))
+	case bytes >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(bytes)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", bytes)
+}
@@ -0,0 +1,235 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// MoveSymbol moves a top-level symbol out of a file with the server's move
+// refactoring, such as gopls "Move to a new file" or rust-analyzer "Move item to
+// file", which also updates imports. Servers pick the new file themselves. When
+// destination is set, the file the server would create is created at destination
+// instead, which must be a new file in the same directory. With preview set the
+// edit is only shown.
+func MoveSymbol(ctx context.Context, client *lsp.Client, filePath string, symbolName string, destination string, preview bool) (string, error) {
+	if err := client.OpenFile(ctx, filePath); err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+	uri := protocol.DocumentUri("file://" + filePath)
+
+	symbols, err := documentSymbols(ctx, client, filePath)
+	if err != nil {
+		return "", err
+	}
+	var symbol *protocol.DocumentSymbol
+	for _, sym := range symbols {
+		if ds, ok := sym.(*protocol.DocumentSymbol); ok && (ds.Name == symbolName || normalizeReceiver(ds.Name) == symbolName) {
+			symbol = ds
+			break
+		}
+	}
+	if symbol == nil {
+		return "", fmt.Errorf("no top-level symbol %s in %s", symbolName, filePath)
+	}
+
+	actions, err := codeActions(ctx, client, uri, symbol.Range, nil, []string{string(protocol.Refactor)})
+	if err != nil {
+		return "", err
+	}
+	var action *protocol.CodeAction
+	for i := range actions {
+		kind := strings.ToLower(string(actions[i].Kind))
+		title := strings.ToLower(actions[i].Title)
+		if actions[i].Disabled == nil && (strings.Contains(title, "move") || strings.Contains(kind, "move") || strings.Contains(kind, "tonewfile")) {
+			action = &actions[i]
+			break
+		}
+	}
+	if action == nil {
+		return "", fmt.Errorf("the language server offers no move refactoring for %s", symbolName)
+	}
+
+	if action.Edit == nil && action.Command == nil {
+		resolved, err := client.ResolveCodeAction(ctx, *action)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve %q: %v", action.Title, err)
+		}
+		action = &resolved
+	}
+	if action.Edit == nil {
+		if preview || destination != "" {
+			return "", fmt.Errorf("%q runs a server command instead of returning an edit, so it cannot be previewed or given a destination", action.Title)
+		}
+		if _, err := applyCodeAction(ctx, client, *action); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Applied %q. The language server made the edits itself, re-read the affected files.", action.Title), nil
+	}
+
+	if destination != "" {
+		if err := retargetCreatedFile(action.Edit, destination); err != nil {
+			return "", err
+		}
+	}
+
+	diff, err := previewWorkspaceEdit(*action.Edit)
+	if err != nil {
+		return "", err
+	}
+	if preview {
+		return fmt.Sprintf("Preview of %q, nothing was written:\n\n%s", action.Title, diff), nil
+	}
+
+	changed, err := applyCodeAction(ctx, client, *action)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Applied %q, changing %s.\n\n%s", action.Title, pluralize(len(changed), "file"), diff), nil
+}
+
+// retargetCreatedFile makes an edit create destination instead of the one file it
+// creates. Both must be in the same directory, so the symbol stays in its package.
+func retargetCreatedFile(edit *protocol.WorkspaceEdit, destination string) error {
+	destination, err := filepath.Abs(destination)
+	if err != nil {
+		return fmt.Errorf("invalid destination: %v", err)
+	}
+	if _, err := os.Stat(destination); err == nil {
+		return fmt.Errorf("%s already exists, the symbol can only be moved to a new file", destination)
+	}
+
+	var created []protocol.DocumentUri
+	for _, change := range edit.DocumentChanges {
+		if change.CreateFile != nil {
+			created = append(created, change.CreateFile.URI)
+		}
+	}
+	for uri := range edit.Changes {
+		if _, err := os.Stat(strings.TrimPrefix(string(uri), "file://")); os.IsNotExist(err) {
+			created = append(created, uri)
+		}
+	}
+	if len(created) != 1 {
+		return fmt.Errorf("the refactoring creates %d files, a destination can only replace a single new file", len(created))
+	}
+	from := created[0]
+	if filepath.Dir(strings.TrimPrefix(string(from), "file://")) != filepath.Dir(destination) {
+		return fmt.Errorf("the language server moves the symbol to %s, the destination must be in the same directory", strings.TrimPrefix(string(from), "file://"))
+	}
+
+	to := protocol.DocumentUri("file://" + destination)
+	for i := range edit.DocumentChanges {
+		change := &edit.DocumentChanges[i]
+		if change.CreateFile != nil && change.CreateFile.URI == from {
+			change.CreateFile.URI = to
+		}
+		if change.TextDocumentEdit != nil && change.TextDocumentEdit.TextDocument.URI == from {
+			change.TextDocumentEdit.TextDocument.URI = to
+		}
+	}
+	if edits, ok := edit.Changes[from]; ok {
+		delete(edit.Changes, from)
+		edit.Changes[to] = edits
+	}
+	return nil
+}
+
+// previewWorkspaceEdit applies a workspace edit in memory and returns a unified diff
+// for each file it changes, creates, renames or deletes
+func previewWorkspaceEdit(edit protocol.WorkspaceEdit) (string, error) {
+	type fileState struct {
+		original []byte
+		content  []byte
+		created  bool
+		deleted  bool
+		from     string // Path before a rename
+	}
+	files := make(map[string]*fileState)
+	load := func(path string) *fileState {
+		if state, ok := files[path]; ok {
+			return state
+		}
+		content, err := os.ReadFile(path)
+		state := &fileState{original: content, content: content, created: err != nil}
+		files[path] = state
+		return state
+	}
+	applyEdits := func(uri protocol.DocumentUri, edits []protocol.TextEdit) error {
+		state := load(strings.TrimPrefix(string(uri), "file://"))
+		content, err := utilities.EditContent(state.content, edits)
+		if err != nil {
+			return fmt.Errorf("failed to preview edits of %s: %v", uri, err)
+		}
+		state.content = content
+		return nil
+	}
+
+	for uri, edits := range edit.Changes {
+		if err := applyEdits(uri, edits); err != nil {
+			return "", err
+		}
+	}
+	for _, change := range edit.DocumentChanges {
+		switch {
+		case change.CreateFile != nil:
+			path := strings.TrimPrefix(string(change.CreateFile.URI), "file://")
+			files[path] = &fileState{created: true}
+		case change.RenameFile != nil:
+			from := strings.TrimPrefix(string(change.RenameFile.OldURI), "file://")
+			state := load(from)
+			delete(files, from)
+			state.from = from
+			files[strings.TrimPrefix(string(change.RenameFile.NewURI), "file://")] = state
+		case change.DeleteFile != nil:
+			load(strings.TrimPrefix(string(change.DeleteFile.URI), "file://")).deleted = true
+		case change.TextDocumentEdit != nil:
+			edits := make([]protocol.TextEdit, 0, len(change.TextDocumentEdit.Edits))
+			for _, e := range change.TextDocumentEdit.Edits {
+				textEdit, err := e.AsTextEdit()
+				if err != nil {
+					return "", err
+				}
+				edits = append(edits, textEdit)
+			}
+			if err := applyEdits(change.TextDocumentEdit.TextDocument.URI, edits); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var sb strings.Builder
+	for _, path := range paths {
+		state := files[path]
+		aName := path
+		switch {
+		case state.deleted:
+			sb.WriteString(fmt.Sprintf("Delete %s\n", path))
+			continue
+		case state.created:
+			aName = "/dev/null"
+		case state.from != "":
+			sb.WriteString(fmt.Sprintf("Rename %s to %s\n", state.from, path))
+			aName = state.from
+		}
+		diff, err := utilities.UnifiedDiff(aName, path, string(state.original), string(state.content), 3)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(diff)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// notebookCell opens a notebook in the server and returns one of its cells, which
+// are numbered from 1 like lines
+func notebookCell(ctx context.Context, client *lsp.Client, notebookPath string, cell int) (lsp.NotebookCell, error) {
+	cells, err := client.OpenNotebook(ctx, notebookPath)
+	if err != nil {
+		return lsp.NotebookCell{}, fmt.Errorf("could not open notebook: %v", err)
+	}
+	if cell < 1 || cell > len(cells) {
+		return lsp.NotebookCell{}, fmt.Errorf("%s has %d cells, there is no cell %d", notebookPath, len(cells), cell)
+	}
+	return cells[cell-1], nil
+}
+
+// GetNotebookHoverInfo is GetHoverInfo for a position in a notebook cell, with the
+// line and column counted from the start of the cell
+func GetNotebookHoverInfo(ctx context.Context, client *lsp.Client, notebookPath string, cell, line, column int, opts HoverOptions) (string, error) {
+	opts = opts.withVerbosity(ctx)
+	nbCell, err := notebookCell(ctx, client, notebookPath, cell)
+	if err != nil {
+		return "", err
+	}
+	contents, err := hoverContentsAt(ctx, client, nbCell.URI, line, column)
+	if err != nil {
+		return "", err
+	}
+	return formatHover(contents, opts), nil
+}
+
+// GetNotebookDiagnostics retrieves the diagnostics of a notebook cell, or of every
+// code cell of the notebook when cell is 0. Line numbers are within each cell.
+func GetNotebookDiagnostics(ctx context.Context, client *lsp.Client, notebookPath string, cell int, includeContext bool, showLineNumbers bool) (string, error) {
+	var cells []lsp.NotebookCell
+	if cell != 0 {
+		nbCell, err := notebookCell(ctx, client, notebookPath, cell)
+		if err != nil {
+			return "", err
+		}
+		cells = []lsp.NotebookCell{nbCell}
+	} else {
+		all, err := client.OpenNotebook(ctx, notebookPath)
+		if err != nil {
+			return "", fmt.Errorf("could not open notebook: %v", err)
+		}
+		for _, nbCell := range all {
+			if nbCell.Kind == protocol.Code {
+				cells = append(cells, nbCell)
+			}
+		}
+	}
+
+	// Cells share one wait, servers publish diagnostics for all of them at once
+	deadline := time.Now().Add(fileDiagnosticsTimeout)
+	var result strings.Builder
+	var clean []string
+	total := 0
+	for _, nbCell := range cells {
+		diagnostics, err := cellDiagnostics(ctx, client, nbCell.URI, time.Until(deadline))
+		if err != nil {
+			return "", err
+		}
+		if len(diagnostics) == 0 {
+			_, index, _ := lsp.NotebookCellFromURI(nbCell.URI)
+			clean = append(clean, strconv.Itoa(index+1))
+			continue
+		}
+		total += len(diagnostics)
+		result.WriteString("\n")
+		result.WriteString(formatFileDiagnostics(ctx, client, nbCell.URI, diagnostics, includeContext, showLineNumbers))
+	}
+
+	if total == 0 {
+		if cell != 0 {
+			return fmt.Sprintf("No diagnostics found for cell %d of %s", cell, notebookPath), nil
+		}
+		return fmt.Sprintf("No diagnostics found for the %d code cells of %s", len(cells), notebookPath), nil
+	}
+	header := fmt.Sprintf("Diagnostics for %s: %d issues in %d of %d cells checked\n", notebookPath, total, len(cells)-len(clean), len(cells))
+	switch len(clean) {
+	case 0:
+	case 1:
+		header += fmt.Sprintf("No diagnostics in cell %s\n", clean[0])
+	default:
+		header += fmt.Sprintf("No diagnostics in cells %s\n", strings.Join(clean, ", "))
+	}
+	return header + result.String(), nil
+}
+
+// cellDiagnostics pulls the diagnostics of a cell, or waits up to timeout for a
+// server that publishes them to report on its current text
+func cellDiagnostics(ctx context.Context, client *lsp.Client, uri protocol.DocumentUri, timeout time.Duration) ([]protocol.Diagnostic, error) {
+	if client.PullsDiagnostics() {
+		diagnostics, err := client.PullDiagnostics(ctx, uri)
+		if err != nil {
+			return nil, fmt.Errorf("failed to pull diagnostics: %v", err)
+		}
+		return diagnostics, nil
+	}
+
+	published := client.NextDiagnostics(uri)
+	if !client.HasCurrentDiagnostics(uri) && timeout > 0 {
+		if diagnostics, received := waitForDiagnostics(ctx, client, uri, published, timeout); received {
+			return diagnostics, nil
+		}
+	}
+	return client.GetFileDiagnostics(uri), nil
+}
@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// openFileDiagnosticsTimeout is how long open_file waits for the first diagnostics of
+// a document it opened
+const openFileDiagnosticsTimeout = 5 * time.Second
+
+// OpenFile opens a document in the language server and pins it, so it is not closed
+// until CloseFile is called. The diagnostics the server publishes for it are reported.
+func OpenFile(ctx context.Context, client *lsp.Client, filePath string) (string, error) {
+	uri := protocol.DocumentUri("file://" + filePath)
+
+	var published <-chan []protocol.Diagnostic
+	if !client.IsFileOpen(filePath) {
+		published = client.NextDiagnostics(uri)
+	}
+	wasOpen, err := client.PinFile(ctx, filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+
+	var sb strings.Builder
+	if wasOpen {
+		sb.WriteString(fmt.Sprintf("Pinned %s, which was already open. It stays open until close_file is called.\n", filePath))
+	} else {
+		sb.WriteString(fmt.Sprintf("Opened and pinned %s. It stays open until close_file is called.\n", filePath))
+	}
+
+	diagnostics := client.GetFileDiagnostics(uri)
+	if published != nil {
+		var received bool
+		diagnostics, received = waitForDiagnostics(ctx, client, uri, published, openFileDiagnosticsTimeout)
+		if !received {
+			sb.WriteString(fmt.Sprintf("The language server published no diagnostics within %s.\n", openFileDiagnosticsTimeout))
+			return sb.String(), nil
+		}
+	}
+
+	errors, warnings := countSeverities(diagnostics)
+	sb.WriteString(fmt.Sprintf("Diagnostics: %s and %s\n", pluralize(errors, "error"), pluralize(warnings, "warning")))
+	for _, diag := range diagnostics {
+		sb.WriteString(formatDiagnosticLine(diag))
+	}
+	return sb.String(), nil
+}
+
+// CloseFile closes a document in the language server, whether it was pinned or
+// opened by a tool. Tools reopen it when they need it.
+func CloseFile(ctx context.Context, client *lsp.Client, filePath string) (string, error) {
+	if client.IsOverlay(filePath) {
+		return "", fmt.Errorf("%s is an overlay, use discard_overlay to drop it", filePath)
+	}
+	if !client.IsFileOpen(filePath) {
+		return fmt.Sprintf("%s is not open.", filePath), nil
+	}
+	if err := client.CloseFile(ctx, filePath); err != nil {
+		return "", fmt.Errorf("could not close file: %v", err)
+	}
+	return fmt.Sprintf("Closed %s. Tools reopen it when they need it.", filePath), nil
+}
@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+// CreateOverlay opens an in-memory document with content at filePath. The file does
+// not need to exist, if it does the language server sees content instead of it.
+func CreateOverlay(ctx context.Context, client *lsp.Client, filePath string, content string) (string, error) {
+	if client.IsOverlay(filePath) {
+		return "", fmt.Errorf("%s already has an overlay, use update_overlay to change it", filePath)
+	}
+	if _, err := client.SetOverlay(ctx, filePath, []byte(content)); err != nil {
+		return "", fmt.Errorf("failed to create overlay: %v", err)
+	}
+	// Overlays change what the server sees without a file event
+	ClearSymbolCache()
+	return fmt.Sprintf("Created overlay for %s (%s). Tools see this text instead of the file on disk until discard_overlay is called.",
+		filePath, pluralize(lineCount(content), "line")), nil
+}
+
+// UpdateOverlay replaces the content of an existing overlay
+func UpdateOverlay(ctx context.Context, client *lsp.Client, filePath string, content string) (string, error) {
+	if !client.IsOverlay(filePath) {
+		return "", fmt.Errorf("%s has no overlay, use create_overlay first", filePath)
+	}
+	if _, err := client.SetOverlay(ctx, filePath, []byte(content)); err != nil {
+		return "", fmt.Errorf("failed to update overlay: %v", err)
+	}
+	ClearSymbolCache()
+	return fmt.Sprintf("Updated overlay for %s (%s).", filePath, pluralize(lineCount(content), "line")), nil
+}
+
+// DiscardOverlay drops an overlay, so the language server sees the file on disk again
+func DiscardOverlay(ctx context.Context, client *lsp.Client, filePath string) (string, error) {
+	stillOpen, err := client.DiscardOverlay(ctx, filePath)
+	if err != nil {
+		return "", err
+	}
+	ClearSymbolCache()
+	if !stillOpen {
+		return fmt.Sprintf("Discarded overlay for %s, the document is closed since the file does not exist on disk.", filePath), nil
+	}
+	return fmt.Sprintf("Discarded overlay for %s, the language server sees the file on disk again.", filePath), nil
+}
+
+func lineCount(content string) int {
+	if content == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimSuffix(content, "\n"), "\n") + 1
+}
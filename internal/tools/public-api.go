@@ -0,0 +1,295 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/text"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+)
+
+// maxPublicAPISymbols caps the symbols described by hover requests. Symbols past
+// it are still listed, with their declaration line as the signature.
+const maxPublicAPISymbols = 200
+
+// maxDocSummaryLength caps the doc summary shown under a symbol
+const maxDocSummaryLength = 160
+
+var sentenceEndPattern = regexp.MustCompile(`[.!?](\s|$)`)
+
+// visibilityKeywords are the words that make a declaration public in languages
+// where symbols are private or module-local by default, by file extension
+var visibilityKeywords = map[string][]string{
+	".ts":   {"export"},
+	".tsx":  {"export"},
+	".mts":  {"export"},
+	".cts":  {"export"},
+	".js":   {"export"},
+	".jsx":  {"export"},
+	".mjs":  {"export"},
+	".cjs":  {"export"},
+	".rs":   {"pub"},
+	".java": {"public"},
+	".cs":   {"public"},
+}
+
+// apiSymbol is an exported symbol with its description
+type apiSymbol struct {
+	symbol    *protocol.DocumentSymbol
+	depth     int
+	signature string
+	summary   string
+}
+
+// GetPublicAPI lists the exported symbols declared in the files of a directory,
+// not its subdirectories, with their kinds, signatures and doc summaries from
+// hover. Test files are left out.
+func GetPublicAPI(ctx context.Context, client *lsp.Client, w *watcher.WorkspaceWatcher, directory string, topLevelOnly bool) (string, error) {
+	directory, err := filepath.Abs(directory)
+	if err != nil {
+		return "", fmt.Errorf("invalid directory: %v", err)
+	}
+	found, err := findDiagnosticsFiles(ctx, w, directory, "")
+	if err != nil {
+		return "", err
+	}
+	var paths []string
+	for _, path := range found {
+		if filepath.Dir(path) == directory && !isTestFile(path) {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return fmt.Sprintf("No source files found in %s", relativeTo(w, directory)), nil
+	}
+
+	var sections []string
+	total, described := 0, 0
+	filesWithSymbols := 0
+	for _, path := range paths {
+		if err := client.OpenFile(ctx, path); err != nil {
+			debugLogger.Printf("Warning: could not open %s: %v\n", path, err)
+			continue
+		}
+		content, err := client.GetFileContent(path)
+		if err != nil {
+			debugLogger.Printf("Warning: failed to read %s: %v\n", path, err)
+			continue
+		}
+		lines := text.Lines(content)
+		symbols, err := documentSymbols(ctx, client, path)
+		if err != nil {
+			debugLogger.Printf("Warning: %v\n", err)
+			continue
+		}
+
+		var exported []*apiSymbol
+		var collect func(symbols []protocol.DocumentSymbol, depth int)
+		collect = func(symbols []protocol.DocumentSymbol, depth int) {
+			for i := range symbols {
+				ds := &symbols[i]
+				if !isPublicSymbol(path, ds, lines, depth == 0) {
+					continue
+				}
+				exported = append(exported, &apiSymbol{symbol: ds, depth: depth})
+				if !topLevelOnly {
+					collect(ds.Children, depth+1)
+				}
+			}
+		}
+		var topLevel []protocol.DocumentSymbol
+		for _, sym := range symbols {
+			// SymbolInformation results have no declaration ranges to read
+			if ds, ok := sym.(*protocol.DocumentSymbol); ok {
+				topLevel = append(topLevel, *ds)
+			}
+		}
+		collect(topLevel, 0)
+		if len(exported) == 0 {
+			continue
+		}
+
+		for _, sym := range exported {
+			if described < maxPublicAPISymbols {
+				described++
+				sym.signature, sym.summary = describeSymbol(ctx, client, path, sym.symbol)
+			}
+			if sym.signature == "" {
+				sym.signature = declarationLine(lines, sym.symbol)
+			}
+		}
+		total += len(exported)
+		filesWithSymbols++
+		sections = append(sections, formatAPIFile(relativeTo(w, path), exported))
+	}
+
+	if total == 0 {
+		return fmt.Sprintf("No exported symbols found in %s (%s)", relativeTo(w, directory), pluralize(len(paths), "file")), nil
+	}
+
+	var result strings.Builder
+	fmt.Fprintf(&result, "Public API of %s: %s in %s\n", relativeTo(w, directory), pluralize(total, "exported symbol"), pluralize(filesWithSymbols, "file"))
+	if total > described {
+		fmt.Fprintf(&result, "Signatures of the last %d symbols are their declaration lines, hover was skipped\n", total-described)
+	}
+	result.WriteString("\n")
+	result.WriteString(strings.Join(sections, "\n\n"))
+	return result.String(), nil
+}
+
+// formatAPIFile lists the exported symbols of one file, members indented under
+// their parents
+func formatAPIFile(relPath string, symbols []*apiSymbol) string {
+	lines := []string{relPath}
+	for _, sym := range symbols {
+		indent := strings.Repeat("  ", sym.depth+1)
+		kind := utilities.GetSymbolKindString(sym.symbol.Kind)
+		lines = append(lines, fmt.Sprintf("%s%s (L%d)", indent, utilities.FormatSymbolWithKind(kind, sym.symbol.Name), sym.symbol.SelectionRange.Start.Line+1))
+		if sym.signature != "" {
+			lines = append(lines, indent+"    "+sym.signature)
+		}
+		if sym.summary != "" {
+			lines = append(lines, indent+"    // "+sym.summary)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// describeSymbol returns the one line signature and the first sentence of the
+// documentation of a symbol from its hover, empty if the server has none
+func describeSymbol(ctx context.Context, client *lsp.Client, filePath string, ds *protocol.DocumentSymbol) (string, string) {
+	pos := ds.SelectionRange.Start
+	contents, err := hoverContents(ctx, client, filePath, int(pos.Line)+1, int(pos.Character)+1)
+	if err != nil || contents.Value == "" {
+		return "", ""
+	}
+	isMarkdown := contents.Kind == protocol.Markdown
+
+	signature, _ := renderHover(contents, HoverOptions{SignatureOnly: true, PlainText: true})
+	summary := docSummary(contents.Value, isMarkdown)
+	return firstSignatureLine(signature), summary
+}
+
+// firstSignatureLine shortens a multi-line signature, such as a struct with its
+// fields, to its first line
+func firstSignatureLine(signature string) string {
+	first, rest, multiline := strings.Cut(strings.TrimSpace(signature), "\n")
+	first = strings.TrimSpace(first)
+	if multiline && strings.TrimSpace(rest) != "" {
+		first += " ..."
+	}
+	return first
+}
+
+// docSummary returns the first sentence of the prose following the signature of
+// hover contents
+func docSummary(text string, isMarkdown bool) string {
+	signature := hoverSignature(text, isMarkdown)
+	if i := strings.Index(text, signature); signature != "" && i >= 0 {
+		text = text[i+len(signature):]
+	}
+	if isMarkdown {
+		text = markdownToPlainText(text)
+	}
+
+	var paragraph []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if len(paragraph) > 0 {
+				break
+			}
+			continue
+		}
+		paragraph = append(paragraph, line)
+	}
+	summary := strings.Join(paragraph, " ")
+	if loc := sentenceEndPattern.FindStringIndex(summary); loc != nil {
+		summary = summary[:loc[0]+1]
+	}
+	if runes := []rune(summary); len(runes) > maxDocSummaryLength {
+		summary = string(runes[:maxDocSummaryLength-3]) + "..."
+	}
+	return summary
+}
+
+// isPublicSymbol reports whether a symbol is part of a file's public API. Go
+// symbols are exported by capitalization and Python symbols unless they start
+// with an underscore. In languages with visibilityKeywords the declaration must
+// use one, except members of TypeScript and JavaScript classes, which are public
+// unless marked private. Symbols of other languages are all listed.
+func isPublicSymbol(filePath string, ds *protocol.DocumentSymbol, lines []string, topLevel bool) bool {
+	name := normalizeReceiver(ds.Name)
+	ext := strings.ToLower(filepath.Ext(filePath))
+	switch ext {
+	case ".go":
+		// Methods are named after their receiver, which must be exported too
+		for _, part := range strings.Split(name, ".") {
+			if !isExported(part) {
+				return false
+			}
+		}
+		return true
+	case ".py", ".pyi":
+		return !strings.HasPrefix(name, "_") || strings.HasPrefix(name, "__") && strings.HasSuffix(name, "__")
+	}
+
+	keywords, ok := visibilityKeywords[ext]
+	if !ok {
+		return !strings.HasPrefix(name, "_")
+	}
+	words := strings.FieldsFunc(declarationText(lines, ds), func(r rune) bool {
+		return !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+	if !topLevel && keywords[0] == "export" {
+		return !strings.HasPrefix(name, "#") && !containsString(words, "private") && !containsString(words, "protected")
+	}
+	for _, keyword := range keywords {
+		if containsString(words, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// declarationText is the source from the start of a symbol to its name, which
+// holds its modifiers
+func declarationText(lines []string, ds *protocol.DocumentSymbol) string {
+	start, end := int(ds.Range.Start.Line), int(ds.SelectionRange.Start.Line)
+	if end >= len(lines) || start > end {
+		return ""
+	}
+	text := strings.Join(lines[start:end+1], "\n")
+	// Cut the last line at the name
+	if cut := len(text) - len(lines[end]) + int(ds.SelectionRange.Start.Character); cut >= 0 && cut <= len(text) {
+		text = text[:cut]
+	}
+	return text
+}
+
+// declarationLine is the trimmed source line declaring a symbol
+func declarationLine(lines []string, ds *protocol.DocumentSymbol) string {
+	line := int(ds.SelectionRange.Start.Line)
+	if line >= len(lines) {
+		return ds.Detail
+	}
+	return strings.TrimSpace(lines[line])
+}
+
+// isTestFile reports whether a file holds tests by the naming conventions of
+// common languages
+func isTestFile(path string) bool {
+	base := filepath.Base(path)
+	name := strings.TrimSuffix(base, filepath.Ext(base))
+	return strings.HasSuffix(name, "_test") ||
+		strings.HasPrefix(name, "test_") ||
+		strings.HasSuffix(name, ".test") ||
+		strings.HasSuffix(name, ".spec") ||
+		strings.HasSuffix(name, "Test") && filepath.Ext(base) == ".java"
+}
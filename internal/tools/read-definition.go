@@ -3,12 +3,14 @@ package tools
 import (
 	"context"
 	"fmt"
-	"os"
 	"sort" // Needed for sorting definitions if multiple found
 	"strings"
+	"unicode/utf8"
 
+	"github.com/isaacphi/mcp-language-server/internal/coverage"
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/text"
 	"github.com/isaacphi/mcp-language-server/internal/utilities"
 	// "github.com/davecgh/go-spew/spew" // Useful for debugging complex structs
 )
@@ -19,46 +21,102 @@ type DefinitionInfo struct {
 	SymbolKind     protocol.SymbolKind
 	HasKind        bool
 	FilePath       string
+	URI            protocol.DocumentUri
 	Range          protocol.Range // The precise range of the definition symbol
 	DefinitionText string
+	// AliasedBy names the alias this definition was reached through, if any
+	AliasedBy string
+	// External definitions are in a dependency rather than the workspace, see
+	// isDependency. For documents that are not files on disk, such as classes in
+	// a jar, FilePath names the document, see lsp.ExternalName.
+	External bool
 	// ContainerName string // Can be added if needed by traversing DocumentSymbol parents
 }
 
+// DefinitionOptions controls which definitions read_definition reports and how it
+// annotates them
+type DefinitionOptions struct {
+	// Profile, when set, marks each line with whether it ran in the tests
+	Profile *coverage.Profile
+	// ScopePath restricts definitions to a directory or package
+	ScopePath string
+	// MatchMode compares the symbol name with workspace symbols, see nameMatches
+	MatchMode string
+	// FollowAliases is how many levels of aliases and re-exports are followed to
+	// the definitions they lead to
+	FollowAliases int
+	// Highlight marks the occurrences of the symbol in each definition, see
+	// highlightBlock
+	Highlight string
+}
+
+func (o DefinitionOptions) validate() error {
+	if err := validateMatchMode(o.MatchMode); err != nil {
+		return err
+	}
+	return validateHighlight(o.Highlight)
+}
+
 // ReadDefinition intelligently finds and extracts the definition text for a symbol.
-// It prioritizes using documentSymbol for precise range finding.
-func ReadDefinition(ctx context.Context, client *lsp.Client, symbolName string, showLineNumbers bool) (string, error) {
+// It prioritizes using documentSymbol for precise range finding. With blame set,
+// each line is prefixed with the commit that last changed it. opts restricts the
+// definitions searched, follows aliases to what they name and adds coverage and
+// highlighting. The minimal verbosity leaves the code out and full adds the
+// comments above it.
+func ReadDefinition(ctx context.Context, client *lsp.Client, symbolName string, showLineNumbers bool, blame bool, opts DefinitionOptions) (string, error) {
 	debugLogger.Printf("--- GetDefinition called for symbol: %s ---\n", symbolName)
 
+	if err := opts.validate(); err != nil {
+		return "", err
+	}
+	if _, err := symbolRegex(symbolName); err != nil {
+		return "", err
+	}
+
+	scope, err := newPathScope(opts.ScopePath)
+	if err != nil {
+		return "", err
+	}
+
 	// --- Stage 1: Find *potential* symbol locations ---
 	// We use workspace/symbol first to get *any* location (definition or usage) to start the process.
-	wsSymbolResult, err := client.Symbol(ctx, protocol.WorkspaceSymbolParams{Query: symbolName})
+	wsSymbols, err := workspaceSymbols(ctx, client, serverSymbolQuery(symbolName))
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch workspace symbols for '%s': %w", symbolName, err)
 	}
-	wsSymbols, err := wsSymbolResult.Results()
-	if err != nil {
-		return "", fmt.Errorf("failed to parse workspace symbol results for '%s': %w", symbolName, err)
-	}
 
 	var initialLocations []protocol.Location
-	processedURIs := make(map[protocol.DocumentUri]bool) // Avoid hitting definition/documentSymbol multiple times for the same file if symbol has multiple entries there
+	var initialNames []string // Name of the workspace symbol at each initial location
+	outOfScope := 0
+	// Avoid hitting definition/documentSymbol multiple times for the same file if symbol has multiple entries there
+	type fileSymbol struct {
+		uri  protocol.DocumentUri
+		name string
+	}
+	processedURIs := make(map[fileSymbol]bool)
 
 	debugLogger.Printf("Found %d potential workspace symbols for '%s'\n", len(wsSymbols), symbolName)
 	for _, symbol := range wsSymbols {
-		// Strict name match is crucial here
-		if symbol.GetName() != symbolName {
+		// Strict name match is crucial here, unless a looser match mode was asked for
+		if !nameMatches(symbol.GetName(), symbolName, opts.MatchMode) {
 			continue
 		}
 		loc := symbol.GetLocation()
+		key := fileSymbol{uri: loc.URI, name: symbol.GetName()}
 		// Skip invalid locations or already processed files
-		if loc.URI == "" || processedURIs[loc.URI] {
+		if loc.URI == "" || processedURIs[key] {
+			continue
+		}
+		processedURIs[key] = true
+		if !scope.contains(loc.URI) {
+			outOfScope++
 			continue
 		}
 
 		// We only need one good starting point per file.
 		// Using the first match is usually sufficient.
 		initialLocations = append(initialLocations, loc)
-		processedURIs[loc.URI] = true
+		initialNames = append(initialNames, symbol.GetName())
 		debugLogger.Printf("  -> Found potential initial location in %s at L%d\n", loc.URI, loc.Range.Start.Line+1)
 		// Optimization: If we only need *one* definition, we could potentially break here.
 		// But let's find all distinct definitions for completeness.
@@ -66,14 +124,18 @@ func ReadDefinition(ctx context.Context, client *lsp.Client, symbolName string,
 
 	if len(initialLocations) == 0 {
 		debugLogger.Printf("No initial locations found via workspace/symbol matching name '%s' exactly.\n", symbolName)
-		return fmt.Sprintf("Symbol '%s' not found in workspace.", symbolName), nil
+		if outOfScope > 0 {
+			return fmt.Sprintf("Symbol '%s' not found in %s, there are %s outside it.", symbolName, scope, pluralize(outOfScope, "declaration")), nil
+		}
+		return fmt.Sprintf("Symbol '%s' not found in workspace.", symbolName) + caseMismatchHint(wsSymbols, symbolName, opts.MatchMode), nil
 	}
 
 	// --- Stage 2 & 3: Refine Location & Find Precise Scope ---
 	var foundDefinitions []DefinitionInfo
 	processedDefinitionRanges := make(map[string]bool) // Key: "URI:StartLine:StartChar"
 
-	for _, startLoc := range initialLocations {
+	for i, startLoc := range initialLocations {
+		name := initialNames[i]
 		debugLogger.Printf("\n--- Processing initial location: %s:%d ---\n", startLoc.URI, startLoc.Range.Start.Line+1)
 
 		// --- Stage 2: Use textDocument/definition for canonical location ---
@@ -92,88 +154,14 @@ func ReadDefinition(ctx context.Context, client *lsp.Client, symbolName string,
 		// --- Stage 3: Process each definition location found ---
 		var definitionLocations []protocol.Location
 
-		// --- Unpack the result ---
-		// Helper function to extract locations from the potentially nested value
-		extractLocations := func(value interface{}) ([]protocol.Location, bool) {
-			var extracted []protocol.Location
-			switch v := value.(type) {
-			case nil:
-				debugLogger.Printf("  Inner definition value is nil.\n")
-				return nil, true // Successfully processed null, result is empty list
-			case protocol.Location:
-				extracted = []protocol.Location{v}
-				debugLogger.Printf("  Inner definition resolved to Single Location: %s L%d:%d\n", v.URI, v.Range.Start.Line+1, v.Range.Start.Character+1)
-				return extracted, true
-			case []protocol.Location:
-				if len(v) == 0 {
-					debugLogger.Printf("  Inner definition resolved to an EMPTY slice of Locations.\n")
-				} else {
-					debugLogger.Printf("  Inner definition resolved to Multiple Locations (%d)\n", len(v))
-					// Optionally log the first few locations
-					for i := 0; i < len(v) && i < 3; i++ {
-						debugLogger.Printf("    Loc %d: %s L%d:%d\n", i, v[i].URI, v[i].Range.Start.Line+1, v[i].Range.Start.Character+1)
-					}
-				}
-				extracted = v
-				return extracted, true
-			case []protocol.LocationLink:
-				if len(v) == 0 {
-					debugLogger.Printf("  Inner definition resolved to an EMPTY slice of LocationLinks.\n")
-					extracted = []protocol.Location{} // Initialize empty slice
-				} else {
-					debugLogger.Printf("  Inner definition resolved to LocationLinks (%d), extracting targets...\n", len(v))
-					extracted = make([]protocol.Location, 0, len(v)) // Initialize slice
-					for linkIdx, link := range v {
-						targetRange := link.TargetSelectionRange
-						zeroRange := protocol.Range{}
-						if targetRange == zeroRange || (targetRange.Start.Line == 0 && targetRange.Start.Character == 0 && targetRange.End.Line == 0 && targetRange.End.Character == 0) {
-							debugLogger.Printf("    Link %d: TargetSelectionRange is zero/empty, falling back to TargetRange.\n", linkIdx)
-							targetRange = link.TargetRange
-						}
-
-						if link.TargetURI == "" {
-							debugLogger.Printf("    Link %d: Skipping because TargetURI is empty.\n", linkIdx)
-							continue
-						}
-
-						if targetRange.Start.Line > targetRange.End.Line || (targetRange.Start.Line == targetRange.End.Line && targetRange.Start.Character > targetRange.End.Character) {
-							debugLogger.Printf("    Link %d: Skipping Link Target '%s' due to invalid range: L%d:%d - L%d:%d\n",
-								linkIdx, link.TargetURI, targetRange.Start.Line+1, targetRange.Start.Character+1, targetRange.End.Line+1, targetRange.End.Character+1)
-							continue
-						}
-
-						extractedLoc := protocol.Location{
-							URI:   link.TargetURI,
-							Range: targetRange,
-						}
-						extracted = append(extracted, extractedLoc)
-						debugLogger.Printf("    Link %d: Extracted Target: %s L%d:%d - L%d:%d\n",
-							linkIdx,
-							extractedLoc.URI,
-							extractedLoc.Range.Start.Line+1, extractedLoc.Range.Start.Character+1,
-							extractedLoc.Range.End.Line+1, extractedLoc.Range.End.Character+1)
-					}
-					if len(extracted) == 0 {
-						debugLogger.Printf("  Finished processing LocationLinks, but none resulted in a valid Location.\n")
-					}
-				}
-				return extracted, true // Return the (potentially empty) extracted list
-
-			default:
-				// This case means the *inner* value was unexpected
-				debugLogger.Printf("Error: Inner definition value contained an unexpected type (%T).\n", value)
-				return nil, false // Indicate failure to extract
-			}
-		}
-
-		// --- Main Type Switch on defResult.Value ---
+		// --- Unpack the result, which may be nested in another "Or" type ---
 		var ok bool
 		// ** Adjust the type name 'protocol.Or_Definition' if it's different in your library! **
 		switch v := defResult.Value.(type) {
 		case protocol.Or_Definition: // Check for the nested "Or" type first
 			debugLogger.Printf("  Definition result Value is type %T, extracting inner value...\n", v)
 			// Recursively (or directly) check the inner value
-			definitionLocations, ok = extractLocations(v.Value)
+			definitionLocations, ok = extractDefinitionLocations(v.Value)
 			if !ok {
 				// The inner extraction failed
 				debugLogger.Printf("Error: Failed to extract locations from nested %T. Skipping this path.\n", v)
@@ -182,9 +170,9 @@ func ReadDefinition(ctx context.Context, client *lsp.Client, symbolName string,
 		default:
 			// Try extracting directly if it wasn't the nested type
 			debugLogger.Printf("  Definition result Value is type %T, attempting direct extraction...\n", v)
-			definitionLocations, ok = extractLocations(v) // v here is defResult.Value
+			definitionLocations, ok = extractDefinitionLocations(v) // v here is defResult.Value
 			if !ok {
-				// Direct extraction failed (e.g., default case in extractLocations hit)
+				// Direct extraction failed (e.g., default case in extractDefinitionLocations hit)
 				debugLogger.Printf("Error: Direct extraction failed for type %T. Skipping this path.\n", v)
 				continue
 			}
@@ -216,81 +204,11 @@ func ReadDefinition(ctx context.Context, client *lsp.Client, symbolName string,
 			// Mark immediately *before* trying file IO etc.
 			processedDefinitionRanges[defLocKey] = true
 			debugLogger.Printf("  -> Processing definition location: %s L%d:%d - L%d:%d\n", defLoc.URI, defLoc.Range.Start.Line+1, defLoc.Range.Start.Character+1, defLoc.Range.End.Line+1, defLoc.Range.End.Character+1)
-			filePath := strings.TrimPrefix(string(defLoc.URI), "file://")
-
-			// --- Stage 3a: Get Document Symbols for the definition's file ---
-			var preciseRange protocol.Range = defLoc.Range // Default to definition result range
-			var defSymbolKind protocol.SymbolKind = 0
-			var hasKind bool = false
-
-			docSymParams := protocol.DocumentSymbolParams{TextDocument: protocol.TextDocumentIdentifier{URI: defLoc.URI}}
-			docSymResult, docSymErr := client.DocumentSymbol(ctx, docSymParams)
-
-			if docSymErr == nil {
-				docSymbols, _ := docSymResult.Results()
-				if len(docSymbols) > 0 {
-					if _, ok := docSymbols[0].(*protocol.DocumentSymbol); ok {
-						debugLogger.Printf("  -> Searching document symbols in %s for position L%d:%d\n", defLoc.URI, defLoc.Range.Start.Line+1, defLoc.Range.Start.Character+1)
-						containingSymbol, foundSymbol := findSymbolContainingPosition(docSymbols, defLoc.Range.Start, 0)
-
-						if foundSymbol {
-							if containingSymbol.Name == symbolName {
-								debugLogger.Printf("    --> Found matching DocumentSymbol: '%s' (%s), Range: L%d:%d - L%d:%d\n",
-									containingSymbol.Name, utilities.GetSymbolKindString(containingSymbol.Kind),
-									containingSymbol.Range.Start.Line+1, containingSymbol.Range.Start.Character+1,
-									containingSymbol.Range.End.Line+1, containingSymbol.Range.End.Character+1)
-								preciseRange = containingSymbol.Range
-								defSymbolKind = containingSymbol.Kind
-								hasKind = true
-							} else {
-								debugLogger.Printf("    --> Found containing DocumentSymbol '%s' but name mismatch (expected '%s'). Using its range: L%d:%d - L%d:%d\n",
-									containingSymbol.Name, symbolName,
-									containingSymbol.Range.Start.Line+1, containingSymbol.Range.Start.Character+1,
-									containingSymbol.Range.End.Line+1, containingSymbol.Range.End.Character+1)
-								preciseRange = containingSymbol.Range
-								defSymbolKind = containingSymbol.Kind
-								hasKind = true
-							}
-						} else {
-							debugLogger.Printf("    --> No specific DocumentSymbol found containing L%d:%d. Using range from textDocument/definition.\n", defLoc.Range.Start.Line+1, defLoc.Range.Start.Character+1)
-						}
-					} else {
-						debugLogger.Printf("  -> Received SymbolInformation instead of DocumentSymbol for %s. Using range from textDocument/definition.\n", defLoc.URI)
-					}
-				} else {
-					debugLogger.Printf("  -> No document symbols returned for %s. Using range from textDocument/definition.\n", defLoc.URI)
-				}
-			} else {
-				debugLogger.Printf("Warning: Failed to get document symbols for %s: %v. Using range from textDocument/definition.\n", defLoc.URI, docSymErr)
-			}
-
-			// --- Stage 4: Fetch Definition Text using the determined range ---
-			debugLogger.Printf("    Attempting to read file: %s\n", filePath)
-			fileContent, readErr := os.ReadFile(filePath)
-			if readErr != nil {
-				debugLogger.Printf("Error: Failed to read file content for %s: %v. Skipping this definition location.\n", filePath, readErr)
-				continue // Skip this defLoc
-			}
-			debugLogger.Printf("    Successfully read %d bytes from %s\n", len(fileContent), filePath)
-
-			debugLogger.Printf("    Attempting to extract text for range: L%d:%d - L%d:%d\n", preciseRange.Start.Line+1, preciseRange.Start.Character+1, preciseRange.End.Line+1, preciseRange.End.Character+1)
-			definitionText, textErr := getTextForRange(ctx, defLoc.URI, fileContent, preciseRange)
-			if textErr != nil {
-				debugLogger.Printf("Error: Failed to extract text for range L%d-L%d in %s: %v. Skipping this definition location.\n", preciseRange.Start.Line+1, preciseRange.End.Line+1, filePath, textErr)
+			defInfo, ok := resolveDefinition(ctx, client, defLoc, name)
+			if !ok {
 				continue // Skip this defLoc
 			}
-			debugLogger.Printf("    Successfully extracted text (length %d).\n", len(definitionText))
-
-			// --- Append to Results ---
-			debugLogger.Printf("    --> SUCCESS: Appending definition to results.\n")
-			foundDefinitions = append(foundDefinitions, DefinitionInfo{
-				SymbolName:     symbolName, // Use the requested name
-				SymbolKind:     defSymbolKind,
-				HasKind:        hasKind,
-				FilePath:       filePath,
-				Range:          preciseRange,
-				DefinitionText: definitionText,
-			})
+			foundDefinitions = append(foundDefinitions, defInfo)
 			processedAnyInThisBatch = true // Mark success for this batch
 
 		} // End loop through definitionLocations
@@ -320,6 +238,30 @@ func ReadDefinition(ctx context.Context, client *lsp.Client, symbolName string,
 		return foundDefinitions[i].Range.Start.Line < foundDefinitions[j].Range.Start.Line
 	})
 
+	// Definitions in generated files are only counted, unless there are no others
+	var handWritten []DefinitionInfo
+	generatedDefs := make(map[string]int)
+	var generatedFiles []string
+	for _, defInfo := range foundDefinitions {
+		if !isGeneratedFile(defInfo.FilePath) {
+			handWritten = append(handWritten, defInfo)
+			continue
+		}
+		if generatedDefs[defInfo.FilePath] == 0 {
+			generatedFiles = append(generatedFiles, defInfo.FilePath)
+		}
+		generatedDefs[defInfo.FilePath]++
+	}
+	if len(handWritten) == 0 {
+		generatedFiles = nil
+	} else {
+		foundDefinitions = handWritten
+	}
+	if opts.FollowAliases > 0 {
+		foundDefinitions = followAliasChains(ctx, client, foundDefinitions, opts.FollowAliases)
+	}
+
+	verbosity := VerbosityFromContext(ctx)
 	var output strings.Builder
 	for i, defInfo := range foundDefinitions {
 		if i > 0 {
@@ -328,26 +270,248 @@ func ReadDefinition(ctx context.Context, client *lsp.Client, symbolName string,
 
 		// Header
 		output.WriteString(fmt.Sprintf("Symbol: %s\n", defInfo.SymbolName))
+		if defInfo.AliasedBy != "" {
+			output.WriteString(fmt.Sprintf("Aliased by: %s\n", defInfo.AliasedBy))
+		}
 		if defInfo.HasKind {
 			kindStr := utilities.GetSymbolKindString(defInfo.SymbolKind)
 			if kindStr != "" && kindStr != "Unknown" {
 				output.WriteString(fmt.Sprintf("Kind: %s\n", kindStr))
 			}
 		}
-		output.WriteString(fmt.Sprintf("File: %s\n", defInfo.FilePath))
+		if defInfo.External {
+			output.WriteString(fmt.Sprintf("File: %s (external dependency, read-only)\n", defInfo.FilePath))
+		} else {
+			output.WriteString(fmt.Sprintf("File: %s\n", defInfo.FilePath))
+		}
 		output.WriteString(fmt.Sprintf("Location: Lines %d-%d\n",
 			defInfo.Range.Start.Line+1,
 			defInfo.Range.End.Line+1))
+		if symbolIndex != nil {
+			if count, ok := symbolIndex.References(defInfo.SymbolName); ok {
+				output.WriteString(fmt.Sprintf("References: %d when last counted\n", count))
+			}
+		}
+		if verbosity == VerbosityMinimal {
+			continue
+		}
 		output.WriteString("\n") // Separator before code
+		if defInfo.External && defInfo.DefinitionText == "" {
+			output.WriteString("Source not available: the language server could not provide the dependency's source.\n")
+			continue
+		}
 
 		// Code
-		codeBlock := defInfo.DefinitionText
+		definitionText := defInfo.DefinitionText
+		startLine, endLine := int(defInfo.Range.Start.Line)+1, int(defInfo.Range.End.Line)+1
+		var occurrences map[int][]ReferencePosition
+		firstColumn := 0 // In runes, where the definition starts on its first line
+		if opts.Highlight != "" || verbosity == VerbosityFull {
+			if content, err := client.ReadDocument(ctx, defInfo.URI); err == nil {
+				if opts.Highlight != "" {
+					occurrences = symbolOccurrences(ctx, client, defInfo.URI, content, defInfo.Range, defInfo.SymbolName)
+				}
+				if lines := text.Lines(content); int(defInfo.Range.Start.Line) < len(lines) {
+					line := lines[defInfo.Range.Start.Line]
+					before := line[:text.ByteOffset(line, defInfo.Range.Start.Character)]
+					docStart := startLine - 1
+					if verbosity == VerbosityFull {
+						docStart = docCommentStart(lines, docStart)
+					}
+					if docStart < startLine-1 {
+						// With its documentation the definition starts on a whole line
+						definitionText = strings.Join(lines[docStart:startLine-1], "\n") + "\n" + before + definitionText
+						startLine = docStart + 1
+					} else {
+						firstColumn = utf8.RuneCountInString(before)
+					}
+				}
+			}
+		}
+		codeBlock := definitionText
 		if showLineNumbers {
-			codeBlock = addLineNumbers(codeBlock, int(defInfo.Range.Start.Line)+1)
+			var marked []int
+			for line := range occurrences {
+				marked = append(marked, line-startLine)
+			}
+			codeBlock = addLineNumbers(codeBlock, startLine, marked...)
+		}
+		if opts.Profile != nil {
+			output.WriteString(coverageSummaryLine(opts.Profile, defInfo.FilePath, startLine, endLine))
+			codeBlock = prefixLines(codeBlock, startLine, coverageMarker(opts.Profile, defInfo.FilePath))
 		}
-		output.WriteString(codeBlock)
+		if blame {
+			annotator := newBlameAnnotator(ctx, client, defInfo.FilePath, startLine, endLine)
+			output.WriteString(annotator.note())
+			codeBlock = prefixLines(codeBlock, startLine, annotator.prefix)
+		}
+		codeBlock = highlightBlock(codeBlock, strings.Split(definitionText, "\n"), startLine, firstColumn, occurrences, opts.Highlight)
+		output.WriteString(fenceCode(codeBlock, defInfo.FilePath))
+	}
+	if len(generatedFiles) > 0 {
+		output.WriteString("\n---\n\nGenerated: " + formatGeneratedCount("definition", generatedDefs, generatedFiles) + "\n")
 	}
 
 	debugLogger.Printf("--- GetDefinition finished for '%s', found %d definition(s) ---\n", symbolName, len(foundDefinitions))
 	return output.String(), nil
 }
+
+// resolveDefinition extends a definition location to the whole symbol declared
+// there, using document symbols, and reads its text
+func resolveDefinition(ctx context.Context, client *lsp.Client, defLoc protocol.Location, name string) (DefinitionInfo, bool) {
+	filePath := documentPath(defLoc.URI)
+	external := isDependency(defLoc.URI)
+
+	// --- Stage 3a: Get Document Symbols for the definition's file ---
+	var preciseRange protocol.Range = defLoc.Range // Default to definition result range
+	var defSymbolKind protocol.SymbolKind = 0
+	var hasKind bool = false
+
+	docSymParams := protocol.DocumentSymbolParams{TextDocument: protocol.TextDocumentIdentifier{URI: defLoc.URI}}
+	docSymResult, docSymErr := client.DocumentSymbol(ctx, docSymParams)
+
+	if docSymErr == nil {
+		docSymbols, _ := docSymResult.Results()
+		if len(docSymbols) > 0 {
+			if _, ok := docSymbols[0].(*protocol.DocumentSymbol); ok {
+				debugLogger.Printf("  -> Searching document symbols in %s for position L%d:%d\n", defLoc.URI, defLoc.Range.Start.Line+1, defLoc.Range.Start.Character+1)
+				if chain := findSymbolsContainingPosition(docSymbols, defLoc.Range.Start); len(chain) > 0 {
+					containingSymbol := chain[len(chain)-1]
+					if containingSymbol.Name == name {
+						debugLogger.Printf("    --> Found matching DocumentSymbol: '%s' (%s), Range: L%d:%d - L%d:%d\n",
+							containingSymbol.Name, utilities.GetSymbolKindString(containingSymbol.Kind),
+							containingSymbol.Range.Start.Line+1, containingSymbol.Range.Start.Character+1,
+							containingSymbol.Range.End.Line+1, containingSymbol.Range.End.Character+1)
+						preciseRange = containingSymbol.Range
+						defSymbolKind = containingSymbol.Kind
+						hasKind = true
+					} else {
+						debugLogger.Printf("    --> Found containing DocumentSymbol '%s' but name mismatch (expected '%s'). Using its range: L%d:%d - L%d:%d\n",
+							containingSymbol.Name, name,
+							containingSymbol.Range.Start.Line+1, containingSymbol.Range.Start.Character+1,
+							containingSymbol.Range.End.Line+1, containingSymbol.Range.End.Character+1)
+						preciseRange = containingSymbol.Range
+						defSymbolKind = containingSymbol.Kind
+						hasKind = true
+					}
+				} else {
+					debugLogger.Printf("    --> No specific DocumentSymbol found containing L%d:%d. Using range from textDocument/definition.\n", defLoc.Range.Start.Line+1, defLoc.Range.Start.Character+1)
+				}
+			} else {
+				debugLogger.Printf("  -> Received SymbolInformation instead of DocumentSymbol for %s. Using range from textDocument/definition.\n", defLoc.URI)
+			}
+		} else {
+			debugLogger.Printf("  -> No document symbols returned for %s. Using range from textDocument/definition.\n", defLoc.URI)
+		}
+	} else {
+		debugLogger.Printf("Warning: Failed to get document symbols for %s: %v. Using range from textDocument/definition.\n", defLoc.URI, docSymErr)
+	}
+
+	// --- Stage 4: Fetch Definition Text using the determined range ---
+	debugLogger.Printf("    Attempting to read file: %s\n", filePath)
+	fileContent, readErr := client.ReadDocument(ctx, defLoc.URI)
+	if readErr != nil && external {
+		// Still worth reporting, the agent learns where the symbol comes from
+		debugLogger.Printf("Warning: No source for external definition %s: %v\n", filePath, readErr)
+		return DefinitionInfo{SymbolName: name, SymbolKind: defSymbolKind, HasKind: hasKind, FilePath: filePath, Range: preciseRange, External: true}, true
+	}
+	if readErr != nil {
+		debugLogger.Printf("Error: Failed to read file content for %s: %v. Skipping this definition location.\n", filePath, readErr)
+		return DefinitionInfo{}, false
+	}
+	debugLogger.Printf("    Successfully read %d bytes from %s\n", len(fileContent), filePath)
+
+	debugLogger.Printf("    Attempting to extract text for range: L%d:%d - L%d:%d\n", preciseRange.Start.Line+1, preciseRange.Start.Character+1, preciseRange.End.Line+1, preciseRange.End.Character+1)
+	definitionText, textErr := text.Extract(fileContent, preciseRange)
+	if textErr != nil {
+		debugLogger.Printf("Error: Failed to extract text for range L%d-L%d in %s: %v. Skipping this definition location.\n", preciseRange.Start.Line+1, preciseRange.End.Line+1, filePath, textErr)
+		return DefinitionInfo{}, false
+	}
+	debugLogger.Printf("    Successfully extracted text (length %d).\n", len(definitionText))
+
+	debugLogger.Printf("    --> SUCCESS: Resolved definition.\n")
+	return DefinitionInfo{
+		SymbolName:     name, // The name of the matched workspace symbol
+		SymbolKind:     defSymbolKind,
+		HasKind:        hasKind,
+		FilePath:       filePath,
+		URI:            defLoc.URI,
+		Range:          preciseRange,
+		DefinitionText: definitionText,
+		External:       external,
+	}, true
+}
+
+// extractDefinitionLocations extracts the locations from the value of a definition
+// result, which may be a single location, a list of them or a list of links
+func extractDefinitionLocations(value interface{}) ([]protocol.Location, bool) {
+	var extracted []protocol.Location
+	switch v := value.(type) {
+	case nil:
+		debugLogger.Printf("  Inner definition value is nil.\n")
+		return nil, true // Successfully processed null, result is empty list
+	case protocol.Location:
+		extracted = []protocol.Location{v}
+		debugLogger.Printf("  Inner definition resolved to Single Location: %s L%d:%d\n", v.URI, v.Range.Start.Line+1, v.Range.Start.Character+1)
+		return extracted, true
+	case []protocol.Location:
+		if len(v) == 0 {
+			debugLogger.Printf("  Inner definition resolved to an EMPTY slice of Locations.\n")
+		} else {
+			debugLogger.Printf("  Inner definition resolved to Multiple Locations (%d)\n", len(v))
+			// Optionally log the first few locations
+			for i := 0; i < len(v) && i < 3; i++ {
+				debugLogger.Printf("    Loc %d: %s L%d:%d\n", i, v[i].URI, v[i].Range.Start.Line+1, v[i].Range.Start.Character+1)
+			}
+		}
+		extracted = v
+		return extracted, true
+	case []protocol.LocationLink:
+		if len(v) == 0 {
+			debugLogger.Printf("  Inner definition resolved to an EMPTY slice of LocationLinks.\n")
+			extracted = []protocol.Location{} // Initialize empty slice
+		} else {
+			debugLogger.Printf("  Inner definition resolved to LocationLinks (%d), extracting targets...\n", len(v))
+			extracted = make([]protocol.Location, 0, len(v)) // Initialize slice
+			for linkIdx, link := range v {
+				targetRange := link.TargetSelectionRange
+				zeroRange := protocol.Range{}
+				if targetRange == zeroRange || (targetRange.Start.Line == 0 && targetRange.Start.Character == 0 && targetRange.End.Line == 0 && targetRange.End.Character == 0) {
+					debugLogger.Printf("    Link %d: TargetSelectionRange is zero/empty, falling back to TargetRange.\n", linkIdx)
+					targetRange = link.TargetRange
+				}
+
+				if link.TargetURI == "" {
+					debugLogger.Printf("    Link %d: Skipping because TargetURI is empty.\n", linkIdx)
+					continue
+				}
+
+				if targetRange.Start.Line > targetRange.End.Line || (targetRange.Start.Line == targetRange.End.Line && targetRange.Start.Character > targetRange.End.Character) {
+					debugLogger.Printf("    Link %d: Skipping Link Target '%s' due to invalid range: L%d:%d - L%d:%d\n",
+						linkIdx, link.TargetURI, targetRange.Start.Line+1, targetRange.Start.Character+1, targetRange.End.Line+1, targetRange.End.Character+1)
+					continue
+				}
+
+				extractedLoc := protocol.Location{
+					URI:   link.TargetURI,
+					Range: targetRange,
+				}
+				extracted = append(extracted, extractedLoc)
+				debugLogger.Printf("    Link %d: Extracted Target: %s L%d:%d - L%d:%d\n",
+					linkIdx,
+					extractedLoc.URI,
+					extractedLoc.Range.Start.Line+1, extractedLoc.Range.Start.Character+1,
+					extractedLoc.Range.End.Line+1, extractedLoc.Range.End.Character+1)
+			}
+			if len(extracted) == 0 {
+				debugLogger.Printf("  Finished processing LocationLinks, but none resulted in a valid Location.\n")
+			}
+		}
+		return extracted, true // Return the (potentially empty) extracted list
+
+	default:
+		// This case means the *inner* value was unexpected
+		debugLogger.Printf("Error: Inner definition value contained an unexpected type (%T).\n", value)
+		return nil, false // Indicate failure to extract
+	}
+}
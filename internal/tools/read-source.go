@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/text"
+)
+
+// ReadSource returns the text of a file, or a range of its lines, as the language server sees it.
+// startLine and endLine are 1-indexed and inclusive; zero means the start or end of the file.
+// If symbolName is set, lines containing that identifier are marked.
+func ReadSource(ctx context.Context, client *lsp.Client, filePath string, startLine, endLine int, symbolName string, showLineNumbers bool) (string, error) {
+	err := client.OpenFile(ctx, filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+
+	content, err := client.GetFileContent(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+
+	lines := text.Lines(content)
+	// A trailing newline does not start another line
+	if len(lines) > 1 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if startLine <= 0 {
+		startLine = 1
+	}
+	if endLine <= 0 || endLine > len(lines) {
+		endLine = len(lines)
+	}
+	if startLine > endLine {
+		return "", fmt.Errorf("invalid line range %d-%d (file has %d lines)", startLine, endLine, len(lines))
+	}
+
+	selectedLines := lines[startLine-1 : endLine]
+
+	var highlights []int
+	var occurrences int
+	if symbolName != "" {
+		pattern, err := regexp.Compile(`\b` + regexp.QuoteMeta(symbolName) + `\b`)
+		if err != nil {
+			return "", fmt.Errorf("invalid symbol name: %v", err)
+		}
+		for i, line := range selectedLines {
+			if matches := pattern.FindAllStringIndex(line, -1); len(matches) > 0 {
+				highlights = append(highlights, i)
+				occurrences += len(matches)
+			}
+		}
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("File: %s\n", filePath))
+	result.WriteString(fmt.Sprintf("Lines: %d-%d of %d\n", startLine, endLine, len(lines)))
+	if symbolName != "" {
+		result.WriteString(fmt.Sprintf("Occurrences of '%s': %d on %d lines\n", symbolName, occurrences, len(highlights)))
+	}
+	result.WriteString("\n")
+
+	var code strings.Builder
+	text := strings.Join(selectedLines, "\n")
+	if showLineNumbers {
+		code.WriteString(addLineNumbers(text, startLine, highlights...))
+	} else {
+		highlighted := make(map[int]bool)
+		for _, line := range highlights {
+			highlighted[line] = true
+		}
+		for i, line := range selectedLines {
+			// Mirror the markers used by find_references without line numbers
+			marker := "  "
+			if symbolName == "" {
+				marker = ""
+			} else if highlighted[i] {
+				marker = "> "
+			}
+			code.WriteString(marker + line + "\n")
+		}
+	}
+	result.WriteString(fenceCode(code.String(), filePath))
+
+	return result.String(), nil
+}
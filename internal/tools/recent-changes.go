@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+)
+
+// fileChanges summarizes the journal entries for one file
+type fileChanges struct {
+	path   string
+	types  []protocol.FileChangeType // Distinct, in order of first occurrence
+	events int
+	last   time.Time
+}
+
+// RecentChanges lists the workspace files that changed after journal entry afterSeq
+// and no earlier than since, most recent first. limit caps the number of files listed.
+func RecentChanges(w *watcher.WorkspaceWatcher, afterSeq uint64, since time.Time, limit int) (string, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	entries, complete := w.Journal().Since(afterSeq, since)
+	if len(entries) == 0 {
+		return "No file changes recorded in the workspace.", nil
+	}
+
+	var files []*fileChanges
+	byPath := make(map[string]*fileChanges)
+	for _, entry := range entries {
+		changes, ok := byPath[entry.Path]
+		if !ok {
+			changes = &fileChanges{path: entry.Path}
+			byPath[entry.Path] = changes
+			files = append(files, changes)
+		}
+		if !containsChangeType(changes.types, entry.Type) {
+			changes.types = append(changes.types, entry.Type)
+		}
+		changes.events++
+		changes.last = entry.Time
+	}
+
+	// Most recently changed first
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].last.After(files[j].last)
+	})
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("%d file events in %d files since %s\n",
+		len(entries), len(files), entries[0].Time.Format(time.TimeOnly)))
+	if !complete {
+		result.WriteString("Older events were dropped from the journal, the list may be incomplete.\n")
+	}
+	result.WriteString("\n")
+
+	root := w.WorkspacePath()
+	for i, changes := range files {
+		if i == limit {
+			result.WriteString(fmt.Sprintf("... and %d more files\n", len(files)-limit))
+			break
+		}
+		path := changes.path
+		if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+		var types []string
+		for _, changeType := range changes.types {
+			types = append(types, watcher.ChangeTypeName(changeType))
+		}
+		result.WriteString(fmt.Sprintf("%s: %s (%d events, last at %s)\n",
+			path, strings.Join(types, ", "), changes.events, changes.last.Format(time.TimeOnly)))
+	}
+
+	var hints ResponseHints
+	if len(files) > limit {
+		hints = ResponseHints{
+			Omitted:     len(files) - limit,
+			OmittedKind: "files",
+			Suggestions: []string{fmt.Sprintf("limit=%d to list every file", len(files))},
+		}
+	}
+	return AddFooter(result.String(), hints), nil
+}
+
+func containsChangeType(types []protocol.FileChangeType, changeType protocol.FileChangeType) bool {
+	for _, t := range types {
+		if t == changeType {
+			return true
+		}
+	}
+	return false
+}
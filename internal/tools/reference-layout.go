@@ -0,0 +1,220 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// ReferenceOptions controls which references find_references reports and how it
+// orders and groups them
+type ReferenceOptions struct {
+	// SortBy orders files and groups by "path" (the default), "count" (most
+	// references first) or "proximity" (closest to the definition first)
+	SortBy string
+	// GroupBy puts files in one part each ("file", the default), or gathers them
+	// by "package" (directory) or by the symbol "kind" of the containing scopes
+	GroupBy string
+	// ScopePath restricts definitions and references to a directory or package
+	ScopePath string
+	// MatchMode compares the symbol name with workspace symbols, see nameMatches
+	MatchMode string
+	// Highlight marks references in the code with carets (the default), inline
+	// or not at all, see highlightBlock
+	Highlight string
+	// Emit, when set, is passed each file's part as soon as it is formatted, in
+	// the order SortBy gives, and the part is not returned. Files are only
+	// emitted when they are not grouped, so a report on a heavily used symbol
+	// never has to be held in memory as a whole.
+	Emit func(part string)
+}
+
+func (o ReferenceOptions) validate() error {
+	switch o.SortBy {
+	case "", "path", "count", "proximity":
+	default:
+		return fmt.Errorf("invalid sortBy %q, expected path, count or proximity", o.SortBy)
+	}
+	switch o.GroupBy {
+	case "", "file", "package", "kind":
+	default:
+		return fmt.Errorf("invalid groupBy %q, expected file, package or kind", o.GroupBy)
+	}
+	if err := validateHighlight(o.Highlight); err != nil {
+		return err
+	}
+	return validateMatchMode(o.MatchMode)
+}
+
+// referenceFile is the formatted references of one file
+type referenceFile struct {
+	path   string
+	header string
+	refs   int
+	// Directories between the file and the nearest definition, 0 in its file
+	distance int
+	scopes   []referenceScope
+}
+
+// referenceScope is the formatted references in one scope of a file
+type referenceScope struct {
+	kind  string
+	refs  int
+	lines []string
+}
+
+// referenceGroup is the files of one package or scope kind
+type referenceGroup struct {
+	name     string
+	refs     int
+	distance int
+	files    []referenceFile
+}
+
+// text formats the file with all its scopes
+func (f referenceFile) text() string {
+	lines := []string{f.header}
+	for _, scope := range f.scopes {
+		lines = append(lines, scope.lines...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// arrangeReferenceFiles sorts and groups the files of a reference report into
+// the parts that follow its summary
+func arrangeReferenceFiles(files []referenceFile, opts ReferenceOptions) []string {
+	sortReferenceFiles(files, opts.SortBy)
+
+	var groups []referenceGroup
+	switch opts.GroupBy {
+	case "package":
+		index := make(map[string]int)
+		for _, file := range files {
+			dir := filepath.Dir(file.path)
+			i, ok := index[dir]
+			if !ok {
+				i = len(groups)
+				index[dir] = i
+				groups = append(groups, referenceGroup{name: dir, distance: file.distance})
+			}
+			groups[i].refs += file.refs
+			groups[i].distance = min(groups[i].distance, file.distance)
+			groups[i].files = append(groups[i].files, file)
+		}
+	case "kind":
+		index := make(map[string]int)
+		for _, file := range files {
+			// Each group gets the file with only the scopes of its kind
+			byKind := make(map[string]*referenceFile)
+			var kinds []string
+			for _, scope := range file.scopes {
+				part, ok := byKind[scope.kind]
+				if !ok {
+					part = &referenceFile{path: file.path, distance: file.distance}
+					byKind[scope.kind] = part
+					kinds = append(kinds, scope.kind)
+				}
+				part.refs += scope.refs
+				part.scopes = append(part.scopes, scope)
+			}
+			for _, kind := range kinds {
+				part := byKind[kind]
+				part.header = fmt.Sprintf("File: %s (%d references)", part.path, part.refs)
+				i, ok := index[kind]
+				if !ok {
+					i = len(groups)
+					index[kind] = i
+					groups = append(groups, referenceGroup{name: kind, distance: part.distance})
+				}
+				groups[i].refs += part.refs
+				groups[i].distance = min(groups[i].distance, part.distance)
+				groups[i].files = append(groups[i].files, *part)
+			}
+		}
+	default:
+		parts := make([]string, len(files))
+		for i, file := range files {
+			parts[i] = file.text()
+		}
+		return parts
+	}
+
+	// Files keep the order they were sorted in, groups are sorted the same way
+	sort.SliceStable(groups, func(i, j int) bool {
+		switch opts.SortBy {
+		case "count":
+			if groups[i].refs != groups[j].refs {
+				return groups[i].refs > groups[j].refs
+			}
+		case "proximity":
+			if groups[i].distance != groups[j].distance {
+				return groups[i].distance < groups[j].distance
+			}
+		}
+		return groups[i].name < groups[j].name
+	})
+
+	parts := make([]string, len(groups))
+	for i, group := range groups {
+		label := "Package"
+		if opts.GroupBy == "kind" {
+			label = "Scope kind"
+		}
+		texts := []string{fmt.Sprintf("%s: %s (%d references in %s)", label, group.name, group.refs, pluralize(len(group.files), "file"))}
+		for _, file := range group.files {
+			texts = append(texts, file.text())
+		}
+		parts[i] = strings.Join(texts, "\n\n")
+	}
+	return parts
+}
+
+// sortReferenceFiles orders files by path, by reference count or by distance from
+// the definition, ties broken by path
+func sortReferenceFiles(files []referenceFile, sortBy string) {
+	sort.SliceStable(files, func(i, j int) bool {
+		switch sortBy {
+		case "count":
+			if files[i].refs != files[j].refs {
+				return files[i].refs > files[j].refs
+			}
+		case "proximity":
+			if files[i].distance != files[j].distance {
+				return files[i].distance < files[j].distance
+			}
+		}
+		return files[i].path < files[j].path
+	})
+}
+
+// definitionDistance counts the directory steps from the nearest definition to a
+// file: 0 for the definition's own file, 1 for its directory, and one more for
+// each directory up or down from there
+func definitionDistance(filePath string, definitions []protocol.Location) int {
+	best := -1
+	for _, def := range definitions {
+		defPath := strings.TrimPrefix(string(def.URI), "file://")
+		distance := 0
+		if defPath != filePath {
+			rel, err := filepath.Rel(filepath.Dir(defPath), filepath.Dir(filePath))
+			if err != nil {
+				continue
+			}
+			distance = 1
+			if rel != "." {
+				distance += len(strings.Split(rel, string(filepath.Separator)))
+			}
+		}
+		if best < 0 || distance < best {
+			best = distance
+		}
+	}
+	if best < 0 {
+		return math.MaxInt
+	}
+	return best
+}
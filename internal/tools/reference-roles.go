@@ -0,0 +1,193 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/text"
+)
+
+// Roles of a reference, how the code at the reference uses the symbol
+const (
+	roleRead  = "read"
+	roleWrite = "write"
+	roleCall  = "call"
+)
+
+// maxHighlightRequests caps the document highlight requests made per file. One
+// request usually covers every reference in the file.
+const maxHighlightRequests = 10
+
+// semanticToken is the type and modifiers of a decoded semantic token
+type semanticToken struct {
+	tokenType string
+	modifiers []string
+}
+
+// classifyReferences returns the role of each reference in a file by its start
+// position. Writes come from document highlights of kind Write or semantic tokens
+// with the "modification" modifier, and calls are references followed by "(" that
+// are not typed as something other than a function by semantic tokens. References
+// neither source covers are left out, so the map is empty when the server
+// supports neither.
+func classifyReferences(ctx context.Context, client *lsp.Client, uri protocol.DocumentUri, refs []protocol.Location, fileContent []byte) map[protocol.Position]string {
+	kinds := make(map[protocol.Position]protocol.DocumentHighlightKind)
+	requests := 0
+	for _, ref := range refs {
+		if _, ok := kinds[ref.Range.Start]; ok {
+			continue
+		}
+		if requests == maxHighlightRequests {
+			break
+		}
+		requests++
+		highlights, err := client.DocumentHighlight(ctx, protocol.DocumentHighlightParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+				Position:     ref.Range.Start,
+			},
+		})
+		if err != nil {
+			debugLogger.Printf("Warning: Failed to get document highlights for %s: %v\n", uri, err)
+			break
+		}
+		for _, highlight := range highlights {
+			kind := highlight.Kind
+			if kind == 0 {
+				kind = protocol.Text
+			}
+			kinds[highlight.Range.Start] = kind
+		}
+		if _, ok := kinds[ref.Range.Start]; !ok {
+			// Not highlighted, do not ask again for this reference
+			kinds[ref.Range.Start] = 0
+		}
+	}
+
+	tokens := semanticTokensOf(ctx, client, uri)
+
+	var lines []string
+	if fileContent != nil {
+		lines = text.Lines(fileContent)
+	}
+	roles := make(map[protocol.Position]string)
+	for _, ref := range refs {
+		kind := kinds[ref.Range.Start]
+		token, hasToken := tokens[ref.Range.Start]
+		if kind == 0 && !hasToken {
+			continue
+		}
+		switch {
+		case kind == protocol.Write || hasToken && containsString(token.modifiers, "modification"):
+			roles[ref.Range.Start] = roleWrite
+		case followedByCall(lines, ref.Range) && (!hasToken || isCallableToken(token.tokenType)):
+			roles[ref.Range.Start] = roleCall
+		default:
+			roles[ref.Range.Start] = roleRead
+		}
+	}
+	return roles
+}
+
+// semanticTokensOf decodes the semantic tokens of a whole document by start
+// position, nil if the server provides none
+func semanticTokensOf(ctx context.Context, client *lsp.Client, uri protocol.DocumentUri) map[protocol.Position]semanticToken {
+	legend := client.SemanticTokensLegend()
+	if legend == nil {
+		return nil
+	}
+	result, err := client.SemanticTokensFull(ctx, protocol.SemanticTokensParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	})
+	if err != nil {
+		debugLogger.Printf("Warning: Failed to get semantic tokens for %s: %v\n", uri, err)
+		return nil
+	}
+
+	// Tokens are runs of five integers: line delta, start delta (relative to the
+	// previous token on the same line), length, type index and modifier bits
+	tokens := make(map[protocol.Position]semanticToken)
+	var line, start uint32
+	for i := 0; i+4 < len(result.Data); i += 5 {
+		deltaLine, deltaStart := result.Data[i], result.Data[i+1]
+		if deltaLine > 0 {
+			line += deltaLine
+			start = deltaStart
+		} else {
+			start += deltaStart
+		}
+		var token semanticToken
+		if typeIndex := int(result.Data[i+3]); typeIndex < len(legend.TokenTypes) {
+			token.tokenType = legend.TokenTypes[typeIndex]
+		}
+		for bit, modifier := range legend.TokenModifiers {
+			if result.Data[i+4]&(1<<uint(bit)) != 0 {
+				token.modifiers = append(token.modifiers, modifier)
+			}
+		}
+		tokens[protocol.Position{Line: line, Character: start}] = token
+	}
+	return tokens
+}
+
+// isCallableToken reports whether a semantic token type names something that can
+// be called, as opposed to e.g. a type in a conversion
+func isCallableToken(tokenType string) bool {
+	switch tokenType {
+	case "function", "method", "macro", "":
+		return true
+	}
+	return false
+}
+
+// followedByCall reports whether the reference is followed by an opening
+// parenthesis on its line. Columns are treated as one per rune.
+func followedByCall(lines []string, rng protocol.Range) bool {
+	if int(rng.End.Line) >= len(lines) {
+		return false
+	}
+	runes := []rune(lines[rng.End.Line])
+	end := int(rng.End.Character)
+	if end > len(runes) {
+		return false
+	}
+	return strings.HasPrefix(strings.TrimLeft(string(runes[end:]), " \t"), "(")
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// roleCounts tallies roles, with unclassified references counted under ""
+type roleCounts map[string]int
+
+func (c roleCounts) add(role string) {
+	c[role]++
+}
+
+// classified reports whether any reference has a role
+func (c roleCounts) classified() bool {
+	return c[roleRead]+c[roleWrite]+c[roleCall] > 0
+}
+
+// String lists the non-zero counts, e.g. "3 reads, 1 write"
+func (c roleCounts) String() string {
+	var counts []string
+	for _, role := range []string{roleRead, roleWrite, roleCall} {
+		if c[role] > 0 {
+			counts = append(counts, pluralize(c[role], role))
+		}
+	}
+	if c[""] > 0 {
+		counts = append(counts, fmt.Sprintf("%d unclassified", c[""]))
+	}
+	return strings.Join(counts, ", ")
+}
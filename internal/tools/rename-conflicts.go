@@ -0,0 +1,142 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/text"
+)
+
+// maxConflictFiles caps the number of files a rename edits that are scanned for
+// conflicts
+const maxConflictFiles = 50
+
+// renameConflict is an existing identifier with a rename's new name, in a scope
+// that contains one of the renamed occurrences or is contained by one
+type renameConflict struct {
+	path  string
+	pos   protocol.Position
+	line  string
+	scope string
+}
+
+// findRenameConflicts scans the files a rename edits for identifiers that already
+// have the new name and share a scope with a renamed occurrence, where the rename
+// would shadow them or collide with them. Servers reject some of these collisions
+// but not all, e.g. a local variable shadowing the renamed package-level one. The
+// scan is textual, so names in comments and strings are reported too.
+func findRenameConflicts(ctx context.Context, client *lsp.Client, edit protocol.WorkspaceEdit, newName string) []renameConflict {
+	editRanges := make(map[string][]protocol.Range)
+	for uri, edits := range edit.Changes {
+		for _, e := range edits {
+			path := strings.TrimPrefix(string(uri), "file://")
+			editRanges[path] = append(editRanges[path], e.Range)
+		}
+	}
+	for _, change := range edit.DocumentChanges {
+		if change.TextDocumentEdit == nil {
+			continue
+		}
+		path := strings.TrimPrefix(string(change.TextDocumentEdit.TextDocument.URI), "file://")
+		for _, e := range change.TextDocumentEdit.Edits {
+			if textEdit, err := e.AsTextEdit(); err == nil {
+				editRanges[path] = append(editRanges[path], textEdit.Range)
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(editRanges))
+	for path := range editRanges {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	if len(paths) > maxConflictFiles {
+		paths = paths[:maxConflictFiles]
+	}
+
+	var conflicts []renameConflict
+	for _, path := range paths {
+		content, err := client.GetFileContent(path)
+		if err != nil {
+			continue
+		}
+		lines := text.Lines(content)
+
+		var existing []protocol.Position
+		for i, line := range lines {
+			for _, loc := range identifierPattern.FindAllStringIndex(line, -1) {
+				if line[loc[0]:loc[1]] != newName {
+					continue
+				}
+				pos := protocol.Position{Line: uint32(i), Character: uint32(loc[0])}
+				if !anyRangeContains(editRanges[path], pos) {
+					existing = append(existing, pos)
+				}
+			}
+		}
+		if len(existing) == 0 {
+			continue
+		}
+
+		// Without symbols every occurrence is treated as sharing the file's scope
+		var symbols []protocol.DocumentSymbolResult
+		if err := client.OpenFile(ctx, path); err == nil {
+			symbols, _ = getDocumentSymbols(ctx, client, protocol.DocumentUri("file://"+path))
+		}
+		for _, pos := range existing {
+			scope, scopeName := innermostScope(symbols, pos)
+			shared := false
+			for _, edited := range editRanges[path] {
+				editScope, _ := innermostScope(symbols, edited.Start)
+				if scope == nil || editScope == nil || containsPosition(*scope, edited.Start) || containsPosition(*editScope, pos) {
+					shared = true
+					break
+				}
+			}
+			if shared {
+				conflicts = append(conflicts, renameConflict{
+					path:  path,
+					pos:   pos,
+					line:  strings.TrimSpace(lines[pos.Line]),
+					scope: scopeName,
+				})
+			}
+		}
+	}
+	return conflicts
+}
+
+// innermostScope returns the range and name of the innermost symbol containing pos,
+// or nil and "file scope" outside any symbol
+func innermostScope(symbols []protocol.DocumentSymbolResult, pos protocol.Position) (*protocol.Range, string) {
+	chain := findSymbolsContainingPosition(symbols, pos)
+	if len(chain) == 0 {
+		return nil, "file scope"
+	}
+	innermost := chain[len(chain)-1]
+	return &innermost.Range, innermost.Name
+}
+
+func anyRangeContains(ranges []protocol.Range, pos protocol.Position) bool {
+	for _, r := range ranges {
+		if containsPosition(r, pos) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatRenameConflicts lists the conflicts of renaming to newName
+func formatRenameConflicts(conflicts []renameConflict, newName string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s already named %s in scopes the rename touches, which the rename may shadow or collide with:\n",
+		pluralize(len(conflicts), "identifier"), newName))
+	for _, c := range conflicts {
+		sb.WriteString(fmt.Sprintf("- %s:%d:%d (in %s): %s\n", c.path, c.pos.Line+1, c.pos.Character+1, c.scope, c.line))
+	}
+	return sb.String()
+}
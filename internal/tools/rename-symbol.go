@@ -10,8 +10,10 @@ import (
 )
 
 // RenameSymbol renames a symbol (variable, function, class, etc.) at the specified position
-// It uses the LSP rename functionality to handle all references across files
-func RenameSymbol(ctx context.Context, client *lsp.Client, filePath string, line, column int, newName string) (string, error) {
+// It uses the LSP rename functionality to handle all references across files.
+// Renames that would collide with or shadow existing identifiers named newName are
+// reported instead of applied, unless ignoreConflicts is set.
+func RenameSymbol(ctx context.Context, client *lsp.Client, filePath string, line, column int, newName string, ignoreConflicts bool) (string, error) {
 	// Open the file if not already open
 	err := client.OpenFile(ctx, filePath)
 	if err != nil {
@@ -63,12 +65,23 @@ func RenameSymbol(ctx context.Context, client *lsp.Client, filePath string, line
 		}
 	}
 
+	// Check for collisions before any file is written
+	conflicts := findRenameConflicts(ctx, client, workspaceEdit, newName)
+	if len(conflicts) > 0 && !ignoreConflicts {
+		return fmt.Sprintf("Rename to '%s' was not applied.\n%sRename to another name, or re-run with ignoreConflicts=true if these are not real conflicts.",
+			newName, formatRenameConflicts(conflicts, newName)), nil
+	}
+
 	// Apply the workspace edit to files
 	if err := utilities.ApplyWorkspaceEdit(workspaceEdit); err != nil {
 		return "", fmt.Errorf("failed to apply changes: %v", err)
 	}
 
 	// Generate a summary of changes made
-	return fmt.Sprintf("Successfully renamed symbol to '%s'.\nUpdated %d occurrences across %d files.",
-		newName, changeCount, fileCount), nil
+	summary := fmt.Sprintf("Successfully renamed symbol to '%s'.\nUpdated %d occurrences across %d files.",
+		newName, changeCount, fileCount)
+	if len(conflicts) > 0 {
+		summary += "\nWARNING: " + formatRenameConflicts(conflicts, newName)
+	}
+	return summary, nil
 }
@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// DefaultRunTimeout bounds how long run_runnable waits for a test or binary
+const DefaultRunTimeout = 5 * time.Minute
+
+// maxRunOutputLines is how many of the last lines of a run's output are returned
+const maxRunOutputLines = 200
+
+// requireRustAnalyzer fails for servers without rust-analyzer's extensions
+func requireRustAnalyzer(client *lsp.Client, tool string) error {
+	if client.IsServer("rust-analyzer") {
+		return nil
+	}
+	return fmt.Errorf("%s needs rust-analyzer, the language server is %s", tool, filepath.Base(client.Cmd.Path))
+}
+
+// ExpandMacro shows the recursive expansion of the Rust macro call at a position
+func ExpandMacro(ctx context.Context, client *lsp.Client, filePath string, line, column int) (string, error) {
+	if err := requireRustAnalyzer(client, "expand_macro"); err != nil {
+		return "", err
+	}
+	if err := client.OpenFile(ctx, filePath); err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+
+	expanded, err := client.ExpandMacro(ctx, protocol.TextDocumentPositionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentUri("file://" + filePath)},
+		Position:     protocol.Position{Line: uint32(line - 1), Character: uint32(column - 1)},
+	})
+	if err != nil {
+		return "", err
+	}
+	if expanded == nil {
+		return fmt.Sprintf("No macro call at %s:%d:%d.", filePath, line, column), nil
+	}
+	return fmt.Sprintf("Macro: %s!\n\n%s", expanded.Name, fenceCode(expanded.Expansion, filePath)), nil
+}
+
+// runnables returns the runnables of a file, or those at a line when it is set
+func runnables(ctx context.Context, client *lsp.Client, filePath string, line int) ([]lsp.Runnable, error) {
+	if err := client.OpenFile(ctx, filePath); err != nil {
+		return nil, fmt.Errorf("could not open file: %v", err)
+	}
+	params := lsp.RunnablesParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentUri("file://" + filePath)},
+	}
+	if line > 0 {
+		params.Position = &protocol.Position{Line: uint32(line - 1)}
+	}
+	return client.Runnables(ctx, params)
+}
+
+// ListRunnables lists the tests, benchmarks and binaries rust-analyzer can run in a
+// file, or at a line of it, with the command each one runs
+func ListRunnables(ctx context.Context, client *lsp.Client, filePath string, line int) (string, error) {
+	if err := requireRustAnalyzer(client, "runnables"); err != nil {
+		return "", err
+	}
+	found, err := runnables(ctx, client, filePath, line)
+	if err != nil {
+		return "", err
+	}
+	if len(found) == 0 {
+		return fmt.Sprintf("No runnables in %s.", filePath), nil
+	}
+
+	var result strings.Builder
+	for i, runnable := range found {
+		program, args := runnable.Command()
+		fmt.Fprintf(&result, "%d. %s\n   Command: %s\n", i+1, runnable.Label, strings.Join(append([]string{program}, args...), " "))
+		if runnable.Location != nil {
+			fmt.Fprintf(&result, "   Location: %s:%d\n", documentPath(runnable.Location.TargetURI), runnable.Location.TargetSelectionRange.Start.Line+1)
+		}
+	}
+	result.WriteString("\nRun one with run_runnable and its label.\n")
+	return result.String(), nil
+}
+
+// RunRunnable runs the runnable of a file with the given label, as listed by
+// ListRunnables, and reports its exit status and the end of its output
+func RunRunnable(ctx context.Context, client *lsp.Client, filePath, label string, timeout time.Duration) (string, error) {
+	if err := requireRustAnalyzer(client, "run_runnable"); err != nil {
+		return "", err
+	}
+	found, err := runnables(ctx, client, filePath, 0)
+	if err != nil {
+		return "", err
+	}
+	var runnable *lsp.Runnable
+	var labels []string
+	for i := range found {
+		if found[i].Label == label {
+			runnable = &found[i]
+			break
+		}
+		labels = append(labels, found[i].Label)
+	}
+	if runnable == nil {
+		return "", fmt.Errorf("no runnable labeled %q in %s, the runnables are: %s", label, filePath, strings.Join(labels, "; "))
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultRunTimeout
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	program, args := runnable.Command()
+	cmd := exec.CommandContext(runCtx, program, args...)
+	cmd.Dir = runnable.Args.Cwd
+	if cmd.Dir == "" {
+		cmd.Dir = runnable.Args.WorkspaceRoot
+	}
+	cmd.Env = os.Environ()
+	for key, value := range runnable.Args.Environment {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	start := time.Now()
+	runErr := cmd.Run()
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	var result strings.Builder
+	fmt.Fprintf(&result, "Ran: %s\n", strings.Join(append([]string{program}, args...), " "))
+	var exitErr *exec.ExitError
+	switch {
+	case runCtx.Err() == context.DeadlineExceeded:
+		fmt.Fprintf(&result, "Result: timed out after %s\n", timeout)
+	case errors.As(runErr, &exitErr):
+		fmt.Fprintf(&result, "Result: failed with exit code %d after %s\n", exitErr.ExitCode(), elapsed)
+	case runErr != nil:
+		return "", fmt.Errorf("failed to run %s: %v", program, runErr)
+	default:
+		fmt.Fprintf(&result, "Result: succeeded after %s\n", elapsed)
+	}
+
+	lines := strings.Split(strings.TrimRight(output.String(), "\n"), "\n")
+	if len(lines) > maxRunOutputLines {
+		fmt.Fprintf(&result, "\nOutput (last %d of %d lines):\n", maxRunOutputLines, len(lines))
+		lines = lines[len(lines)-maxRunOutputLines:]
+	} else {
+		result.WriteString("\nOutput:\n")
+	}
+	result.WriteString(fenceCode(strings.Join(lines, "\n"), ""))
+	return result.String(), nil
+}
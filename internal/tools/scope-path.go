@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// pathScope restricts symbol lookups to the files below a directory or in a
+// package. A nil scope contains every file.
+type pathScope struct {
+	// Absolute directory, or file, that is in scope
+	dir string
+	// Package path matched against the end of file directories, used when no
+	// directory of that name exists, e.g. "internal/lsp" or "com.example.app"
+	pkg string
+	// Directory package paths are relative to
+	root string
+	// The scopePath as given
+	name string
+}
+
+// newPathScope resolves a scopePath, relative paths against the workspace. A
+// path that does not exist is taken as a package path: slash-separated like a Go
+// import path, or dotted like a Java or Python package.
+func newPathScope(scopePath string) (*pathScope, error) {
+	if scopePath == "" {
+		return nil, nil
+	}
+	abs, err := filepath.Abs(scopePath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scopePath: %v", err)
+	}
+	if _, err := os.Stat(abs); err == nil {
+		return &pathScope{dir: abs, name: scopePath}, nil
+	}
+	if filepath.IsAbs(scopePath) {
+		return nil, fmt.Errorf("scopePath %s does not exist", scopePath)
+	}
+
+	pkg := strings.Trim(filepath.ToSlash(scopePath), "/")
+	if !strings.Contains(pkg, "/") {
+		pkg = strings.ReplaceAll(pkg, ".", "/")
+	}
+	root, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("invalid scopePath: %v", err)
+	}
+	return &pathScope{pkg: pkg, root: root, name: scopePath}, nil
+}
+
+// contains reports whether a document is in the scope. A package contains the
+// files of directories ending with it, and of directories the package path ends
+// with relative to the workspace, so full Go import paths match too.
+func (s *pathScope) contains(uri protocol.DocumentUri) bool {
+	if s == nil {
+		return true
+	}
+	path := strings.TrimPrefix(string(uri), "file://")
+	if s.dir != "" {
+		return path == s.dir || strings.HasPrefix(path, s.dir+string(filepath.Separator))
+	}
+
+	dir := filepath.ToSlash(filepath.Dir(path))
+	if strings.HasSuffix("/"+dir, "/"+s.pkg) {
+		return true
+	}
+	rel, err := filepath.Rel(s.root, filepath.Dir(path))
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	return strings.HasSuffix("/"+s.pkg, "/"+filepath.ToSlash(rel))
+}
+
+// String names the scope in messages
+func (s *pathScope) String() string {
+	return s.name
+}
@@ -0,0 +1,221 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/text"
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+)
+
+// textMatch is a single line matching a text search
+type textMatch struct {
+	line int // 0-indexed
+}
+
+// SearchText scans workspace files for a regex or literal pattern, skipping files
+// excluded by the watcher's gitignore and exclusion rules.
+// includeGlobs restricts the search to files matching at least one glob (relative to the workspace root).
+func SearchText(ctx context.Context, w *watcher.WorkspaceWatcher, query string, isRegex bool, caseSensitive bool, includeGlobs []string, contextLines int, maxResults int) (string, error) {
+	if query == "" {
+		return "", fmt.Errorf("query must not be empty")
+	}
+
+	expr := query
+	if !isRegex {
+		expr = regexp.QuoteMeta(query)
+	}
+	if !caseSensitive {
+		expr = "(?i)" + expr
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return "", fmt.Errorf("invalid regular expression: %v", err)
+	}
+
+	if contextLines < 0 {
+		contextLines = 0
+	}
+	if maxResults <= 0 {
+		maxResults = 100
+	}
+
+	root := w.WorkspacePath()
+	totalMatches := 0
+	filesMatched := 0
+	truncated := false
+	omitted := 0                         // Matches found after truncating
+	matchesByDir := make(map[string]int) // Matches per top-level directory, for suggestions
+
+	var output strings.Builder
+
+	err = w.WalkWorkspace(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip unreadable entries
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if d.IsDir() {
+			if w.IsExcluded(path, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if w.IsExcluded(path, false) {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			relPath = path
+		}
+		if len(includeGlobs) > 0 && !matchesAnyGlob(includeGlobs, relPath) {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil || isBinaryContent(content) {
+			return nil
+		}
+
+		lines := text.Lines(content)
+		dir := topLevelDir(relPath)
+
+		// Past the limit, matches are only counted so the footer can say what was left out
+		if truncated {
+			for _, line := range lines {
+				if pattern.MatchString(line) {
+					omitted++
+					matchesByDir[dir]++
+				}
+			}
+			return nil
+		}
+
+		var matches []textMatch
+		for i, line := range lines {
+			if pattern.MatchString(line) {
+				if totalMatches >= maxResults {
+					truncated = true
+					omitted++
+					matchesByDir[dir]++
+					continue
+				}
+				matches = append(matches, textMatch{line: i})
+				totalMatches++
+				matchesByDir[dir]++
+			}
+		}
+		if len(matches) == 0 {
+			return nil
+		}
+
+		filesMatched++
+		writeFileMatches(&output, path, lines, matches, contextLines)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to search workspace: %v", err)
+	}
+
+	if totalMatches == 0 {
+		return fmt.Sprintf("No matches found for '%s'", query), nil
+	}
+
+	header := fmt.Sprintf("Search: %s (%d matches in %d files)\n", query, totalMatches, filesMatched)
+	if truncated {
+		header += fmt.Sprintf("Results truncated at %d matches. Narrow the search with includeGlobs.\n", maxResults)
+	}
+
+	hints := ResponseHints{Omitted: omitted, OmittedKind: "matches"}
+	if truncated {
+		if len(includeGlobs) == 0 {
+			if dir := busiestDir(matchesByDir); dir != "" {
+				hints.Suggestions = append(hints.Suggestions, fmt.Sprintf(`includeGlobs=["%s/**"] (%d of %d matches)`, dir, matchesByDir[dir], totalMatches+omitted))
+			}
+		}
+		hints.Suggestions = append(hints.Suggestions, fmt.Sprintf("maxResults=%d to see every match", totalMatches+omitted))
+	} else if contextLines > 0 && EstimateTokens(output.String()) >= LargeResponseTokens {
+		hints.Suggestions = append(hints.Suggestions, "contextLines=0 for matching lines only")
+	}
+
+	return AddFooter(header+"\n"+output.String(), hints), nil
+}
+
+// topLevelDir returns the first directory of a workspace relative path, or "" for
+// files at the root
+func topLevelDir(relPath string) string {
+	dir, _, found := strings.Cut(filepath.ToSlash(relPath), "/")
+	if !found {
+		return ""
+	}
+	return dir
+}
+
+// busiestDir returns the directory with the most matches, ignoring the root. It
+// returns "" when all matches are in one directory, narrowing to it would not help.
+func busiestDir(matchesByDir map[string]int) string {
+	if len(matchesByDir) < 2 {
+		return ""
+	}
+	best := ""
+	for dir, n := range matchesByDir {
+		if dir != "" && (best == "" || n > matchesByDir[best] || n == matchesByDir[best] && dir < best) {
+			best = dir
+		}
+	}
+	return best
+}
+
+// writeFileMatches writes the matches in a file, merging overlapping context windows
+func writeFileMatches(output *strings.Builder, path string, lines []string, matches []textMatch, contextLines int) {
+	output.WriteString(fmt.Sprintf("File: %s (%d matches)\n", path, len(matches)))
+
+	for i := 0; i < len(matches); {
+		start := max(matches[i].line-contextLines, 0)
+		end := min(matches[i].line+contextLines, len(lines)-1)
+
+		var highlights []int
+		j := i
+		for ; j < len(matches) && matches[j].line-contextLines <= end+1; j++ {
+			end = min(matches[j].line+contextLines, len(lines)-1)
+			highlights = append(highlights, matches[j].line-start)
+		}
+		i = j
+
+		block := addLineNumbers(strings.Join(lines[start:end+1], "\n"), start+1, highlights...)
+		output.WriteString(indentBlock(fenceCode(block, path), "  ") + "\n")
+		if i < len(matches) {
+			output.WriteString("  ...\n")
+		}
+	}
+	output.WriteString("\n")
+}
+
+// matchesAnyGlob reports whether the path or its base name matches one of the globs
+func matchesAnyGlob(globs []string, relPath string) bool {
+	for _, glob := range globs {
+		if watcher.MatchesGlob(glob, relPath) || watcher.MatchesGlob(glob, filepath.Base(relPath)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBinaryContent guesses whether content is binary by looking for NUL bytes near the start
+func isBinaryContent(content []byte) bool {
+	const sniffLen = 8000
+	if len(content) > sniffLen {
+		content = content[:sniffLen]
+	}
+	return bytes.IndexByte(content, 0) != -1
+}
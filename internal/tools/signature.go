@@ -0,0 +1,221 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/text"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// maxSignatureLines caps the lines of a declaration, for parameter lists that
+// never close because the brackets were miscounted
+const maxSignatureLines = 20
+
+var whitespacePattern = regexp.MustCompile(`\s+`)
+
+// GetSignature returns the declaration lines of a symbol, without its body, and
+// the type the language server reports for it on hover. It is a cheaper
+// read_definition for when only a symbol's contract is needed.
+func GetSignature(ctx context.Context, client *lsp.Client, symbolName string, scopePath string, matchMode string) (string, error) {
+	if err := validateMatchMode(matchMode); err != nil {
+		return "", err
+	}
+	if _, err := symbolRegex(symbolName); err != nil {
+		return "", err
+	}
+	scope, err := newPathScope(scopePath)
+	if err != nil {
+		return "", err
+	}
+
+	wsSymbols, err := workspaceSymbols(ctx, client, serverSymbolQuery(symbolName))
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch workspace symbols for '%s': %w", symbolName, err)
+	}
+
+	var signatures []string
+	seen := make(map[protocol.Location]bool)
+	outOfScope := 0
+	for _, symbol := range wsSymbols {
+		loc := symbol.GetLocation()
+		if loc.URI == "" || seen[loc] || !symbolMatches(ctx, client, symbol, symbolName, matchMode) {
+			continue
+		}
+		seen[loc] = true
+		if !scope.contains(loc.URI) {
+			outOfScope++
+			continue
+		}
+
+		signature, err := formatSignature(ctx, client, symbol)
+		if err != nil {
+			debugLogger.Printf("Warning: no signature for %s in %s: %v\n", symbol.GetName(), loc.URI, err)
+			continue
+		}
+		signatures = append(signatures, signature)
+	}
+
+	if len(signatures) == 0 {
+		if outOfScope > 0 {
+			return fmt.Sprintf("Symbol '%s' not found in %s, there are %s outside it.", symbolName, scope, pluralize(outOfScope, "declaration")), nil
+		}
+		return fmt.Sprintf("Symbol '%s' not found in workspace.", symbolName) + caseMismatchHint(wsSymbols, symbolName, matchMode), nil
+	}
+	return strings.Join(signatures, "\n---\n\n"), nil
+}
+
+// formatSignature formats the declaration and hover type of one workspace symbol
+func formatSignature(ctx context.Context, client *lsp.Client, symbol protocol.WorkspaceSymbolResult) (string, error) {
+	loc := symbol.GetLocation()
+	filePath := documentPath(loc.URI)
+	if !lsp.IsExternal(loc.URI) {
+		if err := client.OpenFile(ctx, filePath); err != nil {
+			return "", fmt.Errorf("could not open file: %v", err)
+		}
+	}
+	content, err := client.ReadDocument(ctx, loc.URI)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	lines := text.Lines(content)
+
+	line, column, err := symbolNamePosition(lines, symbol)
+	if err != nil {
+		return "", err
+	}
+	declaration, endLine := declarationLines(lines, line)
+
+	var result strings.Builder
+	fmt.Fprintf(&result, "Symbol: %s\n", symbol.GetName())
+	if kind := utilities.GetSymbolKindString(symbol.GetKind()); kind != "" && kind != "Unknown" {
+		fmt.Fprintf(&result, "Kind: %s\n", kind)
+	}
+	if isDependency(loc.URI) {
+		fmt.Fprintf(&result, "File: %s (external dependency, read-only)\n", filePath)
+	} else {
+		fmt.Fprintf(&result, "File: %s\n", filePath)
+	}
+	if endLine > line {
+		fmt.Fprintf(&result, "Location: Lines %d-%d\n\n", line+1, endLine+1)
+	} else {
+		fmt.Fprintf(&result, "Location: Line %d\n\n", line+1)
+	}
+	result.WriteString(fenceCode(declaration, filePath))
+	if lsp.IsExternal(loc.URI) {
+		return result.String(), nil // Hover only takes file paths
+	}
+
+	// The hover type is left out when it only repeats the declaration
+	contents, err := hoverContents(ctx, client, filePath, line+1, int(text.Column(lines[line], column))+1)
+	if err != nil {
+		debugLogger.Printf("Warning: %v\n", err)
+	} else if contents.Value != "" {
+		hover, _ := renderHover(contents, HoverOptions{SignatureOnly: true, PlainText: true})
+		if hover != "" && normalizeWhitespace(hover) != normalizeWhitespace(declaration) {
+			result.WriteString("\nType:\n")
+			result.WriteString(fenceCode(hover, filePath))
+		}
+	}
+	return result.String(), nil
+}
+
+// declarationLines returns the declaration starting on line start, up to the line
+// where its parentheses and brackets are closed, and the index of that line. A
+// body opened on the last line is cut off, so "func F() {" becomes "func F()".
+func declarationLines(lines []string, start int) (string, int) {
+	depth := 0
+	var declaration []string
+	end := start
+	for ; end < len(lines) && end < start+maxSignatureLines; end++ {
+		line := lines[end]
+		cut := -1
+		for i, c := range line {
+			switch c {
+			case '(', '[':
+				depth++
+			case ')', ']':
+				depth--
+			case '{':
+				if depth <= 0 {
+					cut = i
+				}
+			}
+			if cut >= 0 {
+				break
+			}
+		}
+		if cut >= 0 {
+			line = line[:cut]
+		}
+		declaration = append(declaration, strings.TrimRight(line, " \t"))
+		if cut >= 0 || depth <= 0 {
+			break
+		}
+	}
+	if end == len(lines) || end == start+maxSignatureLines {
+		end--
+	}
+	return strings.TrimSpace(strings.Join(declaration, "\n")), end
+}
+
+// symbolNamePosition returns the 0-indexed line and byte offset of a workspace
+// symbol's name in its document's lines. Servers locate symbols by their name or
+// by their whole declaration, which may start with doc comments or decorators, so
+// the name is looked for from there.
+func symbolNamePosition(lines []string, symbol protocol.WorkspaceSymbolResult) (int, int, error) {
+	loc := symbol.GetLocation()
+	name := normalizeReceiver(symbol.GetName())
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	line, column := int(loc.Range.Start.Line), int(loc.Range.Start.Character)
+	if line >= len(lines) {
+		return 0, 0, fmt.Errorf("location L%d is past the end of the file", line+1)
+	}
+	for i := line; i < len(lines) && i <= int(loc.Range.End.Line); i++ {
+		if isCommentLine(lines[i]) {
+			continue
+		}
+		if col := identifierIndex(lines[i], name); col >= 0 {
+			return i, col, nil
+		}
+	}
+	return line, column, nil
+}
+
+// identifierIndex returns the byte offset of name as a whole word in line, or -1
+func identifierIndex(line string, name string) int {
+	if name == "" {
+		return -1
+	}
+	re, err := regexp.Compile(`\b` + regexp.QuoteMeta(name) + `\b`)
+	if err != nil {
+		return -1
+	}
+	if loc := re.FindStringIndex(line); loc != nil {
+		return loc[0]
+	}
+	return strings.Index(line, name)
+}
+
+// isCommentLine reports whether a line starts with a comment in one of the common
+// comment syntaxes. "#" only starts one when followed by a space, so C directives
+// and Rust attributes are not comments.
+func isCommentLine(line string) bool {
+	line = strings.TrimSpace(line)
+	for _, prefix := range []string{"//", "/*", "*", "# ", "--", ";", `"""`, "'''"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return line == "#"
+}
+
+func normalizeWhitespace(text string) string {
+	return whitespacePattern.ReplaceAllString(strings.TrimSpace(text), " ")
+}
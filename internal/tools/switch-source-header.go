@@ -0,0 +1,30 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// SwitchSourceHeader returns the path of the header belonging to a C or C++ source
+// file, or of the source file implementing a header
+func SwitchSourceHeader(ctx context.Context, client *lsp.Client, filePath string) (string, error) {
+	if !client.IsServer("clangd") {
+		return "", fmt.Errorf("switching between source and header needs clangd, the language server is %s", filepath.Base(client.Cmd.Path))
+	}
+	if err := client.OpenFile(ctx, filePath); err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+
+	target, err := client.SwitchSourceHeader(ctx, protocol.DocumentUri("file://"+filePath))
+	if err != nil {
+		return "", err
+	}
+	if target == "" {
+		return fmt.Sprintf("No matching header or source file found for %s.", filePath), nil
+	}
+	return documentPath(target), nil
+}
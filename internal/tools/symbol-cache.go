@@ -0,0 +1,171 @@
+package tools
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/memory"
+	"github.com/isaacphi/mcp-language-server/internal/metrics"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// DefaultSymbolCacheTTL is how long workspace symbol results are reused unless
+// configured otherwise
+const DefaultSymbolCacheTTL = 10 * time.Second
+
+// workspaceSymbolCache holds recent workspace/symbol results by query, so tool calls for
+// related symbols do not repeat the same workspace-wide search
+var workspaceSymbolCache = &symbolQueryCache{
+	ttl:     DefaultSymbolCacheTTL,
+	entries: make(map[string]symbolCacheEntry),
+}
+
+type symbolQueryCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]symbolCacheEntry
+}
+
+type symbolCacheEntry struct {
+	results []protocol.WorkspaceSymbolResult
+	// Files declaring the results, whose changes invalidate the entry
+	files   map[string]bool
+	expires time.Time
+	// memory.Tick of the last lookup and approximate size, for the memory manager
+	lastUsed uint64
+	size     int64
+}
+
+// symbolResultOverhead approximates the size of a cached symbol besides its strings
+const symbolResultOverhead = 96
+
+// SymbolCache returns the workspace symbol cache for the memory manager
+func SymbolCache() memory.Cache {
+	return workspaceSymbolCache
+}
+
+// SetSymbolCacheTTL sets how long workspace symbol results are reused, 0 to query
+// the language server every time. It must be called before tools are used.
+func SetSymbolCacheTTL(ttl time.Duration) {
+	workspaceSymbolCache.mu.Lock()
+	defer workspaceSymbolCache.mu.Unlock()
+	workspaceSymbolCache.ttl = ttl
+	workspaceSymbolCache.entries = make(map[string]symbolCacheEntry)
+}
+
+// ClearSymbolCache drops every cached workspace symbol result, e.g. when the
+// language server is replaced
+func ClearSymbolCache() {
+	workspaceSymbolCache.mu.Lock()
+	defer workspaceSymbolCache.mu.Unlock()
+	workspaceSymbolCache.entries = make(map[string]symbolCacheEntry)
+}
+
+// InvalidateSymbolCache drops the cached results that a file event may have made
+// stale. Changes and deletions drop the queries with results in the file. A new
+// file may declare symbols matching any query, so its creation drops them all.
+// Changes that add matching symbols to a file with none before are only picked
+// up when the entries expire.
+func InvalidateSymbolCache(path string, changeType protocol.FileChangeType) {
+	workspaceSymbolCache.mu.Lock()
+	defer workspaceSymbolCache.mu.Unlock()
+	if changeType == protocol.FileChangeType(protocol.Created) {
+		workspaceSymbolCache.entries = make(map[string]symbolCacheEntry)
+		return
+	}
+	for query, entry := range workspaceSymbolCache.entries {
+		if entry.files[path] {
+			delete(workspaceSymbolCache.entries, query)
+		}
+	}
+}
+
+// lookup returns the unexpired results of a query
+func (c *symbolQueryCache) lookup(query string) ([]protocol.WorkspaceSymbolResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ttl <= 0 {
+		return nil, false
+	}
+	entry, ok := c.entries[query]
+	if !ok || time.Now().After(entry.expires) {
+		delete(c.entries, query)
+		metrics.Miss("workspace_symbols")
+		return nil, false
+	}
+	metrics.Hit("workspace_symbols")
+	entry.lastUsed = memory.Tick()
+	c.entries[query] = entry
+	return entry.results, true
+}
+
+// store caches the results of a query. Empty results are not cached, the server
+// may still be indexing.
+func (c *symbolQueryCache) store(query string, results []protocol.WorkspaceSymbolResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ttl <= 0 || len(results) == 0 {
+		return
+	}
+	files := make(map[string]bool)
+	size := int64(len(query))
+	for _, symbol := range results {
+		uri := string(symbol.GetLocation().URI)
+		files[strings.TrimPrefix(uri, "file://")] = true
+		size += symbolResultOverhead + int64(len(symbol.GetName())+len(uri))
+	}
+	c.entries[query] = symbolCacheEntry{
+		results:  results,
+		files:    files,
+		expires:  time.Now().Add(c.ttl),
+		lastUsed: memory.Tick(),
+		size:     size,
+	}
+	memory.Default.Changed()
+}
+
+func (c *symbolQueryCache) Name() string {
+	return "workspace_symbols"
+}
+
+func (c *symbolQueryCache) Usage() (int, int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	bytes := int64(0)
+	for _, entry := range c.entries {
+		bytes += entry.size
+	}
+	return len(c.entries), bytes
+}
+
+func (c *symbolQueryCache) Oldest() (uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	query, ok := c.oldest()
+	return c.entries[query].lastUsed, ok
+}
+
+func (c *symbolQueryCache) EvictOldest() (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	query, ok := c.oldest()
+	if !ok {
+		return 0, false
+	}
+	size := c.entries[query].size
+	delete(c.entries, query)
+	return size, true
+}
+
+// oldest returns the least recently used query, the caller must hold mu
+func (c *symbolQueryCache) oldest() (string, bool) {
+	var oldestQuery string
+	found := false
+	for query, entry := range c.entries {
+		if !found || entry.lastUsed < c.entries[oldestQuery].lastUsed {
+			oldestQuery, found = query, true
+		}
+	}
+	return oldestQuery, found
+}
@@ -0,0 +1,152 @@
+package tools
+
+import (
+	"context"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// splitQualifiedName splits a name like "Foo.Name" into its container "Foo" and
+// member "Name". Names without a dot have no container.
+func splitQualifiedName(name string) (container, member string) {
+	i := strings.LastIndex(name, ".")
+	if i <= 0 || i == len(name)-1 {
+		return "", name
+	}
+	return name[:i], name[i+1:]
+}
+
+// normalizeReceiver strips the pointer and parentheses from Go style receivers, so
+// "(*Foo).Name" becomes "Foo.Name"
+func normalizeReceiver(name string) string {
+	return strings.NewReplacer("(", "", ")", "", "*", "", "&", "").Replace(name)
+}
+
+// containerNameMatches reports whether a server reported container, which may be
+// qualified by a package or module ("pkg.Foo", "mod::Foo"), names container
+func containerNameMatches(reported, container string) bool {
+	reported = normalizeReceiver(reported)
+	container = normalizeReceiver(container)
+	if reported == container {
+		return true
+	}
+	for _, sep := range []string{".", "::", "/"} {
+		if strings.HasSuffix(reported, sep+container) {
+			return true
+		}
+	}
+	return false
+}
+
+// isMemberKind reports whether symbols of a kind belong to a type
+func isMemberKind(kind protocol.SymbolKind) bool {
+	switch kind {
+	case protocol.Method, protocol.Field, protocol.Property, protocol.Constructor, protocol.EnumMember:
+		return true
+	}
+	return false
+}
+
+// anySymbolNamed reports whether a result is named exactly name
+func anySymbolNamed(results []protocol.WorkspaceSymbolResult, name string) bool {
+	for _, symbol := range results {
+		if symbol.GetName() == name || normalizeReceiver(symbol.GetName()) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// containerKeyName returns the last component of a qualified container name
+func containerKeyName(container string) string {
+	container = normalizeReceiver(container)
+	if i := strings.LastIndexAny(container, ".:/"); i >= 0 && i < len(container)-1 {
+		return container[i+1:]
+	}
+	return container
+}
+
+// symbolMatches reports whether a workspace symbol is the symbol named by query,
+// comparing names by a match mode (see nameMatches). Servers like gopls qualify
+// member names themselves ("Foo.Name"). Others report the bare member name, which is
+// only accepted when its container is the one in the query, from the symbol's
+// containerName or else its parent in the document symbols. The container is
+// always matched in full. A "regex:" query is only matched against whole names.
+func symbolMatches(ctx context.Context, client *lsp.Client, symbol protocol.WorkspaceSymbolResult, query string, mode string) bool {
+	name := symbol.GetName()
+	if nameMatches(name, query, mode) || nameMatches(normalizeReceiver(name), query, mode) {
+		return true
+	}
+
+	if strings.HasPrefix(query, regexQueryPrefix) {
+		return false
+	}
+	container, member := splitQualifiedName(query)
+	if container == "" || !nameMatches(name, member, mode) {
+		return false
+	}
+	containerMatches := containerNameMatches
+	if mode == matchCaseInsensitive {
+		containerMatches = func(reported, container string) bool {
+			return containerNameMatches(strings.ToLower(reported), strings.ToLower(container))
+		}
+	}
+	if reported := symbol.GetContainerName(); reported != "" {
+		return containerMatches(reported, container)
+	}
+
+	parent := parentSymbolName(ctx, client, symbol.GetLocation())
+	return parent != "" && containerMatches(parent, container)
+}
+
+// parentSymbolName returns the name of the document symbol enclosing the symbol at
+// loc, or "" if it is top-level or the server has no document symbols
+func parentSymbolName(ctx context.Context, client *lsp.Client, loc protocol.Location) string {
+	result, err := client.DocumentSymbol(ctx, protocol.DocumentSymbolParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: loc.URI},
+	})
+	if err != nil {
+		debugLogger.Printf("Warning: Failed to get document symbols for %s: %v\n", loc.URI, err)
+		return ""
+	}
+	symbols, err := result.Results()
+	if err != nil {
+		return ""
+	}
+
+	var parent string
+	for {
+		var next *protocol.DocumentSymbol
+		for _, sym := range symbols {
+			ds, ok := sym.(*protocol.DocumentSymbol)
+			if ok && rangeContains(ds.Range, loc.Range.Start) {
+				next = ds
+				break
+			}
+		}
+		// The location is the symbol's name or, for some servers, its whole range
+		if next == nil || rangeContains(next.SelectionRange, loc.Range.Start) || next.Range.Start == loc.Range.Start {
+			return parent
+		}
+		parent = next.Name
+		symbols = make([]protocol.DocumentSymbolResult, len(next.Children))
+		for i := range next.Children {
+			symbols[i] = &next.Children[i]
+		}
+	}
+}
+
+func rangeContains(r protocol.Range, pos protocol.Position) bool {
+	if pos.Line < r.Start.Line || pos.Line > r.End.Line {
+		return false
+	}
+	if pos.Line == r.Start.Line && pos.Character < r.Start.Character {
+		return false
+	}
+	if pos.Line == r.End.Line && pos.Character > r.End.Character {
+		return false
+	}
+	return true
+}
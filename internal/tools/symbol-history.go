@@ -0,0 +1,156 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/git"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// defaultHistoryCommits is how many of the newest commits symbol_history lists
+const defaultHistoryCommits = 10
+
+// GetSymbolHistory finds the definitions of a symbol and follows the lines of each
+// back through git history, reporting the commit that introduced them, the one that
+// last changed them and the newest maxCommits commits in between, followed by the
+// definition. The introducing commit is the oldest one git log -L reaches, which is
+// where the code was moved from if it came from another file. The minimal verbosity
+// leaves the definition out, full lists every commit unless maxCommits is given.
+func GetSymbolHistory(ctx context.Context, client *lsp.Client, symbolName string, scopePath string, matchMode string, maxCommits int) (string, error) {
+	verbosity := VerbosityFromContext(ctx)
+	if maxCommits <= 0 {
+		maxCommits = defaultHistoryCommits
+		if verbosity == VerbosityFull {
+			maxCommits = math.MaxInt
+		}
+	}
+	if err := validateMatchMode(matchMode); err != nil {
+		return "", err
+	}
+	if _, err := symbolRegex(symbolName); err != nil {
+		return "", err
+	}
+	scope, err := newPathScope(scopePath)
+	if err != nil {
+		return "", err
+	}
+
+	wsSymbols, err := workspaceSymbols(ctx, client, serverSymbolQuery(symbolName))
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch workspace symbols for '%s': %w", symbolName, err)
+	}
+
+	var definitions []DefinitionInfo
+	seen := make(map[protocol.Location]bool)
+	dependencies := 0
+	for _, symbol := range wsSymbols {
+		loc := symbol.GetLocation()
+		if loc.URI == "" || seen[loc] || !scope.contains(loc.URI) || !symbolMatches(ctx, client, symbol, symbolName, matchMode) {
+			continue
+		}
+		seen[loc] = true
+		// Dependencies and documents that are not files have no history in the workspace
+		if lsp.IsExternal(loc.URI) || isDependency(loc.URI) {
+			dependencies++
+			continue
+		}
+		if err := client.OpenFile(ctx, documentPath(loc.URI)); err != nil {
+			debugLogger.Printf("Warning: could not open %s: %v\n", loc.URI, err)
+			continue
+		}
+		if definition, ok := resolveDefinition(ctx, client, loc, symbol.GetName()); ok {
+			definitions = append(definitions, definition)
+		}
+	}
+	if len(definitions) == 0 {
+		if dependencies > 0 {
+			return fmt.Sprintf("Symbol '%s' is only declared in dependencies, which have no history in the workspace.", symbolName), nil
+		}
+		return fmt.Sprintf("Symbol '%s' not found in workspace.", symbolName) + caseMismatchHint(wsSymbols, symbolName, matchMode), nil
+	}
+	sort.Slice(definitions, func(i, j int) bool {
+		if definitions[i].FilePath != definitions[j].FilePath {
+			return definitions[i].FilePath < definitions[j].FilePath
+		}
+		return definitions[i].Range.Start.Line < definitions[j].Range.Start.Line
+	})
+
+	var output strings.Builder
+	for i, definition := range definitions {
+		if i > 0 {
+			output.WriteString("\n---\n\n")
+		}
+		startLine, endLine := int(definition.Range.Start.Line)+1, int(definition.Range.End.Line)+1
+
+		output.WriteString(fmt.Sprintf("Symbol: %s\n", definition.SymbolName))
+		if definition.HasKind {
+			if kind := utilities.GetSymbolKindString(definition.SymbolKind); kind != "" && kind != "Unknown" {
+				output.WriteString(fmt.Sprintf("Kind: %s\n", kind))
+			}
+		}
+		output.WriteString(fmt.Sprintf("File: %s\n", definition.FilePath))
+		output.WriteString(fmt.Sprintf("Location: Lines %d-%d\n", startLine, endLine))
+		output.WriteString(formatSymbolHistory(ctx, definition.FilePath, startLine, endLine, maxCommits))
+		if verbosity == VerbosityMinimal {
+			continue
+		}
+		output.WriteString("\n")
+		output.WriteString(fenceCode(addLineNumbers(definition.DefinitionText, startLine), definition.FilePath))
+	}
+	return output.String(), nil
+}
+
+// formatSymbolHistory describes the commits that changed lines start to end of a file
+func formatSymbolHistory(ctx context.Context, filePath string, startLine, endLine int, maxCommits int) string {
+	commits, uncommitted, err := git.LineHistory(ctx, filePath, startLine, endLine)
+	if err != nil {
+		return fmt.Sprintf("History unavailable: %v\n", err)
+	}
+
+	var b strings.Builder
+	if len(commits) == 0 {
+		if uncommitted {
+			b.WriteString("History: none, the definition has not been committed yet\n")
+		} else {
+			b.WriteString("History: no commits changed these lines\n")
+		}
+		return b.String()
+	}
+
+	oldest, newest := commits[len(commits)-1], commits[0]
+	b.WriteString(fmt.Sprintf("Introduced: %s\n", formatHistoryCommit(oldest)))
+	if len(commits) > 1 {
+		b.WriteString(fmt.Sprintf("Last modified: %s\n", formatHistoryCommit(newest)))
+	}
+	if uncommitted {
+		b.WriteString("Uncommitted changes: some of these lines differ from HEAD\n")
+	}
+
+	if len(commits) > 2 {
+		shown := commits
+		if len(shown) > maxCommits {
+			shown = shown[:maxCommits]
+			b.WriteString(fmt.Sprintf("\nCommits: %d, the %d newest:\n", len(commits), maxCommits))
+		} else {
+			b.WriteString(fmt.Sprintf("\nCommits: %d\n", len(commits)))
+		}
+		for _, commit := range shown {
+			lines := fmt.Sprintf("+%d -%d", commit.Added, commit.Removed)
+			b.WriteString(fmt.Sprintf("  %.8s %s %-20s %-9s %s\n",
+				commit.Hash, commit.Time.Format("2006-01-02"), commit.Author, lines, commit.Subject))
+		}
+	}
+	return b.String()
+}
+
+// formatHistoryCommit describes a commit on one line
+func formatHistoryCommit(commit git.Commit) string {
+	return fmt.Sprintf("%.8s %s by %s <%s>: %s",
+		commit.Hash, commit.Time.Format("2006-01-02 15:04"), commit.Author, commit.Email, commit.Subject)
+}
@@ -0,0 +1,53 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/isaacphi/mcp-language-server/internal/index"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// symbolIndex is the persisted symbol index, nil when it is disabled
+var symbolIndex *index.Index
+
+// SetSymbolIndex sets the index used when the language server finds no symbols,
+// which happens while it is still indexing the workspace. It must be called before
+// tools are used.
+func SetSymbolIndex(idx *index.Index) {
+	symbolIndex = idx
+}
+
+// workspaceSymbols queries the language server for symbols matching query, reusing
+// recent results from the symbol cache. When the server returns nothing, the
+// persisted index is used, so definitions can be found while the server is still
+// starting up.
+func workspaceSymbols(ctx context.Context, client *lsp.Client, query string) ([]protocol.WorkspaceSymbolResult, error) {
+	if results, ok := workspaceSymbolCache.lookup(query); ok {
+		return results, nil
+	}
+	result, err := client.Symbol(ctx, protocol.WorkspaceSymbolParams{Query: query})
+	if err != nil {
+		return nil, err
+	}
+	results, err := result.Results()
+	if err == nil {
+		workspaceSymbolCache.store(query, results)
+	}
+	if err != nil || len(results) > 0 || symbolIndex == nil {
+		return results, err
+	}
+
+	results = symbolIndex.Lookup(query)
+	if len(results) > 0 {
+		debugLogger.Printf("Language server found no symbols for '%s', using %d from the symbol index\n", query, len(results))
+	}
+	return results, nil
+}
+
+// recordReferences stores the number of references found for a symbol in the index
+func recordReferences(symbolName string, count int) {
+	if symbolIndex != nil {
+		symbolIndex.RecordReferences(symbolName, count)
+	}
+}
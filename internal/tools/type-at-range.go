@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/text"
+)
+
+// maxExpressionDisplay is how much of an expression's text is echoed in the result
+const maxExpressionDisplay = 200
+
+// GetTypeAtRange explains the expression in a range of a file, given with 1-indexed
+// lines and columns and endColumn the column of its last character. Servers with
+// the hoverRange extension, such as rust-analyzer, are asked about the range itself
+// and answer with the expression's type. Others get a hover at the middle of the
+// expression, or at its start when there is nothing there, which gives the type of
+// the identifier hovered rather than of the whole expression.
+func GetTypeAtRange(ctx context.Context, client *lsp.Client, filePath string, startLine, startColumn, endLine, endColumn int, opts HoverOptions) (string, error) {
+	opts = opts.withVerbosity(ctx)
+	if startLine < 1 || startColumn < 1 || endLine < 1 || endColumn < 1 {
+		return "", fmt.Errorf("lines and columns are 1-indexed")
+	}
+	if endLine < startLine || endLine == startLine && endColumn < startColumn {
+		return "", fmt.Errorf("the range ends before it starts")
+	}
+	if err := client.OpenFile(ctx, filePath); err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+	content, err := client.GetFileContent(filePath)
+	if err != nil {
+		return "", err
+	}
+	lines := text.Lines(content)
+	rng := protocol.Range{
+		Start: protocol.Position{Line: uint32(startLine - 1), Character: uint32(startColumn - 1)},
+		End:   protocol.Position{Line: uint32(endLine - 1), Character: uint32(endColumn)},
+	}
+	expression, err := text.Range(lines, rng)
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	display := strings.Join(strings.Fields(expression), " ")
+	if runes := []rune(display); len(runes) > maxExpressionDisplay {
+		display = string(runes[:maxExpressionDisplay]) + "…"
+	}
+	result.WriteString(fmt.Sprintf("Expression: %s (%d:%d-%d:%d)\n", display, startLine, startColumn, endLine, endColumn))
+
+	uri := protocol.DocumentUri("file://" + filePath)
+	var contents protocol.MarkupContent
+	if client.SupportsHoverRange() {
+		raw, err := client.HoverRange(ctx, uri, rng)
+		if err != nil {
+			return "", fmt.Errorf("failed to get hover information: %v", err)
+		}
+		if contents, err = protocol.HoverContents(raw); err != nil {
+			return "", fmt.Errorf("failed to get hover information: %v", err)
+		}
+		result.WriteString("Source: the language server's hover over the range\n")
+	} else {
+		line, column := rangeMidpoint(lines, rng)
+		contents, err = hoverContents(ctx, client, filePath, line, column)
+		if err != nil {
+			return "", err
+		}
+		where := "the middle of the expression"
+		if contents.Value == "" && (line != startLine || column != startColumn) {
+			line, column = startLine, startColumn
+			where = "the start of the expression"
+			if contents, err = hoverContents(ctx, client, filePath, line, column); err != nil {
+				return "", err
+			}
+		}
+		result.WriteString(fmt.Sprintf("Source: hover at %d:%d, %s\n", line, column, where))
+	}
+
+	result.WriteString("\n")
+	if contents.Value == "" {
+		result.WriteString("No type information available for this expression")
+		return result.String(), nil
+	}
+	rendered, kind := renderHover(contents, opts)
+	if kind != "" {
+		result.WriteString(fmt.Sprintf("Kind: %s\n\n", kind))
+	}
+	result.WriteString(rendered)
+	return result.String(), nil
+}
+
+// rangeMidpoint returns the 1-indexed line and column halfway through a range,
+// moved forward past whitespace so the hover lands on code
+func rangeMidpoint(lines []string, rng protocol.Range) (int, int) {
+	type offset struct{ line, byte int }
+	start := offset{int(rng.Start.Line), text.ByteOffset(lines[rng.Start.Line], rng.Start.Character)}
+	end := offset{int(rng.End.Line), text.ByteOffset(lines[rng.End.Line], rng.End.Character)}
+
+	// Count the bytes of the range, with a byte for each line break
+	length := end.byte - start.byte
+	for i := start.line; i < end.line; i++ {
+		length += len(lines[i]) + 1
+	}
+	mid := start
+	for remaining := length / 2; remaining > 0; {
+		if rest := len(lines[mid.line]) - mid.byte; remaining > rest {
+			remaining -= rest + 1
+			mid = offset{mid.line + 1, 0}
+			continue
+		}
+		mid.byte += remaining
+		break
+	}
+
+	for {
+		line := lines[mid.line]
+		for mid.byte < len(line) && !utf8.RuneStart(line[mid.byte]) {
+			mid.byte++
+		}
+		if mid.line == end.line && mid.byte >= end.byte {
+			break
+		}
+		if mid.byte >= len(line) {
+			mid = offset{mid.line + 1, 0}
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(line[mid.byte:])
+		if !unicode.IsSpace(r) {
+			return mid.line + 1, int(text.Column(line, mid.byte)) + 1
+		}
+		mid.byte += size
+	}
+	// The second half is blank, the range's start is the best guess
+	return start.line + 1, int(rng.Start.Character) + 1
+}
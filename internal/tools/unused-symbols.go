@@ -0,0 +1,188 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// unusedSymbolWorkers is the number of concurrent references requests
+const unusedSymbolWorkers = 8
+
+// unusedCandidate is a workspace symbol whose references are checked
+type unusedCandidate struct {
+	symbol   protocol.WorkspaceSymbolResult
+	refCount int
+	err      error
+}
+
+// FindUnusedSymbols reports workspace symbols with no references outside their declaration.
+// query is passed to workspace/symbol (e.g. a package name), kinds restricts symbol kinds,
+// and directory restricts candidates to files below that directory.
+func FindUnusedSymbols(ctx context.Context, client *lsp.Client, query string, kinds []string, directory string, exportedOnly bool) (string, error) {
+	kindFilter, err := utilities.ParseSymbolKinds(kinds)
+	if err != nil {
+		return "", err
+	}
+
+	if directory != "" {
+		directory, err = filepath.Abs(directory)
+		if err != nil {
+			return "", fmt.Errorf("invalid directory: %v", err)
+		}
+	}
+
+	symbolResult, err := client.Symbol(ctx, protocol.WorkspaceSymbolParams{Query: query})
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch workspace symbols: %v", err)
+	}
+	results, err := symbolResult.Results()
+	if err != nil {
+		return "", fmt.Errorf("failed to parse workspace symbols: %v", err)
+	}
+
+	var candidates []*unusedCandidate
+	seen := make(map[protocol.Location]bool)
+	for _, symbol := range results {
+		loc := symbol.GetLocation()
+		if loc.URI == "" || seen[loc] {
+			continue
+		}
+		if len(kindFilter) > 0 && !kindFilter[symbol.GetKind()] {
+			continue
+		}
+		if exportedOnly && !isExported(symbol.GetName()) {
+			continue
+		}
+		if directory != "" {
+			path := strings.TrimPrefix(string(loc.URI), "file://")
+			if path != directory && !strings.HasPrefix(path, directory+string(filepath.Separator)) {
+				continue
+			}
+		}
+		seen[loc] = true
+		candidates = append(candidates, &unusedCandidate{symbol: symbol})
+	}
+
+	if len(candidates) == 0 {
+		return "No matching workspace symbols to check.", nil
+	}
+
+	countReferences(ctx, candidates, func(ctx context.Context, loc protocol.Location) ([]protocol.Location, error) {
+		return client.References(ctx, protocol.ReferenceParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: loc.URI},
+				Position:     loc.Range.Start,
+			},
+			Context: protocol.ReferenceContext{IncludeDeclaration: false},
+		})
+	})
+
+	var unused []*unusedCandidate
+	failed := 0
+	for _, candidate := range candidates {
+		if candidate.err != nil {
+			failed++
+			debugLogger.Printf("Warning: failed to get references for %s: %v\n", candidate.symbol.GetName(), candidate.err)
+			continue
+		}
+		if candidate.refCount == 0 {
+			unused = append(unused, candidate)
+		}
+	}
+
+	sort.Slice(unused, func(i, j int) bool {
+		li, lj := unused[i].symbol.GetLocation(), unused[j].symbol.GetLocation()
+		if li.URI != lj.URI {
+			return li.URI < lj.URI
+		}
+		return li.Range.Start.Line < lj.Range.Start.Line
+	})
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Checked %d symbols, %d have no references", len(candidates), len(unused)))
+	if failed > 0 {
+		result.WriteString(fmt.Sprintf(" (%d could not be checked)", failed))
+	}
+	result.WriteString("\n")
+
+	currentFile := ""
+	for _, candidate := range unused {
+		loc := candidate.symbol.GetLocation()
+		filePath := strings.TrimPrefix(string(loc.URI), "file://")
+		if filePath != currentFile {
+			result.WriteString(fmt.Sprintf("\nFile: %s\n", filePath))
+			currentFile = filePath
+		}
+		result.WriteString(fmt.Sprintf("  %s (L%d:C%d)\n",
+			utilities.FormatSymbolWithKind(utilities.GetSymbolKindString(candidate.symbol.GetKind()), candidate.symbol.GetName()),
+			loc.Range.Start.Line+1, loc.Range.Start.Character+1))
+	}
+
+	return result.String(), nil
+}
+
+// countReferences fills in refCount for each candidate with a bounded number of
+// concurrent lookups. Candidates that are never looked up because ctx is done get
+// ctx's error, so they are reported as unchecked rather than unused.
+func countReferences(ctx context.Context, candidates []*unusedCandidate, references func(context.Context, protocol.Location) ([]protocol.Location, error)) {
+	jobs := make(chan *unusedCandidate)
+	var wg sync.WaitGroup
+	for range min(unusedSymbolWorkers, len(candidates)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for candidate := range jobs {
+				loc := candidate.symbol.GetLocation()
+				refs, err := references(ctx, loc)
+				if err == nil {
+					err = ctx.Err()
+				}
+				if err != nil {
+					candidate.err = err
+					continue
+				}
+				for _, ref := range refs {
+					// Some servers include the declaration regardless of the context flag
+					if ref.URI == loc.URI && containsPosition(loc.Range, ref.Range.Start) {
+						continue
+					}
+					candidate.refCount++
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for i, candidate := range candidates {
+		select {
+		case <-ctx.Done():
+			for _, rest := range candidates[i:] {
+				rest.err = ctx.Err()
+			}
+			break dispatch
+		case jobs <- candidate:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// isExported reports whether a symbol name starts with an upper case letter.
+// Qualified names such as "Type.Method" are checked on their last component.
+func isExported(name string) bool {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(r)
+}
@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+func TestCountReferencesCancelled(t *testing.T) {
+	var candidates []*unusedCandidate
+	for i := range 50 {
+		uri := protocol.DocumentUri(fmt.Sprintf("file:///ws/f%d.go", i))
+		candidates = append(candidates, &unusedCandidate{symbol: &protocol.SymbolInformation{
+			Name:     fmt.Sprintf("sym%d", i),
+			Location: protocol.Location{URI: uri},
+		}})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls atomic.Int32
+	countReferences(ctx, candidates, func(ctx context.Context, loc protocol.Location) ([]protocol.Location, error) {
+		if calls.Add(1) == 3 {
+			cancel()
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return []protocol.Location{{URI: "file:///ws/other.go"}}, nil
+	})
+
+	unchecked := 0
+	for _, candidate := range candidates {
+		switch {
+		case candidate.err != nil:
+			if !errors.Is(candidate.err, context.Canceled) {
+				t.Errorf("%s: err = %v, want context.Canceled", candidate.symbol.GetName(), candidate.err)
+			}
+			unchecked++
+		case candidate.refCount == 0:
+			t.Errorf("%s reported with no references after cancellation", candidate.symbol.GetName())
+		}
+	}
+	if unchecked == 0 {
+		t.Fatal("no candidates were left unchecked after cancellation")
+	}
+	if int(calls.Load()) == len(candidates) {
+		t.Errorf("all %d candidates were looked up after cancellation", len(candidates))
+	}
+}
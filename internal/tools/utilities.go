@@ -4,70 +4,42 @@ import (
 	"context"
 	"fmt"
 	"net/url"
-	"os"
 	"strconv"
 	"strings"
 
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/text"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
 )
 
+// ExtractTextFromLocation reads the text of a location from the file on disk
 func ExtractTextFromLocation(loc protocol.Location) (string, error) {
-	path := strings.TrimPrefix(string(loc.URI), "file://")
-
-	content, err := os.ReadFile(path)
-	if err != nil {
-		return "", fmt.Errorf("failed to read file: %w", err)
-	}
-
-	lines := strings.Split(string(content), "\n")
-
-	startLine := int(loc.Range.Start.Line)
-	endLine := int(loc.Range.End.Line)
-	if startLine < 0 || startLine >= len(lines) || endLine < 0 || endLine >= len(lines) {
-		return "", fmt.Errorf("invalid Location range: %v", loc.Range)
-	}
-
-	// Handle single-line case
-	if startLine == endLine {
-		line := lines[startLine]
-		startChar := int(loc.Range.Start.Character)
-		endChar := int(loc.Range.End.Character)
-
-		if startChar < 0 || startChar > len(line) || endChar < 0 || endChar > len(line) {
-			return "", fmt.Errorf("invalid character range: %v", loc.Range)
-		}
-
-		return line[startChar:endChar], nil
-	}
-
-	// Handle multi-line case
-	var result strings.Builder
+	return text.ReadRange(strings.TrimPrefix(string(loc.URI), "file://"), loc.Range)
+}
 
-	// First line
-	firstLine := lines[startLine]
-	startChar := int(loc.Range.Start.Character)
-	if startChar < 0 || startChar > len(firstLine) {
-		return "", fmt.Errorf("invalid start character: %v", loc.Range.Start)
+// documentPath returns the file path of a document, or a readable name for one that
+// is not a file on disk, see lsp.ExternalName
+func documentPath(uri protocol.DocumentUri) string {
+	if lsp.IsExternal(uri) {
+		return lsp.ExternalName(uri)
 	}
-	result.WriteString(firstLine[startChar:])
-
-	// Middle lines
-	for i := startLine + 1; i < endLine; i++ {
-		result.WriteString("\n")
-		result.WriteString(lines[i])
+	path, err := url.PathUnescape(strings.TrimPrefix(string(uri), "file://"))
+	if err != nil {
+		return strings.TrimPrefix(string(uri), "file://")
 	}
+	return path
+}
 
-	// Last line
-	lastLine := lines[endLine]
-	endChar := int(loc.Range.End.Character)
-	if endChar < 0 || endChar > len(lastLine) {
-		return "", fmt.Errorf("invalid end character: %v", loc.Range.End)
+// isDependency reports whether a document is third-party code rather than part of
+// the workspace: inside an archive, the Go module cache, node_modules or
+// site-packages. Tools show it as read-only, edits to it are refused. Notebook
+// cells are the workspace's own code.
+func isDependency(uri protocol.DocumentUri) bool {
+	if notebookPath, _, ok := lsp.NotebookCellFromURI(uri); ok {
+		return utilities.IsDependencyPath(notebookPath)
 	}
-	result.WriteString("\n")
-	result.WriteString(lastLine[:endChar])
-
-	return result.String(), nil
+	return lsp.IsExternal(uri) || utilities.IsDependencyPath(documentPath(uri))
 }
 
 func containsPosition(r protocol.Range, p protocol.Position) bool {
@@ -136,70 +108,36 @@ func GetFullDefinition(ctx context.Context, client *lsp.Client, startLocation pr
 	}
 
 	if found {
-		// Convert URI to filesystem path
-		filePath, err := url.PathUnescape(strings.TrimPrefix(string(startLocation.URI), "file://"))
-		if err != nil {
-			return "", protocol.Location{}, fmt.Errorf("failed to unescape URI: %w", err)
-		}
+		filePath := documentPath(startLocation.URI)
 
 		// Read the file to get the full lines of the definition
 		// because we may have a start and end column
-		content, err := os.ReadFile(filePath)
+		content, err := client.ReadDocument(ctx, startLocation.URI)
 		if err != nil {
 			return "", protocol.Location{}, fmt.Errorf("failed to read file: %w", err)
 		}
 
-		lines := strings.Split(string(content), "\n")
-
-		// Extend start to beginning of line
-		symbolRange.Start.Character = 0
+		lines := text.Lines(content)
 
 		// Get the line at the end of the range
 		if int(symbolRange.End.Line) >= len(lines) {
 			return "", protocol.Location{}, fmt.Errorf("line number out of range")
 		}
 
-		line := lines[symbolRange.End.Line]
-		trimmedLine := strings.TrimSpace(line)
-
 		// In some cases, constant definitions do not include the full body and instead
-		// end with an opening bracket. In this case, parse the file until the closing bracket
-		if len(trimmedLine) > 0 {
-			lastChar := trimmedLine[len(trimmedLine)-1]
-			if lastChar == '(' || lastChar == '[' || lastChar == '{' || lastChar == '<' {
-				// Find matching closing bracket
-				bracketStack := []rune{rune(lastChar)}
-				lineNum := symbolRange.End.Line + 1
-
-				for lineNum < uint32(len(lines)) {
-					line := lines[lineNum]
-					for pos, char := range line {
-						if char == '(' || char == '[' || char == '{' || char == '<' {
-							bracketStack = append(bracketStack, char)
-						} else if char == ')' || char == ']' || char == '}' || char == '>' {
-							if len(bracketStack) > 0 {
-								lastOpen := bracketStack[len(bracketStack)-1]
-								if (lastOpen == '(' && char == ')') ||
-									(lastOpen == '[' && char == ']') ||
-									(lastOpen == '{' && char == '}') ||
-									(lastOpen == '<' && char == '>') {
-									bracketStack = bracketStack[:len(bracketStack)-1]
-									if len(bracketStack) == 0 {
-										// Found matching bracket - update range
-										symbolRange.End.Line = lineNum
-										symbolRange.End.Character = uint32(pos + 1)
-										goto foundClosing
-									}
-								}
-							}
-						}
-					}
-					lineNum++
-				}
-			foundClosing:
-			}
+		// end with an opening bracket. In this case, parse the file until the closing
+		// bracket, skipping brackets in strings and comments.
+		// When the brackets never close, e.g. in a file that does not parse, the
+		// server's folding ranges may still know where the block ends.
+		if end, ok := findBalancedEnd(lines, symbolRange.Start, symbolRange.End, syntaxForLanguage(lsp.DetectLanguageID(filePath))); ok {
+			symbolRange.End = end
+		} else if endLine, ok := foldingRangeEnd(ctx, client, startLocation.URI, symbolRange.End.Line, lines); ok {
+			symbolRange.End = protocol.Position{Line: endLine, Character: text.Column(lines[endLine], len(lines[endLine]))}
 		}
 
+		// Extend start to beginning of line
+		symbolRange.Start.Character = 0
+
 		// Update location with new range
 		startLocation.Range = symbolRange
 
@@ -215,6 +153,33 @@ func GetFullDefinition(ctx context.Context, client *lsp.Client, startLocation pr
 	return "", protocol.Location{}, fmt.Errorf("symbol not found")
 }
 
+// foldingRangeEnd returns the last line of the largest folding range starting on
+// line, including the line closing it, which servers often leave out
+func foldingRangeEnd(ctx context.Context, client *lsp.Client, uri protocol.DocumentUri, line uint32, lines []string) (uint32, bool) {
+	foldingRanges, err := client.FoldingRange(ctx, protocol.FoldingRangeParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	})
+	if err != nil {
+		debugLogger.Printf("Warning: failed to get folding ranges for %s: %v\n", uri, err)
+		return 0, false
+	}
+	end, found := uint32(0), false
+	for _, fr := range foldingRanges {
+		if fr.StartLine == line && fr.EndLine > end {
+			end, found = fr.EndLine, true
+		}
+	}
+	if !found || int(end) >= len(lines) {
+		return 0, false
+	}
+	if next := int(end) + 1; next < len(lines) {
+		if trimmed := strings.TrimSpace(lines[next]); trimmed != "" && strings.ContainsRune(")]}", rune(trimmed[0])) {
+			end++
+		}
+	}
+	return end, true
+}
+
 // addLineNumbers adds line numbers to each line of text with proper padding, starting from startLine
 // If highlightLines is provided, those line numbers (0-indexed relative to the start of the text) will be marked
 func addLineNumbers(text string, startLine int, highlightLines ...int) string {
@@ -247,79 +212,41 @@ func addLineNumbers(text string, startLine int, highlightLines ...int) string {
 	return result.String()
 }
 
+// prefixLines prefixes each line of text, which starts at startLine (1-based), with
+// prefix(line number)
+func prefixLines(text string, startLine int, prefix func(line int) string) string {
+	trailingNewline := strings.HasSuffix(text, "\n")
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = prefix(startLine+i) + line
+	}
+	result := strings.Join(lines, "\n")
+	if trailingNewline {
+		result += "\n"
+	}
+	return result
+}
+
 // GetDefinitionWithContext returns the text around a given position with configurable context,
 // along with the location (Range) corresponding to that returned text.
 // contextLines specifies how many lines before and after the reference line to include.
 // loc is the location of the original reference point.
 func GetDefinitionWithContext(ctx context.Context, client *lsp.Client /* Remove client if not used */, loc protocol.Location, contextLines int) (string, protocol.Location, error) {
-	// Convert URI to filesystem path
-	filePath, err := url.PathUnescape(strings.TrimPrefix(string(loc.URI), "file://"))
-	if err != nil {
-		return "", protocol.Location{}, fmt.Errorf("failed to unescape URI: %w", err)
-	}
+	filePath := documentPath(loc.URI)
 
-	// Read the file content
-	content, err := os.ReadFile(filePath)
+	// Read the file content, or the source of a document outside the file system
+	content, err := client.ReadDocument(ctx, loc.URI)
 	if err != nil {
 		// Return zero location on error
 		return "", protocol.Location{}, fmt.Errorf("failed to read file '%s': %w", filePath, err)
 	}
 
-	// It's generally safer to handle different line endings
-	// Replace CRLF with LF for consistent splitting
-	normalizedContent := strings.ReplaceAll(string(content), "\r\n", "\n")
-	fileLines := strings.Split(normalizedContent, "\n")
-
-	// Calculate the range to show, ensuring we don't go out of bounds
-	refLine := int(loc.Range.Start.Line) // The line where the reference occurs
-
-	// Check if the reference line itself is valid
-	if refLine < 0 || refLine >= len(fileLines) {
-		return "", protocol.Location{}, fmt.Errorf("reference line %d is out of bounds for file %s (0-%d)", refLine+1, filePath, len(fileLines)-1)
-	}
-
-	startLine := refLine - contextLines
-	if startLine < 0 {
-		startLine = 0
-	}
-
-	endLine := refLine + contextLines
-	if endLine >= len(fileLines) {
-		endLine = len(fileLines) - 1
-	}
-
-	// Ensure startLine is not greater than endLine (can happen if contextLines is large and file is small)
-	if startLine > endLine {
-		startLine = endLine
-	}
-
-	// Extract the lines
-	selectedLines := fileLines[startLine : endLine+1]
-	contextText := strings.Join(selectedLines, "\n")
-
-	// Create the location corresponding to the extracted text
-	// Start position: beginning of the startLine
-	// End position: end of the endLine (use a large character number or actual length if needed,
-	// but for scope identification, just the lines are often sufficient).
-	// Using length of last line for slightly more accuracy.
-	endChar := uint32(0)
-	if endLine >= 0 && endLine < len(fileLines) { // Check bounds for fileLines[endLine]
-		endChar = uint32(len(fileLines[endLine]))
-	}
-
-	contextLocation := protocol.Location{
-		URI: loc.URI, // Use the original URI
-		Range: protocol.Range{
-			Start: protocol.Position{
-				Line:      uint32(startLine),
-				Character: 0, // Start of the line
-			},
-			End: protocol.Position{
-				Line:      uint32(endLine),
-				Character: endChar, // End of the last included line
-			},
-		},
+	// Show the lines around the line where the reference occurs
+	contextText, contextRange, err := text.Around(text.Lines(content), int(loc.Range.Start.Line), contextLines)
+	if err != nil {
+		return "", protocol.Location{}, fmt.Errorf("reference in %s: %w", filePath, err)
 	}
+	contextLocation := protocol.Location{URI: loc.URI, Range: contextRange}
 
 	// Return the extracted text, its location, and nil error
 	return contextText, contextLocation, nil
@@ -388,3 +315,18 @@ func TruncateDefinition(definition string, targetLine int, contextSize int, maxL
 
 	return strings.Join(result, "\n")
 }
+
+// getDocumentSymbols fetches the symbols of a document
+func getDocumentSymbols(ctx context.Context, client *lsp.Client, uri protocol.DocumentUri) ([]protocol.DocumentSymbolResult, error) {
+	symResult, err := client.DocumentSymbol(ctx, protocol.DocumentSymbolParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document symbols: %w", err)
+	}
+	symbols, err := symResult.Results()
+	if err != nil {
+		return nil, fmt.Errorf("failed to process document symbols: %w", err)
+	}
+	return symbols, nil
+}
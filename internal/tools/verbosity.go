@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Verbosity presets set how much of what they find tools include. Flags a tool
+// has of its own, such as hover's signatureOnly or get_diagnostics'
+// includeContext, still apply when they are set.
+const (
+	// VerbosityMinimal keeps names, locations and signatures and drops code bodies
+	VerbosityMinimal = "minimal"
+	// VerbosityNormal is each tool's default output
+	VerbosityNormal = "normal"
+	// VerbosityFull adds documentation and surrounding context and skips truncation
+	VerbosityFull = "full"
+)
+
+// ValidateVerbosity checks a verbosity preset, "" standing for the default
+func ValidateVerbosity(verbosity string) error {
+	switch verbosity {
+	case "", VerbosityMinimal, VerbosityNormal, VerbosityFull:
+		return nil
+	}
+	return fmt.Errorf("invalid verbosity %q, expected minimal, normal or full", verbosity)
+}
+
+type verbosityKey struct{}
+
+// WithVerbosity returns a context whose tool calls use a verbosity preset
+func WithVerbosity(ctx context.Context, verbosity string) context.Context {
+	return context.WithValue(ctx, verbosityKey{}, verbosity)
+}
+
+// VerbosityFromContext returns the verbosity preset of a context, "normal" unless
+// one was set
+func VerbosityFromContext(ctx context.Context) string {
+	if verbosity, _ := ctx.Value(verbosityKey{}).(string); verbosity != "" {
+		return verbosity
+	}
+	return VerbosityNormal
+}
+
+// withVerbosity applies the minimal preset, which keeps only signatures
+func (o HoverOptions) withVerbosity(ctx context.Context) HoverOptions {
+	if VerbosityFromContext(ctx) == VerbosityMinimal {
+		o.SignatureOnly = true
+	}
+	return o
+}
+
+// docCommentStart returns the first line of the comments and attributes directly
+// above line (0-based), or line itself when there are none. Ranges from document
+// symbols often leave a declaration's documentation out.
+func docCommentStart(lines []string, line int) int {
+	start := line
+	inBlock := false
+	for i := line - 1; i >= 0 && i < len(lines); i-- {
+		trimmed := strings.TrimSpace(lines[i])
+		switch {
+		case inBlock:
+			// Inside a /* */ comment, read bottom up
+			inBlock = !strings.HasPrefix(trimmed, "/*")
+		case strings.HasSuffix(trimmed, "*/"):
+			inBlock = !strings.HasPrefix(trimmed, "/*")
+		case strings.HasPrefix(trimmed, "//"), strings.HasPrefix(trimmed, "#"),
+			strings.HasPrefix(trimmed, "--"), strings.HasPrefix(trimmed, "@"):
+		default:
+			return start
+		}
+		start = i
+	}
+	return start
+}
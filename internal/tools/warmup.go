@@ -0,0 +1,199 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+)
+
+const (
+	// warmupQuiet is how long the server must report no progress to count as idle
+	warmupQuiet = time.Second
+
+	// warmupPollInterval is how often the workspace symbol query is retried while it
+	// finds nothing
+	warmupPollInterval = 500 * time.Millisecond
+)
+
+// Warmup gets the language server ready for queries: it opens up to maxFiles
+// representative files, waits for the server's progress reports to finish and
+// queries workspace symbols until a symbol declared in an opened file is found. It
+// reports how long the server took to become ready.
+func Warmup(ctx context.Context, client *lsp.Client, w *watcher.WorkspaceWatcher, maxFiles int, timeout time.Duration) (string, error) {
+	if maxFiles <= 0 {
+		maxFiles = 5
+	}
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	files, err := representativeFiles(w, maxFiles)
+	if err != nil {
+		return "", fmt.Errorf("failed to list workspace files: %v", err)
+	}
+	var opened []string
+	for _, path := range files {
+		if err := client.OpenFile(ctx, path); err != nil {
+			debugLogger.Printf("Warning: warmup could not open %s: %v\n", path, err)
+			continue
+		}
+		opened = append(opened, path)
+	}
+
+	// A symbol the workspace index must contain once it is built
+	var probe string
+	for _, path := range opened {
+		symbols, err := documentSymbols(ctx, client, path)
+		if err == nil && len(symbols) > 0 {
+			probe = symbols[0].GetName()
+			break
+		}
+	}
+
+	progressErr := client.WaitForProgressIdle(ctx, warmupQuiet)
+	if progressErr == nil && client.IsServer("jdtls") {
+		// jdtls imports Gradle and Maven projects between progress reports
+		progressErr = client.WaitForJavaReady(ctx)
+	}
+
+	var found int
+	var symbolErr error
+	if probe != "" {
+		for {
+			var results []protocol.WorkspaceSymbolResult
+			results, symbolErr = workspaceSymbolResults(ctx, client, probe)
+			found = len(results)
+			if found > 0 || symbolErr != nil || ctx.Err() != nil {
+				break
+			}
+			select {
+			case <-time.After(warmupPollInterval):
+			case <-ctx.Done():
+			}
+		}
+	}
+
+	ready := progressErr == nil && (probe == "" || found > 0)
+	var result strings.Builder
+	if ready {
+		result.WriteString(fmt.Sprintf("Language server ready after %s (%s since it started)\n",
+			time.Since(start).Round(time.Millisecond), time.Since(client.Health().StartTime).Round(time.Millisecond)))
+	} else {
+		result.WriteString(fmt.Sprintf("Language server not ready after %s\n", timeout))
+	}
+
+	result.WriteString(fmt.Sprintf("\nOpened %s:\n", pluralize(len(opened), "file")))
+	for _, path := range opened {
+		if rel, err := filepath.Rel(w.WorkspacePath(), path); err == nil {
+			path = rel
+		}
+		result.WriteString(fmt.Sprintf("  %s\n", path))
+	}
+
+	titles := client.ProgressTitles()
+	if len(titles) > 0 {
+		result.WriteString(fmt.Sprintf("\nProgress reported: %s\n", strings.Join(titles, ", ")))
+	} else {
+		result.WriteString("\nThe server reported no progress.\n")
+	}
+	for _, task := range client.ActiveProgress() {
+		line := fmt.Sprintf("  Still running: %s", task.Title)
+		if task.Message != "" {
+			line += " - " + task.Message
+		}
+		if task.HasPercent {
+			line += fmt.Sprintf(" (%d%%)", task.Percentage)
+		}
+		result.WriteString(line + "\n")
+	}
+
+	switch {
+	case probe == "":
+		result.WriteString("Workspace symbols: not checked, the opened files have no symbols\n")
+	case symbolErr != nil:
+		result.WriteString(fmt.Sprintf("Workspace symbols: query for '%s' failed: %v\n", probe, symbolErr))
+	case found > 0:
+		result.WriteString(fmt.Sprintf("Workspace symbols: '%s' found (%s)\n", probe, pluralize(found, "result")))
+	default:
+		result.WriteString(fmt.Sprintf("Workspace symbols: '%s' not found yet\n", probe))
+	}
+
+	writeJavaStatus(&result, client)
+	return result.String(), nil
+}
+
+// workspaceSymbolResults queries the language server only, unlike workspaceSymbols
+// the persisted index is never used
+func workspaceSymbolResults(ctx context.Context, client *lsp.Client, query string) ([]protocol.WorkspaceSymbolResult, error) {
+	result, err := client.Symbol(ctx, protocol.WorkspaceSymbolParams{Query: query})
+	if err != nil {
+		return nil, err
+	}
+	return result.Results()
+}
+
+// representativeFiles picks up to maxFiles source files in the workspace's most
+// common language, taking one per directory before taking more from any, so that
+// servers which load code per package or project see several of them
+func representativeFiles(w *watcher.WorkspaceWatcher, maxFiles int) ([]string, error) {
+	byLanguage := make(map[protocol.LanguageKind][]string)
+	err := w.WalkWorkspace(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if w.IsExcluded(path, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		language := lsp.DetectLanguageID(path)
+		if language == "" || w.IsExcluded(path, false) {
+			return nil
+		}
+		byLanguage[language] = append(byLanguage[language], path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var common []string
+	for _, paths := range byLanguage {
+		if len(paths) > len(common) || (len(paths) == len(common) && len(paths) > 0 && paths[0] < common[0]) {
+			common = paths
+		}
+	}
+
+	// Round robin over directories, in walk order
+	var dirs []string
+	byDir := make(map[string][]string)
+	for _, path := range common {
+		dir := filepath.Dir(path)
+		if _, ok := byDir[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		byDir[dir] = append(byDir[dir], path)
+	}
+	var files []string
+	for round := 0; len(files) < maxFiles && len(files) < len(common); round++ {
+		for _, dir := range dirs {
+			if round < len(byDir[dir]) && len(files) < maxFiles {
+				files = append(files, byDir[dir][round])
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
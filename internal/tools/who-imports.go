@@ -0,0 +1,189 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/text"
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+)
+
+var (
+	// jsImportPattern matches ES module imports and exports, require and dynamic import calls
+	jsImportPattern = regexp.MustCompile(`(?:\bfrom\s+|\bimport\s+|\brequire\s*\(\s*|\bimport\s*\(\s*)['"]([^'"]+)['"]`)
+	// pyImportPattern matches "import a.b" and "from a.b import c"
+	pyImportPattern = regexp.MustCompile(`^\s*(?:from\s+([\w.]+)\s+import\b|import\s+([\w.]+(?:\s*,\s*[\w.]+)*))`)
+)
+
+// importSite is a single import of the target in a workspace file
+type importSite struct {
+	path       string
+	line       int // 1-indexed
+	importPath string
+}
+
+// FindImporters reports the workspace files and packages (directories) that import a
+// package or module path. Go files are parsed, JavaScript, TypeScript and Python
+// imports are matched by pattern. If includeSubpackages is set, imports of paths below
+// importPath are reported too.
+func FindImporters(ctx context.Context, w *watcher.WorkspaceWatcher, importPath string, includeSubpackages bool) (string, error) {
+	importPath = strings.TrimSpace(importPath)
+	if importPath == "" {
+		return "", fmt.Errorf("importPath must not be empty")
+	}
+
+	root := w.WorkspacePath()
+	var sites []importSite
+
+	err := w.WalkWorkspace(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip unreadable entries
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			if w.IsExcluded(path, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if w.IsExcluded(path, false) {
+			return nil
+		}
+
+		var imports []importSite
+		switch filepath.Ext(path) {
+		case ".go":
+			imports = goImports(path)
+		case ".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx", ".mts", ".cts":
+			imports = patternImports(path, jsImportPattern)
+		case ".py", ".pyi":
+			imports = patternImports(path, pyImportPattern)
+		}
+
+		for _, imp := range imports {
+			if importMatches(imp.importPath, importPath, includeSubpackages) {
+				sites = append(sites, imp)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to scan workspace: %v", err)
+	}
+
+	if len(sites) == 0 {
+		return fmt.Sprintf("No files import '%s'", importPath), nil
+	}
+
+	// Group by the directory of the importing file. A file can import the target
+	// more than once, files are counted once.
+	packages := make(map[string][]importSite)
+	files := make(map[string]bool)
+	for _, site := range sites {
+		files[site.path] = true
+		rel, err := filepath.Rel(root, filepath.Dir(site.path))
+		if err != nil {
+			rel = filepath.Dir(site.path)
+		}
+		packages[rel] = append(packages[rel], site)
+	}
+	names := make([]string, 0, len(packages))
+	for name := range packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Importers of %s: %d files in %d packages\n", importPath, len(files), len(packages)))
+
+	for _, name := range names {
+		pkgSites := packages[name]
+		sort.Slice(pkgSites, func(i, j int) bool {
+			if pkgSites[i].path != pkgSites[j].path {
+				return pkgSites[i].path < pkgSites[j].path
+			}
+			return pkgSites[i].line < pkgSites[j].line
+		})
+
+		result.WriteString(fmt.Sprintf("\nPackage: %s\n", name))
+		for _, site := range pkgSites {
+			line := fmt.Sprintf("  %s:%d", site.path, site.line)
+			if site.importPath != importPath {
+				line += fmt.Sprintf(" (%s)", site.importPath)
+			}
+			result.WriteString(line + "\n")
+		}
+	}
+
+	return result.String(), nil
+}
+
+// importMatches reports whether an imported path refers to the target
+func importMatches(imported, target string, includeSubpackages bool) bool {
+	if imported == target {
+		return true
+	}
+	if !includeSubpackages {
+		return false
+	}
+	// Go and JavaScript paths use slashes, Python modules use dots
+	return strings.HasPrefix(imported, target+"/") || strings.HasPrefix(imported, target+".")
+}
+
+// goImports parses the import declarations of a Go file
+func goImports(path string) []importSite {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, parser.ImportsOnly)
+	if err != nil || file == nil {
+		return nil
+	}
+
+	var imports []importSite
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		imports = append(imports, importSite{
+			path:       path,
+			line:       fset.Position(spec.Pos()).Line,
+			importPath: importPath,
+		})
+	}
+	return imports
+}
+
+// patternImports finds imports in a file line by line using a pattern whose
+// non-empty submatches are imported paths
+func patternImports(path string, pattern *regexp.Regexp) []importSite {
+	content, err := os.ReadFile(path)
+	if err != nil || isBinaryContent(content) {
+		return nil
+	}
+
+	var imports []importSite
+	for i, line := range text.Lines(content) {
+		for _, match := range pattern.FindAllStringSubmatch(line, -1) {
+			for _, group := range match[1:] {
+				// "import a, b" lists several modules in one group
+				for _, name := range strings.Split(group, ",") {
+					if name = strings.TrimSpace(name); name != "" {
+						imports = append(imports, importSite{path: path, line: i + 1, importPath: name})
+					}
+				}
+			}
+		}
+	}
+	return imports
+}
@@ -0,0 +1,58 @@
+package utilities
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// dependencyDirs are directory names that only hold installed third-party code
+var dependencyDirs = map[string]bool{
+	"node_modules":  true,
+	"site-packages": true,
+	"dist-packages": true,
+}
+
+var (
+	goModCache     string
+	goModCacheOnce sync.Once
+)
+
+// moduleCache returns the Go module cache directory, as go env GOMODCACHE would
+// without running the go command
+func moduleCache() string {
+	goModCacheOnce.Do(func() {
+		if dir := os.Getenv("GOMODCACHE"); dir != "" {
+			goModCache = filepath.Clean(dir)
+			return
+		}
+		gopath := os.Getenv("GOPATH")
+		if gopath == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return
+			}
+			gopath = filepath.Join(home, "go")
+		}
+		gopath, _, _ = strings.Cut(gopath, string(filepath.ListSeparator))
+		goModCache = filepath.Join(gopath, "pkg", "mod")
+	})
+	return goModCache
+}
+
+// IsDependencyPath reports whether path is installed third-party code: the Go
+// module cache, node_modules or a Python site-packages directory. Such files are
+// shown as external dependencies and never edited.
+func IsDependencyPath(path string) bool {
+	path = filepath.Clean(path)
+	if cache := moduleCache(); cache != "" && strings.HasPrefix(path, cache+string(filepath.Separator)) {
+		return true
+	}
+	for _, dir := range strings.Split(filepath.Dir(path), string(filepath.Separator)) {
+		if dependencyDirs[dir] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package utilities
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffCells bounds the size of the table used to diff two texts, about 200MB
+const maxDiffCells = 25_000_000
+
+// UnifiedDiff returns a unified diff turning a into b, with contextLines lines of
+// context around changes. It returns "" when the texts are equal.
+func UnifiedDiff(aName, bName, a, b string, contextLines int) (string, error) {
+	aLines := splitDiffLines(a)
+	bLines := splitDiffLines(b)
+	if len(aLines)*len(bLines) > maxDiffCells {
+		return "", fmt.Errorf("texts are too large to diff (%d and %d lines)", len(aLines), len(bLines))
+	}
+
+	ops := diffLines(aLines, bLines)
+	hunks := groupHunks(ops, contextLines)
+	if len(hunks) == 0 {
+		return "", nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", aName, bName))
+	for _, hunk := range hunks {
+		aStart, aCount, bStart, bCount := 0, 0, 0, 0
+		for i, op := range hunk {
+			if i == 0 {
+				aStart, bStart = op.aLine+1, op.bLine+1
+			}
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+		// An empty side of a hunk is given the line before it, as diff does
+		if aCount == 0 {
+			aStart--
+		}
+		if bCount == 0 {
+			bStart--
+		}
+		sb.WriteString(fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount)))
+		for _, op := range hunk {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.text)
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String(), nil
+}
+
+// hunkRange formats the start and length of one side of a hunk, leaving out a
+// length of one as diff does
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// diffOp is one line of a diff: ' ' for a line in both texts, '-' for a line only
+// in a and '+' for a line only in b. aLine and bLine are the 0-based positions the
+// op is at in each text.
+type diffOp struct {
+	kind         byte
+	text         string
+	aLine, bLine int
+}
+
+func splitDiffLines(text string) []string {
+	text = strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// diffLines computes a line diff from the longest common subsequence of a and b
+func diffLines(a, b []string) []diffOp {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i], i, j})
+			i++
+			j++
+		// Deletions go before insertions, as in diff
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i], i, j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j], i, j})
+			j++
+		}
+	}
+	return ops
+}
+
+// groupHunks splits a diff into hunks of changes with up to contextLines unchanged
+// lines around them. Changes closer than twice that share a hunk.
+func groupHunks(ops []diffOp, contextLines int) [][]diffOp {
+	var hunks [][]diffOp
+	start, end := -1, -1 // Range of ops in the current hunk
+	for i, op := range ops {
+		if op.kind == ' ' {
+			continue
+		}
+		from := max(i-contextLines, 0)
+		if start >= 0 && from > end+1 {
+			hunks = append(hunks, ops[start:end+1])
+			start = -1
+		}
+		if start < 0 {
+			start = from
+		}
+		end = min(i+contextLines, len(ops)-1)
+	}
+	if start >= 0 {
+		hunks = append(hunks, ops[start:end+1])
+	}
+	return hunks
+}
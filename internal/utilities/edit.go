@@ -19,6 +19,21 @@ func applyTextEdits(uri protocol.DocumentUri, edits []protocol.TextEdit) error {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
+	newContent, err := EditContent(content, edits)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, newContent, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return nil
+}
+
+// EditContent applies text edits to a document's content in memory, keeping its
+// line ending style and trailing newline
+func EditContent(content []byte, edits []protocol.TextEdit) ([]byte, error) {
 	// Detect line ending style
 	var lineEnding string
 	if bytes.Contains(content, []byte("\r\n")) {
@@ -37,7 +52,7 @@ func applyTextEdits(uri protocol.DocumentUri, edits []protocol.TextEdit) error {
 	for i := 0; i < len(edits); i++ {
 		for j := i + 1; j < len(edits); j++ {
 			if rangesOverlap(edits[i].Range, edits[j].Range) {
-				return fmt.Errorf("overlapping edits detected between edit %d and %d", i, j)
+				return nil, fmt.Errorf("overlapping edits detected between edit %d and %d", i, j)
 			}
 		}
 	}
@@ -56,7 +71,7 @@ func applyTextEdits(uri protocol.DocumentUri, edits []protocol.TextEdit) error {
 	for _, edit := range sortedEdits {
 		newLines, err := applyTextEdit(lines, edit, lineEnding)
 		if err != nil {
-			return fmt.Errorf("failed to apply edit: %w", err)
+			return nil, fmt.Errorf("failed to apply edit: %w", err)
 		}
 		lines = newLines
 	}
@@ -75,11 +90,7 @@ func applyTextEdits(uri protocol.DocumentUri, edits []protocol.TextEdit) error {
 		newContent.WriteString(lineEnding)
 	}
 
-	if err := os.WriteFile(path, []byte(newContent.String()), 0644); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
-	}
-
-	return nil
+	return []byte(newContent.String()), nil
 }
 
 func applyTextEdit(lines []string, edit protocol.TextEdit, lineEnding string) ([]string, error) {
@@ -208,6 +219,10 @@ func applyDocumentChange(change protocol.DocumentChange) error {
 
 // ApplyWorkspaceEdit applies the given WorkspaceEdit to the filesystem
 func ApplyWorkspaceEdit(edit protocol.WorkspaceEdit) error {
+	if err := checkEditSandbox(edit); err != nil {
+		return err
+	}
+
 	// Handle Changes field
 	for uri, textEdits := range edit.Changes {
 		if err := applyTextEdits(uri, textEdits); err != nil {
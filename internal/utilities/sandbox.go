@@ -0,0 +1,138 @@
+package utilities
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// Sandbox confines file paths to the workspace and an optional list of extra
+// directories. Paths are compared after resolving symlinks, so neither ".." nor a
+// link inside the workspace can reach files outside it.
+type Sandbox struct {
+	roots []string // Resolved, absolute
+}
+
+// NewSandbox creates a sandbox for the workspace and the extra allowed paths
+func NewSandbox(workspaceDir string, allowedPaths []string) (*Sandbox, error) {
+	s := &Sandbox{}
+	for _, root := range append([]string{workspaceDir}, allowedPaths...) {
+		resolved, err := resolvePath(root)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sandbox path %s: %v", root, err)
+		}
+		s.roots = append(s.roots, resolved)
+	}
+	return s, nil
+}
+
+// Check returns an error unless path, after resolving symlinks, is inside the
+// workspace or an allowed path. Relative paths are relative to the working directory,
+// which is the workspace. Paths that do not exist yet are checked through their
+// nearest existing parent.
+func (s *Sandbox) Check(path string) error {
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return fmt.Errorf("invalid path %s: %v", path, err)
+	}
+	for _, root := range s.roots {
+		if resolved == root || strings.HasPrefix(resolved, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("path %s is outside the workspace", path)
+}
+
+// CheckURI checks the path of a file:// URI. Other URIs, such as classes inside a
+// jar, are never in the workspace.
+func (s *Sandbox) CheckURI(uri protocol.DocumentUri) error {
+	if !strings.HasPrefix(string(uri), "file://") {
+		return fmt.Errorf("%s is not a file in the workspace", uri)
+	}
+	return s.Check(strings.TrimPrefix(string(uri), "file://"))
+}
+
+// resolvePath makes a path absolute and resolves symlinks in its longest existing prefix
+func resolvePath(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	var missing []string
+	for {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", err
+		}
+		missing = append([]string{filepath.Base(path)}, missing...)
+		path = parent
+	}
+}
+
+var (
+	editSandbox   *Sandbox
+	editSandboxMu sync.RWMutex
+)
+
+// SetEditSandbox restricts ApplyWorkspaceEdit to files inside the sandbox, nil
+// removes the restriction
+func SetEditSandbox(s *Sandbox) {
+	editSandboxMu.Lock()
+	defer editSandboxMu.Unlock()
+	editSandbox = s
+}
+
+// checkEditSandbox verifies every file a workspace edit touches before any is
+// changed. Files of external dependencies, and documents that are not files such
+// as notebook cells, are refused even without a sandbox.
+func checkEditSandbox(edit protocol.WorkspaceEdit) error {
+	editSandboxMu.RLock()
+	s := editSandbox
+	editSandboxMu.RUnlock()
+
+	var uris []protocol.DocumentUri
+	for uri := range edit.Changes {
+		uris = append(uris, uri)
+	}
+	for _, change := range edit.DocumentChanges {
+		if change.CreateFile != nil {
+			uris = append(uris, change.CreateFile.URI)
+		}
+		if change.DeleteFile != nil {
+			uris = append(uris, change.DeleteFile.URI)
+		}
+		if change.RenameFile != nil {
+			uris = append(uris, change.RenameFile.OldURI, change.RenameFile.NewURI)
+		}
+		if change.TextDocumentEdit != nil {
+			uris = append(uris, change.TextDocumentEdit.TextDocument.URI)
+		}
+	}
+	for _, uri := range uris {
+		if !strings.HasPrefix(string(uri), "file://") {
+			return fmt.Errorf("refusing workspace edit: %s is not a file on disk", uri)
+		}
+		if path := strings.TrimPrefix(string(uri), "file://"); IsDependencyPath(path) {
+			return fmt.Errorf("refusing workspace edit: %s is an external dependency and read-only", path)
+		}
+		if s == nil {
+			continue
+		}
+		if err := s.CheckURI(uri); err != nil {
+			return fmt.Errorf("refusing workspace edit: %w", err)
+		}
+	}
+	return nil
+}
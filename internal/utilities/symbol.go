@@ -3,6 +3,7 @@ package utilities
 import (
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 )
@@ -105,3 +106,29 @@ func ExtractSymbolKind(sym protocol.DocumentSymbolResult) string {
 
 	return kindStr
 }
+
+// ParseSymbolKind converts a symbol kind name such as "Function" or "[Method]" to a SymbolKind.
+// Matching is case-insensitive.
+func ParseSymbolKind(name string) (protocol.SymbolKind, bool) {
+	name = strings.ToLower(strings.Trim(strings.TrimSpace(name), "[]"))
+	for kind := protocol.SymbolKind(1); kind <= protocol.TypeParameter; kind++ {
+		if strings.ToLower(strings.Trim(GetSymbolKindString(kind), "[]")) == name {
+			return kind, true
+		}
+	}
+	return 0, false
+}
+
+// ParseSymbolKinds converts a list of kind names to a set of SymbolKinds.
+// It returns an error naming the first kind that is not recognized.
+func ParseSymbolKinds(names []string) (map[protocol.SymbolKind]bool, error) {
+	kinds := make(map[protocol.SymbolKind]bool, len(names))
+	for _, name := range names {
+		kind, ok := ParseSymbolKind(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown symbol kind: %s", name)
+		}
+		kinds[kind] = true
+	}
+	return kinds, nil
+}
@@ -0,0 +1,76 @@
+package watcher
+
+import (
+	"maps"
+	"strings"
+	"time"
+)
+
+// Config holds the watcher's exclusion rules and timing
+type Config struct {
+	// Directory names that are never watched or opened
+	ExcludedDirNames map[string]bool
+	// File extensions, with leading dot, that are never opened
+	ExcludedFileExtensions map[string]bool
+	// Files larger than this many bytes are never opened
+	MaxFileSize int64
+	// How long to wait for further events on a file before notifying the server
+	DebounceTime time.Duration
+	// How often directories that cannot be watched natively are scanned, zero disables polling
+	PollInterval time.Duration
+	// Whether symlinked directories are walked and watched
+	FollowSymlinks bool
+}
+
+// DefaultConfig returns the built-in exclusion rules
+func DefaultConfig() Config {
+	extensions := maps.Clone(excludedFileExtensions)
+	maps.Copy(extensions, largeBinaryExtensions)
+	return Config{
+		ExcludedDirNames:       maps.Clone(excludedDirNames),
+		ExcludedFileExtensions: extensions,
+		MaxFileSize:            maxFileSize,
+		DebounceTime:           300 * time.Millisecond,
+		PollInterval:           5 * time.Second,
+	}
+}
+
+// UpdateExcludedDirs adds directory names to the exclusions, or removes
+// them when prefixed with "!" (e.g. "third_party" or "!vendor")
+func (c *Config) UpdateExcludedDirs(patterns []string) {
+	updateSet(c.ExcludedDirNames, patterns, func(name string) string {
+		return strings.Trim(name, "/")
+	})
+}
+
+// UpdateExcludedFileExtensions adds file extensions to the exclusions, or removes
+// them when prefixed with "!" (e.g. ".pb" or "!.log")
+func (c *Config) UpdateExcludedFileExtensions(patterns []string) {
+	updateSet(c.ExcludedFileExtensions, patterns, func(ext string) string {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		return ext
+	})
+}
+
+func updateSet(set map[string]bool, patterns []string, normalize func(string) string) {
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if name, ok := strings.CutPrefix(pattern, "!"); ok {
+			delete(set, normalize(name))
+		} else {
+			set[normalize(pattern)] = true
+		}
+	}
+}
+
+// SetConfig replaces the watcher's exclusion rules and timing.
+// It must be called before WatchWorkspace.
+func (w *WorkspaceWatcher) SetConfig(config Config) {
+	w.config = config
+}
@@ -0,0 +1,129 @@
+package watcher
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+// sniffLength is how much of a file is read to decide whether it is binary, the
+// same amount git looks at
+const sniffLength = 8000
+
+// SkipReason says why a file that is not excluded by name is still not opened
+type SkipReason string
+
+const (
+	SkipTooLarge SkipReason = "too large"
+	SkipBinary   SkipReason = "binary"
+)
+
+// SkippedFileHandler is called once for each file skipped for its size or content,
+// and again if the file changes and is still skipped
+type SkippedFileHandler func(path string, reason SkipReason, size int64)
+
+// contentCheck caches the result of checkContent until the file changes
+type contentCheck struct {
+	modTime time.Time
+	size    int64
+	skip    SkipReason
+}
+
+// AddSkippedFileHandler registers a handler for files skipped because of their size
+// or content
+func (w *WorkspaceWatcher) AddSkippedFileHandler(handler SkippedFileHandler) {
+	w.skippedFileHandlersMu.Lock()
+	defer w.skippedFileHandlersMu.Unlock()
+	w.skippedFileHandlers = append(w.skippedFileHandlers, handler)
+}
+
+// checkContent returns why a file should not be opened: it is larger than the
+// configured limit, or it has no known language and its content looks binary. It is
+// empty for files that can be opened.
+func (w *WorkspaceWatcher) checkContent(path string, info os.FileInfo) SkipReason {
+	w.contentChecksMu.Lock()
+	check, cached := w.contentChecks[path]
+	w.contentChecksMu.Unlock()
+	if cached && check.modTime.Equal(info.ModTime()) && check.size == info.Size() {
+		return check.skip
+	}
+
+	check = contentCheck{modTime: info.ModTime(), size: info.Size()}
+	switch {
+	case w.config.MaxFileSize > 0 && info.Size() > w.config.MaxFileSize:
+		check.skip = SkipTooLarge
+	case lsp.DetectLanguageID(path) == "" && isBinaryFile(path):
+		// Files of known languages are text, only the others are read
+		check.skip = SkipBinary
+	}
+
+	w.contentChecksMu.Lock()
+	w.contentChecks[path] = check
+	w.contentChecksMu.Unlock()
+
+	if check.skip != "" {
+		if debug {
+			log.Printf("Skipping %s file: %s (%.2f MB)", check.skip, path, float64(info.Size())/(1024*1024))
+		}
+		w.skippedFileHandlersMu.RLock()
+		handlers := w.skippedFileHandlers
+		w.skippedFileHandlersMu.RUnlock()
+		for _, handler := range handlers {
+			handler(path, check.skip, info.Size())
+		}
+	}
+	return check.skip
+}
+
+// forgetContentCheck drops the cached check of a file that no longer exists
+func (w *WorkspaceWatcher) forgetContentCheck(path string) {
+	w.contentChecksMu.Lock()
+	defer w.contentChecksMu.Unlock()
+	delete(w.contentChecks, path)
+}
+
+// skippedCounts returns the number of files currently skipped for each reason
+func (w *WorkspaceWatcher) skippedCounts() map[SkipReason]int {
+	w.contentChecksMu.Lock()
+	defer w.contentChecksMu.Unlock()
+	counts := make(map[SkipReason]int)
+	for _, check := range w.contentChecks {
+		if check.skip != "" {
+			counts[check.skip]++
+		}
+	}
+	return counts
+}
+
+// isBinaryFile reports whether the start of a file contains a NUL byte or is
+// recognized as a non-text format, such as an image or an archive
+func isBinaryFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	buf := make([]byte, sniffLength)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false
+	}
+	return isBinaryContent(buf[:n])
+}
+
+func isBinaryContent(content []byte) bool {
+	if len(content) == 0 {
+		return false
+	}
+	if bytes.IndexByte(content, 0) != -1 {
+		return true
+	}
+	return !strings.HasPrefix(http.DetectContentType(content), "text/")
+}
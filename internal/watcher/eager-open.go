@@ -0,0 +1,41 @@
+package watcher
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EagerOpenMode controls whether files matching the server's watch registrations
+// are opened as soon as the registrations arrive
+type EagerOpenMode string
+
+const (
+	// EagerOpenAuto only opens a file of each project for servers that load whole
+	// projects, see openProjects
+	EagerOpenAuto EagerOpenMode = "auto"
+	// EagerOpenAlways opens matching files for every server
+	EagerOpenAlways EagerOpenMode = "always"
+	// EagerOpenNever leaves files closed until a tool needs them
+	EagerOpenNever EagerOpenMode = "never"
+)
+
+// ParseEagerOpenMode validates an eager open mode from the command line
+func ParseEagerOpenMode(mode string) (EagerOpenMode, error) {
+	switch m := EagerOpenMode(strings.ToLower(mode)); m {
+	case EagerOpenAuto, EagerOpenAlways, EagerOpenNever:
+		return m, nil
+	}
+	return "", fmt.Errorf("invalid eager open mode %q (expected auto, always or never)", mode)
+}
+
+// SetEagerOpen configures the scan run after watch registrations. maxFiles caps the
+// number of files the scan opens, zero means only the client's open file limit applies.
+func (w *WorkspaceWatcher) SetEagerOpen(mode EagerOpenMode, maxFiles int) {
+	w.eagerOpen = mode
+	w.eagerOpenMaxFiles = max(maxFiles, 0)
+}
+
+// shouldEagerOpen resolves the eager open mode for the running language server
+func (w *WorkspaceWatcher) shouldEagerOpen() bool {
+	return w.eagerOpen == EagerOpenAlways
+}
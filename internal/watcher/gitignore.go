@@ -0,0 +1,278 @@
+package watcher
+
+import (
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	gitignore "github.com/sabhiram/go-gitignore"
+)
+
+// ignoreFile is a compiled gitignore file whose patterns are relative to base
+type ignoreFile struct {
+	path string
+	base string // Directory relative to the workspace root, "" for the root
+	// matcher reports ignored paths, negated holds the "!" patterns on their own
+	// so that an explicit re-include can override a less specific file
+	matcher *gitignore.GitIgnore
+	negated *gitignore.GitIgnore
+}
+
+// ignoreRules evaluates gitignore rules the way git does: .gitignore files in every
+// directory, with deeper files taking precedence, then .git/info/exclude and the
+// user's global excludes file. A .mcpignore file at the root overrides them all.
+type ignoreRules struct {
+	root string
+
+	// .mcpignore at the workspace root
+	override   *ignoreFile
+	overrideMu sync.RWMutex
+
+	// .gitignore files by directory relative to root, nil when a directory has none
+	dirs   map[string]*ignoreFile
+	dirsMu sync.RWMutex
+
+	// .git/info/exclude and the global excludes file, in order of precedence
+	globalPaths []string
+	global      []*ignoreFile
+	globalMu    sync.RWMutex
+}
+
+// mcpIgnoreFile is the name of the ignore file that overrides git's rules
+const mcpIgnoreFile = ".mcpignore"
+
+func newIgnoreRules(root string) *ignoreRules {
+	r := &ignoreRules{
+		root: root,
+		dirs: make(map[string]*ignoreFile),
+	}
+	for _, path := range []string{filepath.Join(root, ".git", "info", "exclude"), globalExcludesFile(root)} {
+		if path != "" {
+			r.globalPaths = append(r.globalPaths, path)
+		}
+	}
+	r.loadGlobal()
+	r.override = loadIgnoreFile(filepath.Join(root, mcpIgnoreFile), "")
+	return r
+}
+
+func (r *ignoreRules) loadGlobal() {
+	var global []*ignoreFile
+	for _, path := range r.globalPaths {
+		if f := loadIgnoreFile(path, ""); f != nil {
+			global = append(global, f)
+		}
+	}
+	r.globalMu.Lock()
+	r.global = global
+	r.globalMu.Unlock()
+}
+
+// isIgnoreFile reports whether a change to path can change the rules
+func (r *ignoreRules) isIgnoreFile(path string) bool {
+	if filepath.Base(path) == ".gitignore" || path == filepath.Join(r.root, mcpIgnoreFile) {
+		return true
+	}
+	for _, globalPath := range r.globalPaths {
+		if path == globalPath {
+			return true
+		}
+	}
+	return false
+}
+
+// reload recompiles the rules from an ignore file that was created, changed or removed
+func (r *ignoreRules) reload(path string) {
+	switch {
+	case path == filepath.Join(r.root, mcpIgnoreFile):
+		f := loadIgnoreFile(path, "")
+		r.overrideMu.Lock()
+		r.override = f
+		r.overrideMu.Unlock()
+	case filepath.Base(path) == ".gitignore":
+		relDir, err := filepath.Rel(r.root, filepath.Dir(path))
+		if err != nil {
+			return
+		}
+		if relDir == "." {
+			relDir = ""
+		}
+		// Loaded again on next use
+		r.dirsMu.Lock()
+		delete(r.dirs, filepath.ToSlash(relDir))
+		r.dirsMu.Unlock()
+	default:
+		r.loadGlobal()
+	}
+}
+
+// ignored reports whether a path is ignored by itself or through an ignored parent directory
+func (r *ignoreRules) ignored(path string, isDir bool) bool {
+	relPath, err := filepath.Rel(r.root, path)
+	if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+		return false
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	// Files in an ignored directory cannot be re-included
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		if r.matches(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return r.matches(relPath, isDir)
+}
+
+// included reports whether .mcpignore explicitly re-includes a path with a "!" pattern,
+// which takes precedence over git's rules and the built-in exclusions
+func (r *ignoreRules) included(path string, isDir bool) bool {
+	r.overrideMu.RLock()
+	override := r.override
+	r.overrideMu.RUnlock()
+	if override == nil {
+		return false
+	}
+
+	relPath, err := filepath.Rel(r.root, path)
+	if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+		return false
+	}
+	decided, ignored := override.match(filepath.ToSlash(relPath), isDir)
+	return decided && !ignored
+}
+
+// matches checks a path against the most specific ignore file with a matching pattern
+func (r *ignoreRules) matches(relPath string, isDir bool) bool {
+	r.overrideMu.RLock()
+	override := r.override
+	r.overrideMu.RUnlock()
+	if override != nil {
+		if decided, ignored := override.match(relPath, isDir); decided {
+			return ignored
+		}
+	}
+
+	dir := relPath
+	for dir != "" {
+		dir = parentDir(dir)
+		if f := r.dirIgnoreFile(dir); f != nil {
+			if decided, ignored := f.match(relPath, isDir); decided {
+				return ignored
+			}
+		}
+	}
+	r.globalMu.RLock()
+	global := r.global
+	r.globalMu.RUnlock()
+	for _, f := range global {
+		if decided, ignored := f.match(relPath, isDir); decided {
+			return ignored
+		}
+	}
+	return false
+}
+
+// dirIgnoreFile returns the .gitignore of a directory, loading it on first use
+func (r *ignoreRules) dirIgnoreFile(dir string) *ignoreFile {
+	r.dirsMu.RLock()
+	f, loaded := r.dirs[dir]
+	r.dirsMu.RUnlock()
+	if loaded {
+		return f
+	}
+
+	f = loadIgnoreFile(filepath.Join(r.root, filepath.FromSlash(dir), ".gitignore"), dir)
+
+	r.dirsMu.Lock()
+	r.dirs[dir] = f
+	r.dirsMu.Unlock()
+	return f
+}
+
+// match reports whether the file has a pattern for the path, and if so whether it ignores it
+func (f *ignoreFile) match(relPath string, isDir bool) (decided bool, ignored bool) {
+	if f.base != "" {
+		relPath = strings.TrimPrefix(relPath, f.base+"/")
+	}
+	// Directory patterns like "build/" only match with a trailing slash
+	if isDir {
+		relPath += "/"
+	}
+	if f.matcher.MatchesPath(relPath) {
+		return true, true
+	}
+	if f.negated != nil && f.negated.MatchesPath(relPath) {
+		return true, false
+	}
+	return false, false
+}
+
+// loadIgnoreFile compiles a gitignore file, returning nil if it does not exist
+func loadIgnoreFile(path string, base string) *ignoreFile {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Error reading ignore file %s: %v", path, err)
+		}
+		return nil
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	var negatedLines []string
+	for _, line := range lines {
+		if pattern, ok := strings.CutPrefix(strings.TrimSpace(line), "!"); ok {
+			negatedLines = append(negatedLines, pattern)
+		}
+	}
+
+	f := &ignoreFile{
+		path:    path,
+		base:    base,
+		matcher: gitignore.CompileIgnoreLines(lines...),
+	}
+	if len(negatedLines) > 0 {
+		f.negated = gitignore.CompileIgnoreLines(negatedLines...)
+	}
+	if debug {
+		log.Printf("Loaded ignore file %s", path)
+	}
+	return f
+}
+
+// globalExcludesFile returns the path of the user's global gitignore: core.excludesFile
+// if set, otherwise git's default location
+func globalExcludesFile(root string) string {
+	cmd := exec.Command("git", "config", "--get", "core.excludesFile")
+	cmd.Dir = root
+	if out, err := cmd.Output(); err == nil {
+		path := strings.TrimSpace(string(out))
+		if rest, ok := strings.CutPrefix(path, "~/"); ok {
+			if home, err := os.UserHomeDir(); err == nil {
+				path = filepath.Join(home, rest)
+			}
+		}
+		if path != "" {
+			return path
+		}
+	}
+
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); configHome != "" {
+		return filepath.Join(configHome, "git", "ignore")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".config", "git", "ignore")
+	}
+	return ""
+}
+
+// parentDir returns the parent of a slash separated relative path, "" for the root
+func parentDir(relPath string) string {
+	if i := strings.LastIndex(relPath, "/"); i >= 0 {
+		return relPath[:i]
+	}
+	return ""
+}
@@ -0,0 +1,115 @@
+package watcher
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// MatchesGlob reports whether a slash-separated path matches a glob pattern
+func MatchesGlob(pattern, path string) bool {
+	return matchesGlob(pattern, filepath.ToSlash(path))
+}
+
+// matchesGlob matches a path against an LSP glob pattern:
+//
+//   - `*` matches zero or more characters in a path segment
+//   - `?` matches one character in a path segment
+//   - `**` matches any number of path segments, including none
+//   - `{a,b}` matches any of the alternatives, which may be nested
+//   - `[a-z]` matches a character in a range, `[!a-z]` one outside it
+func matchesGlob(pattern, path string) bool {
+	for _, alternative := range expandBraces(pattern) {
+		if matchSegments(strings.Split(alternative, "/"), strings.Split(path, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches path segments against pattern segments, where a "**"
+// segment matches any number of path segments
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Collapse repeated ** and try every split of the remaining segments
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := range segments {
+				if matchSegments(pattern, segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(segments) == 0 || !matchSegment(pattern[0], segments[0]) {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// matchSegment matches a single path segment, "**" inside a segment acts like "*"
+func matchSegment(pattern, segment string) bool {
+	pattern = strings.ReplaceAll(pattern, "**", "*")
+	// Glob character classes are negated with "!", path.Match uses "^"
+	pattern = strings.ReplaceAll(pattern, "[!", "[^")
+	matched, err := path.Match(pattern, segment)
+	return err == nil && matched
+}
+
+// expandBraces expands {a,b} groups, including nested ones, into every alternative.
+// Unbalanced braces are matched literally.
+func expandBraces(pattern string) []string {
+	start := strings.IndexByte(pattern, '{')
+	if start == -1 {
+		return []string{pattern}
+	}
+
+	// Find the matching close brace and the top level commas in between
+	depth := 0
+	commas := []int{}
+	end := -1
+	for i := start; i < len(pattern) && end == -1; i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				end = i
+			}
+		case ',':
+			if depth == 1 {
+				commas = append(commas, i)
+			}
+		}
+	}
+	if end == -1 {
+		return []string{pattern}
+	}
+
+	prefix, suffix := pattern[:start], pattern[end+1:]
+	var alternatives []string
+	from := start + 1
+	for _, to := range append(commas, end) {
+		alternatives = append(alternatives, pattern[from:to])
+		from = to + 1
+	}
+
+	var expanded []string
+	for _, suffixAlternative := range expandBraces(suffix) {
+		for _, alternative := range alternatives {
+			for _, inner := range expandBraces(alternative) {
+				expanded = append(expanded, prefix+inner+suffixAlternative)
+			}
+		}
+	}
+	return expanded
+}
@@ -0,0 +1,74 @@
+package watcher
+
+import "testing"
+
+func TestMatchesGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		// Wildcards within a segment
+		{"*.go", "main.go", true},
+		{"*.go", "internal/main.go", false},
+		{"*.go", "main.go.bak", false},
+		{"*", "", true},
+		{"?.ts", "a.ts", true},
+		{"?.ts", "ab.ts", false},
+		{"main.*", "main.go", true},
+
+		// Globstar
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "internal/watcher/watcher.go", true},
+		{"**/*.go", "internal/watcher/watcher.go.orig", false},
+		{"**", "a/b/c", true},
+		{"src/**", "src/a/b.ts", true},
+		{"src/**", "lib/a.ts", false},
+		{"src/**/test/*.ts", "src/test/a.ts", true},
+		{"src/**/test/*.ts", "src/a/b/test/c.ts", true},
+		{"src/**/test/*.ts", "src/a/test/b/c.ts", false},
+		{"src/**/test/*.ts", "src/a/mytest/c.ts", false},
+		{"**/node_modules/**", "a/node_modules/b/c.js", true},
+		{"**/node_modules/**", "a/node_modules_old/c.js", false},
+		{"**/**/*.go", "a/b.go", true},
+		{"a/**b", "a/xb", true},
+
+		// Braces
+		{"*.{go,mod,sum}", "go.mod", true},
+		{"*.{go,mod,sum}", "go.work", false},
+		{"**/*.{ts,tsx}", "src/app.tsx", true},
+		{"{src,lib}/**/*.js", "lib/a/b.js", true},
+		{"{src,lib}/**/*.js", "test/a.js", false},
+		{"*.{j{s,sx},ts}", "a.jsx", true},
+		{"*.{j{s,sx},ts}", "a.js", true},
+		{"*.{j{s,sx},ts}", "a.j", false},
+		{"{a,b}/{c,d}.txt", "b/d.txt", true},
+		{"*.{go", "x.{go", true},
+
+		// Character classes
+		{"file[0-9].txt", "file7.txt", true},
+		{"file[0-9].txt", "filex.txt", false},
+		{"file[!0-9].txt", "filex.txt", true},
+		{"file[!0-9].txt", "file7.txt", false},
+		{"[abc]/*.go", "b/x.go", true},
+	}
+
+	for _, tt := range tests {
+		if got := MatchesGlob(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("MatchesGlob(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestExpandBraces(t *testing.T) {
+	got := expandBraces("a{b,c{d,e}}f")
+	want := []string{"abf", "acdf", "acef"}
+	if len(got) != len(want) {
+		t.Fatalf("expandBraces = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expandBraces = %v, want %v", got, want)
+		}
+	}
+}
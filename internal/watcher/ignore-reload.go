@@ -0,0 +1,94 @@
+package watcher
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// scheduleIgnoreReload reloads the ignore rules once an ignore file stops changing,
+// then brings watches and open documents in line with the new rules
+func (w *WorkspaceWatcher) scheduleIgnoreReload(ctx context.Context, fsw *fsnotify.Watcher, path string) {
+	w.ignoreReloadMu.Lock()
+	defer w.ignoreReloadMu.Unlock()
+
+	if w.ignoreReloadPaths == nil {
+		w.ignoreReloadPaths = make(map[string]bool)
+	}
+	w.ignoreReloadPaths[path] = true
+
+	if w.ignoreReloadTimer != nil {
+		w.ignoreReloadTimer.Stop()
+	}
+	w.ignoreReloadTimer = time.AfterFunc(w.config.DebounceTime, func() {
+		w.ignoreReloadMu.Lock()
+		paths := w.ignoreReloadPaths
+		w.ignoreReloadPaths = nil
+		w.ignoreReloadMu.Unlock()
+
+		for path := range paths {
+			log.Printf("Reloading ignore rules from %s", path)
+			w.ignore.reload(path)
+		}
+		w.applyIgnoreRules(ctx, fsw)
+	})
+}
+
+// applyIgnoreRules removes watches from newly ignored directories, watches newly
+// included ones and closes documents that are now ignored
+func (w *WorkspaceWatcher) applyIgnoreRules(ctx context.Context, fsw *fsnotify.Watcher) {
+	watched := make(map[string]bool)
+	for _, path := range fsw.WatchList() {
+		watched[path] = true
+	}
+
+	keep := map[string]bool{
+		filepath.Join(w.workspacePath, ".git", "info"): true,
+	}
+	added := 0
+	err := w.WalkWorkspace(func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != w.workspacePath && w.shouldExcludeDir(path) {
+			return filepath.SkipDir
+		}
+		keep[path] = true
+		if !watched[path] && !w.poller.polling(path) {
+			w.addWatch(fsw, path)
+			added++
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error walking workspace: %v", err)
+	}
+
+	removed := w.poller.removeUnless(keep)
+	for path := range watched {
+		if keep[path] {
+			continue
+		}
+		if err := fsw.Remove(path); err != nil && debug {
+			log.Printf("Error removing watch for %s: %v", path, err)
+		}
+		removed++
+	}
+
+	closed := 0
+	for _, path := range w.client.OpenFiles() {
+		if w.shouldExcludeFile(path) {
+			if err := w.client.CloseFile(ctx, path); err != nil {
+				log.Printf("Error closing ignored file %s: %v", path, err)
+				continue
+			}
+			closed++
+		}
+	}
+
+	log.Printf("Applied ignore rules: watching %d more and %d fewer directories, closed %d files", added, removed, closed)
+}
@@ -0,0 +1,94 @@
+package watcher
+
+import (
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// DefaultJournalSize is the number of file events kept in the change journal
+const DefaultJournalSize = 1000
+
+// JournalEntry is a file event the watcher processed
+type JournalEntry struct {
+	// Seq increases by one for every recorded event
+	Seq  uint64
+	Path string
+	Type protocol.FileChangeType
+	Time time.Time
+}
+
+// Journal is a bounded, in-memory log of workspace file events
+type Journal struct {
+	mu      sync.RWMutex
+	entries []JournalEntry // Ring buffer, oldest entry at start
+	start   int
+	lastSeq uint64
+}
+
+// NewJournal creates a journal keeping the last size events
+func NewJournal(size int) *Journal {
+	return &Journal{entries: make([]JournalEntry, 0, max(size, 1))}
+}
+
+// Record appends an event, dropping the oldest one when the journal is full
+func (j *Journal) Record(path string, changeType protocol.FileChangeType) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.lastSeq++
+	entry := JournalEntry{Seq: j.lastSeq, Path: path, Type: changeType, Time: time.Now()}
+	if len(j.entries) < cap(j.entries) {
+		j.entries = append(j.entries, entry)
+		return
+	}
+	j.entries[j.start] = entry
+	j.start = (j.start + 1) % len(j.entries)
+}
+
+// Since returns the events after seq that happened at or after since, oldest first.
+// complete is false when older matching events were already dropped from the journal.
+func (j *Journal) Since(seq uint64, since time.Time) (entries []JournalEntry, complete bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	complete = true
+	for i := range j.entries {
+		entry := j.entries[(j.start+i)%len(j.entries)]
+		if i == 0 && entry.Seq > seq+1 && !entry.Time.Before(since) {
+			complete = false
+		}
+		if entry.Seq > seq && !entry.Time.Before(since) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, complete
+}
+
+// Len returns the number of events in the journal
+func (j *Journal) Len() int {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return len(j.entries)
+}
+
+// LastSeq returns the sequence number of the latest event, 0 if there is none
+func (j *Journal) LastSeq() uint64 {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.lastSeq
+}
+
+// ChangeTypeName returns the lower case name of a file change type
+func ChangeTypeName(changeType protocol.FileChangeType) string {
+	switch changeType {
+	case protocol.FileChangeType(protocol.Created):
+		return "created"
+	case protocol.FileChangeType(protocol.Changed):
+		return "changed"
+	case protocol.FileChangeType(protocol.Deleted):
+		return "deleted"
+	}
+	return "unknown"
+}
@@ -0,0 +1,153 @@
+package watcher
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+// OpenPacing limits how fast the workspace scan opens files, so a server that is
+// still indexing or slow to read its input is not flooded with didOpen
+type OpenPacing struct {
+	// Files opened per second at most, zero for no limit
+	MaxRate int
+	// The scan pauses while this many requests wait for a response, zero to ignore
+	MaxInFlight int
+	// Average request latency above which the scan slows down
+	SlowLatency time.Duration
+}
+
+const (
+	// slowWriteStall is the average time writes to the server may block before the
+	// scan slows down, a server reading its input keeps it near zero
+	slowWriteStall = 5 * time.Millisecond
+
+	// maxOpenInterval is the longest the scan waits between two files when slowed down
+	maxOpenInterval = 100 * time.Millisecond
+
+	// maxInFlightWait bounds each pause for outstanding requests, so one stuck request
+	// does not stop the scan
+	maxInFlightWait = 5 * time.Second
+
+	inFlightPollInterval = 20 * time.Millisecond
+)
+
+// defaultOpenPacing applies to servers without an entry in serverOpenPacing
+var defaultOpenPacing = OpenPacing{
+	MaxRate:     1000,
+	MaxInFlight: 8,
+	SlowLatency: time.Second,
+}
+
+// serverOpenPacing holds the pacing for servers that need gentler scans, keyed by
+// command name prefix
+var serverOpenPacing = map[string]OpenPacing{
+	// tsserver handles requests one at a time and rebuilds the project on each open
+	"typescript-language-server": {MaxRate: 200, MaxInFlight: 2, SlowLatency: 2 * time.Second},
+	// jdtls builds the workspace on open and answers slowly while it does
+	"jdtls": {MaxRate: 100, MaxInFlight: 2, SlowLatency: 3 * time.Second},
+}
+
+// SetOpenPacing overrides the pacing of the workspace scan. Zero fields keep the
+// default for the running server.
+func (w *WorkspaceWatcher) SetOpenPacing(pacing OpenPacing) {
+	w.openPacing = pacing
+}
+
+// resolveOpenPacing returns the pacing for the running language server, with the
+// fields set by SetOpenPacing taking precedence
+func (w *WorkspaceWatcher) resolveOpenPacing() OpenPacing {
+	pacing := defaultOpenPacing
+	command := strings.ToLower(filepath.Base(w.client.Cmd.Path))
+	for server, serverPacing := range serverOpenPacing {
+		if strings.HasPrefix(command, server) {
+			pacing = serverPacing
+			break
+		}
+	}
+
+	if w.openPacing.MaxRate > 0 {
+		pacing.MaxRate = w.openPacing.MaxRate
+	}
+	if w.openPacing.MaxInFlight > 0 {
+		pacing.MaxInFlight = w.openPacing.MaxInFlight
+	}
+	if w.openPacing.SlowLatency > 0 {
+		pacing.SlowLatency = w.openPacing.SlowLatency
+	}
+	return pacing
+}
+
+// openPacer spaces out the files opened by one scan. The interval between files
+// starts at the one allowed by MaxRate, grows while the server is slow to answer or
+// to read its input and shrinks again once it keeps up.
+type openPacer struct {
+	client   *lsp.Client
+	pacing   OpenPacing
+	minimum  time.Duration
+	interval time.Duration
+	last     time.Time
+	// Time spent waiting beyond MaxRate, for the scan summary
+	throttled time.Duration
+}
+
+func newOpenPacer(client *lsp.Client, pacing OpenPacing) *openPacer {
+	p := &openPacer{client: client, pacing: pacing}
+	if pacing.MaxRate > 0 {
+		p.minimum = time.Second / time.Duration(pacing.MaxRate)
+	}
+	p.interval = p.minimum
+	return p
+}
+
+// wait blocks until the next file may be opened
+func (p *openPacer) wait(ctx context.Context) error {
+	start := time.Now()
+
+	load := p.client.Load()
+	if p.pacing.MaxInFlight > 0 && load.InFlight >= p.pacing.MaxInFlight {
+		deadline := time.Now().Add(maxInFlightWait)
+		for load.InFlight >= p.pacing.MaxInFlight && time.Now().Before(deadline) {
+			if err := sleepContext(ctx, inFlightPollInterval); err != nil {
+				return err
+			}
+			load = p.client.Load()
+		}
+	}
+
+	slow := load.WriteStall > slowWriteStall || (p.pacing.SlowLatency > 0 && load.Latency > p.pacing.SlowLatency)
+	if slow {
+		// Spacing files by the time writes block matches the rate the server reads at
+		p.interval = min(max(p.interval+p.interval/2, load.WriteStall, time.Millisecond), maxOpenInterval)
+	} else {
+		p.interval = max(p.interval*3/4, p.minimum)
+	}
+
+	if !p.last.IsZero() {
+		if err := sleepContext(ctx, time.Until(p.last.Add(p.interval))); err != nil {
+			return err
+		}
+	}
+	p.last = time.Now()
+	if waited := time.Since(start); waited > p.minimum {
+		p.throttled += waited - p.minimum
+	}
+	return nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
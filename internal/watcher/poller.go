@@ -0,0 +1,202 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// fileState is what the poller compares between scans of a directory
+type fileState struct {
+	modTime time.Time
+	size    int64
+	isDir   bool
+}
+
+// poller periodically scans directories that could not be watched natively, for
+// example when inotify watches are exhausted, and reports changes as fsnotify events
+type poller struct {
+	interval time.Duration
+	events   chan fsnotify.Event
+
+	// Last scan of each polled directory, by entry name
+	dirs   map[string]map[string]fileState
+	dirsMu sync.Mutex
+
+	warnOnce sync.Once
+}
+
+func newPoller(interval time.Duration) *poller {
+	return &poller{
+		interval: interval,
+		events:   make(chan fsnotify.Event, 100),
+		dirs:     make(map[string]map[string]fileState),
+	}
+}
+
+// addWatch watches a directory with fsnotify, falling back to polling when the
+// system is out of watches or file descriptors
+func (w *WorkspaceWatcher) addWatch(fsw *fsnotify.Watcher, path string) {
+	err := fsw.Add(path)
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, syscall.ENOSPC) && !errors.Is(err, syscall.EMFILE) {
+		log.Printf("Error watching path %s: %v", path, err)
+		return
+	}
+
+	w.poller.warnOnce.Do(func() {
+		if w.poller.interval > 0 {
+			log.Printf("Warning: out of file watches (%v), polling unwatched directories every %s instead. "+
+				"On Linux, raise the limit with: sysctl fs.inotify.max_user_watches=524288", err, w.poller.interval)
+		} else {
+			log.Printf("Warning: out of file watches (%v) and polling is disabled, changes in some directories will be missed. "+
+				"On Linux, raise the limit with: sysctl fs.inotify.max_user_watches=524288", err)
+		}
+	})
+	if w.poller.interval > 0 {
+		w.poller.add(path)
+	}
+}
+
+// add starts polling a directory
+func (p *poller) add(dir string) {
+	state := scanDir(dir)
+
+	p.dirsMu.Lock()
+	defer p.dirsMu.Unlock()
+	if _, exists := p.dirs[dir]; !exists {
+		p.dirs[dir] = state
+		if debug {
+			log.Printf("Polling directory: %s", dir)
+		}
+	}
+}
+
+// polling reports whether a directory is polled
+func (p *poller) polling(dir string) bool {
+	p.dirsMu.Lock()
+	defer p.dirsMu.Unlock()
+	_, exists := p.dirs[dir]
+	return exists
+}
+
+// removeUnless stops polling directories not in keep and returns how many were dropped
+func (p *poller) removeUnless(keep map[string]bool) int {
+	p.dirsMu.Lock()
+	defer p.dirsMu.Unlock()
+
+	removed := 0
+	for dir := range p.dirs {
+		if !keep[dir] {
+			delete(p.dirs, dir)
+			removed++
+		}
+	}
+	return removed
+}
+
+// run scans the polled directories every interval until ctx is done
+func (p *poller) run(ctx context.Context) {
+	if p.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.scan(ctx)
+		}
+	}
+}
+
+// scan compares each polled directory with its last scan and sends the differences
+func (p *poller) scan(ctx context.Context) {
+	p.dirsMu.Lock()
+	dirs := make([]string, 0, len(p.dirs))
+	for dir := range p.dirs {
+		dirs = append(dirs, dir)
+	}
+	p.dirsMu.Unlock()
+
+	for _, dir := range dirs {
+		var events []fsnotify.Event
+
+		if _, err := os.Stat(dir); err != nil {
+			// Removal is reported by the parent directory
+			p.dirsMu.Lock()
+			delete(p.dirs, dir)
+			p.dirsMu.Unlock()
+			continue
+		}
+		current := scanDir(dir)
+
+		p.dirsMu.Lock()
+		previous, exists := p.dirs[dir]
+		if exists {
+			p.dirs[dir] = current
+		}
+		p.dirsMu.Unlock()
+		if !exists {
+			continue
+		}
+
+		for name, state := range current {
+			path := filepath.Join(dir, name)
+			old, existed := previous[name]
+			switch {
+			case !existed:
+				events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Create})
+			case !state.isDir && (state.modTime != old.modTime || state.size != old.size):
+				events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Write})
+			}
+		}
+		for name := range previous {
+			if _, exists := current[name]; !exists {
+				events = append(events, fsnotify.Event{Name: filepath.Join(dir, name), Op: fsnotify.Remove})
+			}
+		}
+
+		for _, event := range events {
+			select {
+			case p.events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// scanDir records the state of a directory's entries
+func scanDir(dir string) map[string]fileState {
+	state := make(map[string]fileState)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return state
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		state[entry.Name()] = fileState{
+			modTime: info.ModTime(),
+			size:    info.Size(),
+			isDir:   entry.IsDir(),
+		}
+	}
+	return state
+}
@@ -0,0 +1,144 @@
+package watcher
+
+import (
+	"context"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// projectLayout describes the projects of a server that loads a whole project,
+// and searches all of it, once one of its files is open
+type projectLayout struct {
+	// Files marking the root of a project
+	configFiles []string
+	// Extensions of the project's source files
+	extensions []string
+	// Suffixes of files that do not load the project when opened
+	skipSuffixes []string
+}
+
+// projectServers are keyed by command name prefix
+var projectServers = map[string]projectLayout{
+	// tsserver creates a project for each tsconfig.json with an open file, in a
+	// monorepo a file of each package has to be open for references across them
+	"typescript-language-server": {
+		configFiles:  []string{"tsconfig.json", "jsconfig.json"},
+		extensions:   []string{".ts", ".tsx", ".mts", ".cts", ".js", ".jsx", ".mjs", ".cjs"},
+		skipSuffixes: []string{".d.ts", ".d.mts", ".d.cts"},
+	},
+}
+
+// projectLayout returns the layout of the running server's projects, false for
+// servers that search the workspace without open files
+func (w *WorkspaceWatcher) projectLayout() (projectLayout, bool) {
+	for server, layout := range projectServers {
+		if w.client.IsServer(server) {
+			return layout, true
+		}
+	}
+	return projectLayout{}, false
+}
+
+// openProjects opens, and pins, one source file of each project in the workspace,
+// so that servers like tsserver load every project rather than only those of files
+// a tool happened to open. It does nothing for other servers or when eager opening
+// is disabled.
+func (w *WorkspaceWatcher) openProjects(ctx context.Context) {
+	layout, ok := w.projectLayout()
+	if !ok || w.eagerOpen == EagerOpenNever {
+		return
+	}
+
+	roots := w.findProjectRoots(layout)
+	isRoot := make(map[string]bool, len(roots))
+	for _, root := range roots {
+		isRoot[root] = true
+	}
+
+	opened := 0
+	for _, root := range roots {
+		if ctx.Err() != nil {
+			return
+		}
+		path := w.projectSourceFile(root, layout, isRoot)
+		if path == "" {
+			if debug {
+				log.Printf("No source file found for the project in %s", root)
+			}
+			continue
+		}
+		if _, err := w.client.PinFile(ctx, path); err != nil {
+			log.Printf("Error opening %s to load its project: %v", path, err)
+			continue
+		}
+		if debug {
+			log.Printf("Loaded the project in %s by opening %s", root, path)
+		}
+		opened++
+	}
+	if len(roots) > 0 {
+		log.Printf("Loaded %d of %d projects in the workspace", opened, len(roots))
+	}
+}
+
+// findProjectRoots returns the directories holding one of the layout's config files
+func (w *WorkspaceWatcher) findProjectRoots(layout projectLayout) []string {
+	var roots []string
+	err := w.WalkWorkspace(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != w.workspacePath && w.shouldExcludeDir(path) {
+			return filepath.SkipDir
+		}
+		for _, name := range layout.configFiles {
+			if _, err := os.Stat(filepath.Join(path, name)); err == nil {
+				roots = append(roots, path)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error searching the workspace for projects: %v", err)
+	}
+	return roots
+}
+
+// projectSourceFile returns the first source file under root that does not belong
+// to a nested project, "" if there is none
+func (w *WorkspaceWatcher) projectSourceFile(root string, layout projectLayout, isRoot map[string]bool) string {
+	var found string
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && (isRoot[path] || w.shouldExcludeDir(path)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if w.shouldExcludeFile(path) || !hasSuffix(path, layout.extensions) || hasSuffix(path, layout.skipSuffixes) {
+			return nil
+		}
+		found = path
+		return filepath.SkipAll
+	})
+	return found
+}
+
+func hasSuffix(path string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package watcher
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WalkWorkspace walks the workspace like filepath.WalkDir. When following symlinks,
+// symlinked directories are walked too, with paths reported under the link so they
+// stay inside the workspace. Links into the workspace itself, or that would loop,
+// are skipped as their targets are already walked.
+func (w *WorkspaceWatcher) WalkWorkspace(fn fs.WalkDirFunc) error {
+	workspaceReal, err := filepath.EvalSymlinks(w.workspacePath)
+	if err != nil {
+		workspaceReal = w.workspacePath
+	}
+	return w.walk(w.workspacePath, w.workspacePath, []string{workspaceReal}, fn)
+}
+
+// walk walks root, reporting its paths with the display prefix instead of root.
+// walked holds the real paths of the trees being walked, for cycle detection.
+func (w *WorkspaceWatcher) walk(root, display string, walked []string, fn fs.WalkDirFunc) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		displayPath := display + strings.TrimPrefix(path, root)
+		if err != nil || d.Type()&fs.ModeSymlink == 0 {
+			return fn(displayPath, d, err)
+		}
+
+		target, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return fn(displayPath, d, nil) // Broken link
+		}
+		info, err := os.Stat(target)
+		if err != nil || !info.IsDir() {
+			return fn(displayPath, d, nil)
+		}
+
+		if !w.config.FollowSymlinks {
+			if debug {
+				log.Printf("Not following symlinked directory: %s -> %s", displayPath, target)
+			}
+			return nil
+		}
+		for _, dir := range walked {
+			if pathWithin(target, dir) || pathWithin(dir, target) {
+				if debug {
+					log.Printf("Skipping symlinked directory that is already walked: %s -> %s", displayPath, target)
+				}
+				return nil
+			}
+		}
+
+		if err := w.walk(target, displayPath, append(walked, target), fn); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// pathWithin reports whether path is dir or inside it
+func pathWithin(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
@@ -12,8 +12,8 @@ import (
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/metrics"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
-	gitignore "github.com/sabhiram/go-gitignore"
 )
 
 var debug = true // Force debug logging on
@@ -22,24 +22,91 @@ var debug = true // Force debug logging on
 type WorkspaceWatcher struct {
 	client        *lsp.Client
 	workspacePath string
-	gitIgnore     *gitignore.GitIgnore
+	ignore        *ignoreRules
 
-	debounceTime time.Duration
-	debounceMap  map[string]*time.Timer
-	debounceMu   sync.Mutex
+	config Config
+
+	debounceMap map[string]*time.Timer
+	debounceMu  sync.Mutex
+
+	// Native watcher and scanner for directories it could not watch, set by WatchWorkspace
+	fsw    *fsnotify.Watcher
+	poller *poller
+	// Guards fsw and poller being set
+	startMu sync.RWMutex
+
+	// Ignore files changed since the rules were last reloaded
+	ignoreReloadPaths map[string]bool
+	ignoreReloadTimer *time.Timer
+	ignoreReloadMu    sync.Mutex
 
 	// File watchers registered by the server
 	registrations  []protocol.FileSystemWatcher
 	registrationMu sync.RWMutex
+
+	// Whether and how many files to open after watch registrations
+	eagerOpen         EagerOpenMode
+	eagerOpenMaxFiles int
+
+	// Overrides of the scan's pacing, see SetOpenPacing
+	openPacing OpenPacing
+
+	// Recent file events in the workspace
+	journal *Journal
+
+	// Handlers notified of file events in the workspace
+	fileEventHandlers   []FileEventHandler
+	fileEventHandlersMu sync.RWMutex
+
+	// Size and content checks of files, see checkContent
+	contentChecks   map[string]contentCheck
+	contentChecksMu sync.Mutex
+
+	// Handlers notified of files skipped for their size or content
+	skippedFileHandlers   []SkippedFileHandler
+	skippedFileHandlersMu sync.RWMutex
 }
 
+// FileEventHandler is called with the path of a workspace file that was created, changed or deleted
+type FileEventHandler func(path string, changeType protocol.FileChangeType)
+
 // NewWorkspaceWatcher creates a new workspace watcher
 func NewWorkspaceWatcher(client *lsp.Client) *WorkspaceWatcher {
 	return &WorkspaceWatcher{
 		client:        client,
-		debounceTime:  300 * time.Millisecond,
+		config:        DefaultConfig(),
 		debounceMap:   make(map[string]*time.Timer),
+		contentChecks: make(map[string]contentCheck),
 		registrations: []protocol.FileSystemWatcher{},
+		eagerOpen:     EagerOpenAuto,
+		journal:       NewJournal(DefaultJournalSize),
+	}
+}
+
+// AddFileEventHandler registers a handler for file events in the workspace.
+// Events are reported regardless of the server's file watcher registrations.
+func (w *WorkspaceWatcher) AddFileEventHandler(handler FileEventHandler) {
+	w.fileEventHandlersMu.Lock()
+	defer w.fileEventHandlersMu.Unlock()
+	w.fileEventHandlers = append(w.fileEventHandlers, handler)
+}
+
+// Journal returns the log of recent file events in the workspace
+func (w *WorkspaceWatcher) Journal() *Journal {
+	return w.journal
+}
+
+// notifyFileEventHandlers records a file event and calls the registered file event handlers
+func (w *WorkspaceWatcher) notifyFileEventHandlers(path string, changeType protocol.FileChangeType) {
+	w.journal.Record(path, changeType)
+	metrics.Event(ChangeTypeName(changeType))
+
+	w.fileEventHandlersMu.RLock()
+	handlers := w.fileEventHandlers
+	w.fileEventHandlersMu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(path, changeType)
 	}
 }
 
@@ -103,16 +170,24 @@ func (w *WorkspaceWatcher) AddRegistrations(ctx context.Context, id string, watc
 		}
 	}
 
-	// Find and open all existing files that match the newly registered patterns
-	// TODO: not all language servers require this, but typescript does. Make this configurable
+	// Find and open all existing files that match the newly registered patterns.
+	// Servers that load whole projects only need one file of each, see openProjects.
+	if !w.shouldEagerOpen() {
+		return
+	}
 	go func() {
 		startTime := time.Now()
+		filesScanned := 0
 		filesOpened := 0
+		pacer := newOpenPacer(w.client, w.resolveOpenPacing())
 
-		err := filepath.WalkDir(w.workspacePath, func(path string, d os.DirEntry, err error) error {
+		err := w.WalkWorkspace(func(path string, d os.DirEntry, err error) error {
 			if err != nil {
 				return err
 			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 
 			// Skip directories that should be excluded
 			if d.IsDir() {
@@ -123,14 +198,19 @@ func (w *WorkspaceWatcher) AddRegistrations(ctx context.Context, id string, watc
 					return filepath.SkipDir
 				}
 			} else {
-				// Process files
-				w.openMatchingFile(ctx, path)
-				filesOpened++
+				// Files opened past the client's limit would only evict earlier ones
+				if w.client.OpenFileLimitReached() || (w.eagerOpenMaxFiles > 0 && filesOpened >= w.eagerOpenMaxFiles) {
+					if debug {
+						log.Printf("Open file limit reached, stopping workspace scan")
+					}
+					return filepath.SkipAll
+				}
 
-				// Add a small delay after every 100 files to prevent overwhelming the server
-				if filesOpened%100 == 0 {
-					time.Sleep(10 * time.Millisecond)
+				// Process files, paced to what the server keeps up with
+				if w.openMatchingFile(ctx, path, pacer) {
+					filesOpened++
 				}
+				filesScanned++
 			}
 
 			return nil
@@ -138,7 +218,8 @@ func (w *WorkspaceWatcher) AddRegistrations(ctx context.Context, id string, watc
 
 		elapsedTime := time.Since(startTime)
 		if debug {
-			log.Printf("Workspace scan complete: processed %d files in %.2f seconds", filesOpened, elapsedTime.Seconds())
+			log.Printf("Workspace scan complete: opened %d of %d files in %.2f seconds (%.2f seconds slowed down for the server)",
+				filesOpened, filesScanned, elapsedTime.Seconds(), pacer.throttled.Seconds())
 		}
 
 		if err != nil && debug {
@@ -151,23 +232,8 @@ func (w *WorkspaceWatcher) AddRegistrations(ctx context.Context, id string, watc
 func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePath string) {
 	w.workspacePath = workspacePath
 
-	// Load .gitignore
-	gitignorePath := filepath.Join(workspacePath, ".gitignore")
-	if _, err := os.Stat(gitignorePath); err == nil {
-		var compileErr error
-		w.gitIgnore, compileErr = gitignore.CompileIgnoreFile(gitignorePath)
-		if compileErr != nil {
-			log.Printf("Error compiling .gitignore file %s: %v", gitignorePath, compileErr)
-			// Continue without gitignore rules if compilation fails
-			w.gitIgnore = nil
-		} else if debug {
-			log.Printf("Successfully loaded .gitignore from %s", gitignorePath)
-		}
-	} else if !os.IsNotExist(err) {
-		log.Printf("Error checking for .gitignore file %s: %v", gitignorePath, err)
-	} else if debug {
-		log.Printf(".gitignore not found at %s", gitignorePath)
-	}
+	// .gitignore files are loaded as directories are visited
+	w.ignore = newIgnoreRules(workspacePath)
 
 	// Register handler for file watcher registrations from the server
 	lsp.RegisterFileWatchHandler(func(id string, watchers []protocol.FileSystemWatcher) {
@@ -180,8 +246,15 @@ func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePath str
 	}
 	defer watcher.Close()
 
+	// Directories that cannot be watched natively are polled
+	w.startMu.Lock()
+	w.fsw = watcher
+	w.poller = newPoller(w.config.PollInterval)
+	w.startMu.Unlock()
+	go w.poller.run(ctx)
+
 	// Watch the workspace recursively
-	err = filepath.WalkDir(workspacePath, func(path string, d os.DirEntry, err error) error {
+	err = w.WalkWorkspace(func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -198,10 +271,7 @@ func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePath str
 
 		// Add directories to watcher
 		if d.IsDir() {
-			err = watcher.Add(path)
-			if err != nil {
-				log.Printf("Error watching path %s: %v", path, err)
-			}
+			w.addWatch(watcher, path)
 		}
 
 		return nil
@@ -211,7 +281,16 @@ func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePath str
 		log.Fatalf("Error walking workspace: %v", err)
 	}
 
-	// Event loop
+	// .git is excluded, but changes to .git/info/exclude change the ignore rules
+	if info, err := os.Stat(filepath.Join(workspacePath, ".git", "info")); err == nil && info.IsDir() {
+		if err := watcher.Add(filepath.Join(workspacePath, ".git", "info")); err != nil {
+			log.Printf("Error watching .git/info: %v", err)
+		}
+	}
+
+	go w.openProjects(ctx)
+
+	// Event loop, polled directories report their events alongside fsnotify's
 	for {
 		select {
 		case <-ctx.Done():
@@ -220,76 +299,118 @@ func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePath str
 			if !ok {
 				return
 			}
+			w.handleEvent(ctx, watcher, event)
+		case event := <-w.poller.events:
+			w.handleEvent(ctx, watcher, event)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Watcher error: %v\n", err)
+		}
+	}
+}
 
-			uri := fmt.Sprintf("file://%s", event.Name)
-
-			// Add new directories to the watcher
-			if event.Op&fsnotify.Create != 0 {
-				if info, err := os.Stat(event.Name); err == nil {
-					if info.IsDir() {
-						// Skip excluded directories
-						if !w.shouldExcludeDir(event.Name) {
-							if err := watcher.Add(event.Name); err != nil {
-								log.Printf("Error watching new directory: %v", err)
-							}
-						}
-					} else {
-						// For newly created files
-						if !w.shouldExcludeFile(event.Name) {
-							w.openMatchingFile(ctx, event.Name)
-						}
-					}
+// handleEvent processes a file system event from fsnotify or the poller
+func (w *WorkspaceWatcher) handleEvent(ctx context.Context, fsw *fsnotify.Watcher, event fsnotify.Event) {
+	uri := fmt.Sprintf("file://%s", event.Name)
+
+	// Ignore files are reloaded at runtime
+	if w.ignore.isIgnoreFile(event.Name) {
+		w.scheduleIgnoreReload(ctx, fsw, event.Name)
+	}
+	// .git/info is only watched for the exclude file
+	if filepath.Dir(event.Name) == filepath.Join(w.workspacePath, ".git", "info") {
+		return
+	}
+
+	// Add new directories to the watcher
+	if event.Op&fsnotify.Create != 0 {
+		if info, err := os.Stat(event.Name); err == nil {
+			if info.IsDir() {
+				// Skip excluded directories
+				if !w.shouldExcludeDir(event.Name) {
+					w.addWatch(fsw, event.Name)
+				}
+			} else {
+				// For newly created files
+				if w.shouldEagerOpen() && !w.shouldExcludeFile(event.Name) {
+					w.openMatchingFile(ctx, event.Name, nil)
 				}
 			}
+		}
+	}
 
-			// Debug logging
-			if debug {
-				matched, kind := w.isPathWatched(event.Name)
-				log.Printf("Event: %s, Op: %s, Watched: %v, Kind: %d",
-					event.Name, event.Op.String(), matched, kind)
-			}
+	w.handleWorkspaceEvent(event)
 
-			// Check if this path should be watched according to server registrations
-			if watched, watchKind := w.isPathWatched(event.Name); watched {
-				switch {
-				case event.Op&fsnotify.Write != 0:
-					if watchKind&protocol.WatchChange != 0 {
-						w.debounceHandleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Changed))
-					}
-				case event.Op&fsnotify.Create != 0:
-					// Already handled earlier in the event loop
-					// Just send the notification if needed
-					info, _ := os.Stat(event.Name)
-					if info != nil && !info.IsDir() && watchKind&protocol.WatchCreate != 0 && !w.shouldExcludeFile(event.Name) {
-						w.debounceHandleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Created))
-					}
-				case event.Op&fsnotify.Remove != 0:
-					if watchKind&protocol.WatchDelete != 0 && !w.shouldExcludeFile(event.Name) {
-						w.handleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Deleted))
-					}
-				case event.Op&fsnotify.Rename != 0:
-					// For renames, first delete if not excluded
-					if watchKind&protocol.WatchDelete != 0 && !w.shouldExcludeFile(event.Name) {
-						w.handleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Deleted))
-					}
+	// Debug logging
+	if debug {
+		matched, kind := w.isPathWatched(event.Name)
+		log.Printf("Event: %s, Op: %s, Watched: %v, Kind: %d",
+			event.Name, event.Op.String(), matched, kind)
+	}
 
-					// Then check if the new file exists and create an event if not excluded
-					if info, err := os.Stat(event.Name); err == nil && !info.IsDir() {
-						if watchKind&protocol.WatchCreate != 0 && !w.shouldExcludeFile(event.Name) {
-							w.debounceHandleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Created))
-						}
-					}
-				}
+	// Check if this path should be watched according to server registrations
+	if watched, watchKind := w.isPathWatched(event.Name); watched {
+		switch {
+		case event.Op&fsnotify.Write != 0:
+			if watchKind&protocol.WatchChange != 0 {
+				w.debounceHandleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Changed))
 			}
-		case err, ok := <-watcher.Errors:
-			if !ok {
-				return
+		case event.Op&fsnotify.Create != 0:
+			// Already handled earlier in the event loop
+			// Just send the notification if needed
+			info, _ := os.Stat(event.Name)
+			if info != nil && !info.IsDir() && watchKind&protocol.WatchCreate != 0 && !w.shouldExcludeFile(event.Name) {
+				w.debounceHandleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Created))
+			}
+		case event.Op&fsnotify.Remove != 0:
+			if watchKind&protocol.WatchDelete != 0 && !w.shouldExcludeFile(event.Name) {
+				w.handleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Deleted))
+			}
+		case event.Op&fsnotify.Rename != 0:
+			// For renames, first delete if not excluded
+			if watchKind&protocol.WatchDelete != 0 && !w.shouldExcludeFile(event.Name) {
+				w.handleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Deleted))
+			}
+
+			// Then check if the new file exists and create an event if not excluded
+			if info, err := os.Stat(event.Name); err == nil && !info.IsDir() {
+				if watchKind&protocol.WatchCreate != 0 && !w.shouldExcludeFile(event.Name) {
+					w.debounceHandleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Created))
+				}
 			}
-			log.Printf("Watcher error: %v\n", err)
 		}
 	}
 }
 
+// handleWorkspaceEvent reports file events to the registered handlers.
+// Removed files cannot be checked against the exclusion rules that stat the file,
+// so deletions are always reported.
+func (w *WorkspaceWatcher) handleWorkspaceEvent(event fsnotify.Event) {
+	switch {
+	case event.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+		if _, err := os.Stat(event.Name); err != nil {
+			w.notifyFileEventHandlers(event.Name, protocol.FileChangeType(protocol.Deleted))
+			return
+		}
+		// Renamed onto an existing path
+		if !w.shouldExcludeFile(event.Name) {
+			w.notifyFileEventHandlers(event.Name, protocol.FileChangeType(protocol.Created))
+		}
+	case event.Op&(fsnotify.Create|fsnotify.Write) != 0:
+		info, err := os.Stat(event.Name)
+		if err != nil || info.IsDir() || w.shouldExcludeFile(event.Name) {
+			return
+		}
+		changeType := protocol.FileChangeType(protocol.Changed)
+		if event.Op&fsnotify.Create != 0 {
+			changeType = protocol.FileChangeType(protocol.Created)
+		}
+		w.notifyFileEventHandlers(event.Name, changeType)
+	}
+}
+
 // isPathWatched checks if a path should be watched based on server registrations
 func (w *WorkspaceWatcher) isPathWatched(path string) (bool, protocol.WatchKind) {
 	w.registrationMu.RLock()
@@ -315,108 +436,6 @@ func (w *WorkspaceWatcher) isPathWatched(path string) (bool, protocol.WatchKind)
 	return false, 0
 }
 
-// matchesGlob handles advanced glob patterns including ** and alternatives
-func matchesGlob(pattern, path string) bool {
-	// Handle file extension patterns with braces like *.{go,mod,sum}
-	if strings.Contains(pattern, "{") && strings.Contains(pattern, "}") {
-		// Extract extensions from pattern like "*.{go,mod,sum}"
-		parts := strings.SplitN(pattern, "{", 2)
-		if len(parts) == 2 {
-			prefix := parts[0]
-			extPart := strings.SplitN(parts[1], "}", 2)
-			if len(extPart) == 2 {
-				extensions := strings.Split(extPart[0], ",")
-				suffix := extPart[1]
-
-				// Check if the path matches any of the extensions
-				for _, ext := range extensions {
-					extPattern := prefix + ext + suffix
-					isMatch := matchesSimpleGlob(extPattern, path)
-					if isMatch {
-						return true
-					}
-				}
-				return false
-			}
-		}
-	}
-
-	return matchesSimpleGlob(pattern, path)
-}
-
-// matchesSimpleGlob handles glob patterns with ** wildcards
-func matchesSimpleGlob(pattern, path string) bool {
-	// Handle special case for **/*.ext pattern (common in LSP)
-	if strings.HasPrefix(pattern, "**/") {
-		rest := strings.TrimPrefix(pattern, "**/")
-
-		// If the rest is a simple file extension pattern like *.go
-		if strings.HasPrefix(rest, "*.") {
-			ext := strings.TrimPrefix(rest, "*")
-			isMatch := strings.HasSuffix(path, ext)
-			return isMatch
-		}
-
-		// Otherwise, try to check if the path ends with the rest part
-		isMatch := strings.HasSuffix(path, rest)
-
-		// If it matches directly, great!
-		if isMatch {
-			return true
-		}
-
-		// Otherwise, check if any path component matches
-		pathComponents := strings.Split(path, "/")
-		for i := 0; i < len(pathComponents); i++ {
-			subPath := strings.Join(pathComponents[i:], "/")
-			if strings.HasSuffix(subPath, rest) {
-				return true
-			}
-		}
-
-		return false
-	}
-
-	// Handle other ** wildcard pattern cases
-	if strings.Contains(pattern, "**") {
-		parts := strings.Split(pattern, "**")
-
-		// Validate the path starts with the first part
-		if !strings.HasPrefix(path, parts[0]) && parts[0] != "" {
-			return false
-		}
-
-		// For patterns like "**/*.go", just check the suffix
-		if len(parts) == 2 && parts[0] == "" {
-			isMatch := strings.HasSuffix(path, parts[1])
-			return isMatch
-		}
-
-		// For other patterns, handle middle part
-		remaining := strings.TrimPrefix(path, parts[0])
-		if len(parts) == 2 {
-			isMatch := strings.HasSuffix(remaining, parts[1])
-			return isMatch
-		}
-	}
-
-	// Handle simple * wildcard for file extension patterns (*.go, *.sum, etc)
-	if strings.HasPrefix(pattern, "*.") {
-		ext := strings.TrimPrefix(pattern, "*")
-		isMatch := strings.HasSuffix(path, ext)
-		return isMatch
-	}
-
-	// Fall back to simple matching for simpler patterns
-	matched, err := filepath.Match(pattern, path)
-	if err != nil {
-		log.Printf("Error matching pattern %s: %v", pattern, err)
-		return false
-	}
-
-	return matched
-}
-
 // matchesPattern checks if a path matches the glob pattern
 func (w *WorkspaceWatcher) matchesPattern(path string, pattern protocol.GlobPattern) bool {
 	patternInfo, err := pattern.AsPattern()
@@ -470,7 +489,7 @@ func (w *WorkspaceWatcher) debounceHandleFileEvent(ctx context.Context, uri stri
 	}
 
 	// Create new timer
-	w.debounceMap[key] = time.AfterFunc(w.debounceTime, func() {
+	w.debounceMap[key] = time.AfterFunc(w.config.DebounceTime, func() {
 		w.handleFileEvent(ctx, uri, changeType)
 
 		// Cleanup timer after execution
@@ -480,10 +499,83 @@ func (w *WorkspaceWatcher) debounceHandleFileEvent(ctx context.Context, uri stri
 	})
 }
 
+// Stats describes what the watcher is tracking
+type Stats struct {
+	Registrations     int // File watchers registered by the language server
+	WatchedDirs       int // Directories watched natively
+	PolledDirs        int // Directories polled because they could not be watched
+	PendingEvents     int // Debounced events not yet sent
+	JournalEntries    int
+	LastJournalSeq    uint64
+	EagerOpen         EagerOpenMode
+	EagerOpenMaxFiles int
+	SkippedLarge      int // Files not opened because they exceed MaxFileSize
+	SkippedBinary     int // Files not opened because their content is binary
+}
+
+// Stats returns a snapshot of the watcher's state
+func (w *WorkspaceWatcher) Stats() Stats {
+	stats := Stats{
+		JournalEntries:    w.journal.Len(),
+		LastJournalSeq:    w.journal.LastSeq(),
+		EagerOpen:         w.eagerOpen,
+		EagerOpenMaxFiles: w.eagerOpenMaxFiles,
+	}
+	skipped := w.skippedCounts()
+	stats.SkippedLarge = skipped[SkipTooLarge]
+	stats.SkippedBinary = skipped[SkipBinary]
+
+	w.registrationMu.RLock()
+	stats.Registrations = len(w.registrations)
+	w.registrationMu.RUnlock()
+
+	w.debounceMu.Lock()
+	stats.PendingEvents = len(w.debounceMap)
+	w.debounceMu.Unlock()
+
+	w.startMu.RLock()
+	fsw, poller := w.fsw, w.poller
+	w.startMu.RUnlock()
+	if fsw != nil {
+		stats.WatchedDirs = len(fsw.WatchList())
+	}
+	if poller != nil {
+		poller.dirsMu.Lock()
+		stats.PolledDirs = len(poller.dirs)
+		poller.dirsMu.Unlock()
+	}
+	return stats
+}
+
+// Stop cancels pending debounced events and ignore reloads, so nothing is sent to a
+// language server that is shutting down. The watch loop itself ends with its context.
+func (w *WorkspaceWatcher) Stop() {
+	w.debounceMu.Lock()
+	for key, timer := range w.debounceMap {
+		timer.Stop()
+		delete(w.debounceMap, key)
+	}
+	w.debounceMu.Unlock()
+
+	w.ignoreReloadMu.Lock()
+	if w.ignoreReloadTimer != nil {
+		w.ignoreReloadTimer.Stop()
+		w.ignoreReloadTimer = nil
+	}
+	w.ignoreReloadPaths = nil
+	w.ignoreReloadMu.Unlock()
+}
+
 // handleFileEvent sends file change notifications
 func (w *WorkspaceWatcher) handleFileEvent(ctx context.Context, uri string, changeType protocol.FileChangeType) {
 	// If the file is open and it's a change event, use didChange notification
 	filePath := uri[7:] // Remove "file://" prefix
+	if changeType == protocol.FileChangeType(protocol.Changed) && w.client.IsNotebookOpen(filePath) {
+		if err := w.client.SyncNotebook(ctx, filePath); err != nil {
+			log.Printf("Error syncing notebook: %v", err)
+		}
+		return
+	}
 	if changeType == protocol.FileChangeType(protocol.Changed) && w.client.IsFileOpen(filePath) {
 		err := w.client.NotifyChange(ctx, filePath)
 		if err != nil {
@@ -516,8 +608,7 @@ func (w *WorkspaceWatcher) notifyFileEvent(ctx context.Context, uri string, chan
 	return w.client.DidChangeWatchedFiles(ctx, params)
 }
 
-// Common patterns for directories and files to exclude
-// TODO: make configurable
+// Default patterns for directories and files to exclude, see Config
 var (
 	excludedDirNames = map[string]bool{
 		".git":         true,
@@ -575,12 +666,30 @@ var (
 	maxFileSize int64 = 5 * 1024 * 1024
 )
 
+// WorkspacePath returns the root of the watched workspace
+func (w *WorkspaceWatcher) WorkspacePath() string {
+	return w.workspacePath
+}
+
+// IsExcluded returns true if the path is excluded from watching/opening by gitignore
+// rules or the built-in exclusion lists
+func (w *WorkspaceWatcher) IsExcluded(path string, isDir bool) bool {
+	if isDir {
+		return path != w.workspacePath && w.shouldExcludeDir(path)
+	}
+	return w.shouldExcludeFile(path)
+}
+
 // shouldExcludeDir returns true if the directory should be excluded from watching/opening
 func (w *WorkspaceWatcher) shouldExcludeDir(dirPath string) bool {
-	// Check gitignore first
-	relPath, err := filepath.Rel(w.workspacePath, dirPath)
-	if err == nil && w.gitIgnore != nil && w.gitIgnore.MatchesPath(filepath.ToSlash(relPath)) {
-		return true
+	// Check .mcpignore and gitignore first
+	if w.ignore != nil {
+		if w.ignore.included(dirPath, true) {
+			return false
+		}
+		if w.ignore.ignored(dirPath, true) {
+			return true
+		}
 	}
 
 	dirName := filepath.Base(dirPath)
@@ -591,7 +700,7 @@ func (w *WorkspaceWatcher) shouldExcludeDir(dirPath string) bool {
 	}
 
 	// Skip common excluded directories
-	if excludedDirNames[dirName] {
+	if w.config.ExcludedDirNames[dirName] {
 		return true
 	}
 
@@ -600,66 +709,72 @@ func (w *WorkspaceWatcher) shouldExcludeDir(dirPath string) bool {
 
 // shouldExcludeFile returns true if the file should be excluded from opening
 func (w *WorkspaceWatcher) shouldExcludeFile(filePath string) bool {
-	// Check gitignore first
-	relPath, err := filepath.Rel(w.workspacePath, filePath)
-	if err == nil && w.gitIgnore != nil && w.gitIgnore.MatchesPath(filepath.ToSlash(relPath)) {
+	// Check .mcpignore and gitignore first, files explicitly included by
+	// .mcpignore skip the name based rules
+	included := w.ignore != nil && w.ignore.included(filePath, false)
+	if !included && w.ignore != nil && w.ignore.ignored(filePath, false) {
 		return true
 	}
 
 	fileName := filepath.Base(filePath)
 
 	// Skip dot files (common convention, often covered by gitignore but good fallback)
-	if strings.HasPrefix(fileName, ".") && fileName != "." && fileName != ".." {
+	if !included && strings.HasPrefix(fileName, ".") && fileName != "." && fileName != ".." {
 		return true
 	}
 
 	// Check file extension
 	ext := strings.ToLower(filepath.Ext(filePath))
-	if excludedFileExtensions[ext] || largeBinaryExtensions[ext] {
+	if !included && w.config.ExcludedFileExtensions[ext] {
 		return true
 	}
 
 	// Skip temporary files
-	if strings.HasSuffix(filePath, "~") {
+	if !included && strings.HasSuffix(filePath, "~") {
 		return true
 	}
 
-	// Check file size
 	info, err := os.Stat(filePath)
 	if err != nil {
 		// If we can't stat the file, skip it
+		w.forgetContentCheck(filePath)
 		return true
 	}
 
-	// Skip large files
-	if info.Size() > maxFileSize {
-		if debug {
-			log.Printf("Skipping large file: %s (%.2f MB)", filePath, float64(info.Size())/(1024*1024))
-		}
-		return true
-	}
-
-	return false
+	// Skip large and binary files
+	return w.checkContent(filePath, info) != ""
 }
 
 // openMatchingFile opens a file if it matches any of the registered patterns
-func (w *WorkspaceWatcher) openMatchingFile(ctx context.Context, path string) {
+// and reports whether it was opened. With a pacer, files that are not open yet wait
+// for their turn.
+func (w *WorkspaceWatcher) openMatchingFile(ctx context.Context, path string, pacer *openPacer) bool {
 	// Skip directories
 	info, err := os.Stat(path)
 	if err != nil || info.IsDir() {
-		return
+		return false
 	}
 
 	// Skip excluded files
 	if w.shouldExcludeFile(path) {
-		return
+		return false
 	}
 
 	// Check if this path should be watched according to server registrations
 	if watched, _ := w.isPathWatched(path); watched {
+		if pacer != nil && !w.client.IsFileOpen(path) {
+			if err := pacer.wait(ctx); err != nil {
+				return false
+			}
+		}
 		// Don't need to check if it's already open - the client.OpenFile handles that
-		if err := w.client.OpenFile(ctx, path); err != nil && debug {
-			log.Printf("Error opening file %s: %v", path, err)
+		if err := w.client.OpenFile(ctx, path); err != nil {
+			if debug {
+				log.Printf("Error opening file %s: %v", path, err)
+			}
+			return false
 		}
+		return true
 	}
+	return false
 }
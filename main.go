@@ -9,36 +9,183 @@ import (
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/isaacphi/mcp-language-server/internal/index"
+	"github.com/isaacphi/mcp-language-server/internal/install"
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/memory"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
 	"github.com/isaacphi/mcp-language-server/internal/watcher"
 	mcp_golang "github.com/metoro-io/mcp-golang"
+	"github.com/metoro-io/mcp-golang/transport"
 	"github.com/metoro-io/mcp-golang/transport/stdio"
 )
 
 var debug = os.Getenv("DEBUG") != ""
 
 type config struct {
-	workspaceDir string
-	lspCommand   string
-	lspArgs      []string
+	workspaceDir   string
+	lspCommand     string
+	lspArgs        []string
+	httpAddr       string
+	authToken      string
+	metrics        bool
+	readOnly       bool
+	enabledTools   []string
+	disabledTools  []string
+	toolPrefix     string
+	toolSuffix     string
+	toolLanguage   string
+	allowedPaths   []string
+	codeFences     bool
+	verbosity      string
+	generated      []string
+	maxInFlight    int
+	maxOpenFiles   int
+	eagerOpen      watcher.EagerOpenMode
+	eagerOpenMax   int
+	openPacing     watcher.OpenPacing
+	languageIDs    map[string]protocol.LanguageKind
+	cargoFeatures  []string
+	cargoNoDefault bool
+	pythonEnv      string
+	tsPlugins      []lsp.TypeScriptPlugin
+	jdtlsCleanData bool
+	goplsEnv       map[string]string
+	goDriver       string
+	pathMappings   []lsp.PathMapping
+	install        bool
+	installURL     string
+	installSHA256  string
+	lspDir         string
+	lspEnv         []string
+	lspMaxMemoryMB int
+	symbolIndex    bool
+	symbolTTL      time.Duration
+	memoryBudget   int64
+	warmup         bool
+	warmupTimeout  time.Duration
+	watcher        watcher.Config
 }
 
 type server struct {
 	config           config
 	lspClient        *lsp.Client
 	mcpServer        *mcp_golang.Server
+	mcpTransport     *subscriptionTransport
 	ctx              context.Context
 	cancelFunc       context.CancelFunc
 	workspaceWatcher *watcher.WorkspaceWatcher
+
+	// Ends the watcher and background work of the current workspace, see setWorkspace
+	workspaceCtx    context.Context
+	workspaceCancel context.CancelFunc
+
+	// Held for reading by tool calls and for writing while the workspace is switched
+	workspaceMu sync.RWMutex
+
+	// Resources registered for the files of the current workspace
+	workspaceResources   map[string]bool
+	workspaceResourcesMu sync.Mutex
+
+	// Journal position each session last saw in recent_changes
+	changesCursors   map[string]uint64
+	changesCursorsMu sync.Mutex
+
+	// Argument defaults each session set with set_defaults
+	defaults *sessionDefaults
+
+	// Confines tool path arguments and workspace edits
+	sandbox *utilities.Sandbox
+
+	// Directories set_workspace may switch to: the workspace given at startup and
+	// the allowed paths
+	workspaceRoots *utilities.Sandbox
+
+	// Persisted workspace symbols, nil unless --symbol-index is set
+	symbolIndex *index.Index
+
+	// Names of the registered tools, before any are disabled
+	toolNames []string
+
+	// Closed when the MCP client disconnects, cleanup runs once
+	transportClosed     chan struct{}
+	transportClosedOnce sync.Once
+	cleanupOnce         sync.Once
 }
 
 func parseConfig() (*config, error) {
 	cfg := &config{}
 	flag.StringVar(&cfg.workspaceDir, "workspace", "", "Path to workspace directory")
 	flag.StringVar(&cfg.lspCommand, "lsp", "", "LSP command to run (args should be passed after --)")
+	flag.StringVar(&cfg.httpAddr, "http", "", "Serve MCP over HTTP with SSE on this address (e.g. :8080) instead of stdio")
+	flag.StringVar(&cfg.authToken, "auth-token", os.Getenv("MCP_AUTH_TOKEN"), "Bearer token required by the HTTP transport (defaults to $MCP_AUTH_TOKEN)")
+	flag.BoolVar(&cfg.metrics, "metrics", false, "Serve Prometheus metrics at /metrics (requires --http)")
+	flag.BoolVar(&cfg.readOnly, "read-only", false, "Disable tools that modify files ("+strings.Join(mutatingToolNames(), ", ")+") and reject edits requested by the language server")
+	enabledTools := flag.String("tools", "", "Comma-separated list of tools to enable, all tools by default")
+	disabledTools := flag.String("disable-tools", "", "Comma-separated list of tools to disable")
+	flag.StringVar(&cfg.toolPrefix, "tool-prefix", "", "Prefix for the names of all tools, e.g. go_ for go_read_definition, so servers for several languages can be attached to one client")
+	flag.StringVar(&cfg.toolSuffix, "tool-suffix", "", "Suffix for the names of all tools, e.g. _go for read_definition_go")
+	flag.StringVar(&cfg.toolLanguage, "tool-language", "", "Language named at the start of each tool description, detected from --lsp by default when --tool-prefix or --tool-suffix is set")
+	allowedPaths := flag.String("allow-path", "", "Comma-separated list of directories outside the workspace that tools may access")
+	flag.BoolVar(&cfg.codeFences, "code-fences", true, "Wrap code in tool output in fenced blocks tagged with the file's language")
+	flag.StringVar(&cfg.verbosity, "verbosity", tools.VerbosityNormal, "Default detail of tool output, which tools' verbosity argument overrides: minimal (locations and signatures, no code bodies), normal or full (documentation and context, nothing truncated)")
+	generated := flag.String("generated", strings.Join(tools.DefaultGeneratedPatterns, ","), "Comma-separated globs of generated files, whose references and definitions are reduced to a count in tool output. Empty to show them in full")
+	flag.IntVar(&cfg.maxInFlight, "max-inflight", lsp.DefaultMaxInFlight, "Maximum number of concurrent requests to the language server")
+	flag.IntVar(&cfg.maxOpenFiles, "max-open-files", lsp.DefaultMaxOpenFiles, "Maximum number of documents kept open in the language server, 0 for no limit")
+	eagerOpen := flag.String("eager-open", string(watcher.EagerOpenAuto), "Open files up front: auto, always or never (auto opens one file of each project for servers that load whole projects, like typescript-language-server, always opens every file matching the server's watch registrations too)")
+	flag.IntVar(&cfg.eagerOpenMax, "eager-open-max", 0, "Maximum number of files opened up front, 0 for no limit beyond --max-open-files")
+	flag.IntVar(&cfg.openPacing.MaxRate, "open-rate", 0, "Maximum number of files opened per second up front, 0 for the language server's default (1000, less for servers known to be slow)")
+	flag.IntVar(&cfg.openPacing.MaxInFlight, "open-max-inflight", 0, "Pause opening files up front while this many requests await a response, 0 for the language server's default")
+	excludeDirs := flag.String("exclude-dirs", "", "Comma separated directory names to exclude from watching, prefix with ! to include a default exclusion (e.g. third_party,!vendor)")
+	excludeExts := flag.String("exclude-exts", "", "Comma separated file extensions to exclude from opening, prefix with ! to include a default exclusion (e.g. .pb,!.log)")
+	maxFileSizeMB := flag.Int("max-file-size", 5, "Largest file in MB that is opened in the language server, 0 for no limit")
+	memoryBudgetMB := flag.Int("memory-budget", 256, "Approximate size in MB of the open documents, diagnostics and symbol searches kept in memory, least recently used ones are dropped beyond it. 0 for no limit")
+	debounce := flag.Duration("debounce", 300*time.Millisecond, "How long to wait for further changes to a file before notifying the language server")
+	pollInterval := flag.Duration("poll-interval", 5*time.Second, "How often to scan directories that cannot be watched natively (e.g. when inotify watches run out), 0 to disable")
+	flag.BoolVar(&cfg.symbolIndex, "symbol-index", false, "Keep an index of workspace symbols in the user cache directory, so symbols can be found right after a restart while the language server is still indexing")
+	flag.DurationVar(&cfg.symbolTTL, "symbol-cache-ttl", tools.DefaultSymbolCacheTTL, "How long workspace symbol search results are reused, 0 to always ask the language server. Results are dropped early when files declaring them change")
+	flag.BoolVar(&cfg.warmup, "warmup", false, "Before accepting MCP requests, open representative files and wait for the language server to finish indexing, then log the time it took")
+	flag.DurationVar(&cfg.warmupTimeout, "warmup-timeout", 2*time.Minute, "How long --warmup waits for the language server")
+	languageIDs := flag.String("language-ids", "", "Comma separated extension=languageId pairs sent in didOpen, overriding the built-in table (e.g. .svelte=svelte,.templ=templ)")
+	cargoFeatures := flag.String("cargo-features", "", "Comma-separated cargo features rust-analyzer analyzes the workspace with, or 'all'")
+	flag.BoolVar(&cfg.cargoNoDefault, "cargo-no-default-features", false, "Have rust-analyzer leave out the default cargo features")
+	flag.StringVar(&cfg.pythonEnv, "python-env", "", "Virtualenv or Python interpreter that Python language servers resolve imports with, detected by default from $VIRTUAL_ENV, .venv, poetry or conda")
+	tsPlugins := flag.String("ts-plugins", "", "Comma-separated tsserver plugins typescript-language-server loads, as name or name=directory holding its node_modules (the workspace by default)")
+	flag.BoolVar(&cfg.jdtlsCleanData, "jdtls-clean-data", false, "Delete jdtls' data directory for the workspace before starting it, to rebuild a corrupt or outdated index")
+	flag.StringVar(&cfg.goDriver, "go-packages-driver", "", "Program gopls loads packages with instead of go list (GOPACKAGESDRIVER), e.g. rules_go's gopackagesdriver for Bazel. Found in tools/ of Bazel workspaces by default")
+	flag.Func("gopls-env", "KEY=VALUE environment variable for the go commands and packages driver gopls runs, may be repeated (e.g. GOFLAGS=-tags=integration)", func(variable string) error {
+		key, value, err := lsp.ParseGoplsEnv(variable)
+		if err != nil {
+			return err
+		}
+		if cfg.goplsEnv == nil {
+			cfg.goplsEnv = make(map[string]string)
+		}
+		cfg.goplsEnv[key] = value
+		return nil
+	})
+	flag.Func("path-map", "local=remote directory mapping applied to the URIs exchanged with a language server running under WSL, in a container or over SSH, may be repeated. 'wsl' maps Windows drives to /mnt/<drive> for a server under WSL, 'windows' the reverse", func(spec string) error {
+		mappings, err := lsp.ParsePathMappings(spec)
+		cfg.pathMappings = append(cfg.pathMappings, mappings...)
+		return err
+	})
+	flag.BoolVar(&cfg.install, "install", false, "Install the language server into the user cache directory when --lsp is not found: gopls at a pinned version, others from --install-url")
+	flag.StringVar(&cfg.installURL, "install-url", "", "URL --install downloads the language server's executable from, gunzipped if it ends in .gz")
+	flag.StringVar(&cfg.installSHA256, "install-sha256", "", "SHA-256 the download from --install-url must have")
+	flag.StringVar(&cfg.lspDir, "lsp-dir", "", "Working directory of the language server, relative to the workspace, the workspace by default")
+	flag.Func("lsp-env", "KEY=VALUE environment variable for the language server on top of this server's environment, may be repeated (e.g. NODE_OPTIONS=--max-old-space-size=4096, RUST_LOG=info)", func(variable string) error {
+		cfg.lspEnv = append(cfg.lspEnv, variable)
+		return lsp.CheckEnvVariable(variable)
+	})
+	flag.IntVar(&cfg.lspMaxMemoryMB, "lsp-max-memory", 0, "Resident memory in MB the language server and its child processes may use before it is stopped, 0 for no limit (Linux only)")
+	followSymlinks := flag.Bool("follow-symlinks", false, "Watch and search symlinked directories that point outside the workspace")
 	flag.Parse()
 
 	// Get remaining args after -- as LSP arguments
@@ -59,38 +206,152 @@ func parseConfig() (*config, error) {
 		return nil, fmt.Errorf("workspace directory does not exist: %s", cfg.workspaceDir)
 	}
 
+	cfg.eagerOpen, err = watcher.ParseEagerOpenMode(*eagerOpen)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.languageIDs, err = lsp.ParseLanguageIDs(*languageIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.verbosity, err = parseVerbosity(cfg.verbosity)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.enabledTools = splitList(*enabledTools)
+	cfg.disabledTools = splitList(*disabledTools)
+	cfg.allowedPaths = splitList(*allowedPaths)
+	cfg.generated = splitList(*generated)
+	cfg.cargoFeatures = splitList(*cargoFeatures)
+	for _, plugin := range splitList(*tsPlugins) {
+		cfg.tsPlugins = append(cfg.tsPlugins, lsp.ParseTypeScriptPlugin(plugin))
+	}
+
+	cfg.watcher = watcher.DefaultConfig()
+	cfg.watcher.UpdateExcludedDirs(strings.Split(*excludeDirs, ","))
+	cfg.watcher.UpdateExcludedFileExtensions(strings.Split(*excludeExts, ","))
+	cfg.watcher.MaxFileSize = int64(*maxFileSizeMB) * 1024 * 1024
+	cfg.memoryBudget = int64(*memoryBudgetMB) * 1024 * 1024
+	cfg.watcher.DebounceTime = *debounce
+	cfg.watcher.PollInterval = *pollInterval
+	cfg.watcher.FollowSymlinks = *followSymlinks
+
 	// Validate LSP command
 	if cfg.lspCommand == "" {
 		return nil, fmt.Errorf("LSP command is required")
 	}
 
 	if _, err := exec.LookPath(cfg.lspCommand); err != nil {
-		return nil, fmt.Errorf("LSP command not found: %s", cfg.lspCommand)
+		if !cfg.install {
+			return nil, fmt.Errorf("LSP command not found: %s", cfg.lspCommand)
+		}
+		cfg.lspCommand, err = installServer(cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !validToolAffix(cfg.toolPrefix) || !validToolAffix(cfg.toolSuffix) {
+		return nil, fmt.Errorf("--tool-prefix and --tool-suffix may only contain letters, digits, '_' and '-'")
+	}
+	if cfg.toolLanguage == "" && (cfg.toolPrefix != "" || cfg.toolSuffix != "") {
+		cfg.toolLanguage = lsp.ServerLanguage(cfg.lspCommand)
+	}
+
+	if cfg.metrics && cfg.httpAddr == "" {
+		return nil, fmt.Errorf("--metrics requires --http")
 	}
 
 	return cfg, nil
 }
 
+// installServer installs the language server named by --lsp, or downloads it from
+// --install-url, and returns the path of its command
+func installServer(cfg *config) (string, error) {
+	server, known := install.Servers[filepath.Base(cfg.lspCommand)]
+	if cfg.installURL != "" {
+		var err error
+		server, err = install.DownloadServer(cfg.lspCommand, cfg.installURL, cfg.installSHA256)
+		if err != nil {
+			return "", err
+		}
+	} else if !known {
+		return "", fmt.Errorf("LSP command not found: %s, and --install has no pinned version of it, give --install-url and --install-sha256", cfg.lspCommand)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	return install.Ensure(ctx, server)
+}
+
 func newServer(config *config) (*server, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &server{
-		config:     *config,
-		ctx:        ctx,
-		cancelFunc: cancel,
+		config:             *config,
+		ctx:                ctx,
+		cancelFunc:         cancel,
+		changesCursors:     make(map[string]uint64),
+		defaults:           newSessionDefaults(),
+		workspaceResources: make(map[string]bool),
+		transportClosed:    make(chan struct{}),
 	}, nil
 }
 
 func (s *server) initializeLSP() error {
+	s.workspaceCtx, s.workspaceCancel = context.WithCancel(s.ctx)
+
 	if err := os.Chdir(s.config.workspaceDir); err != nil {
 		return fmt.Errorf("failed to change to workspace directory: %v", err)
 	}
 
-	client, err := lsp.NewClient(s.config.lspCommand, s.config.lspArgs...)
+	sandbox, err := utilities.NewSandbox(s.config.workspaceDir, s.config.allowedPaths)
+	if err != nil {
+		return err
+	}
+	s.sandbox = sandbox
+	utilities.SetEditSandbox(sandbox)
+
+	lsp.ClientVersion = serverVersion()
+	lsp.SetLanguageIDs(s.config.languageIDs)
+	lsp.SetCargoFeatures(s.config.cargoFeatures, s.config.cargoNoDefault)
+	lsp.SetPythonEnvironment(s.config.pythonEnv)
+	lsp.SetTypeScriptPlugins(s.config.tsPlugins)
+	lsp.SetJdtlsCleanData(s.config.jdtlsCleanData)
+	lsp.SetGoplsEnv(s.config.goplsEnv)
+	lsp.SetGoPackagesDriver(s.config.goDriver)
+	lsp.SetPathMappings(s.config.pathMappings)
+	lspDir := s.config.lspDir
+	if lspDir != "" && !filepath.IsAbs(lspDir) {
+		lspDir = filepath.Join(s.config.workspaceDir, lspDir)
+	}
+	lsp.SetProcessConfig(lsp.ProcessConfig{
+		Dir:       lspDir,
+		Env:       s.config.lspEnv,
+		MaxMemory: int64(s.config.lspMaxMemoryMB) << 20,
+	})
+	client, err := lsp.NewClient(s.config.lspCommand, lsp.ServerArgs(s.config.lspCommand, s.config.workspaceDir, s.config.lspArgs)...)
 	if err != nil {
 		return fmt.Errorf("failed to create LSP client: %v", err)
 	}
+	client.SetMaxInFlight(s.config.maxInFlight)
+	client.SetMaxOpenFiles(s.config.maxOpenFiles)
+	client.SetReadOnly(s.config.readOnly)
+	for _, cache := range client.Caches() {
+		memory.Default.Register(cache)
+	}
 	s.lspClient = client
 	s.workspaceWatcher = watcher.NewWorkspaceWatcher(client)
+	s.workspaceWatcher.SetConfig(s.config.watcher)
+	s.workspaceWatcher.SetEagerOpen(s.config.eagerOpen, s.config.eagerOpenMax)
+	s.workspaceWatcher.SetOpenPacing(s.config.openPacing)
+	s.workspaceWatcher.AddFileEventHandler(tools.InvalidateSymbolCache)
+
+	if s.config.symbolIndex {
+		s.loadSymbolIndex()
+	}
 
 	initResult, err := client.InitializeLSPClient(s.ctx, s.config.workspaceDir)
 	if err != nil {
@@ -101,21 +362,76 @@ func (s *server) initializeLSP() error {
 		log.Printf("Server capabilities: %+v\n\n", initResult.Capabilities)
 	}
 
-	go s.workspaceWatcher.WatchWorkspace(s.ctx, s.config.workspaceDir)
-	return client.WaitForServerReady(s.ctx)
+	go s.workspaceWatcher.WatchWorkspace(s.workspaceCtx, s.config.workspaceDir)
+	if err := client.WaitForServerReady(s.ctx); err != nil {
+		return err
+	}
+	s.reconcileSymbolIndex()
+	return nil
 }
 
 func (s *server) start() error {
 	if err := s.initializeLSP(); err != nil {
 		return err
 	}
+	s.workspaceRoots = s.sandbox
+
+	if s.config.warmup {
+		report, err := tools.Warmup(s.ctx, s.lspClient, s.workspaceWatcher, 0, s.config.warmupTimeout)
+		if err != nil {
+			log.Printf("Warmup failed: %v", err)
+		} else {
+			log.Printf("Warmup report:\n%s", report)
+		}
+	}
 
-	s.mcpServer = mcp_golang.NewServer(stdio.NewStdioServerTransport())
+	stdin := &eofReader{Reader: os.Stdin}
+	stdioTransport := stdio.NewStdioServerTransportWithIO(stdin, os.Stdout)
+	stdin.onEOF = func() { stdioTransport.Close() }
+	var mcpTransport transport.Transport = stdioTransport
+	if s.config.httpAddr != "" {
+		if s.config.authToken == "" {
+			log.Printf("Warning: serving MCP over HTTP without an auth token")
+		}
+		sseTransport := newSSETransport(s.config.httpAddr, s.config.authToken)
+		sseTransport.SetSessionCloseHandler(s.closeSession)
+		if s.config.metrics {
+			sseTransport.Handle("/metrics", metricsHandler)
+		}
+		mcpTransport = sseTransport
+	}
+	s.mcpTransport = newSubscriptionTransport(mcpTransport, s.defaults, func() {
+		s.transportClosedOnce.Do(func() { close(s.transportClosed) })
+	})
+	tools.SetCodeFences(s.config.codeFences)
+	tools.SetGeneratedPatterns(s.config.generated)
+	tools.SetSymbolCacheTTL(s.config.symbolTTL)
+	memory.Default.Register(tools.SymbolCache())
+	memory.Default.SetBudget(s.config.memoryBudget)
+	s.mcpServer = mcp_golang.NewServer(s.mcpTransport,
+		mcp_golang.WithName("mcp-language-server"),
+		mcp_golang.WithVersion(serverVersion()))
 	err := s.registerTools()
 	if err != nil {
 		return fmt.Errorf("tool registration failed: %v", err)
 	}
 
+	err = s.applyToolAccess()
+	if err != nil {
+		return fmt.Errorf("tool configuration failed: %v", err)
+	}
+
+	err = s.registerResources()
+	if err != nil {
+		return fmt.Errorf("resource registration failed: %v", err)
+	}
+	s.registerNotifications()
+
+	err = s.registerPrompts()
+	if err != nil {
+		return fmt.Errorf("prompt registration failed: %v", err)
+	}
+
 	return s.mcpServer.Serve()
 }
 
@@ -172,6 +488,9 @@ func main() {
 		case <-parentDeath:
 			log.Printf("Parent death detected, initiating shutdown")
 			cleanup(server, done)
+		case <-server.transportClosed:
+			log.Printf("MCP transport closed, initiating shutdown")
+			cleanup(server, done)
 		}
 	}()
 
@@ -185,40 +504,3 @@ func main() {
 	log.Printf("Server shutdown complete for PID: %d", os.Getpid())
 	os.Exit(0)
 }
-
-func cleanup(s *server, done chan struct{}) {
-	log.Printf("Cleanup initiated for PID: %d", os.Getpid())
-
-	// Create a context with timeout for shutdown operations
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if s.lspClient != nil {
-		log.Printf("Closing open files")
-		s.lspClient.CloseAllFiles(ctx)
-
-		log.Printf("Sending shutdown request")
-		if err := s.lspClient.Shutdown(ctx); err != nil {
-			log.Printf("Shutdown request failed: %v", err)
-		}
-
-		log.Printf("Sending exit notification")
-		if err := s.lspClient.Exit(ctx); err != nil {
-			log.Printf("Exit notification failed: %v", err)
-		}
-
-		log.Printf("Closing LSP client")
-		if err := s.lspClient.Close(); err != nil {
-			log.Printf("Failed to close LSP client: %v", err)
-		}
-	}
-
-	// Send signal to the done channel
-	select {
-	case <-done: // Channel already closed
-	default:
-		close(done)
-	}
-
-	log.Printf("Cleanup completed for PID: %d", os.Getpid())
-}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/memory"
+	"github.com/isaacphi/mcp-language-server/internal/metrics"
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// toolCall is a tools/call request awaiting its response
+type toolCall struct {
+	name  string
+	start time.Time
+	// The language server error the call failed with, see withErrorGuidance
+	lspError *lsp.ResponseError
+}
+
+// trackToolCall notes when a tool call arrived so its latency can be recorded
+func (t *subscriptionTransport) trackToolCall(request *transport.BaseJSONRPCRequest) {
+	var params struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(request.Params, &params); err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	t.toolCalls[request.Id] = toolCall{name: params.Name, start: time.Now()}
+	t.mu.Unlock()
+}
+
+// finishToolCall records a tool call once its response or error is sent. Tools
+// report failures either as a JSON-RPC error or as a result with isError set.
+// Results of calls that failed with a language server error get its code in _meta.
+func (t *subscriptionTransport) finishToolCall(message *transport.BaseJsonRpcMessage) {
+	if message.Type != transport.BaseMessageTypeJSONRPCResponseType && message.Type != transport.BaseMessageTypeJSONRPCErrorType {
+		return
+	}
+	id := responseID(message)
+
+	t.mu.Lock()
+	call, ok := t.toolCalls[id]
+	delete(t.toolCalls, id)
+	t.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	failed := message.Type == transport.BaseMessageTypeJSONRPCErrorType
+	if !failed {
+		var result struct {
+			IsError bool `json:"isError"`
+		}
+		if err := json.Unmarshal(message.JsonRpcResponse.Result, &result); err == nil {
+			failed = result.IsError
+		}
+	}
+	metrics.ObserveTool(call.name, time.Since(call.start), failed)
+
+	if call.lspError != nil && message.Type == transport.BaseMessageTypeJSONRPCResponseType {
+		if result, err := withToolErrorMeta(message.JsonRpcResponse.Result, call.lspError); err == nil {
+			message.JsonRpcResponse.Result = result
+		} else {
+			log.Printf("Failed to add the language server error to the result: %v", err)
+		}
+	}
+}
+
+// metricsHandler serves the metrics and the memory held by caches in the
+// Prometheus text format
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := metrics.Default.WritePrometheus(w); err != nil {
+		return
+	}
+
+	stats := memory.Default.Stats()
+	fmt.Fprintf(w, "# TYPE mcp_language_server_memory_budget_bytes gauge\n")
+	fmt.Fprintf(w, "mcp_language_server_memory_budget_bytes %d\n", memory.Default.Budget())
+	fmt.Fprintf(w, "# TYPE mcp_language_server_cache_bytes gauge\n")
+	for _, cache := range stats {
+		fmt.Fprintf(w, "mcp_language_server_cache_bytes{cache=%q} %d\n", cache.Name, cache.Bytes)
+	}
+	fmt.Fprintf(w, "# TYPE mcp_language_server_cache_entries gauge\n")
+	for _, cache := range stats {
+		fmt.Fprintf(w, "mcp_language_server_cache_entries{cache=%q} %d\n", cache.Name, cache.Entries)
+	}
+	fmt.Fprintf(w, "# TYPE mcp_language_server_cache_evictions_total counter\n")
+	for _, cache := range stats {
+		fmt.Fprintf(w, "mcp_language_server_cache_evictions_total{cache=%q} %d\n", cache.Name, cache.Evictions)
+	}
+}
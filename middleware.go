@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"reflect"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+)
+
+// toolHandler is a tool handler whose arguments are passed as any, so middleware
+// can run around handlers of every argument type
+type toolHandler func(ctx context.Context, args any) (*mcp_golang.ToolResponse, error)
+
+// toolMiddleware runs around a tool handler. It may replace the context, check the
+// arguments and return before calling next, or change what next returns.
+type toolMiddleware func(next toolHandler) toolHandler
+
+var (
+	contextType  = reflect.TypeFor[context.Context]()
+	responseType = reflect.TypeFor[*mcp_golang.ToolResponse]()
+	errorType    = reflect.TypeFor[error]()
+)
+
+// wrapHandler runs middleware around a tool handler, the first one outermost. The
+// result has the handler's type, so the tool's input schema is unchanged. Handlers
+// without the func(context.Context, Args) (*mcp_golang.ToolResponse, error) shape
+// are returned as they are.
+func wrapHandler(handler any, middleware ...toolMiddleware) any {
+	fn := reflect.ValueOf(handler)
+	fnType := fn.Type()
+	if fnType.Kind() != reflect.Func || fnType.NumIn() != 2 || fnType.NumOut() != 2 ||
+		fnType.In(0) != contextType || fnType.Out(0) != responseType || fnType.Out(1) != errorType {
+		return handler
+	}
+
+	call := func(ctx context.Context, args any) (*mcp_golang.ToolResponse, error) {
+		out := fn.Call([]reflect.Value{reflect.ValueOf(&ctx).Elem(), reflect.ValueOf(args)})
+		response, _ := out[0].Interface().(*mcp_golang.ToolResponse)
+		err, _ := out[1].Interface().(error)
+		return response, err
+	}
+	for i := len(middleware) - 1; i >= 0; i-- {
+		call = middleware[i](call)
+	}
+
+	return reflect.MakeFunc(fnType, func(in []reflect.Value) []reflect.Value {
+		ctx, _ := in[0].Interface().(context.Context)
+		response, err := call(ctx, in[1].Interface())
+		return []reflect.Value{reflect.ValueOf(response), reflect.ValueOf(&err).Elem()}
+	}).Interface()
+}
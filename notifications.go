@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// logLevel is an MCP logging level, ordered by severity as in RFC 5424
+type logLevel int
+
+const (
+	logDebug logLevel = iota
+	logInfo
+	logNotice
+	logWarning
+	logError
+	logCritical
+	logAlert
+	logEmergency
+)
+
+var logLevelNames = []string{"debug", "info", "notice", "warning", "error", "critical", "alert", "emergency"}
+
+func (l logLevel) String() string {
+	return logLevelNames[l]
+}
+
+func parseLogLevel(name string) (logLevel, bool) {
+	for i, levelName := range logLevelNames {
+		if levelName == name {
+			return logLevel(i), true
+		}
+	}
+	return 0, false
+}
+
+// fileChangeBatchDelay is how long file events are collected before one notification
+// is sent for all of them, so a checkout or build does not flood clients
+const fileChangeBatchDelay = 500 * time.Millisecond
+
+// handleSetLevel opts a session into log notifications at or above the requested level
+func (t *subscriptionTransport) handleSetLevel(ctx context.Context, request *transport.BaseJSONRPCRequest) {
+	var params struct {
+		Level string `json:"level"`
+	}
+	err := json.Unmarshal(request.Params, &params)
+	level, ok := parseLogLevel(params.Level)
+	if err != nil || !ok {
+		err := t.Transport.Send(ctx, transport.NewBaseMessageError(&transport.BaseJSONRPCError{
+			Jsonrpc: "2.0",
+			Id:      request.Id,
+			Error: transport.BaseJSONRPCErrorInner{
+				Code:    -32602,
+				Message: "Invalid params: level must be one of " + strings.Join(logLevelNames, ", "),
+			},
+		}))
+		if err != nil {
+			log.Printf("Failed to send setLevel error: %v", err)
+		}
+		return
+	}
+
+	t.mu.Lock()
+	t.logLevels[lsp.SessionFromContext(ctx)] = level
+	t.mu.Unlock()
+
+	if debug {
+		log.Printf("logging/setLevel: %s", level)
+	}
+
+	err = t.Transport.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+		Jsonrpc: "2.0",
+		Id:      request.Id,
+		Result:  json.RawMessage("{}"),
+	}))
+	if err != nil {
+		log.Printf("Failed to send setLevel response: %v", err)
+	}
+}
+
+// LogMessage sends a notifications/message to every session that set a log level
+// at or below level. Sessions that never called logging/setLevel get nothing.
+func (t *subscriptionTransport) LogMessage(ctx context.Context, level logLevel, logger string, data interface{}) {
+	t.mu.Lock()
+	var sessions []string
+	for sessionID, minLevel := range t.logLevels {
+		if level >= minLevel {
+			sessions = append(sessions, sessionID)
+		}
+	}
+	t.mu.Unlock()
+	if len(sessions) == 0 {
+		return
+	}
+
+	params, err := json.Marshal(map[string]interface{}{
+		"level":  level.String(),
+		"logger": logger,
+		"data":   data,
+	})
+	if err != nil {
+		return
+	}
+	for _, sessionID := range sessions {
+		t.queueNotification(lsp.WithSession(ctx, sessionID), transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+			Jsonrpc: "2.0",
+			Method:  "notifications/message",
+			Params:  params,
+		}), "")
+	}
+}
+
+// registerNotifications pushes file changes, skipped files and diagnostic changes to
+// clients as log notifications, so agents can react without polling. File events are
+// batched, diagnostics are only reported when a file's error or warning count changes.
+func (s *server) registerNotifications() {
+	var (
+		mu      sync.Mutex
+		pending = make(map[string]protocol.FileChangeType)
+		timer   *time.Timer
+	)
+	flush := func() {
+		mu.Lock()
+		changes := pending
+		pending = make(map[string]protocol.FileChangeType)
+		timer = nil
+		mu.Unlock()
+		s.notifyFileChanges(changes)
+	}
+	s.workspaceWatcher.AddFileEventHandler(func(path string, changeType protocol.FileChangeType) {
+		mu.Lock()
+		defer mu.Unlock()
+		// A file created and then changed within a batch is still new
+		if previous, exists := pending[path]; !exists || previous != protocol.FileChangeType(protocol.Created) || changeType == protocol.FileChangeType(protocol.Deleted) {
+			pending[path] = changeType
+		}
+		if timer == nil {
+			timer = time.AfterFunc(fileChangeBatchDelay, flush)
+		}
+	})
+
+	s.workspaceWatcher.AddSkippedFileHandler(func(path string, reason watcher.SkipReason, size int64) {
+		s.mcpTransport.LogMessage(s.ctx, logInfo, "watcher", map[string]interface{}{
+			"message": fmt.Sprintf("Skipped %s file %s", reason, s.relativePath(path)),
+			"path":    s.relativePath(path),
+			"reason":  string(reason),
+			"size":    size,
+		})
+	})
+
+	type counts struct{ errors, warnings int }
+	var (
+		countsMu sync.Mutex
+		known    = make(map[protocol.DocumentUri]counts)
+	)
+	s.lspClient.AddDiagnosticsHandler(func(uri protocol.DocumentUri, diagnostics []protocol.Diagnostic) {
+		var current counts
+		for _, diag := range diagnostics {
+			switch diag.Severity {
+			case protocol.SeverityError:
+				current.errors++
+			case protocol.SeverityWarning:
+				current.warnings++
+			}
+		}
+
+		countsMu.Lock()
+		previous := known[uri]
+		if current == (counts{}) {
+			delete(known, uri)
+		} else {
+			known[uri] = current
+		}
+		countsMu.Unlock()
+		if current == previous {
+			return
+		}
+
+		path := s.relativePath(strings.TrimPrefix(string(uri), "file://"))
+		var message string
+		switch {
+		case current.errors > previous.errors:
+			message = fmt.Sprintf("%s appeared in %s", pluralize(current.errors-previous.errors, "error"), path)
+		case current.errors == 0 && previous.errors > 0:
+			message = fmt.Sprintf("All errors in %s are resolved", path)
+		default:
+			message = fmt.Sprintf("%s now has %s and %s", path, pluralize(current.errors, "error"), pluralize(current.warnings, "warning"))
+		}
+
+		level := logInfo
+		if current.errors > 0 {
+			level = logWarning
+		}
+		s.mcpTransport.LogMessage(s.ctx, level, "diagnostics", map[string]interface{}{
+			"message":  message,
+			"uri":      string(uri),
+			"errors":   current.errors,
+			"warnings": current.warnings,
+		})
+	})
+}
+
+// notifyFileChanges sends one notification describing a batch of file events
+func (s *server) notifyFileChanges(changes map[string]protocol.FileChangeType) {
+	if len(changes) == 0 {
+		return
+	}
+
+	paths := make([]string, 0, len(changes))
+	for path := range changes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	files := make([]map[string]string, 0, len(paths))
+	for _, path := range paths {
+		files = append(files, map[string]string{
+			"path":   s.relativePath(path),
+			"change": watcher.ChangeTypeName(changes[path]),
+		})
+	}
+
+	var message string
+	if len(files) == 1 {
+		message = fmt.Sprintf("File %s: %s", files[0]["change"], files[0]["path"])
+	} else {
+		message = fmt.Sprintf("%d files changed", len(files))
+	}
+	s.mcpTransport.LogMessage(s.ctx, logInfo, "watcher", map[string]interface{}{
+		"message": message,
+		"files":   files,
+	})
+}
+
+// relativePath shortens a path to be relative to the workspace when it is inside it
+func (s *server) relativePath(path string) string {
+	if rel, err := filepath.Rel(s.config.workspaceDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
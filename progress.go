@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// progressKey is the context key of a tool call's progressReporter
+type progressKey struct{}
+
+// progressReporter sends notifications/progress for a tool call whose request
+// carried a progressToken. Tools that stream their output send its parts as the
+// notifications' messages.
+type progressReporter struct {
+	transport *subscriptionTransport
+	ctx       context.Context
+	token     json.RawMessage
+
+	mu   sync.Mutex
+	sent int
+}
+
+// withProgress returns ctx with a progressReporter when a tools/call request asks
+// for progress notifications
+func (t *subscriptionTransport) withProgress(ctx context.Context, request *transport.BaseJSONRPCRequest) context.Context {
+	var params struct {
+		Meta struct {
+			ProgressToken json.RawMessage `json:"progressToken"`
+		} `json:"_meta"`
+	}
+	if err := json.Unmarshal(request.Params, &params); err != nil {
+		return ctx
+	}
+	token := params.Meta.ProgressToken
+	if len(token) == 0 || string(token) == "null" {
+		return ctx
+	}
+	return context.WithValue(ctx, progressKey{}, &progressReporter{transport: t, ctx: ctx, token: token})
+}
+
+// progressFromContext returns the tool call's progressReporter, nil when the
+// client did not ask for progress
+func progressFromContext(ctx context.Context) *progressReporter {
+	reporter, _ := ctx.Value(progressKey{}).(*progressReporter)
+	return reporter
+}
+
+// Report sends message as the next progress notification of the call. The
+// progress value counts the notifications, the total is not known in advance.
+func (p *progressReporter) Report(message string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sent++
+
+	params, err := json.Marshal(map[string]interface{}{
+		"progressToken": p.token,
+		"progress":      p.sent,
+		"message":       message,
+	})
+	if err != nil {
+		return err
+	}
+	return p.transport.Transport.Send(p.ctx, transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+		Jsonrpc: "2.0",
+		Method:  "notifications/progress",
+		Params:  params,
+	}))
+}
+
+// Sent returns the number of notifications sent so far
+func (p *progressReporter) Sent() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.sent
+}
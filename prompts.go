@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	mcp_golang "github.com/metoro-io/mcp-golang"
+)
+
+type ExplainSymbolPromptArgs struct {
+	SymbolName string `json:"symbolName" jsonschema:"required,description=The symbol to explain (e.g. 'mypackage.MyFunction' or 'MyType')"`
+}
+
+type ImpactAnalysisPromptArgs struct {
+	SymbolName string  `json:"symbolName" jsonschema:"required,description=The symbol that will change"`
+	Change     *string `json:"change" jsonschema:"description=A description of the planned change"`
+}
+
+type RefactorPlanPromptArgs struct {
+	SymbolName string  `json:"symbolName" jsonschema:"required,description=The symbol to refactor"`
+	Goal       *string `json:"goal" jsonschema:"description=What the refactor should achieve"`
+}
+
+// registerPrompts registers prompts for common code navigation workflows. Each prompt
+// includes the results of the tool calls it starts from and suggests follow-up calls.
+func (s *server) registerPrompts() error {
+	err := s.mcpServer.RegisterPrompt(
+		"explain_symbol",
+		"Explain what a symbol does, starting from its definition.",
+		func(args ExplainSymbolPromptArgs) (*mcp_golang.PromptResponse, error) {
+			var prompt strings.Builder
+			prompt.WriteString(fmt.Sprintf("Explain the symbol `%s` in this codebase: its purpose, inputs and outputs, side effects, and how it is typically used.\n\n", args.SymbolName))
+			prompt.WriteString(s.promptToolResult("read_definition", func() (string, error) {
+				return tools.ReadDefinition(s.ctx, s.lspClient, args.SymbolName, true, false, tools.DefinitionOptions{})
+			}))
+			prompt.WriteString(fmt.Sprintf("If you need examples of how it is used, call `find_references` with symbolName `%s`. ", args.SymbolName))
+			prompt.WriteString("Use `hover` on unfamiliar identifiers in the definition to see their types and documentation.")
+			return promptResponse("Explain "+args.SymbolName, prompt.String()), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register prompt: %v", err)
+	}
+
+	err = s.mcpServer.RegisterPrompt(
+		"impact_analysis",
+		"Assess the impact of changing a symbol, starting from its definition and references.",
+		func(args ImpactAnalysisPromptArgs) (*mcp_golang.PromptResponse, error) {
+			var prompt strings.Builder
+			prompt.WriteString(fmt.Sprintf("Analyze the impact of changing `%s`", args.SymbolName))
+			if args.Change != nil && *args.Change != "" {
+				prompt.WriteString(fmt.Sprintf(" as follows: %s", *args.Change))
+			}
+			prompt.WriteString(".\nList every affected call site, which of them would break or change behavior, and how risky the change is overall.\n\n")
+			prompt.WriteString(s.promptToolResult("read_definition", func() (string, error) {
+				return tools.ReadDefinition(s.ctx, s.lspClient, args.SymbolName, true, false, tools.DefinitionOptions{})
+			}))
+			prompt.WriteString(s.promptToolResult("find_references", func() (string, error) {
+				return tools.FindReferences(s.ctx, s.lspClient, args.SymbolName, true, false, tools.ReferenceOptions{})
+			}))
+			prompt.WriteString("If the symbol is an interface, call `implementations` to find the types that must change with it. ")
+			prompt.WriteString("If the change affects a package's API, call `who_imports` with the package path. ")
+			prompt.WriteString("Call `get_diagnostics` on affected files after any edit to confirm nothing broke.")
+			return promptResponse("Impact of changing "+args.SymbolName, prompt.String()), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register prompt: %v", err)
+	}
+
+	err = s.mcpServer.RegisterPrompt(
+		"refactor_plan",
+		"Prepare a step by step plan for refactoring a symbol without making any edits.",
+		func(args RefactorPlanPromptArgs) (*mcp_golang.PromptResponse, error) {
+			var prompt strings.Builder
+			prompt.WriteString(fmt.Sprintf("Prepare a refactoring plan for `%s`", args.SymbolName))
+			if args.Goal != nil && *args.Goal != "" {
+				prompt.WriteString(fmt.Sprintf(" with the goal: %s", *args.Goal))
+			}
+			prompt.WriteString(".\nDo not edit any files yet. Produce an ordered list of steps, naming the file and symbol each step touches, and note which steps can be done with `rename_symbol` rather than manual edits.\n\n")
+			prompt.WriteString(s.promptToolResult("read_definition", func() (string, error) {
+				return tools.ReadDefinition(s.ctx, s.lspClient, args.SymbolName, true, false, tools.DefinitionOptions{})
+			}))
+			prompt.WriteString(s.promptToolResult("find_references", func() (string, error) {
+				return tools.FindReferences(s.ctx, s.lspClient, args.SymbolName, true, false, tools.ReferenceOptions{})
+			}))
+			prompt.WriteString("Use `document_symbols` or `file_outline` on the files involved to understand their structure before planning.")
+			return promptResponse("Refactor plan for "+args.SymbolName, prompt.String()), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register prompt: %v", err)
+	}
+
+	return nil
+}
+
+// promptToolResult runs a tool for a prompt and formats its output, or the error it returned
+func (s *server) promptToolResult(name string, run func() (string, error)) string {
+	text, err := run()
+	if err != nil {
+		return fmt.Sprintf("Result of `%s`: failed: %v\n\n", name, err)
+	}
+	return fmt.Sprintf("Result of `%s`:\n\n%s\n\n", name, strings.TrimSpace(text))
+}
+
+func promptResponse(description string, text string) *mcp_golang.PromptResponse {
+	return mcp_golang.NewPromptResponse(description, mcp_golang.NewPromptMessage(mcp_golang.NewTextContent(text), mcp_golang.RoleUser))
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"log"
+	"mime"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	mcp_golang "github.com/metoro-io/mcp-golang"
+)
+
+// maxFileResources caps the number of workspace files exposed as resources
+const maxFileResources = 10000
+
+// registerResources exposes workspace files as MCP resources with file:// URIs,
+// along with the change journal and diagnostics.
+func (s *server) registerResources() error {
+	if err := s.registerChangesResource(); err != nil {
+		return err
+	}
+	if err := s.registerDiagnosticsResources(); err != nil {
+		return err
+	}
+	return s.registerWorkspaceResources()
+}
+
+// registerWorkspaceResources registers the resources for the files of the current
+// workspace and hooks them up to its watcher and language server. Files created or
+// deleted later are added and removed as the watcher reports them.
+func (s *server) registerWorkspaceResources() error {
+	count := 0
+
+	err := s.workspaceWatcher.WalkWorkspace(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip unreadable entries
+		}
+		if d.IsDir() {
+			if s.workspaceWatcher.IsExcluded(path, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if s.workspaceWatcher.IsExcluded(path, false) {
+			return nil
+		}
+		if count >= maxFileResources {
+			log.Printf("Workspace has more than %d files, not all are exposed as resources", maxFileResources)
+			return filepath.SkipAll
+		}
+
+		if err := s.registerFileResource(path); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register file resources: %v", err)
+	}
+
+	s.workspaceWatcher.AddFileEventHandler(func(path string, changeType protocol.FileChangeType) {
+		uri := "file://" + path
+		switch changeType {
+		case protocol.FileChangeType(protocol.Created):
+			if err := s.registerFileResource(path); err != nil {
+				log.Printf("Failed to register resource %s: %v", uri, err)
+			}
+		case protocol.FileChangeType(protocol.Changed):
+			s.mcpTransport.ResourceUpdated(s.ctx, uri)
+		case protocol.FileChangeType(protocol.Deleted):
+			s.deregisterWorkspaceResource(uri)
+		}
+		s.mcpTransport.ResourceUpdated(s.ctx, recentChangesURI)
+	})
+
+	if debug {
+		log.Printf("Registered %d file resources", count)
+	}
+
+	for uri := range s.lspClient.GetAllDiagnostics() {
+		s.registerFileDiagnosticsResource(uri)
+	}
+	s.lspClient.AddDiagnosticsHandler(func(uri protocol.DocumentUri, diagnostics []protocol.Diagnostic) {
+		s.mcpTransport.ResourceUpdated(s.ctx, s.registerFileDiagnosticsResource(uri))
+		s.mcpTransport.ResourceUpdated(s.ctx, workspaceDiagnosticsURI)
+	})
+
+	return nil
+}
+
+// registerWorkspaceResource registers a resource belonging to the current workspace,
+// unless it is already registered
+func (s *server) registerWorkspaceResource(uri, name, description, mimeType string, handler any) error {
+	s.workspaceResourcesMu.Lock()
+	defer s.workspaceResourcesMu.Unlock()
+	if s.workspaceResources[uri] {
+		return nil
+	}
+	if err := s.mcpServer.RegisterResource(uri, name, description, mimeType, handler); err != nil {
+		return err
+	}
+	s.workspaceResources[uri] = true
+	return nil
+}
+
+// deregisterWorkspaceResource removes a resource registered by registerWorkspaceResource
+func (s *server) deregisterWorkspaceResource(uri string) {
+	s.workspaceResourcesMu.Lock()
+	defer s.workspaceResourcesMu.Unlock()
+	if !s.workspaceResources[uri] {
+		return
+	}
+	delete(s.workspaceResources, uri)
+	if err := s.mcpServer.DeregisterResource(uri); err != nil {
+		log.Printf("Failed to deregister resource %s: %v", uri, err)
+	}
+}
+
+// deregisterWorkspaceResources removes the resources of the current workspace, before
+// switching to another
+func (s *server) deregisterWorkspaceResources() {
+	s.workspaceResourcesMu.Lock()
+	uris := make([]string, 0, len(s.workspaceResources))
+	for uri := range s.workspaceResources {
+		uris = append(uris, uri)
+	}
+	s.workspaceResourcesMu.Unlock()
+
+	for _, uri := range uris {
+		s.deregisterWorkspaceResource(uri)
+	}
+}
+
+// recentChangesURI is the resource listing recent file events in the workspace
+const recentChangesURI = "changes://recent"
+
+// registerChangesResource exposes the watcher's change journal as a resource.
+// Subscribers are notified whenever a workspace file is created, changed or deleted,
+// see registerWorkspaceResources.
+func (s *server) registerChangesResource() error {
+	err := s.mcpServer.RegisterResource(
+		recentChangesURI,
+		"Recent changes",
+		"Files recently created, changed or deleted in the workspace",
+		"text/plain",
+		func() (*mcp_golang.ResourceResponse, error) {
+			s.workspaceMu.RLock()
+			defer s.workspaceMu.RUnlock()
+			text, err := tools.RecentChanges(s.workspaceWatcher, 0, time.Time{}, 0)
+			if err != nil {
+				return nil, err
+			}
+			return mcp_golang.NewResourceResponse(mcp_golang.NewTextEmbeddedResource(recentChangesURI, text, "text/plain")), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register changes resource: %v", err)
+	}
+	return nil
+}
+
+// workspaceDiagnosticsURI is the resource summarizing diagnostics for the whole workspace
+const workspaceDiagnosticsURI = "diagnostics://workspace"
+
+// registerDiagnosticsResources exposes cached diagnostics as diagnostics:// resources,
+// one per file plus a workspace summary. Subscribers are notified when the language
+// server publishes new diagnostics, see registerWorkspaceResources.
+func (s *server) registerDiagnosticsResources() error {
+	err := s.mcpServer.RegisterResource(
+		workspaceDiagnosticsURI,
+		"Workspace diagnostics",
+		"Number of errors and warnings per file in the workspace",
+		"text/plain",
+		func() (*mcp_golang.ResourceResponse, error) {
+			s.workspaceMu.RLock()
+			defer s.workspaceMu.RUnlock()
+			text := tools.FormatDiagnosticsSummary(s.lspClient.GetAllDiagnostics())
+			return mcp_golang.NewResourceResponse(mcp_golang.NewTextEmbeddedResource(workspaceDiagnosticsURI, text, "text/plain")), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register diagnostics resource: %v", err)
+	}
+
+	return nil
+}
+
+// registerFileDiagnosticsResource registers the diagnostics:// resource for a file
+// and returns its URI
+func (s *server) registerFileDiagnosticsResource(uri protocol.DocumentUri) string {
+	path := strings.TrimPrefix(string(uri), "file://")
+	resourceURI := "diagnostics://" + path
+
+	err := s.registerWorkspaceResource(
+		resourceURI,
+		"Diagnostics for "+path,
+		"Errors and warnings published by the language server for "+path,
+		"text/plain",
+		func() (*mcp_golang.ResourceResponse, error) {
+			s.workspaceMu.RLock()
+			defer s.workspaceMu.RUnlock()
+			text := tools.FormatDiagnostics(path, s.lspClient.GetFileDiagnostics(uri))
+			return mcp_golang.NewResourceResponse(mcp_golang.NewTextEmbeddedResource(resourceURI, text, "text/plain")), nil
+		},
+	)
+	if err != nil {
+		log.Printf("Failed to register resource %s: %v", resourceURI, err)
+	}
+	return resourceURI
+}
+
+// registerFileResource registers a single workspace file as a resource
+func (s *server) registerFileResource(path string) error {
+	uri := "file://" + path
+	name, err := filepath.Rel(s.config.workspaceDir, path)
+	if err != nil {
+		name = path
+	}
+	mimeType := fileMimeType(path)
+
+	return s.registerWorkspaceResource(
+		uri,
+		name,
+		fmt.Sprintf("Workspace file (%s)", lsp.DetectLanguageID(uri)),
+		mimeType,
+		func() (*mcp_golang.ResourceResponse, error) {
+			s.workspaceMu.RLock()
+			defer s.workspaceMu.RUnlock()
+			// Open files are read from the language server's view of the document
+			content, err := s.lspClient.GetFileContent(path)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to read file: %v", err)
+			}
+			if bytes.IndexByte(content[:min(len(content), 8000)], 0) != -1 {
+				return mcp_golang.NewResourceResponse(
+					mcp_golang.NewBlobEmbeddedResource(uri, base64.StdEncoding.EncodeToString(content), "application/octet-stream"),
+				), nil
+			}
+			return mcp_golang.NewResourceResponse(mcp_golang.NewTextEmbeddedResource(uri, string(content), mimeType)), nil
+		},
+	)
+}
+
+// fileMimeType guesses a file's MIME type from its extension, defaulting to plain text
+func fileMimeType(path string) string {
+	if mimeType := mime.TypeByExtension(filepath.Ext(path)); mimeType != "" {
+		return mimeType
+	}
+	return "text/plain"
+}
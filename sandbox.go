@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+)
+
+// pathArguments are the JSON names of tool arguments holding file or directory paths
+var pathArguments = map[string]bool{
+	"filePath":        true,
+	"filePaths":       true,
+	"directory":       true,
+	"profilePath":     true,
+	"coverageProfile": true,
+	"destinationFile": true,
+	"scopePath":       true,
+	"notebookPath":    true,
+}
+
+// sandboxed fails tool calls whose path arguments resolve outside the sandbox
+// before the tool runs
+func (s *server) sandboxed(next toolHandler) toolHandler {
+	return func(ctx context.Context, args any) (*mcp_golang.ToolResponse, error) {
+		if err := s.checkPathArguments(reflect.ValueOf(args)); err != nil {
+			return nil, fmt.Errorf("Access denied: %v", err)
+		}
+		return next(ctx, args)
+	}
+}
+
+// checkPathArguments checks the path fields of a tool's arguments, including those
+// of nested structs and slices such as hover_batch positions
+func (s *server) checkPathArguments(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return s.checkPathArguments(v.Elem())
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := s.checkPathArguments(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			value := v.Field(i)
+			if pathArguments[name] && value.Kind() == reflect.String {
+				if path := value.String(); path != "" {
+					if err := s.sandbox.Check(path); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if pathArguments[name] && value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.String {
+				for j := 0; j < value.Len(); j++ {
+					if path := value.Index(j).String(); path != "" {
+						if err := s.sandbox.Check(path); err != nil {
+							return err
+						}
+					}
+				}
+				continue
+			}
+			if err := s.checkPathArguments(value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
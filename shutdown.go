@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+)
+
+// eofReader calls onEOF once when the underlying reader is exhausted. The stdio
+// transport stops reading at EOF without closing, so this is how a client that
+// closes stdin is noticed.
+type eofReader struct {
+	io.Reader
+	onEOF func()
+	once  sync.Once
+}
+
+func (r *eofReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err == io.EOF && r.onEOF != nil {
+		r.once.Do(r.onEOF)
+	}
+	return n, err
+}
+
+// cleanup shuts the server down: pending watcher events are dropped, the language
+// server gets shutdown and exit, the MCP transport and debug log are closed, and
+// done is closed. Concurrent calls wait for the first to finish.
+func cleanup(s *server, done chan struct{}) {
+	s.cleanupOnce.Do(func() {
+		log.Printf("Cleanup initiated for PID: %d", os.Getpid())
+
+		// Create a context with timeout for shutdown operations
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		// Ends background work tied to the server
+		s.cancelFunc()
+		s.shutdownLSP(ctx)
+
+		if s.mcpTransport != nil {
+			if err := s.mcpTransport.Close(); err != nil {
+				log.Printf("Failed to close MCP transport: %v", err)
+			}
+		}
+
+		if err := tools.CloseDebugLog(); err != nil {
+			log.Printf("Failed to close debug log: %v", err)
+		}
+
+		log.Printf("Cleanup completed for PID: %d", os.Getpid())
+		close(done)
+	})
+}
+
+// shutdownLSP stops the workspace watcher, gives the language server shutdown and
+// exit, and saves the symbol index. It runs on exit and when the workspace is switched.
+func (s *server) shutdownLSP(ctx context.Context) {
+	if s.workspaceWatcher != nil {
+		log.Printf("Stopping workspace watcher")
+		s.workspaceWatcher.Stop()
+	}
+	// Ends the watch loop, the poller and the symbol index reconcile
+	if s.workspaceCancel != nil {
+		s.workspaceCancel()
+	}
+
+	if s.lspClient != nil {
+		log.Printf("Closing open files")
+		s.lspClient.CloseAllFiles(ctx)
+
+		log.Printf("Sending shutdown request")
+		if err := s.lspClient.Shutdown(ctx); err != nil {
+			log.Printf("Shutdown request failed: %v", err)
+		}
+
+		log.Printf("Sending exit notification")
+		if err := s.lspClient.Exit(ctx); err != nil {
+			log.Printf("Exit notification failed: %v", err)
+		}
+
+		log.Printf("Closing LSP client")
+		if err := s.lspClient.Close(); err != nil {
+			log.Printf("Failed to close LSP client: %v", err)
+		}
+	}
+
+	// Keeps reference counts recorded since the last reconcile
+	if s.symbolIndex != nil {
+		if err := s.symbolIndex.Save(); err != nil {
+			log.Printf("Failed to save symbol index: %v", err)
+		}
+	}
+}
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// maxMessageSize limits the size of a message posted by a client
+const maxMessageSize = 4 * 1024 * 1024
+
+// sessionSendTimeout is how long a message waits for room in a session's event
+// queue. A client that does not read its stream for that long is disconnected.
+const sessionSendTimeout = 5 * time.Second
+
+// sseTransport serves MCP over HTTP using the SSE transport: clients open an event
+// stream with GET /sse, receive the endpoint to post messages to, and get responses
+// and notifications as events on the stream.
+//
+// Several clients can be connected at once. Request IDs are rewritten to be unique
+// across sessions so responses can be routed back to the session that asked, and
+// requests are handled with a context carrying the session ID.
+type sseTransport struct {
+	addr      string
+	authToken string
+	server    *http.Server
+
+	mu              sync.Mutex
+	sessions        map[string]*sseSession
+	pending         map[transport.RequestId]pendingRequest
+	nextID          transport.RequestId
+	onMessage       func(ctx context.Context, message *transport.BaseJsonRpcMessage)
+	onClose         func()
+	onError         func(error)
+	onSessionClosed func(sessionID string)
+
+	// Additional endpoints served next to the MCP ones, behind the same auth
+	handlers map[string]http.HandlerFunc
+}
+
+// sseSession is a connected client
+type sseSession struct {
+	id     string
+	events chan []byte
+
+	// Closed to end the event stream of a client that stopped reading it
+	dropped  chan struct{}
+	dropOnce sync.Once
+}
+
+// drop ends the session's event stream, the client then has to reconnect
+func (s *sseSession) drop() {
+	s.dropOnce.Do(func() { close(s.dropped) })
+}
+
+// pendingRequest is a request awaiting a response, keyed by its rewritten ID
+type pendingRequest struct {
+	sessionID string
+	id        transport.RequestId
+}
+
+func newSSETransport(addr string, authToken string) *sseTransport {
+	return &sseTransport{
+		addr:      addr,
+		authToken: authToken,
+		sessions:  make(map[string]*sseSession),
+		pending:   make(map[transport.RequestId]pendingRequest),
+		handlers:  make(map[string]http.HandlerFunc),
+	}
+}
+
+// Handle serves an additional endpoint, it must be called before Start
+func (t *sseTransport) Handle(pattern string, handler http.HandlerFunc) {
+	t.handlers[pattern] = handler
+}
+
+// Start listens on the configured address and serves requests in the background
+func (t *sseTransport) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", t.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", t.addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", t.authorize(t.handleStream))
+	mux.HandleFunc("/message", t.authorize(t.handleMessage))
+	for pattern, handler := range t.handlers {
+		mux.HandleFunc(pattern, t.authorize(handler))
+	}
+	t.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	log.Printf("Serving MCP over HTTP at http://%s/sse", listener.Addr())
+	go func() {
+		if err := t.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			t.handleError(fmt.Errorf("http server error: %v", err))
+		}
+	}()
+	return nil
+}
+
+// Send routes responses to the session that made the request. Other messages go to
+// the session of the context, or to every session if the context has none.
+func (t *sseTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	var targets []*sseSession
+
+	t.mu.Lock()
+	switch message.Type {
+	case transport.BaseMessageTypeJSONRPCResponseType, transport.BaseMessageTypeJSONRPCErrorType:
+		id := responseID(message)
+		request, ok := t.pending[id]
+		if !ok {
+			t.mu.Unlock()
+			return fmt.Errorf("no pending request with id %d", id)
+		}
+		delete(t.pending, id)
+		setResponseID(message, request.id)
+		if session, ok := t.sessions[request.sessionID]; ok {
+			targets = append(targets, session)
+		}
+	default:
+		if sessionID := lsp.SessionFromContext(ctx); sessionID != "" {
+			if session, ok := t.sessions[sessionID]; ok {
+				targets = append(targets, session)
+			}
+		} else {
+			for _, session := range t.sessions {
+				targets = append(targets, session)
+			}
+		}
+	}
+	t.mu.Unlock()
+
+	if len(targets) == 0 {
+		return fmt.Errorf("no client connected")
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	var sendErr error
+	for _, session := range targets {
+		if err := t.deliver(ctx, session, data); err != nil && sendErr == nil {
+			sendErr = err
+		}
+	}
+	return sendErr
+}
+
+// deliver queues a message on a session's event stream. It waits at most
+// sessionSendTimeout for a client that is behind, and drops the session instead of
+// holding up the caller any longer.
+func (t *sseTransport) deliver(ctx context.Context, session *sseSession, data []byte) error {
+	select {
+	case session.events <- data:
+		return nil
+	case <-session.dropped:
+		return fmt.Errorf("session %s was disconnected", session.id)
+	default:
+	}
+
+	timer := time.NewTimer(sessionSendTimeout)
+	defer timer.Stop()
+	select {
+	case session.events <- data:
+		return nil
+	case <-session.dropped:
+		return fmt.Errorf("session %s was disconnected", session.id)
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		log.Printf("MCP session %s is not reading its event stream, disconnecting it", session.id)
+		session.drop()
+		return fmt.Errorf("session %s is not reading its event stream", session.id)
+	}
+}
+
+// Close shuts down the HTTP server
+func (t *sseTransport) Close() error {
+	var err error
+	if t.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err = t.server.Shutdown(ctx)
+	}
+
+	t.mu.Lock()
+	onClose := t.onClose
+	t.mu.Unlock()
+	if onClose != nil {
+		onClose()
+	}
+	return err
+}
+
+func (t *sseTransport) SetCloseHandler(handler func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onClose = handler
+}
+
+func (t *sseTransport) SetErrorHandler(handler func(error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onError = handler
+}
+
+func (t *sseTransport) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onMessage = handler
+}
+
+// SetSessionCloseHandler sets the callback for when a client disconnects
+func (t *sseTransport) SetSessionCloseHandler(handler func(sessionID string)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onSessionClosed = handler
+}
+
+// closeSession releases what a disconnected session held: its subscriptions, its
+// defaults and the documents only it had open
+func (s *server) closeSession(sessionID string) {
+	s.mcpTransport.forgetSession(sessionID)
+	s.defaults.forget(sessionID)
+	if s.lspClient != nil {
+		s.lspClient.ReleaseSession(s.ctx, sessionID)
+	}
+}
+
+// authorize rejects requests without the configured bearer token
+func (t *sseTransport) authorize(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if t.authToken != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(t.authToken)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// handleStream opens an event stream and announces the endpoint for posting messages
+func (t *sseTransport) handleStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	sessionID, err := newSessionID()
+	if err != nil {
+		http.Error(w, "failed to create session", http.StatusInternalServerError)
+		return
+	}
+	session := &sseSession{id: sessionID, events: make(chan []byte, 64), dropped: make(chan struct{})}
+
+	t.mu.Lock()
+	t.sessions[sessionID] = session
+	t.mu.Unlock()
+	log.Printf("MCP session %s connected from %s", sessionID, r.RemoteAddr)
+
+	defer t.closeSession(sessionID)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "event: endpoint\ndata: /message?sessionId=%s\n\n", sessionID)
+	flusher.Flush()
+
+	// A client that stops reading would otherwise block a write, and with it the
+	// stream, forever
+	controller := http.NewResponseController(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-session.dropped:
+			return
+		case data := <-session.events:
+			_ = controller.SetWriteDeadline(time.Now().Add(sessionSendTimeout))
+			if _, err := fmt.Fprintf(w, "event: message\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			if err := controller.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleMessage accepts a JSON-RPC message posted by the client of a session
+func (t *sseTransport) handleMessage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID := r.URL.Query().Get("sessionId")
+	t.mu.Lock()
+	_, ok := t.sessions[sessionID]
+	handler := t.onMessage
+	t.mu.Unlock()
+	if !ok {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxMessageSize))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	message, err := decodeMessage(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if message.Type == transport.BaseMessageTypeJSONRPCRequestType {
+		// Make the ID unique across sessions so the response can be routed back
+		t.mu.Lock()
+		t.nextID++
+		t.pending[t.nextID] = pendingRequest{sessionID: sessionID, id: message.JsonRpcRequest.Id}
+		message.JsonRpcRequest.Id = t.nextID
+		t.mu.Unlock()
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+
+	// Responses are sent on the event stream, which outlives this request
+	if handler != nil {
+		handler(lsp.WithSession(context.Background(), sessionID), message)
+	}
+}
+
+// closeSession forgets a disconnected session and its pending requests
+func (t *sseTransport) closeSession(sessionID string) {
+	t.mu.Lock()
+	delete(t.sessions, sessionID)
+	for id, request := range t.pending {
+		if request.sessionID == sessionID {
+			delete(t.pending, id)
+		}
+	}
+	onSessionClosed := t.onSessionClosed
+	t.mu.Unlock()
+
+	log.Printf("MCP session %s disconnected", sessionID)
+	if onSessionClosed != nil {
+		onSessionClosed(sessionID)
+	}
+}
+
+func responseID(message *transport.BaseJsonRpcMessage) transport.RequestId {
+	if message.Type == transport.BaseMessageTypeJSONRPCErrorType {
+		return message.JsonRpcError.Id
+	}
+	return message.JsonRpcResponse.Id
+}
+
+func setResponseID(message *transport.BaseJsonRpcMessage, id transport.RequestId) {
+	if message.Type == transport.BaseMessageTypeJSONRPCErrorType {
+		message.JsonRpcError.Id = id
+	} else {
+		message.JsonRpcResponse.Id = id
+	}
+}
+
+func (t *sseTransport) handleError(err error) {
+	t.mu.Lock()
+	handler := t.onError
+	t.mu.Unlock()
+
+	if handler != nil {
+		handler(err)
+	} else {
+		log.Printf("%v", err)
+	}
+}
+
+// decodeMessage parses a JSON-RPC request, notification, response or error
+func decodeMessage(data []byte) (*transport.BaseJsonRpcMessage, error) {
+	var request transport.BaseJSONRPCRequest
+	if err := json.Unmarshal(data, &request); err == nil {
+		return transport.NewBaseMessageRequest(&request), nil
+	}
+	var notification transport.BaseJSONRPCNotification
+	if err := json.Unmarshal(data, &notification); err == nil {
+		return transport.NewBaseMessageNotification(&notification), nil
+	}
+	var response transport.BaseJSONRPCResponse
+	if err := json.Unmarshal(data, &response); err == nil {
+		return transport.NewBaseMessageResponse(&response), nil
+	}
+	var errorResponse transport.BaseJSONRPCError
+	if err := json.Unmarshal(data, &errorResponse); err == nil {
+		return transport.NewBaseMessageError(&errorResponse), nil
+	}
+	return nil, fmt.Errorf("invalid JSON-RPC message")
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/metoro-io/mcp-golang/transport"
+)
+
+// subscriptionTransport wraps an MCP transport to support resource subscriptions
+// and log notifications, which mcp-golang does not implement. Subscribe, unsubscribe
+// and logging/setLevel requests are answered here and never reach the server, and
+// the initialize response is amended to advertise both. State is tracked per session.
+// Tool calls get the argument defaults of their session filled in.
+type subscriptionTransport struct {
+	transport.Transport
+
+	mu            sync.Mutex
+	subscriptions map[string]map[string]bool // URI to subscribed sessions
+	logLevels     map[string]logLevel        // Minimum level of log notifications per session
+	initializeIDs map[transport.RequestId]bool
+	toolCalls     map[transport.RequestId]toolCall // Tool calls awaiting a response, for metrics
+	defaults      *sessionDefaults
+
+	// Notifications waiting to be sent, see queueNotification
+	outboxMu      sync.Mutex
+	outbox        []queuedNotification
+	queuedKeys    map[string]bool // Keys of the queued notifications that coalesce
+	sending       bool            // A goroutine is sending the outbox
+	droppedNotify int             // Notifications dropped since the outbox was last empty
+
+	// Called after the underlying transport closes
+	onClosed func()
+}
+
+func newSubscriptionTransport(t transport.Transport, defaults *sessionDefaults, onClosed func()) *subscriptionTransport {
+	return &subscriptionTransport{
+		Transport:     t,
+		onClosed:      onClosed,
+		defaults:      defaults,
+		subscriptions: make(map[string]map[string]bool),
+		logLevels:     make(map[string]logLevel),
+		initializeIDs: make(map[transport.RequestId]bool),
+		toolCalls:     make(map[transport.RequestId]toolCall),
+		queuedKeys:    make(map[string]bool),
+	}
+}
+
+// SetMessageHandler intercepts subscription requests before passing messages on
+func (t *subscriptionTransport) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
+	t.Transport.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		if message.Type != transport.BaseMessageTypeJSONRPCRequestType {
+			handler(ctx, message)
+			return
+		}
+
+		request := message.JsonRpcRequest
+		switch request.Method {
+		case "initialize":
+			t.mu.Lock()
+			t.initializeIDs[request.Id] = true
+			t.mu.Unlock()
+		case "tools/call":
+			request.Params = t.defaults.apply(lsp.SessionFromContext(ctx), request.Params)
+			t.trackToolCall(request)
+			ctx = t.withProgress(withToolCall(ctx, request), request)
+		case "resources/subscribe", "resources/unsubscribe":
+			t.handleSubscription(ctx, request)
+			return
+		case "logging/setLevel":
+			t.handleSetLevel(ctx, request)
+			return
+		}
+		handler(ctx, message)
+	})
+}
+
+// SetCloseHandler chains the server's close callback after the protocol's handler
+func (t *subscriptionTransport) SetCloseHandler(handler func()) {
+	t.Transport.SetCloseHandler(func() {
+		if handler != nil {
+			handler()
+		}
+		if t.onClosed != nil {
+			t.onClosed()
+		}
+	})
+}
+
+// Send advertises resource subscriptions and logging in the initialize response,
+// and records the latency and language server errors of tool calls. Notifications
+// are queued, list_changed ones coalescing, so registering resources never waits
+// for a client.
+func (t *subscriptionTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	if message.Type == transport.BaseMessageTypeJSONRPCNotificationType {
+		var key string
+		if method := message.JsonRpcNotification.Method; strings.HasSuffix(method, "/list_changed") {
+			key = lsp.SessionFromContext(ctx) + " " + method
+		}
+		t.queueNotification(ctx, message, key)
+		return nil
+	}
+	t.finishToolCall(message)
+	if message.Type == transport.BaseMessageTypeJSONRPCResponseType {
+		t.mu.Lock()
+		isInitialize := t.initializeIDs[message.JsonRpcResponse.Id]
+		delete(t.initializeIDs, message.JsonRpcResponse.Id)
+		t.mu.Unlock()
+
+		if isInitialize {
+			if result, err := withNotificationCapabilities(message.JsonRpcResponse.Result); err == nil {
+				message.JsonRpcResponse.Result = result
+			} else {
+				log.Printf("Failed to advertise resource subscriptions: %v", err)
+			}
+		}
+	}
+	return t.Transport.Send(ctx, message)
+}
+
+// ResourceUpdated notifies the sessions subscribed to a resource that it changed
+func (t *subscriptionTransport) ResourceUpdated(ctx context.Context, uri string) {
+	t.mu.Lock()
+	sessions := make([]string, 0, len(t.subscriptions[uri]))
+	for sessionID := range t.subscriptions[uri] {
+		sessions = append(sessions, sessionID)
+	}
+	t.mu.Unlock()
+	if len(sessions) == 0 {
+		return
+	}
+
+	params, err := json.Marshal(map[string]string{"uri": uri})
+	if err != nil {
+		return
+	}
+	for _, sessionID := range sessions {
+		// An update still queued for the session already tells it to read the resource again
+		t.queueNotification(lsp.WithSession(ctx, sessionID), transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+			Jsonrpc: "2.0",
+			Method:  "notifications/resources/updated",
+			Params:  params,
+		}), sessionID+" "+uri)
+	}
+}
+
+// maxQueuedNotifications bounds the notifications waiting to be sent. Beyond it new
+// ones are dropped until the clients catch up.
+const maxQueuedNotifications = 1000
+
+// queuedNotification is a notification waiting in the outbox
+type queuedNotification struct {
+	ctx     context.Context
+	message *transport.BaseJsonRpcMessage
+	key     string
+}
+
+// queueNotification adds a notification to the outbox and returns without waiting
+// for it to be sent, callers such as the language server's notification handlers
+// must never wait for a client. A notification with a key is dropped while another
+// with the same key is still queued.
+func (t *subscriptionTransport) queueNotification(ctx context.Context, message *transport.BaseJsonRpcMessage, key string) {
+	t.outboxMu.Lock()
+	defer t.outboxMu.Unlock()
+	if key != "" && t.queuedKeys[key] {
+		return
+	}
+	if len(t.outbox) >= maxQueuedNotifications {
+		if t.droppedNotify == 0 {
+			log.Printf("Clients are not keeping up with notifications, dropping new ones")
+		}
+		t.droppedNotify++
+		return
+	}
+
+	t.outbox = append(t.outbox, queuedNotification{ctx: ctx, message: message, key: key})
+	if key != "" {
+		t.queuedKeys[key] = true
+	}
+	if !t.sending {
+		t.sending = true
+		go t.sendNotifications()
+	}
+}
+
+// sendNotifications sends the outbox in order until it is empty
+func (t *subscriptionTransport) sendNotifications() {
+	for {
+		t.outboxMu.Lock()
+		if len(t.outbox) == 0 {
+			if t.droppedNotify > 0 {
+				log.Printf("Dropped %d notifications", t.droppedNotify)
+				t.droppedNotify = 0
+			}
+			t.sending = false
+			t.outboxMu.Unlock()
+			return
+		}
+		next := t.outbox[0]
+		t.outbox[0] = queuedNotification{}
+		t.outbox = t.outbox[1:]
+		delete(t.queuedKeys, next.key)
+		t.outboxMu.Unlock()
+
+		// Best effort, e.g. a broadcast fails while no client is connected
+		if err := t.Transport.Send(next.ctx, next.message); err != nil && debug {
+			log.Printf("Failed to send %s: %v", next.message.JsonRpcNotification.Method, err)
+		}
+	}
+}
+
+// forgetSession drops the subscriptions and log level of a disconnected session
+func (t *subscriptionTransport) forgetSession(sessionID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.logLevels, sessionID)
+	for uri, sessions := range t.subscriptions {
+		delete(sessions, sessionID)
+		if len(sessions) == 0 {
+			delete(t.subscriptions, uri)
+		}
+	}
+}
+
+func (t *subscriptionTransport) handleSubscription(ctx context.Context, request *transport.BaseJSONRPCRequest) {
+	var params struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(request.Params, &params); err != nil || params.URI == "" {
+		err := t.Transport.Send(ctx, transport.NewBaseMessageError(&transport.BaseJSONRPCError{
+			Jsonrpc: "2.0",
+			Id:      request.Id,
+			Error:   transport.BaseJSONRPCErrorInner{Code: -32602, Message: "Invalid params: uri is required"},
+		}))
+		if err != nil {
+			log.Printf("Failed to send subscription error: %v", err)
+		}
+		return
+	}
+
+	sessionID := lsp.SessionFromContext(ctx)
+	t.mu.Lock()
+	if request.Method == "resources/subscribe" {
+		if t.subscriptions[params.URI] == nil {
+			t.subscriptions[params.URI] = make(map[string]bool)
+		}
+		t.subscriptions[params.URI][sessionID] = true
+	} else {
+		delete(t.subscriptions[params.URI], sessionID)
+	}
+	t.mu.Unlock()
+
+	if debug {
+		log.Printf("%s: %s", request.Method, params.URI)
+	}
+
+	err := t.Transport.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+		Jsonrpc: "2.0",
+		Id:      request.Id,
+		Result:  json.RawMessage("{}"),
+	}))
+	if err != nil {
+		log.Printf("Failed to send subscription response: %v", err)
+	}
+}
+
+// withNotificationCapabilities sets capabilities.resources.subscribe and
+// capabilities.logging in an initialize result
+func withNotificationCapabilities(result json.RawMessage) (json.RawMessage, error) {
+	var initResult map[string]json.RawMessage
+	if err := json.Unmarshal(result, &initResult); err != nil {
+		return nil, err
+	}
+
+	capabilities := make(map[string]json.RawMessage)
+	if raw, ok := initResult["capabilities"]; ok {
+		if err := json.Unmarshal(raw, &capabilities); err != nil {
+			return nil, err
+		}
+	}
+
+	resources := make(map[string]interface{})
+	if raw, ok := capabilities["resources"]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &resources); err != nil {
+			return nil, err
+		}
+	}
+	resources["subscribe"] = true
+
+	var err error
+	if capabilities["resources"], err = json.Marshal(resources); err != nil {
+		return nil, err
+	}
+	capabilities["logging"] = json.RawMessage("{}")
+	if initResult["capabilities"], err = json.Marshal(capabilities); err != nil {
+		return nil, err
+	}
+	return json.Marshal(initResult)
+}
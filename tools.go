@@ -1,20 +1,47 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"time"
 
+	"github.com/isaacphi/mcp-language-server/internal/coverage"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/tools"
 	mcp_golang "github.com/metoro-io/mcp-golang"
 )
 
 type ReadDefinitionArgs struct {
-	SymbolName      string `json:"symbolName" jsonschema:"required,description=The name of the symbol whose definition you want to find (e.g. 'mypackage.MyFunction', 'MyType.MyMethod')"`
-	ShowLineNumbers bool   `json:"showLineNumbers" jsonschema:"required,default=true,description=Include line numbers in the returned source code"`
+	SymbolName      string `json:"symbolName" jsonschema:"required,description=The name of the symbol whose definition you want to find (e.g. 'mypackage.MyFunction', 'MyType.MyMethod'). Prefix with 'regex:' to read every symbol whose name matches a regular expression (e.g. 'regex:Handle.*Request')"`
+	ShowLineNumbers bool   `json:"showLineNumbers" jsonschema:"default=true,description=Include line numbers in the returned source code"`
+	Blame           bool   `json:"blame,omitempty" jsonschema:"default=false,description=Prefix each line with the commit hash, author and date of the change that last touched it (git blame)"`
+	CoverageProfile string `json:"coverageProfile,omitempty" jsonschema:"description=Path to a Go coverprofile or lcov file. Lines are marked '+' if they ran in the tests and '!' if not"`
+	ScopePath       string `json:"scopePath,omitempty" jsonschema:"description=Only look for definitions in this directory or package (e.g. 'internal/lsp', 'com.example.app')"`
+	MatchMode       string `json:"matchMode,omitempty" jsonschema:"default=exact,description=How symbol names are compared with symbolName: 'exact'; 'prefix' or 'substring' (case-sensitive); or 'caseInsensitive' for an exact match ignoring case"`
+	FollowAliases   int    `json:"followAliases,omitempty" jsonschema:"default=0,description=When a definition is a type alias or re-export (e.g. 'type Foo = Bar' or 'export { Foo } from'), also show the definition it names, following up to this many aliases in a row (at most 5)"`
+	Highlight       string `json:"highlight,omitempty" jsonschema:"description=Mark each occurrence of the symbol in the definition: 'carets' underlines it on the following line, 'inline' wraps it in « and », 'none' only marks its lines with '>'. Unmarked by default"`
+	Verbosity       string `json:"verbosity,omitempty" jsonschema:"description=How much detail to return: 'minimal' gives only where each definition is, 'normal' its code and 'full' also the doc comments above it. Defaults to the server's preset"`
+}
+
+type GetSignatureArgs struct {
+	SymbolName string `json:"symbolName" jsonschema:"required,description=The name of the symbol whose signature you want (e.g. 'mypackage.MyFunction', 'MyType.MyMethod'). Prefix with 'regex:' to match symbol names with a regular expression"`
+	ScopePath  string `json:"scopePath,omitempty" jsonschema:"description=Only look for declarations in this directory or package (e.g. 'internal/lsp', 'com.example.app')"`
+	MatchMode  string `json:"matchMode,omitempty" jsonschema:"default=exact,description=How symbol names are compared with symbolName: 'exact'; 'prefix' or 'substring' (case-sensitive); or 'caseInsensitive' for an exact match ignoring case"`
 }
 
 type FindReferencesArgs struct {
-	SymbolName      string `json:"symbolName" jsonschema:"required,description=The name of the symbol to search for (e.g. 'mypackage.MyFunction', 'MyType')"`
-	ShowLineNumbers bool   `json:"showLineNumbers" jsonschema:"required,default=true,description=Include line numbers when showing where the symbol is used"`
+	SymbolName      string `json:"symbolName" jsonschema:"required,description=The name of the symbol to search for (e.g. 'mypackage.MyFunction', 'MyType'). Qualify fields and methods with their type (e.g. 'MyType.Name') to exclude members of other types with the same name. Prefix with 'regex:' to match symbol names with a regular expression (e.g. 'regex:Handle.*Request')"`
+	ShowLineNumbers bool   `json:"showLineNumbers" jsonschema:"default=true,description=Include line numbers when showing where the symbol is used"`
+	Blame           bool   `json:"blame,omitempty" jsonschema:"default=false,description=Prefix each code line with the commit hash, author and date of the change that last touched it (git blame)"`
+	SingleBlock     bool   `json:"singleBlock,omitempty" jsonschema:"default=false,description=Return all files in one text block instead of one content part per file"`
+	Stream          bool   `json:"stream,omitempty" jsonschema:"default=false,description=Send each file as a progress notification message as soon as it is ready and return only the summary. Needs a progressToken in the request and groupBy file. Use it for symbols with thousands of references."`
+	SortBy          string `json:"sortBy,omitempty" jsonschema:"default=path,description=Order files and groups by 'path', by reference 'count' (most first) or by 'proximity' to the definition (its file, then its directory, then nearby directories)"`
+	GroupBy         string `json:"groupBy,omitempty" jsonschema:"default=file,description=Return one part per 'file', per 'package' (directory) or per symbol 'kind' of the scopes containing the references (e.g. Function, Method)"`
+	ScopePath       string `json:"scopePath,omitempty" jsonschema:"description=Only include definitions and references in this directory or package (e.g. 'internal/lsp', 'com.example.app')"`
+	MatchMode       string `json:"matchMode,omitempty" jsonschema:"default=exact,description=How symbol names are compared with symbolName. References of all matching symbols are listed together: 'exact'; 'prefix' or 'substring' (case-sensitive); or 'caseInsensitive' for an exact match ignoring case"`
+	Highlight       string `json:"highlight,omitempty" jsonschema:"default=carets,description=How references are marked in the code besides the '>' on their lines: 'carets' underlines them on the following line, 'inline' wraps them in « and », 'none' leaves the code as is"`
+	Verbosity       string `json:"verbosity,omitempty" jsonschema:"description=How much detail to return: 'minimal' lists the scopes and positions of references without code, 'normal' shows the code with long scopes shortened and 'full' shows scopes whole. Defaults to the server's preset"`
 }
 
 type ApplyTextEditArgs struct {
@@ -23,9 +50,15 @@ type ApplyTextEditArgs struct {
 }
 
 type GetDiagnosticsArgs struct {
-	FilePath        string `json:"filePath" jsonschema:"required,description=The path to the file to get diagnostics for"`
-	IncludeContext  bool   `json:"includeContext" jsonschema:"default=false,description=Include additional context for each diagnostic. Prefer false."`
-	ShowLineNumbers bool   `json:"showLineNumbers" jsonschema:"required,default=true,description=If true, adds line numbers to the output"`
+	FilePath        string   `json:"filePath,omitempty" jsonschema:"description=The path to the file to get diagnostics for"`
+	FilePaths       []string `json:"filePaths,omitempty" jsonschema:"description=Several files to get diagnostics for in one grouped report"`
+	Directory       string   `json:"directory,omitempty" jsonschema:"description=Get diagnostics for the source files under this workspace directory"`
+	Glob            string   `json:"glob,omitempty" jsonschema:"description=Only files whose workspace-relative path or name matches this glob, e.g. internal/**/*.go. Without directory the whole workspace is searched."`
+	NotebookPath    string   `json:"notebookPath,omitempty" jsonschema:"description=Get diagnostics for the code cells of this Jupyter notebook"`
+	Cell            int      `json:"cell,omitempty" jsonschema:"description=Only the cell (1-indexed, counting markdown cells) of notebookPath"`
+	IncludeContext  bool     `json:"includeContext" jsonschema:"default=false,description=Include additional context for each diagnostic. Prefer false."`
+	ShowLineNumbers bool     `json:"showLineNumbers" jsonschema:"default=true,description=If true, adds line numbers to the output"`
+	Verbosity       string   `json:"verbosity,omitempty" jsonschema:"description=How much detail to return: 'minimal' gives only the messages, 'normal' also the line of each and 'full' the enclosing definition as with includeContext. Defaults to the server's preset"`
 }
 
 type GetCodeLensArgs struct {
@@ -37,30 +70,296 @@ type ExecuteCodeLensArgs struct {
 	Index    int    `json:"index" jsonschema:"required,description=The index of the code lens to execute (from get_codelens output), 1 indexed"`
 }
 
+type GetCodeActionsArgs struct {
+	FilePath        string   `json:"filePath" jsonschema:"required,description=The path to the file to get code actions for"`
+	StartLine       int      `json:"startLine" jsonschema:"required,description=The first line of the range (1-indexed)"`
+	StartColumn     int      `json:"startColumn,omitempty" jsonschema:"description=The column the range starts at (1-indexed). Defaults to the start of the line"`
+	EndLine         int      `json:"endLine,omitempty" jsonschema:"description=The last line of the range (1-indexed). Defaults to startLine"`
+	EndColumn       int      `json:"endColumn,omitempty" jsonschema:"description=The column the range ends at (1-indexed). Defaults to the end of the line"`
+	Kinds           []string `json:"kinds,omitempty" jsonschema:"description=Only actions of these kinds or their sub-kinds, e.g. quickfix, refactor, refactor.extract, source.organizeImports"`
+	DiagnosticsOnly bool     `json:"diagnosticsOnly,omitempty" jsonschema:"default=false,description=Only quick fixes for the diagnostics in the range"`
+}
+
+type FixAllArgs struct {
+	FilePath string `json:"filePath" jsonschema:"required,description=The path to the file to fix"`
+}
+
+type ExtractArgs struct {
+	FilePath    string `json:"filePath" jsonschema:"required,description=The path to the file containing the code to extract"`
+	StartLine   int    `json:"startLine" jsonschema:"required,description=The first line of the code to extract (1-indexed)"`
+	StartColumn int    `json:"startColumn,omitempty" jsonschema:"description=The column the code starts at (1-indexed). Defaults to the start of the line"`
+	EndLine     int    `json:"endLine,omitempty" jsonschema:"description=The last line of the code to extract (1-indexed). Defaults to startLine"`
+	EndColumn   int    `json:"endColumn,omitempty" jsonschema:"description=The column the code ends at (1-indexed), exclusive. Defaults to the end of the line"`
+	NewName     string `json:"newName,omitempty" jsonschema:"description=Name for the new function or variable. Without it the language server's generated name is kept"`
+}
+
+type MoveSymbolArgs struct {
+	FilePath        string `json:"filePath" jsonschema:"required,description=The path to the file declaring the symbol"`
+	SymbolName      string `json:"symbolName" jsonschema:"required,description=The name of the top-level symbol to move"`
+	DestinationFile string `json:"destinationFile,omitempty" jsonschema:"description=A new file in the same directory to move the symbol to. Defaults to the file the language server chooses"`
+	Preview         bool   `json:"preview,omitempty" jsonschema:"default=false,description=Only show the diff of the move without writing any file"`
+}
+
 type RenameSymbolArgs struct {
-	FilePath string `json:"filePath" jsonschema:"required,description=The path to the file containing the symbol to rename"`
-	Line     int    `json:"line" jsonschema:"required,description=The line number (1-indexed) where the symbol appears"`
-	Column   int    `json:"column" jsonschema:"required,description=The column number (1-indexed) where the symbol appears"`
-	NewName  string `json:"newName" jsonschema:"required,description=The new name for the symbol"`
+	FilePath        string `json:"filePath" jsonschema:"required,description=The path to the file containing the symbol to rename"`
+	Line            int    `json:"line" jsonschema:"required,description=The line number (1-indexed) where the symbol appears"`
+	Column          int    `json:"column" jsonschema:"required,description=The column number (1-indexed) where the symbol appears"`
+	NewName         string `json:"newName" jsonschema:"required,description=The new name for the symbol"`
+	IgnoreConflicts bool   `json:"ignoreConflicts,omitempty" jsonschema:"default=false,description=Apply the rename even if identifiers named newName already exist in the scopes it touches"`
 }
 
 type HoverArgs struct {
-	FilePath string `json:"filePath" jsonschema:"required,description=The path to the file containing the symbol to get hover information for"`
-	Line     int    `json:"line" jsonschema:"required,description=The line number (1-indexed) where the symbol appears"`
-	Column   int    `json:"column" jsonschema:"required,description=The column number (1-indexed) where the symbol appears"`
+	FilePath      string `json:"filePath,omitempty" jsonschema:"description=The path to the file containing the symbol to get hover information for"`
+	NotebookPath  string `json:"notebookPath,omitempty" jsonschema:"description=The path to a Jupyter notebook containing the symbol, instead of filePath. line and column are then within the cell"`
+	Cell          int    `json:"cell,omitempty" jsonschema:"description=The cell (1-indexed, counting markdown cells) of notebookPath containing the symbol"`
+	Line          int    `json:"line" jsonschema:"required,description=The line number (1-indexed) where the symbol appears"`
+	Column        int    `json:"column" jsonschema:"required,description=The column number (1-indexed) where the symbol appears"`
+	PlainText     bool   `json:"plainText,omitempty" jsonschema:"default=false,description=Render markdown hover contents as plain text"`
+	StripLinks    bool   `json:"stripLinks,omitempty" jsonschema:"default=false,description=Replace markdown links with their text"`
+	SignatureOnly bool   `json:"signatureOnly,omitempty" jsonschema:"default=false,description=Return only the signature (the first code block) without documentation"`
+	Verbosity     string `json:"verbosity,omitempty" jsonschema:"description=How much detail to return: 'minimal' returns only the signature as with signatureOnly, 'normal' and 'full' include the documentation. Defaults to the server's preset"`
+}
+
+type GetTypeAtRangeArgs struct {
+	FilePath    string `json:"filePath" jsonschema:"required,description=The path to the file containing the expression"`
+	StartLine   int    `json:"startLine" jsonschema:"required,description=The line (1-indexed) where the expression starts"`
+	StartColumn int    `json:"startColumn" jsonschema:"required,description=The column (1-indexed) of the expression's first character"`
+	EndLine     int    `json:"endLine" jsonschema:"required,description=The line (1-indexed) where the expression ends"`
+	EndColumn   int    `json:"endColumn" jsonschema:"required,description=The column (1-indexed) of the expression's last character"`
+	PlainText   bool   `json:"plainText,omitempty" jsonschema:"default=false,description=Render markdown hover contents as plain text"`
+	StripLinks  bool   `json:"stripLinks,omitempty" jsonschema:"default=false,description=Replace markdown links with their text"`
+	Verbosity   string `json:"verbosity,omitempty" jsonschema:"description=How much detail to return: 'minimal' returns only the type's signature, 'normal' and 'full' include its documentation. Defaults to the server's preset"`
 }
 
 type DocumentSymbolsArgs struct {
-	FilePath        string `json:"filePath" jsonschema:"required,description=The path to the file to list symbols for"`
-	ShowLineNumbers bool   `json:"showLineNumbers" jsonschema:"required,default=true,description=Include line numbers in the output"`
+	FilePath        string   `json:"filePath" jsonschema:"required,description=The path to the file to list symbols for"`
+	ShowLineNumbers bool     `json:"showLineNumbers" jsonschema:"default=true,description=Include line numbers in the output"`
+	Kinds           []string `json:"kinds,omitempty" jsonschema:"description=Only list symbols of these kinds (e.g. 'Function', 'Method'). Their parents are kept for context"`
+	MaxDepth        int      `json:"maxDepth,omitempty" jsonschema:"description=Maximum nesting depth to list, 1 lists only top-level symbols. 0 means unlimited"`
+}
+
+type FileOutlineArgs struct {
+	FilePath        string `json:"filePath" jsonschema:"required,description=The path to the file to outline"`
+	ShowLineNumbers bool   `json:"showLineNumbers" jsonschema:"default=true,description=Include line numbers in the output"`
+}
+
+type ReadSourceArgs struct {
+	FilePath        string `json:"filePath" jsonschema:"required,description=The path to the file to read"`
+	StartLine       int    `json:"startLine,omitempty" jsonschema:"description=First line to return (1-indexed). Defaults to the start of the file"`
+	EndLine         int    `json:"endLine,omitempty" jsonschema:"description=Last line to return (1-indexed, inclusive). Defaults to the end of the file"`
+	SymbolName      string `json:"symbolName,omitempty" jsonschema:"description=Optional identifier whose occurrences should be marked in the output"`
+	ShowLineNumbers bool   `json:"showLineNumbers" jsonschema:"default=true,description=Include line numbers in the output"`
+}
+
+type BreadcrumbsArgs struct {
+	FilePath string `json:"filePath" jsonschema:"required,description=The path to the file containing the position"`
+	Line     int    `json:"line" jsonschema:"required,description=The line number (1-indexed) of the position"`
+	Column   int    `json:"column" jsonschema:"required,description=The column number (1-indexed) of the position"`
+}
+
+type SearchTextArgs struct {
+	Query         string   `json:"query" jsonschema:"required,description=The text or regular expression to search for"`
+	IsRegex       bool     `json:"isRegex,omitempty" jsonschema:"default=false,description=Treat the query as a regular expression (RE2 syntax) instead of literal text"`
+	CaseSensitive bool     `json:"caseSensitive,omitempty" jsonschema:"default=false,description=Match case exactly"`
+	IncludeGlobs  []string `json:"includeGlobs,omitempty" jsonschema:"description=Only search files matching one of these glob patterns (e.g. '**/*.go' or 'internal/**')"`
+	ContextLines  int      `json:"contextLines,omitempty" jsonschema:"default=2,description=Number of lines of context to show around each match"`
+	MaxResults    int      `json:"maxResults,omitempty" jsonschema:"default=100,description=Maximum number of matching lines to return"`
+}
+
+type UnusedSymbolsArgs struct {
+	Query        string   `json:"query,omitempty" jsonschema:"description=Workspace symbol query used to select candidates (e.g. a package or name prefix). Empty checks all symbols the server returns"`
+	Kinds        []string `json:"kinds,omitempty" jsonschema:"description=Only check symbols of these kinds (e.g. Function, Method, Struct)"`
+	Directory    string   `json:"directory,omitempty" jsonschema:"description=Only check symbols declared in files below this directory"`
+	ExportedOnly *bool    `json:"exportedOnly,omitempty" jsonschema:"default=true,description=Only check exported (capitalized) symbols"`
+}
+
+type ImplementationsArgs struct {
+	InterfaceName   string `json:"interfaceName" jsonschema:"required,description=The name of the interface to list implementations of (e.g. 'Reader', 'WorkspaceSymbolResult')"`
+	ShowLineNumbers bool   `json:"showLineNumbers" jsonschema:"default=true,description=Include line numbers in the output"`
+	MatchMode       string `json:"matchMode,omitempty" jsonschema:"default=exact,description=How symbol names are compared with interfaceName: 'exact'; 'prefix' or 'substring' (case-sensitive); or 'caseInsensitive' for an exact match ignoring case"`
+}
+
+type CallGraphArgs struct {
+	SymbolName string `json:"symbolName" jsonschema:"required,description=The function or method the graph starts from (e.g. 'main', 'Server.Handle')"`
+	Direction  string `json:"direction,omitempty" jsonschema:"default=outgoing,description=Follow the calls the symbol makes ('outgoing'), its callers ('incoming') or 'both'"`
+	Depth      int    `json:"depth,omitempty" jsonschema:"default=2,description=How many calls away from the symbol to expand (at most 6)"`
+	MaxNodes   int    `json:"maxNodes,omitempty" jsonschema:"default=200,description=Maximum number of symbols in the graph, the ones closest to the root are kept"`
+	Format     string `json:"format,omitempty" jsonschema:"default=dot,description=Output format: 'dot' for Graphviz or 'json' with nodes (symbols and their locations) and edges (call sites)"`
+	ScopePath  string `json:"scopePath,omitempty" jsonschema:"description=Only start from definitions in this directory or package (e.g. 'internal/lsp')"`
+	MatchMode  string `json:"matchMode,omitempty" jsonschema:"default=exact,description=How symbol names are compared with symbolName: 'exact'; 'prefix' or 'substring' (case-sensitive); or 'caseInsensitive' for an exact match ignoring case"`
+}
+
+type WhoImportsArgs struct {
+	ImportPath         string `json:"importPath" jsonschema:"required,description=The package or module path to look for (e.g. 'github.com/user/repo/internal/lsp', 'lodash', 'mypkg.utils')"`
+	IncludeSubpackages bool   `json:"includeSubpackages,omitempty" jsonschema:"default=false,description=Also report imports of packages below the path"`
+}
+
+type DependencyGraphArgs struct {
+	Directory       string `json:"directory,omitempty" jsonschema:"description=Only graph the packages below this directory (default: the whole workspace)"`
+	Format          string `json:"format,omitempty" jsonschema:"default=tree,description=Output format: 'tree' lists each package's imports indented below it, 'dot' for Graphviz"`
+	IncludeExternal bool   `json:"includeExternal,omitempty" jsonschema:"default=false,description=Also show the packages imported from outside the workspace"`
+	IncludeTests    bool   `json:"includeTests,omitempty" jsonschema:"default=false,description=Also follow the imports of test files"`
+}
+
+type PublicAPIArgs struct {
+	Directory    string `json:"directory" jsonschema:"required,description=The package directory whose files are summarized (subdirectories are not included)"`
+	TopLevelOnly bool   `json:"topLevelOnly,omitempty" jsonschema:"default=false,description=Leave out the exported members of types, such as fields and methods"`
+}
+
+type HoverBatchArgs struct {
+	FilePath      string                `json:"filePath,omitempty" jsonschema:"description=The file containing the positions, used for positions without their own filePath"`
+	Positions     []tools.HoverPosition `json:"positions" jsonschema:"required,description=The positions to get hover information for"`
+	PlainText     bool                  `json:"plainText,omitempty" jsonschema:"default=false,description=Render markdown hover contents as plain text"`
+	StripLinks    bool                  `json:"stripLinks,omitempty" jsonschema:"default=false,description=Replace markdown links with their text"`
+	SignatureOnly bool                  `json:"signatureOnly,omitempty" jsonschema:"default=false,description=Return only the signature (the first code block) without documentation"`
+	Verbosity     string                `json:"verbosity,omitempty" jsonschema:"description=How much detail to return: 'minimal' returns only signatures as with signatureOnly, 'normal' and 'full' include documentation. Defaults to the server's preset"`
+}
+
+// sessionContext attributes a tool call to the MCP session that made it, and
+// keeps its verbosity and document hold, while keeping the server's lifetime for
+// cancellation
+func (s *server) sessionContext(ctx context.Context) context.Context {
+	callCtx := lsp.WithDocumentHold(lsp.WithSession(s.ctx, lsp.SessionFromContext(ctx)), ctx)
+	return tools.WithVerbosity(callCtx, tools.VerbosityFromContext(ctx))
+}
+
+// changesCursor returns the journal position and start time for recent_changes.
+// Without a duration, a session sees the changes since its previous call.
+func (s *server) changesCursor(ctx context.Context, within string) (uint64, time.Time, error) {
+	if within != "" {
+		d, err := time.ParseDuration(within)
+		if err != nil {
+			return 0, time.Time{}, fmt.Errorf("invalid duration %q: %v", within, err)
+		}
+		return 0, time.Now().Add(-d), nil
+	}
+
+	sessionID := lsp.SessionFromContext(ctx)
+	lastSeq := s.workspaceWatcher.Journal().LastSeq()
+
+	s.changesCursorsMu.Lock()
+	defer s.changesCursorsMu.Unlock()
+	afterSeq := s.changesCursors[sessionID]
+	s.changesCursors[sessionID] = lastSeq
+	return afterSeq, time.Time{}, nil
+}
+
+type RecentChangesArgs struct {
+	Since string `json:"since,omitempty" jsonschema:"description=Only list changes within this duration before now (e.g. '10m' or '1h'). Defaults to changes since this session last called recent_changes"`
+	Limit int    `json:"limit,omitempty" jsonschema:"default=100,description=Maximum number of files to list"`
+}
+
+// HealthArgs is empty, the health tool takes no arguments
+type HealthArgs struct{}
+
+// GetMetricsArgs is empty, the get_metrics tool takes no arguments
+type GetMetricsArgs struct{}
+
+// ClearCachesArgs names the caches to clear, all of them when empty
+type ClearCachesArgs struct {
+	Caches []string `json:"caches,omitempty" jsonschema:"description=Caches to clear: documents, diagnostics or workspace_symbols. All caches are cleared when empty."`
+}
+
+// AboutArgs is empty, the about tool takes no arguments
+type AboutArgs struct{}
+
+type CheckEditArgs struct {
+	FilePath string           `json:"filePath" jsonschema:"required,description=The path to the file the edits would change"`
+	Edits    []tools.TextEdit `json:"edits" jsonschema:"required,description=The proposed edits, in the same form as apply_text_edit"`
+}
+
+type OverlayArgs struct {
+	FilePath string `json:"filePath" jsonschema:"required,description=The path of the document, which does not need to exist on disk"`
+	Content  string `json:"content" jsonschema:"required,description=The full text of the document"`
+}
+
+type DiscardOverlayArgs struct {
+	FilePath string `json:"filePath" jsonschema:"required,description=The path of the overlay to discard"`
+}
+
+type DiffDefinitionsArgs struct {
+	SymbolName      string `json:"symbolName" jsonschema:"required,description=The symbol whose definition is the base of the diff (e.g. 'MyType.MyMethod')"`
+	OtherSymbolName string `json:"otherSymbolName,omitempty" jsonschema:"description=The symbol to compare with. Defaults to symbolName, to compare one symbol at two revisions"`
+	BaseRef         string `json:"baseRef,omitempty" jsonschema:"description=Git revision of the base definition (e.g. 'HEAD~1' or 'main'). Defaults to the working tree"`
+	OtherRef        string `json:"otherRef,omitempty" jsonschema:"description=Git revision of the other definition. Defaults to the working tree"`
+}
+
+type SymbolHistoryArgs struct {
+	SymbolName string `json:"symbolName" jsonschema:"required,description=The symbol whose history you want (e.g. 'MyType.MyMethod')"`
+	MaxCommits int    `json:"maxCommits,omitempty" jsonschema:"default=10,description=How many of the newest commits that changed the definition to list"`
+	ScopePath  string `json:"scopePath,omitempty" jsonschema:"description=Only look for definitions in this directory or package (e.g. 'internal/lsp', 'com.example.app')"`
+	MatchMode  string `json:"matchMode,omitempty" jsonschema:"default=exact,description=How symbol names are compared with symbolName: 'exact'; 'prefix' or 'substring' (case-sensitive); or 'caseInsensitive' for an exact match ignoring case"`
+	Verbosity  string `json:"verbosity,omitempty" jsonschema:"description=How much detail to return: 'minimal' leaves out the definition's code, 'normal' shows it and 'full' also lists every commit when maxCommits is not given. Defaults to the server's preset"`
+}
+
+type GetDiagnosticsForChangesArgs struct {
+	BaseRef string `json:"baseRef,omitempty" jsonschema:"default=HEAD,description=Git revision to diff the working tree against (e.g. 'main' or 'HEAD~3'). Defaults to HEAD"`
+}
+
+type GetDiagnosticsSummaryArgs struct {
+	ErrorsOnly bool `json:"errorsOnly,omitempty" jsonschema:"default=false,description=List only files with errors"`
+	MaxFiles   int  `json:"maxFiles,omitempty" jsonschema:"default=50,description=Maximum number of files to list, most errors first"`
+}
+
+type GetCoverageArgs struct {
+	ProfilePath string `json:"profilePath" jsonschema:"required,description=Path to a Go coverprofile (go test -coverprofile) or lcov tracefile"`
+	FilePath    string `json:"filePath,omitempty" jsonschema:"description=Only report this source file"`
+	MaxFiles    int    `json:"maxFiles,omitempty" jsonschema:"default=20,description=Maximum number of files to report, least covered first"`
+}
+
+type WarmupArgs struct {
+	MaxFiles       int `json:"maxFiles,omitempty" jsonschema:"default=5,description=Number of representative files to open"`
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty" jsonschema:"default=120,description=How long to wait for the server to become ready"`
+}
+
+type SetDefaultsArgs struct {
+	ShowLineNumbers *bool  `json:"showLineNumbers,omitempty" jsonschema:"description=Default for showLineNumbers"`
+	Verbosity       string `json:"verbosity,omitempty" jsonschema:"description=Default verbosity preset: 'minimal', 'normal' or 'full'"`
+	ScopePath       string `json:"scopePath,omitempty" jsonschema:"description=Default scopePath, a directory or package the symbol tools are limited to (e.g. 'internal/lsp')"`
+	Format          string `json:"format,omitempty" jsonschema:"description=Default format of the graph tools, e.g. 'dot'. Only the tools that accept the format get it: call_graph takes 'dot' or 'json', dependency_graph 'tree' or 'dot'"`
+	MaxTokens       int    `json:"maxTokens,omitempty" jsonschema:"description=Cut every response to about this many tokens, with a footer counting the lines left out"`
+	Clear           bool   `json:"clear,omitempty" jsonschema:"default=false,description=Drop the defaults set before applying the ones given"`
+}
+
+type SetWorkspaceArgs struct {
+	WorkspaceDir string `json:"workspaceDir" jsonschema:"required,description=Path of the new workspace root. It must be inside the workspace the server was started with or a directory allowed by --allow-path"`
+}
+
+type OpenFileArgs struct {
+	FilePath string `json:"filePath" jsonschema:"required,description=The path of the file to open"`
+}
+
+type CloseFileArgs struct {
+	FilePath string `json:"filePath" jsonschema:"required,description=The path of the file to close"`
+}
+
+type ExpandMacroArgs struct {
+	FilePath string `json:"filePath" jsonschema:"required,description=The path to the Rust file containing the macro call"`
+	Line     int    `json:"line" jsonschema:"required,description=The line number (1-indexed) of the macro call"`
+	Column   int    `json:"column" jsonschema:"required,description=The column number (1-indexed) of the macro's name"`
+}
+
+type RunnablesArgs struct {
+	FilePath string `json:"filePath" jsonschema:"required,description=The path to the Rust file"`
+	Line     int    `json:"line,omitempty" jsonschema:"description=Only list the runnables at this line (1-indexed), such as the test function on it. All runnables of the file by default"`
+}
+
+type RunRunnableArgs struct {
+	FilePath       string `json:"filePath" jsonschema:"required,description=The path to the Rust file the runnable is in"`
+	Label          string `json:"label" jsonschema:"required,description=The label of the runnable as listed by runnables (e.g. 'test tests::it_works')"`
+	TimeoutSeconds int    `json:"timeoutSeconds,omitempty" jsonschema:"default=300,description=How long to let it run"`
+}
+
+type SwitchSourceHeaderArgs struct {
+	FilePath string `json:"filePath" jsonschema:"required,description=The path of a C or C++ source file or header"`
 }
 
 func (s *server) registerTools() error {
-	err := s.mcpServer.RegisterTool(
+	err := s.registerTool(
 		"apply_text_edit",
 		"Apply multiple text edits to a file.",
-		func(args ApplyTextEditArgs) (*mcp_golang.ToolResponse, error) {
-			response, err := tools.ApplyTextEdits(s.ctx, s.lspClient, args.FilePath, args.Edits)
+		func(ctx context.Context, args ApplyTextEditArgs) (*mcp_golang.ToolResponse, error) {
+			response, err := tools.ApplyTextEdits(s.sessionContext(ctx), s.lspClient, args.FilePath, args.Edits)
 			if err != nil {
 				return nil, fmt.Errorf("Failed to apply edits: %v", err)
 			}
@@ -70,11 +369,24 @@ func (s *server) registerTools() error {
 		return fmt.Errorf("failed to register tool: %v", err)
 	}
 
-	err = s.mcpServer.RegisterTool(
+	err = s.registerTool(
 		"read_definition",
 		"Read the source code definition of a symbol (function, type, constant, etc.) from the codebase. Returns the complete implementation code where the symbol is defined.",
-		func(args ReadDefinitionArgs) (*mcp_golang.ToolResponse, error) {
-			text, err := tools.ReadDefinition(s.ctx, s.lspClient, args.SymbolName, args.ShowLineNumbers)
+		func(ctx context.Context, args ReadDefinitionArgs) (*mcp_golang.ToolResponse, error) {
+			opts := tools.DefinitionOptions{
+				ScopePath:     args.ScopePath,
+				MatchMode:     args.MatchMode,
+				FollowAliases: args.FollowAliases,
+				Highlight:     args.Highlight,
+			}
+			if args.CoverageProfile != "" {
+				profile, err := coverage.Load(args.CoverageProfile, s.config.workspaceDir)
+				if err != nil {
+					return nil, fmt.Errorf("Failed to load coverage: %v", err)
+				}
+				opts.Profile = profile
+			}
+			text, err := tools.ReadDefinition(s.sessionContext(ctx), s.lspClient, args.SymbolName, args.ShowLineNumbers, args.Blame, opts)
 			if err != nil {
 				return nil, fmt.Errorf("Failed to get definition: %v", err)
 			}
@@ -84,25 +396,78 @@ func (s *server) registerTools() error {
 		return fmt.Errorf("failed to register tool: %v", err)
 	}
 
-	err = s.mcpServer.RegisterTool(
+	err = s.registerTool(
+		"get_signature",
+		"Get only the declaration of a symbol, without its body, and the type the language server reports for it. Cheaper than read_definition when you only need a function's parameters and results or a type's declaration.",
+		func(ctx context.Context, args GetSignatureArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.GetSignature(s.sessionContext(ctx), s.lspClient, args.SymbolName, args.ScopePath, args.MatchMode)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to get signature: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		})
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
 		"find_references",
 		"Find all usages and references of a symbol throughout the codebase. Returns a list of all files and locations where the symbol appears.",
-		func(args FindReferencesArgs) (*mcp_golang.ToolResponse, error) {
-			text, err := tools.FindReferences(s.ctx, s.lspClient, args.SymbolName, args.ShowLineNumbers)
+		func(ctx context.Context, args FindReferencesArgs) (*mcp_golang.ToolResponse, error) {
+			opts := tools.ReferenceOptions{
+				SortBy:    args.SortBy,
+				GroupBy:   args.GroupBy,
+				ScopePath: args.ScopePath,
+				MatchMode: args.MatchMode,
+				Highlight: args.Highlight,
+			}
+			progress := progressFromContext(ctx)
+			if args.Stream && progress != nil {
+				opts.Emit = func(part string) {
+					if err := progress.Report(part); err != nil {
+						log.Printf("Failed to stream references: %v", err)
+					}
+				}
+			}
+			parts, err := tools.FindReferenceParts(s.sessionContext(ctx), s.lspClient, args.SymbolName, args.ShowLineNumbers, args.Blame, opts)
 			if err != nil {
 				return nil, fmt.Errorf("Failed to find references: %v", err)
 			}
-			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+			if opts.Emit != nil && progress.Sent() > 0 {
+				parts[0] += fmt.Sprintf("\nStreamed: %s sent as progress notifications", pluralize(progress.Sent(), "file"))
+			}
+			if args.SingleBlock {
+				return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(tools.JoinReferenceParts(parts))), nil
+			}
+			content := make([]*mcp_golang.Content, len(parts))
+			for i, part := range parts {
+				content[i] = mcp_golang.NewTextContent(part)
+			}
+			return mcp_golang.NewToolResponse(content...), nil
 		})
 	if err != nil {
 		return fmt.Errorf("failed to register tool: %v", err)
 	}
 
-	err = s.mcpServer.RegisterTool(
+	err = s.registerTool(
 		"get_diagnostics",
-		"Get diagnostic information for a specific file from the language server.",
-		func(args GetDiagnosticsArgs) (*mcp_golang.ToolResponse, error) {
-			text, err := tools.GetDiagnosticsForFile(s.ctx, s.lspClient, args.FilePath, args.IncludeContext, args.ShowLineNumbers)
+		"Get diagnostic information from the language server for a file, or for several files, a directory or a glob at once in a report grouped by file.",
+		func(ctx context.Context, args GetDiagnosticsArgs) (*mcp_golang.ToolResponse, error) {
+			var text string
+			var err error
+			if args.NotebookPath != "" {
+				text, err = tools.GetNotebookDiagnostics(s.sessionContext(ctx), s.lspClient, args.NotebookPath, args.Cell, args.IncludeContext, args.ShowLineNumbers)
+			} else if len(args.FilePaths) > 0 || args.Directory != "" || args.Glob != "" {
+				filePaths := args.FilePaths
+				if args.FilePath != "" {
+					filePaths = append([]string{args.FilePath}, filePaths...)
+				}
+				text, err = tools.GetDiagnosticsForFiles(s.sessionContext(ctx), s.lspClient, s.workspaceWatcher, filePaths, args.Directory, args.Glob, args.IncludeContext, args.ShowLineNumbers)
+			} else if args.FilePath != "" {
+				text, err = tools.GetDiagnosticsForFile(s.sessionContext(ctx), s.lspClient, args.FilePath, args.IncludeContext, args.ShowLineNumbers)
+			} else {
+				return nil, fmt.Errorf("Failed to get diagnostics: one of filePath, filePaths, directory, glob or notebookPath is required")
+			}
 			if err != nil {
 				return nil, fmt.Errorf("Failed to get diagnostics: %v", err)
 			}
@@ -113,11 +478,11 @@ func (s *server) registerTools() error {
 		return fmt.Errorf("failed to register tool: %v", err)
 	}
 
-	err = s.mcpServer.RegisterTool(
+	err = s.registerTool(
 		"get_codelens",
 		"Get code lens hints for a given file from the language server.",
-		func(args GetCodeLensArgs) (*mcp_golang.ToolResponse, error) {
-			text, err := tools.GetCodeLens(s.ctx, s.lspClient, args.FilePath)
+		func(ctx context.Context, args GetCodeLensArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.GetCodeLens(s.sessionContext(ctx), s.lspClient, args.FilePath)
 			if err != nil {
 				return nil, fmt.Errorf("Failed to get code lens: %v", err)
 			}
@@ -128,11 +493,11 @@ func (s *server) registerTools() error {
 		return fmt.Errorf("failed to register tool: %v", err)
 	}
 
-	err = s.mcpServer.RegisterTool(
+	err = s.registerTool(
 		"execute_codelens",
-		"Execute a code lens command for a given file and lens index.",
-		func(args ExecuteCodeLensArgs) (*mcp_golang.ToolResponse, error) {
-			text, err := tools.ExecuteCodeLens(s.ctx, s.lspClient, args.FilePath, args.Index)
+		"Execute a code lens command for a given file and lens index, as listed by get_codelens. Fails if the file changed since the lenses were listed.",
+		func(ctx context.Context, args ExecuteCodeLensArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.ExecuteCodeLens(s.sessionContext(ctx), s.lspClient, args.FilePath, args.Index)
 			if err != nil {
 				return nil, fmt.Errorf("Failed to execute code lens: %v", err)
 			}
@@ -143,11 +508,86 @@ func (s *server) registerTools() error {
 		return fmt.Errorf("failed to register tool: %v", err)
 	}
 
-	err = s.mcpServer.RegisterTool(
+	err = s.registerTool(
+		"get_code_actions",
+		"List the code actions the language server offers for a range of a file: quick fixes for diagnostics, refactorings and source actions such as organizing imports. Filter with kinds, or set diagnosticsOnly for just the fixes of current errors.",
+		func(ctx context.Context, args GetCodeActionsArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.GetCodeActions(s.sessionContext(ctx), s.lspClient, args.FilePath, args.StartLine, args.StartColumn, args.EndLine, args.EndColumn, args.Kinds, args.DiagnosticsOnly)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to get code actions: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"fix_all",
+		"Apply all safe automatic fixes to a file: the language server's source.fixAll action, or else the preferred quick fixes of its diagnostics. Reports the diff and the diagnostics that remain.",
+		func(ctx context.Context, args FixAllArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.FixAll(s.sessionContext(ctx), s.lspClient, args.FilePath)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to fix file: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"extract_function",
+		"Move the statements in a range of a file into a new function using the language server's extract refactoring, replacing them with a call. Optionally names the new function. Returns the diff.",
+		func(ctx context.Context, args ExtractArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.ExtractFunction(s.sessionContext(ctx), s.lspClient, args.FilePath, args.StartLine, args.StartColumn, args.EndLine, args.EndColumn, args.NewName)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to extract function: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"extract_variable",
+		"Replace the expression in a range of a file with a new variable using the language server's extract refactoring. Give exact columns for the expression. Optionally names the new variable. Returns the diff.",
+		func(ctx context.Context, args ExtractArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.ExtractVariable(s.sessionContext(ctx), s.lspClient, args.FilePath, args.StartLine, args.StartColumn, args.EndLine, args.EndColumn, args.NewName)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to extract variable: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"move_symbol",
+		"Move a top-level symbol to a new file using the language server's move refactoring (e.g. gopls \"Move to a new file\", rust-analyzer \"Move item to file\"), which also updates imports. Set preview to see the diff first.",
+		func(ctx context.Context, args MoveSymbolArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.MoveSymbol(s.sessionContext(ctx), s.lspClient, args.FilePath, args.SymbolName, args.DestinationFile, args.Preview)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to move symbol: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
 		"rename_symbol",
 		"Rename a symbol (variable, function, class, etc.) and all its references across files.",
-		func(args RenameSymbolArgs) (*mcp_golang.ToolResponse, error) {
-			text, err := tools.RenameSymbol(s.ctx, s.lspClient, args.FilePath, args.Line, args.Column, args.NewName)
+		func(ctx context.Context, args RenameSymbolArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.RenameSymbol(s.sessionContext(ctx), s.lspClient, args.FilePath, args.Line, args.Column, args.NewName, args.IgnoreConflicts)
 			if err != nil {
 				return nil, fmt.Errorf("Failed to rename symbol: %v", err)
 			}
@@ -158,11 +598,24 @@ func (s *server) registerTools() error {
 		return fmt.Errorf("failed to register tool: %v", err)
 	}
 
-	err = s.mcpServer.RegisterTool(
+	err = s.registerTool(
 		"hover",
 		"Get hover information (type, documentation) for a symbol at the specified position.",
-		func(args HoverArgs) (*mcp_golang.ToolResponse, error) {
-			text, err := tools.GetHoverInfo(s.ctx, s.lspClient, args.FilePath, args.Line, args.Column)
+		func(ctx context.Context, args HoverArgs) (*mcp_golang.ToolResponse, error) {
+			opts := tools.HoverOptions{
+				PlainText:     args.PlainText,
+				StripLinks:    args.StripLinks,
+				SignatureOnly: args.SignatureOnly,
+			}
+			var text string
+			var err error
+			if args.NotebookPath != "" {
+				text, err = tools.GetNotebookHoverInfo(s.sessionContext(ctx), s.lspClient, args.NotebookPath, args.Cell, args.Line, args.Column, opts)
+			} else if args.FilePath != "" {
+				text, err = tools.GetHoverInfo(s.sessionContext(ctx), s.lspClient, args.FilePath, args.Line, args.Column, opts)
+			} else {
+				return nil, fmt.Errorf("Failed to get hover information: filePath or notebookPath is required")
+			}
 			if err != nil {
 				return nil, fmt.Errorf("Failed to get hover information: %v", err)
 			}
@@ -173,11 +626,29 @@ func (s *server) registerTools() error {
 		return fmt.Errorf("failed to register tool: %v", err)
 	}
 
-	err = s.mcpServer.RegisterTool(
+	err = s.registerTool(
+		"get_type_at_range",
+		"Explain an expression: get the inferred type and documentation of the expression in a range of a file. rust-analyzer reports the type of the whole expression, other servers the hover at its middle.",
+		func(ctx context.Context, args GetTypeAtRangeArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.GetTypeAtRange(s.sessionContext(ctx), s.lspClient, args.FilePath, args.StartLine, args.StartColumn, args.EndLine, args.EndColumn, tools.HoverOptions{
+				PlainText:  args.PlainText,
+				StripLinks: args.StripLinks,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("Failed to get type at range: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
 		"document_symbols",
-		"List all symbols (functions, methods, classes, etc.) in a document in a hierarchical structure.",
-		func(args DocumentSymbolsArgs) (*mcp_golang.ToolResponse, error) {
-			text, err := tools.GetDocumentSymbols(s.ctx, s.lspClient, args.FilePath, args.ShowLineNumbers)
+		"List all symbols (functions, methods, classes, etc.) in a document in a hierarchical structure. Use kinds and maxDepth to shorten the output for large files.",
+		func(ctx context.Context, args DocumentSymbolsArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.GetDocumentSymbols(s.sessionContext(ctx), s.lspClient, args.FilePath, args.ShowLineNumbers, args.Kinds, args.MaxDepth)
 			if err != nil {
 				return nil, fmt.Errorf("Failed to get document symbols: %v", err)
 			}
@@ -188,5 +659,558 @@ func (s *server) registerTools() error {
 		return fmt.Errorf("failed to register tool: %v", err)
 	}
 
+	err = s.registerTool(
+		"file_outline",
+		"Show the source of a file with function and method bodies elided, keeping signatures, types, and doc comments. A token-efficient way to view a whole file.",
+		func(ctx context.Context, args FileOutlineArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.GetFileOutline(s.sessionContext(ctx), s.lspClient, args.FilePath, args.ShowLineNumbers)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to get file outline: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"read_source",
+		"Read a file or a range of its lines as the language server sees it, optionally marking the lines where a given symbol occurs.",
+		func(ctx context.Context, args ReadSourceArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.ReadSource(s.sessionContext(ctx), s.lspClient, args.FilePath, args.StartLine, args.EndLine, args.SymbolName, args.ShowLineNumbers)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to read source: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"breadcrumbs",
+		"Get the chain of symbols (e.g. type, method) enclosing a position in a file, with their ranges. Useful for orienting within stack traces and diagnostics.",
+		func(ctx context.Context, args BreadcrumbsArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.GetBreadcrumbs(s.sessionContext(ctx), s.lspClient, args.FilePath, args.Line, args.Column)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to get breadcrumbs: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"search_text",
+		"Search workspace files for text or a regular expression, respecting .gitignore and default exclusions. Finds strings, comments, and config files that symbol-based search misses.",
+		func(ctx context.Context, args SearchTextArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.SearchText(s.sessionContext(ctx), s.workspaceWatcher, args.Query, args.IsRegex, args.CaseSensitive, args.IncludeGlobs, args.ContextLines, args.MaxResults)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to search text: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"unused_symbols",
+		"Report workspace symbols that have no references outside their own declaration. Restrict with a query, kinds, or directory to keep runtimes bounded.",
+		func(ctx context.Context, args UnusedSymbolsArgs) (*mcp_golang.ToolResponse, error) {
+			// Left out, only exported symbols are checked as the schema advertises
+			exportedOnly := args.ExportedOnly == nil || *args.ExportedOnly
+			text, err := tools.FindUnusedSymbols(s.sessionContext(ctx), s.lspClient, args.Query, args.Kinds, args.Directory, exportedOnly)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to find unused symbols: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"implementations",
+		"List all types implementing an interface, with how many of the interface's methods each type implements.",
+		func(ctx context.Context, args ImplementationsArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.FindImplementations(s.sessionContext(ctx), s.lspClient, args.InterfaceName, args.ShowLineNumbers, args.MatchMode)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to find implementations: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"call_graph",
+		"Export the call graph around a function or method as Graphviz DOT or JSON, following its callees, callers or both for a few levels. Symbols in dependencies are included but not expanded.",
+		func(ctx context.Context, args CallGraphArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.GetCallGraph(s.sessionContext(ctx), s.lspClient, args.SymbolName, tools.CallGraphOptions{
+				Direction: args.Direction,
+				Depth:     args.Depth,
+				MaxNodes:  args.MaxNodes,
+				Format:    args.Format,
+				ScopePath: args.ScopePath,
+				MatchMode: args.MatchMode,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("Failed to get call graph: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"who_imports",
+		"Find the files and packages in the workspace that import a package or module path. Use this to judge the impact of changing a package's API.",
+		func(ctx context.Context, args WhoImportsArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.FindImporters(s.sessionContext(ctx), s.workspaceWatcher, args.ImportPath, args.IncludeSubpackages)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to find importers: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"dependency_graph",
+		"Build the import graph between the packages (directories) of the workspace and return it as a tree or Graphviz DOT, with import cycles and the imports that close them listed first. Use this to plan refactors that move code between packages.",
+		func(ctx context.Context, args DependencyGraphArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.GetDependencyGraph(s.sessionContext(ctx), s.workspaceWatcher, tools.DependencyGraphOptions{
+				Directory:       args.Directory,
+				IncludeExternal: args.IncludeExternal,
+				IncludeTests:    args.IncludeTests,
+				Format:          args.Format,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("Failed to build dependency graph: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"public_api",
+		"List the exported symbols declared in a package directory with their kinds, signatures and doc summaries, an overview of the package's API surface for design reviews. Test files are skipped.",
+		func(ctx context.Context, args PublicAPIArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.GetPublicAPI(s.sessionContext(ctx), s.lspClient, s.workspaceWatcher, args.Directory, args.TopLevelOnly)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to get public API: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"hover_batch",
+		"Get hover information for several positions in one call, e.g. every identifier flagged in a diagnostic.",
+		func(ctx context.Context, args HoverBatchArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.GetHoverInfoBatch(s.sessionContext(ctx), s.lspClient, args.FilePath, args.Positions, tools.HoverOptions{
+				PlainText:     args.PlainText,
+				StripLinks:    args.StripLinks,
+				SignatureOnly: args.SignatureOnly,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("Failed to get hover information: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"recent_changes",
+		"List the files in the workspace that were created, changed or deleted since this session last called recent_changes, or within a given duration. Use this to catch up on edits made outside your own tool calls.",
+		func(ctx context.Context, args RecentChangesArgs) (*mcp_golang.ToolResponse, error) {
+			afterSeq, since, err := s.changesCursor(ctx, args.Since)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to list recent changes: %v", err)
+			}
+			text, err := tools.RecentChanges(s.workspaceWatcher, afterSeq, since, args.Limit)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to list recent changes: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"health",
+		"Report the state of the language server process, its initialize latency, open documents, cached diagnostics, watcher registrations and the last error the server returned. Use this to find out why queries return empty or fail.",
+		func(ctx context.Context, args HealthArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.Health(s.lspClient, s.workspaceWatcher, s.config.lspCommand)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to check health: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"get_metrics",
+		"Report per-tool call counts and latencies, language server request counts and latencies by method, cache hit rates and watcher event rates since the server started. Use this to find slow tools or a struggling language server.",
+		func(ctx context.Context, args GetMetricsArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.GetMetrics()
+			if err != nil {
+				return nil, fmt.Errorf("Failed to get metrics: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"clear_caches",
+		"Drop cached open documents, diagnostics and workspace symbol searches to free memory in a long-running session. Documents are reopened and symbols searched again on demand. Cache sizes are shown by get_metrics.",
+		func(ctx context.Context, args ClearCachesArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.ClearCaches(args.Caches)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to clear caches: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"about",
+		"Report the mcp-language-server version, the language server binary and version, the Go runtime and the workspace root. Include this output when reporting a bug.",
+		func(ctx context.Context, args AboutArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.About(s.sessionContext(ctx), s.lspClient, tools.AboutInfo{
+				Version:      serverVersion(),
+				WorkspaceDir: s.config.workspaceDir,
+				LSPCommand:   s.config.lspCommand,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("Failed to get version information: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"check_edit",
+		"Check proposed text edits without writing them: the language server sees the edited file, the resulting diagnostics are reported, and the file is left unchanged. Use it to validate a patch before apply_text_edit.",
+		func(ctx context.Context, args CheckEditArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.CheckEdit(s.sessionContext(ctx), s.lspClient, args.FilePath, args.Edits)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to check edit: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"create_overlay",
+		"Create an in-memory document with the given text, e.g. unsaved editor contents or generated code. Hover, definition, references and diagnostics then use this text instead of the file on disk, which is never written. The path does not need to exist.",
+		func(ctx context.Context, args OverlayArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.CreateOverlay(s.sessionContext(ctx), s.lspClient, args.FilePath, args.Content)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to create overlay: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"update_overlay",
+		"Replace the text of an overlay created with create_overlay.",
+		func(ctx context.Context, args OverlayArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.UpdateOverlay(s.sessionContext(ctx), s.lspClient, args.FilePath, args.Content)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to update overlay: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"discard_overlay",
+		"Discard an overlay, so the language server sees the file on disk again.",
+		func(ctx context.Context, args DiscardOverlayArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.DiscardOverlay(s.sessionContext(ctx), s.lspClient, args.FilePath)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to discard overlay: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"diff_definitions",
+		"Compare the definitions of two symbols, or of one symbol at two git revisions, as a unified diff. Useful for reconciling duplicated implementations or finding what changed in a function.",
+		func(ctx context.Context, args DiffDefinitionsArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.DiffDefinitions(s.sessionContext(ctx), s.lspClient, args.SymbolName, args.OtherSymbolName, args.BaseRef, args.OtherRef)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to diff definitions: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"symbol_history",
+		"Show when a symbol's definition was introduced and last modified, and the commits that changed it, by following its lines back through git history. The definition is returned along with the commits.",
+		func(ctx context.Context, args SymbolHistoryArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.GetSymbolHistory(s.sessionContext(ctx), s.lspClient, args.SymbolName, args.ScopePath, args.MatchMode, args.MaxCommits)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to get symbol history: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"get_diagnostics_for_changes",
+		"Get the diagnostics on lines changed since a git revision (default HEAD), across all modified and new files in the workspace. Diagnostics on unchanged lines are left out, so this shows what recent edits broke.",
+		func(ctx context.Context, args GetDiagnosticsForChangesArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.GetDiagnosticsForChanges(s.sessionContext(ctx), s.lspClient, s.config.workspaceDir, args.BaseRef)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to get diagnostics for changes: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"get_diagnostics_summary",
+		"Get only the number of errors and warnings per file across the workspace, files with the most errors first. No messages are included, so this is a cheap way to decide which files to inspect with get_diagnostics. Covers the files the language server has reported on.",
+		func(ctx context.Context, args GetDiagnosticsSummaryArgs) (*mcp_golang.ToolResponse, error) {
+			text := tools.GetDiagnosticsSummary(s.lspClient, s.config.workspaceDir, args.ErrorsOnly, args.MaxFiles)
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"get_coverage",
+		"Report test coverage per function by combining a Go coverprofile or lcov file with the language server's document symbols. Files are listed least covered first, to help choose where to add tests.",
+		func(ctx context.Context, args GetCoverageArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.GetCoverage(s.sessionContext(ctx), s.lspClient, s.config.workspaceDir, args.ProfilePath, args.FilePath, args.MaxFiles)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to get coverage: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"warmup",
+		"Open representative files, wait for the language server to finish indexing and check that workspace symbol queries work. Reports the time until the server was ready. Call this first in large workspaces when other tools return nothing.",
+		func(ctx context.Context, args WarmupArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.Warmup(s.sessionContext(ctx), s.lspClient, s.workspaceWatcher, args.MaxFiles, time.Duration(args.TimeoutSeconds)*time.Second)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to warm up: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		setDefaultsTool,
+		"Set arguments once for the rest of the session instead of repeating them: showLineNumbers, verbosity, scopePath and format are used by every tool that takes them when a call leaves them out, and maxTokens limits the size of every response. Arguments given in a call always win. Defaults add to those set before unless clear is set. Returns the session's defaults.",
+		func(ctx context.Context, args SetDefaultsArgs) (*mcp_golang.ToolResponse, error) {
+			sessionID := lsp.SessionFromContext(ctx)
+			defaults := s.defaults.get(sessionID)
+			if args.Clear {
+				defaults = toolDefaults{}
+			}
+			if args.ShowLineNumbers != nil {
+				defaults.ShowLineNumbers = args.ShowLineNumbers
+			}
+			if args.Verbosity != "" {
+				defaults.Verbosity = args.Verbosity
+			}
+			if args.ScopePath != "" {
+				defaults.ScopePath = args.ScopePath
+			}
+			if args.Format != "" {
+				defaults.Format = args.Format
+			}
+			if args.MaxTokens != 0 {
+				defaults.MaxTokens = args.MaxTokens
+			}
+			if err := defaults.validate(); err != nil {
+				return nil, err
+			}
+			s.defaults.set(sessionID, defaults)
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(defaults.describe())), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"set_workspace",
+		"Switch the workspace to another root directory. The language server and file watcher are shut down and started again for the new root, which takes as long as a server restart. Files opened in the previous workspace are closed.",
+		func(ctx context.Context, args SetWorkspaceArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := s.setWorkspace(args.WorkspaceDir)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to switch workspace: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"open_file",
+		"Open a file in the language server and pin it, so it stays open and its diagnostics stay up to date until close_file is called. Files are otherwise opened on demand and the least recently used ones are closed. Returns the file's diagnostics.",
+		func(ctx context.Context, args OpenFileArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.OpenFile(s.sessionContext(ctx), s.lspClient, args.FilePath)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to open file: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"close_file",
+		"Close a file in the language server, including pinned files, to reduce the server's memory use. Tools reopen the file when they need it.",
+		func(ctx context.Context, args CloseFileArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.CloseFile(s.sessionContext(ctx), s.lspClient, args.FilePath)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to close file: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"expand_macro",
+		"Show the recursive expansion of the Rust macro call at a position, using rust-analyzer.",
+		func(ctx context.Context, args ExpandMacroArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.ExpandMacro(s.sessionContext(ctx), s.lspClient, args.FilePath, args.Line, args.Column)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to expand macro: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"runnables",
+		"List the tests, benchmarks and binaries in a Rust file that rust-analyzer can run, with the cargo command for each.",
+		func(ctx context.Context, args RunnablesArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.ListRunnables(s.sessionContext(ctx), s.lspClient, args.FilePath, args.Line)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to list runnables: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"run_runnable",
+		"Run a test, benchmark or binary listed by runnables and return whether it succeeded and the end of its output.",
+		func(ctx context.Context, args RunRunnableArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.RunRunnable(s.sessionContext(ctx), s.lspClient, args.FilePath, args.Label, time.Duration(args.TimeoutSeconds)*time.Second)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to run: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
+	err = s.registerTool(
+		"switch_source_header",
+		"Find the header of a C or C++ source file, or the source file implementing a header, using clangd. Returns the path of the counterpart.",
+		func(ctx context.Context, args SwitchSourceHeaderArgs) (*mcp_golang.ToolResponse, error) {
+			text, err := tools.SwitchSourceHeader(s.sessionContext(ctx), s.lspClient, args.FilePath)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to switch source and header: %v", err)
+			}
+			return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(text)), nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tool: %v", err)
+	}
+
 	return nil
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	mcp_golang "github.com/metoro-io/mcp-golang"
+)
+
+// withVerbosity gives a tool call's context the verbosity preset of the call: the
+// tool's verbosity argument when it has one and it is set, otherwise the server's
+// --verbosity
+func (s *server) withVerbosity(next toolHandler) toolHandler {
+	return func(ctx context.Context, args any) (*mcp_golang.ToolResponse, error) {
+		verbosity := verbosityArgument(reflect.ValueOf(args))
+		if err := tools.ValidateVerbosity(verbosity); err != nil {
+			return nil, err
+		}
+		if verbosity == "" {
+			verbosity = s.config.verbosity
+		}
+		return next(tools.WithVerbosity(ctx, verbosity), args)
+	}
+}
+
+// verbosityArgument returns the verbosity field of a tool's arguments, or ""
+func verbosityArgument(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	for i := 0; i < v.NumField(); i++ {
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		if name == "verbosity" && v.Field(i).Kind() == reflect.String {
+			return v.Field(i).String()
+		}
+	}
+	return ""
+}
+
+// parseVerbosity checks the --verbosity flag
+func parseVerbosity(verbosity string) (string, error) {
+	if verbosity == "" {
+		return tools.VerbosityNormal, nil
+	}
+	if err := tools.ValidateVerbosity(verbosity); err != nil {
+		return "", fmt.Errorf("--verbosity: %v", err)
+	}
+	return verbosity, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import buildinfo "runtime/debug"
+
+// version is set at build time with -ldflags "-X main.version=v1.2.3"
+var version string
+
+// serverVersion returns the build version, falling back to the module version or
+// VCS revision recorded by the Go toolchain
+func serverVersion() string {
+	if version != "" {
+		return version
+	}
+	info, ok := buildinfo.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	revision, modified := "", false
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision == "" {
+		return "devel"
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if modified {
+		revision += "-dirty"
+	}
+	return "devel+" + revision
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/memory"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	mcp_golang "github.com/metoro-io/mcp-golang"
+)
+
+// setWorkspaceTool is the tool that switches workspaces. It takes the workspace lock
+// itself, so its handler is not wrapped by workspaceLocked.
+const setWorkspaceTool = "set_workspace"
+
+// workspaceSwitchTimeout bounds shutting down the language server of the previous
+// workspace
+const workspaceSwitchTimeout = 5 * time.Second
+
+// workspaceLocked runs a tool call under the read lock of the workspace, so it never
+// sees the language server or watcher while they are replaced. The documents the
+// call opens are held until it returns, see lsp.HoldDocuments.
+func (s *server) workspaceLocked(next toolHandler) toolHandler {
+	return func(ctx context.Context, args any) (*mcp_golang.ToolResponse, error) {
+		s.workspaceMu.RLock()
+		defer s.workspaceMu.RUnlock()
+		ctx, release := lsp.HoldDocuments(ctx)
+		defer release()
+		return next(ctx, args)
+	}
+}
+
+// registerWorkspaceHandlers hooks resources and notifications up to the watcher and
+// language server of the current workspace
+func (s *server) registerWorkspaceHandlers() error {
+	if err := s.registerWorkspaceResources(); err != nil {
+		return fmt.Errorf("resource registration failed: %v", err)
+	}
+	s.registerNotifications()
+	return nil
+}
+
+// setWorkspace shuts down the language server and watcher, then starts them again
+// for dir. The new workspace must be inside the workspace given at startup or a path
+// allowed by --allow-path. If the language server cannot be started for dir, the
+// previous workspace is restored.
+func (s *server) setWorkspace(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("invalid workspace directory: %v", err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", fmt.Errorf("invalid workspace directory: %v", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", dir)
+	}
+	if err := s.workspaceRoots.Check(dir); err != nil {
+		return "", fmt.Errorf("%v, pass it to --allow-path to allow switching to it", err)
+	}
+
+	s.workspaceMu.Lock()
+	defer s.workspaceMu.Unlock()
+
+	previous := s.config.workspaceDir
+	if dir == previous {
+		return fmt.Sprintf("Workspace is already %s", dir), nil
+	}
+
+	log.Printf("Switching workspace from %s to %s", previous, dir)
+	start := time.Now()
+	previousClient := s.lspClient
+	s.closeWorkspace()
+
+	s.config.workspaceDir = dir
+	if err := s.initializeLSP(); err != nil {
+		log.Printf("Failed to start language server for %s: %v", dir, err)
+		if s.lspClient != previousClient {
+			s.closeWorkspace()
+		} else {
+			s.workspaceCancel()
+		}
+
+		s.config.workspaceDir = previous
+		if restoreErr := s.initializeLSP(); restoreErr != nil {
+			return "", fmt.Errorf("failed to switch to %s: %v, and restarting the language server for %s failed: %v", dir, err, previous, restoreErr)
+		}
+		if restoreErr := s.registerWorkspaceHandlers(); restoreErr != nil {
+			log.Printf("Failed to restore workspace %s: %v", previous, restoreErr)
+		}
+		return "", fmt.Errorf("failed to switch to %s: %v, the workspace is still %s", dir, err, previous)
+	}
+	if err := s.registerWorkspaceHandlers(); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Switched workspace from %s to %s in %s.\n", previous, dir, time.Since(start).Round(time.Millisecond)))
+	sb.WriteString("The language server was restarted. Files opened in the previous workspace, overlays and recent changes were discarded.\n")
+
+	if s.config.warmup {
+		report, err := tools.Warmup(s.ctx, s.lspClient, s.workspaceWatcher, 0, s.config.warmupTimeout)
+		if err != nil {
+			sb.WriteString(fmt.Sprintf("\nWarmup failed: %v\n", err))
+		} else {
+			sb.WriteString("\n" + report)
+		}
+	}
+	return sb.String(), nil
+}
+
+// closeWorkspace shuts down the language server and watcher of the current workspace
+// and drops the state that belongs to it. The caller holds the workspace lock.
+func (s *server) closeWorkspace() {
+	ctx, cancel := context.WithTimeout(context.Background(), workspaceSwitchTimeout)
+	defer cancel()
+	if s.lspClient != nil {
+		for _, cache := range s.lspClient.Caches() {
+			memory.Default.Unregister(cache.Name())
+		}
+	}
+	s.shutdownLSP(ctx)
+	s.deregisterWorkspaceResources()
+
+	s.changesCursorsMu.Lock()
+	s.changesCursors = make(map[string]uint64)
+	s.changesCursorsMu.Unlock()
+
+	if s.symbolIndex != nil {
+		s.symbolIndex = nil
+		tools.SetSymbolIndex(nil)
+	}
+	tools.ClearSymbolCache()
+	tools.ClearCodeLensCache()
+}